@@ -0,0 +1,253 @@
+// Package analytics keeps daily counts of the messages posted in public
+// channels, how many members posted them, and which channels they were posted
+// in. It also builds the weekly digest of those counts, which is posted by a
+// scheduled job.
+package analytics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// DigestJobName is the name of the scheduled job that posts the weekly digest.
+// The consumer's scheduled job handler should run Analytics.Digest for it.
+const DigestJobName = "analytics_digest"
+
+const (
+	redisMessagesPrefix = "analytics:messages:"
+	redisUsersPrefix    = "analytics:users:"
+	redisChannelsPrefix = "analytics:channels:"
+
+	// retention is how long the daily counts are kept
+	retention = 35 * 24 * time.Hour
+
+	dayFormat = "2006-01-02"
+
+	digestDays        = 7
+	digestTopChannels = 5
+)
+
+// DigestPayload is the payload of the digest scheduled job.
+type DigestPayload struct {
+	// ChannelID is the channel the digest is posted to.
+	ChannelID string `json:"channel_id"`
+}
+
+// ChannelCount is the number of messages posted in a channel.
+type ChannelCount struct {
+	ChannelID string
+	Messages  int64
+}
+
+// Day is the counts for a day, in UTC.
+type Day struct {
+	Date     time.Time
+	Messages int64
+}
+
+// Summary is the counts for a range of days.
+type Summary struct {
+	// Days are the message counts for each day, oldest first.
+	Days []Day
+
+	// Messages is the total number of messages.
+	Messages int64
+
+	// ActiveUsers is the number of members who posted at least once. It's an
+	// estimate, with a standard error of 0.81%.
+	ActiveUsers int64
+
+	// TopChannels are the busiest channels, busiest first.
+	TopChannels []ChannelCount
+}
+
+// Analytics is the Redis-backed analytics store.
+type Analytics struct {
+	r redis.UniversalClient
+}
+
+// New returns an *Analytics.
+func New(rc redis.UniversalClient) *Analytics {
+	return &Analytics{r: rc}
+}
+
+func day(t time.Time) string { return t.UTC().Format(dayFormat) }
+
+// Record satisfies workqueue.MessageHandler. It counts messages posted by
+// members in public channels, ignoring bots and events like channel joins.
+func (a *Analytics) Record(ctx workqueue.Context, me *slackevents.MessageEvent) (bool, bool, error) {
+	if len(me.User) == 0 || len(me.BotID) > 0 || me.User == ctx.Self().ID {
+		return false, true, fmt.Errorf("message isn't from a member")
+	}
+
+	if len(me.SubType) > 0 && me.SubType != "thread_broadcast" {
+		return false, true, fmt.Errorf("message subtype %s isn't counted", me.SubType)
+	}
+
+	d := day(ctx.Meta().Time)
+
+	pipe := a.r.TxPipeline()
+
+	pipe.Incr(ctx, redisMessagesPrefix+d)
+	pipe.Expire(ctx, redisMessagesPrefix+d, retention)
+	pipe.PFAdd(ctx, redisUsersPrefix+d, me.User)
+	pipe.Expire(ctx, redisUsersPrefix+d, retention)
+	pipe.ZIncrBy(ctx, redisChannelsPrefix+d, 1, me.Channel)
+	pipe.Expire(ctx, redisChannelsPrefix+d, retention)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return true, false, fmt.Errorf("failed to record message: %w", err)
+	}
+
+	return false, false, nil
+}
+
+// Summarize returns the counts for the days days up to and including the day
+// end is in.
+func (a *Analytics) Summarize(ctx context.Context, end time.Time, days, topChannels int) (Summary, error) {
+	end = end.UTC().Truncate(24 * time.Hour)
+
+	var s Summary
+
+	userKeys := make([]string, 0, days)
+	channels := make(map[string]int64)
+
+	for i := days - 1; i >= 0; i-- {
+		date := end.AddDate(0, 0, -i)
+		d := day(date)
+
+		n, err := a.r.Get(ctx, redisMessagesPrefix+d).Int64()
+		if err != nil && err != redis.Nil {
+			return Summary{}, fmt.Errorf("failed to get message count for %s: %w", d, err)
+		}
+
+		s.Days = append(s.Days, Day{Date: date, Messages: n})
+		s.Messages += n
+
+		userKeys = append(userKeys, redisUsersPrefix+d)
+
+		zs, err := a.r.ZRangeWithScores(ctx, redisChannelsPrefix+d, 0, -1).Result()
+		if err != nil {
+			return Summary{}, fmt.Errorf("failed to get channel counts for %s: %w", d, err)
+		}
+
+		for _, z := range zs {
+			id, _ := z.Member.(string)
+			channels[id] += int64(z.Score)
+		}
+	}
+
+	// with more than one key, PFCOUNT counts the union of the sets
+	users, err := a.r.PFCount(ctx, userKeys...).Result()
+	if err != nil {
+		return Summary{}, fmt.Errorf("failed to count active users: %w", err)
+	}
+
+	s.ActiveUsers = users
+	s.TopChannels = top(channels, topChannels)
+
+	return s, nil
+}
+
+// top returns the n channels with the most messages, busiest first.
+func top(channels map[string]int64, n int) []ChannelCount {
+	list := make([]ChannelCount, 0, len(channels))
+
+	for id, c := range channels {
+		list = append(list, ChannelCount{ChannelID: id, Messages: c})
+	}
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Messages == list[j].Messages {
+			return list[i].ChannelID < list[j].ChannelID
+		}
+
+		return list[i].Messages > list[j].Messages
+	})
+
+	if len(list) > n {
+		list = list[:n]
+	}
+
+	return list
+}
+
+// Digest handles the DigestJobName scheduled job, posting the digest for the
+// week before the job was due to the channel in its DigestPayload.
+func (a *Analytics) Digest(ctx workqueue.Context, sj *workqueue.ScheduledJobEvent) (bool, bool, error) {
+	var p DigestPayload
+
+	if err := json.Unmarshal(sj.Payload, &p); err != nil {
+		return false, false, fmt.Errorf("failed to unmarshal digest payload: %w", err)
+	}
+
+	if len(p.ChannelID) == 0 {
+		return false, false, fmt.Errorf("digest payload has no channel_id")
+	}
+
+	// the digest is for the days before the job was due, so the last day
+	// isn't still being counted
+	end := time.Unix(sj.ScheduledTime, 0).UTC().AddDate(0, 0, -1)
+
+	s, err := a.Summarize(ctx, end, digestDays, digestTopChannels)
+	if err != nil {
+		return true, false, err
+	}
+
+	opts := []slack.MsgOption{
+		slack.MsgOptionDisableLinkUnfurl(),
+		slack.MsgOptionText(digestMessage(s), false),
+	}
+
+	if _, _, _, err := ctx.Slack().SendMessageContext(ctx, p.ChannelID, opts...); err != nil {
+		return true, false, fmt.Errorf("failed to SendMessageContext: %w", err)
+	}
+
+	return false, false, nil
+}
+
+// digestMessage returns the digest for the summary.
+func digestMessage(s Summary) string {
+	if len(s.Days) == 0 {
+		return "There's nothing to report this week."
+	}
+
+	b := &strings.Builder{}
+
+	first, last := s.Days[0], s.Days[len(s.Days)-1]
+
+	fmt.Fprintf(b, ":bar_chart: *Weekly digest for %s to %s*\n\n", first.Date.Format("Jan 2"), last.Date.Format("Jan 2"))
+
+	busiest := first
+	for _, d := range s.Days[1:] {
+		if d.Messages > busiest.Messages {
+			busiest = d
+		}
+	}
+
+	fmt.Fprintf(b, "- %d messages were posted in public channels, %d a day on average\n", s.Messages, s.Messages/int64(len(s.Days)))
+	fmt.Fprintf(b, "- About %d members posted at least once\n", s.ActiveUsers)
+
+	if busiest.Messages > 0 {
+		fmt.Fprintf(b, "- The busiest day was %s, with %d messages\n", busiest.Date.Format("Monday"), busiest.Messages)
+	}
+
+	if len(s.TopChannels) > 0 {
+		b.WriteString("\nThe busiest channels were:\n")
+
+		for i, c := range s.TopChannels {
+			fmt.Fprintf(b, "%d. <#%s>: %d messages\n", i+1, c.ChannelID, c.Messages)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,65 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_top(t *testing.T) {
+	channels := map[string]int64{
+		"C1": 10,
+		"C2": 30,
+		"C3": 10,
+		"C4": 5,
+	}
+
+	want := []ChannelCount{
+		{ChannelID: "C2", Messages: 30},
+		{ChannelID: "C1", Messages: 10},
+		{ChannelID: "C3", Messages: 10},
+	}
+
+	if diff := cmp.Diff(want, top(channels, 3)); diff != "" {
+		t.Fatalf("top() mismatch (-want +got):\n%s", diff)
+	}
+
+	if got := top(nil, 3); len(got) != 0 {
+		t.Fatalf("top(nil) = %v, want empty", got)
+	}
+}
+
+func Test_digestMessage(t *testing.T) {
+	start := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC) // a Monday
+
+	s := Summary{
+		Messages:    70,
+		ActiveUsers: 12,
+		TopChannels: []ChannelCount{{ChannelID: "C1", Messages: 50}, {ChannelID: "C2", Messages: 20}},
+	}
+
+	for i := 0; i < digestDays; i++ {
+		n := int64(5)
+		if i == 2 {
+			n = 40
+		}
+
+		s.Days = append(s.Days, Day{Date: start.AddDate(0, 0, i), Messages: n})
+	}
+
+	want := `:bar_chart: *Weekly digest for Jun 1 to Jun 7*
+
+- 70 messages were posted in public channels, 10 a day on average
+- About 12 members posted at least once
+- The busiest day was Wednesday, with 40 messages
+
+The busiest channels were:
+1. <#C1>: 50 messages
+2. <#C2>: 20 messages
+`
+
+	if diff := cmp.Diff(want, digestMessage(s)); diff != "" {
+		t.Fatalf("digestMessage() mismatch (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,164 @@
+// Package archive stores public messages in PostgreSQL, for search and
+// analytics. Edits and deletions are recorded against the archived message, so
+// the archive reflects what's in the channel while keeping what was said.
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	// register the postgres database/sql driver
+	_ "github.com/lib/pq"
+)
+
+// Message is an archived message.
+type Message struct {
+	ChannelID string
+	TS        string
+
+	// ThreadTS is the ts of the thread's parent message. It's empty for
+	// messages that aren't in a thread.
+	ThreadTS string
+
+	// UserID is the ID of the user who posted the message, or the bot ID for
+	// bot messages.
+	UserID string
+
+	// SubType is the Slack message subtype, like channel_join. It's empty for
+	// normal messages.
+	SubType string
+
+	Text string
+
+	// Raw is the Slack event.
+	Raw json.RawMessage
+}
+
+// Archive is the PostgreSQL message archive.
+type Archive struct {
+	db *sql.DB
+}
+
+// New returns an *Archive. Migrate should be called before it's used.
+func New(db *sql.DB) *Archive {
+	return &Archive{db: db}
+}
+
+// Open connects to the PostgreSQL database at databaseURL, like the one in
+// Heroku's DATABASE_URL, and returns an *Archive using it.
+func Open(databaseURL string) (*Archive, error) {
+	db, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	db.SetMaxOpenConns(8)
+	db.SetConnMaxLifetime(30 * time.Minute)
+
+	return New(db), nil
+}
+
+// Close closes the database.
+func (a *Archive) Close() error {
+	return a.db.Close()
+}
+
+// ParseTS returns the time of a Slack message ts, like "1590000000.000100".
+func ParseTS(ts string) (time.Time, error) {
+	i := strings.IndexByte(ts, '.')
+	if i == -1 {
+		i = len(ts)
+	}
+
+	sec, err := strconv.ParseInt(ts[:i], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse ts %q: %w", ts, err)
+	}
+
+	var usec int64
+
+	if i < len(ts) {
+		frac := ts[i+1:]
+		if len(frac) > 6 {
+			frac = frac[:6]
+		}
+
+		if usec, err = strconv.ParseInt(frac+strings.Repeat("0", 6-len(frac)), 10, 64); err != nil {
+			return time.Time{}, fmt.Errorf("failed to parse ts %q: %w", ts, err)
+		}
+	}
+
+	return time.Unix(sec, usec*int64(time.Microsecond)).UTC(), nil
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: len(s) > 0}
+}
+
+// Insert archives the message. Messages that are already archived, like when
+// an event is delivered twice, are left as they are.
+func (a *Archive) Insert(ctx context.Context, m Message) error {
+	posted, err := ParseTS(m.TS)
+	if err != nil {
+		return err
+	}
+
+	raw := m.Raw
+	if len(raw) == 0 {
+		raw = json.RawMessage("{}")
+	}
+
+	const q = `INSERT INTO messages (channel_id, ts, thread_ts, user_id, subtype, text, posted_at, raw)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+ON CONFLICT (channel_id, ts) DO NOTHING`
+
+	if _, err := a.db.ExecContext(ctx, q, m.ChannelID, m.TS, nullString(m.ThreadTS), m.UserID, nullString(m.SubType), m.Text, posted, []byte(raw)); err != nil {
+		return fmt.Errorf("failed to insert message %s/%s: %w", m.ChannelID, m.TS, err)
+	}
+
+	return nil
+}
+
+// Edit records the message's new text. The text before the first edit is kept
+// in original_text. It returns false if the message isn't archived, like edits
+// to messages in private channels.
+func (a *Archive) Edit(ctx context.Context, channelID, ts, text string, at time.Time) (bool, error) {
+	const q = `UPDATE messages
+SET original_text = COALESCE(original_text, text), text = $3, edited_at = $4
+WHERE channel_id = $1 AND ts = $2`
+
+	res, err := a.db.ExecContext(ctx, q, channelID, ts, text, at.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to edit message %s/%s: %w", channelID, ts, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return n > 0, nil
+}
+
+// Delete marks the message as deleted. It returns false if the message isn't
+// archived.
+func (a *Archive) Delete(ctx context.Context, channelID, ts string, at time.Time) (bool, error) {
+	const q = `UPDATE messages SET deleted_at = $3 WHERE channel_id = $1 AND ts = $2 AND deleted_at IS NULL`
+
+	res, err := a.db.ExecContext(ctx, q, channelID, ts, at.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to delete message %s/%s: %w", channelID, ts, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return n > 0, nil
+}
@@ -0,0 +1,57 @@
+package archive
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTS(t *testing.T) {
+	tests := []struct {
+		name    string
+		ts      string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "micros",
+			ts:   "1590000000.000100",
+			want: time.Unix(1590000000, 100*int64(time.Microsecond)).UTC(),
+		},
+		{
+			name: "short_fraction",
+			ts:   "1590000000.5",
+			want: time.Unix(1590000000, 500*int64(time.Millisecond)).UTC(),
+		},
+		{
+			name: "no_fraction",
+			ts:   "1590000000",
+			want: time.Unix(1590000000, 0).UTC(),
+		},
+		{
+			name:    "invalid",
+			ts:      "abc.123",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTS(tt.ts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseTS() error = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Fatalf("ParseTS() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrationsOrdered(t *testing.T) {
+	for i, m := range migrations {
+		if m.version != i+1 {
+			t.Fatalf("migration %q has version %d, want %d", m.name, m.version, i+1)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package archive
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// migration is a schema change. Migrations are applied in order, and each is
+// only applied once. Once a migration is released it shouldn't be changed;
+// add a new one instead.
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create_messages",
+		sql: `CREATE TABLE messages (
+	channel_id    text        NOT NULL,
+	ts            text        NOT NULL,
+	thread_ts     text,
+	user_id       text        NOT NULL,
+	subtype       text,
+	text          text        NOT NULL,
+	original_text text,
+	posted_at     timestamptz NOT NULL,
+	edited_at     timestamptz,
+	deleted_at    timestamptz,
+	raw           jsonb       NOT NULL,
+	archived_at   timestamptz NOT NULL DEFAULT now(),
+
+	PRIMARY KEY (channel_id, ts)
+);
+
+CREATE INDEX messages_posted_at_idx ON messages (posted_at);
+CREATE INDEX messages_user_id_posted_at_idx ON messages (user_id, posted_at);
+CREATE INDEX messages_thread_idx ON messages (channel_id, thread_ts) WHERE thread_ts IS NOT NULL;`,
+	},
+	{
+		version: 2,
+		name:    "messages_text_search",
+		sql:     `CREATE INDEX messages_text_search_idx ON messages USING gin (to_tsvector('english', text));`,
+	},
+}
+
+// migrationsLockID is the Postgres advisory lock held while migrating, so
+// archivers starting together don't both apply the same migration
+const migrationsLockID = 0x676f70686572 // "gopher"
+
+// Migrate applies the migrations that haven't been applied yet, and returns
+// how many were.
+func (a *Archive) Migrate(ctx context.Context) (int, error) {
+	const create = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    integer     PRIMARY KEY,
+	name       text        NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`
+
+	if _, err := a.db.ExecContext(ctx, create); err != nil {
+		return 0, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var applied int
+
+	for _, m := range migrations {
+		ok, err := a.migrate(ctx, m)
+		if err != nil {
+			return applied, err
+		}
+
+		if ok {
+			applied++
+		}
+	}
+
+	return applied, nil
+}
+
+// migrate applies the migration in its own transaction, if it hasn't been
+// applied already.
+func (a *Archive) migrate(ctx context.Context, m migration) (bool, error) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+	}
+
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationsLockID); err != nil {
+		return false, fmt.Errorf("failed to lock migrations: %w", err)
+	}
+
+	var exists bool
+
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM schema_migrations WHERE version = $1)`, m.version).Scan(&exists)
+	if err != nil && err != sql.ErrNoRows {
+		return false, fmt.Errorf("failed to check migration %d: %w", m.version, err)
+	}
+
+	if exists {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, m.sql); err != nil {
+		return false, fmt.Errorf("failed to apply migration %d (%s): %w", m.version, m.name, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, m.version, m.name); err != nil {
+		return false, fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,72 @@
+package archive
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Query is a full-text search of the archive.
+type Query struct {
+	// Text is the search, in the web search syntax Postgres supports, like
+	// `"exact phrase" -excluded either or other`.
+	Text string
+
+	// ChannelID limits the results to a channel, if set.
+	ChannelID string
+
+	// UserID limits the results to a user's messages, if set.
+	UserID string
+
+	// Limit is the most results to return.
+	Limit int
+}
+
+// Result is a message matching a Query.
+type Result struct {
+	ChannelID string
+	TS        string
+	UserID    string
+	Text      string
+	Posted    time.Time
+}
+
+// Search returns the messages matching the query, most relevant first.
+// Deleted messages are never returned.
+func (a *Archive) Search(ctx context.Context, q Query) ([]Result, error) {
+	// the to_tsvector expression must match the messages_text_search_idx
+	// index, otherwise it isn't used
+	const query = `SELECT channel_id, ts, user_id, text, posted_at
+FROM messages, websearch_to_tsquery('english', $1) query
+WHERE deleted_at IS NULL
+	AND to_tsvector('english', text) @@ query
+	AND ($2 = '' OR channel_id = $2)
+	AND ($3 = '' OR user_id = $3)
+ORDER BY ts_rank(to_tsvector('english', text), query) DESC, posted_at DESC
+LIMIT $4`
+
+	rows, err := a.db.QueryContext(ctx, query, q.Text, q.ChannelID, q.UserID, q.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+
+	defer func() { _ = rows.Close() }()
+
+	var results []Result
+
+	for rows.Next() {
+		var r Result
+
+		if err := rows.Scan(&r.ChannelID, &r.TS, &r.UserID, &r.Text, &r.Posted); err != nil {
+			return nil, fmt.Errorf("failed to scan search result: %w", err)
+		}
+
+		results = append(results, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read search results: %w", err)
+	}
+
+	return results, nil
+}
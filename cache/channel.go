@@ -7,7 +7,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 	"github.com/rs/zerolog"
 	"github.com/slack-go/slack"
 )
@@ -21,6 +21,7 @@ type channelPutter interface {
 	Hash(ctx context.Context, id string) (string, bool, error)
 	TTL(ctx context.Context, id string) (time.Duration, bool, error)
 	Put(ctx context.Context, id, name, data, hash string) error
+	Delete(ctx context.Context, id string) error
 }
 
 // ChannelFiller is channel cache filler.
@@ -31,8 +32,8 @@ type ChannelFiller struct {
 }
 
 // NewChannelFiller generates a new cache populator.
-func NewChannelFiller(sc *slack.Client, rc *redis.Client, logger zerolog.Logger) (*ChannelFiller, error) {
-	res := rc.Set(redisByIDPrefix+"populator_test_id_should_be_auto_removed", "foobar", time.Second)
+func NewChannelFiller(ctx context.Context, sc *slack.Client, rc redis.UniversalClient, logger zerolog.Logger) (*ChannelFiller, error) {
+	res := rc.Set(ctx, redisByIDPrefix+"populator_test_id_should_be_auto_removed", "foobar", time.Second)
 	if err := res.Err(); err != nil {
 		return nil, fmt.Errorf("failed to set test key: %w", err)
 	}
@@ -44,14 +45,10 @@ func NewChannelFiller(sc *slack.Client, rc *redis.Client, logger zerolog.Logger)
 	}, nil
 }
 
-var channelHasher = sha256.New()
-
+// hashit is safe to call concurrently, as channels may be refreshed by
+// several handlers at once.
 func hashit(j []byte) string {
-	defer channelHasher.Reset()
-	if _, err := channelHasher.Write(j); err != nil {
-		panic(err.Error())
-	}
-	return fmt.Sprintf("%x", channelHasher.Sum(nil))
+	return fmt.Sprintf("%x", sha256.Sum256(j))
 }
 
 // Fill loads the cache.
@@ -107,20 +104,59 @@ func (c *ChannelFiller) Fill(ctx context.Context) error {
 	return nil
 }
 
+// Refresh updates the channel in the cache, for when it's created, renamed, or
+// unarchived. Archived channels are removed, like Fill skips them.
+func (c *ChannelFiller) Refresh(ctx context.Context, id string) error {
+	ch, err := c.s.GetConversationInfoContext(ctx, id, false)
+	if err != nil {
+		return fmt.Errorf("failed to get channel %s info: %w", id, err)
+	}
+
+	if ch.IsArchived {
+		return c.Remove(ctx, id)
+	}
+
+	j, _ := json.Marshal(ch)
+
+	if err := c.store.Put(ctx, ch.ID, ch.Name, string(j), hashit(j)); err != nil {
+		return err
+	}
+
+	c.l.Debug().
+		Str("channel_id", ch.ID).
+		Str("channel_name", ch.Name).
+		Msg("refreshed channel")
+
+	return nil
+}
+
+// Remove removes the channel from the cache, for when it's archived.
+func (c *ChannelFiller) Remove(ctx context.Context, id string) error {
+	if err := c.store.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	c.l.Debug().
+		Str("channel_id", id).
+		Msg("removed channel")
+
+	return nil
+}
+
 // Channel represents a Redis-backed channel cache.
 type Channel struct {
 	store channelGetter
 }
 
 // NewChannel creates a new channel cache.
-func NewChannel(rc *redis.Client) *Channel {
+func NewChannel(rc redis.UniversalClient) *Channel {
 	return &Channel{store: &store{r: rc}}
 }
 
 // Channel finds a channel by its ID in the cache. If the channel is not found,
 // err will be nil and notFound true.
-func (c *Channel) Channel(id string) (channel slack.Channel, notFound bool, err error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func (c *Channel) Channel(ctx context.Context, id string) (channel slack.Channel, notFound bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
 	return c.store.GetByID(ctx, id)
@@ -128,8 +164,8 @@ func (c *Channel) Channel(id string) (channel slack.Channel, notFound bool, err
 
 // Lookup finds a channel by its name, without the #, in the cache. If the
 // channel is not found, err will be nil and notFound true.
-func (c *Channel) Lookup(name string) (slack.Channel, bool, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+func (c *Channel) Lookup(ctx context.Context, name string) (slack.Channel, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
 	return c.store.GetByName(ctx, name)
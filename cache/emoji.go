@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// redisEmojiKey is the hash of custom emoji names to their image URL, or
+	// alias:<name> for aliases
+	redisEmojiKey = "cache:emoji"
+
+	// emojiCacheTTL is how long the emoji list is kept if it stops being
+	// refreshed
+	emojiCacheTTL = 24 * time.Hour
+)
+
+// EmojiFiller is the custom emoji cache filler.
+type EmojiFiller struct {
+	s *slack.Client
+	r redis.UniversalClient
+	l zerolog.Logger
+}
+
+// NewEmojiFiller generates a new emoji cache populator.
+func NewEmojiFiller(sc *slack.Client, rc redis.UniversalClient, logger zerolog.Logger) *EmojiFiller {
+	return &EmojiFiller{
+		s: sc,
+		r: rc,
+		l: logger,
+	}
+}
+
+// Fill loads the cache, replacing the emoji that were in it.
+func (e *EmojiFiller) Fill(ctx context.Context) error {
+	emoji, err := e.s.GetEmojiContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get emoji list: %w", err)
+	}
+
+	// build the new list under a temporary key, and rename it over the old
+	// one, so the cache is never missing emoji while it's filled
+	tmp := redisEmojiKey + ":filling"
+
+	pipe := e.r.TxPipeline()
+	pipe.Del(ctx, tmp)
+
+	if len(emoji) > 0 {
+		fields := make(map[string]interface{}, len(emoji))
+
+		for name, url := range emoji {
+			fields[name] = url
+		}
+
+		pipe.HMSet(ctx, tmp, fields)
+		pipe.Rename(ctx, tmp, redisEmojiKey)
+		pipe.Expire(ctx, redisEmojiKey, emojiCacheTTL)
+	} else {
+		pipe.Del(ctx, redisEmojiKey)
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store emoji list: %w", err)
+	}
+
+	e.l.Debug().
+		Int("processed_count", len(emoji)).
+		Msg("processed emoji")
+
+	return nil
+}
+
+// Emoji represents a Redis-backed custom emoji cache.
+type Emoji struct {
+	r redis.UniversalClient
+}
+
+// NewEmoji creates a new emoji cache.
+func NewEmoji(rc redis.UniversalClient) *Emoji {
+	return &Emoji{r: rc}
+}
+
+// Exists returns whether the workspace has a custom emoji, or alias, with the
+// name. The name may be wrapped in colons, like :gopher:. Slack's standard
+// emoji aren't in the cache.
+func (e *Emoji) Exists(ctx context.Context, name string) (bool, error) {
+	name = strings.Trim(name, ":")
+
+	ok, err := e.r.HExists(ctx, redisEmojiKey, name).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check emoji %s: %w", name, err)
+	}
+
+	return ok, nil
+}
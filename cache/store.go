@@ -6,7 +6,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 	"github.com/slack-go/slack"
 )
 
@@ -16,13 +16,13 @@ const (
 )
 
 type store struct {
-	r *redis.Client
+	r redis.UniversalClient
 }
 
 func (s *store) Hash(ctx context.Context, id string) (string, bool, error) {
 	key := fmt.Sprintf("%s%s:hash", redisByIDPrefix, id)
 
-	res := s.r.Get(key)
+	res := s.r.Get(ctx, key)
 	if err := res.Err(); err != nil {
 		if err == redis.Nil {
 			return "", true, nil
@@ -40,7 +40,7 @@ func (s *store) Hash(ctx context.Context, id string) (string, bool, error) {
 }
 
 func (s *store) TTL(ctx context.Context, id string) (time.Duration, bool, error) {
-	res := s.r.TTL(redisByIDPrefix + id)
+	res := s.r.TTL(ctx, redisByIDPrefix+id)
 	if err := res.Err(); err != nil {
 		if err == redis.Nil {
 			return 0, true, nil
@@ -60,17 +60,22 @@ func (s *store) TTL(ctx context.Context, id string) (time.Duration, bool, error)
 const channelCacheTTL = 14 * 24 * time.Hour // 14 days
 
 func (s *store) Put(ctx context.Context, id, name, data, hash string) error {
-	res := s.r.Set(redisByIDPrefix+id, data, channelCacheTTL)
+	// so a renamed channel can't be looked up by its old name
+	if err := s.deleteStaleName(ctx, id, name); err != nil {
+		return err
+	}
+
+	res := s.r.Set(ctx, redisByIDPrefix+id, data, channelCacheTTL)
 	if err := res.Err(); err != nil {
 		return fmt.Errorf("failed to set channel data: %w", err)
 	}
 
-	res = s.r.Set(redisByNamePrefix+name, id, channelCacheTTL)
+	res = s.r.Set(ctx, redisByNamePrefix+name, id, channelCacheTTL)
 	if err := res.Err(); err != nil {
 		return fmt.Errorf("failed to set name to ID mapping: %w", err)
 	}
 
-	res = s.r.Set(redisByIDPrefix+id+":hash", hash, channelCacheTTL)
+	res = s.r.Set(ctx, redisByIDPrefix+id+":hash", hash, channelCacheTTL)
 	if err := res.Err(); err != nil {
 		return fmt.Errorf("failed to set channel data hash: %w", err)
 	}
@@ -78,8 +83,50 @@ func (s *store) Put(ctx context.Context, id, name, data, hash string) error {
 	return nil
 }
 
+// Delete removes the channel from the cache.
+func (s *store) Delete(ctx context.Context, id string) error {
+	if err := s.deleteStaleName(ctx, id, ""); err != nil {
+		return err
+	}
+
+	if err := s.r.Del(ctx, redisByIDPrefix+id, redisByIDPrefix+id+":hash").Err(); err != nil {
+		return fmt.Errorf("failed to delete channel data: %w", err)
+	}
+
+	return nil
+}
+
+// deleteStaleName removes the name to ID mapping for the name the channel is
+// cached with, if it's not name.
+func (s *store) deleteStaleName(ctx context.Context, id, name string) error {
+	ch, nf, err := s.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if nf || ch.Name == name {
+		return nil
+	}
+
+	// only if it wasn't taken over by another channel
+	cur, err := s.r.Get(ctx, redisByNamePrefix+ch.Name).Result()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to get name to ID mapping: %w", err)
+	}
+
+	if cur != id {
+		return nil
+	}
+
+	if err := s.r.Del(ctx, redisByNamePrefix+ch.Name).Err(); err != nil {
+		return fmt.Errorf("failed to delete name to ID mapping: %w", err)
+	}
+
+	return nil
+}
+
 func (s *store) GetByID(ctx context.Context, id string) (slack.Channel, bool, error) {
-	res := s.r.Get(redisByIDPrefix + id)
+	res := s.r.Get(ctx, redisByIDPrefix+id)
 	if err := res.Err(); err != nil {
 		if err == redis.Nil {
 			return slack.Channel{}, true, nil
@@ -102,7 +149,7 @@ func (s *store) GetByID(ctx context.Context, id string) (slack.Channel, bool, er
 }
 
 func (s *store) GetByName(ctx context.Context, name string) (slack.Channel, bool, error) {
-	res := s.r.Get(redisByNamePrefix + name)
+	res := s.r.Get(ctx, redisByNamePrefix+name)
 	if err := res.Err(); err != nil {
 		if err == redis.Nil {
 			return slack.Channel{}, true, nil
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+const (
+	redisUserGroupByHandlePrefix = "cache:usergroup:by_handle:"
+	redisUserGroupByIDPrefix     = "cache:usergroup:by_id:"
+	redisUserGroupMembersPrefix  = "cache:usergroup:members:"
+
+	userGroupCacheTTL = 24 * time.Hour
+)
+
+// UserGroupFiller is the user group cache filler.
+type UserGroupFiller struct {
+	s *slack.Client
+	r redis.UniversalClient
+	l zerolog.Logger
+}
+
+// NewUserGroupFiller generates a new user group cache populator.
+func NewUserGroupFiller(sc *slack.Client, rc redis.UniversalClient, logger zerolog.Logger) *UserGroupFiller {
+	return &UserGroupFiller{
+		s: sc,
+		r: rc,
+		l: logger,
+	}
+}
+
+// Fill loads the cache.
+func (u *UserGroupFiller) Fill(ctx context.Context) error {
+	groups, err := u.s.GetUserGroupsContext(ctx, slack.GetUserGroupsOptionIncludeUsers(true))
+	if err != nil {
+		return fmt.Errorf("failed to get user groups: %w", err)
+	}
+
+	for _, ug := range groups {
+		if err := u.Put(ctx, ug); err != nil {
+			return err
+		}
+	}
+
+	u.l.Debug().
+		Int("processed_count", len(groups)).
+		Msg("processed user groups")
+
+	return nil
+}
+
+// Put updates the user group in the cache, like when it's changed. Disabled
+// user groups are removed.
+func (u *UserGroupFiller) Put(ctx context.Context, ug slack.UserGroup) error {
+	old, err := u.r.Get(ctx, redisUserGroupByIDPrefix+ug.ID).Result()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to get user group %s handle: %w", ug.ID, err)
+	}
+
+	pipe := u.r.TxPipeline()
+
+	// so a renamed group can't be looked up by its old handle
+	if len(old) > 0 && old != ug.Handle {
+		pipe.Del(ctx, redisUserGroupByHandlePrefix+old)
+	}
+
+	members := redisUserGroupMembersPrefix + ug.ID
+	pipe.Del(ctx, members)
+
+	if ug.DateDelete != 0 {
+		pipe.Del(ctx, redisUserGroupByIDPrefix+ug.ID, redisUserGroupByHandlePrefix+ug.Handle)
+	} else {
+		pipe.Set(ctx, redisUserGroupByIDPrefix+ug.ID, ug.Handle, userGroupCacheTTL)
+		pipe.Set(ctx, redisUserGroupByHandlePrefix+ug.Handle, ug.ID, userGroupCacheTTL)
+
+		if len(ug.Users) > 0 {
+			vals := make([]interface{}, len(ug.Users))
+			for i, m := range ug.Users {
+				vals[i] = m
+			}
+
+			pipe.SAdd(ctx, members, vals...)
+			pipe.Expire(ctx, members, userGroupCacheTTL)
+		}
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to store user group %s: %w", ug.ID, err)
+	}
+
+	return nil
+}
+
+// UserGroup represents a Redis-backed user group cache.
+type UserGroup struct {
+	r redis.UniversalClient
+}
+
+// NewUserGroup creates a new user group cache.
+func NewUserGroup(rc redis.UniversalClient) *UserGroup {
+	return &UserGroup{r: rc}
+}
+
+// InGroup returns whether the user is a member of the user group with the
+// handle. The handle may start with an @, like @gopher-admins. If the group
+// isn't found, err will be nil and the user isn't a member.
+func (u *UserGroup) InGroup(ctx context.Context, userID, handle string) (bool, error) {
+	handle = strings.TrimPrefix(handle, "@")
+
+	id, err := u.r.Get(ctx, redisUserGroupByHandlePrefix+handle).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to get user group %s: %w", handle, err)
+	}
+
+	ok, err := u.r.SIsMember(ctx, redisUserGroupMembersPrefix+id, userID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check user group %s membership: %w", handle, err)
+	}
+
+	return ok, nil
+}
@@ -0,0 +1,145 @@
+// Package chat describes messages, users, channels, and replying to them
+// without tying them to a chat platform's API, so a handler can be written
+// once and serve every platform there's an adapter for. The slackchat package
+// is the adapter for Slack, and the platforms bridged onto its events, like
+// Discord.
+//
+// Platforms don't all do the same things, like Discord having no ephemeral
+// messages, so a handler should check the Replier's Capabilities include what
+// it's about to do, and do something else if they don't.
+package chat
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrUnsupported is wrapped by the errors of the Replier methods the platform
+// can't do.
+var ErrUnsupported = errors.New("not supported by the chat platform")
+
+// User is someone on the platform, or a bot.
+type User struct {
+	// ID is the user's ID on the platform, which mentions use.
+	ID string
+
+	// Name is the user's handle, like "gopher".
+	Name string
+
+	// RealName is the name the user goes by, falling back to Name.
+	RealName string
+
+	// IsBot is whether the user is a bot.
+	IsBot bool
+}
+
+// DisplayName returns the name the user goes by.
+func (u User) DisplayName() string {
+	if len(u.RealName) > 0 {
+		return u.RealName
+	}
+
+	return u.Name
+}
+
+// Channel is where a message was sent.
+type Channel struct {
+	// ID is the channel's ID on the platform.
+	ID string
+
+	// Name is the channel's name, without a #. It's empty when the adapter
+	// doesn't know it, like for direct messages.
+	Name string
+
+	// IsDM is whether the channel is a direct message with the bot.
+	IsDM bool
+}
+
+// Message is a message someone sent.
+type Message struct {
+	// Platform is the chat platform the message was sent on, like "slack".
+	Platform string
+
+	// ID is the message's ID on the platform, which it's reacted to and
+	// replied to with.
+	ID string
+
+	// Channel is where the message was sent.
+	Channel Channel
+
+	// User is who sent it. Adapters fill in what the event says about them,
+	// which may only be the ID.
+	User User
+
+	// Text is the message's text, in the platform's markup.
+	Text string
+
+	// ThreadID is the ID of the message that started the thread the message
+	// is in, or of the message it replies to, if any.
+	ThreadID string
+}
+
+// InThread returns whether the message is in a thread, or a reply to another.
+func (m *Message) InThread() bool {
+	return len(m.ThreadID) > 0 && m.ThreadID != m.ID
+}
+
+// Capability is something a platform can do when replying, which not all of
+// them can. They're flags, so a Replier's are or'd together.
+type Capability uint
+
+const (
+	// Threads is replying in a thread, or to a message, instead of in the
+	// channel.
+	Threads Capability = 1 << iota
+
+	// Ephemeral is replying with a message only one user can see.
+	Ephemeral
+
+	// Reactions is reacting to a message with an emoji.
+	Reactions
+
+	// DirectMessages is messaging a user directly.
+	DirectMessages
+
+	// Files is uploading files.
+	Files
+)
+
+// Has returns whether c includes all of caps.
+func (c Capability) Has(caps Capability) bool {
+	return c&caps == caps
+}
+
+// Replier replies to an event, on the platform it happened on.
+type Replier interface {
+	// Capabilities returns what the platform can do.
+	Capabilities() Capability
+
+	// Reply sends a message to the event's channel, in its thread if it's in
+	// one.
+	Reply(text string) error
+
+	// ReplyInThread replies in the thread of the event's message, starting
+	// one if it's not in a thread already. Requires Threads.
+	ReplyInThread(text string) error
+
+	// ReplyEphemeral replies in the event's channel with a message only the
+	// user can see. Requires Ephemeral.
+	ReplyEphemeral(userID, text string) error
+
+	// ReplyDM sends the user a direct message. Requires DirectMessages.
+	ReplyDM(userID, text string) error
+
+	// React adds the emoji reaction, by its Slack name like "wave", to the
+	// event's message. Requires Reactions.
+	React(emoji string) error
+}
+
+// MessageHandler handles a message, replying with r. What happens to the event
+// when it fails is up to the adapter it's registered with.
+type MessageHandler func(ctx context.Context, m *Message, r Replier) error
+
+// JoinHandler handles someone joining the community, replying with r, which
+// has no channel to Reply in.
+type JoinHandler func(ctx context.Context, u *User, r Replier) error
@@ -0,0 +1,45 @@
+package chat
+
+import "testing"
+
+func TestCapability_Has(t *testing.T) {
+	c := Threads | Reactions
+
+	if !c.Has(Threads) || !c.Has(Threads|Reactions) {
+		t.Fatalf("%b.Has() = false, want true for what it includes", c)
+	}
+
+	if c.Has(Ephemeral) || c.Has(Threads|Ephemeral) {
+		t.Fatalf("%b.Has() = true, want false for what it doesn't include", c)
+	}
+}
+
+func TestMessage_InThread(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Message
+		want bool
+	}{
+		{name: "channel", m: Message{ID: "1"}, want: false},
+		{name: "parent", m: Message{ID: "1", ThreadID: "1"}, want: false},
+		{name: "reply", m: Message{ID: "2", ThreadID: "1"}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.InThread(); got != tt.want {
+				t.Fatalf("InThread() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUser_DisplayName(t *testing.T) {
+	if got := (User{Name: "gopher", RealName: "Gopher"}).DisplayName(); got != "Gopher" {
+		t.Fatalf("DisplayName() = %q, want %q", got, "Gopher")
+	}
+
+	if got := (User{Name: "gopher"}).DisplayName(); got != "gopher" {
+		t.Fatalf("DisplayName() = %q, want %q", got, "gopher")
+	}
+}
@@ -0,0 +1,198 @@
+// Package slackchat is the chat adapter for Slack, and for the platforms the
+// gateway bridges onto Slack's events, like Discord. It converts the workqueue's
+// Slack events to chat types, and replies with the workqueue.Context, so chat
+// handlers can be registered with the workqueue like any other.
+package slackchat
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/gobridge/gopherbot/chat"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// SlackCapabilities are what replying on Slack can do, which is everything.
+const SlackCapabilities = chat.Threads | chat.Ephemeral | chat.Reactions | chat.DirectMessages | chat.Files
+
+var (
+	// capsMu protects capabilities
+	capsMu sync.RWMutex
+
+	// capabilities are what replying can do on each platform the workqueue
+	// handles events from
+	capabilities = map[string]chat.Capability{
+		workqueue.PlatformSlack: SlackCapabilities,
+	}
+)
+
+// Register sets what replying on a platform bridged onto Slack's events can
+// do, so chat handlers know. It should be called when the platform's
+// workqueue.Workspace is set up, before its events are handled.
+func Register(platform string, caps chat.Capability) {
+	capsMu.Lock()
+	defer capsMu.Unlock()
+
+	capabilities[platform] = caps
+}
+
+// Capabilities returns what replying on the platform can do. Platforms that
+// weren't registered can only be replied to in the channel.
+func Capabilities(platform string) chat.Capability {
+	capsMu.RLock()
+	defer capsMu.RUnlock()
+
+	return capabilities[platform]
+}
+
+// platform returns the platform the event is from, which is Slack unless its
+// metadata says otherwise.
+func platform(ctx workqueue.Context) string {
+	if p := ctx.Meta().Platform; len(p) > 0 {
+		return p
+	}
+
+	return workqueue.PlatformSlack
+}
+
+// Message returns the chat.Message for the message event.
+func Message(ctx workqueue.Context, me *slackevents.MessageEvent) *chat.Message {
+	return &chat.Message{
+		Platform: platform(ctx),
+		ID:       me.TimeStamp,
+		Channel: chat.Channel{
+			ID:   me.Channel,
+			IsDM: me.ChannelType == "im",
+		},
+		User: chat.User{
+			ID:    me.User,
+			Name:  me.Username,
+			IsBot: len(me.BotID) > 0,
+		},
+		Text:     me.Text,
+		ThreadID: me.ThreadTimeStamp,
+	}
+}
+
+// User returns the chat.User for the Slack user.
+func User(u *slack.User) chat.User {
+	return chat.User{
+		ID:       u.ID,
+		Name:     u.Name,
+		RealName: u.RealName,
+		IsBot:    u.IsBot,
+	}
+}
+
+// replier is the chat.Replier for an event, which replies with its Context.
+type replier struct {
+	ctx  workqueue.Context
+	caps chat.Capability
+
+	// channel and threadTS are where Reply posts, if the event has a channel
+	channel  string
+	threadTS string
+}
+
+var _ chat.Replier = replier{}
+
+// NewReplier returns a chat.Replier for the event ctx is handling. Reply posts
+// in m's channel, and thread, so it returns workqueue.ErrNoReplyTarget if m is
+// nil. Replying in a way the platform can't returns an error wrapping
+// chat.ErrUnsupported, which is discarded instead of retried.
+func NewReplier(ctx workqueue.Context, m *chat.Message) chat.Replier {
+	r := replier{ctx: ctx, caps: Capabilities(platform(ctx))}
+
+	if m != nil {
+		r.channel, r.threadTS = m.Channel.ID, m.ThreadID
+	}
+
+	return r
+}
+
+func unsupported(method string) error {
+	return workqueue.Discard(fmt.Errorf("%s: %w", method, chat.ErrUnsupported))
+}
+
+// Capabilities satisfies chat.Replier.
+func (r replier) Capabilities() chat.Capability { return r.caps }
+
+// Reply satisfies chat.Replier.
+func (r replier) Reply(text string) error {
+	if len(r.channel) == 0 {
+		return workqueue.ErrNoReplyTarget
+	}
+
+	opts := []slack.MsgOption{
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionDisableLinkUnfurl(),
+	}
+
+	if len(r.threadTS) > 0 && r.caps.Has(chat.Threads) {
+		opts = append(opts, slack.MsgOptionTS(r.threadTS))
+	}
+
+	if _, _, err := r.ctx.Slack().PostMessageContext(r.ctx, r.channel, opts...); err != nil {
+		return fmt.Errorf("failed to PostMessageContext: %w", err)
+	}
+
+	return nil
+}
+
+// ReplyInThread satisfies chat.Replier.
+func (r replier) ReplyInThread(text string) error {
+	if !r.caps.Has(chat.Threads) {
+		return unsupported("ReplyInThread")
+	}
+
+	return r.ctx.ReplyInThread(text)
+}
+
+// ReplyEphemeral satisfies chat.Replier.
+func (r replier) ReplyEphemeral(userID, text string) error {
+	if !r.caps.Has(chat.Ephemeral) {
+		return unsupported("ReplyEphemeral")
+	}
+
+	return r.ctx.ReplyEphemeral(userID, text)
+}
+
+// ReplyDM satisfies chat.Replier.
+func (r replier) ReplyDM(userID, text string) error {
+	if !r.caps.Has(chat.DirectMessages) {
+		return unsupported("ReplyDM")
+	}
+
+	return r.ctx.ReplyDM(userID, text)
+}
+
+// React satisfies chat.Replier.
+func (r replier) React(emoji string) error {
+	if !r.caps.Has(chat.Reactions) {
+		return unsupported("React")
+	}
+
+	return r.ctx.React(emoji)
+}
+
+// MessageHandler returns h as a workqueue message handler, which can be
+// registered with any of the message Register methods. Errors are handled
+// like a MessageHandlerV2's, so h can wrap them with workqueue.Retryable or
+// workqueue.Discard.
+func MessageHandler(h chat.MessageHandler) workqueue.MessageHandlerV2 {
+	return func(ctx workqueue.Context, me *slackevents.MessageEvent) error {
+		m := Message(ctx, me)
+		return h(ctx.StdContext(), m, NewReplier(ctx, m))
+	}
+}
+
+// JoinHandler returns h as a workqueue team join handler. Errors are handled
+// like a TeamJoinHandlerV2's.
+func JoinHandler(h chat.JoinHandler) workqueue.TeamJoinHandlerV2 {
+	return func(ctx workqueue.Context, tj *slack.TeamJoinEvent) error {
+		u := User(&tj.User)
+		return h(ctx.StdContext(), &u, NewReplier(ctx, nil))
+	}
+}
@@ -0,0 +1,159 @@
+package slackchat
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gobridge/gopherbot/chat"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/gobridge/gopherbot/workqueue/wqtest"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// testPlatform is a platform bridged onto Slack's events, like Discord, and
+// testCaps are what replying on it can do, which isn't replying ephemerally.
+const (
+	testPlatform = "example"
+	testCaps     = chat.Threads | chat.Reactions
+)
+
+// greet is a handler written once for every platform, which replies in the
+// thread where it can and in the channel where it can't.
+func greet(_ context.Context, m *chat.Message, r chat.Replier) error {
+	if m.User.IsBot {
+		return nil
+	}
+
+	text := "hello <@" + m.User.ID + ">"
+
+	if r.Capabilities().Has(chat.Threads) {
+		return r.ReplyInThread(text)
+	}
+
+	return r.Reply(text)
+}
+
+func TestMessageHandler(t *testing.T) {
+	me := &slackevents.MessageEvent{
+		Channel:     "C123",
+		ChannelType: "channel",
+		User:        "U123",
+		Text:        "hi",
+		TimeStamp:   "1.000",
+	}
+
+	Register(testPlatform, testCaps)
+
+	for _, platform := range []string{"", workqueue.PlatformSlack, testPlatform} {
+		t.Run(platform, func(t *testing.T) {
+			s := wqtest.NewSlack(t)
+
+			ctx := wqtest.NewContext(t, workqueue.ContextConfig{
+				Meta:        workqueue.EventMetadata{Platform: platform},
+				RawEvent:    []byte(`{"type":"message","channel":"C123","user":"U123","text":"hi","ts":"1.000"}`),
+				SlackClient: s.Client(),
+			})
+
+			if err := MessageHandler(greet)(ctx, me); err != nil {
+				t.Fatalf("handler unexpected error: %v", err)
+			}
+
+			calls := s.CallsTo("chat.postMessage")
+
+			if len(calls) != 1 || calls[0].Values.Get("channel") != "C123" || calls[0].Values.Get("thread_ts") != "1.000" || calls[0].Values.Get("text") != "hello <@U123>" {
+				t.Fatalf("chat.postMessage calls = %+v, want one in the thread", calls)
+			}
+		})
+	}
+}
+
+func TestMessage(t *testing.T) {
+	ctx := workqueue.NewContext(context.Background(), workqueue.ContextConfig{
+		Meta: workqueue.EventMetadata{Platform: testPlatform},
+	})
+
+	got := Message(ctx, &slackevents.MessageEvent{
+		Channel:         "123",
+		ChannelType:     "im",
+		User:            "42",
+		Username:        "robot",
+		BotID:           "42",
+		Text:            "beep",
+		TimeStamp:       "456",
+		ThreadTimeStamp: "455",
+	})
+
+	want := chat.Message{
+		Platform: testPlatform,
+		ID:       "456",
+		Channel:  chat.Channel{ID: "123", IsDM: true},
+		User:     chat.User{ID: "42", Name: "robot", IsBot: true},
+		Text:     "beep",
+		ThreadID: "455",
+	}
+
+	if *got != want {
+		t.Fatalf("Message() = %+v, want %+v", *got, want)
+	}
+}
+
+func TestReplier_unsupported(t *testing.T) {
+	s := wqtest.NewSlack(t)
+
+	Register(testPlatform, testCaps)
+
+	ctx := wqtest.NewContext(t, workqueue.ContextConfig{
+		Meta:        workqueue.EventMetadata{Platform: testPlatform},
+		SlackClient: s.Client(),
+	})
+
+	r := NewReplier(ctx, nil)
+
+	if r.Capabilities() != testCaps {
+		t.Fatalf("Capabilities() = %b, want %b", r.Capabilities(), testCaps)
+	}
+
+	err := r.ReplyEphemeral("U123", "psst")
+	if !errors.Is(err, chat.ErrUnsupported) || !workqueue.IsDiscarded(err) {
+		t.Fatalf("ReplyEphemeral() error = %v, want a discarded chat.ErrUnsupported", err)
+	}
+
+	if err := r.Reply("hi"); !errors.Is(err, workqueue.ErrNoReplyTarget) {
+		t.Fatalf("Reply() error = %v, want workqueue.ErrNoReplyTarget", err)
+	}
+
+	if n := len(s.Calls()); n != 0 {
+		t.Fatalf("Slack called %d times, want none", n)
+	}
+}
+
+func TestJoinHandler(t *testing.T) {
+	s := wqtest.NewSlack(t)
+
+	ctx := wqtest.NewContext(t, workqueue.ContextConfig{SlackClient: s.Client()})
+
+	var got chat.User
+
+	h := JoinHandler(func(_ context.Context, u *chat.User, r chat.Replier) error {
+		got = *u
+		return r.ReplyDM(u.ID, "welcome, "+u.DisplayName())
+	})
+
+	tj := &slack.TeamJoinEvent{User: slack.User{ID: "U123", Name: "gopher", RealName: "Gopher"}}
+
+	if err := h(ctx, tj); err != nil {
+		t.Fatalf("handler unexpected error: %v", err)
+	}
+
+	if want := (chat.User{ID: "U123", Name: "gopher", RealName: "Gopher"}); got != want {
+		t.Fatalf("user = %+v, want %+v", got, want)
+	}
+
+	calls := s.CallsTo("chat.postMessage")
+
+	if len(calls) != 1 || calls[0].Values.Get("channel") != "U123" || calls[0].Values.Get("text") != "welcome, Gopher" {
+		t.Fatalf("chat.postMessage calls = %+v, want one DM", calls)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gobridge/gopherbot/archive"
+	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/internal/heartbeat"
+	"github.com/gobridge/gopherbot/internal/keyring"
+	"github.com/gobridge/gopherbot/internal/redisutil"
+	"github.com/gobridge/gopherbot/internal/shutdown"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// consumerGroupSuffix is appended to the app name for the archiver's consumer
+// group, so it gets its own copy of each event instead of taking them from the
+// bot's consumers
+const consumerGroupSuffix = "-archiver"
+
+func runServer(cfg config.C, logger zerolog.Logger) error {
+	// set up signal catching
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, syscall.SIGTERM, syscall.SIGINT)
+
+	logger.Info().
+		Str("env", string(cfg.Env)).
+		Str("commit", cfg.Heroku.Commit).
+		Str("log_level", cfg.LogLevel.String()).
+		Msg("configuration values")
+
+	if len(cfg.DatabaseURL) == 0 {
+		return errors.New("DATABASE_URL must be set to run the archiver")
+	}
+
+	ar, err := archive.Open(cfg.DatabaseURL)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = ar.Close() }()
+
+	mctx, mcancel := context.WithTimeout(context.Background(), time.Minute)
+	applied, err := ar.Migrate(mctx)
+	mcancel()
+
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+
+	logger.Info().
+		Int("applied", applied).
+		Msg("database migrated")
+
+	rc := redisutil.ConnectRedis(cfg.Redis)
+	defer func() { _ = rc.Close() }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	defer cancel()
+
+	lhb := logger.With().Str("context", "heartbeater").Logger()
+
+	// start checking Redis health
+	_, err = heartbeat.New(ctx, heartbeat.Config{
+		RedisClient: rc,
+		Logger:      lhb,
+		AppName:     cfg.Heroku.AppName + consumerGroupSuffix,
+		UID:         cfg.Heroku.DynoID,
+		Warn:        4 * time.Second,
+		Fail:        8 * time.Second,
+	})
+	if err != nil {
+		// maybe Redis is undergoing some maintenance
+		// let's pause for a bit
+		logger.Error().
+			Err(err).
+			Msg("failed to start heartbeating; sleeping for 10 seconds before exiting")
+
+		time.Sleep(10 * time.Second)
+
+		return fmt.Errorf("failed to heartbeat: %w", err)
+	}
+
+	// event payloads may be encrypted, with the token encryption keys
+	var payloadKeys workqueue.Keyring
+
+	if len(cfg.TokenEncryptionKey) > 0 {
+		kr, err := keyring.Parse(cfg.TokenEncryptionKey, cfg.TokenEncryptionOldKeys...)
+		if err != nil {
+			return fmt.Errorf("failed to parse token encryption keys: %w", err)
+		}
+
+		payloadKeys = kr
+	}
+
+	q, err := workqueue.New(workqueue.Config{
+		ConsumerName:      cfg.Heroku.DynoID,
+		ConsumerGroup:     cfg.Heroku.AppName + consumerGroupSuffix,
+		VisibilityTimeout: 10 * time.Second,
+		RedisClient:       rc,
+		Logger:            &logger,
+		Keyring:           payloadKeys,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build workqueue: %w", err)
+	}
+
+	al := logger.With().Str("context", "archiver").Logger()
+	a := archiver{a: ar, logger: al}
+
+	q.RegisterPublicMessagesHandler(workqueue.HandlerOpts{Timeout: 10 * time.Second, Concurrency: 4, Prefetch: 16}, a.message)
+	q.RegisterMessageChangedHandler(workqueue.HandlerOpts{Timeout: 10 * time.Second}, a.messageChanged)
+	q.RegisterMessageDeletedHandler(workqueue.HandlerOpts{Timeout: 10 * time.Second}, a.messageDeleted)
+
+	stopping, drained := make(chan struct{}), make(chan struct{})
+
+	// signal handling / graceful shutdown goroutine
+	go func() {
+		defer close(drained)
+
+		sig := <-signalCh
+		close(stopping)
+
+		logger.Info().
+			Str("signal", sig.String()).
+			Msg("shutting down archiver gracefully")
+
+		// there's no HTTP intake here, so the handlers are drained first, and
+		// then what they use is stopped
+		_ = shutdown.New(logger.With().Str("context", "shutdown").Logger()).
+			Then("drain workqueue", func(ctx context.Context) error {
+				returned, err := q.DrainAndShutdown(ctx)
+
+				logger.Info().
+					Int64("returned", returned).
+					Msg("drained archiver")
+
+				return err
+			}).
+			Then("stop background tasks", func(context.Context) error {
+				cancel()
+				return nil
+			}).
+			Run(cfg.ShutdownTimeout)
+	}()
+
+	logger.Info().Msg("waiting for events")
+
+	q.Run()
+
+	// wait for the drain to be reported, if that's why Run returned
+	select {
+	case <-stopping:
+		<-drained
+	default:
+	}
+
+	return nil
+}
+
+// archiver has the workqueue handlers that write to the archive.
+//
+// The handlers never ask for a retry. Retries are published to the stream
+// again, so they would be delivered to the bot's consumer group as well, and
+// its handlers would run twice. Failed events go to the dead-letter stream
+// instead.
+type archiver struct {
+	a      *archive.Archive
+	logger zerolog.Logger
+}
+
+func (a archiver) message(ctx workqueue.Context, me *slackevents.MessageEvent) (bool, bool, error) {
+	// only public channels are archived, as anyone can search the archive
+	if me.ChannelType != "channel" {
+		return false, true, errors.New("message isn't in a public channel")
+	}
+
+	userID := me.User
+	if len(userID) == 0 {
+		userID = me.BotID
+	}
+
+	// threaded messages have a thread_ts, and so does the parent once it has
+	// replies, so only keep it for the replies
+	threadTS := me.ThreadTimeStamp
+	if threadTS == me.TimeStamp {
+		threadTS = ""
+	}
+
+	err := a.a.Insert(ctx, archive.Message{
+		ChannelID: me.Channel,
+		TS:        me.TimeStamp,
+		ThreadTS:  threadTS,
+		UserID:    userID,
+		SubType:   me.SubType,
+		Text:      me.Text,
+		Raw:       ctx.RawEvent(),
+	})
+	if err != nil {
+		return false, false, err
+	}
+
+	return false, false, nil
+}
+
+func (a archiver) messageChanged(ctx workqueue.Context, me *slackevents.MessageEvent) (bool, bool, error) {
+	if me.Message == nil {
+		return false, true, errors.New("message_changed event without message")
+	}
+
+	at := ctx.Meta().Time
+	if me.Message.Edited != nil {
+		if t, err := archive.ParseTS(me.Message.Edited.TimeStamp); err == nil {
+			at = t
+		}
+	}
+
+	ok, err := a.a.Edit(ctx, me.Channel, me.Message.TimeStamp, me.Message.Text, at)
+	if err != nil {
+		return false, false, err
+	}
+
+	if !ok {
+		return false, true, errors.New("edited message isn't archived")
+	}
+
+	return false, false, nil
+}
+
+func (a archiver) messageDeleted(ctx workqueue.Context, md *workqueue.MessageDeletedEvent) (bool, bool, error) {
+	ok, err := a.a.Delete(ctx, md.Channel, md.DeletedTimeStamp, ctx.Meta().Time)
+	if err != nil {
+		return false, false, err
+	}
+
+	if !ok {
+		return false, true, errors.New("deleted message isn't archived")
+	}
+
+	return false, false, nil
+}
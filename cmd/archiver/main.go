@@ -0,0 +1,22 @@
+package main
+
+import (
+	"log"
+
+	_ "github.com/heroku/x/hmetrics/onload"
+
+	"github.com/gobridge/gopherbot/config"
+)
+
+func main() {
+	c, err := config.Load()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	l := config.DefaultLogger(c)
+
+	if err := runServer(c, l); err != nil {
+		log.Fatalf("failed to run new archiver server: %v", err.Error())
+	}
+}
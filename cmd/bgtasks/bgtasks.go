@@ -11,9 +11,10 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/go-redis/redis"
 	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/internal/dryrun"
 	"github.com/gobridge/gopherbot/internal/heartbeat"
+	"github.com/gobridge/gopherbot/internal/redisutil"
 	"github.com/rs/zerolog"
 	"github.com/slack-go/slack"
 )
@@ -26,20 +27,25 @@ func runServer(cfg config.C, logger zerolog.Logger) error {
 
 	logger.Info().
 		Str("env", string(cfg.Env)).
-		Str("app", cfg.Heroku.AppName).
-		Str("dyno_id", cfg.Heroku.DynoID).
 		Str("commit", cfg.Heroku.Commit).
 		Str("slack_client_id", cfg.Slack.ClientID).
 		Str("log_level", cfg.LogLevel.String()).
+		Bool("dry_run", cfg.DryRun).
 		Msg("configuration values")
 
-	rc := redis.NewClient(config.DefaultRedis(cfg))
+	rc := redisutil.ConnectRedis(cfg.Redis)
 	defer func() { _ = rc.Close() }()
 
 	ctx, cancel := context.WithCancel(context.Background())
 
 	defer cancel() // only to appease govet
 
+	// reload the settings that can change on SIGHUP, or when the config file
+	// changes
+	cw := config.NewWatcher(cfg, logger.With().Str("context", "config_watcher").Logger())
+	cw.Subscribe(config.LogLevelSubscriber(logger))
+	cw.Run(ctx)
+
 	lhb := logger.With().Str("context", "heartbeater").Logger()
 
 	// start checking Redis health
@@ -63,7 +69,12 @@ func runServer(cfg config.C, logger zerolog.Logger) error {
 		return fmt.Errorf("failed to heartbeat: %w", err)
 	}
 
-	sc := slack.New(cfg.Slack.BotAccessToken, slack.OptionHTTPClient(newHTTPClient()))
+	httpc := newHTTPClient()
+	if cfg.DryRun {
+		httpc = dryrun.New(newHTTPTransport(), logger.With().Str("context", "dry_run").Logger()).Client()
+	}
+
+	sc := slack.New(cfg.Slack.BotAccessToken, slack.OptionHTTPClient(httpc))
 
 	var shadowMode bool
 	if cfg.Env != config.Production {
@@ -80,7 +91,12 @@ func runServer(cfg config.C, logger zerolog.Logger) error {
 		return err
 	}
 
-	ccDone, err := setUpChannelCacheFiller(ctx, logger, sc, rc)
+	cacheDone, err := setUpCacheFillers(ctx, cfg, logger, sc, rc)
+	if err != nil {
+		return err
+	}
+
+	schedDone, err := setUpScheduler(ctx, cfg, cw, logger, rc)
 	if err != nil {
 		return err
 	}
@@ -99,7 +115,8 @@ func runServer(cfg config.C, logger zerolog.Logger) error {
 	logger.Info().Msg("presumably running...")
 	<-gerritDone
 	<-gotimeDone
-	<-ccDone
+	<-cacheDone
+	<-schedDone
 
 	return nil
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/cache"
+	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/internal/leader"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+// redisCacheFillersLockKey is the leader lock for the cache fillers
+const redisCacheFillersLockKey = "bgtasks:cache_fillers:lock"
+
+// setUpCacheFillers runs the cache fillers while this dyno is the elected
+// leader, so running more than one bgtasks doesn't multiply the cache fills.
+func setUpCacheFillers(ctx context.Context, cfg config.C, logger zerolog.Logger, sc *slack.Client, rc redis.UniversalClient) (<-chan struct{}, error) {
+	ccLogger := logger.With().Str("context", "channel_cache_filler").Logger()
+
+	ccFiller, err := cache.NewChannelFiller(ctx, sc, rc, ccLogger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build cache filler: %w", err)
+	}
+
+	e, err := leader.New(leader.Config{
+		RedisClient: rc,
+		Logger:      logger.With().Str("context", "cache_fillers_leader").Logger(),
+		Key:         redisCacheFillersLockKey,
+		ID:          cfg.Heroku.DynoID,
+		OnElected: func(ctx context.Context) {
+			ccDone := setUpChannelCacheFiller(ctx, ccLogger, ccFiller)
+			ecDone := setUpEmojiCacheFiller(ctx, logger, sc, rc)
+			ugDone := setUpUserGroupCacheFiller(ctx, logger, sc, rc)
+
+			<-ccDone
+			<-ecDone
+			<-ugDone
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build cache fillers leader elector: %w", err)
+	}
+
+	return e.Run(ctx), nil
+}
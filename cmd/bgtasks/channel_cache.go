@@ -2,23 +2,13 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"time"
 
-	"github.com/go-redis/redis"
 	"github.com/rs/zerolog"
-	"github.com/slack-go/slack"
 	"github.com/gobridge/gopherbot/cache"
 )
 
-func setUpChannelCacheFiller(ctx context.Context, logger zerolog.Logger, sc *slack.Client, rc *redis.Client) (chan struct{}, error) {
-	logger = logger.With().Str("context", "channel_cache_filler").Logger()
-
-	filler, err := cache.NewChannelFiller(sc, rc, logger)
-	if err != nil {
-		return nil, fmt.Errorf("failed to build cache filler: %w", err)
-	}
-
+func setUpChannelCacheFiller(ctx context.Context, logger zerolog.Logger, filler *cache.ChannelFiller) chan struct{} {
 	t := time.NewTimer(0)
 	w := make(chan struct{})
 
@@ -59,5 +49,5 @@ func setUpChannelCacheFiller(ctx context.Context, logger zerolog.Logger, sc *sla
 		}
 	}()
 
-	return w, nil
+	return w
 }
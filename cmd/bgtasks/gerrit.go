@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 	"github.com/gobridge/gopherbot/internal/poller/gerrit"
 	"github.com/rs/zerolog"
 	"github.com/slack-go/slack"
@@ -49,8 +49,8 @@ func gerritNotifyFactory(logger zerolog.Logger, c *slack.Client, channelID strin
 
 const gerritPollTimeKey = "bgtasks:poller:gerrit:last_refresh_ts"
 
-func lastPoll(rc *redis.Client) (time.Time, error) {
-	res := rc.Get(gerritPollTimeKey)
+func lastPoll(ctx context.Context, rc redis.UniversalClient) (time.Time, error) {
+	res := rc.Get(ctx, gerritPollTimeKey)
 	if err := res.Err(); err != nil {
 		if err == redis.Nil {
 			return time.Time{}, nil
@@ -67,10 +67,10 @@ func lastPoll(rc *redis.Client) (time.Time, error) {
 	return time.Unix(ts, 0), nil
 }
 
-func updateLastPoll(rc *redis.Client) error {
+func updateLastPoll(ctx context.Context, rc redis.UniversalClient) error {
 	now := time.Now().UnixNano() / int64(time.Second)
 
-	res := rc.Set(gerritPollTimeKey, now, 31*24*time.Hour)
+	res := rc.Set(ctx, gerritPollTimeKey, now, 31*24*time.Hour)
 	if err := res.Err(); err != nil {
 		return fmt.Errorf("failed to set poll time: %w", err)
 	}
@@ -86,8 +86,8 @@ func initialTimer(last time.Time, duration time.Duration) time.Duration {
 	return tu
 }
 
-func setUpGerrit(ctx context.Context, shadowMode bool, logger zerolog.Logger, sc *slack.Client, rc *redis.Client) (chan struct{}, error) {
-	gs, err := gerrit.NewStore(rc)
+func setUpGerrit(ctx context.Context, shadowMode bool, logger zerolog.Logger, sc *slack.Client, rc redis.UniversalClient) (chan struct{}, error) {
+	gs, err := gerrit.NewStore(ctx, rc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build gerrit store: %w", err)
 	}
@@ -109,7 +109,7 @@ func setUpGerrit(ctx context.Context, shadowMode bool, logger zerolog.Logger, sc
 		return nil, fmt.Errorf("failed to create new gerrit poller: %w", err)
 	}
 
-	lp, err := lastPoll(rc)
+	lp, err := lastPoll(ctx, rc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get last gerrit poll time: %w", err)
 	}
@@ -146,7 +146,7 @@ func setUpGerrit(ctx context.Context, shadowMode bool, logger zerolog.Logger, sc
 
 				t.Reset(hr)
 
-				if err = updateLastPoll(rc); err != nil {
+				if err = updateLastPoll(ctx, rc); err != nil {
 					logger.Error().
 						Err(err).
 						Msg("failed to save latest poll time")
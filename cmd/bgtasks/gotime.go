@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 	"github.com/gobridge/gopherbot/internal/poller/gotime"
 	"github.com/rs/zerolog"
 	"github.com/slack-go/slack"
@@ -38,8 +38,8 @@ func goTimeNotifyFactory(logger zerolog.Logger, c *slack.Client, channelID strin
 	}
 }
 
-func setUpGoTime(ctx context.Context, shadowMode bool, logger zerolog.Logger, sc *slack.Client, rc *redis.Client) (chan struct{}, error) {
-	gs, err := gotime.NewStore(rc)
+func setUpGoTime(ctx context.Context, shadowMode bool, logger zerolog.Logger, sc *slack.Client, rc redis.UniversalClient) (chan struct{}, error) {
+	gs, err := gotime.NewStore(ctx, rc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build gotime store: %w", err)
 	}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/analytics"
+	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/scheduler"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+)
+
+func setUpScheduler(ctx context.Context, cfg config.C, cw *config.Watcher, logger zerolog.Logger, rc redis.UniversalClient) (<-chan struct{}, error) {
+	logger = logger.With().Str("context", "scheduler").Logger()
+
+	q, err := workqueue.New(workqueue.Config{
+		ConsumerName:      cfg.Heroku.DynoID,
+		ConsumerGroup:     cfg.Heroku.AppName,
+		VisibilityTimeout: 10 * time.Second,
+		RedisClient:       rc,
+		Logger:            &logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build workqueue: %w", err)
+	}
+
+	s := scheduler.New(rc, q, logger, cfg.Heroku.DynoID)
+
+	if err := putDigestJob(ctx, s, cfg.DigestChannelID); err != nil {
+		return nil, err
+	}
+
+	cw.Subscribe(digestSubscriber(ctx, s, logger))
+
+	return s.Run(ctx)
+}
+
+// digestSubscriber returns a config.Watcher subscriber that moves the weekly
+// analytics digest when its channel changes.
+func digestSubscriber(ctx context.Context, s *scheduler.Scheduler, logger zerolog.Logger) func(old, cur config.C) {
+	return func(old, cur config.C) {
+		if old.DigestChannelID == cur.DigestChannelID {
+			return
+		}
+
+		if err := putDigestJob(ctx, s, cur.DigestChannelID); err != nil {
+			logger.Error().
+				Err(err).
+				Str("digest_channel_id", cur.DigestChannelID).
+				Msg("failed to reschedule analytics digest")
+
+			return
+		}
+
+		logger.Info().
+			Str("digest_channel_id", cur.DigestChannelID).
+			Msg("digest channel changed")
+	}
+}
+
+// digestSpec is when the weekly analytics digest is posted: Mondays at 15:00
+// UTC, which is the morning in the Americas and the afternoon in Europe
+const digestSpec = "0 15 * * 1"
+
+// putDigestJob schedules the weekly analytics digest to be posted to the
+// channel, or removes the job if channelID is empty.
+func putDigestJob(ctx context.Context, s *scheduler.Scheduler, channelID string) error {
+	if len(channelID) == 0 {
+		return s.Delete(ctx, analytics.DigestJobName)
+	}
+
+	payload, err := json.Marshal(analytics.DigestPayload{ChannelID: channelID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal digest payload: %w", err)
+	}
+
+	return s.Put(ctx, scheduler.Job{
+		Name:    analytics.DigestJobName,
+		Spec:    digestSpec,
+		Payload: payload,
+	})
+}
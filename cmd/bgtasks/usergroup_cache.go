@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/cache"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+func setUpUserGroupCacheFiller(ctx context.Context, logger zerolog.Logger, sc *slack.Client, rc redis.UniversalClient) chan struct{} {
+	logger = logger.With().Str("context", "usergroup_cache_filler").Logger()
+
+	filler := cache.NewUserGroupFiller(sc, rc, logger)
+
+	t := time.NewTimer(0)
+	w := make(chan struct{})
+
+	go func() {
+		logger.Info().Msg("starting user group cache filler")
+
+		for {
+			select {
+			case <-t.C:
+				gctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+
+				err := filler.Fill(gctx)
+
+				cancel()
+
+				t.Reset(30 * time.Minute)
+
+				if err != nil {
+					logger.Error().
+						Err(err).
+						Msg("trying cache fill again in 30 minutes")
+
+					continue
+				}
+
+				logger.Trace().
+					Msg("cache fill again in 30 minutes")
+
+			case <-ctx.Done():
+				defer close(w)
+
+				logger.Info().
+					Err(ctx.Err()).
+					Msg("context canceled: shutting down poller")
+
+				return
+			}
+		}
+	}()
+
+	return w
+}
@@ -0,0 +1,222 @@
+// Package announce provides a Client struct with MessageMatchFn and Handler
+// methods, which let admins broadcast a status or incident announcement to a
+// set of channels, and later update or resolve every copy of it.
+package announce
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+// Trigger is the command prefix.
+const Trigger = "!announce"
+
+// Usage is the help text for the command.
+const Usage = "Usage: `!announce <message>`, `!announce update <id> <message>`, or `!announce resolve <id> [message]`"
+
+const (
+	redisSeqKey     = "announce:seq"
+	redisPrefix     = "announce:by_id:"
+	announcementTTL = 30 * 24 * time.Hour // 30 days
+)
+
+// Client is the announcement client.
+type Client struct {
+	rc        redis.UniversalClient
+	logger    zerolog.Logger
+	channelID string
+	targets   []string
+}
+
+// New returns an announcement Client. The command is only accepted in the
+// channel with ID channelID, and announcements are posted to the channels
+// named in targets (without the leading #).
+func New(rc redis.UniversalClient, logger zerolog.Logger, channelID string, targets []string) *Client {
+	return &Client{
+		rc:        rc,
+		logger:    logger,
+		channelID: channelID,
+		targets:   targets,
+	}
+}
+
+// MessageMatchFn satisfies handler.MessageMatchFn.
+func (c *Client) MessageMatchFn(_ bool, m handler.Messenger) bool {
+	if m.ChannelID() != c.channelID {
+		return false
+	}
+
+	return strings.HasPrefix(strings.ToLower(m.Text()), Trigger)
+}
+
+// Handler satisfies handler.MessageActionFn.
+func (c *Client) Handler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	// use the raw text so any mentions in the announcement are kept
+	text := strings.TrimSpace(m.RawText())
+	if !strings.HasPrefix(strings.ToLower(text), Trigger) {
+		return r.RespondTo(ctx, Usage)
+	}
+
+	text = strings.TrimSpace(text[len(Trigger):])
+
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return r.RespondTo(ctx, Usage)
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "update", "resolve":
+		if len(fields) < 2 {
+			return r.RespondTo(ctx, Usage)
+		}
+
+		id := fields[1]
+		rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(text[len(fields[0]):]), id))
+		resolve := strings.EqualFold(fields[0], "resolve")
+
+		if !resolve && len(rest) == 0 {
+			return r.RespondTo(ctx, Usage)
+		}
+
+		return c.update(ctx, r, id, rest, resolve)
+
+	default:
+		return c.announce(ctx, r, text)
+	}
+}
+
+func key(id string) string { return redisPrefix + id }
+
+func format(id, text string, updated, resolved bool) string {
+	switch {
+	case resolved:
+		return fmt.Sprintf(":white_check_mark: *Resolved* (announcement %s)\n%s", id, text)
+	case updated:
+		return fmt.Sprintf(":rotating_light: *Workspace announcement* (%s, updated)\n%s", id, text)
+	default:
+		return fmt.Sprintf(":rotating_light: *Workspace announcement* (%s)\n%s", id, text)
+	}
+}
+
+func (c *Client) announce(ctx workqueue.Context, r handler.Responder, text string) error {
+	seq, err := c.rc.Incr(ctx, redisSeqKey).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get announcement ID: %w", err)
+	}
+
+	id := strconv.FormatInt(seq, 10)
+	msg := format(id, text, false, false)
+
+	posted := make(map[string]interface{}, len(c.targets)+1)
+
+	for _, name := range c.targets {
+		ch, notFound, err := ctx.ChannelSvc().Lookup(ctx, name)
+		if err == nil && notFound {
+			err = errors.New("channel not found in cache")
+		}
+
+		if err == nil {
+			var ts string
+
+			_, ts, err = ctx.Slack().PostMessageContext(ctx, ch.ID, slack.MsgOptionText(msg, false))
+			if err == nil {
+				posted[ch.ID] = ts
+				continue
+			}
+		}
+
+		// all channels get the announcement, or none do
+		c.rollback(ctx, posted)
+
+		_ = r.RespondTo(ctx, fmt.Sprintf("Failed to post the announcement to #%s, so it was retracted everywhere: %s", name, err))
+
+		return fmt.Errorf("failed to post announcement to %s: %w", name, err)
+	}
+
+	posted["_text"] = text
+
+	pipe := c.rc.TxPipeline()
+	pipe.HMSet(ctx, key(id), posted)
+	pipe.Expire(ctx, key(id), announcementTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to track announcement %s: %w", id, err)
+	}
+
+	c.logger.Info().
+		Str("announcement_id", id).
+		Int("channels", len(c.targets)).
+		Msg("announcement posted")
+
+	return r.RespondTo(ctx, fmt.Sprintf("Announcement %s posted to %d channels. Use `!announce update %s <message>` or `!announce resolve %s` to change it.", id, len(c.targets), id, id))
+}
+
+func (c *Client) rollback(ctx workqueue.Context, posted map[string]interface{}) {
+	for channelID, ts := range posted {
+		if _, _, err := ctx.Slack().DeleteMessageContext(ctx, channelID, ts.(string)); err != nil {
+			c.logger.Error().
+				Err(err).
+				Str("channel_id", channelID).
+				Msg("failed to retract partial announcement")
+		}
+	}
+}
+
+func (c *Client) update(ctx workqueue.Context, r handler.Responder, id, text string, resolve bool) error {
+	copies, err := c.rc.HGetAll(ctx, key(id)).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get announcement %s: %w", id, err)
+	}
+
+	if len(copies) == 0 {
+		return r.RespondTo(ctx, fmt.Sprintf("I don't know about announcement %s, it may have expired.", id))
+	}
+
+	if len(text) == 0 {
+		text = copies["_text"]
+	}
+
+	msg := format(id, text, !resolve, resolve)
+
+	var failed int
+
+	for channelID, ts := range copies {
+		if channelID == "_text" {
+			continue
+		}
+
+		if _, _, _, err := ctx.Slack().UpdateMessageContext(ctx, channelID, ts, slack.MsgOptionText(msg, false)); err != nil {
+			failed++
+
+			c.logger.Error().
+				Err(err).
+				Str("announcement_id", id).
+				Str("channel_id", channelID).
+				Msg("failed to update announcement")
+		}
+	}
+
+	if err := c.rc.HSet(ctx, key(id), "_text", text).Err(); err != nil {
+		return fmt.Errorf("failed to track announcement %s: %w", id, err)
+	}
+
+	verb := "updated"
+	if resolve {
+		verb = "resolved"
+	}
+
+	if failed > 0 {
+		return r.RespondTo(ctx, fmt.Sprintf("Announcement %s %s, but %d copies failed to update.", id, verb, failed))
+	}
+
+	return r.RespondTo(ctx, fmt.Sprintf("Announcement %s %s.", id, verb))
+}
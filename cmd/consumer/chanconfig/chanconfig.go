@@ -0,0 +1,128 @@
+// Package chanconfig provides a Client struct with MessageMatchFn and Handler
+// methods, which let admins view and change the settings of the channel the
+// command is sent in.
+package chanconfig
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/permissions"
+	"github.com/gobridge/gopherbot/settings"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+)
+
+// Trigger is the command prefix.
+const Trigger = "!config"
+
+// Usage is the help text for the command.
+const Usage = "Usage: `!config get [setting]` or `!config set <setting> <value>`"
+
+// Client is the channel settings client.
+type Client struct {
+	s      *settings.Store
+	logger zerolog.Logger
+}
+
+// New returns a channel settings Client.
+func New(s *settings.Store, logger zerolog.Logger) *Client {
+	return &Client{
+		s:      s,
+		logger: logger,
+	}
+}
+
+// MessageMatchFn satisfies handler.MessageMatchFn.
+func (c *Client) MessageMatchFn(_ bool, m handler.Messenger) bool {
+	text := strings.ToLower(m.Text())
+	return text == Trigger || strings.HasPrefix(text, Trigger+" ")
+}
+
+// Handler satisfies handler.MessageActionFn.
+func (c *Client) Handler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	ok, err := ctx.UserHasRole(m.UserID(), permissions.Admin)
+	if err != nil {
+		return fmt.Errorf("failed to check user %s role: %w", m.UserID(), err)
+	}
+
+	if !ok {
+		return r.RespondTo(ctx, "Sorry, only admins can change channel settings.")
+	}
+
+	fields := strings.Fields(m.Text())[1:]
+	if len(fields) == 0 {
+		return r.RespondTo(ctx, Usage)
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "get":
+		if len(fields) > 2 {
+			return r.RespondTo(ctx, Usage)
+		}
+
+		return c.get(ctx, m, r, fields[1:])
+
+	case "set":
+		if len(fields) != 3 {
+			return r.RespondTo(ctx, Usage)
+		}
+
+		return c.set(ctx, m, r, strings.ToLower(fields[1]), fields[2])
+
+	default:
+		return r.RespondTo(ctx, Usage)
+	}
+}
+
+func (c *Client) get(ctx workqueue.Context, m handler.Messenger, r handler.Responder, keys []string) error {
+	cs, err := c.s.Channel(ctx, m.ChannelID())
+	if err != nil {
+		return err
+	}
+
+	if len(keys) == 0 {
+		keys = settings.Keys()
+	}
+
+	var b strings.Builder
+
+	for _, k := range keys {
+		k = strings.ToLower(k)
+
+		v, err := cs.Get(k)
+		if err != nil {
+			return r.RespondTo(ctx, fmt.Sprintf("I don't know the %q setting. The settings are: %s", k, strings.Join(settings.Keys(), ", ")))
+		}
+
+		desc, _ := settings.Describe(k)
+
+		fmt.Fprintf(&b, "• `%s`: `%s` - %s\n", k, v, desc)
+	}
+
+	return r.RespondTo(ctx, "The settings for this channel are:\n"+b.String())
+}
+
+func (c *Client) set(ctx workqueue.Context, m handler.Messenger, r handler.Responder, key, value string) error {
+	cs, err := c.s.Set(ctx, m.ChannelID(), key, value)
+	if err != nil {
+		if errors.Is(err, settings.ErrUnknownSetting) || errors.Is(err, settings.ErrInvalidValue) {
+			return r.RespondTo(ctx, fmt.Sprintf("I couldn't change that setting: %s", err))
+		}
+
+		return err
+	}
+
+	v, _ := cs.Get(key)
+
+	c.logger.Info().
+		Str("channel_id", m.ChannelID()).
+		Str("user_id", m.UserID()).
+		Str("setting", key).
+		Str("value", v).
+		Msg("channel setting changed")
+
+	return r.RespondTo(ctx, fmt.Sprintf("`%s` is now `%s` for this channel.", key, v))
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gobridge/gopherbot/cache"
+	"github.com/gobridge/gopherbot/workqueue"
+)
+
+// channelCacheUpdater returns the handler that keeps the channel cache up to
+// date as channels change, instead of waiting for bgtasks to refill it. Each
+// kind of change has its own stream, so an archive can be handled after the
+// unarchive that followed it. Refreshing looks up whether the channel is
+// archived now, rather than trusting the event, so the order doesn't matter.
+func channelCacheUpdater(filler *cache.ChannelFiller) workqueue.ChannelChangeHandler {
+	return func(ctx workqueue.Context, cc *workqueue.ChannelChangeEvent) (bool, bool, error) {
+		if err := filler.Refresh(ctx, cc.ChannelID); err != nil {
+			return true, false, fmt.Errorf("failed to update channel %s in cache: %w", cc.ChannelID, err)
+		}
+
+		return false, false, nil
+	}
+}
+
+// userGroupCacheUpdater returns the handler that keeps the user group cache up
+// to date as groups change, using the group included in the event.
+func userGroupCacheUpdater(filler *cache.UserGroupFiller) workqueue.SubteamHandler {
+	return func(ctx workqueue.Context, se *workqueue.SubteamEvent) (bool, bool, error) {
+		if err := filler.Put(ctx, se.Subteam); err != nil {
+			return true, false, fmt.Errorf("failed to update user group %s in cache: %w", se.Subteam.ID, err)
+		}
+
+		return false, false, nil
+	}
+}
@@ -7,32 +7,68 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"reflect"
 	"runtime"
 	"syscall"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/gobridge/gopherbot/analytics"
+	"github.com/gobridge/gopherbot/archive"
 	"github.com/gobridge/gopherbot/cache"
+	"github.com/gobridge/gopherbot/cmd/consumer/announce"
+	"github.com/gobridge/gopherbot/cmd/consumer/chanconfig"
+	"github.com/gobridge/gopherbot/cmd/consumer/export"
+	"github.com/gobridge/gopherbot/cmd/consumer/factoid"
+	"github.com/gobridge/gopherbot/cmd/consumer/filescan"
+	"github.com/gobridge/gopherbot/cmd/consumer/karma"
+	"github.com/gobridge/gopherbot/cmd/consumer/linkscan"
+	"github.com/gobridge/gopherbot/cmd/consumer/moderation"
 	"github.com/gobridge/gopherbot/cmd/consumer/playground"
+	"github.com/gobridge/gopherbot/cmd/consumer/remind"
+	"github.com/gobridge/gopherbot/cmd/consumer/search"
+	"github.com/gobridge/gopherbot/cmd/consumer/spam"
 	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/factoids"
 	"github.com/gobridge/gopherbot/glossary"
 	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/installs"
+	"github.com/gobridge/gopherbot/internal/errreport"
 	"github.com/gobridge/gopherbot/internal/heartbeat"
+	"github.com/gobridge/gopherbot/internal/keyring"
+	"github.com/gobridge/gopherbot/internal/postqueue"
+	"github.com/gobridge/gopherbot/internal/redisutil"
+	"github.com/gobridge/gopherbot/internal/shutdown"
+	"github.com/gobridge/gopherbot/internal/slacklimit"
+	"github.com/gobridge/gopherbot/onboarding"
+	"github.com/gobridge/gopherbot/permissions"
+	"github.com/gobridge/gopherbot/reminders"
+	"github.com/gobridge/gopherbot/settings"
 	"github.com/gobridge/gopherbot/workqueue"
 	"github.com/rs/zerolog"
 	"github.com/slack-go/slack"
 )
 
+// adminChannelID is the admins' private channel, which the admin commands are
+// used in and alerts are sent to
+const adminChannelID = "G1L7RN06B"
+
 // playgroundChannelBlacklist sets a list of channels the playground uploader will
 // not operate in
 var playgroundChannelBlacklist = []string{
 	"C4U9J9QBT", // #admin-help
 	"C029RQSEG", // #random
-	"G1L7RN06B", // admin private channel
+	adminChannelID,
 	"G207C8R1R", // gobridge ops chanel
 	"GB1KBRGKA", // modnar (private random channel)
 }
 
+// announcementChannels are the channels, by name, that !announce posts to
+var announcementChannels = []string{
+	"general",
+	"newbies",
+	"admin-help",
+}
+
 func getSelf(c *slack.Client) (*slack.User, error) {
 	// full lifetime of this function
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -59,15 +95,24 @@ func runServer(cfg config.C, logger zerolog.Logger) error {
 
 	logger.Info().
 		Str("env", string(cfg.Env)).
-		Str("app", cfg.Heroku.AppName).
-		Str("dyno_id", cfg.Heroku.DynoID).
 		Str("commit", cfg.Heroku.Commit).
 		Str("slack_request_token", cfg.Slack.RequestToken).
 		Str("slack_client_id", cfg.Slack.ClientID).
 		Str("log_level", cfg.LogLevel.String()).
+		Bool("dry_run", cfg.DryRun).
+		Strs("dry_run_plugins", cfg.DryRunPlugins).
 		Msg("configuration values")
 
-	sc := slack.New(cfg.Slack.BotAccessToken, slack.OptionHTTPClient(newHTTPClient()))
+	dl := logger.With().Str("context", "dry_run").Logger()
+	sl := logger.With().Str("context", "slack_rate_limit").Logger()
+
+	httpc := newSlackHTTPClient(sl)
+	if cfg.DryRun {
+		httpc = newDryRunHTTPClient(dl)
+	}
+
+	sc := slack.New(cfg.Slack.BotAccessToken, slack.OptionHTTPClient(httpc))
+	dr := newDryRunner(cfg.DryRun, cfg.DryRunPlugins, slack.New(cfg.Slack.BotAccessToken, slack.OptionHTTPClient(newDryRunHTTPClient(dl))))
 
 	// test credentails and get self reference
 	self, err := getSelf(sc)
@@ -75,7 +120,25 @@ func runServer(cfg config.C, logger zerolog.Logger) error {
 		return err
 	}
 
-	rc := redis.NewClient(config.DefaultRedis(cfg))
+	var er workqueue.ErrorReporter
+
+	if len(cfg.SentryDSN) > 0 {
+		sr, err := errreport.NewSentry(errreport.Config{
+			DSN:         cfg.SentryDSN,
+			Environment: string(cfg.Env),
+			Release:     cfg.Heroku.Commit,
+			ServerName:  cfg.Heroku.DynoID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set up error reporting: %w", err)
+		}
+
+		defer sr.Flush(2 * time.Second)
+
+		er = sr
+	}
+
+	rc := redisutil.ConnectRedis(cfg.Redis)
 	defer func() { _ = rc.Close() }()
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -107,6 +170,53 @@ func runServer(cfg config.C, logger zerolog.Logger) error {
 
 	cCache := cache.NewChannel(rc)
 
+	cFiller, err := cache.NewChannelFiller(ctx, sc, rc, logger.With().Str("context", "channel_cache").Logger())
+	if err != nil {
+		return fmt.Errorf("failed to build channel cache filler: %w", err)
+	}
+
+	ugFiller := cache.NewUserGroupFiller(sc, rc, logger.With().Str("context", "usergroup_cache").Logger())
+	perms := permissions.New(rc, sc, cfg.Admins)
+	chanSettings := settings.New(rc)
+
+	// reload the settings that can change on SIGHUP, or when the config file
+	// changes
+	cw := config.NewWatcher(cfg, logger.With().Str("context", "config_watcher").Logger())
+	cw.Subscribe(config.LogLevelSubscriber(logger))
+	cw.Subscribe(dr.subscriber(dl))
+	cw.Subscribe(adminsSubscriber(perms, logger))
+	cw.Run(ctx)
+
+	// events from the other workspaces the app is installed to use their own
+	// bot token, which needs the key it's encrypted with, as do reminders and
+	// encrypted event payloads
+	var (
+		kr          *keyring.Keyring
+		payloadKeys workqueue.Keyring
+		workspaces  workqueue.WorkspaceSvc
+	)
+
+	if len(cfg.TokenEncryptionKey) > 0 {
+		if kr, err = keyring.Parse(cfg.TokenEncryptionKey, cfg.TokenEncryptionOldKeys...); err != nil {
+			return fmt.Errorf("failed to parse token encryption keys: %w", err)
+		}
+
+		payloadKeys = kr
+
+		pl := logger.With().Str("context", "postqueue").Logger()
+
+		workspaces = installs.NewResolver(installs.NewStore(rc, kr), func(sc workqueue.SlackClient) workqueue.SlackClient {
+			return postqueue.New(sc, pl, postqueue.DefaultInterval)
+		}, slack.OptionHTTPClient(httpc))
+	}
+
+	// events from Discord, if there's a bot for it, are handled with its own
+	// client
+	pfs, err := platforms(cfg, logger.With().Str("context", "discord").Logger())
+	if err != nil {
+		return err
+	}
+
 	// set up the workqueue
 	q, err := workqueue.New(workqueue.Config{
 		ConsumerName:      cfg.Heroku.DynoID,
@@ -114,9 +224,19 @@ func runServer(cfg config.C, logger zerolog.Logger) error {
 		VisibilityTimeout: 10 * time.Second,
 		RedisClient:       rc,
 		Logger:            &logger,
-		SlackClient:       sc,
+		HandlerLogger:     config.HandlerLogger,
+		ErrorReporter:     er,
+		SlackClient:       postqueue.New(sc, logger.With().Str("context", "postqueue").Logger(), postqueue.DefaultInterval),
 		SlackUser:         self,
+		Workspaces:        workspaces,
+		Platforms:         pfs,
 		ChannelCache:      cCache,
+		EmojiCache:        cache.NewEmoji(rc),
+		UserGroupCache:    cache.NewUserGroup(rc),
+		Permissions:       perms,
+		Settings:          chanSettings,
+		Keyring:           payloadKeys,
+		EncryptPayloads:   cfg.WorkqueueEncryption,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to build workqueue: %w", err)
@@ -160,40 +280,194 @@ func runServer(cfg config.C, logger zerolog.Logger) error {
 	// set up the Go Playground uploader
 	lp := logger.With().Str("context", "playground")
 	pg := playground.New(newHTTPClient(), lp.Logger(), playgroundChannelBlacklist)
-	ma.HandleDynamic(pg.MessageMatchFn, pg.Handler)
+	ma.HandleDynamic(pg.MessageMatchFn, dr.action("playground", pg.Handler))
+
+	// set up the malicious link scanner
+	ll := logger.With().Str("context", "linkscan").Logger()
+	ls := linkscan.New(ll, shadowMode, linkScanEscalate, linkScanners(newHTTPClient(), cfg.SafeBrowsingAPIKey)...)
+	ma.HandleDynamic(ls.MessageMatchFn, dr.action("linkscan", ls.Handler))
+
+	// set up the moderators' conversation export command
+	el := logger.With().Str("context", "export").Logger()
+	ex := export.New(el, adminChannelID)
+	ma.HandleDynamic(ex.MessageMatchFn, dr.action("export", ex.Handler))
+
+	// set up the admins' workspace announcement command
+	al := logger.With().Str("context", "announce").Logger()
+	an := announce.New(rc, al, adminChannelID, announcementChannels)
+	ma.HandleDynamic(an.MessageMatchFn, dr.action("announce", an.Handler))
+
+	// set up the admins' channel settings command
+	crl := logger.With().Str("context", "chanconfig").Logger()
+	cc := chanconfig.New(chanSettings, crl)
+	ma.HandleDynamic(cc.MessageMatchFn, dr.action("chanconfig", cc.Handler))
+
+	// set up the reminders command
+	rems := reminders.New(rc, q, kr)
+	rl := logger.With().Str("context", "remind").Logger()
+	rm := remind.New(rems, rl)
+	ma.HandleDynamic(rm.MessageMatchFn, dr.action("remind", rm.Handler))
+
+	// set up karma votes, and the command for seeing the scores
+	kl := logger.With().Str("context", "karma").Logger()
+	ka := karma.New(rc, kl)
+	ma.HandleDynamic(ka.VoteMatchFn, dr.action("karma", ka.VoteHandler))
+	ma.HandleDynamic(ka.MessageMatchFn, dr.action("karma", ka.Handler))
+
+	// set up the factoid commands
+	fal := logger.With().Str("context", "factoid").Logger()
+	fa := factoid.New(factoids.New(rc), fal)
+	ma.HandleDynamic(fa.MessageMatchFn, dr.action("factoid", fa.Handler))
+
+	// set up the search command, if messages are being archived
+	if len(cfg.DatabaseURL) > 0 {
+		ar, err := archive.Open(cfg.DatabaseURL)
+		if err != nil {
+			return err
+		}
+
+		defer func() { _ = ar.Close() }()
+
+		srl := logger.With().Str("context", "search").Logger()
+		se := search.New(ar, srl)
+		ma.HandleDynamic(se.MessageMatchFn, dr.action("search", se.Handler))
+	}
 
-	injectTeamJoinHandlers(tja)
+	// adminClient returns the Slack client for deleting other users' messages,
+	// which is nil if there is no admin token
+	adminClient := func(plugin string) *slack.Client {
+		if len(cfg.Slack.AdminAccessToken) == 0 {
+			return nil
+		}
+
+		ahc := &http.Client{
+			Transport: dr.transport(plugin, newSlackHTTPClient(sl).Transport, newDryRunHTTPClient(dl).Transport),
+		}
+
+		return slack.New(cfg.Slack.AdminAccessToken, slack.OptionHTTPClient(ahc))
+	}
+
+	// set up the banned word and link filters
+	mpol, mdef, err := moderationPolicies()
+	if err != nil {
+		return err
+	}
+
+	mol := logger.With().Str("context", "moderation").Logger()
+	mo := moderation.New(mol, shadowMode, adminClient("moderation"), moderationNotifyChannelID, mpol, mdef)
+	ma.HandleDynamic(mo.MessageMatchFn, dr.action("moderation", mo.Handler))
+
+	// set up the spam detector, which publishes flagged members as moderation
+	// events
+	spl := logger.With().Str("context", "spam").Logger()
+	sp := spam.New(rc, q, spl, spam.DefaultConfig)
+	ma.HandleDynamic(sp.MessageMatchFn, dr.action("spam", sp.Handler))
+
+	// set up the shared file scanner
+	fpol, fdef := fileScanPolicies(newHTTPClient(), cfg.FileScanAVURL)
+	fl := logger.With().Str("context", "filescan").Logger()
+	fsc := filescan.New(fl, shadowMode, adminClient("filescan"), fileScanAlert, fpol, fdef)
+
+	stats := analytics.New(rc)
+
+	ob, err := onboarding.New(rc, q, onboardingSteps()...)
+	if err != nil {
+		return fmt.Errorf("failed to build onboarding pipeline: %w", err)
+	}
+
+	injectTeamJoinHandlers(tja, ob)
 	injectChannelJoinHandlers(cja)
 
-	q.RegisterTeamJoinsHandler(2*time.Second, tja.Handler)
-	q.RegisterChannelJoinsHandler(10*time.Second, cja.Handler)
-	q.RegisterPublicMessagesHandler(10*time.Second, ma.Handler)
-	q.RegisterPrivateMessagesHandler(10*time.Second, ma.Handler)
+	q.RegisterTeamJoinsHandler(workqueue.HandlerOpts{Timeout: 2 * time.Second}, tja.Handler)
+	q.RegisterChannelJoinsHandler(workqueue.HandlerOpts{Timeout: 10 * time.Second}, cja.Handler)
+	q.RegisterPublicMessagesHandler(workqueue.HandlerOpts{Timeout: 10 * time.Second, Concurrency: 8, Prefetch: 16, IgnoreSelf: true}, ma.Handler)
+	q.RegisterPublicMessagesHandler(workqueue.HandlerOpts{Timeout: 2 * time.Second}, stats.Record)
+	q.RegisterPrivateMessagesHandler(workqueue.HandlerOpts{Timeout: 10 * time.Second, Concurrency: 4, Prefetch: 4, IgnoreSelf: true}, ma.Handler)
+	q.RegisterFileSharedHandler(workqueue.HandlerOpts{Timeout: 30 * time.Second}, dr.fileShared("filescan", fsc.Handler))
+	q.RegisterChannelChangesHandler(workqueue.HandlerOpts{Timeout: 10 * time.Second}, channelCacheUpdater(cFiller))
+	q.RegisterSubteamHandler(workqueue.HandlerOpts{Timeout: 10 * time.Second}, userGroupCacheUpdater(ugFiller))
+	q.RegisterScheduledJobHandler(workqueue.HandlerOpts{Timeout: 30 * time.Second}, scheduledJobs(map[string]scheduledJobFn{
+		reminders.JobName:       rems.Deliver,
+		onboarding.JobName:      ob.Deliver,
+		analytics.DigestJobName: stats.Digest,
+	}))
+	q.RegisterModerationHandler(workqueue.HandlerOpts{Timeout: 10 * time.Second}, mo.EventHandler)
+
+	stopping, drained := make(chan struct{}), make(chan struct{})
 
 	// signal handling / graceful shutdown goroutine
 	go func() {
+		defer close(drained)
+
 		sig := <-signalCh
+		close(stopping)
 
 		logger.Info().
 			Str("signal", sig.String()).
 			Msg("shutting down consumer gracefully")
 
-		q.Shutdown()
+		// there's no HTTP intake here, so the handlers are drained first, and
+		// then what they use is stopped
+		_ = shutdown.New(logger.With().Str("context", "shutdown").Logger()).
+			Then("drain workqueue", func(ctx context.Context) error {
+				returned, err := q.DrainAndShutdown(ctx)
+
+				logger.Info().
+					Int64("returned", returned).
+					Msg("drained consumer")
+
+				return err
+			}).
+			Then("wait for exports", ex.Wait).
+			Then("stop background tasks", func(context.Context) error {
+				cancel()
+				return nil
+			}).
+			Run(cfg.ShutdownTimeout)
 	}()
 
 	logger.Info().Msg("waiting for events")
 
 	q.Run()
 
+	// wait for the drain to be reported, if that's why Run returned
+	select {
+	case <-stopping:
+		<-drained
+	default:
+	}
+
 	return nil
 }
 
+// adminsSubscriber returns a config.Watcher subscriber that applies changes to
+// the users that are always admins.
+func adminsSubscriber(perms *permissions.Store, logger zerolog.Logger) func(old, cur config.C) {
+	return func(old, cur config.C) {
+		if reflect.DeepEqual(old.Admins, cur.Admins) {
+			return
+		}
+
+		perms.SetAdmins(cur.Admins)
+
+		logger.Info().
+			Strs("admins", cur.Admins).
+			Msg("admins changed")
+	}
+}
+
 func newHTTPClient() *http.Client {
 	return &http.Client{
 		Transport: newHTTPTransport(),
 	}
 }
 
+// newSlackHTTPClient returns an *http.Client for the Slack client, that
+// handles Slack's rate limits so handlers don't fail during spikes.
+func newSlackHTTPClient(logger zerolog.Logger) *http.Client {
+	return slacklimit.New(newHTTPTransport(), logger, nil).Client()
+}
+
 // newHTTPTransport returns an *http.Transport with some reasonable defaults.
 func newHTTPTransport() *http.Transport {
 	return &http.Transport{
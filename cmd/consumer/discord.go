@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gobridge/gopherbot/chat/slackchat"
+	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/internal/discord"
+	"github.com/gobridge/gopherbot/internal/postqueue"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+)
+
+// platforms returns the clients for the chat platforms other than Slack that
+// are configured, which the workqueue handles their events with.
+func platforms(cfg config.C, logger zerolog.Logger) (map[string]workqueue.Workspace, error) {
+	if len(cfg.DiscordBotToken) == 0 {
+		return nil, nil
+	}
+
+	// dry-run mode only knows the Slack API, so Discord would be posted to
+	// for real
+	if cfg.DryRun {
+		logger.Warn().Msg("not handling discord events in dry-run mode")
+		return nil, nil
+	}
+
+	dc := discord.NewClient(cfg.DiscordBotToken, newHTTPClient())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	self, err := dc.Self(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("discord authentication test failed: %w", err)
+	}
+
+	// so chat handlers know not to reply with what Discord can't do
+	slackchat.Register(discord.Platform, discord.Capabilities)
+
+	return map[string]workqueue.Workspace{
+		discord.Platform: {
+			SlackClient: postqueue.New(dc, logger.With().Str("context", "discord_postqueue").Logger(), postqueue.DefaultInterval),
+			SlackUser:   self,
+		},
+	}, nil
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"net/http"
+	"reflect"
+	"sync"
+
+	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/internal/dryrun"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+func newDryRunHTTPClient(logger zerolog.Logger) *http.Client {
+	return dryrun.New(newHTTPTransport(), logger).Client()
+}
+
+// dryRunner decides which plugins run in dry-run mode, and wraps their
+// handlers so they use a Slack client that only logs what it would do. Which
+// plugins run in dry-run mode is decided per event, so it can change when the
+// config is reloaded.
+type dryRunner struct {
+	global bool
+	sc     *slack.Client
+
+	// mu protects plugins
+	mu      sync.RWMutex
+	plugins map[string]struct{}
+}
+
+func toSet(l []string) map[string]struct{} {
+	m := make(map[string]struct{}, len(l))
+
+	for _, v := range l {
+		m[v] = struct{}{}
+	}
+
+	return m
+}
+
+func newDryRunner(global bool, plugins []string, sc *slack.Client) *dryRunner {
+	return &dryRunner{
+		global:  global,
+		plugins: toSet(plugins),
+		sc:      sc,
+	}
+}
+
+// enabled returns whether plugin runs in dry-run mode.
+func (d *dryRunner) enabled(plugin string) bool {
+	if d.global {
+		return true
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	_, ok := d.plugins[plugin]
+
+	return ok
+}
+
+// subscriber returns a config.Watcher subscriber that applies changes to the
+// plugins that run in dry-run mode. Global dry-run mode picks the workqueue's
+// Slack client, so it can't change without a restart.
+func (d *dryRunner) subscriber(logger zerolog.Logger) func(old, cur config.C) {
+	return func(old, cur config.C) {
+		if reflect.DeepEqual(old.DryRunPlugins, cur.DryRunPlugins) {
+			return
+		}
+
+		plugins := toSet(cur.DryRunPlugins)
+
+		d.mu.Lock()
+		d.plugins = plugins
+		d.mu.Unlock()
+
+		logger.Info().
+			Strs("dry_run_plugins", cur.DryRunPlugins).
+			Msg("dry-run plugins changed")
+	}
+}
+
+// action wraps fn so it runs in dry-run mode while plugin should. In global
+// dry-run mode the workqueue's Slack client is already a dry-run one, so fn is
+// returned as-is.
+func (d *dryRunner) action(plugin string, fn handler.MessageActionFn) handler.MessageActionFn {
+	if d.global {
+		return fn
+	}
+
+	dry := handler.WithSlackClient(d.sc, fn)
+
+	return func(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+		if d.enabled(plugin) {
+			return dry(ctx, m, r)
+		}
+
+		return fn(ctx, m, r)
+	}
+}
+
+// fileShared is the same as action, but for workqueue.FileSharedHandler.
+func (d *dryRunner) fileShared(plugin string, fn workqueue.FileSharedHandler) workqueue.FileSharedHandler {
+	if d.global {
+		return fn
+	}
+
+	return func(ctx workqueue.Context, fs *workqueue.FileSharedEvent) (bool, bool, error) {
+		if d.enabled(plugin) {
+			ctx = workqueue.WithSlack(ctx, d.sc)
+		}
+
+		return fn(ctx, fs)
+	}
+}
+
+// transport returns the http.RoundTripper for a Slack client plugin was built
+// with, which sends each request with dry while plugin runs in dry-run mode,
+// and with real otherwise.
+func (d *dryRunner) transport(plugin string, real, dry http.RoundTripper) http.RoundTripper {
+	return dryRunTransport{d: d, plugin: plugin, real: real, dry: dry}
+}
+
+type dryRunTransport struct {
+	d         *dryRunner
+	plugin    string
+	real, dry http.RoundTripper
+}
+
+// RoundTrip satisfies http.RoundTripper.
+func (t dryRunTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.d.enabled(t.plugin) {
+		return t.dry.RoundTrip(req)
+	}
+
+	return t.real.RoundTrip(req)
+}
@@ -0,0 +1,457 @@
+// Package export provides a Client struct with MessageMatchFn and Handler
+// methods, which together let moderators export the history of a channel or
+// thread to a file delivered to them by DM.
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/mparser"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+// Trigger is the command prefix, after the bot is mentioned.
+const Trigger = "export"
+
+// Usage is the help text for the command.
+const Usage = "Usage: `@gopher export #channel <from YYYY-MM-DD> <to YYYY-MM-DD> [thread-ts] [json|csv]`"
+
+const (
+	// pageSize is the number of messages requested per API call
+	pageSize = 200
+
+	// pageDelay is the delay between API calls, to stay well under the Tier 3
+	// rate limit of conversations.history and conversations.replies
+	pageDelay = 1200 * time.Millisecond
+
+	// maxMessages is the cap on messages in a single export
+	maxMessages = 50000
+
+	// exportTimeout is how long an export may run for in total
+	exportTimeout = 15 * time.Minute
+
+	dateFormat = "2006-01-02"
+)
+
+// Format is the output format of an export.
+type Format string
+
+const (
+	// FormatJSON is a JSON array of messages.
+	FormatJSON Format = "json"
+
+	// FormatCSV is a CSV file with a header row.
+	FormatCSV Format = "csv"
+)
+
+// Request is a parsed export command.
+type Request struct {
+	ChannelID string
+	ThreadTS  string
+	From      time.Time
+	To        time.Time
+	Format    Format
+}
+
+// ParseRequest parses the text of an export command, with the mentions in the
+// message already spliced out of text. The from and to dates are inclusive and
+// in UTC.
+func ParseRequest(text string, mentions []mparser.Mention) (Request, error) {
+	var r Request
+
+	for _, m := range mentions {
+		if m.Type == mparser.TypeChannelRef {
+			r.ChannelID = m.ID
+			break
+		}
+	}
+
+	if len(r.ChannelID) == 0 {
+		return Request{}, errors.New("no channel given")
+	}
+
+	fields := strings.Fields(strings.TrimSpace(strings.TrimPrefix(strings.ToLower(text), Trigger)))
+
+	var dates []time.Time
+
+	for _, f := range fields {
+		switch {
+		case f == string(FormatJSON) || f == string(FormatCSV):
+			r.Format = Format(f)
+
+		case strings.Count(f, "-") == 2:
+			t, err := time.Parse(dateFormat, f)
+			if err != nil {
+				return Request{}, fmt.Errorf("invalid date %q", f)
+			}
+
+			dates = append(dates, t)
+
+		case strings.Count(f, ".") == 1:
+			if _, err := strconv.ParseFloat(f, 64); err != nil {
+				return Request{}, fmt.Errorf("invalid thread timestamp %q", f)
+			}
+
+			r.ThreadTS = f
+
+		default:
+			return Request{}, fmt.Errorf("unknown argument %q", f)
+		}
+	}
+
+	if len(dates) != 2 {
+		return Request{}, errors.New("expected a from and to date")
+	}
+
+	r.From, r.To = dates[0], dates[1].Add(24*time.Hour-time.Nanosecond)
+
+	if r.To.Before(r.From) {
+		return Request{}, errors.New("the from date must be before the to date")
+	}
+
+	if len(r.Format) == 0 {
+		r.Format = FormatJSON
+	}
+
+	return r, nil
+}
+
+// Client is the conversation export client.
+type Client struct {
+	logger    zerolog.Logger
+	channelID string
+
+	// running counts the exports in progress, which shutdown waits for
+	running sync.WaitGroup
+}
+
+// New returns a conversation export Client. The command is only accepted in the
+// channel with ID channelID, which should be one only moderators can access.
+func New(logger zerolog.Logger, channelID string) *Client {
+	return &Client{
+		logger:    logger,
+		channelID: channelID,
+	}
+}
+
+// MessageMatchFn satisfies handler.MessageMatchFn.
+func (c *Client) MessageMatchFn(_ bool, m handler.Messenger) bool {
+	if m.ChannelID() != c.channelID || !m.BotMentioned() {
+		return false
+	}
+
+	return strings.HasPrefix(strings.ToLower(m.Text()), Trigger+" ")
+}
+
+// Handler satisfies handler.MessageActionFn. Exports can take a while, longer
+// than the workqueue would let a handler run for, so the export itself happens
+// in the background and the requester gets a DM once it's done.
+func (c *Client) Handler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	req, err := ParseRequest(m.Text(), m.AllMentions())
+	if err != nil {
+		return r.RespondTo(ctx, fmt.Sprintf("I couldn't understand that: %s.\n%s", err, Usage))
+	}
+
+	if err := r.RespondTo(ctx, "Starting the export, I'll DM you the file when it's ready."); err != nil {
+		return err
+	}
+
+	c.running.Add(1)
+
+	go func() {
+		defer c.running.Done()
+		c.run(ctx.Slack(), m.UserID(), req)
+	}()
+
+	return nil
+}
+
+// Wait waits for the exports in progress to be delivered, or for ctx to be
+// done. The workqueue should be drained first, so no more are started.
+func (c *Client) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+
+	go func() {
+		c.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to wait for exports in progress: %w", ctx.Err())
+	}
+}
+
+func (c *Client) run(sc workqueue.SlackClient, userID string, req Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	logger := c.logger.With().
+		Str("user_id", userID).
+		Str("channel_id", req.ChannelID).
+		Str("thread_ts", req.ThreadTS).
+		Str("format", string(req.Format)).
+		Logger()
+
+	logger.Info().Msg("conversation export started")
+
+	err := c.export(ctx, sc, userID, req)
+	if err == nil {
+		logger.Info().Msg("conversation export delivered")
+		return
+	}
+
+	logger.Error().
+		Err(err).
+		Msg("conversation export failed")
+
+	if derr := dm(ctx, sc, userID, fmt.Sprintf("Sorry, the export failed: %s", err)); derr != nil {
+		logger.Error().
+			Err(derr).
+			Msg("failed to notify user of failed export")
+	}
+}
+
+func (c *Client) export(ctx context.Context, sc workqueue.SlackClient, userID string, req Request) error {
+	msgs, err := fetch(ctx, sc, req)
+	if err != nil {
+		return err
+	}
+
+	var body []byte
+
+	switch req.Format {
+	case FormatCSV:
+		body, err = encodeCSV(msgs)
+	default:
+		body, err = encodeJSON(msgs)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to encode export: %w", err)
+	}
+
+	ch, _, _, err := sc.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{userID}})
+	if err != nil {
+		return fmt.Errorf("failed to open DM: %w", err)
+	}
+
+	name := fmt.Sprintf("%s_%s_%s", req.ChannelID, req.From.Format(dateFormat), req.To.Format(dateFormat))
+	if len(req.ThreadTS) > 0 {
+		name += "_" + req.ThreadTS
+	}
+
+	name += "." + string(req.Format)
+
+	_, err = sc.UploadFileContext(ctx, slack.FileUploadParameters{
+		Content:        string(body),
+		Filetype:       string(req.Format),
+		Filename:       name,
+		Title:          name,
+		InitialComment: fmt.Sprintf("Here's the export you asked for, with %d messages.", len(msgs)),
+		Channels:       []string{ch.ID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload export: %w", err)
+	}
+
+	return nil
+}
+
+func dm(ctx context.Context, sc workqueue.SlackClient, userID, msg string) error {
+	ch, _, _, err := sc.OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{userID}})
+	if err != nil {
+		return fmt.Errorf("failed to open DM: %w", err)
+	}
+
+	if _, _, _, err := sc.SendMessageContext(ctx, ch.ID, slack.MsgOptionText(msg, false)); err != nil {
+		return fmt.Errorf("failed to SendMessageContext: %w", err)
+	}
+
+	return nil
+}
+
+func slackTS(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10) + ".000000"
+}
+
+// sleep waits for d, returning early with an error if ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// fetch pages through the channel or thread history, backing off when Slack
+// tells us we're being rate limited.
+func fetch(ctx context.Context, sc workqueue.SlackClient, req Request) ([]slack.Message, error) {
+	var (
+		msgs   []slack.Message
+		cursor string
+	)
+
+	oldest, latest := slackTS(req.From), slackTS(req.To)
+
+	for {
+		var (
+			page    []slack.Message
+			hasMore bool
+			next    string
+			err     error
+		)
+
+		if len(req.ThreadTS) > 0 {
+			page, hasMore, next, err = sc.GetConversationRepliesContext(ctx, &slack.GetConversationRepliesParameters{
+				ChannelID: req.ChannelID,
+				Timestamp: req.ThreadTS,
+				Cursor:    cursor,
+				Inclusive: true,
+				Oldest:    oldest,
+				Latest:    latest,
+				Limit:     pageSize,
+			})
+		} else {
+			var resp *slack.GetConversationHistoryResponse
+
+			resp, err = sc.GetConversationHistoryContext(ctx, &slack.GetConversationHistoryParameters{
+				ChannelID: req.ChannelID,
+				Cursor:    cursor,
+				Inclusive: true,
+				Oldest:    oldest,
+				Latest:    latest,
+				Limit:     pageSize,
+			})
+			if err == nil {
+				page, hasMore, next = resp.Messages, resp.HasMore, resp.ResponseMetaData.NextCursor
+			}
+		}
+
+		if err != nil {
+			var rle *slack.RateLimitedError
+			if errors.As(err, &rle) {
+				if err := sleep(ctx, rle.RetryAfter); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			return nil, fmt.Errorf("failed to get conversation history: %w", err)
+		}
+
+		msgs = append(msgs, page...)
+
+		if len(msgs) >= maxMessages {
+			return nil, fmt.Errorf("export exceeds %d messages, try a smaller date range", maxMessages)
+		}
+
+		if !hasMore || len(next) == 0 {
+			break
+		}
+
+		cursor = next
+
+		if err := sleep(ctx, pageDelay); err != nil {
+			return nil, err
+		}
+	}
+
+	// conversations.history returns newest first, but an export reads better
+	// in the order it happened
+	if len(req.ThreadTS) == 0 {
+		for i, j := 0, len(msgs)-1; i < j; i, j = i+1, j-1 {
+			msgs[i], msgs[j] = msgs[j], msgs[i]
+		}
+	}
+
+	return msgs, nil
+}
+
+type record struct {
+	TS         string `json:"ts"`
+	Time       string `json:"time"`
+	User       string `json:"user"`
+	ThreadTS   string `json:"thread_ts,omitempty"`
+	SubType    string `json:"subtype,omitempty"`
+	ReplyCount int    `json:"reply_count,omitempty"`
+	Text       string `json:"text"`
+}
+
+func toRecord(m slack.Message) record {
+	var t string
+
+	if sec, err := strconv.ParseFloat(m.Timestamp, 64); err == nil {
+		t = time.Unix(int64(sec), 0).UTC().Format(time.RFC3339)
+	}
+
+	user := m.User
+	if len(user) == 0 {
+		user = m.BotID
+	}
+
+	return record{
+		TS:         m.Timestamp,
+		Time:       t,
+		User:       user,
+		ThreadTS:   m.ThreadTimestamp,
+		SubType:    m.SubType,
+		ReplyCount: m.ReplyCount,
+		Text:       m.Text,
+	}
+}
+
+func encodeJSON(msgs []slack.Message) ([]byte, error) {
+	records := make([]record, 0, len(msgs))
+
+	for _, m := range msgs {
+		records = append(records, toRecord(m))
+	}
+
+	return json.MarshalIndent(records, "", "  ")
+}
+
+func encodeCSV(msgs []slack.Message) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+
+	if err := w.Write([]string{"ts", "time", "user", "thread_ts", "subtype", "reply_count", "text"}); err != nil {
+		return nil, err
+	}
+
+	for _, m := range msgs {
+		r := toRecord(m)
+
+		row := []string{r.TS, r.Time, r.User, r.ThreadTS, r.SubType, strconv.Itoa(r.ReplyCount), r.Text}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,121 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gobridge/gopherbot/mparser"
+)
+
+func TestParseRequest(t *testing.T) {
+	channel := []mparser.Mention{{Type: mparser.TypeChannelRef, ID: "C123", Label: "general"}}
+
+	from := time.Date(2020, time.June, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2020, time.June, 30, 23, 59, 59, 999999999, time.UTC)
+
+	tests := []struct {
+		name     string
+		text     string
+		mentions []mparser.Mention
+		want     Request
+		wantErr  bool
+	}{
+		{
+			name:     "channel",
+			text:     "export  2020-06-01 2020-06-30",
+			mentions: channel,
+			want:     Request{ChannelID: "C123", From: from, To: to, Format: FormatJSON},
+		},
+		{
+			name:     "thread_csv",
+			text:     "EXPORT  2020-06-01 2020-06-30 1591012800.000100 CSV",
+			mentions: channel,
+			want:     Request{ChannelID: "C123", ThreadTS: "1591012800.000100", From: from, To: to, Format: FormatCSV},
+		},
+		{
+			name: "first_channel",
+			text: "export   2020-06-01 2020-06-30",
+			mentions: []mparser.Mention{
+				{Type: mparser.TypeUser, ID: "U123"},
+				{Type: mparser.TypeChannelRef, ID: "C123"},
+				{Type: mparser.TypeChannelRef, ID: "C456"},
+			},
+			want: Request{ChannelID: "C123", From: from, To: to, Format: FormatJSON},
+		},
+		{
+			name:     "same_day",
+			text:     "export  2020-06-01 2020-06-01",
+			mentions: channel,
+			want:     Request{ChannelID: "C123", From: from, To: from.Add(24*time.Hour - time.Nanosecond), Format: FormatJSON},
+		},
+		{
+			name:    "no_channel",
+			text:    "export 2020-06-01 2020-06-30",
+			wantErr: true,
+		},
+		{
+			name:     "one_date",
+			text:     "export  2020-06-01",
+			mentions: channel,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid_date",
+			text:     "export  2020-13-01 2020-06-30",
+			mentions: channel,
+			wantErr:  true,
+		},
+		{
+			name:     "backwards",
+			text:     "export  2020-06-30 2020-06-01",
+			mentions: channel,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid_thread",
+			text:     "export  2020-06-01 2020-06-30 abc.def",
+			mentions: channel,
+			wantErr:  true,
+		},
+		{
+			name:     "unknown_argument",
+			text:     "export  2020-06-01 2020-06-30 xml",
+			mentions: channel,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseRequest(tt.text, tt.mentions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRequest() error = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("ParseRequest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClient_Wait(t *testing.T) {
+	var c Client
+
+	c.running.Add(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := c.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait() = %v with an export running, want context.DeadlineExceeded", err)
+	}
+
+	c.running.Done()
+
+	if err := c.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait() unexpected error: %v", err)
+	}
+}
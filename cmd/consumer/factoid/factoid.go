@@ -0,0 +1,292 @@
+// Package factoid provides a Client struct with MessageMatchFn and Handler
+// methods for the factoid commands, which let admins teach the bot canned
+// responses that anyone can then ask for.
+package factoid
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gobridge/gopherbot/factoids"
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/permissions"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+// The command prefixes.
+const (
+	TriggerLearn    = "!learn"
+	TriggerForget   = "!forget"
+	TriggerFact     = "!fact"
+	TriggerFactoids = "!factoids"
+)
+
+// Usage is the help text for the commands.
+const Usage = "Usage: `!fact <key>`, or `!factoids stats`. Admins can also use `!learn <key> is <text>`, `!forget <key>`, `!factoids export`, and `!factoids import` with the exported JSON file attached."
+
+const (
+	statsSize = 10
+
+	// maxImportSize is the largest file that's imported, in bytes
+	maxImportSize = 1 << 20
+)
+
+// Client is the factoids client.
+type Client struct {
+	s      *factoids.Store
+	logger zerolog.Logger
+}
+
+// New returns a factoids Client.
+func New(s *factoids.Store, logger zerolog.Logger) *Client {
+	return &Client{
+		s:      s,
+		logger: logger,
+	}
+}
+
+// command returns the trigger the text starts with, and the text after it.
+func command(text string) (trigger, rest string, ok bool) {
+	text = strings.TrimSpace(text)
+
+	i := strings.IndexAny(text, " \n")
+	if i == -1 {
+		i = len(text)
+	}
+
+	trigger = strings.ToLower(text[:i])
+
+	switch trigger {
+	case TriggerLearn, TriggerForget, TriggerFact, TriggerFactoids:
+		return trigger, strings.TrimSpace(text[i:]), true
+	default:
+		return "", "", false
+	}
+}
+
+// MessageMatchFn satisfies handler.MessageMatchFn.
+func (c *Client) MessageMatchFn(_ bool, m handler.Messenger) bool {
+	_, _, ok := command(m.RawText())
+	return ok
+}
+
+// Handler satisfies handler.MessageActionFn.
+func (c *Client) Handler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	// use the raw text so any links or mentions in the factoid are kept
+	trigger, rest, _ := command(m.RawText())
+
+	switch trigger {
+	case TriggerFact:
+		if len(rest) == 0 {
+			return r.RespondTo(ctx, Usage)
+		}
+
+		return c.fact(ctx, r, rest)
+
+	case TriggerLearn:
+		return c.admin(ctx, m, r, func() error { return c.learn(ctx, m, r, rest) })
+
+	case TriggerForget:
+		return c.admin(ctx, m, r, func() error { return c.forget(ctx, m, r, rest) })
+	}
+
+	switch strings.ToLower(rest) {
+	case "stats":
+		return c.stats(ctx, r)
+
+	case "export":
+		return c.admin(ctx, m, r, func() error { return c.export(ctx, m, r) })
+
+	case "import":
+		return c.admin(ctx, m, r, func() error { return c.importFiles(ctx, m, r) })
+
+	default:
+		return r.RespondTo(ctx, Usage)
+	}
+}
+
+// admin runs fn if the user is an admin.
+func (c *Client) admin(ctx workqueue.Context, m handler.Messenger, r handler.Responder, fn func() error) error {
+	ok, err := ctx.UserHasRole(m.UserID(), permissions.Admin)
+	if err != nil {
+		return fmt.Errorf("failed to check user %s role: %w", m.UserID(), err)
+	}
+
+	if !ok {
+		return r.RespondTo(ctx, "Sorry, only admins can change factoids.")
+	}
+
+	return fn()
+}
+
+func (c *Client) fact(ctx workqueue.Context, r handler.Responder, key string) error {
+	f, exact, err := c.s.Lookup(ctx, key)
+	if err != nil {
+		if errors.Is(err, factoids.ErrNotFound) {
+			return r.RespondTo(ctx, fmt.Sprintf("I don't know anything about `%s`.", factoids.NormalizeKey(key)))
+		}
+
+		return err
+	}
+
+	if !exact {
+		return r.Respond(ctx, fmt.Sprintf("(I think you meant `%s`)\n%s", f.Key, f.Text))
+	}
+
+	return r.Respond(ctx, f.Text)
+}
+
+func (c *Client) learn(ctx workqueue.Context, m handler.Messenger, r handler.Responder, rest string) error {
+	i := strings.Index(strings.ToLower(rest), " is ")
+	if i == -1 {
+		return r.RespondTo(ctx, Usage)
+	}
+
+	f, err := c.s.Learn(ctx, factoids.Factoid{
+		Key:    rest[:i],
+		Text:   rest[i+len(" is "):],
+		Author: m.UserID(),
+	})
+	if err != nil {
+		if errors.Is(err, factoids.ErrInvalid) {
+			return r.RespondTo(ctx, fmt.Sprintf("I couldn't learn that: %s", err))
+		}
+
+		return err
+	}
+
+	c.logger.Info().
+		Str("user_id", m.UserID()).
+		Str("factoid_key", f.Key).
+		Msg("factoid learned")
+
+	return r.RespondTo(ctx, fmt.Sprintf("OK, I'll remember `%s`.", f.Key))
+}
+
+func (c *Client) forget(ctx workqueue.Context, m handler.Messenger, r handler.Responder, key string) error {
+	if len(key) == 0 {
+		return r.RespondTo(ctx, Usage)
+	}
+
+	key = factoids.NormalizeKey(key)
+
+	if err := c.s.Forget(ctx, key); err != nil {
+		if errors.Is(err, factoids.ErrNotFound) {
+			return r.RespondTo(ctx, fmt.Sprintf("I don't know anything about `%s`.", key))
+		}
+
+		return err
+	}
+
+	c.logger.Info().
+		Str("user_id", m.UserID()).
+		Str("factoid_key", key).
+		Msg("factoid forgotten")
+
+	return r.RespondTo(ctx, fmt.Sprintf("OK, I've forgotten `%s`.", key))
+}
+
+func (c *Client) stats(ctx workqueue.Context, r handler.Responder) error {
+	stats, err := c.s.Stats(ctx, statsSize)
+	if err != nil {
+		return err
+	}
+
+	if len(stats) == 0 {
+		return r.RespondTo(ctx, "Nobody has asked for any factoids yet.")
+	}
+
+	var b strings.Builder
+
+	b.WriteString("The most used factoids are:\n")
+
+	for i, u := range stats {
+		fmt.Fprintf(&b, "%d. `%s`: %d\n", i+1, u.Key, u.Uses)
+	}
+
+	return r.RespondTo(ctx, b.String())
+}
+
+func (c *Client) export(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	data, err := c.s.Export(ctx)
+	if err != nil {
+		return err
+	}
+
+	ch, _, _, err := ctx.Slack().OpenConversationContext(ctx, &slack.OpenConversationParameters{Users: []string{m.UserID()}})
+	if err != nil {
+		return fmt.Errorf("failed to open DM: %w", err)
+	}
+
+	_, err = ctx.Slack().UploadFileContext(ctx, slack.FileUploadParameters{
+		Content:        string(data),
+		Filetype:       "json",
+		Filename:       "factoids.json",
+		Title:          "factoids.json",
+		InitialComment: "Here are the factoids. Attach this file to `!factoids import` to import them.",
+		Channels:       []string{ch.ID},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload factoids export: %w", err)
+	}
+
+	return r.RespondTo(ctx, "I've sent you the factoids in a DM.")
+}
+
+func (c *Client) importFiles(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	files := m.Files()
+	if len(files) == 0 {
+		return r.RespondTo(ctx, "Please attach the JSON file to import to the `!factoids import` message.")
+	}
+
+	var total int
+
+	for _, f := range files {
+		data, err := download(ctx, ctx.Slack(), f.ID)
+		if err != nil {
+			return err
+		}
+
+		n, err := c.s.Import(ctx, data)
+		total += n
+
+		if err != nil {
+			if errors.Is(err, factoids.ErrInvalid) {
+				return r.RespondTo(ctx, fmt.Sprintf("I couldn't import %s: %s", f.Name, err))
+			}
+
+			return err
+		}
+	}
+
+	c.logger.Info().
+		Str("user_id", m.UserID()).
+		Int("factoids", total).
+		Msg("factoids imported")
+
+	return r.RespondTo(ctx, fmt.Sprintf("Imported %d factoids.", total))
+}
+
+func download(ctx context.Context, sc workqueue.SlackClient, fileID string) ([]byte, error) {
+	f, _, _, err := sc.GetFileInfoContext(ctx, fileID, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file info for %s: %w", fileID, err)
+	}
+
+	if f.Size > maxImportSize {
+		return nil, fmt.Errorf("file %s is too big to import: %d bytes", fileID, f.Size)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := sc.GetFile(f.URLPrivateDownload, buf); err != nil {
+		return nil, fmt.Errorf("failed to get file %s: %w", fileID, err)
+	}
+
+	return buf.Bytes(), nil
+}
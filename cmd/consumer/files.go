@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gobridge/gopherbot/cmd/consumer/filescan"
+	"github.com/gobridge/gopherbot/mparser"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+)
+
+// fileScanAlertChannelID is where files violating a channel policy are reported
+const fileScanAlertChannelID = adminChannelID
+
+// fileScanMaxSize is the largest file permitted in public channels
+const fileScanMaxSize = 25 * 1024 * 1024 // 25 MB
+
+// fileScanBlockedTypes are the MIME and Slack file types that are never
+// permitted in public channels
+var fileScanBlockedTypes = []string{
+	"application/x-msdownload",
+	"application/x-dosexec",
+	"application/x-msi",
+	"exe",
+	"msi",
+	"bat",
+	"scr",
+}
+
+// fileScanExempt are the channels files are not scanned in
+var fileScanExempt = []string{
+	"C4U9J9QBT", // #admin-help
+	adminChannelID,
+	"G207C8R1R", // gobridge ops chanel
+}
+
+// fileScanPolicies returns the per-channel policies, and the default policy
+// for all other channels.
+func fileScanPolicies(httpc *http.Client, avURL string) (map[string]filescan.Policy, *filescan.Policy) {
+	scanners := []filescan.Scanner{
+		filescan.NewBlockedTypes(fileScanBlockedTypes),
+		filescan.MaxSize(fileScanMaxSize),
+	}
+
+	if len(avURL) > 0 {
+		scanners = append(scanners, filescan.NewAntiVirus(httpc, avURL))
+	}
+
+	policies := make(map[string]filescan.Policy, len(fileScanExempt))
+
+	for _, id := range fileScanExempt {
+		policies[id] = filescan.Policy{}
+	}
+
+	return policies, &filescan.Policy{
+		Scanners: scanners,
+		Action:   filescan.ActionDelete,
+	}
+}
+
+func fileScanAlert(ctx workqueue.Context, fs *workqueue.FileSharedEvent, f *slack.File, violations []filescan.Violation, deleted bool) error {
+	b := &strings.Builder{}
+
+	for _, v := range violations {
+		fmt.Fprintf(b, "- %s: %s\n", v.Scanner, v.Reason)
+	}
+
+	user := mparser.Mention{Type: mparser.TypeUser, ID: fs.UserID}
+	channel := mparser.Mention{Type: mparser.TypeChannelRef, ID: fs.ChannelID}
+
+	action := "it was *not* deleted"
+	if deleted {
+		action = "it was deleted"
+	}
+
+	msg := fmt.Sprintf("File `%s` (%s) shared by %s in %s violates the channel's file policy; %s:", f.Name, f.ID, user.String(), channel.String(), action)
+
+	opts := []slack.MsgOption{
+		slack.MsgOptionDisableLinkUnfurl(),
+		slack.MsgOptionDisableMediaUnfurl(),
+		slack.MsgOptionText(msg, false),
+		slack.MsgOptionAttachments(slack.Attachment{Text: b.String()}),
+	}
+
+	if _, _, _, err := ctx.Slack().SendMessageContext(ctx, fileScanAlertChannelID, opts...); err != nil {
+		return fmt.Errorf("failed to SendMessageContext: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,197 @@
+// Package filescan provides a Client struct with a Handler method that
+// satisfies workqueue.FileSharedHandler, which checks files shared in channels
+// against per-channel policies.
+package filescan
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+// Action is what to do when a file violates a Policy.
+type Action uint8
+
+const (
+	// ActionAlert notifies the moderators about the file.
+	ActionAlert Action = iota
+
+	// ActionDelete deletes the file, and then notifies the moderators. This
+	// requires an admin client to be provided to New(), otherwise it behaves
+	// the same as ActionAlert.
+	ActionDelete
+)
+
+func (a Action) String() string {
+	switch a {
+	case ActionAlert:
+		return "alert"
+	case ActionDelete:
+		return "delete"
+	default:
+		return "unknown"
+	}
+}
+
+// Violation is a policy violation found by a Scanner.
+type Violation struct {
+	// Scanner is the name of the Scanner that found the violation.
+	Scanner string
+
+	// Reason is a short, human-readable, reason for the violation.
+	Reason string
+}
+
+// Scanner is the interface implemented by file checkers. The Slack client is
+// provided so that scanners needing the file's contents can download it.
+type Scanner interface {
+	// Name is the name of the scanner, used in logging and Violations.
+	Name() string
+
+	// Scan checks the file. If the file violates the scanner's rules, the
+	// returned bool is true.
+	Scan(ctx context.Context, sc workqueue.SlackClient, f *slack.File) (Violation, bool, error)
+}
+
+// Policy is the set of scanners to run against files shared in a channel, and
+// the action to take if any of them find a violation.
+type Policy struct {
+	Scanners []Scanner
+	Action   Action
+}
+
+// AlertFunc is called with the violations found for a file, after the policy's
+// action was taken. deleted is true if the file was removed.
+type AlertFunc func(ctx workqueue.Context, fs *workqueue.FileSharedEvent, f *slack.File, violations []Violation, deleted bool) error
+
+// Client is the file scanning client.
+type Client struct {
+	logger   zerolog.Logger
+	shadow   bool
+	admin    *slack.Client
+	alert    AlertFunc
+	policies map[string]Policy
+	fallback *Policy
+}
+
+// New returns a file scanning Client. policies maps channel IDs to the policy
+// for that channel, with defaultPolicy being used for all other channels. If
+// defaultPolicy is nil files shared in other channels are ignored.
+//
+// The admin client must be authenticated with a token permitted to delete
+// other users' files, and may be nil if no policy uses ActionDelete. If
+// shadowMode is true violations are only logged.
+func New(logger zerolog.Logger, shadowMode bool, admin *slack.Client, alert AlertFunc, policies map[string]Policy, defaultPolicy *Policy) *Client {
+	return &Client{
+		logger:   logger,
+		shadow:   shadowMode,
+		admin:    admin,
+		alert:    alert,
+		policies: policies,
+		fallback: defaultPolicy,
+	}
+}
+
+func (c *Client) policy(channelID string) (Policy, bool) {
+	if p, ok := c.policies[channelID]; ok {
+		return p, true
+	}
+
+	if c.fallback != nil {
+		return *c.fallback, true
+	}
+
+	return Policy{}, false
+}
+
+// Handler satisfies workqueue.FileSharedHandler.
+func (c *Client) Handler(ctx workqueue.Context, fs *workqueue.FileSharedEvent) (bool, bool, error) {
+	if fs.UserID == ctx.Self().ID {
+		return false, true, nil // no reason given, as it's normal and shouldn't be logged
+	}
+
+	p, ok := c.policy(fs.ChannelID)
+	if !ok || len(p.Scanners) == 0 {
+		return false, true, nil // no reason given, as it's normal and shouldn't be logged
+	}
+
+	f, _, _, err := ctx.Slack().GetFileInfoContext(ctx, fs.FileID, 0, 0)
+	if err != nil {
+		// if it's too old discard
+		if time.Since(ctx.Meta().Time) >= 10*time.Minute {
+			return false, true, fmt.Errorf("discarding file scan due to age: %w", err)
+		}
+
+		return true, false, fmt.Errorf("failed to get file info for %s: %w", fs.FileID, err)
+	}
+
+	var violations []Violation
+
+	for _, s := range p.Scanners {
+		v, bad, err := s.Scan(ctx, ctx.Slack(), f)
+		if err != nil {
+			// one scanner being down shouldn't prevent the others from running
+			ctx.Logger().Error().
+				Err(err).
+				Str("scanner", s.Name()).
+				Str("file_id", f.ID).
+				Msg("failed to scan file")
+
+			continue
+		}
+
+		if bad {
+			violations = append(violations, v)
+		}
+	}
+
+	if len(violations) == 0 {
+		return false, false, nil
+	}
+
+	for _, v := range violations {
+		c.logger.Warn().
+			Str("channel_id", fs.ChannelID).
+			Str("user_id", fs.UserID).
+			Str("file_id", f.ID).
+			Str("file_name", f.Name).
+			Str("mimetype", f.Mimetype).
+			Int("size", f.Size).
+			Str("scanner", v.Scanner).
+			Str("reason", v.Reason).
+			Str("action", p.Action.String()).
+			Bool("shadow_mode", c.shadow).
+			Msg("file violates channel policy")
+	}
+
+	if c.shadow {
+		return false, false, nil
+	}
+
+	var deleted bool
+
+	if p.Action == ActionDelete && c.admin != nil {
+		if err := c.admin.DeleteFileContext(ctx, f.ID); err != nil {
+			ctx.Logger().Error().
+				Err(err).
+				Str("file_id", f.ID).
+				Msg("failed to delete file")
+		} else {
+			deleted = true
+		}
+	}
+
+	if c.alert == nil {
+		return false, false, nil
+	}
+
+	if err := c.alert(ctx, fs, f, violations, deleted); err != nil {
+		return false, false, fmt.Errorf("failed to alert moderators: %w", err)
+	}
+
+	return false, false, nil
+}
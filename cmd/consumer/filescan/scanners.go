@@ -0,0 +1,148 @@
+package filescan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+)
+
+// BlockedTypes is a Scanner that flags files with one of the listed MIME types,
+// or Slack file types (e.g., "exe").
+type BlockedTypes struct {
+	types map[string]struct{}
+}
+
+var _ Scanner = BlockedTypes{}
+
+// NewBlockedTypes returns a BlockedTypes for the given MIME or Slack file
+// types.
+func NewBlockedTypes(types []string) BlockedTypes {
+	m := make(map[string]struct{}, len(types))
+
+	for _, t := range types {
+		m[strings.ToLower(t)] = struct{}{}
+	}
+
+	return BlockedTypes{types: m}
+}
+
+// Name satisfies Scanner.
+func (b BlockedTypes) Name() string { return "blocked_types" }
+
+// Scan satisfies Scanner.
+func (b BlockedTypes) Scan(_ context.Context, _ workqueue.SlackClient, f *slack.File) (Violation, bool, error) {
+	for _, t := range []string{f.Mimetype, f.Filetype} {
+		if _, ok := b.types[strings.ToLower(t)]; ok {
+			return Violation{Scanner: b.Name(), Reason: fmt.Sprintf("file type %s is not permitted", t)}, true, nil
+		}
+	}
+
+	return Violation{}, false, nil
+}
+
+// MaxSize is a Scanner that flags files larger than the number of bytes it
+// represents.
+type MaxSize int
+
+var _ Scanner = MaxSize(0)
+
+// Name satisfies Scanner.
+func (m MaxSize) Name() string { return "max_size" }
+
+// Scan satisfies Scanner.
+func (m MaxSize) Scan(_ context.Context, _ workqueue.SlackClient, f *slack.File) (Violation, bool, error) {
+	if f.Size <= int(m) {
+		return Violation{}, false, nil
+	}
+
+	return Violation{Scanner: m.Name(), Reason: fmt.Sprintf("file is %d bytes, limit is %d", f.Size, int(m))}, true, nil
+}
+
+// maxAVSize is the largest file we'll download to send to the AV API
+const maxAVSize = 20 * 1024 * 1024 // 20 MB
+
+// AntiVirus is a Scanner that downloads the file from Slack, and POSTs it to an
+// external anti-virus HTTP API. The API is expected to respond with a 200 and a
+// JSON body of the form:
+//
+//	{"infected": true, "signature": "Eicar-Test-Signature"}
+type AntiVirus struct {
+	httpc *http.Client
+	url   string
+}
+
+var _ Scanner = (*AntiVirus)(nil)
+
+// NewAntiVirus returns an *AntiVirus that sends files to the API at url.
+func NewAntiVirus(httpc *http.Client, url string) *AntiVirus {
+	return &AntiVirus{
+		httpc: httpc,
+		url:   url,
+	}
+}
+
+// Name satisfies Scanner.
+func (a *AntiVirus) Name() string { return "antivirus" }
+
+// Scan satisfies Scanner.
+func (a *AntiVirus) Scan(ctx context.Context, sc workqueue.SlackClient, f *slack.File) (Violation, bool, error) {
+	if f.IsExternal || len(f.URLPrivateDownload) == 0 {
+		return Violation{}, false, nil
+	}
+
+	if f.Size > maxAVSize {
+		return Violation{}, false, fmt.Errorf("file too large to scan: %d bytes", f.Size)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := sc.GetFile(f.URLPrivateDownload, buf); err != nil {
+		return Violation{}, false, fmt.Errorf("failed to download file %s: %w", f.ID, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, buf)
+	if err != nil {
+		return Violation{}, false, err
+	}
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Add("User-Agent", "Gophers Slack Bot V2")
+
+	resp, err := a.httpc.Do(req)
+	if err != nil {
+		return Violation{}, false, fmt.Errorf("failed to query AV API: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return Violation{}, false, fmt.Errorf("unexpected HTTP response status: %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if err != nil {
+		return Violation{}, false, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var r struct {
+		Infected  bool   `json:"infected"`
+		Signature string `json:"signature"`
+	}
+
+	if err = json.Unmarshal(body, &r); err != nil {
+		return Violation{}, false, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if !r.Infected {
+		return Violation{}, false, nil
+	}
+
+	return Violation{Scanner: a.Name(), Reason: fmt.Sprintf("detected %s", r.Signature)}, true, nil
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gobridge/gopherbot/workqueue"
+)
+
+// scheduledJobFn handles one of the scheduled jobs, using the same return
+// values as a workqueue.ScheduledJobHandler.
+type scheduledJobFn func(ctx workqueue.Context, sj *workqueue.ScheduledJobEvent) (bool, bool, error)
+
+// scheduledJobs returns the handler that runs the scheduled jobs with the
+// function for their name, in jobs.
+func scheduledJobs(jobs map[string]scheduledJobFn) workqueue.ScheduledJobHandler {
+	return func(ctx workqueue.Context, sj *workqueue.ScheduledJobEvent) (bool, bool, error) {
+		fn, ok := jobs[sj.Name]
+		if !ok {
+			return false, true, fmt.Errorf("no handler for scheduled job %q", sj.Name)
+		}
+
+		return fn(ctx, sj)
+	}
+}
@@ -0,0 +1,256 @@
+// Package karma provides a Client struct with MessageMatchFn and Handler
+// methods for giving karma to people and things in public channels, with
+// <@user>++ or thing--, and the !karma command for viewing the scores.
+package karma
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+)
+
+// Trigger is the command prefix.
+const Trigger = "!karma"
+
+// Usage is the help text for the command.
+const Usage = "Usage: `!karma [@user|thing]`, `!karma top`, or `!karma bottom`. Give karma with `@user++` or `thing++`, and take it away with `--`."
+
+const (
+	redisScoresKey = "karma:scores"
+
+	// storeNamespace is the ctx.Store() namespace the cooldowns are kept in
+	storeNamespace = "karma"
+
+	// cooldown is how long someone has to wait to vote for the same target
+	// again
+	cooldown = time.Minute
+
+	// maxVotes is the most targets counted in a single message
+	maxVotes = 5
+
+	leaderboardSize = 10
+)
+
+// Client is the karma client.
+type Client struct {
+	rc     redis.UniversalClient
+	logger zerolog.Logger
+}
+
+// New returns a karma Client.
+func New(rc redis.UniversalClient, logger zerolog.Logger) *Client {
+	return &Client{
+		rc:     rc,
+		logger: logger,
+	}
+}
+
+// vote is a change to a target's karma
+type vote struct {
+	// target is the member of the scores set, like user:U123 or thing:go
+	target string
+	delta  int64
+}
+
+var (
+	// voteRE matches user mentions, with an optional space as Slack adds one
+	// when completing them, or words of at least two characters. The ++ or --
+	// has to end the word, so C++ or --flags aren't votes.
+	voteRE = regexp.MustCompile(`(?:<@([UW][A-Z0-9]+)(?:\|[^>]*)?>\s?|([\p{L}\p{N}_.\-]*[\p{L}\p{N}_][\p{L}\p{N}_.\-]*))(\+\+|--)(?:$|[\s.,!?;:)])`)
+
+	codeBlockRE  = regexp.MustCompile("(?s)```.*?```")
+	inlineCodeRE = regexp.MustCompile("`[^`]*`")
+)
+
+// parseVotes returns the votes in the raw message text, ignoring any in code.
+// Each target is only counted once, and votes by the sender for themselves are
+// dropped.
+func parseVotes(text, senderID string) []vote {
+	text = codeBlockRE.ReplaceAllString(text, "")
+	text = inlineCodeRE.ReplaceAllString(text, "")
+
+	var votes []vote
+
+	seen := make(map[string]struct{})
+
+	for _, m := range voteRE.FindAllStringSubmatch(text, -1) {
+		var target string
+
+		switch {
+		case len(m[1]) > 0:
+			if m[1] == senderID {
+				continue
+			}
+
+			target = "user:" + m[1]
+
+		case len([]rune(m[2])) >= 2:
+			target = "thing:" + strings.ToLower(m[2])
+
+		default:
+			continue
+		}
+
+		if _, ok := seen[target]; ok {
+			continue
+		}
+
+		seen[target] = struct{}{}
+
+		delta := int64(1)
+		if m[3] == "--" {
+			delta = -1
+		}
+
+		votes = append(votes, vote{target: target, delta: delta})
+
+		if len(votes) == maxVotes {
+			break
+		}
+	}
+
+	return votes
+}
+
+// display returns how the target is shown in messages.
+func display(target string) string {
+	if strings.HasPrefix(target, "user:") {
+		return "<@" + strings.TrimPrefix(target, "user:") + ">"
+	}
+
+	return "`" + strings.TrimPrefix(target, "thing:") + "`"
+}
+
+// VoteMatchFn satisfies handler.MessageMatchFn. It matches public messages
+// containing votes.
+func (c *Client) VoteMatchFn(shadowMode bool, m handler.Messenger) bool {
+	if shadowMode || m.ChannelType() != handler.ChannelPublic {
+		return false
+	}
+
+	return len(parseVotes(m.RawText(), m.UserID())) > 0
+}
+
+// VoteHandler satisfies handler.MessageActionFn.
+func (c *Client) VoteHandler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	store := ctx.Store().Namespace(storeNamespace)
+
+	var lines []string
+
+	for _, v := range parseVotes(m.RawText(), m.UserID()) {
+		// only count the first vote for a target in each cooldown
+		n, err := store.Incr(ctx, "cooldown:"+m.UserID()+":"+v.target, cooldown)
+		if err != nil {
+			return fmt.Errorf("failed to check karma cooldown: %w", err)
+		}
+
+		if n > 1 {
+			continue
+		}
+
+		score, err := c.rc.ZIncrBy(ctx, redisScoresKey, float64(v.delta), v.target).Result()
+		if err != nil {
+			return fmt.Errorf("failed to change karma for %s: %w", v.target, err)
+		}
+
+		c.logger.Debug().
+			Str("user_id", m.UserID()).
+			Str("target", v.target).
+			Int64("delta", v.delta).
+			Msg("karma changed")
+
+		lines = append(lines, fmt.Sprintf("%s now has %d karma", display(v.target), int64(score)))
+	}
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	return r.Respond(ctx, strings.Join(lines, "\n"))
+}
+
+// MessageMatchFn satisfies handler.MessageMatchFn. It matches the !karma
+// command.
+func (c *Client) MessageMatchFn(_ bool, m handler.Messenger) bool {
+	text := strings.ToLower(m.RawText())
+	return text == Trigger || strings.HasPrefix(text, Trigger+" ")
+}
+
+// Handler satisfies handler.MessageActionFn.
+func (c *Client) Handler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	fields := strings.Fields(m.RawText())[1:]
+
+	switch {
+	case len(fields) == 0:
+		return c.score(ctx, r, "user:"+m.UserID())
+
+	case len(fields) > 1:
+		return r.RespondTo(ctx, Usage)
+
+	case strings.EqualFold(fields[0], "top"):
+		return c.leaderboard(ctx, r, true)
+
+	case strings.EqualFold(fields[0], "bottom"):
+		return c.leaderboard(ctx, r, false)
+
+	case strings.EqualFold(fields[0], "help"):
+		return r.RespondTo(ctx, Usage)
+	}
+
+	sm := voteRE.FindStringSubmatch(fields[0] + "++")
+	if sm == nil {
+		return r.RespondTo(ctx, Usage)
+	}
+
+	if len(sm[1]) > 0 {
+		return c.score(ctx, r, "user:"+sm[1])
+	}
+
+	return c.score(ctx, r, "thing:"+strings.ToLower(sm[2]))
+}
+
+func (c *Client) score(ctx workqueue.Context, r handler.Responder, target string) error {
+	score, err := c.rc.ZScore(ctx, redisScoresKey, target).Result()
+	if err != nil && err != redis.Nil {
+		return fmt.Errorf("failed to get karma for %s: %w", target, err)
+	}
+
+	return r.RespondTo(ctx, fmt.Sprintf("%s has %d karma", display(target), int64(score)))
+}
+
+func (c *Client) leaderboard(ctx workqueue.Context, r handler.Responder, top bool) error {
+	get := c.rc.ZRangeWithScores
+	title := "Lowest karma:"
+
+	if top {
+		get = c.rc.ZRevRangeWithScores
+		title = "Highest karma:"
+	}
+
+	scores, err := get(ctx, redisScoresKey, 0, leaderboardSize-1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get karma leaderboard: %w", err)
+	}
+
+	if len(scores) == 0 {
+		return r.RespondTo(ctx, "Nobody has any karma yet.")
+	}
+
+	var b strings.Builder
+
+	b.WriteString(title + "\n")
+
+	for i, z := range scores {
+		target, _ := z.Member.(string)
+		b.WriteString(strconv.Itoa(i+1) + ". " + display(target) + ": " + strconv.FormatInt(int64(z.Score), 10) + "\n")
+	}
+
+	return r.RespondTo(ctx, b.String())
+}
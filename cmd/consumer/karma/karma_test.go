@@ -0,0 +1,68 @@
+package karma
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_parseVotes(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []vote
+	}{
+		{
+			name: "mention",
+			text: "thanks <@U123>++",
+			want: []vote{{target: "user:U123", delta: 1}},
+		},
+		{
+			name: "mention_space",
+			text: "<@U123|bob> ++ for the help",
+			want: []vote{{target: "user:U123", delta: 1}},
+		},
+		{
+			name: "thing",
+			text: "Generics-- and gophers++!",
+			want: []vote{{target: "thing:generics", delta: -1}, {target: "thing:gophers", delta: 1}},
+		},
+		{
+			name: "self",
+			text: "<@U999>++",
+		},
+		{
+			name: "duplicate",
+			text: "go++ go++ Go--",
+			want: []vote{{target: "thing:go", delta: 1}},
+		},
+		{
+			name: "not_votes",
+			text: "I write c++ and run ls --all, or x--y",
+		},
+		{
+			name: "code",
+			text: "run `i++` or ```\nfor i := 0; i < n; i++ {\n```",
+		},
+		{
+			name: "max",
+			text: "a1++ b2++ c3++ d4++ e5++ f6++",
+			want: []vote{
+				{target: "thing:a1", delta: 1},
+				{target: "thing:b2", delta: 1},
+				{target: "thing:c3", delta: 1},
+				{target: "thing:d4", delta: 1},
+				{target: "thing:e5", delta: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseVotes(tt.text, "U999")
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseVotes() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
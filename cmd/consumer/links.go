@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gobridge/gopherbot/cmd/consumer/linkscan"
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/mparser"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+)
+
+// linkScanEscalationChannelID is where flagged links are reported
+const linkScanEscalationChannelID = adminChannelID
+
+// linkBlocklist is the list of hosts (and their subdomains) that are always
+// flagged when they are linked to in a public channel
+var linkBlocklist = []string{}
+
+func linkScanners(httpc *http.Client, safeBrowsingKey string) []linkscan.Scanner {
+	scanners := []linkscan.Scanner{linkscan.NewBlocklist(linkBlocklist)}
+
+	if len(safeBrowsingKey) > 0 {
+		scanners = append(scanners, linkscan.NewSafeBrowsing(httpc, safeBrowsingKey))
+	}
+
+	return scanners
+}
+
+func linkScanEscalate(ctx workqueue.Context, m handler.Messenger, findings []linkscan.Finding) error {
+	b := &strings.Builder{}
+
+	for _, f := range findings {
+		fmt.Fprintf(b, "- `%s` (%s: %s)\n", f.URL, f.Scanner, f.Reason)
+	}
+
+	user := mparser.Mention{Type: mparser.TypeUser, ID: m.UserID()}
+	channel := mparser.Mention{Type: mparser.TypeChannelRef, ID: m.ChannelID()}
+
+	msg := fmt.Sprintf("Flagged link(s) posted by %s in %s (message ts %s):", user.String(), channel.String(), m.MessageTS())
+
+	opts := []slack.MsgOption{
+		slack.MsgOptionDisableLinkUnfurl(),
+		slack.MsgOptionDisableMediaUnfurl(),
+		slack.MsgOptionText(msg, false),
+		slack.MsgOptionAttachments(slack.Attachment{Text: b.String()}),
+	}
+
+	if _, _, _, err := ctx.Slack().SendMessageContext(ctx, linkScanEscalationChannelID, opts...); err != nil {
+		return fmt.Errorf("failed to SendMessageContext: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+// Package linkscan provides a handler.MessageMatchFn and a Client struct with
+// a Handler method that can be used as a handler.MessageActionFn, which checks
+// the links in public messages against a set of pluggable Scanners.
+package linkscan
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/mparser"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+)
+
+// Finding is a link a Scanner considers to be malicious.
+type Finding struct {
+	// URL is the link that was flagged.
+	URL string
+
+	// Scanner is the name of the Scanner that flagged the link.
+	Scanner string
+
+	// Reason is a short, human-readable, reason why the link was flagged.
+	Reason string
+}
+
+// Scanner is the interface implemented by link checkers.
+type Scanner interface {
+	// Name is the name of the scanner, used in logging and Findings.
+	Name() string
+
+	// Scan checks the URLs, and returns a Finding for each that's considered
+	// malicious. URLs that are fine should be omitted from the return value.
+	Scan(ctx context.Context, urls []string) ([]Finding, error)
+}
+
+// EscalateFunc is called with all of the findings for a message, if there were
+// any. It's meant to notify the folks who need to take action.
+type EscalateFunc func(ctx workqueue.Context, m handler.Messenger, findings []Finding) error
+
+// Client is the link scanning client.
+type Client struct {
+	logger   zerolog.Logger
+	scanners []Scanner
+	escalate EscalateFunc
+	shadow   bool
+}
+
+// New returns a link scanning Client. If shadowMode is true findings are only
+// logged, and escalate is never called.
+func New(logger zerolog.Logger, shadowMode bool, escalate EscalateFunc, scanners ...Scanner) *Client {
+	return &Client{
+		logger:   logger,
+		scanners: scanners,
+		escalate: escalate,
+		shadow:   shadowMode,
+	}
+}
+
+// MessageMatchFn satisfies handler.MessageMatchFn. It matches messages in
+// public channels that contain at least one link.
+func (c *Client) MessageMatchFn(shadowMode bool, m handler.Messenger) bool {
+	if len(c.scanners) == 0 || m.ChannelType() != handler.ChannelPublic {
+		return false
+	}
+
+	return len(mparser.Links(m.RawText())) > 0
+}
+
+// Handler is a handler.MessageActionFn.
+func (c *Client) Handler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	links := mparser.Links(m.RawText())
+
+	urls := make([]string, 0, len(links))
+	for _, l := range links {
+		urls = append(urls, l.URL)
+	}
+
+	var findings []Finding
+
+	for _, s := range c.scanners {
+		f, err := s.Scan(ctx, urls)
+		if err != nil {
+			// one scanner being down shouldn't prevent the others from running
+			ctx.Logger().Error().
+				Err(err).
+				Str("scanner", s.Name()).
+				Msg("failed to scan links")
+
+			continue
+		}
+
+		findings = append(findings, f...)
+	}
+
+	if len(findings) == 0 {
+		return nil
+	}
+
+	for _, f := range findings {
+		c.logger.Warn().
+			Str("channel_id", m.ChannelID()).
+			Str("user_id", m.UserID()).
+			Str("message_ts", m.MessageTS()).
+			Str("url", f.URL).
+			Str("scanner", f.Scanner).
+			Str("reason", f.Reason).
+			Bool("shadow_mode", c.shadow).
+			Msg("flagged link")
+	}
+
+	if c.shadow || c.escalate == nil {
+		return nil
+	}
+
+	if err := c.escalate(ctx, m, findings); err != nil {
+		return fmt.Errorf("failed to escalate flagged links: %w", err)
+	}
+
+	return nil
+}
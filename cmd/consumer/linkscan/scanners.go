@@ -0,0 +1,176 @@
+package linkscan
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Blocklist is a Scanner that flags links to a static list of hosts. A host in
+// the list also matches all of its subdomains.
+type Blocklist struct {
+	hosts map[string]struct{}
+}
+
+var _ Scanner = Blocklist{}
+
+// NewBlocklist returns a Blocklist for the given hosts.
+func NewBlocklist(hosts []string) Blocklist {
+	m := make(map[string]struct{}, len(hosts))
+
+	for _, h := range hosts {
+		m[strings.ToLower(h)] = struct{}{}
+	}
+
+	return Blocklist{hosts: m}
+}
+
+// Name satisfies Scanner.
+func (b Blocklist) Name() string { return "blocklist" }
+
+// Scan satisfies Scanner.
+func (b Blocklist) Scan(_ context.Context, urls []string) ([]Finding, error) {
+	var findings []Finding
+
+	for _, u := range urls {
+		pu, err := url.Parse(u)
+		if err != nil {
+			continue
+		}
+
+		host := strings.ToLower(pu.Hostname())
+
+		for len(host) > 0 {
+			if _, ok := b.hosts[host]; ok {
+				findings = append(findings, Finding{
+					URL:     u,
+					Scanner: b.Name(),
+					Reason:  fmt.Sprintf("host %s is blocklisted", host),
+				})
+
+				break
+			}
+
+			i := strings.IndexByte(host, '.')
+			if i == -1 {
+				break
+			}
+
+			host = host[i+1:]
+		}
+	}
+
+	return findings, nil
+}
+
+const safeBrowsingURL = "https://safebrowsing.googleapis.com/v4/threatMatches:find"
+
+// SafeBrowsing is a Scanner backed by the Google Safe Browsing Lookup API (v4).
+type SafeBrowsing struct {
+	httpc  *http.Client
+	apiKey string
+}
+
+var _ Scanner = (*SafeBrowsing)(nil)
+
+// NewSafeBrowsing returns a *SafeBrowsing using the provided HTTP client and
+// API key.
+func NewSafeBrowsing(httpc *http.Client, apiKey string) *SafeBrowsing {
+	return &SafeBrowsing{
+		httpc:  httpc,
+		apiKey: apiKey,
+	}
+}
+
+// Name satisfies Scanner.
+func (s *SafeBrowsing) Name() string { return "safe_browsing" }
+
+type sbEntry struct {
+	URL string `json:"url"`
+}
+
+type sbRequest struct {
+	Client struct {
+		ClientID      string `json:"clientId"`
+		ClientVersion string `json:"clientVersion"`
+	} `json:"client"`
+	ThreatInfo struct {
+		ThreatTypes      []string  `json:"threatTypes"`
+		PlatformTypes    []string  `json:"platformTypes"`
+		ThreatEntryTypes []string  `json:"threatEntryTypes"`
+		ThreatEntries    []sbEntry `json:"threatEntries"`
+	} `json:"threatInfo"`
+}
+
+type sbResponse struct {
+	Matches []struct {
+		ThreatType string  `json:"threatType"`
+		Threat     sbEntry `json:"threat"`
+	} `json:"matches"`
+}
+
+// Scan satisfies Scanner.
+func (s *SafeBrowsing) Scan(ctx context.Context, urls []string) ([]Finding, error) {
+	var sr sbRequest
+
+	sr.Client.ClientID = "gopherbot"
+	sr.Client.ClientVersion = "2"
+	sr.ThreatInfo.ThreatTypes = []string{"MALWARE", "SOCIAL_ENGINEERING", "UNWANTED_SOFTWARE", "POTENTIALLY_HARMFUL_APPLICATION"}
+	sr.ThreatInfo.PlatformTypes = []string{"ANY_PLATFORM"}
+	sr.ThreatInfo.ThreatEntryTypes = []string{"URL"}
+
+	for _, u := range urls {
+		sr.ThreatInfo.ThreatEntries = append(sr.ThreatInfo.ThreatEntries, sbEntry{URL: u})
+	}
+
+	body, err := json.Marshal(sr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, safeBrowsingURL+"?key="+url.QueryEscape(s.apiKey), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Add("User-Agent", "Gophers Slack Bot V2")
+
+	resp, err := s.httpc.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Safe Browsing: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP response status: %s", resp.Status)
+	}
+
+	rb, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var sresp sbResponse
+	if err = json.Unmarshal(rb, &sresp); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	findings := make([]Finding, 0, len(sresp.Matches))
+
+	for _, m := range sresp.Matches {
+		findings = append(findings, Finding{
+			URL:     m.Threat.URL,
+			Scanner: s.Name(),
+			Reason:  strings.ToLower(m.ThreatType),
+		})
+	}
+
+	return findings, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/gobridge/gopherbot/cmd/consumer/moderation"
+)
+
+// moderationNotifyChannelID is where messages violating a channel policy are
+// reported
+const moderationNotifyChannelID = adminChannelID
+
+// moderationBannedWords are the words and phrases that aren't permitted in
+// public channels
+var moderationBannedWords = []string{}
+
+// moderationBannedPatterns are the regular expressions messages in public
+// channels may not match
+var moderationBannedPatterns = []string{}
+
+// moderationBlockedHosts are the hosts (and their subdomains) that may not be
+// linked to in public channels
+var moderationBlockedHosts = []string{}
+
+// moderationExempt are the channels messages are not moderated in
+var moderationExempt = []string{
+	"C4U9J9QBT", // #admin-help
+	adminChannelID,
+	"G207C8R1R", // gobridge ops chanel
+}
+
+// moderationPolicies returns the per-channel policies, and the default policy
+// for all other channels.
+func moderationPolicies() (map[string]moderation.Policy, *moderation.Policy, error) {
+	patterns, err := moderation.NewPatterns("banned_patterns", moderationBannedPatterns)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build banned patterns rule: %w", err)
+	}
+
+	rules := []moderation.Rule{
+		moderation.NewWords("banned_words", moderationBannedWords),
+		patterns,
+		moderation.NewLinks("blocked_links", moderationBlockedHosts, nil),
+	}
+
+	policies := make(map[string]moderation.Policy, len(moderationExempt))
+
+	for _, id := range moderationExempt {
+		policies[id] = moderation.Policy{}
+	}
+
+	return policies, &moderation.Policy{
+		Rules:   rules,
+		Actions: moderation.ActionWarn | moderation.ActionNotify,
+	}, nil
+}
@@ -0,0 +1,341 @@
+// Package moderation provides a Client struct with MessageMatchFn and Handler
+// methods, which check public messages against per-channel policies of banned
+// words, patterns, and links, and then take the policy's actions.
+package moderation
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/mparser"
+	"github.com/gobridge/gopherbot/permissions"
+	"github.com/gobridge/gopherbot/settings"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+// Action is what to do when a message violates a Policy. Actions can be
+// combined, like ActionWarn | ActionNotify.
+type Action uint8
+
+const (
+	// ActionWarn replies to the message in a thread, or with an ephemeral
+	// message if it was deleted, telling the user why it was flagged.
+	ActionWarn Action = 1 << iota
+
+	// ActionDM sends the user a DM telling them why the message was flagged.
+	ActionDM
+
+	// ActionNotify notifies the moderators in their channel.
+	ActionNotify
+
+	// ActionDelete deletes the message. This requires an admin client to be
+	// provided to New(), otherwise it's skipped.
+	ActionDelete
+)
+
+func (a Action) String() string {
+	var names []string
+
+	for _, n := range [...]struct {
+		a    Action
+		name string
+	}{{ActionWarn, "warn"}, {ActionDM, "dm"}, {ActionNotify, "notify"}, {ActionDelete, "delete"}} {
+		if a&n.a != 0 {
+			names = append(names, n.name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, "|")
+}
+
+// Violation is a policy violation found by a Rule.
+type Violation struct {
+	// Rule is the name of the Rule that found the violation.
+	Rule string
+
+	// Reason is a short, human-readable, reason for the violation. It's shown
+	// to the user, so it shouldn't repeat the banned word.
+	Reason string
+}
+
+// Rule is the interface implemented by message checkers.
+type Rule interface {
+	// Name is the name of the rule, used in logging and Violations.
+	Name() string
+
+	// Check checks the message text, with mentions removed, and the URLs it
+	// links to. If the message violates the rule, the returned bool is true.
+	Check(text string, urls []string) (Violation, bool)
+}
+
+// Policy is the set of rules to check messages in a channel against, and the
+// actions to take if any of them are violated.
+type Policy struct {
+	Rules   []Rule
+	Actions Action
+}
+
+// Client is the moderation client.
+type Client struct {
+	logger   zerolog.Logger
+	shadow   bool
+	admin    *slack.Client
+	notifyID string
+	policies map[string]Policy
+	fallback *Policy
+}
+
+// New returns a moderation Client. policies maps channel IDs to the policy for
+// that channel, with defaultPolicy being used for all other channels. If
+// defaultPolicy is nil messages in other channels are ignored. Moderators are
+// notified in the channel with ID notifyChannelID.
+//
+// The admin client must be authenticated with a token permitted to delete
+// other users' messages, and may be nil if no policy uses ActionDelete. If
+// shadowMode is true violations are only logged.
+func New(logger zerolog.Logger, shadowMode bool, admin *slack.Client, notifyChannelID string, policies map[string]Policy, defaultPolicy *Policy) *Client {
+	return &Client{
+		logger:   logger,
+		shadow:   shadowMode,
+		admin:    admin,
+		notifyID: notifyChannelID,
+		policies: policies,
+		fallback: defaultPolicy,
+	}
+}
+
+func (c *Client) policy(channelID string) (Policy, bool) {
+	if p, ok := c.policies[channelID]; ok {
+		return p, true
+	}
+
+	if c.fallback != nil {
+		return *c.fallback, true
+	}
+
+	return Policy{}, false
+}
+
+// MessageMatchFn satisfies handler.MessageMatchFn. It matches messages in
+// public channels that have a policy with rules.
+func (c *Client) MessageMatchFn(_ bool, m handler.Messenger) bool {
+	if m.ChannelType() != handler.ChannelPublic {
+		return false
+	}
+
+	p, ok := c.policy(m.ChannelID())
+
+	return ok && len(p.Rules) > 0
+}
+
+// Handler satisfies handler.MessageActionFn.
+func (c *Client) Handler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	p, _ := c.policy(m.ChannelID())
+
+	violations := check(p.Rules, m.Text(), mparser.Links(m.RawText()))
+	if len(violations) == 0 {
+		return nil
+	}
+
+	// the channel's moderation level can turn moderation off, or make it
+	// stricter by deleting messages too
+	actions := p.Actions
+
+	cs, err := ctx.ChannelSettings(m.ChannelID())
+	if err != nil && !errors.Is(err, workqueue.ErrNoSettingsSvc) {
+		return fmt.Errorf("failed to get channel settings: %w", err)
+	}
+
+	if err == nil {
+		switch cs.ModerationLevel {
+		case settings.ModerationOff:
+			return nil
+		case settings.ModerationStrict:
+			actions |= ActionDelete
+		}
+	}
+
+	// moderators are trusted to quote things, like when explaining a rule
+	mod, err := ctx.UserHasRole(m.UserID(), permissions.Moderator)
+	if err != nil && !errors.Is(err, workqueue.ErrNoPermissionSvc) {
+		return fmt.Errorf("failed to check user %s role: %w", m.UserID(), err)
+	}
+
+	for _, v := range violations {
+		c.logger.Warn().
+			Str("channel_id", m.ChannelID()).
+			Str("user_id", m.UserID()).
+			Str("message_ts", m.MessageTS()).
+			Str("rule", v.Rule).
+			Str("reason", v.Reason).
+			Str("actions", actions.String()).
+			Bool("moderator", mod).
+			Bool("shadow_mode", c.shadow).
+			Msg("message violates channel policy")
+	}
+
+	if c.shadow || mod {
+		return nil
+	}
+
+	return c.act(ctx, m, actions, violations)
+}
+
+func check(rules []Rule, text string, links []mparser.Link) []Violation {
+	urls := make([]string, 0, len(links))
+	for _, l := range links {
+		urls = append(urls, l.URL)
+	}
+
+	var violations []Violation
+
+	for _, rule := range rules {
+		if v, bad := rule.Check(text, urls); bad {
+			violations = append(violations, v)
+		}
+	}
+
+	return violations
+}
+
+func (c *Client) act(ctx workqueue.Context, m handler.Messenger, actions Action, violations []Violation) error {
+	var deleted bool
+
+	if actions&ActionDelete != 0 && c.admin != nil {
+		if _, _, err := c.admin.DeleteMessageContext(ctx, m.ChannelID(), m.MessageTS()); err != nil {
+			ctx.Logger().Error().
+				Err(err).
+				Str("message_ts", m.MessageTS()).
+				Msg("failed to delete message")
+		} else {
+			deleted = true
+		}
+	}
+
+	reasons := make([]string, 0, len(violations))
+	for _, v := range violations {
+		reasons = append(reasons, v.Reason)
+	}
+
+	warning := fmt.Sprintf("Your message in <#%s> was flagged by the moderation filters (%s). Please take a look at the rules all members are expected to follow: <http://coc.golangbridge.org>", m.ChannelID(), strings.Join(reasons, "; "))
+
+	var errs []string
+
+	if actions&ActionWarn != 0 {
+		var err error
+
+		if deleted {
+			err = ctx.ReplyEphemeral(m.UserID(), warning)
+		} else {
+			err = ctx.ReplyInThread(warning)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("failed to warn user: %s", err))
+		}
+	}
+
+	if actions&ActionDM != 0 {
+		if err := ctx.ReplyDM(m.UserID(), warning); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to DM user: %s", err))
+		}
+	}
+
+	if actions&ActionNotify != 0 && len(c.notifyID) > 0 {
+		if err := c.notify(ctx, m, violations, deleted); err != nil {
+			errs = append(errs, fmt.Sprintf("failed to notify moderators: %s", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (c *Client) notify(ctx workqueue.Context, m handler.Messenger, violations []Violation, deleted bool) error {
+	b := &strings.Builder{}
+
+	for _, v := range violations {
+		fmt.Fprintf(b, "- %s: %s\n", v.Rule, v.Reason)
+	}
+
+	fmt.Fprintf(b, "\n> %s\n", strings.ReplaceAll(m.RawText(), "\n", "\n> "))
+
+	user := mparser.Mention{Type: mparser.TypeUser, ID: m.UserID()}
+	channel := mparser.Mention{Type: mparser.TypeChannelRef, ID: m.ChannelID()}
+
+	action := "it was *not* deleted"
+	if deleted {
+		action = "it was deleted"
+	}
+
+	msg := fmt.Sprintf("Message posted by %s in %s (message ts %s) violates the channel's moderation policy; %s:", user.String(), channel.String(), m.MessageTS(), action)
+
+	opts := []slack.MsgOption{
+		slack.MsgOptionDisableLinkUnfurl(),
+		slack.MsgOptionDisableMediaUnfurl(),
+		slack.MsgOptionText(msg, false),
+		slack.MsgOptionAttachments(slack.Attachment{Text: b.String()}),
+	}
+
+	if _, _, _, err := ctx.Slack().SendMessageContext(ctx, c.notifyID, opts...); err != nil {
+		return fmt.Errorf("failed to SendMessageContext: %w", err)
+	}
+
+	return nil
+}
+
+// EventHandler satisfies workqueue.ModerationHandler. It notifies the
+// moderators about members flagged by the other detectors, like the spam
+// detector.
+func (c *Client) EventHandler(ctx workqueue.Context, me *workqueue.ModerationEvent) (bool, bool, error) {
+	c.logger.Warn().
+		Str("detector", me.Detector).
+		Str("kind", me.Kind).
+		Str("user_id", me.UserID).
+		Str("channel_id", me.ChannelID).
+		Str("message_ts", me.MessageTS).
+		Str("reason", me.Reason).
+		Bool("shadow_mode", c.shadow).
+		Msg("member flagged")
+
+	if c.shadow || len(c.notifyID) == 0 {
+		return false, false, nil
+	}
+
+	user := mparser.Mention{Type: mparser.TypeUser, ID: me.UserID}
+	channel := mparser.Mention{Type: mparser.TypeChannelRef, ID: me.ChannelID}
+
+	msg := fmt.Sprintf("%s was flagged by the %s detector in %s (message ts %s): %s", user.String(), me.Detector, channel.String(), me.MessageTS, me.Reason)
+
+	if len(me.Channels) > 0 {
+		refs := make([]string, 0, len(me.Channels))
+		for _, id := range me.Channels {
+			refs = append(refs, mparser.Mention{Type: mparser.TypeChannelRef, ID: id}.String())
+		}
+
+		msg += fmt.Sprintf(" (channels: %s)", strings.Join(refs, ", "))
+	}
+
+	opts := []slack.MsgOption{
+		slack.MsgOptionDisableLinkUnfurl(),
+		slack.MsgOptionDisableMediaUnfurl(),
+		slack.MsgOptionText(msg, false),
+	}
+
+	if _, _, _, err := ctx.Slack().SendMessageContext(ctx, c.notifyID, opts...); err != nil {
+		return true, false, fmt.Errorf("failed to SendMessageContext: %w", err)
+	}
+
+	return false, false, nil
+}
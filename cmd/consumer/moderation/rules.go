@@ -0,0 +1,169 @@
+package moderation
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Words is a Rule that flags messages containing any of a list of words or
+// phrases. Matching is case-insensitive, and only on whole words, so banning
+// "ass" doesn't flag "class".
+type Words struct {
+	name string
+	re   *regexp.Regexp
+}
+
+var _ Rule = Words{}
+
+// NewWords returns a Words rule for the list of words or phrases. If words is
+// empty, the rule never matches.
+func NewWords(name string, words []string) Words {
+	quoted := make([]string, 0, len(words))
+
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if len(w) == 0 {
+			continue
+		}
+
+		quoted = append(quoted, regexp.QuoteMeta(w))
+	}
+
+	if len(quoted) == 0 {
+		return Words{name: name}
+	}
+
+	return Words{
+		name: name,
+		re:   regexp.MustCompile(`(?i)(?:^|\W)(?:` + strings.Join(quoted, "|") + `)(?:$|\W)`),
+	}
+}
+
+// Name satisfies Rule.
+func (w Words) Name() string { return w.name }
+
+// Check satisfies Rule.
+func (w Words) Check(text string, _ []string) (Violation, bool) {
+	if w.re == nil || !w.re.MatchString(text) {
+		return Violation{}, false
+	}
+
+	return Violation{Rule: w.name, Reason: "it contains a banned word"}, true
+}
+
+// Patterns is a Rule that flags messages matching any of a list of regular
+// expressions.
+type Patterns struct {
+	name string
+	res  []*regexp.Regexp
+}
+
+var _ Rule = Patterns{}
+
+// NewPatterns returns a Patterns rule for the regular expressions, which use
+// the regexp package syntax. Use (?i) for case-insensitive matching.
+func NewPatterns(name string, patterns []string) (Patterns, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return Patterns{}, fmt.Errorf("failed to compile pattern %q: %w", p, err)
+		}
+
+		res = append(res, re)
+	}
+
+	return Patterns{name: name, res: res}, nil
+}
+
+// Name satisfies Rule.
+func (p Patterns) Name() string { return p.name }
+
+// Check satisfies Rule.
+func (p Patterns) Check(text string, _ []string) (Violation, bool) {
+	for _, re := range p.res {
+		if re.MatchString(text) {
+			return Violation{Rule: p.name, Reason: "it matches a banned pattern"}, true
+		}
+	}
+
+	return Violation{}, false
+}
+
+// Links is a Rule that flags links to blocked hosts. If it has a list of
+// allowed hosts, links to any other host are flagged too. A host in either list
+// also matches all of its subdomains.
+type Links struct {
+	name    string
+	blocked map[string]struct{}
+	allowed map[string]struct{}
+}
+
+var _ Rule = Links{}
+
+// NewLinks returns a Links rule. If allowed is empty, links to any host that
+// isn't blocked are permitted.
+func NewLinks(name string, blocked, allowed []string) Links {
+	return Links{
+		name:    name,
+		blocked: hostSet(blocked),
+		allowed: hostSet(allowed),
+	}
+}
+
+func hostSet(hosts []string) map[string]struct{} {
+	m := make(map[string]struct{}, len(hosts))
+
+	for _, h := range hosts {
+		m[strings.ToLower(h)] = struct{}{}
+	}
+
+	return m
+}
+
+// inSet returns whether the host, or any domain it's a subdomain of, is in the
+// set.
+func inSet(set map[string]struct{}, host string) bool {
+	for len(host) > 0 {
+		if _, ok := set[host]; ok {
+			return true
+		}
+
+		i := strings.IndexByte(host, '.')
+		if i == -1 {
+			break
+		}
+
+		host = host[i+1:]
+	}
+
+	return false
+}
+
+// Name satisfies Rule.
+func (l Links) Name() string { return l.name }
+
+// Check satisfies Rule.
+func (l Links) Check(_ string, urls []string) (Violation, bool) {
+	for _, u := range urls {
+		pu, err := url.Parse(u)
+		if err != nil {
+			continue
+		}
+
+		host := strings.ToLower(pu.Hostname())
+
+		if inSet(l.blocked, host) {
+			return Violation{Rule: l.name, Reason: fmt.Sprintf("links to %s aren't allowed", host)}, true
+		}
+
+		if len(l.allowed) > 0 && !inSet(l.allowed, host) {
+			return Violation{Rule: l.name, Reason: fmt.Sprintf("links to %s aren't allowed in this channel", host)}, true
+		}
+	}
+
+	return Violation{}, false
+}
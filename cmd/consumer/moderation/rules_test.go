@@ -0,0 +1,108 @@
+package moderation
+
+import "testing"
+
+func TestWords(t *testing.T) {
+	w := NewWords("words", []string{"badword", "two words", ""})
+
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{name: "match", text: "this has a badword in it", want: true},
+		{name: "case", text: "BadWord!", want: true},
+		{name: "phrase", text: "there are Two Words here", want: true},
+		{name: "substring", text: "notabadwordreally", want: false},
+		{name: "clean", text: "nothing to see", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := w.Check(tt.text, nil); got != tt.want {
+				t.Fatalf("Check(%q) = %t, want %t", tt.text, got, tt.want)
+			}
+		})
+	}
+
+	if _, got := NewWords("empty", nil).Check("anything", nil); got {
+		t.Fatal("empty Words rule matched")
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	if _, err := NewPatterns("bad", []string{"("}); err == nil {
+		t.Fatal("NewPatterns() with invalid pattern: expected error")
+	}
+
+	p, err := NewPatterns("invites", []string{`(?i)discord\.gg/\w+`})
+	if err != nil {
+		t.Fatalf("NewPatterns() unexpected error: %v", err)
+	}
+
+	if _, got := p.Check("join us at discord.gg/abc", nil); !got {
+		t.Fatal("Check() did not match")
+	}
+
+	if _, got := p.Check("join us in #general", nil); got {
+		t.Fatal("Check() matched clean text")
+	}
+}
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		name    string
+		blocked []string
+		allowed []string
+		urls    []string
+		want    bool
+	}{
+		{
+			name:    "blocked",
+			blocked: []string{"example.com"},
+			urls:    []string{"https://example.com/x"},
+			want:    true,
+		},
+		{
+			name:    "blocked_subdomain",
+			blocked: []string{"example.com"},
+			urls:    []string{"https://WWW.Example.com/x"},
+			want:    true,
+		},
+		{
+			name:    "not_blocked",
+			blocked: []string{"example.com"},
+			urls:    []string{"https://golang.org", "https://notexample.com"},
+			want:    false,
+		},
+		{
+			name:    "allowed",
+			allowed: []string{"golang.org"},
+			urls:    []string{"https://play.golang.org/p/abc"},
+			want:    false,
+		},
+		{
+			name:    "not_allowed",
+			allowed: []string{"golang.org"},
+			urls:    []string{"https://golang.org", "https://example.com"},
+			want:    true,
+		},
+		{
+			name:    "blocked_wins",
+			blocked: []string{"play.golang.org"},
+			allowed: []string{"golang.org"},
+			urls:    []string{"https://play.golang.org/p/abc"},
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLinks("links", tt.blocked, tt.allowed)
+
+			if _, got := l.Check("", tt.urls); got != tt.want {
+				t.Fatalf("Check(%v) = %t, want %t", tt.urls, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,274 @@
+// Package remind provides a Client struct with MessageMatchFn and Handler
+// methods, which let users ask the bot to remind them, or the channel they're
+// in, about something later.
+package remind
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/reminders"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+)
+
+// Trigger is the command prefix.
+const Trigger = "!remind"
+
+// Usage is the help text for the command.
+const Usage = "Usage: `!remind <me|here> in <duration> <message>`, `!remind <me|here> at <time> <message>`, `!remind list`, or `!remind cancel <id>`. Durations look like `30m`, `2h`, or `1d12h`, and times like `15:04` (UTC) or `2020-06-01T15:04:00Z`."
+
+const (
+	// maxAhead is the furthest ahead a reminder can be set
+	maxAhead = 365 * 24 * time.Hour
+
+	// a user can set this many reminders per rateWindow
+	rateLimit  = 10
+	rateWindow = time.Hour
+)
+
+// Client is the reminders client.
+type Client struct {
+	s      *reminders.Store
+	logger zerolog.Logger
+}
+
+// New returns a reminders Client.
+func New(s *reminders.Store, logger zerolog.Logger) *Client {
+	return &Client{
+		s:      s,
+		logger: logger,
+	}
+}
+
+// MessageMatchFn satisfies handler.MessageMatchFn.
+func (c *Client) MessageMatchFn(_ bool, m handler.Messenger) bool {
+	text := strings.ToLower(m.Text())
+	return text == Trigger || strings.HasPrefix(text, Trigger+" ")
+}
+
+// Handler satisfies handler.MessageActionFn.
+func (c *Client) Handler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	// use the raw text so any mentions in the reminder are kept
+	text := strings.TrimSpace(m.RawText())
+	if !strings.HasPrefix(strings.ToLower(text), Trigger) {
+		return r.RespondTo(ctx, Usage)
+	}
+
+	fields := strings.Fields(text[len(Trigger):])
+	if len(fields) == 0 {
+		return r.RespondTo(ctx, Usage)
+	}
+
+	switch strings.ToLower(fields[0]) {
+	case "list":
+		return c.list(ctx, m, r)
+
+	case "cancel":
+		if len(fields) != 2 {
+			return r.RespondTo(ctx, Usage)
+		}
+
+		return c.cancel(ctx, m, r, fields[1])
+
+	case "me", "here":
+		return c.add(ctx, m, r, fields)
+
+	default:
+		return r.RespondTo(ctx, Usage)
+	}
+}
+
+func (c *Client) add(ctx workqueue.Context, m handler.Messenger, r handler.Responder, fields []string) error {
+	if len(fields) < 4 {
+		return r.RespondTo(ctx, Usage)
+	}
+
+	at, err := parseWhen(fields[1], fields[2], time.Now())
+	if err != nil {
+		return r.RespondTo(ctx, fmt.Sprintf("I couldn't work out when to remind you: %s. %s", err, Usage))
+	}
+
+	ok, err := ctx.Allow("remind:"+m.UserID(), rateLimit, rateWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check rate limit: %w", err)
+	}
+
+	if !ok {
+		return r.RespondTo(ctx, "You've set a lot of reminders recently, please try again later.")
+	}
+
+	rem := reminders.Reminder{
+		UserID:    m.UserID(),
+		ChannelID: m.UserID(),
+		Text:      strings.Join(fields[3:], " "),
+		At:        at,
+	}
+
+	if strings.EqualFold(fields[0], "here") {
+		rem.ChannelID = m.ChannelID()
+	}
+
+	rem, err = c.s.Add(ctx, rem)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Info().
+		Str("reminder_id", rem.ID).
+		Str("user_id", rem.UserID).
+		Str("channel_id", rem.ChannelID).
+		Time("at", rem.At).
+		Msg("reminder set")
+
+	return r.RespondTo(ctx, fmt.Sprintf("OK, I'll remind %s at %s (reminder %s).", target(rem), formatTime(rem.At), rem.ID))
+}
+
+func (c *Client) list(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	list, err := c.s.List(ctx, m.UserID())
+	if err != nil {
+		return err
+	}
+
+	if len(list) == 0 {
+		return r.RespondEphemeral(ctx, "You don't have any reminders set.")
+	}
+
+	var b strings.Builder
+
+	b.WriteString("Your reminders:\n")
+
+	for _, rem := range list {
+		fmt.Fprintf(&b, "• %s: %s, reminding %s: %s\n", rem.ID, formatTime(rem.At), target(rem), rem.Text)
+	}
+
+	return r.RespondEphemeral(ctx, b.String())
+}
+
+func (c *Client) cancel(ctx workqueue.Context, m handler.Messenger, r handler.Responder, id string) error {
+	if err := c.s.Cancel(ctx, m.UserID(), id); err != nil {
+		if errors.Is(err, reminders.ErrNotFound) {
+			return r.RespondTo(ctx, fmt.Sprintf("You don't have a reminder %s. Use `!remind list` to see your reminders.", id))
+		}
+
+		return err
+	}
+
+	return r.RespondTo(ctx, fmt.Sprintf("Reminder %s canceled.", id))
+}
+
+func target(r reminders.Reminder) string {
+	if r.DM() {
+		return "you"
+	}
+
+	return "<#" + r.ChannelID + ">"
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04 MST")
+}
+
+// parseWhen returns the time for `in <duration>` or `at <time>`, relative to
+// now.
+func parseWhen(kind, value string, now time.Time) (time.Time, error) {
+	var at time.Time
+
+	switch strings.ToLower(kind) {
+	case "in":
+		d, err := parseDuration(value)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		at = now.Add(d)
+
+	case "at":
+		t, err := parseTime(value, now)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		at = t
+
+	default:
+		return time.Time{}, fmt.Errorf("expected `in` or `at`, not %q", kind)
+	}
+
+	if !at.After(now) {
+		return time.Time{}, errors.New("that's in the past")
+	}
+
+	if at.Sub(now) > maxAhead {
+		return time.Time{}, errors.New("that's more than a year away")
+	}
+
+	return at, nil
+}
+
+// parseDuration is time.ParseDuration, but also accepts days (d) and weeks
+// (w), like 1w2d or 1d12h.
+func parseDuration(s string) (time.Duration, error) {
+	var d time.Duration
+
+	rest := strings.ToLower(s)
+
+	for _, u := range [...]struct {
+		suffix byte
+		unit   time.Duration
+	}{{'w', 7 * 24 * time.Hour}, {'d', 24 * time.Hour}} {
+		i := strings.IndexByte(rest, u.suffix)
+		if i == -1 {
+			continue
+		}
+
+		n, err := strconv.Atoi(rest[:i])
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("%q is not a duration", s)
+		}
+
+		d += time.Duration(n) * u.unit
+		rest = rest[i+1:]
+	}
+
+	if len(rest) > 0 {
+		pd, err := time.ParseDuration(rest)
+		if err != nil || pd < 0 {
+			return 0, fmt.Errorf("%q is not a duration", s)
+		}
+
+		d += pd
+	}
+
+	if d == 0 {
+		return 0, fmt.Errorf("%q is not a duration", s)
+	}
+
+	return d, nil
+}
+
+// parseTime parses an RFC 3339 time, or a time of day in UTC, which is the
+// next time it's that time of day.
+func parseTime(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, strings.ToUpper(s)); err == nil {
+		return t, nil
+	}
+
+	tod, err := time.Parse("15:04", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a time", s)
+	}
+
+	now = now.UTC()
+
+	t := time.Date(now.Year(), now.Month(), now.Day(), tod.Hour(), tod.Minute(), 0, 0, time.UTC)
+	if !t.After(now) {
+		t = t.AddDate(0, 0, 1)
+	}
+
+	return t, nil
+}
@@ -0,0 +1,107 @@
+package remind
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_parseWhen(t *testing.T) {
+	now := time.Date(2020, time.June, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		kind    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:  "in_minutes",
+			kind:  "in",
+			value: "30m",
+			want:  now.Add(30 * time.Minute),
+		},
+		{
+			name:  "in_days_hours",
+			kind:  "in",
+			value: "1d12h",
+			want:  now.Add(36 * time.Hour),
+		},
+		{
+			name:  "in_weeks_days",
+			kind:  "IN",
+			value: "1w2d",
+			want:  now.Add(9 * 24 * time.Hour),
+		},
+		{
+			name:  "at_later_today",
+			kind:  "at",
+			value: "15:04",
+			want:  time.Date(2020, time.June, 1, 15, 4, 0, 0, time.UTC),
+		},
+		{
+			name:  "at_tomorrow",
+			kind:  "at",
+			value: "09:00",
+			want:  time.Date(2020, time.June, 2, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:  "at_rfc3339",
+			kind:  "at",
+			value: "2020-06-03T10:00:00Z",
+			want:  time.Date(2020, time.June, 3, 10, 0, 0, 0, time.UTC),
+		},
+		{
+			name:    "past",
+			kind:    "at",
+			value:   "2020-05-01T10:00:00Z",
+			wantErr: true,
+		},
+		{
+			name:    "too_far",
+			kind:    "in",
+			value:   "400d",
+			wantErr: true,
+		},
+		{
+			name:    "bad_duration",
+			kind:    "in",
+			value:   "soon",
+			wantErr: true,
+		},
+		{
+			name:    "zero",
+			kind:    "in",
+			value:   "0d",
+			wantErr: true,
+		},
+		{
+			name:    "bad_kind",
+			kind:    "on",
+			value:   "monday",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWhen(tt.kind, tt.value, now)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseWhen() = %s, expected error", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseWhen() unexpected error: %v", err)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Fatalf("parseWhen() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -88,7 +88,7 @@ func injectMessageResponseFuncs(ma *handler.MessageActions) {
 			builder := &strings.Builder{}
 
 			for _, channel := range recommendedChannels {
-				c, notFound, err := ctx.ChannelSvc().Lookup(channel.name)
+				c, notFound, err := ctx.ChannelSvc().Lookup(ctx, channel.name)
 				if err != nil {
 					return fmt.Errorf("failed to look up channel: %w", err)
 				}
@@ -0,0 +1,150 @@
+// Package search provides a Client struct with MessageMatchFn and Handler
+// methods for the !search command, which searches the messages archived from
+// public channels.
+package search
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gobridge/gopherbot/archive"
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+// Trigger is the command prefix.
+const Trigger = "!search"
+
+// Usage is the help text for the command.
+const Usage = "Usage: `!search <words> [in:#channel] [from:@user]`. Use quotes for an exact phrase, and `-word` to exclude a word."
+
+const (
+	maxResults = 5
+
+	// snippetLength is the longest a result's text is shown as, in characters
+	snippetLength = 120
+
+	rateLimit  = 10
+	rateWindow = time.Minute
+)
+
+// Searcher is the interface for searching the archive. Generally this is
+// implemented by an *archive.Archive.
+type Searcher interface {
+	Search(ctx context.Context, q archive.Query) ([]archive.Result, error)
+}
+
+// Client is the search client.
+type Client struct {
+	s      Searcher
+	logger zerolog.Logger
+}
+
+// New returns a search Client.
+func New(s Searcher, logger zerolog.Logger) *Client {
+	return &Client{
+		s:      s,
+		logger: logger,
+	}
+}
+
+var (
+	inRE   = regexp.MustCompile(`(?i)(?:^|\s)in:<#([CG][A-Z0-9]+)(?:\|[^>]*)?>`)
+	fromRE = regexp.MustCompile(`(?i)(?:^|\s)from:<@([UW][A-Z0-9]+)(?:\|[^>]*)?>`)
+)
+
+// parseQuery returns the query for the text after the trigger, in the raw
+// message text so channel and user references can be found.
+func parseQuery(text string) archive.Query {
+	q := archive.Query{Limit: maxResults}
+
+	if m := inRE.FindStringSubmatch(text); m != nil {
+		q.ChannelID = m[1]
+		text = inRE.ReplaceAllString(text, " ")
+	}
+
+	if m := fromRE.FindStringSubmatch(text); m != nil {
+		q.UserID = m[1]
+		text = fromRE.ReplaceAllString(text, " ")
+	}
+
+	q.Text = strings.Join(strings.Fields(text), " ")
+
+	return q
+}
+
+// snippet returns the text on one line, shortened to snippetLength.
+func snippet(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+
+	if utf8.RuneCountInString(text) <= snippetLength {
+		return text
+	}
+
+	return string([]rune(text)[:snippetLength-1]) + "…"
+}
+
+// MessageMatchFn satisfies handler.MessageMatchFn.
+func (c *Client) MessageMatchFn(_ bool, m handler.Messenger) bool {
+	text := strings.ToLower(m.RawText())
+	return text == Trigger || strings.HasPrefix(text, Trigger+" ")
+}
+
+// Handler satisfies handler.MessageActionFn.
+func (c *Client) Handler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	q := parseQuery(m.RawText()[len(Trigger):])
+	if len(q.Text) == 0 {
+		return r.RespondTo(ctx, Usage)
+	}
+
+	ok, err := ctx.Allow("search:"+m.UserID(), rateLimit, rateWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check rate limit: %w", err)
+	}
+
+	if !ok {
+		return r.RespondTo(ctx, "You've searched a lot recently, please try again in a minute.")
+	}
+
+	results, err := c.s.Search(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	c.logger.Debug().
+		Str("user_id", m.UserID()).
+		Str("query", q.Text).
+		Int("results", len(results)).
+		Msg("archive searched")
+
+	if len(results) == 0 {
+		return r.RespondTo(ctx, "I couldn't find any messages matching that.")
+	}
+
+	b := &strings.Builder{}
+
+	b.WriteString("Here's what I found:\n")
+
+	for _, res := range results {
+		link, err := ctx.Slack().GetPermalinkContext(ctx, &slack.PermalinkParameters{Channel: res.ChannelID, Ts: res.TS})
+		if err != nil {
+			return fmt.Errorf("failed to get permalink for %s/%s: %w", res.ChannelID, res.TS, err)
+		}
+
+		fmt.Fprintf(b, "- <@%s> in <#%s> on %s: <%s|%s>\n", res.UserID, res.ChannelID, res.Posted.Format("2006-01-02"), link, escape(snippet(res.Text)))
+	}
+
+	return r.RespondTo(ctx, b.String())
+}
+
+// escape stops the snippet from breaking out of the link, as it's shown as the
+// link's label.
+func escape(s string) string {
+	return strings.NewReplacer("|", "¦", ">", "›", "<", "‹").Replace(s)
+}
@@ -0,0 +1,56 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gobridge/gopherbot/archive"
+)
+
+func Test_parseQuery(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want archive.Query
+	}{
+		{
+			name: "words",
+			text: " generics   proposal ",
+			want: archive.Query{Text: "generics proposal", Limit: maxResults},
+		},
+		{
+			name: "in_channel",
+			text: " modules in:<#C123ABC|general> vendor",
+			want: archive.Query{Text: "modules vendor", ChannelID: "C123ABC", Limit: maxResults},
+		},
+		{
+			name: "from_user",
+			text: ` "go mod" from:<@U456DEF>`,
+			want: archive.Query{Text: `"go mod"`, UserID: "U456DEF", Limit: maxResults},
+		},
+		{
+			name: "only_filters",
+			text: " in:<#C123ABC>",
+			want: archive.Query{ChannelID: "C123ABC", Limit: maxResults},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseQuery(tt.text); got != tt.want {
+				t.Fatalf("parseQuery(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_snippet(t *testing.T) {
+	if got := snippet("line one\n  line   two"); got != "line one line two" {
+		t.Fatalf("snippet() = %q", got)
+	}
+
+	long := strings.Repeat("é", snippetLength+10)
+	if got := []rune(snippet(long)); len(got) != snippetLength {
+		t.Fatalf("snippet() is %d characters, want %d", len(got), snippetLength)
+	}
+}
@@ -0,0 +1,210 @@
+// Package spam provides a Client struct with MessageMatchFn and Handler
+// methods, which look for members flooding public channels, posting the same
+// message across channels, or mass-mentioning people. Flagged members are
+// published as workqueue.Moderation events, for the moderation handlers to act
+// on.
+package spam
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/mparser"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+)
+
+// Detector is the name of the detector in the ModerationEvents it publishes.
+const Detector = "spam"
+
+// The kinds of spam detected.
+const (
+	KindFlood       = "flood"
+	KindDuplicate   = "duplicate"
+	KindMassMention = "mass_mention"
+)
+
+const (
+	// storeNamespace is the ctx.Store() namespace the message counters are
+	// kept in
+	storeNamespace = "spam"
+
+	redisDuplicatesPrefix = "spam:duplicates:"
+
+	// minDuplicateLength is the shortest message checked for duplicates, so
+	// people saying "thanks!" in a few channels aren't flagged
+	minDuplicateLength = 20
+)
+
+// Config is the detector thresholds.
+type Config struct {
+	// MaxMessages is the most messages a member can post in public channels
+	// within Window before they're flagged for flooding.
+	MaxMessages int64
+	Window      time.Duration
+
+	// MaxDuplicateChannels is the most channels a member can post the same
+	// message in within DuplicateWindow before they're flagged.
+	MaxDuplicateChannels int64
+	DuplicateWindow      time.Duration
+
+	// MaxMentions is the most users, groups, or @here / @channel mentions a
+	// message can have before it's flagged.
+	MaxMentions int
+}
+
+// DefaultConfig is the default Config.
+var DefaultConfig = Config{
+	MaxMessages:          10,
+	Window:               30 * time.Second,
+	MaxDuplicateChannels: 3,
+	DuplicateWindow:      10 * time.Minute,
+	MaxMentions:          8,
+}
+
+// Client is the spam detection client.
+type Client struct {
+	r      redis.UniversalClient
+	q      workqueue.Publisher
+	logger zerolog.Logger
+	cfg    Config
+}
+
+// New returns a spam detection Client, which publishes flagged members to q.
+func New(rc redis.UniversalClient, q workqueue.Publisher, logger zerolog.Logger, cfg Config) *Client {
+	return &Client{
+		r:      rc,
+		q:      q,
+		logger: logger,
+		cfg:    cfg,
+	}
+}
+
+// MessageMatchFn satisfies handler.MessageMatchFn. It matches all messages in
+// public channels.
+func (c *Client) MessageMatchFn(_ bool, m handler.Messenger) bool {
+	return m.ChannelType() == handler.ChannelPublic
+}
+
+// Handler satisfies handler.MessageActionFn.
+func (c *Client) Handler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	var events []workqueue.ModerationEvent
+
+	if n := mentionCount(m.AllMentions()); n > c.cfg.MaxMentions {
+		events = append(events, c.event(m, KindMassMention, fmt.Sprintf("message mentions %d users or groups", n)))
+	}
+
+	// only flag floods once per window, instead of for every message after
+	// the limit
+	n, err := ctx.Store().Namespace(storeNamespace).Incr(ctx, "messages:"+m.UserID(), c.cfg.Window)
+	if err != nil {
+		return fmt.Errorf("failed to count messages: %w", err)
+	}
+
+	if n == c.cfg.MaxMessages+1 {
+		events = append(events, c.event(m, KindFlood, fmt.Sprintf("posted more than %d messages in %s", c.cfg.MaxMessages, c.cfg.Window)))
+	}
+
+	if text := normalize(m.Text()); len(text) >= minDuplicateLength {
+		channels, err := c.duplicates(ctx, m.UserID(), m.ChannelID(), text)
+		if err != nil {
+			return err
+		}
+
+		if int64(len(channels)) == c.cfg.MaxDuplicateChannels+1 {
+			e := c.event(m, KindDuplicate, fmt.Sprintf("posted the same message in %d channels", len(channels)))
+			e.Channels = channels
+
+			events = append(events, e)
+		}
+	}
+
+	for _, e := range events {
+		if err := c.publish(ctx, m, e); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Client) event(m handler.Messenger, kind, reason string) workqueue.ModerationEvent {
+	return workqueue.ModerationEvent{
+		Detector:  Detector,
+		Kind:      kind,
+		Reason:    reason,
+		UserID:    m.UserID(),
+		ChannelID: m.ChannelID(),
+		MessageTS: m.MessageTS(),
+	}
+}
+
+// duplicates adds the channel to those the user posted the text in, and
+// returns them all.
+func (c *Client) duplicates(ctx context.Context, userID, channelID, text string) ([]string, error) {
+	sum := sha1.Sum([]byte(text))
+	key := redisDuplicatesPrefix + userID + ":" + hex.EncodeToString(sum[:])
+
+	pipe := c.r.TxPipeline()
+	pipe.SAdd(ctx, key, channelID)
+	pipe.Expire(ctx, key, c.cfg.DuplicateWindow)
+	members := pipe.SMembers(ctx, key)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to track duplicate messages: %w", err)
+	}
+
+	return members.Val(), nil
+}
+
+func (c *Client) publish(ctx context.Context, m handler.Messenger, e workqueue.ModerationEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal moderation event: %w", err)
+	}
+
+	c.logger.Info().
+		Str("kind", e.Kind).
+		Str("user_id", e.UserID).
+		Str("channel_id", e.ChannelID).
+		Str("message_ts", e.MessageTS).
+		Str("reason", e.Reason).
+		Msg("member flagged for spam")
+
+	id := Detector + ":" + e.Kind + ":" + e.UserID + ":" + e.MessageTS
+
+	if err := c.q.Publish(ctx, workqueue.Moderation, workqueue.PriorityNormal, time.Now().Unix(), id, id, data); err != nil {
+		return fmt.Errorf("failed to publish moderation event: %w", err)
+	}
+
+	return nil
+}
+
+// mentionCount returns the number of distinct users and groups mentioned,
+// counting @here, @channel, and @everyone as one each.
+func mentionCount(mentions []mparser.Mention) int {
+	seen := make(map[mparser.Mention]struct{}, len(mentions))
+
+	for _, mn := range mentions {
+		if mn.Type == mparser.TypeChannelRef {
+			continue
+		}
+
+		seen[mparser.Mention{Type: mn.Type, ID: mn.ID}] = struct{}{}
+	}
+
+	return len(seen)
+}
+
+// normalize returns the text with case and whitespace differences removed, so
+// trivially changed copies of a message are still duplicates.
+func normalize(text string) string {
+	return strings.ToLower(strings.Join(strings.Fields(text), " "))
+}
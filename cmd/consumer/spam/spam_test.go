@@ -0,0 +1,33 @@
+package spam
+
+import (
+	"testing"
+
+	"github.com/gobridge/gopherbot/mparser"
+)
+
+func Test_mentionCount(t *testing.T) {
+	mentions := []mparser.Mention{
+		{Type: mparser.TypeUser, ID: "U1"},
+		{Type: mparser.TypeUser, ID: "U1", Label: "someone"},
+		{Type: mparser.TypeUser, ID: "U2"},
+		{Type: mparser.TypeGroup, ID: "S1"},
+		{Type: mparser.TypeHere},
+		{Type: mparser.TypeHere},
+		{Type: mparser.TypeChannelRef, ID: "C1"},
+	}
+
+	if got := mentionCount(mentions); got != 4 {
+		t.Fatalf("mentionCount() = %d, want 4", got)
+	}
+
+	if got := mentionCount(nil); got != 0 {
+		t.Fatalf("mentionCount(nil) = %d, want 0", got)
+	}
+}
+
+func Test_normalize(t *testing.T) {
+	if got, want := normalize("  Check OUT\n my   site "), "check out my site"; got != want {
+		t.Fatalf("normalize() = %q, want %q", got, want)
+	}
+}
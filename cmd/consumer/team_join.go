@@ -1,31 +1,50 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/onboarding"
 	"github.com/gobridge/gopherbot/workqueue"
 )
 
-func injectTeamJoinHandlers(t *handler.TeamJoinActions) {
-	t.Handle("new members",
-		func(ctx workqueue.Context, tj handler.TeamJoiner, r handler.Responder) error {
-			wmsg, err := welcomeMessage(recommendedChannels, ctx.ChannelSvc(), ctx.Self().ID, ctx.Self().Name)
-			if err != nil {
-				return fmt.Errorf("failed to generate welcome message: %w", err)
-			}
-
-			ctx.Logger().Debug().
-				Str("user_id", tj.User().ID).
-				Str("user_email", tj.User().Profile.Email).
-				Time("joined_time", ctx.Meta().Time).
-				Int("msg_len", len(wmsg)).
-				Msg("welcoming user")
+func injectTeamJoinHandlers(t *handler.TeamJoinActions, ob *onboarding.Pipeline) {
+	t.Handle("onboarding", ob.TeamJoinAction)
+}
 
-			return r.RespondDM(ctx, wmsg)
+// onboardingSteps are the DMs sent to new members, spread out a little so the
+// rules and channel suggestions aren't lost in one long message.
+func onboardingSteps() []onboarding.Step {
+	return []onboarding.Step{
+		{
+			Name: "welcome",
+			Message: func(ctx workqueue.Context, userID string) (string, error) {
+				ctx.Logger().Debug().
+					Str("user_id", userID).
+					Time("joined_time", ctx.Meta().Time).
+					Msg("welcoming user")
+
+				return welcomeMessage(ctx.Self().ID, ctx.Self().Name), nil
+			},
 		},
-	)
+		{
+			Name:  "rules",
+			Delay: 5 * time.Minute,
+			Message: func(ctx workqueue.Context, _ string) (string, error) {
+				return rulesMessage(ctx, ctx.ChannelSvc())
+			},
+		},
+		{
+			Name:  "channels",
+			Delay: time.Hour,
+			Message: func(ctx workqueue.Context, _ string) (string, error) {
+				return channelsMessage(ctx, recommendedChannels, ctx.ChannelSvc())
+			},
+		},
+	}
 }
 
 const (
@@ -33,14 +52,31 @@ const (
 	sausheongID = "U03QZHXD8"
 )
 
-func welcomeMessage(channels []recommendedChannel, cs workqueue.ChannelSvc, selfID, selfName string) (string, error) {
+func welcomeMessage(selfID, selfName string) string {
+	return fmt.Sprintf(teamJoinWelcomeMessageFormat, selfID, selfName, bkennedyID, sausheongID)
+}
+
+func rulesMessage(ctx context.Context, cs workqueue.ChannelSvc) (string, error) {
+	ch, notFound, err := cs.Lookup(ctx, "admin-help")
+	if err != nil {
+		return "", fmt.Errorf("failed to look up channel: %w", err)
+	}
+
+	if notFound {
+		return "", fmt.Errorf("admin-help channel not found")
+	}
+
+	return fmt.Sprintf(teamJoinRulesMessageFormat, ch.ID), nil
+}
+
+func channelsMessage(ctx context.Context, channels []recommendedChannel, cs workqueue.ChannelSvc) (string, error) {
 	b := &strings.Builder{}
 
-	var generalID, adminHelpID string
+	var generalID string
 
 	for _, c := range channels {
 		if c.welcome {
-			ch, notFound, err := cs.Lookup(c.name)
+			ch, notFound, err := cs.Lookup(ctx, c.name)
 			if err != nil {
 				return "", fmt.Errorf("failed to look up channel: %w", err)
 			}
@@ -49,11 +85,8 @@ func welcomeMessage(channels []recommendedChannel, cs workqueue.ChannelSvc, self
 				continue // weird...
 			}
 
-			switch c.name {
-			case "general":
+			if c.name == "general" {
 				generalID = ch.ID
-			case "admin-help":
-				adminHelpID = ch.ID
 			}
 
 			fmt.Fprintf(b, "- <#%s> -> %s\n", ch.ID, c.desc)
@@ -61,7 +94,7 @@ func welcomeMessage(channels []recommendedChannel, cs workqueue.ChannelSvc, self
 		}
 	}
 
-	return fmt.Sprintf(teamJoinWelcomeMessageFormat, adminHelpID, selfID, selfName, generalID, b.String(), bkennedyID, sausheongID), nil
+	return fmt.Sprintf(teamJoinChannelsMessageFormat, generalID, b.String()), nil
 }
 
 // because of the usage of backticks and quotes in the welcome message, this
@@ -70,8 +103,6 @@ func welcomeMessage(channels []recommendedChannel, cs workqueue.ChannelSvc, self
 // maybe it would be easier to read if it were a slice of strings?
 const teamJoinWelcomeMessageFormat = `Welcome to the Gophers Slack Workspace! This space is meant to connect gophers from all over the world in a central place. I am the community chat bot, and do have a few functions available to help you during your time here. :simple_smile:
 
-Before getting started, we ask that you take a look at the rules all members are expected to follow: <http://coc.golangbridge.org>. If you ever need help from our workspace's community moderators or administrators, please reach out in <#%s>.
-
 If you'd like to learn more about the functions I offer, please send me the ` + " `help` " + `command. You can send commands to me via a DM (like this one), or by mentioning me (<@%s>) in one of the main public channels:
 
 ` + "```" + `
@@ -80,15 +111,6 @@ If you'd like to learn more about the functions I offer, please send me the ` +
 
 There is also a forum <https://forum.golangbridge.org>, which you might want to check it out as well if a Forum is more your style.
 
-<#%s> can sometimes seem busy sometimes, but please don't hesitate to ask your Go related questions there. To share code while asking a question, you should use: <https://play.golang.org/> as it makes it easy for others to help you.
-
-Here's a list of a few other channels you could join:
-%s
-
-If you want more channel suggestions, type` + " `recommended channels` " + `in a direct message to me.
-
-There are quite a few other channels, depending on your interests or location (we have city / country wide channels). Just click on the :heavy_plus_sign: next to the channel list in the sidebar, and click Browse Channels to search for anything that interests you.
-
 If you are new to Go and want a copy of the Go In Action book, <https://www.manning.com/books/go-in-action>, please send an email to <@%s> at bill@ardanlabs.com
 
 If you are interested in a free copy of the Go Web Programming book by Sau Sheong Chang, <@%s>, please send him an email at sausheong@gmail.com
@@ -96,3 +118,14 @@ If you are interested in a free copy of the Go Web Programming book by Sau Sheon
 In case you want to customize your profile picture, you can use <https://gopherize.me/> to create a custom gopher.
 
 Now, enjoy the community and have fun! :gopher:`
+
+const teamJoinRulesMessageFormat = `Before getting started, we ask that you take a look at the rules all members are expected to follow: <http://coc.golangbridge.org>. If you ever need help from our workspace's community moderators or administrators, please reach out in <#%s>.`
+
+const teamJoinChannelsMessageFormat = `<#%s> can sometimes seem busy sometimes, but please don't hesitate to ask your Go related questions there. To share code while asking a question, you should use: <https://play.golang.org/> as it makes it easy for others to help you.
+
+Here's a list of a few other channels you could join:
+%s
+
+If you want more channel suggestions, type` + " `recommended channels` " + `in a direct message to me.
+
+There are quite a few other channels, depending on your interests or location (we have city / country wide channels). Just click on the :heavy_plus_sign: next to the channel list in the sidebar, and click Browse Channels to search for anything that interests you.`
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// replayTimeout is how long a replay request has to publish its events
+const replayTimeout = 20 * time.Second
+
+// replayer replays events from a stream to a consumer group, like
+// *workqueue.I.
+type replayer interface {
+	Replay(ctx context.Context, stream, start, end, group string) (int, error)
+}
+
+type replayRequest struct {
+	Stream string `json:"stream"`
+	Start  string `json:"start"`
+	End    string `json:"end"`
+	Group  string `json:"group"`
+}
+
+type replayResponse struct {
+	Replayed int    `json:"replayed"`
+	Error    string `json:"error,omitempty"`
+}
+
+// replayHandler serves the admin API's /admin/replay, which re-publishes
+// events from a stream so one consumer group handles them again.
+type replayHandler struct {
+	q replayer
+	l zerolog.Logger
+}
+
+func (h replayHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req replayRequest
+
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&req); err != nil {
+		h.respond(w, http.StatusBadRequest, replayResponse{Error: "failed to decode request: " + err.Error()})
+		return
+	}
+
+	if len(req.Stream) == 0 || len(req.Group) == 0 {
+		h.respond(w, http.StatusBadRequest, replayResponse{Error: "stream and group are required"})
+		return
+	}
+
+	if len(req.Start) == 0 {
+		req.Start = "-"
+	}
+
+	if len(req.End) == 0 {
+		req.End = "+"
+	}
+
+	logger := h.l.With().
+		Str("redis_stream", req.Stream).
+		Str("start", req.Start).
+		Str("end", req.End).
+		Str("consumer_group", req.Group).
+		Logger()
+
+	ctx, cancel := context.WithTimeout(r.Context(), replayTimeout)
+	defer cancel()
+
+	n, err := h.q.Replay(ctx, req.Stream, req.Start, req.End, req.Group)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Int("replayed", n).
+			Msg("failed to replay events")
+
+		h.respond(w, http.StatusInternalServerError, replayResponse{Replayed: n, Error: err.Error()})
+
+		return
+	}
+
+	logger.Info().
+		Int("replayed", n).
+		Msg("replayed events")
+
+	h.respond(w, http.StatusOK, replayResponse{Replayed: n})
+}
+
+func (h replayHandler) respond(w http.ResponseWriter, status int, resp replayResponse) {
+	body, err := json.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/valyala/fastjson"
+)
+
+func (s *handler) handleSlashCommand(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lc := s.l.With().Str("context", "command_handler")
+
+	rid, ok := ctxRequestID(ctx)
+	if ok {
+		lc = lc.Str("request_id", rid)
+	}
+
+	logger := lc.Logger()
+
+	if r.Method != http.MethodPost {
+		logger.Info().
+			Str("http_method", r.Method).
+			Msg("unexpected HTTP method")
+
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to parse Content-Type")
+
+		w.Header().Set("Accept", "application/x-www-form-urlencoded")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if mt != "application/x-www-form-urlencoded" {
+		logger.Error().
+			Str("content_type", mt).
+			Msg("content type was not a form")
+
+		w.Header().Set("Accept", "application/x-www-form-urlencoded")
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(r.Body, maxBodySize))
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to read request body")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	if status := s.publishSlashCommand(ctx, logger, rid, requestPayload(r, body)); status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+}
+
+// publishSlashCommand publishes the slash command, as a JSON document, to the
+// workqueue, returning the HTTP status to respond to Slack with.
+func (s *handler) publishSlashCommand(ctx context.Context, logger zerolog.Logger, rid string, payload []byte) int {
+	document, err := fastjson.ParseBytes(payload)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to unmarshal JSON payload")
+
+		s.reportError(err, rid, workqueue.FailureParse)
+
+		return http.StatusUnprocessableEntity
+	}
+
+	command, err := getJSONString(document, "command")
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to parse values from JSON payload")
+
+		s.reportError(err, rid, workqueue.FailureParse)
+
+		return http.StatusUnprocessableEntity
+	}
+
+	logger = logger.With().Str("command", command).Logger()
+
+	s.m.observeEvent(workqueue.SlackSlashCommand)
+
+	// so the consumers use the bot token for the workspace it's from
+	teamID, _ := requestTeamID(document)
+	ctx = workqueue.WithTeamID(ctx, teamID)
+
+	// there's no event ID, but the trigger ID is unique to the invocation
+	triggerID, _ := getJSONString(document, "trigger_id")
+	now := time.Now().Unix()
+
+	// someone ran a command, and is waiting for the result. The empty response
+	// acknowledges it, and the consumer responds using the response_url.
+	err = s.o.publish(ctx, workqueue.SlackSlashCommand, workqueue.PriorityHigh, now, triggerID, rid, payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to publish slash command to workqueue")
+		s.reportError(err, rid, failurePublish)
+		return http.StatusInternalServerError
+	}
+
+	logger.Debug().
+		Int64("event_timestamp", now).
+		Str("trigger_id", triggerID).
+		Msg("published slash command")
+
+	return http.StatusOK
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/internal/discord"
+	"github.com/gobridge/gopherbot/internal/leader"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+)
+
+// redisDiscordLockKey is the leader lock for the Discord gateway connection
+const redisDiscordLockKey = "gateway:discord:lock"
+
+// discordPublisher publishes the Discord gateway's events through the outbox.
+type discordPublisher struct{ o *outbox }
+
+func (p discordPublisher) Publish(ctx context.Context, e workqueue.Event, pr workqueue.Priority, eventTimestamp int64, eventID, requestID string, data []byte) error {
+	return p.o.publish(ctx, e, pr, eventTimestamp, eventID, requestID, data)
+}
+
+// runDiscord keeps the Discord gateway connected while this dyno is the
+// elected leader, as every connection gets every event and running more than
+// one gateway would publish each of them more than once. The returned channel
+// is closed once it has stopped, after ctx is canceled.
+func runDiscord(ctx context.Context, cfg config.C, o *outbox, rc redis.UniversalClient, logger zerolog.Logger) (<-chan struct{}, error) {
+	g, err := discord.New(discord.Config{
+		Token:     cfg.DiscordBotToken,
+		Publisher: discordPublisher{o: o},
+		Logger:    logger,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build discord gateway: %w", err)
+	}
+
+	e, err := leader.New(leader.Config{
+		RedisClient: rc,
+		Logger:      logger.With().Str("context", "discord_leader").Logger(),
+		Key:         redisDiscordLockKey,
+		ID:          cfg.Heroku.DynoID,
+		OnElected: func(ctx context.Context) {
+			err := g.Run(ctx)
+			if errors.Is(err, discord.ErrFatal) {
+				// reconnecting won't help, and neither would another dyno
+				// taking over, so wait to lose leadership
+				logger.Error().
+					Err(err).
+					Msg("discord gateway stopped; fix the bot's token or intents and restart")
+
+				<-ctx.Done()
+			}
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build discord gateway leader elector: %w", err)
+	}
+
+	return e.Run(ctx), nil
+}
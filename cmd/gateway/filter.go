@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/gobridge/gopherbot/config"
+	"github.com/valyala/fastjson"
+)
+
+// eventFilter drops known-noise events before they are published, so they
+// never use queue capacity or handler time.
+type eventFilter struct {
+	dropBotMessages bool
+	dropApps        map[string]struct{}
+	ignoreChannels  map[string]struct{}
+}
+
+func toSet(l []string) map[string]struct{} {
+	m := make(map[string]struct{}, len(l))
+
+	for _, v := range l {
+		m[v] = struct{}{}
+	}
+
+	return m
+}
+
+func newEventFilter(cfg config.F) eventFilter {
+	return eventFilter{
+		dropBotMessages: cfg.DropBotMessages,
+		dropApps:        toSet(cfg.DropApps),
+		ignoreChannels:  toSet(cfg.IgnoreChannels),
+	}
+}
+
+// drop returns true, with the reason why, if the event should not be
+// published.
+func (f eventFilter) drop(event *fastjson.Value) (string, bool) {
+	if f.dropBotMessages {
+		if st, _ := getJSONString(event, "subtype"); st == "bot_message" {
+			return "bot_message subtype", true
+		}
+	}
+
+	if len(f.dropApps) > 0 {
+		for _, k := range []string{"app_id", "bot_id"} {
+			id, _ := getJSONString(event, k)
+			if _, ok := f.dropApps[id]; ok && len(id) > 0 {
+				return "from dropped app " + id, true
+			}
+		}
+	}
+
+	if len(f.ignoreChannels) > 0 {
+		// message and member_joined_channel use channel, file_shared uses
+		// channel_id
+		for _, k := range []string{"channel", "channel_id"} {
+			id, _ := getJSONString(event, k)
+			if _, ok := f.ignoreChannels[id]; ok && len(id) > 0 {
+				return "in ignored channel " + id, true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gobridge/gopherbot/config"
+	"github.com/rs/zerolog"
+	"github.com/valyala/fastjson"
+)
+
+func TestEventFilter_drop(t *testing.T) {
+	cfg := config.F{
+		DropBotMessages: true,
+		DropApps:        []string{"A123", "B123"},
+		IgnoreChannels:  []string{"C123"},
+	}
+
+	tests := []struct {
+		name  string
+		cfg   config.F
+		event string
+		want  bool
+	}{
+		{
+			name:  "message",
+			cfg:   cfg,
+			event: `{"type":"message","channel":"C456","user":"U123","text":"hi"}`,
+		},
+		{
+			name:  "bot_message",
+			cfg:   cfg,
+			event: `{"type":"message","subtype":"bot_message","channel":"C456","bot_id":"B456"}`,
+			want:  true,
+		},
+		{
+			name:  "bot_message_kept",
+			cfg:   config.F{},
+			event: `{"type":"message","subtype":"bot_message","channel":"C456","bot_id":"B456"}`,
+		},
+		{
+			name:  "other_subtype",
+			cfg:   cfg,
+			event: `{"type":"message","subtype":"channel_join","channel":"C456","user":"U123"}`,
+		},
+		{
+			name:  "app_id",
+			cfg:   cfg,
+			event: `{"type":"message","channel":"C456","app_id":"A123"}`,
+			want:  true,
+		},
+		{
+			name:  "bot_id",
+			cfg:   cfg,
+			event: `{"type":"message","channel":"C456","bot_id":"B123"}`,
+			want:  true,
+		},
+		{
+			name:  "other_app",
+			cfg:   cfg,
+			event: `{"type":"message","channel":"C456","app_id":"A456","bot_id":"B456"}`,
+		},
+		{
+			name:  "channel",
+			cfg:   cfg,
+			event: `{"type":"member_joined_channel","channel":"C123","user":"U123"}`,
+			want:  true,
+		},
+		{
+			name:  "channel_id",
+			cfg:   cfg,
+			event: `{"type":"file_shared","channel_id":"C123","file_id":"F123"}`,
+			want:  true,
+		},
+		{
+			name:  "other_channel",
+			cfg:   cfg,
+			event: `{"type":"file_shared","channel_id":"C456","file_id":"F123"}`,
+		},
+		{
+			name:  "empty_ids",
+			cfg:   config.F{DropApps: []string{""}, IgnoreChannels: []string{""}},
+			event: `{"type":"message","channel":"","app_id":"","user":"U123"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := fastjson.Parse(tt.event)
+			if err != nil {
+				t.Fatalf("failed to parse event: %v", err)
+			}
+
+			reason, got := newEventFilter(tt.cfg).drop(event)
+			if got != tt.want {
+				t.Fatalf("drop() = %t (%q), want %t", got, reason, tt.want)
+			}
+
+			if got && len(reason) == 0 {
+				t.Fatal("drop() returned no reason")
+			}
+		})
+	}
+}
+
+func TestHandler_reloadRules(t *testing.T) {
+	l := zerolog.Nop()
+
+	hnd := &handler{l: &l, f: newEventFilter(config.F{})}
+
+	event := fastjson.MustParse(`{"type":"message","channel":"C123","user":"U123"}`)
+
+	cur := config.C{Filter: config.F{IgnoreChannels: []string{"C123"}}}
+
+	hnd.reloadRules(config.C{}, cur)
+
+	f, _ := hnd.rules()
+
+	if _, got := f.drop(event); !got {
+		t.Fatal("drop() = false after reload, want true")
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -10,40 +11,88 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/installs"
+	"github.com/gobridge/gopherbot/internal/errreport"
 	"github.com/gobridge/gopherbot/internal/heartbeat"
+	"github.com/gobridge/gopherbot/internal/keyring"
+	"github.com/gobridge/gopherbot/internal/redisutil"
+	"github.com/gobridge/gopherbot/internal/shutdown"
 	"github.com/gobridge/gopherbot/workqueue"
 	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
 )
 
-func runServer(cfg config.C, logger zerolog.Logger) error {
-	// set up signal catching
-	signalCh := make(chan os.Signal, 1)
-	signal.Notify(signalCh, syscall.SIGTERM, syscall.SIGINT)
+// Server is the gateway server. It's built with the Slack routes already
+// registered, and more can be added with Handle before it's started.
+type Server struct {
+	cfg    config.C
+	logger zerolog.Logger
+
+	rc     redis.UniversalClient
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	hnd *handler
+	o   *outbox
+	sr  *errreport.Sentry
+	m   *metrics
+	rd  *readiness
+	mm  *maintenance
+	mux *http.ServeMux
+
+	httpSrvr    *http.Server
+	serveStop   chan struct{}
+	socketStop  chan struct{}
+	discordStop <-chan struct{}
+	outboxStop  chan struct{}
+	serveErr    error
+}
 
+// NewServer returns a *Server, having connected to Redis and set up the
+// workqueue and the built-in routes.
+func NewServer(cfg config.C, logger zerolog.Logger) (*Server, error) {
 	logger.Info().
 		Str("env", string(cfg.Env)).
-		Str("app", cfg.Heroku.AppName).
-		Str("dyno_id", cfg.Heroku.DynoID).
 		Str("commit", cfg.Heroku.Commit).
 		Str("slack_request_token", cfg.Slack.RequestToken).
 		Str("slack_client_id", cfg.Slack.ClientID).
 		Str("log_level", cfg.LogLevel.String()).
+		Bool("socket_mode", cfg.Slack.SocketMode).
+		Bool("maintenance_mode", cfg.MaintenanceMode).
 		Msg("configuration values")
 
-	rc := redis.NewClient(config.DefaultRedis(cfg))
-	defer func() { _ = rc.Close() }()
+	rc := redisutil.ConnectRedis(cfg.Redis)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	defer cancel()
+	s := &Server{
+		cfg:    cfg,
+		logger: logger,
+		rc:     rc,
+		ctx:    ctx,
+		cancel: cancel,
+		rd:     &readiness{},
+		mux:    http.NewServeMux(),
+	}
+
+	if err := s.setUp(); err != nil {
+		s.close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Server) setUp() error {
+	cfg, logger := s.cfg, s.logger
 
 	lhb := logger.With().Str("context", "heartbeater").Logger()
 
 	// start checking Redis health
-	_, err := heartbeat.New(ctx, heartbeat.Config{
-		RedisClient: rc,
+	_, err := heartbeat.New(s.ctx, heartbeat.Config{
+		RedisClient: s.rc,
 		Logger:      lhb,
 		AppName:     cfg.Heroku.AppName,
 		UID:         cfg.Heroku.DynoID,
@@ -62,43 +111,194 @@ func runServer(cfg config.C, logger zerolog.Logger) error {
 		return fmt.Errorf("failed to heartbeat: %w", err)
 	}
 
+	// report failures to Sentry, if it's configured
+	var er workqueue.ErrorReporter
+
+	if len(cfg.SentryDSN) > 0 {
+		sr, err := errreport.NewSentry(errreport.Config{
+			DSN:         cfg.SentryDSN,
+			Environment: string(cfg.Env),
+			Release:     cfg.Heroku.Commit,
+			ServerName:  cfg.Heroku.DynoID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set up error reporting: %w", err)
+		}
+
+		s.sr, er = sr, sr
+	}
+
 	// set up the workqueue
+	codec := workqueue.JSONCodec
+
+	if len(cfg.WorkqueueCodec) > 0 {
+		c, ok := workqueue.CodecByName(cfg.WorkqueueCodec)
+		if !ok {
+			return fmt.Errorf("unknown workqueue codec %q", cfg.WorkqueueCodec)
+		}
+
+		codec = c
+	}
+
+	// the installations, and the event payloads if they're encrypted, use the
+	// token encryption keys
+	var (
+		kr          *keyring.Keyring
+		payloadKeys workqueue.Keyring
+	)
+
+	if len(cfg.TokenEncryptionKey) > 0 {
+		if kr, err = keyring.Parse(cfg.TokenEncryptionKey, cfg.TokenEncryptionOldKeys...); err != nil {
+			return fmt.Errorf("failed to parse token encryption keys: %w", err)
+		}
+
+		payloadKeys = kr
+	}
+
+	m, err := newMetrics(s.rc)
+	if err != nil {
+		return fmt.Errorf("failed to build metrics: %w", err)
+	}
+
 	q, err := workqueue.New(workqueue.Config{
 		ConsumerName:      cfg.Heroku.DynoID,
 		ConsumerGroup:     cfg.Heroku.AppName,
 		VisibilityTimeout: 10 * time.Second,
-		RedisClient:       rc,
-		Logger:            &logger,
+		RedisClient:       s.rc,
+		Logger:            &s.logger,
+		Codec:             codec,
+		Compression:       cfg.WorkqueueCompression,
+		Keyring:           payloadKeys,
+		EncryptPayloads:   cfg.WorkqueueEncryption,
+		Metrics:           m.workqueueStats,
+		ErrorReporter:     er,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to build workqueue: %w", err)
 	}
 
-	// set up the handler
-	hnd := handler{
-		l: &logger,
-		q: q,
+	// set up the handler, which publishes through the outbox so events
+	// survive Redis being briefly unavailable
+	o := newOutbox(q, logger.With().Str("context", "outbox").Logger(), m)
+
+	hnd := &handler{
+		l: &s.logger,
+		o: o,
+		f: newEventFilter(cfg.Filter),
+		p: newPrioritizer(cfg.PriorityChannels),
+		m: m,
+		r: er,
+	}
+
+	s.hnd, s.o, s.m = hnd, o, m
+
+	// reload the settings that can change on SIGHUP, or when the config file
+	// changes
+	cw := config.NewWatcher(cfg, logger.With().Str("context", "config_watcher").Logger())
+	cw.Subscribe(config.LogLevelSubscriber(logger))
+	cw.Subscribe(hnd.reloadRules)
+	cw.Run(s.ctx)
+
+	// the bot token is only used to check that Slack auth works
+	var sc *slack.Client
+	if len(cfg.Slack.BotAccessToken) > 0 {
+		sc = slack.New(cfg.Slack.BotAccessToken, slack.OptionHTTPClient(&http.Client{Timeout: healthCheckTimeout}))
 	}
 
+	hc := newHealthChecker(s.rc, q, sc, logger.With().Str("context", "health_check").Logger())
+
+	s.mm = newMaintenance(s.rc, cfg.MaintenanceMode, logger.With().Str("context", "maintenance").Logger())
+	go s.mm.watch(s.ctx)
+
 	// set up the router
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", hnd.handleNotFound)
-	mux.HandleFunc("/_ruok", hnd.handleRUOK)
+	s.mux.HandleFunc("/", hnd.handleNotFound)
+	s.mux.HandleFunc("/_ruok", hnd.handleRUOK)
+	s.mux.HandleFunc("/_healthz", hc.handleHealthz)
+	s.mux.HandleFunc("/_ready", s.rd.handleReady)
+	s.mux.HandleFunc("/_live", s.rd.handleLive)
+	s.mux.Handle("/metrics", m.handler(cfg.MetricsToken))
+
+	// the admin API is only served with a token to authenticate it
+	if len(cfg.AdminAPIToken) > 0 {
+		rh := replayHandler{q: q, l: logger.With().Str("context", "admin_api").Logger()}
+		s.mux.Handle("/admin/replay", bearerAuthMiddlewareFactory(cfg.AdminAPIToken, "admin", rh))
+
+		mh := maintenanceHandler{m: s.mm, l: logger.With().Str("context", "admin_api").Logger()}
+		s.mux.Handle("/admin/maintenance", bearerAuthMiddlewareFactory(cfg.AdminAPIToken, "admin", mh))
+	}
+
+	// the OAuth flow, for installing the app to other workspaces, is only
+	// served with a key to encrypt their tokens with
+	if len(cfg.Slack.ClientID) > 0 && len(cfg.Slack.ClientSecret) > 0 && kr != nil {
+		oh := oauthHandler{
+			clientID:     cfg.Slack.ClientID,
+			clientSecret: cfg.Slack.ClientSecret,
+			redirectURL:  cfg.Slack.RedirectURL,
+			appID:        cfg.Slack.AppID,
+			scopes:       cfg.Slack.InstallScopes,
+			s:            installs.NewStore(s.rc, kr),
+			hc:           &http.Client{Timeout: oauthTimeout},
+			l:            logger.With().Str("context", "oauth").Logger(),
+		}
+
+		s.mux.HandleFunc("/slack/install", oh.handleInstall)
+		s.mux.HandleFunc("/slack/oauth/callback", oh.handleCallback)
+	}
 
 	// wrap our slack event handler in the slackSignature middleware.
-	// wrap the slackSignature middleware in the context / heroku header middleware
+	// wrap the slackSignature middleware in the maintenance mode middleware,
+	// so Slack retries events later, and that in the context / heroku header
+	// middleware
 	slackHandler := chMiddlewareFactory(
+		logger,
+		maintenanceMiddlewareFactory(s.mm, slackSignatureMiddlewareFactory(
+			cfg.Slack.RequestSecret, cfg.Slack.RequestToken, cfg.Slack.AppID, cfg.Slack.TeamID, &s.logger, hnd.handleSlackEvent,
+		)),
+	)
+
+	s.mux.HandleFunc("/slack/event", slackHandler)
+
+	interactionHandler := chMiddlewareFactory(
 		logger,
 		slackSignatureMiddlewareFactory(
-			cfg.Slack.RequestSecret, cfg.Slack.RequestToken, cfg.Slack.AppID, cfg.Slack.TeamID, &logger, hnd.handleSlackEvent,
+			cfg.Slack.RequestSecret, cfg.Slack.RequestToken, cfg.Slack.AppID, cfg.Slack.TeamID, &s.logger, hnd.handleSlackInteraction,
 		),
 	)
 
-	mux.HandleFunc("/slack/event", slackHandler)
+	s.mux.HandleFunc("/slack/interactive", interactionHandler)
 
-	socketAddr := fmt.Sprintf("0.0.0.0:%d", cfg.Port)
-	logger.Info().
+	commandHandler := chMiddlewareFactory(
+		logger,
+		slackSignatureMiddlewareFactory(
+			cfg.Slack.RequestSecret, cfg.Slack.RequestToken, cfg.Slack.AppID, cfg.Slack.TeamID, &s.logger, hnd.handleSlashCommand,
+		),
+	)
+
+	s.mux.HandleFunc("/slack/command", commandHandler)
+
+	return nil
+}
+
+// Handle registers the handler for the pattern, like http.ServeMux.Handle. The
+// requests are logged and given request IDs like the built-in routes, but it's
+// up to the handler to authenticate them. It must be called before Start.
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+	s.m.addRoute(pattern)
+}
+
+// Start binds the TCP socket and starts serving requests, and the Socket Mode
+// connection if it's enabled. It returns once the socket is bound.
+func (s *Server) Start() error {
+	tc, err := serverTLSConfig(s.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to configure TLS: %w", err)
+	}
+
+	socketAddr := fmt.Sprintf("0.0.0.0:%d", s.cfg.Port)
+	s.logger.Info().
 		Str("addr", socketAddr).
+		Bool("tls", tc != nil).
 		Msg("binding to TCP socket")
 
 	// set up the network socket
@@ -107,54 +307,164 @@ func runServer(cfg config.C, logger zerolog.Logger) error {
 		return fmt.Errorf("failed to open HTTP socket: %w", err)
 	}
 
-	defer func() { _ = listener.Close() }()
-
 	// set up the HTTP server
-	httpSrvr := &http.Server{
-		Handler:     mux,
+	s.httpSrvr = &http.Server{
+		Handler:     accessLogMiddlewareFactory(s.logger, s.m, recoverMiddlewareFactory(s.logger, s.hnd.r, s.mux)),
 		ReadTimeout: 20 * time.Second,
 		IdleTimeout: 60 * time.Second,
+		TLSConfig:   tc,
 	}
 
-	serveStop, serverShutdown := make(chan struct{}), make(chan struct{})
-	var serveErr, shutdownErr error
+	// in Socket Mode, events come over the socket instead of the HTTP server,
+	// which is still used for health checks
+	s.socketStop = make(chan struct{})
+
+	if s.cfg.Slack.SocketMode {
+		sm := newSocketMode(s.cfg.Slack.AppToken, s.hnd, s.logger.With().Str("context", "socket_mode").Logger())
+
+		go func() {
+			defer close(s.socketStop)
+			sm.run(s.ctx)
+		}()
+	} else {
+		close(s.socketStop)
+	}
+
+	// Discord events come over its gateway, when there's a bot for it
+	if len(s.cfg.DiscordBotToken) > 0 {
+		s.discordStop, err = runDiscord(s.ctx, s.cfg, s.o, s.rc, s.logger.With().Str("context", "discord").Logger())
+		if err != nil {
+			_ = listener.Close()
+			return err
+		}
+	} else {
+		ds := make(chan struct{})
+		close(ds)
+		s.discordStop = ds
+	}
+
+	s.outboxStop = make(chan struct{})
 
-	// HTTP server parent goroutine
 	go func() {
-		defer close(serveStop)
-		serveErr = httpSrvr.Serve(listener)
+		defer close(s.outboxStop)
+		s.o.run(s.ctx)
 	}()
 
-	// signal handling / graceful shutdown goroutine
+	s.serveStop = make(chan struct{})
+
+	// HTTP server parent goroutine
 	go func() {
-		defer close(serverShutdown)
-		sig := <-signalCh
+		defer close(s.serveStop)
 
-		logger.Info().
-			Str("signal", sig.String()).
-			Msg("shutting HTTP server down gracefully")
+		// the certificates are in the TLS config, so no files are given here
+		if tc != nil {
+			s.serveErr = s.httpSrvr.ServeTLS(listener, "", "")
+			return
+		}
 
-		cctx, ccancel := context.WithTimeout(context.Background(), 25*time.Second)
+		s.serveErr = s.httpSrvr.Serve(listener)
+	}()
 
-		defer ccancel()
-		defer cancel()
+	return nil
+}
 
-		if shutdownErr = httpSrvr.Shutdown(cctx); shutdownErr != nil {
-			logger.Error().
-				Err(shutdownErr).
-				Msg("failed to gracefully shut down HTTP server")
-		}
-	}()
+// Stop shuts the server down in order within timeout: it fails readiness
+// checks while it keeps serving requests for drainDelay, shuts the HTTP server
+// down gracefully, and waits for it and Socket Mode to stop. Events still in
+// the outbox then get one last chance to be published in what's left.
+func (s *Server) Stop(timeout time.Duration) error {
+	err := shutdown.New(s.logger.With().Str("context", "shutdown").Logger()).
+		Then("drain", func(ctx context.Context) error {
+			s.logger.Info().
+				Dur("drain_delay", drainDelay).
+				Msg("draining before shutting HTTP server down gracefully")
 
-	// wait for it to die
-	<-serverShutdown
-	<-serveStop
+			// fail readiness checks, but keep serving requests while the
+			// router takes us out of rotation
+			s.rd.drain()
+
+			select {
+			case <-time.After(drainDelay):
+			case <-ctx.Done():
+			}
+
+			return nil
+		}).
+		Then("shut down HTTP server", s.httpSrvr.Shutdown).
+		Then("stop Socket Mode", func(context.Context) error {
+			// stop Socket Mode and Discord, and wait for everything to die
+			s.cancel()
+
+			<-s.serveStop
+			<-s.socketStop
+			<-s.discordStop
+			<-s.outboxStop
+
+			return nil
+		}).
+		Then("flush outbox", func(ctx context.Context) error {
+			if lost := s.o.flush(ctx); lost > 0 {
+				return fmt.Errorf("%d outbox events lost, as they couldn't be published before shutting down", lost)
+			}
+
+			return nil
+		}).
+		Then("close Redis", func(context.Context) error { return s.rc.Close() }).
+		Then("flush error reports", func(ctx context.Context) error {
+			if s.sr == nil {
+				return nil
+			}
+
+			d, _ := ctx.Deadline()
+
+			if !s.sr.Flush(time.Until(d)) {
+				return errors.New("timed out sending error reports")
+			}
+
+			return nil
+		}).
+		Run(timeout)
 
 	// log errors for informational purposes
-	logger.Info().
-		AnErr("serve_err", serveErr).
-		AnErr("shutdown_err", shutdownErr).
+	s.logger.Info().
+		AnErr("serve_err", s.serveErr).
+		AnErr("shutdown_err", err).
 		Msg("server shut down")
 
+	return err
+}
+
+// close stops the heartbeater and closes the Redis client, for when the server
+// never started.
+func (s *Server) close() {
+	s.cancel()
+	_ = s.rc.Close()
+}
+
+func runServer(cfg config.C, logger zerolog.Logger) error {
+	// set up signal catching
+	signalCh := make(chan os.Signal, 1)
+	signal.Notify(signalCh, syscall.SIGTERM, syscall.SIGINT)
+
+	s, err := NewServer(cfg, logger)
+	if err != nil {
+		return err
+	}
+
+	if err := s.Start(); err != nil {
+		s.close()
+		return err
+	}
+
+	sig := <-signalCh
+
+	logger.Info().
+		Str("signal", sig.String()).
+		Msg("received signal")
+
+	// Heroku allows 30 seconds after SIGTERM, so the drain and shutdown have
+	// to fit in that
+	_ = s.Stop(cfg.ShutdownTimeout)
+
 	return nil
 }
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"mime"
 	"net/http"
+	"reflect"
+	"sync"
 
+	"github.com/gobridge/gopherbot/config"
 	"github.com/gobridge/gopherbot/workqueue"
 	"github.com/rs/zerolog"
 	"github.com/valyala/fastjson"
@@ -14,9 +19,61 @@ import (
 
 const maxBodySize = 2 * 1024 * 1024 // 2 MB
 
+// failurePublish is the kind of failure reported when an event can't be
+// published to the workqueue
+const failurePublish = "publish"
+
 type handler struct {
 	l *zerolog.Logger
-	q workqueue.Q
+	o *outbox
+	m *metrics
+	r workqueue.ErrorReporter
+
+	// mu protects f and p, which change when the config is reloaded
+	mu sync.RWMutex
+	f  eventFilter
+	p  prioritizer
+}
+
+// rules returns the event filter and prioritizer to use for an event.
+func (s *handler) rules() (eventFilter, prioritizer) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.f, s.p
+}
+
+// reloadRules is a config.Watcher subscriber that applies changes to the
+// gateway filter and the priority channels.
+func (s *handler) reloadRules(old, cur config.C) {
+	if reflect.DeepEqual(old.Filter, cur.Filter) && reflect.DeepEqual(old.PriorityChannels, cur.PriorityChannels) {
+		return
+	}
+
+	f, p := newEventFilter(cur.Filter), newPrioritizer(cur.PriorityChannels)
+
+	s.mu.Lock()
+	s.f, s.p = f, p
+	s.mu.Unlock()
+
+	s.l.Info().
+		Bool("drop_bot_messages", cur.Filter.DropBotMessages).
+		Strs("drop_apps", cur.Filter.DropApps).
+		Strs("ignore_channels", cur.Filter.IgnoreChannels).
+		Strs("priority_channels", cur.PriorityChannels).
+		Msg("event filter and priority channels changed")
+}
+
+// reportError reports err to the ErrorReporter, if there is one.
+func (s *handler) reportError(err error, rid, kind string) {
+	if s.r == nil {
+		return
+	}
+
+	s.r.ReportError(err, map[string]string{
+		"kind":       kind,
+		"request_id": rid,
+	})
 }
 
 func (s *handler) handleNotFound(w http.ResponseWriter, r *http.Request) {
@@ -103,6 +160,17 @@ func wqEventType(event *fastjson.Value) (workqueue.Event, error) {
 
 	switch eventType {
 	case "message":
+		// edits and deletions have their own streams, so the message handlers
+		// don't need to filter them out
+		st, _ := getJSONString(event, "subtype")
+
+		switch st {
+		case "message_changed":
+			return workqueue.SlackMessageChanged, nil
+		case "message_deleted":
+			return workqueue.SlackMessageDeleted, nil
+		}
+
 		if !event.Exists("channel_type") {
 			return workqueue.SlackMessageChannel, nil
 		}
@@ -130,8 +198,45 @@ func wqEventType(event *fastjson.Value) (workqueue.Event, error) {
 	case "member_joined_channel":
 		return workqueue.SlackChannelJoin, nil
 
+	case "member_left_channel":
+		return workqueue.SlackChannelLeave, nil
+
+	case "user_change":
+		// only deactivations have their own stream, as user_change is sent for
+		// any profile change
+		if event.GetBool("user", "deleted") {
+			return workqueue.SlackUserDeactivated, nil
+		}
+
+		return workqueue.RawEvent(eventType), nil
+
+	case "channel_created":
+		return workqueue.SlackChannelCreated, nil
+
+	case "channel_rename":
+		return workqueue.SlackChannelRenamed, nil
+
+	case "channel_archive":
+		return workqueue.SlackChannelArchived, nil
+
+	case "channel_unarchive":
+		return workqueue.SlackChannelUnarchived, nil
+
+	case "subteam_updated":
+		return workqueue.SlackSubteamUpdated, nil
+
+	case "file_shared":
+		return workqueue.SlackFileShared, nil
+
+	case "reaction_added":
+		return workqueue.SlackReactionAdded, nil
+
+	case "reaction_removed":
+		return workqueue.SlackReactionRemoved, nil
+
 	default:
-		return "", fmt.Errorf("unknown type %s", eventType)
+		// let consumers handle it with a raw handler
+		return workqueue.RawEvent(eventType), nil
 	}
 }
 
@@ -193,6 +298,8 @@ func (s *handler) handleSlackEvent(w http.ResponseWriter, r *http.Request) {
 			Err(err).
 			Msg("failed to unmarshal JSON document")
 
+		s.reportError(err, rid, workqueue.FailureParse)
+
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		return
 	}
@@ -203,6 +310,8 @@ func (s *handler) handleSlackEvent(w http.ResponseWriter, r *http.Request) {
 			Err(err).
 			Msg("failed to parse values from JSON document")
 
+		s.reportError(err, rid, workqueue.FailureParse)
+
 		w.WriteHeader(http.StatusUnprocessableEntity)
 		return
 	}
@@ -212,6 +321,14 @@ func (s *handler) handleSlackEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if status := s.publishEvent(ctx, logger, rid, eventType, eventID, eventTimestamp, document); status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+}
+
+// publishEvent publishes the event in the event_callback document to the
+// workqueue, returning the HTTP status to respond to Slack with.
+func (s *handler) publishEvent(ctx context.Context, logger zerolog.Logger, rid, eventType, eventID string, eventTimestamp int64, document *fastjson.Value) int {
 	logger = logger.With().Str("event_type", eventType).Str("event_id", eventID).Int64("event_time", eventTimestamp).Logger()
 
 	if !document.Exists("event") {
@@ -219,8 +336,9 @@ func (s *handler) handleSlackEvent(w http.ResponseWriter, r *http.Request) {
 			Str("error", "event field does not exist").
 			Msg("failed to unmarshal JSON document")
 
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		return
+		s.reportError(errors.New("event field does not exist"), rid, workqueue.FailureParse)
+
+		return http.StatusUnprocessableEntity
 	}
 
 	event := document.Get("event")
@@ -230,8 +348,22 @@ func (s *handler) handleSlackEvent(w http.ResponseWriter, r *http.Request) {
 			Err(err).
 			Msg("failed to determine event type")
 
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		return
+		s.reportError(err, rid, workqueue.FailureParse)
+
+		return http.StatusUnprocessableEntity
+	}
+
+	s.m.observeEvent(et)
+
+	f, p := s.rules()
+
+	if reason, drop := f.drop(event); drop {
+		logger.Debug().
+			Str("event_type", string(et)).
+			Str("reason", reason).
+			Msg("dropped filtered event")
+
+		return http.StatusOK
 	}
 
 	obj, err := event.Object()
@@ -240,23 +372,33 @@ func (s *handler) handleSlackEvent(w http.ResponseWriter, r *http.Request) {
 			Err(err).
 			Msg("failed to convert event field to object")
 
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		return
+		s.reportError(err, rid, workqueue.FailureParse)
+
+		return http.StatusUnprocessableEntity
 	}
 
 	object := obj.MarshalTo(make([]byte, 0, 4*1024))
 
-	err = s.q.Publish(et, eventTimestamp, eventID, rid, object)
+	// so the consumers use the bot token for the workspace it's from
+	teamID, _ := requestTeamID(document)
+	ctx = workqueue.WithTeamID(ctx, teamID)
+
+	prio := p.priority(et, event)
+
+	err = s.o.publish(ctx, et, prio, eventTimestamp, eventID, rid, object)
 	if err != nil {
 		logger.Error().Err(err).Msg("failed to publish event to workqueue")
-		w.WriteHeader(http.StatusInternalServerError)
-		return
+		s.reportError(err, rid, failurePublish)
+		return http.StatusInternalServerError
 	}
 
 	logger.Debug().
 		Str("event_type", string(et)).
+		Str("priority", prio.String()).
 		Int64("event_timestamp", eventTimestamp).
 		Str("event_id", eventID).
 		Bool("object_has_len", len(object) > 0).
 		Msg("published event")
+
+	return http.StatusOK
 }
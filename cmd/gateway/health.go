@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// healthCacheTTL is how long health check results are reused for, so
+	// frequent checks don't hit Redis and the Slack API on every request
+	healthCacheTTL = 30 * time.Second
+
+	// healthCheckTimeout is how long all the checks have to finish
+	healthCheckTimeout = 5 * time.Second
+
+	// drainDelay is how long the gateway keeps serving requests after it
+	// starts draining, so the router sees /_ready fail and stops sending it
+	// traffic before the HTTP server shuts down
+	drainDelay = 5 * time.Second
+
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+// groupChecker is the interface for checking that the consumers have created
+// their consumer group. Generally this is implemented by a *workqueue.I.
+type groupChecker interface {
+	GroupExists(ctx context.Context, e workqueue.Event, p workqueue.Priority) (bool, error)
+}
+
+// dependencyHealth is the status of one dependency in a healthReport.
+type dependencyHealth struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// healthReport is the JSON document returned by /_healthz.
+type healthReport struct {
+	Status       string                      `json:"status"`
+	CheckedAt    time.Time                   `json:"checked_at"`
+	Dependencies map[string]dependencyHealth `json:"dependencies"`
+}
+
+// healthChecker checks the gateway's dependencies, caching the results for
+// healthCacheTTL.
+type healthChecker struct {
+	rc redis.UniversalClient
+	gc groupChecker
+	sc *slack.Client
+	l  zerolog.Logger
+
+	mu     sync.Mutex
+	report healthReport
+}
+
+// newHealthChecker returns a *healthChecker. If sc is nil, Slack auth isn't
+// checked.
+func newHealthChecker(rc redis.UniversalClient, gc groupChecker, sc *slack.Client, logger zerolog.Logger) *healthChecker {
+	return &healthChecker{
+		rc: rc,
+		gc: gc,
+		sc: sc,
+		l:  logger,
+	}
+}
+
+func healthOf(err error) dependencyHealth {
+	if err != nil {
+		return dependencyHealth{Status: healthFail, Error: err.Error()}
+	}
+
+	return dependencyHealth{Status: healthOK}
+}
+
+// check returns the healthReport, running the checks again if the cached one
+// is older than healthCacheTTL.
+func (h *healthChecker) check(now time.Time) healthReport {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.report.CheckedAt.IsZero() && now.Sub(h.report.CheckedAt) < healthCacheTTL {
+		return h.report
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	deps := make(map[string]dependencyHealth, 3)
+
+	deps["redis"] = healthOf(h.rc.Ping(ctx).Err())
+
+	ok, err := h.gc.GroupExists(ctx, workqueue.SlackMessageChannel, workqueue.PriorityNormal)
+	if err == nil && !ok {
+		err = fmt.Errorf("consumer group doesn't exist on the %s stream yet", workqueue.SlackMessageChannel)
+	}
+
+	deps["consumer_group"] = healthOf(err)
+
+	if h.sc != nil {
+		_, err := h.sc.AuthTestContext(ctx)
+		deps["slack"] = healthOf(err)
+	}
+
+	report := healthReport{
+		Status:       healthOK,
+		CheckedAt:    now,
+		Dependencies: deps,
+	}
+
+	for name, d := range deps {
+		if d.Status == healthOK {
+			continue
+		}
+
+		report.Status = healthFail
+
+		h.l.Warn().
+			Str("dependency", name).
+			Str("error", d.Error).
+			Msg("health check failed")
+	}
+
+	h.report = report
+
+	return report
+}
+
+// handleHealthz responds with the healthReport, with a 503 status if any
+// dependency is unhealthy.
+func (h *healthChecker) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	report := h.check(time.Now())
+
+	body, err := json.Marshal(report)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+	if report.Status != healthOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	_, _ = w.Write(body)
+}
+
+// readiness tracks whether the gateway should be sent traffic. It's ready from
+// when it starts until it begins draining on shutdown.
+type readiness struct {
+	draining int32
+}
+
+// drain marks the gateway as not ready.
+func (rd *readiness) drain() { atomic.StoreInt32(&rd.draining, 1) }
+
+func (rd *readiness) isDraining() bool { return atomic.LoadInt32(&rd.draining) == 1 }
+
+// handleReady responds with a 503 once the gateway is draining, so it's taken
+// out of rotation, and a 200 before then.
+func (rd *readiness) handleReady(w http.ResponseWriter, r *http.Request) {
+	if rd.isDraining() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_, _ = io.WriteString(w, "draining")
+		return
+	}
+
+	_, _ = io.WriteString(w, "ready")
+}
+
+// handleLive always responds with a 200, including while draining, as the
+// process is still alive and finishing its requests.
+func (rd *readiness) handleLive(w http.ResponseWriter, r *http.Request) {
+	_, _ = io.WriteString(w, "alive")
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/valyala/fastjson"
+)
+
+// interactionEvents maps the interactivity payload types we handle to their
+// workqueue Event. Slack sends others, like view_closed, which are dropped.
+var interactionEvents = map[string]workqueue.Event{
+	"block_actions":   workqueue.SlackBlockActions,
+	"view_submission": workqueue.SlackViewSubmission,
+}
+
+func (s *handler) handleSlackInteraction(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	lc := s.l.With().Str("context", "interaction_handler")
+
+	rid, ok := ctxRequestID(ctx)
+	if ok {
+		lc = lc.Str("request_id", rid)
+	}
+
+	logger := lc.Logger()
+
+	if r.Method != http.MethodPost {
+		logger.Info().
+			Str("http_method", r.Method).
+			Msg("unexpected HTTP method")
+
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to parse Content-Type")
+
+		w.Header().Set("Accept", "application/x-www-form-urlencoded")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if mt != "application/x-www-form-urlencoded" {
+		logger.Error().
+			Str("content_type", mt).
+			Msg("content type was not a form")
+
+		w.Header().Set("Accept", "application/x-www-form-urlencoded")
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	if status := s.publishInteraction(ctx, logger, rid, []byte(r.PostFormValue("payload"))); status != http.StatusOK {
+		w.WriteHeader(status)
+	}
+}
+
+// publishInteraction publishes the interactivity payload to the workqueue,
+// returning the HTTP status to respond to Slack with.
+func (s *handler) publishInteraction(ctx context.Context, logger zerolog.Logger, rid string, payload []byte) int {
+	document, err := fastjson.ParseBytes(payload)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to unmarshal JSON payload")
+
+		s.reportError(err, rid, workqueue.FailureParse)
+
+		return http.StatusUnprocessableEntity
+	}
+
+	it, err := getJSONString(document, "type")
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to parse values from JSON payload")
+
+		s.reportError(err, rid, workqueue.FailureParse)
+
+		return http.StatusUnprocessableEntity
+	}
+
+	logger = logger.With().Str("interaction_type", it).Logger()
+
+	et, ok := interactionEvents[it]
+	if !ok {
+		// acknowledge it, so Slack doesn't show the user an error
+		logger.Debug().Msg("dropped unhandled interaction")
+		return http.StatusOK
+	}
+
+	s.m.observeEvent(et)
+
+	// so the consumers use the bot token for the workspace it's from
+	teamID, _ := requestTeamID(document)
+	ctx = workqueue.WithTeamID(ctx, teamID)
+
+	// there's no event ID, but the trigger ID is unique to the interaction
+	triggerID, _ := getJSONString(document, "trigger_id")
+	now := time.Now().Unix()
+
+	// someone clicked something, and is waiting to see what it did
+	err = s.o.publish(ctx, et, workqueue.PriorityHigh, now, triggerID, rid, payload)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to publish interaction to workqueue")
+		s.reportError(err, rid, failurePublish)
+		return http.StatusInternalServerError
+	}
+
+	logger.Debug().
+		Str("event_type", string(et)).
+		Int64("event_timestamp", now).
+		Str("trigger_id", triggerID).
+		Msg("published interaction")
+
+	return http.StatusOK
+}
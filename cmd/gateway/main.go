@@ -9,7 +9,7 @@ import (
 )
 
 func main() {
-	c, err := config.LoadEnv()
+	c, err := config.Load()
 	if err != nil {
 		log.Fatalf("failed to load config: %v", err)
 	}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// redisMaintenanceKey is set while maintenance mode is on, with the
+	// expiry it was turned on with
+	redisMaintenanceKey = "gateway:maintenance"
+
+	// maintenancePollInterval is how often each gateway checks whether
+	// maintenance mode was turned on or off
+	maintenancePollInterval = 5 * time.Second
+
+	// maintenanceRetryAfter is the Retry-After, in seconds, of the responses
+	// Slack events get in maintenance mode, which is about when Slack retries
+	// them anyway
+	maintenanceRetryAfter = 60
+
+	// maintenanceTimeout is how long each Redis call about maintenance mode
+	// has
+	maintenanceTimeout = 2 * time.Second
+
+	// defaultMaintenanceDuration is how long maintenance mode stays on when
+	// the admin API isn't told
+	defaultMaintenanceDuration = time.Hour
+)
+
+// maintenance is whether the gateway is in maintenance mode, in which case
+// Slack events are turned away with a 503 so Slack retries them later, like
+// during planned Redis maintenance. It's turned on with config, or for all
+// gateways with the admin API. The last known state is kept while Redis can't
+// be reached, so it needs to be turned on before Redis goes away.
+type maintenance struct {
+	rc     redis.UniversalClient
+	l      zerolog.Logger
+	forced bool
+
+	// on is 1 while maintenance mode is turned on in Redis
+	on int32
+}
+
+func newMaintenance(rc redis.UniversalClient, forced bool, logger zerolog.Logger) *maintenance {
+	return &maintenance{rc: rc, l: logger, forced: forced}
+}
+
+// enabled returns whether the gateway is in maintenance mode.
+func (m *maintenance) enabled() bool {
+	return m.forced || atomic.LoadInt32(&m.on) == 1
+}
+
+// watch keeps the maintenance mode state up to date until ctx is canceled.
+func (m *maintenance) watch(ctx context.Context) {
+	t := time.NewTicker(maintenancePollInterval)
+	defer t.Stop()
+
+	for {
+		if err := m.load(ctx); err != nil && ctx.Err() == nil {
+			m.l.Warn().
+				Err(err).
+				Bool("maintenance_mode", m.enabled()).
+				Msg("failed to check maintenance mode; keeping the last known state")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// load loads the maintenance mode state from Redis.
+func (m *maintenance) load(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, maintenanceTimeout)
+	defer cancel()
+
+	n, err := m.rc.Exists(ctx, redisMaintenanceKey).Result()
+	if err != nil {
+		return err
+	}
+
+	m.update(n > 0)
+
+	return nil
+}
+
+// set turns maintenance mode on for d, or off, for all gateways.
+func (m *maintenance) set(ctx context.Context, on bool, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, maintenanceTimeout)
+	defer cancel()
+
+	var err error
+
+	if on {
+		err = m.rc.Set(ctx, redisMaintenanceKey, time.Now().Add(d).Unix(), d).Err()
+	} else {
+		err = m.rc.Del(ctx, redisMaintenanceKey).Err()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	m.update(on)
+
+	return nil
+}
+
+func (m *maintenance) update(on bool) {
+	var v int32
+	if on {
+		v = 1
+	}
+
+	if atomic.SwapInt32(&m.on, v) == v {
+		return
+	}
+
+	m.l.Warn().
+		Bool("maintenance_mode", on).
+		Bool("forced", m.forced).
+		Msg("maintenance mode changed")
+}
+
+// maintenanceMiddlewareFactory turns requests away with a 503 while the
+// gateway is in maintenance mode.
+func maintenanceMiddlewareFactory(m *maintenance, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !m.enabled() {
+			next(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(maintenanceRetryAfter))
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+type maintenanceRequest struct {
+	Enabled bool `json:"enabled"`
+
+	// Duration is how long maintenance mode stays on, like "30m", so it
+	// can't be forgotten. It defaults to defaultMaintenanceDuration.
+	Duration string `json:"duration"`
+}
+
+type maintenanceResponse struct {
+	Enabled bool   `json:"enabled"`
+	Forced  bool   `json:"forced,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// maintenanceHandler serves the admin API's /admin/maintenance, which shows
+// whether maintenance mode is on with a GET, and turns it on or off for all
+// gateways with a POST.
+type maintenanceHandler struct {
+	m *maintenance
+	l zerolog.Logger
+}
+
+func (h maintenanceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodGet {
+		h.respond(w, http.StatusOK, maintenanceResponse{Enabled: h.m.enabled(), Forced: h.m.forced})
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req maintenanceRequest
+
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxBodySize)).Decode(&req); err != nil {
+		h.respond(w, http.StatusBadRequest, maintenanceResponse{Error: "failed to decode request: " + err.Error()})
+		return
+	}
+
+	d := defaultMaintenanceDuration
+
+	if len(req.Duration) > 0 {
+		var err error
+
+		if d, err = time.ParseDuration(req.Duration); err != nil || d <= 0 {
+			h.respond(w, http.StatusBadRequest, maintenanceResponse{Error: "duration must be a positive duration, like 30m"})
+			return
+		}
+	}
+
+	if err := h.m.set(r.Context(), req.Enabled, d); err != nil {
+		h.l.Error().
+			Err(err).
+			Bool("maintenance_mode", req.Enabled).
+			Msg("failed to set maintenance mode")
+
+		h.respond(w, http.StatusInternalServerError, maintenanceResponse{Enabled: h.m.enabled(), Error: err.Error()})
+
+		return
+	}
+
+	h.l.Info().
+		Bool("maintenance_mode", req.Enabled).
+		Dur("duration", d).
+		Msg("set maintenance mode")
+
+	h.respond(w, http.StatusOK, maintenanceResponse{Enabled: h.m.enabled(), Forced: h.m.forced})
+}
+
+func (h maintenanceHandler) respond(w http.ResponseWriter, status int, resp maintenanceResponse) {
+	body, err := json.Marshal(resp)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
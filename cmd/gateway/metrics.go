@@ -0,0 +1,198 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metricsRoutes are the built-in paths given their own path label in the
+// request metrics. Any other path is labeled "other", unless it was added with
+// addRoute, so scanners hitting random URLs don't create new time series.
+var metricsRoutes = map[string]struct{}{
+	"/_ruok":                {},
+	"/_healthz":             {},
+	"/_ready":               {},
+	"/_live":                {},
+	"/metrics":              {},
+	"/slack/event":          {},
+	"/slack/interactive":    {},
+	"/slack/command":        {},
+	"/slack/install":        {},
+	"/slack/oauth/callback": {},
+	"/admin/replay":         {},
+	"/admin/maintenance":    {},
+}
+
+// metrics are the Prometheus metrics for the gateway. A nil *metrics records
+// nothing.
+type metrics struct {
+	requests       *prometheus.HistogramVec
+	events         *prometheus.CounterVec
+	publishFailed  *prometheus.CounterVec
+	outboxDepth    prometheus.Gauge
+	outboxDropped  *prometheus.CounterVec
+	redisUp        prometheus.GaugeFunc
+	registry       *prometheus.Registry
+	workqueueStats *workqueue.Metrics
+	routes         map[string]struct{}
+}
+
+// newMetrics returns a *metrics, with its collectors and the workqueue's
+// registered to a new registry. The Redis health gauge pings rc each time the
+// metrics are read.
+func newMetrics(rc redis.UniversalClient) (*metrics, error) {
+	m := &metrics{
+		requests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gopherbot",
+			Subsystem: "gateway",
+			Name:      "request_duration_seconds",
+			Help:      "How long HTTP requests took, by method, path, and status.",
+			Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+		}, []string{"method", "path", "status"}),
+
+		events: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gopherbot",
+			Subsystem: "gateway",
+			Name:      "slack_events_total",
+			Help:      "Slack events received, by workqueue event type.",
+		}, []string{"event"}),
+
+		publishFailed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gopherbot",
+			Subsystem: "gateway",
+			Name:      "publish_failures_total",
+			Help:      "Attempts to publish events to the workqueue that failed, by workqueue event type.",
+		}, []string{"event"}),
+
+		outboxDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "gopherbot",
+			Subsystem: "gateway",
+			Name:      "outbox_events",
+			Help:      "Events buffered in the outbox, waiting to be retried.",
+		}),
+
+		outboxDropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gopherbot",
+			Subsystem: "gateway",
+			Name:      "outbox_dropped_total",
+			Help:      "Events that failed to be published while the outbox was full, by workqueue event type.",
+		}, []string{"event"}),
+
+		redisUp: prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: "gopherbot",
+			Subsystem: "gateway",
+			Name:      "redis_up",
+			Help:      "Whether Redis responded to a PING when the metrics were read.",
+		}, func() float64 {
+			ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+			defer cancel()
+
+			if err := rc.Ping(ctx).Err(); err != nil {
+				return 0
+			}
+
+			return 1
+		}),
+
+		registry:       prometheus.NewRegistry(),
+		workqueueStats: workqueue.NewMetrics(),
+		routes:         make(map[string]struct{}, len(metricsRoutes)),
+	}
+
+	for r := range metricsRoutes {
+		m.routes[r] = struct{}{}
+	}
+
+	collectors := []prometheus.Collector{
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+		m.requests,
+		m.events,
+		m.publishFailed,
+		m.outboxDepth,
+		m.outboxDropped,
+		m.redisUp,
+		m.workqueueStats,
+	}
+
+	for _, c := range collectors {
+		if err := m.registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// addRoute gives the path its own path label. It isn't safe to call while
+// requests are being served.
+func (m *metrics) addRoute(path string) {
+	if m == nil {
+		return
+	}
+
+	m.routes[path] = struct{}{}
+}
+
+func (m *metrics) observeRequest(method, path string, status int, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	if _, ok := m.routes[path]; !ok {
+		path = "other"
+	}
+
+	m.requests.WithLabelValues(method, path, strconv.Itoa(status)).Observe(d.Seconds())
+}
+
+func (m *metrics) observeEvent(et workqueue.Event) {
+	if m == nil {
+		return
+	}
+
+	m.events.WithLabelValues(string(et)).Inc()
+}
+
+func (m *metrics) observePublishFailure(et workqueue.Event) {
+	if m == nil {
+		return
+	}
+
+	m.publishFailed.WithLabelValues(string(et)).Inc()
+}
+
+func (m *metrics) setOutboxDepth(n int) {
+	if m == nil {
+		return
+	}
+
+	m.outboxDepth.Set(float64(n))
+}
+
+func (m *metrics) observeOutboxDropped(et workqueue.Event) {
+	if m == nil {
+		return
+	}
+
+	m.outboxDropped.WithLabelValues(string(et)).Inc()
+}
+
+// handler returns the /metrics handler. If token isn't empty, requests must
+// have it as their bearer token.
+func (m *metrics) handler(token string) http.Handler {
+	h := promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+
+	if len(token) == 0 {
+		return h
+	}
+
+	return bearerAuthMiddlewareFactory(token, "metrics", h)
+}
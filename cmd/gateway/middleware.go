@@ -3,12 +3,20 @@ package main
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
 	"io"
 	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
+	"runtime/debug"
 	"time"
 
 	"github.com/gobridge/gopherbot/signing"
+	"github.com/gobridge/gopherbot/workqueue"
 	"github.com/rs/zerolog"
 	"github.com/valyala/fastjson"
 )
@@ -33,13 +41,116 @@ func ctxRequestID(ctx context.Context) (string, bool) {
 	return rid, true
 }
 
+// newRequestID returns a random request ID, for requests that didn't come
+// through the Heroku router with one.
+func newRequestID() string {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+
+	return hex.EncodeToString(b)
+}
+
+// statusRecorder is an http.ResponseWriter that records the response status.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sr *statusRecorder) WriteHeader(status int) {
+	sr.status = status
+	sr.ResponseWriter.WriteHeader(status)
+}
+
+// accessLogMiddlewareFactory assigns each request an ID, using the one in the
+// X-Request-ID header set by the Heroku router if there is one, and logs the
+// request once it's been handled, recording it in m.
+func accessLogMiddlewareFactory(baseLogger zerolog.Logger, m *metrics, next http.Handler) http.Handler {
+	logger := baseLogger.With().Str("context", "access_log").Logger()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		rid := r.Header.Get("X-Request-ID")
+		if len(rid) == 0 {
+			rid = newRequestID()
+		}
+
+		w.Header().Set("X-Request-ID", rid)
+
+		sr := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(sr, r.WithContext(context.WithValue(r.Context(), ctxKeyReqID, rid)))
+
+		d := time.Since(start)
+
+		m.observeRequest(r.Method, r.URL.Path, sr.status, d)
+
+		logger.Info().
+			Str("request_id", rid).
+			Str("method", r.Method).
+			Str("path", r.URL.Path).
+			Int("status", sr.status).
+			Dur("duration", d).
+			Msg("request handled")
+	})
+}
+
+// recoverMiddlewareFactory recovers handlers that panic, reporting the panic
+// to er if it's not nil, and responds with a 500 instead of dropping the
+// connection.
+func recoverMiddlewareFactory(baseLogger zerolog.Logger, er workqueue.ErrorReporter, next http.Handler) http.Handler {
+	logger := baseLogger.With().Str("context", "recover_middleware").Logger()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+
+			// this is how handlers abort the response, so it's left for the
+			// http.Server
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			rid, _ := ctxRequestID(r.Context())
+			pe := &workqueue.PanicError{Value: v, Stack: debug.Stack()}
+
+			logger.Error().
+				Err(pe).
+				Str("request_id", rid).
+				Str("path", r.URL.Path).
+				Str("stack", string(pe.Stack)).
+				Msg("handler panicked")
+
+			if er != nil {
+				er.ReportError(pe, map[string]string{
+					"kind":       workqueue.FailurePanic,
+					"request_id": rid,
+					"path":       r.URL.Path,
+				})
+			}
+
+			w.WriteHeader(http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func chMiddlewareFactory(baseLogger zerolog.Logger, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := context.Background()
 
-		if rid := r.Header.Get("X-Request-ID"); len(rid) > 0 {
+		// the request ID is carried over from the access log middleware, as
+		// this context isn't derived from the request's, so publishing isn't
+		// canceled if Slack hangs up
+		if rid, ok := ctxRequestID(r.Context()); ok {
 			ctx = context.WithValue(ctx, ctxKeyReqID, rid)
-			w.Header().Set("X-Request-ID", rid)
 		}
 
 		// Slack expects a response within 3 seconds, give ourselves 2.9 seconds
@@ -87,7 +198,7 @@ func slackSignatureMiddlewareFactory(hmacKey, token, appID, teamID string, baseL
 			return
 		}
 
-		document, err := fastjson.ParseBytes(body)
+		document, err := fastjson.ParseBytes(requestPayload(r, body))
 		if err != nil {
 			logger.Error().
 				Err(err).
@@ -154,13 +265,14 @@ func slackSignatureMiddlewareFactory(hmacKey, token, appID, teamID string, baseL
 			return
 		}
 
-		rTeamID, err := getJSONString(document, "team_id")
+		rTeamID, err := requestTeamID(document)
 		if err != nil {
 			logger.Error().
 				Err(err).
 				Msg("failed to validate Slack request")
 
 			w.WriteHeader(http.StatusBadRequest)
+			return
 		}
 
 		if rTeamID != teamID {
@@ -176,3 +288,73 @@ func slackSignatureMiddlewareFactory(hmacKey, token, appID, teamID string, baseL
 		next(w, r)
 	}
 }
+
+// requestPayload returns the JSON document of the request. Events are sent as
+// JSON, but interactivity payloads are sent as a form with the JSON in the
+// payload field, and slash commands as a form of their own.
+func requestPayload(r *http.Request, body []byte) []byte {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mt != "application/x-www-form-urlencoded" {
+		return body
+	}
+
+	v, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil
+	}
+
+	if p, ok := v["payload"]; ok && len(p) > 0 {
+		return []byte(p[0])
+	}
+
+	return slashCommandPayload(v)
+}
+
+// slashCommandType is the type given to the JSON document of slash commands,
+// which don't have one, so they can be validated like other requests
+const slashCommandType = "slash_command"
+
+// slashCommandPayload returns the slash command form as a JSON document.
+func slashCommandPayload(v url.Values) []byte {
+	m := make(map[string]string, len(v)+1)
+
+	for k := range v {
+		m[k] = v.Get(k)
+	}
+
+	m["type"] = slashCommandType
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+
+	return b
+}
+
+// requestTeamID returns the workspace the request is for. Events have a
+// team_id field, but interactivity payloads have a team object instead.
+func requestTeamID(document *fastjson.Value) (string, error) {
+	if team := document.Get("team"); team != nil && team.Type() == fastjson.TypeObject {
+		return getJSONString(team, "id")
+	}
+
+	return getJSONString(document, "team_id")
+}
+
+// bearerAuthMiddlewareFactory returns a handler that only passes requests
+// with token as their bearer token on to next, challenging the others for the
+// realm.
+func bearerAuthMiddlewareFactory(token, realm string, next http.Handler) http.Handler {
+	want := []byte("Bearer " + token)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+realm+`"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gobridge/gopherbot/installs"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// slackAuthorizeURL is where members are sent to install the app
+	slackAuthorizeURL = "https://slack.com/oauth/v2/authorize"
+
+	// oauthStateCookie holds the state the install was started with, so the
+	// callback only completes installs this browser started
+	oauthStateCookie = "gopher_oauth_state"
+
+	// oauthStateTTL is how long a member has to finish installing the app
+	oauthStateTTL = 10 * time.Minute
+
+	// oauthTimeout is how long exchanging the code, and saving the
+	// installation, has
+	oauthTimeout = 10 * time.Second
+)
+
+// oauthHandler serves the OAuth flow that installs the app to other
+// workspaces: /slack/install sends the member to Slack to approve it, and
+// Slack sends them back to /slack/oauth/callback, where the code is exchanged
+// for the workspace's bot token and saved. It's only served if the app has a
+// client ID and secret, and a key to encrypt the tokens with.
+type oauthHandler struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	appID        string
+	scopes       []string
+
+	s  *installs.Store
+	hc *http.Client
+	l  zerolog.Logger
+}
+
+func (h oauthHandler) logger(r *http.Request) zerolog.Logger {
+	lc := h.l.With()
+
+	if rid, ok := ctxRequestID(r.Context()); ok {
+		lc = lc.Str("request_id", rid)
+	}
+
+	return lc.Logger()
+}
+
+// handleInstall redirects the member to Slack to install the app.
+func (h oauthHandler) handleInstall(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		l := h.logger(r)
+		l.Error().Err(err).Msg("failed to generate OAuth state")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	state := hex.EncodeToString(b)
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    state,
+		Path:     "/slack/oauth/callback",
+		MaxAge:   int(oauthStateTTL / time.Second),
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	v := url.Values{
+		"client_id": {h.clientID},
+		"scope":     {strings.Join(h.scopes, ",")},
+		"state":     {state},
+	}
+
+	if len(h.redirectURL) > 0 {
+		v.Set("redirect_uri", h.redirectURL)
+	}
+
+	http.Redirect(w, r, slackAuthorizeURL+"?"+v.Encode(), http.StatusFound)
+}
+
+// handleCallback finishes installing the app, once the member approved it.
+func (h oauthHandler) handleCallback(w http.ResponseWriter, r *http.Request) {
+	logger := h.logger(r)
+
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// the state is only good once
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthStateCookie,
+		Path:     "/slack/oauth/callback",
+		MaxAge:   -1,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	q := r.URL.Query()
+
+	if e := q.Get("error"); len(e) > 0 {
+		logger.Info().
+			Str("error", e).
+			Msg("app installation was not approved")
+
+		h.respond(w, http.StatusBadRequest, "The app wasn't installed: "+e)
+
+		return
+	}
+
+	c, err := r.Cookie(oauthStateCookie)
+	if err != nil || len(c.Value) == 0 || subtle.ConstantTimeCompare([]byte(c.Value), []byte(q.Get("state"))) != 1 {
+		logger.Warn().Msg("OAuth callback state did not match")
+
+		h.respond(w, http.StatusBadRequest, "The installation expired, or was started in another browser. Please try installing the app again.")
+
+		return
+	}
+
+	code := q.Get("code")
+	if len(code) == 0 {
+		h.respond(w, http.StatusBadRequest, "The installation is missing its code. Please try installing the app again.")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), oauthTimeout)
+	defer cancel()
+
+	resp, err := slack.GetOAuthV2ResponseContext(ctx, h.hc, h.clientID, h.clientSecret, code, h.redirectURL)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to exchange OAuth code")
+
+		h.respond(w, http.StatusBadGateway, "Slack didn't accept the installation. Please try installing the app again.")
+
+		return
+	}
+
+	in := installs.Installation{
+		TeamID:       resp.Team.ID,
+		TeamName:     resp.Team.Name,
+		EnterpriseID: resp.Enterprise.ID,
+		AppID:        resp.AppID,
+		BotUserID:    resp.BotUserID,
+		BotToken:     resp.AccessToken,
+		Scope:        resp.Scope,
+		InstalledBy:  resp.AuthedUser.ID,
+		InstalledAt:  time.Now().UTC(),
+	}
+
+	logger = logger.With().
+		Str("team_id", in.TeamID).
+		Str("app_id", in.AppID).
+		Str("installed_by", in.InstalledBy).
+		Logger()
+
+	if len(h.appID) > 0 && in.AppID != h.appID {
+		logger.Error().Msg("OAuth code was for another app")
+
+		h.respond(w, http.StatusBadRequest, "The installation was for another app.")
+
+		return
+	}
+
+	if resp.TokenType != "bot" || len(in.TeamID) == 0 || len(in.BotToken) == 0 {
+		logger.Error().
+			Str("token_type", resp.TokenType).
+			Msg("OAuth response has no bot token")
+
+		h.respond(w, http.StatusBadGateway, "Slack didn't give the app a bot token. Please try installing the app again.")
+
+		return
+	}
+
+	if err := h.s.Save(ctx, in); err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to save installation")
+
+		h.respond(w, http.StatusInternalServerError, "The installation couldn't be saved. Please try installing the app again.")
+
+		return
+	}
+
+	logger.Info().
+		Str("team_name", in.TeamName).
+		Str("scope", in.Scope).
+		Msg("app installed")
+
+	h.respond(w, http.StatusOK, fmt.Sprintf("The app was installed to %s. You can close this page.", in.TeamName))
+}
+
+func (h oauthHandler) respond(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(msg + "\n"))
+}
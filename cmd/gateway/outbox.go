@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+)
+
+const (
+	// outboxSize is how many events the outbox buffers before refusing more
+	outboxSize = 1000
+
+	// outboxPublishTimeout is how long each retry has to publish an event
+	outboxPublishTimeout = 2 * time.Second
+
+	// outboxMinBackoff and outboxMaxBackoff bound how long the outbox waits
+	// between retries, doubling while publishing keeps failing
+	outboxMinBackoff = 100 * time.Millisecond
+	outboxMaxBackoff = 5 * time.Second
+)
+
+// errOutboxFull is returned by outbox.publish if an event couldn't be
+// published, and there was no room to buffer it.
+var errOutboxFull = errors.New("event failed to publish, and the outbox is full")
+
+type outboxEvent struct {
+	e         workqueue.Event
+	p         workqueue.Priority
+	timestamp int64
+	eventID   string
+	requestID string
+	data      []byte
+
+	// teamID is the workspace the event is from, which Publish takes from
+	// its context
+	teamID string
+}
+
+// outbox publishes events to the workqueue, buffering the ones that fail to
+// publish, like while Redis is failing over, and retrying them with backoff.
+// Slack is told an event was received once it's published or buffered, so
+// buffered events are lost if the gateway stops before Redis comes back.
+type outbox struct {
+	q workqueue.Publisher
+	l zerolog.Logger
+	m *metrics
+
+	mu  sync.Mutex
+	buf []outboxEvent
+
+	// flushMu stops the retry loop and the final flush on shutdown from
+	// publishing the same event twice
+	flushMu sync.Mutex
+
+	// wake is signaled when an event is buffered
+	wake chan struct{}
+}
+
+func newOutbox(q workqueue.Publisher, logger zerolog.Logger, m *metrics) *outbox {
+	return &outbox{
+		q:    q,
+		l:    logger,
+		m:    m,
+		wake: make(chan struct{}, 1),
+	}
+}
+
+// publish publishes the event, or buffers it to be retried if that fails. If
+// events are already buffered it goes straight to the buffer, instead of
+// waiting for Redis to time out again. It only returns an error if the buffer
+// is full.
+func (o *outbox) publish(ctx context.Context, e workqueue.Event, p workqueue.Priority, eventTimestamp int64, eventID, requestID string, data []byte) error {
+	if o.depth() == 0 {
+		err := o.q.Publish(ctx, e, p, eventTimestamp, eventID, requestID, data)
+		if err == nil {
+			return nil
+		}
+
+		o.m.observePublishFailure(e)
+
+		o.l.Warn().
+			Err(err).
+			Str("event_type", string(e)).
+			Str("event_id", eventID).
+			Msg("failed to publish event to workqueue; buffering it to retry")
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if len(o.buf) >= outboxSize {
+		o.m.observeOutboxDropped(e)
+		return errOutboxFull
+	}
+
+	o.buf = append(o.buf, outboxEvent{
+		e:         e,
+		p:         p,
+		timestamp: eventTimestamp,
+		eventID:   eventID,
+		requestID: requestID,
+		data:      data,
+		teamID:    workqueue.TeamIDFromContext(ctx),
+	})
+
+	o.m.setOutboxDepth(len(o.buf))
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// depth returns how many events are buffered.
+func (o *outbox) depth() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return len(o.buf)
+}
+
+// run retries the buffered events until ctx is canceled.
+func (o *outbox) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.wake:
+		}
+
+		for backoff := outboxMinBackoff; o.flush(ctx) > 0; backoff *= 2 {
+			if backoff > outboxMaxBackoff {
+				backoff = outboxMaxBackoff
+			}
+
+			t := time.NewTimer(backoff)
+
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return
+			case <-t.C:
+			}
+		}
+	}
+}
+
+// flush publishes the buffered events in order, until one fails or ctx is
+// canceled, and returns how many are left.
+func (o *outbox) flush(ctx context.Context) int {
+	o.flushMu.Lock()
+	defer o.flushMu.Unlock()
+
+	for ctx.Err() == nil {
+		o.mu.Lock()
+
+		if len(o.buf) == 0 {
+			o.mu.Unlock()
+			return 0
+		}
+
+		ev := o.buf[0]
+
+		o.mu.Unlock()
+
+		pctx, cancel := context.WithTimeout(workqueue.WithTeamID(ctx, ev.teamID), outboxPublishTimeout)
+		err := o.q.Publish(pctx, ev.e, ev.p, ev.timestamp, ev.eventID, ev.requestID, ev.data)
+		cancel()
+
+		if err != nil {
+			o.m.observePublishFailure(ev.e)
+
+			o.l.Error().
+				Err(err).
+				Str("event_type", string(ev.e)).
+				Str("event_id", ev.eventID).
+				Int("buffered", o.depth()).
+				Msg("failed to publish buffered event to workqueue")
+
+			break
+		}
+
+		o.mu.Lock()
+		o.buf = o.buf[1:]
+		o.m.setOutboxDepth(len(o.buf))
+		o.mu.Unlock()
+
+		o.l.Info().
+			Str("event_type", string(ev.e)).
+			Str("event_id", ev.eventID).
+			Msg("published buffered event")
+	}
+
+	return o.depth()
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/valyala/fastjson"
+)
+
+// prioritizer decides the workqueue priority events are published at, so
+// admin commands and moderation events don't wait behind ordinary chatter.
+type prioritizer struct {
+	channels map[string]struct{}
+}
+
+func newPrioritizer(channels []string) prioritizer {
+	return prioritizer{channels: toSet(channels)}
+}
+
+func (p prioritizer) priority(et workqueue.Event, event *fastjson.Value) workqueue.Priority {
+	switch {
+	// shared files are scanned, and removed if they break the rules
+	case et == workqueue.SlackFileShared:
+		return workqueue.PriorityHigh
+
+	case et == workqueue.SlackReactionAdded, et == workqueue.SlackReactionRemoved,
+		strings.HasPrefix(string(et), string(workqueue.RawEvent(""))):
+		return workqueue.PriorityLow
+	}
+
+	if len(p.channels) > 0 {
+		for _, k := range []string{"channel", "channel_id"} {
+			id, _ := getJSONString(event, k)
+			if _, ok := p.channels[id]; ok && len(id) > 0 {
+				return workqueue.PriorityHigh
+			}
+		}
+	}
+
+	return workqueue.PriorityNormal
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+	"github.com/valyala/fastjson"
+)
+
+const (
+	connectionsOpenURL = "https://slack.com/api/apps.connections.open"
+
+	// socketReconnectDelay is how long to wait before reconnecting, after the
+	// connection failed
+	socketReconnectDelay = 5 * time.Second
+
+	// socketReadTimeout is how long to wait for a message, including Slack's
+	// pings, before assuming the connection is dead
+	socketReadTimeout = 2 * time.Minute
+)
+
+// socketMode receives events over a Slack Socket Mode connection, and publishes
+// them to the workqueue the same way the HTTP endpoints do. This lets the
+// gateway run without a public URL.
+type socketMode struct {
+	token string
+	h     *handler
+	l     zerolog.Logger
+	httpc *http.Client
+}
+
+func newSocketMode(appToken string, h *handler, logger zerolog.Logger) *socketMode {
+	return &socketMode{
+		token: appToken,
+		h:     h,
+		l:     logger,
+		httpc: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// run keeps a connection open until ctx is canceled, reconnecting whenever
+// it's lost.
+func (sm *socketMode) run(ctx context.Context) {
+	for {
+		err := sm.connect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		sm.l.Warn().
+			Err(err).
+			Dur("delay", socketReconnectDelay).
+			Msg("socket mode connection lost; reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(socketReconnectDelay):
+		}
+	}
+}
+
+// openURL asks Slack for the URL of a new Socket Mode connection.
+func (sm *socketMode) openURL(ctx context.Context) (string, error) {
+	req, err := http.NewRequest(http.MethodPost, connectionsOpenURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build apps.connections.open request: %w", err)
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+sm.token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := sm.httpc.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call apps.connections.open: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var r struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		URL   string `json:"url"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return "", fmt.Errorf("failed to decode apps.connections.open response: %w", err)
+	}
+
+	if !r.OK {
+		return "", fmt.Errorf("apps.connections.open failed: %s", r.Error)
+	}
+
+	return r.URL, nil
+}
+
+// connect opens a connection, and handles its messages until it's closed.
+func (sm *socketMode) connect(ctx context.Context) error {
+	u, err := sm.openURL(ctx)
+	if err != nil {
+		return err
+	}
+
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, u, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to socket: %w", err)
+	}
+
+	defer func() { _ = conn.Close() }()
+
+	// closing the connection is the only way to interrupt ReadMessage
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	conn.SetPingHandler(func(data string) error {
+		_ = conn.SetReadDeadline(time.Now().Add(socketReadTimeout))
+		return conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+
+	for {
+		_ = conn.SetReadDeadline(time.Now().Add(socketReadTimeout))
+
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("failed to read from socket: %w", err)
+		}
+
+		if err := sm.handle(ctx, conn, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// errSocketDisconnect is returned when Slack asks us to reconnect, like before
+// it refreshes the connection
+var errSocketDisconnect = errors.New("slack asked to disconnect")
+
+// handle handles a single Socket Mode message.
+func (sm *socketMode) handle(ctx context.Context, conn *websocket.Conn, msg []byte) error {
+	envelope, err := fastjson.ParseBytes(msg)
+	if err != nil {
+		sm.l.Error().
+			Err(err).
+			Msg("failed to unmarshal socket mode message")
+
+		return nil
+	}
+
+	mt := string(envelope.GetStringBytes("type"))
+	envelopeID := string(envelope.GetStringBytes("envelope_id"))
+
+	// use the envelope ID as the request ID, so the consumer's logs can be
+	// correlated with ours
+	logger := sm.l.With().
+		Str("request_id", envelopeID).
+		Str("socket_message_type", mt).
+		Logger()
+
+	var status int
+
+	switch mt {
+	case "hello":
+		logger.Info().Msg("socket mode connected")
+		return nil
+
+	case "disconnect":
+		return fmt.Errorf("%w: %s", errSocketDisconnect, envelope.GetStringBytes("reason"))
+
+	case "events_api":
+		status = sm.publishEvent(ctx, logger, envelopeID, envelope.Get("payload"))
+
+	case "interactive":
+		status = sm.h.publishInteraction(ctx, logger, envelopeID, payloadBytes(envelope))
+
+	case "slash_commands":
+		status = sm.h.publishSlashCommand(ctx, logger, envelopeID, payloadBytes(envelope))
+
+	default:
+		logger.Debug().Msg("ignored unknown socket mode message")
+		return nil
+	}
+
+	// don't acknowledge it if we failed to publish it, so Slack sends it
+	// again, unless it's a bad payload that would fail again
+	if status >= http.StatusInternalServerError {
+		return nil
+	}
+
+	ack, err := json.Marshal(struct {
+		EnvelopeID string `json:"envelope_id"`
+	}{envelopeID})
+	if err != nil {
+		return fmt.Errorf("failed to marshal socket mode acknowledgement: %w", err)
+	}
+
+	if err := conn.WriteMessage(websocket.TextMessage, ack); err != nil {
+		return fmt.Errorf("failed to acknowledge socket mode message: %w", err)
+	}
+
+	return nil
+}
+
+// publishEvent publishes the event_callback document from an events_api
+// message.
+func (sm *socketMode) publishEvent(ctx context.Context, logger zerolog.Logger, rid string, document *fastjson.Value) int {
+	if document == nil {
+		logger.Error().
+			Str("error", "payload field does not exist").
+			Msg("failed to unmarshal socket mode message")
+
+		return http.StatusUnprocessableEntity
+	}
+
+	eventType, eventID, eventTimestamp, err := requestValues(document)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to parse values from JSON document")
+
+		return http.StatusUnprocessableEntity
+	}
+
+	return sm.h.publishEvent(ctx, logger, rid, eventType, eventID, eventTimestamp, document)
+}
+
+func payloadBytes(envelope *fastjson.Value) []byte {
+	p := envelope.Get("payload")
+	if p == nil {
+		return nil
+	}
+
+	return p.MarshalTo(nil)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+
+	"github.com/gobridge/gopherbot/config"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// defaultAutocertCacheDir is where Let's Encrypt certificates are cached if
+// config.C.TLSAutocertCacheDir isn't set.
+const defaultAutocertCacheDir = "autocert"
+
+// serverTLSConfig returns the TLS config for the HTTP server, or nil if the
+// gateway should serve plain HTTP, like it does behind the Heroku router.
+// HTTP/2 is negotiated over TLS.
+func serverTLSConfig(cfg config.C) (*tls.Config, error) {
+	files := len(cfg.TLSCertFile) > 0 || len(cfg.TLSKeyFile) > 0
+	auto := len(cfg.TLSAutocertDomains) > 0
+
+	switch {
+	case files && auto:
+		return nil, errors.New("TLS certificate files and autocert domains can't both be set")
+
+	case files:
+		if len(cfg.TLSCertFile) == 0 || len(cfg.TLSKeyFile) == 0 {
+			return nil, errors.New("both the TLS certificate and key files must be set")
+		}
+
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+
+		return &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+			NextProtos:   []string{"h2", "http/1.1"},
+		}, nil
+
+	case auto:
+		dir := cfg.TLSAutocertCacheDir
+		if len(dir) == 0 {
+			dir = defaultAutocertCacheDir
+		}
+
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLSAutocertDomains...),
+			Cache:      autocert.DirCache(dir),
+		}
+
+		// this includes the ALPN protocol for the TLS-ALPN-01 challenge, so
+		// certificates can be issued without also listening on port 80
+		tc := m.TLSConfig()
+		tc.MinVersion = tls.VersionTLS12
+
+		return tc, nil
+
+	default:
+		return nil, nil
+	}
+}
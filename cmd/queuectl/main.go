@@ -0,0 +1,77 @@
+// Command queuectl manages the workqueue's streams and consumer groups, so
+// operators don't need redis-cli. It uses the same environment variables as
+// the other components to connect to Redis.
+//
+//	queuectl streams
+//	queuectl groups STREAM
+//	queuectl create-group STREAM GROUP [LAST_ID]
+//	queuectl delete-group STREAM GROUP
+//	queuectl pending [-count N] STREAM GROUP [CONSUMER]
+//	queuectl claim STREAM GROUP CONSUMER ID...
+//	queuectl pause STREAM
+//	queuectl resume STREAM
+//	queuectl paused
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/internal/redisutil"
+	"github.com/gobridge/gopherbot/workqueue"
+)
+
+func main() {
+	count := flag.Int64("count", 100, "how many pending messages to list")
+	timeout := flag.Duration("timeout", 30*time.Second, "how long the command has to finish")
+
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger := config.DefaultLogger(cfg)
+
+	rc := redisutil.ConnectRedis(cfg.Redis)
+	defer func() { _ = rc.Close() }()
+
+	q, err := workqueue.New(workqueue.Config{
+		RedisClient: rc,
+		Logger:      &logger,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build workqueue: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := run(ctx, q, flag.Args(), *count, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+
+		if err == errUsage {
+			flag.Usage()
+			os.Exit(2)
+		}
+
+		os.Exit(1)
+	}
+}
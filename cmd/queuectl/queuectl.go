@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gobridge/gopherbot/workqueue"
+)
+
+const usage = `usage: queuectl [flags] COMMAND [ARGS]
+
+commands:
+  streams                                 list the streams
+  groups STREAM                           list the consumer groups on STREAM
+  create-group STREAM GROUP [LAST_ID]     create GROUP on STREAM, after LAST_ID ("$" by default, "0" for everything)
+  delete-group STREAM GROUP               delete GROUP and its pending messages from STREAM
+  pending STREAM GROUP [CONSUMER]         list the messages pending for GROUP on STREAM, or only CONSUMER's
+  claim STREAM GROUP CONSUMER ID...       make the pending messages pending for CONSUMER, however long they were idle
+  pause STREAM                            stop every worker consuming STREAM, and its priority variants
+  resume STREAM                           undo pause for STREAM
+  paused                                  list the paused streams
+
+flags:`
+
+var errUsage = errors.New("wrong number of arguments")
+
+// admin is the part of *workqueue.I that queuectl uses.
+type admin interface {
+	Streams(ctx context.Context) ([]string, error)
+	Groups(ctx context.Context, stream string) ([]workqueue.GroupInfo, error)
+	CreateGroup(ctx context.Context, stream, group, lastID string) error
+	DeleteGroup(ctx context.Context, stream, group string) (bool, error)
+	PendingEntries(ctx context.Context, stream, group, consumer string, count int64) ([]workqueue.PendingEntry, error)
+	Claim(ctx context.Context, stream, group, consumer string, ids ...string) ([]string, error)
+	Pause(ctx context.Context, stream string) error
+	Resume(ctx context.Context, stream string) error
+	Paused(ctx context.Context) ([]string, error)
+}
+
+// run runs the command in args, writing its output to w.
+func run(ctx context.Context, q admin, args []string, count int64, w io.Writer) error {
+	cmd, args := args[0], args[1:]
+
+	switch {
+	case cmd == "streams" && len(args) == 0:
+		streams, err := q.Streams(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range streams {
+			fmt.Fprintln(w, s)
+		}
+
+		return nil
+
+	case cmd == "groups" && len(args) == 1:
+		groups, err := q.Groups(ctx, args[0])
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "GROUP\tCONSUMERS\tPENDING\tLAG\tLAST DELIVERED")
+
+		for _, g := range groups {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\n", g.Name, g.Consumers, g.Pending, lag(g.Lag), g.LastDeliveredID)
+		}
+
+		return tw.Flush()
+
+	case cmd == "create-group" && (len(args) == 2 || len(args) == 3):
+		lastID := "$"
+		if len(args) == 3 {
+			lastID = args[2]
+		}
+
+		if err := q.CreateGroup(ctx, args[0], args[1], lastID); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "consumer group %s exists on %s\n", args[1], args[0])
+
+		return nil
+
+	case cmd == "delete-group" && len(args) == 2:
+		ok, err := q.DeleteGroup(ctx, args[0], args[1])
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("consumer group %s doesn't exist on %s", args[1], args[0])
+		}
+
+		fmt.Fprintf(w, "deleted consumer group %s from %s\n", args[1], args[0])
+
+		return nil
+
+	case cmd == "pending" && (len(args) == 2 || len(args) == 3):
+		var consumer string
+		if len(args) == 3 {
+			consumer = args[2]
+		}
+
+		entries, err := q.PendingEntries(ctx, args[0], args[1], consumer, count)
+		if err != nil {
+			return err
+		}
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tCONSUMER\tIDLE\tDELIVERIES")
+
+		for _, e := range entries {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", e.ID, e.Consumer, e.Idle.Truncate(time.Millisecond), e.Deliveries)
+		}
+
+		return tw.Flush()
+
+	case cmd == "claim" && len(args) >= 4:
+		claimed, err := q.Claim(ctx, args[0], args[1], args[2], args[3:]...)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "claimed %d of %d messages for %s\n", len(claimed), len(args)-3, args[2])
+
+		return nil
+
+	case cmd == "pause" && len(args) == 1:
+		if err := q.Pause(ctx, args[0]); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "paused %s\n", args[0])
+
+		return nil
+
+	case cmd == "resume" && len(args) == 1:
+		if err := q.Resume(ctx, args[0]); err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "resumed %s\n", args[0])
+
+		return nil
+
+	case cmd == "paused" && len(args) == 0:
+		paused, err := q.Paused(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range paused {
+			fmt.Fprintln(w, s)
+		}
+
+		return nil
+
+	default:
+		return errUsage
+	}
+}
+
+// lag formats the lag, which is -1 if Redis doesn't report it.
+func lag(n int64) string {
+	if n < 0 {
+		return "unknown"
+	}
+
+	return fmt.Sprint(n)
+}
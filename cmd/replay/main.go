@@ -0,0 +1,65 @@
+// Command replay re-publishes events from a workqueue stream, so one consumer
+// group handles them again, like after a buggy handler was deployed. It uses
+// the same environment variables as the other components to connect to Redis.
+//
+//	replay -stream slack_message_public -group gopherbot-consumer -start 1588000000000 -end +
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/internal/redisutil"
+	"github.com/gobridge/gopherbot/workqueue"
+)
+
+func main() {
+	stream := flag.String("stream", "", "the stream to replay events from, like slack_message_public_high or dead_letter")
+	start := flag.String("start", "-", "the ID, or millisecond timestamp, of the first event to replay")
+	end := flag.String("end", "+", "the ID, or millisecond timestamp, of the last event to replay")
+	group := flag.String("group", "", "the consumer group to replay the events to, which is the app name of its component")
+	timeout := flag.Duration("timeout", time.Minute, "how long the replay has to finish")
+	flag.Parse()
+
+	if len(*stream) == 0 || len(*group) == 0 {
+		fmt.Fprintln(os.Stderr, "-stream and -group are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+
+	logger := config.DefaultLogger(cfg)
+
+	rc := redisutil.ConnectRedis(cfg.Redis)
+	defer func() { _ = rc.Close() }()
+
+	q, err := workqueue.New(workqueue.Config{
+		RedisClient: rc,
+		Logger:      &logger,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build workqueue: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	n, err := q.Replay(ctx, *stream, *start, *end, *group)
+
+	fmt.Printf("replayed %d events from %s to %s\n", n, *stream, *group)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to replay events: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gobridge/gopherbot/config"
+)
+
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !run(cfg, os.Stdout) {
+		os.Exit(1)
+	}
+}
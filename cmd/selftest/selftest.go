@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/cache"
+	"github.com/gobridge/gopherbot/config"
+	"github.com/gobridge/gopherbot/internal/redisutil"
+)
+
+// requiredScopes are the bot token OAuth scopes needed by all the components
+var requiredScopes = []string{
+	"channels:history",
+	"channels:read",
+	"chat:write",
+	"commands",
+	"emoji:read",
+	"files:read",
+	"files:write",
+	"groups:history",
+	"groups:read",
+	"im:write",
+	"reactions:read",
+	"reactions:write",
+	"usergroups:read",
+	"users:read",
+}
+
+// cacheProbeChannel is a channel that should always be in the channel cache
+const cacheProbeChannel = "general"
+
+type check struct {
+	name string
+	fn   func(ctx context.Context, cfg config.C, rc redis.UniversalClient) (string, error)
+}
+
+var checks = []check{
+	{name: "config", fn: checkConfig},
+	{name: "redis", fn: checkRedis},
+	{name: "redis_streams", fn: checkRedisStreams},
+	{name: "slack_auth", fn: checkSlackAuth},
+	{name: "channel_cache", fn: checkChannelCache},
+}
+
+// run executes all checks, writing a report to w, and returns whether they all
+// passed. Checks are independent, so a failure doesn't stop the rest from
+// running.
+func run(cfg config.C, w io.Writer) bool {
+	rc := redisutil.ConnectRedis(cfg.Redis)
+	defer func() { _ = rc.Close() }()
+
+	ok := true
+
+	for _, c := range checks {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+		detail, err := c.fn(ctx, cfg, rc)
+
+		cancel()
+
+		if err != nil {
+			ok = false
+
+			fmt.Fprintf(w, "FAIL %s: %v\n", c.name, err)
+
+			continue
+		}
+
+		fmt.Fprintf(w, "PASS %s: %s\n", c.name, detail)
+	}
+
+	if ok {
+		fmt.Fprintln(w, "all checks passed")
+	} else {
+		fmt.Fprintln(w, "one or more checks failed")
+	}
+
+	return ok
+}
+
+func checkConfig(_ context.Context, cfg config.C, _ redis.UniversalClient) (string, error) {
+	required := []struct {
+		env   string
+		value string
+	}{
+		{"REDIS_URL", cfg.Redis.Addr},
+		{"HEROKU_APP_NAME", cfg.Heroku.AppName},
+		{"HEROKU_DYNO_ID", cfg.Heroku.DynoID},
+		{"GOPHER_SLACK_BOT_ACCESS_TOKEN", cfg.Slack.BotAccessToken},
+		{"GOPHER_SLACK_REQUEST_SECRET", cfg.Slack.RequestSecret},
+	}
+
+	var missing []string
+
+	for _, r := range required {
+		if len(r.value) == 0 {
+			missing = append(missing, r.env)
+		}
+	}
+
+	if len(missing) > 0 {
+		return "", fmt.Errorf("missing environment variables: %s", strings.Join(missing, ", "))
+	}
+
+	return fmt.Sprintf("env %s, log level %s", cfg.Env, cfg.LogLevel), nil
+}
+
+func checkRedis(ctx context.Context, cfg config.C, rc redis.UniversalClient) (string, error) {
+	if err := rc.Ping(ctx).Err(); err != nil {
+		return "", fmt.Errorf("failed to ping: %w", err)
+	}
+
+	key := fmt.Sprintf("selftest:%s:%s", cfg.Heroku.AppName, cfg.Heroku.DynoID)
+
+	if err := rc.Set(ctx, key, "ok", time.Minute).Err(); err != nil {
+		return "", fmt.Errorf("failed to write: %w", err)
+	}
+
+	v, err := rc.Get(ctx, key).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to read: %w", err)
+	}
+
+	if v != "ok" {
+		return "", fmt.Errorf("read back %q, expected %q", v, "ok")
+	}
+
+	if err := rc.Del(ctx, key).Err(); err != nil {
+		return "", fmt.Errorf("failed to delete: %w", err)
+	}
+
+	return fmt.Sprintf("ping, read, write, and delete ok on %s", cfg.Redis.Addr), nil
+}
+
+func checkRedisStreams(ctx context.Context, cfg config.C, rc redis.UniversalClient) (string, error) {
+	stream := fmt.Sprintf("selftest:%s:%s:stream", cfg.Heroku.AppName, cfg.Heroku.DynoID)
+
+	defer func() { _ = rc.Del(ctx, stream).Err() }()
+
+	id, err := rc.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		Values: map[string]interface{}{"selftest": "ok"},
+	}).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to XADD: %w", err)
+	}
+
+	msgs, err := rc.XRange(ctx, stream, id, id).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to XRANGE: %w", err)
+	}
+
+	if len(msgs) != 1 {
+		return "", fmt.Errorf("XRANGE returned %d messages, expected 1", len(msgs))
+	}
+
+	if err := rc.XGroupCreate(ctx, stream, "selftest", "0").Err(); err != nil {
+		return "", fmt.Errorf("failed to XGROUP CREATE: %w", err)
+	}
+
+	return "XADD, XRANGE, and XGROUP ok", nil
+}
+
+func checkSlackAuth(ctx context.Context, cfg config.C, _ redis.UniversalClient) (string, error) {
+	// slack-go doesn't expose the response headers, and the scopes granted to
+	// the token are only available in the X-OAuth-Scopes header
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://slack.com/api/auth.test", nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+cfg.Slack.BotAccessToken)
+	req.Header.Add("User-Agent", "Gophers Slack Bot V2")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to call auth.test: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected HTTP response status: %s", resp.Status)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	var at struct {
+		OK     bool   `json:"ok"`
+		Error  string `json:"error"`
+		TeamID string `json:"team_id"`
+		UserID string `json:"user_id"`
+		User   string `json:"user"`
+	}
+
+	if err := json.Unmarshal(body, &at); err != nil {
+		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	if !at.OK {
+		return "", fmt.Errorf("auth.test failed: %s", at.Error)
+	}
+
+	if len(cfg.Slack.TeamID) > 0 && at.TeamID != cfg.Slack.TeamID {
+		return "", fmt.Errorf("token is for team %s, expected %s", at.TeamID, cfg.Slack.TeamID)
+	}
+
+	missing := missingScopes(resp.Header.Get("X-OAuth-Scopes"), requiredScopes)
+	if len(missing) > 0 {
+		return "", fmt.Errorf("authenticated as %s (%s), but token is missing scopes: %s", at.User, at.UserID, strings.Join(missing, ", "))
+	}
+
+	return fmt.Sprintf("authenticated as %s (%s) in team %s, all required scopes granted", at.User, at.UserID, at.TeamID), nil
+}
+
+// missingScopes returns the required scopes not in the comma-separated granted
+// list, sorted.
+func missingScopes(granted string, required []string) []string {
+	have := make(map[string]struct{})
+
+	for _, s := range strings.Split(granted, ",") {
+		have[strings.TrimSpace(s)] = struct{}{}
+	}
+
+	var missing []string
+
+	for _, s := range required {
+		if _, ok := have[s]; !ok {
+			missing = append(missing, s)
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing
+}
+
+func checkChannelCache(ctx context.Context, _ config.C, rc redis.UniversalClient) (string, error) {
+	ch, notFound, err := cache.NewChannel(rc).Lookup(ctx, cacheProbeChannel)
+	if err != nil {
+		return "", fmt.Errorf("failed to look up #%s: %w", cacheProbeChannel, err)
+	}
+
+	if notFound {
+		return "", errors.New("#" + cacheProbeChannel + " not in the channel cache; is bgtasks running?")
+	}
+
+	return fmt.Sprintf("found #%s (%s)", ch.Name, ch.ID), nil
+}
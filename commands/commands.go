@@ -0,0 +1,219 @@
+// Package commands is a command framework on top of the handler package. It
+// parses messages for a prefix, like "!" or a mention of the bot, tokenizes the
+// arguments, and dispatches to the registered Command.
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/workqueue"
+)
+
+// Command is a single bot command.
+type Command interface {
+	// Name is what the command is invoked as, like "define" in "!define".
+	Name() string
+
+	// Help is the one-line description of the command, shown by the help
+	// command.
+	Help() string
+
+	// Run runs the command, which responds with r.
+	Run(ctx workqueue.Context, inv Invocation, r handler.Responder) error
+}
+
+// Invocation is a parsed command invocation.
+type Invocation struct {
+	// Name is the name of the command, as it was invoked. This could be one of
+	// the command's aliases.
+	Name string
+
+	// Args are the tokenized arguments.
+	Args []string
+
+	// RawArgs is everything after the command name, untokenized.
+	RawArgs string
+
+	// Message is the message that invoked the command.
+	Message handler.Messenger
+}
+
+// DefaultTimeout is the command timeout used when one isn't given.
+const DefaultTimeout = 5 * time.Second
+
+type registered struct {
+	cmd     Command
+	timeout time.Duration
+	aliases []string
+}
+
+// Set is a set of commands, invoked with a common prefix.
+type Set struct {
+	prefix string
+	cmds   map[string]*registered
+}
+
+// New returns a new *Set, whose commands are invoked with the given prefix. If
+// prefix is empty, commands are invoked by mentioning the bot (or in a DM)
+// instead. The set includes a help command, listing the others.
+func New(prefix string) *Set {
+	s := &Set{
+		prefix: prefix,
+		cmds:   make(map[string]*registered),
+	}
+
+	s.Register(helpCommand{s}, DefaultTimeout)
+
+	return s
+}
+
+// Register adds the command to the set, and any aliases it should also be
+// invoked as. The command's context is canceled after timeout, or DefaultTimeout
+// if it's zero. It panics if the name or an alias is already registered.
+func (s *Set) Register(c Command, timeout time.Duration, aliases ...string) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	r := &registered{cmd: c, timeout: timeout, aliases: aliases}
+
+	for _, name := range append([]string{c.Name()}, aliases...) {
+		name = strings.ToLower(name)
+
+		if _, ok := s.cmds[name]; ok {
+			panic(fmt.Sprintf("command %q already exists", name))
+		}
+
+		s.cmds[name] = r
+	}
+}
+
+// parse returns the invocation in the message, if there is one.
+func (s *Set) parse(m handler.Messenger) (Invocation, bool) {
+	text := m.Text()
+
+	if len(s.prefix) > 0 {
+		if !strings.HasPrefix(text, s.prefix) {
+			return Invocation{}, false
+		}
+
+		text = text[len(s.prefix):]
+	} else if !m.BotMentioned() && m.ChannelType() != handler.ChannelDM {
+		return Invocation{}, false
+	}
+
+	text = strings.TrimSpace(text)
+
+	name, rawArgs := text, ""
+	if n := strings.IndexAny(text, " \t\n"); n >= 0 {
+		name, rawArgs = text[:n], strings.TrimSpace(text[n+1:])
+	}
+
+	if len(name) == 0 {
+		return Invocation{}, false
+	}
+
+	return Invocation{
+		Name:    strings.ToLower(name),
+		RawArgs: rawArgs,
+		Message: m,
+	}, true
+}
+
+// MessageMatchFn satisfies handler.MessageMatchFn. It matches messages that
+// invoke a command. In shadow mode, only messages that mention the bot or are
+// in a DM match.
+func (s *Set) MessageMatchFn(shadowMode bool, m handler.Messenger) bool {
+	if shadowMode && !m.BotMentioned() && m.ChannelType() != handler.ChannelDM {
+		return false
+	}
+
+	inv, ok := s.parse(m)
+	if !ok {
+		return false
+	}
+
+	_, ok = s.cmds[inv.Name]
+
+	return ok
+}
+
+// Handler satisfies handler.MessageActionFn. It runs the command the message
+// invokes.
+func (s *Set) Handler(ctx workqueue.Context, m handler.Messenger, r handler.Responder) error {
+	inv, ok := s.parse(m)
+	if !ok {
+		return errors.New("message is not a command invocation")
+	}
+
+	rc, ok := s.cmds[inv.Name]
+	if !ok {
+		return fmt.Errorf("unknown command %q", inv.Name)
+	}
+
+	args, err := Tokenize(inv.RawArgs)
+	if err != nil {
+		return r.RespondTo(ctx, fmt.Sprintf("I couldn't parse the arguments to `%s`: %s", inv.Name, err))
+	}
+
+	inv.Args = args
+
+	cctx, cancel := workqueue.WithTimeout(ctx, rc.timeout)
+	defer cancel()
+
+	if err := rc.cmd.Run(cctx, inv, r); err != nil {
+		return fmt.Errorf("command %s failed: %w", rc.cmd.Name(), err)
+	}
+
+	return nil
+}
+
+// Commands returns the registered commands, sorted by name.
+func (s *Set) Commands() []Command {
+	// aliases point to the same *registered
+	seen := make(map[*registered]struct{}, len(s.cmds))
+	cmds := make([]Command, 0, len(s.cmds))
+
+	for _, r := range s.cmds {
+		if _, ok := seen[r]; ok {
+			continue
+		}
+
+		seen[r] = struct{}{}
+		cmds = append(cmds, r.cmd)
+	}
+
+	sort.Slice(cmds, func(i, j int) bool { return cmds[i].Name() < cmds[j].Name() })
+
+	return cmds
+}
+
+type helpCommand struct {
+	s *Set
+}
+
+func (helpCommand) Name() string { return "help" }
+func (helpCommand) Help() string { return "show the commands I support" }
+
+func (h helpCommand) Run(ctx workqueue.Context, _ Invocation, r handler.Responder) error {
+	var b strings.Builder
+
+	b.WriteString("Here are the commands I support:\n")
+
+	for _, c := range h.s.Commands() {
+		fmt.Fprintf(&b, "• `%s%s` - %s", h.s.prefix, c.Name(), c.Help())
+
+		if a := h.s.cmds[strings.ToLower(c.Name())].aliases; len(a) > 0 {
+			fmt.Fprintf(&b, " (aliases: %s)", strings.Join(a, ", "))
+		}
+
+		b.WriteString("\n")
+	}
+
+	return r.RespondTextAttachment(ctx, "", b.String())
+}
@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+)
+
+// Tokenize splits s into arguments on whitespace, like a shell. Arguments can
+// be quoted with single or double quotes, or Slack's “smart” quotes, to include
+// whitespace. A backslash escapes the next character outside of single quotes.
+func Tokenize(s string) ([]string, error) {
+	var (
+		args    []string
+		b       strings.Builder
+		inArg   bool
+		quote   rune
+		escaped bool
+	)
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+
+		case quote != 0:
+			if r == quote || (quote == '“' && r == '”') {
+				quote = 0
+				continue
+			}
+
+			b.WriteRune(r)
+
+		case r == '"' || r == '\'' || r == '“':
+			quote, inArg = r, true
+
+		case unicode.IsSpace(r):
+			if inArg {
+				args = append(args, b.String())
+				b.Reset()
+				inArg = false
+			}
+
+		default:
+			b.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+
+	if escaped {
+		return nil, errors.New("trailing backslash")
+	}
+
+	if inArg {
+		args = append(args, b.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", s: "  "},
+		{name: "words", s: "define  goroutine\tchannel", want: []string{"define", "goroutine", "channel"}},
+		{name: "double_quotes", s: `say "hello world" now`, want: []string{"say", "hello world", "now"}},
+		{name: "single_quotes", s: `say 'it\s' ok`, want: []string{"say", `it\s`, "ok"}},
+		{name: "smart_quotes", s: "say “hello world”", want: []string{"say", "hello world"}},
+		{name: "empty_quotes", s: `a "" b`, want: []string{"a", "", "b"}},
+		{name: "escape", s: `a\ b c\"d`, want: []string{"a b", `c"d`}},
+		{name: "unterminated", s: `say "hello`, wantErr: true},
+		{name: "trailing_backslash", s: `say \`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Tokenize(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Tokenize() error = %v, wantErr %t", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("Tokenize() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -3,7 +3,7 @@
 package config
 
 import (
-	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/url"
@@ -12,7 +12,6 @@ import (
 	"strings"
 	"time"
 
-	"github.com/go-redis/redis"
 	"github.com/rs/zerolog"
 )
 
@@ -62,6 +61,46 @@ type R struct {
 
 	// SkipVerify is whether we skip x.509 certification validation
 	SkipVerify bool
+
+	// SentinelMaster is the name of the master to ask the Sentinels for. If
+	// set, the client connects to whichever server the Sentinels say is the
+	// master, instead of Addr. The password is still taken from REDIS_URL.
+	// Env: GOPHER_REDIS_SENTINEL_MASTER
+	SentinelMaster string
+
+	// SentinelAddrs are the host:port addresses of the Sentinels.
+	// Env: GOPHER_REDIS_SENTINEL_ADDRS (comma-separated)
+	SentinelAddrs []string
+
+	// ClusterAddrs are the host:port addresses of some of the Redis Cluster
+	// nodes. If set, the client connects to the cluster instead of Addr, and
+	// the password is still taken from REDIS_URL.
+	// Env: GOPHER_REDIS_CLUSTER_ADDRS (comma-separated)
+	ClusterAddrs []string
+}
+
+// String satisfies fmt.Stringer, with the password redacted so the config can
+// be logged.
+func (r R) String() string {
+	return fmt.Sprintf(
+		"{Addr:%s User:%s Password:%s Insecure:%t SkipVerify:%t SentinelMaster:%s SentinelAddrs:%v ClusterAddrs:%v}",
+		r.Addr, r.User, redact(r.Password), r.Insecure, r.SkipVerify, r.SentinelMaster, r.SentinelAddrs, r.ClusterAddrs,
+	)
+}
+
+// GoString satisfies fmt.GoStringer, so %#v is redacted too.
+func (r R) GoString() string {
+	return "config.R" + r.String()
+}
+
+// redact returns a placeholder for the secret, so it's clear whether it was
+// set without showing it.
+func redact(secret string) string {
+	if len(secret) == 0 {
+		return ""
+	}
+
+	return "[redacted]"
 }
 
 // H is the Heroku environment configuration
@@ -77,6 +116,9 @@ type H struct {
 
 	// Commit is the HEROKU_SLUG_COMMIT
 	Commit string
+
+	// ReleaseVersion is the HEROKU_RELEASE_VERSION, like v42
+	ReleaseVersion string
 }
 
 // S is the Slack environment configuration
@@ -89,10 +131,17 @@ type S struct {
 	// ENV: SLACK_TEAM_ID
 	TeamID string
 
-	// BotAccessToken is the bot access token for API calls
-	// ENV: SLACK_BOT_ACCESS_TOKEN
+	// BotAccessToken is the bot access token for API calls. Starts with
+	// xoxb-.
+	// Env: GOPHER_SLACK_BOT_ACCESS_TOKEN, or SLACK_BOT_TOKEN
 	BotAccessToken string
 
+	// AdminAccessToken is a user access token, for an admin of the workspace,
+	// used for the few API calls that require more permissions than the bot
+	// has (e.g., deleting other users' files). Optional. Starts with xoxp-.
+	// Env: GOPHER_SLACK_ADMIN_ACCESS_TOKEN
+	AdminAccessToken string
+
 	// ClientID is the Client ID
 	// Env: SLACK_CLIENT_ID
 	ClientID string
@@ -101,29 +150,132 @@ type S struct {
 	// Env: SLACK_CLIENT_SECRET
 	ClientSecret string
 
+	// RedirectURL is the OAuth redirect URL Slack sends installs back to,
+	// which is the gateway's /slack/oauth/callback. Leave blank to use the
+	// one configured for the app.
+	// Env: SLACK_REDIRECT_URL
+	RedirectURL string
+
+	// InstallScopes are the bot scopes requested when the app is installed
+	// to a workspace with the gateway's /slack/install.
+	// Env: SLACK_INSTALL_SCOPES (comma-separated)
+	InstallScopes []string
+
 	// RequestSecret is the HMAC signing secret used for Slack request signing
-	// Env: SLACK_REQUEST_SECRET
+	// Env: GOPHER_SLACK_REQUEST_SECRET, or SLACK_SIGNING_SECRET
 	RequestSecret string
 
 	// RequestToken is the Slack verification token
 	// Env: SLACK_REQUEST_TOKEN
 	RequestToken string
+
+	// AppToken is the App-level token, used to open Socket Mode connections.
+	// Starts with xapp-.
+	// Env: GOPHER_SLACK_APP_TOKEN, or SLACK_APP_TOKEN
+	AppToken string
+
+	// SocketMode is whether the gateway receives events over a Socket Mode
+	// connection, instead of Events API HTTP requests. Requires AppToken.
+	// Env: SLACK_SOCKET_MODE
+	SocketMode bool
+}
+
+// Validate returns an error if the tokens that are set don't look like the
+// kind of token they should be, which usually means they were pasted into
+// the wrong environment variable, or if SocketMode is set without AppToken.
+func (s S) Validate() error {
+	tokens := []struct {
+		env    string
+		value  string
+		prefix string
+	}{
+		{"GOPHER_SLACK_BOT_ACCESS_TOKEN", s.BotAccessToken, "xoxb-"},
+		{"GOPHER_SLACK_ADMIN_ACCESS_TOKEN", s.AdminAccessToken, "xoxp-"},
+		{"GOPHER_SLACK_APP_TOKEN", s.AppToken, "xapp-"},
+	}
+
+	for _, t := range tokens {
+		if len(t.value) > 0 && !strings.HasPrefix(t.value, t.prefix) {
+			return fmt.Errorf("%s must start with %s", t.env, t.prefix)
+		}
+	}
+
+	if s.SocketMode && len(s.AppToken) == 0 {
+		return errors.New("socket mode requires GOPHER_SLACK_APP_TOKEN")
+	}
+
+	return nil
+}
+
+// String satisfies fmt.Stringer, with the secrets redacted so the config can
+// be logged.
+func (s S) String() string {
+	return fmt.Sprintf(
+		"{AppID:%s TeamID:%s BotAccessToken:%s AdminAccessToken:%s ClientID:%s ClientSecret:%s RedirectURL:%s InstallScopes:%v RequestSecret:%s RequestToken:%s AppToken:%s SocketMode:%t}",
+		s.AppID, s.TeamID, redact(s.BotAccessToken), redact(s.AdminAccessToken), s.ClientID,
+		redact(s.ClientSecret), s.RedirectURL, s.InstallScopes, redact(s.RequestSecret), redact(s.RequestToken),
+		redact(s.AppToken), s.SocketMode,
+	)
+}
+
+// GoString satisfies fmt.GoStringer, so %#v is redacted too.
+func (s S) GoString() string {
+	return "config.S" + s.String()
+}
+
+// F is the gateway event filter configuration. Events matching any of these
+// are dropped before they are published to the workqueue.
+type F struct {
+	// DropBotMessages is whether to drop messages with the bot_message subtype
+	// Env: GOPHER_FILTER_DROP_BOT_MESSAGES
+	DropBotMessages bool
+
+	// DropApps is the list of App or Bot IDs whose messages are dropped
+	// Env: GOPHER_FILTER_DROP_APPS (comma-separated)
+	DropApps []string
+
+	// IgnoreChannels is the list of channel IDs whose events are dropped
+	// Env: GOPHER_FILTER_IGNORE_CHANNELS (comma-separated)
+	IgnoreChannels []string
 }
 
 // C is the configuration struct.
 type C struct {
-	// LogLevel is the logging level
-	// Env: LOG_LEVEL
+	// LogLevel is the logging level. Defaults to info.
+	// Env: GOPHER_LOG_LEVEL
 	LogLevel zerolog.Level
 
-	// Env is the current environment.
+	// LogFormat is how logs are written to stdout, either "json" or "console"
+	// for human-readable output. Empty means "json".
+	// Env: GOPHER_LOG_FORMAT
+	LogFormat string
+
+	// LogSampleRate, if more than 1, only logs 1 in every LogSampleRate debug
+	// and trace messages. Messages at info and above are always logged.
+	// Env: GOPHER_LOG_SAMPLE_RATE
+	LogSampleRate uint32
+
+	// HandlerLogLevels overrides LogLevel for the workqueue handlers, by name,
+	// like "message=debug,team_join=warn".
+	// Env: GOPHER_HANDLER_LOG_LEVELS (comma-separated)
+	HandlerLogLevels map[string]zerolog.Level
+
+	// Env is the current environment. Defaults to development.
 	// Env: ENV
 	Env Environment
 
-	// Port is the TCP port for web workers to listen on, loaded from PORT
+	// Port is the TCP port for web workers to listen on, loaded from PORT.
+	// Defaults to 8080.
 	// Env: PORT
 	Port uint16
 
+	// ShutdownTimeout is how long the gateway, consumer, and archiver have to
+	// shut down gracefully after SIGTERM, stopping intake and draining their
+	// handlers. Heroku kills dynos 30 seconds after SIGTERM, so it should be
+	// less than that there. Defaults to 25s.
+	// Env: GOPHER_SHUTDOWN_TIMEOUT (like 20s)
+	ShutdownTimeout time.Duration
+
 	// Heroku are the Labs Dyno Metadata environment variables
 	Heroku H
 
@@ -133,6 +285,128 @@ type C struct {
 	// Slack is the Slack configuration, loaded from a few SLACK_* environment
 	// variables
 	Slack S
+
+	// Filter is the gateway event filter configuration, loaded from a few
+	// GOPHER_FILTER_* environment variables
+	Filter F
+
+	// DryRun is whether the Slack API calls that change something (posting,
+	// reacting, deleting, etc.) are only logged, instead of being made.
+	// Env: GOPHER_DRY_RUN
+	DryRun bool
+
+	// DryRunPlugins is the list of consumer plugins to run in dry-run mode,
+	// for when DryRun is false.
+	// Env: GOPHER_DRY_RUN_PLUGINS (comma-separated)
+	DryRunPlugins []string
+
+	// SafeBrowsingAPIKey is the Google Safe Browsing API key, used to scan
+	// links posted in public channels. If empty, only the local blocklist is
+	// used.
+	// Env: GOPHER_SAFE_BROWSING_API_KEY
+	SafeBrowsingAPIKey string
+
+	// FileScanAVURL is the URL of the anti-virus HTTP API that shared files
+	// are sent to for scanning. If empty, files are not virus scanned.
+	// Env: GOPHER_FILESCAN_AV_URL
+	FileScanAVURL string
+
+	// PriorityChannels is the list of channel IDs, like the admin channels,
+	// whose events the gateway publishes at high priority.
+	// Env: GOPHER_PRIORITY_CHANNELS (comma-separated)
+	PriorityChannels []string
+
+	// WorkqueueCodec is the name of the workqueue.Codec the gateway publishes
+	// event payloads with, either "json" or "msgpack". Empty means "json".
+	// Env: GOPHER_WORKQUEUE_CODEC
+	WorkqueueCodec string
+
+	// WorkqueueCompression is how the gateway compresses event payloads, either
+	// "gzip" or empty for no compression.
+	// Env: GOPHER_WORKQUEUE_COMPRESSION
+	WorkqueueCompression string
+
+	// WorkqueueEncryption is whether event payloads are encrypted with
+	// TokenEncryptionKey before they're published, since they hold message
+	// text and user IDs. Consumers decrypt them whenever TokenEncryptionKey is
+	// set, regardless.
+	// Env: GOPHER_WORKQUEUE_ENCRYPTION
+	WorkqueueEncryption bool
+
+	// Admins is the list of user IDs that always have the admin role, so
+	// there's someone to give the other roles to.
+	// Env: GOPHER_ADMINS (comma-separated)
+	Admins []string
+
+	// DatabaseURL is the PostgreSQL connection URL for the message archive,
+	// as set by Heroku Postgres. The archiver doesn't start without it.
+	// Env: DATABASE_URL
+	DatabaseURL string
+
+	// DigestChannelID is the channel the weekly analytics digest is posted to.
+	// If empty, the digest isn't posted.
+	// Env: GOPHER_DIGEST_CHANNEL
+	DigestChannelID string
+
+	// MetricsToken is the bearer token required to read the gateway's
+	// /metrics endpoint. If empty, the endpoint doesn't require one.
+	// Env: GOPHER_METRICS_TOKEN
+	MetricsToken string
+
+	// AdminAPIToken is the bearer token required to use the gateway's admin
+	// API, like /admin/replay. If empty, the admin API isn't served.
+	// Env: GOPHER_ADMIN_API_TOKEN
+	AdminAPIToken string
+
+	// MaintenanceMode is whether the gateway turns Slack events away with a
+	// 503, so Slack retries them later, regardless of whether maintenance mode
+	// was turned on with the admin API.
+	// Env: GOPHER_MAINTENANCE_MODE
+	MaintenanceMode bool
+
+	// TokenEncryptionKey is the AES-256 key, as 64 hex characters, that the
+	// secrets and personal data kept in Redis are encrypted with, like the bot
+	// tokens of the workspaces the app is installed to, reminders, and event
+	// payloads if WorkqueueEncryption is set. If empty, the app can't be
+	// installed to other workspaces, and only uses Slack.BotAccessToken, and
+	// reminders aren't encrypted.
+	// Env: GOPHER_TOKEN_ENCRYPTION_KEY
+	TokenEncryptionKey string
+
+	// TokenEncryptionOldKeys are the keys TokenEncryptionKey replaced, in the
+	// same format. Data encrypted with them can still be decrypted, and is
+	// encrypted again with TokenEncryptionKey once it's read.
+	// Env: GOPHER_TOKEN_ENCRYPTION_OLD_KEYS (comma-separated)
+	TokenEncryptionOldKeys []string
+
+	// DiscordBotToken is the token of the Discord bot the gateway receives a
+	// Discord community's messages and joins with, and the consumer replies
+	// as, so the same handlers serve it too. If empty, Discord isn't used.
+	// Env: GOPHER_DISCORD_BOT_TOKEN
+	DiscordBotToken string
+
+	// TLSCertFile and TLSKeyFile are the paths to the PEM certificate and key
+	// the gateway serves TLS with, for deployments that aren't behind a proxy
+	// that terminates it. Both must be set, or neither.
+	// Env: GOPHER_TLS_CERT_FILE, GOPHER_TLS_KEY_FILE
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// TLSAutocertDomains are the domains the gateway gets certificates for
+	// from Let's Encrypt, instead of using TLSCertFile and TLSKeyFile.
+	// Env: GOPHER_TLS_AUTOCERT_DOMAINS (comma-separated)
+	TLSAutocertDomains []string
+
+	// TLSAutocertCacheDir is the directory the Let's Encrypt certificates are
+	// cached in, so they aren't requested again on every restart.
+	// Env: GOPHER_TLS_AUTOCERT_CACHE_DIR
+	TLSAutocertCacheDir string
+
+	// SentryDSN is the Sentry project DSN the gateway and consumer report
+	// handler errors, panics, and parse failures to. If empty, they're only
+	// logged.
+	// Env: GOPHER_SENTRY_DSN (or SENTRY_DSN)
+	SentryDSN string
 }
 
 func secureRedisCredentials(s string, insecure bool) (host, user, password string, err error) {
@@ -178,26 +452,121 @@ func secureRedisCredentials(s string, insecure bool) (host, user, password strin
 	}
 }
 
+// splitList splits a comma-separated list, dropping empty elements.
+func splitList(s string) []string {
+	var l []string
+
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			l = append(l, v)
+		}
+	}
+
+	return l
+}
+
+// firstEnv returns the value of the first of the environment variables that's
+// set.
+func firstEnv(getenv func(string) string, names ...string) string {
+	for _, n := range names {
+		if v := getenv(n); len(v) > 0 {
+			return v
+		}
+	}
+
+	return ""
+}
+
 // LoadEnv loads the configuration from the appropriate environment variables.
 func LoadEnv() (C, error) {
-	var c C
+	return load(os.Getenv)
+}
 
-	if p := os.Getenv("PORT"); len(p) > 0 {
-		u, err := strconv.ParseUint(p, 10, 16)
-		if err != nil {
-			return C{}, fmt.Errorf("failed to parse PORT: %w", err)
+// load loads the configuration from the variables, getting their values with
+// getenv, and validates it.
+func load(getenv func(string) string) (C, error) {
+	c, errs := parse(getenv)
+
+	// only check what parsed, so there aren't errors about it being missing
+	if len(errs) == 0 {
+		errs = c.problems()
+	}
+
+	if len(errs) > 0 {
+		return C{}, errs
+	}
+
+	_ = os.Unsetenv("GOPHER_SLACK_CLIENT_SECRET")       // paranoia
+	_ = os.Unsetenv("GOPHER_SLACK_REQUEST_SECRET")      // paranoia
+	_ = os.Unsetenv("GOPHER_SLACK_BOT_ACCESS_TOKEN")    // paranoia
+	_ = os.Unsetenv("GOPHER_SLACK_ADMIN_ACCESS_TOKEN")  // paranoia
+	_ = os.Unsetenv("GOPHER_SLACK_APP_TOKEN")           // paranoia
+	_ = os.Unsetenv("SLACK_SIGNING_SECRET")             // paranoia
+	_ = os.Unsetenv("SLACK_BOT_TOKEN")                  // paranoia
+	_ = os.Unsetenv("SLACK_APP_TOKEN")                  // paranoia
+	_ = os.Unsetenv("GOPHER_SAFE_BROWSING_API_KEY")     // paranoia
+	_ = os.Unsetenv("DATABASE_URL")                     // paranoia
+	_ = os.Unsetenv("GOPHER_METRICS_TOKEN")             // paranoia
+	_ = os.Unsetenv("GOPHER_ADMIN_API_TOKEN")           // paranoia
+	_ = os.Unsetenv("GOPHER_TOKEN_ENCRYPTION_KEY")      // paranoia
+	_ = os.Unsetenv("GOPHER_TOKEN_ENCRYPTION_OLD_KEYS") // paranoia
+	_ = os.Unsetenv("GOPHER_DISCORD_BOT_TOKEN")         // paranoia
+	_ = os.Unsetenv("GOPHER_SENTRY_DSN")                // paranoia
+	_ = os.Unsetenv("SENTRY_DSN")                       // paranoia
+
+	return c, nil
+}
+
+// parse parses the configuration from the variables, getting their values
+// with getenv, and fills in the defaults. It returns all the values that
+// failed to parse.
+func parse(getenv func(string) string) (C, Errors) {
+	var (
+		c    C
+		errs Errors
+	)
+
+	c.Port = DefaultPort
+
+	if p := getenv("PORT"); len(p) > 0 {
+		u, err := strconv.ParseUint(p, 10, 64)
+
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("failed to parse PORT: %w", err))
+		case u == 0 || u > 65535:
+			errs = append(errs, fmt.Errorf("PORT must be between 1 and 65535, got %d", u))
+		default:
+			c.Port = uint16(u)
 		}
+	}
+
+	c.ShutdownTimeout = DefaultShutdownTimeout
+
+	if st := getenv("GOPHER_SHUTDOWN_TIMEOUT"); len(st) > 0 {
+		d, err := time.ParseDuration(st)
 
-		c.Port = uint16(u)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("failed to parse GOPHER_SHUTDOWN_TIMEOUT: %w", err))
+		case d <= 0:
+			errs = append(errs, fmt.Errorf("GOPHER_SHUTDOWN_TIMEOUT must be positive, got %s", d))
+		default:
+			c.ShutdownTimeout = d
+		}
 	}
 
-	if r := os.Getenv("REDIS_URL"); len(r) > 0 {
-		c.Redis.Insecure = os.Getenv("GOPHER_REDIS_INSECURE") == "1"
-		c.Redis.SkipVerify = os.Getenv("GOPHER_REDIS_SKIPVERIFY") == "1"
+	c.Redis.Insecure = getenv("GOPHER_REDIS_INSECURE") == "1"
+	c.Redis.SkipVerify = getenv("GOPHER_REDIS_SKIPVERIFY") == "1"
+	c.Redis.SentinelMaster = getenv("GOPHER_REDIS_SENTINEL_MASTER")
+	c.Redis.SentinelAddrs = splitList(getenv("GOPHER_REDIS_SENTINEL_ADDRS"))
+
+	c.Redis.ClusterAddrs = splitList(getenv("GOPHER_REDIS_CLUSTER_ADDRS"))
 
+	if r := getenv("REDIS_URL"); len(r) > 0 {
 		a, u, p, err := secureRedisCredentials(r, c.Redis.Insecure)
 		if err != nil {
-			return C{}, fmt.Errorf("failed to parse REDIS_URL: %w", err)
+			errs = append(errs, fmt.Errorf("failed to parse REDIS_URL: %w", err))
 		}
 
 		c.Redis.Addr = a
@@ -205,72 +574,90 @@ func LoadEnv() (C, error) {
 		c.Redis.Password = p
 	}
 
-	ll := os.Getenv("GOPHER_LOG_LEVEL")
-	if len(ll) == 0 {
-		ll = "info"
-	}
-
-	l, err := zerolog.ParseLevel(ll)
-	if err != nil {
-		return C{}, fmt.Errorf("failed to parse GOPHER_LOG_LEVEL: %w", err)
-	}
-
-	c.LogLevel = l
-	c.Env = strToEnv(os.Getenv("ENV"))
-
-	c.Heroku.AppID = os.Getenv("HEROKU_APP_ID")
-	c.Heroku.AppName = os.Getenv("HEROKU_APP_NAME")
-	c.Heroku.DynoID = os.Getenv("HEROKU_DYNO_ID")
-	c.Heroku.Commit = os.Getenv("HEROKU_SLUG_COMMIT")
+	c.LogLevel = DefaultLogLevel
 
-	c.Slack.AppID = os.Getenv("GOPHER_SLACK_APP_ID")
-	c.Slack.TeamID = os.Getenv("GOPHER_SLACK_TEAM_ID")
-	c.Slack.ClientID = os.Getenv("GOPHER_SLACK_CLIENT_ID")
-	c.Slack.RequestToken = os.Getenv("GOPHER_SLACK_REQUEST_TOKEN")
+	if ll := getenv("GOPHER_LOG_LEVEL"); len(ll) > 0 {
+		l, err := zerolog.ParseLevel(ll)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse GOPHER_LOG_LEVEL: %w", err))
+		}
 
-	c.Slack.ClientSecret = os.Getenv("GOPHER_SLACK_CLIENT_SECRET")
-	c.Slack.RequestSecret = os.Getenv("GOPHER_SLACK_REQUEST_SECRET")
-	c.Slack.BotAccessToken = os.Getenv("GOPHER_SLACK_BOT_ACCESS_TOKEN")
+		c.LogLevel = l
+	}
 
-	_ = os.Unsetenv("GOPHER_SLACK_CLIENT_SECRET")    // paranoia
-	_ = os.Unsetenv("GOPHER_SLACK_REQUEST_SECRET")   // paranoia
-	_ = os.Unsetenv("GOPHER_SLACK_BOT_ACCESS_TOKEN") // paranoia
+	c.LogFormat = getenv("GOPHER_LOG_FORMAT")
 
-	return c, nil
-}
+	if sr := getenv("GOPHER_LOG_SAMPLE_RATE"); len(sr) > 0 {
+		u, err := strconv.ParseUint(sr, 10, 32)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse GOPHER_LOG_SAMPLE_RATE: %w", err))
+		}
 
-// DefaultLogger returns a zerolog.Logger using settings from our config struct.
-func DefaultLogger(cfg C) zerolog.Logger {
-	// set up zerolog
-	zerolog.TimestampFieldName = "timestamp"
-	zerolog.TimeFieldFormat = zerolog.TimeFormatUnixMs
-	zerolog.SetGlobalLevel(cfg.LogLevel)
+		c.LogSampleRate = uint32(u)
+	}
 
-	// set up logging
-	return zerolog.New(os.Stdout).
-		With().Timestamp().Logger()
-}
+	if hl := splitList(getenv("GOPHER_HANDLER_LOG_LEVELS")); len(hl) > 0 {
+		levels, err := parseHandlerLogLevels(hl)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to parse GOPHER_HANDLER_LOG_LEVELS: %w", err))
+		}
 
-// DefaultRedis returns a default Redis config from our own config struct.
-func DefaultRedis(cfg C) *redis.Options {
-	r := &redis.Options{
-		Network:      "tcp",
-		Addr:         cfg.Redis.Addr,
-		Password:     cfg.Redis.Password,
-		DialTimeout:  2 * time.Second,
-		ReadTimeout:  2 * time.Second,
-		WriteTimeout: 2 * time.Second,
-		PoolSize:     20,
-		MinIdleConns: 5,
-		PoolTimeout:  2 * time.Second,
+		c.HandlerLogLevels = levels
 	}
 
-	// if Redis is TLS secured
-	if !cfg.Redis.Insecure {
-		r.TLSConfig = &tls.Config{
-			InsecureSkipVerify: cfg.Redis.SkipVerify,
-		} // #nosec G402 -- Heroku Redis has an untrusted cert
+	c.Env = strToEnv(getenv("ENV"))
+
+	c.Heroku.AppID = getenv("HEROKU_APP_ID")
+	c.Heroku.AppName = getenv("HEROKU_APP_NAME")
+	c.Heroku.DynoID = getenv("HEROKU_DYNO_ID")
+	c.Heroku.Commit = getenv("HEROKU_SLUG_COMMIT")
+	c.Heroku.ReleaseVersion = getenv("HEROKU_RELEASE_VERSION")
+
+	c.Slack.AppID = getenv("GOPHER_SLACK_APP_ID")
+	c.Slack.TeamID = getenv("GOPHER_SLACK_TEAM_ID")
+	c.Slack.ClientID = getenv("GOPHER_SLACK_CLIENT_ID")
+	c.Slack.RequestToken = getenv("GOPHER_SLACK_REQUEST_TOKEN")
+
+	c.Slack.ClientSecret = getenv("GOPHER_SLACK_CLIENT_SECRET")
+	c.Slack.RedirectURL = getenv("GOPHER_SLACK_REDIRECT_URL")
+	c.Slack.InstallScopes = splitList(getenv("GOPHER_SLACK_INSTALL_SCOPES"))
+	c.Slack.RequestSecret = firstEnv(getenv, "GOPHER_SLACK_REQUEST_SECRET", "SLACK_SIGNING_SECRET")
+	c.Slack.BotAccessToken = firstEnv(getenv, "GOPHER_SLACK_BOT_ACCESS_TOKEN", "SLACK_BOT_TOKEN")
+	c.Slack.AdminAccessToken = getenv("GOPHER_SLACK_ADMIN_ACCESS_TOKEN")
+	c.Slack.AppToken = firstEnv(getenv, "GOPHER_SLACK_APP_TOKEN", "SLACK_APP_TOKEN")
+	c.Slack.SocketMode = getenv("GOPHER_SLACK_SOCKET_MODE") == "1"
+
+	c.Filter.DropBotMessages = getenv("GOPHER_FILTER_DROP_BOT_MESSAGES") == "1"
+	c.Filter.DropApps = splitList(getenv("GOPHER_FILTER_DROP_APPS"))
+	c.Filter.IgnoreChannels = splitList(getenv("GOPHER_FILTER_IGNORE_CHANNELS"))
+
+	c.DryRun = getenv("GOPHER_DRY_RUN") == "1"
+	c.MaintenanceMode = getenv("GOPHER_MAINTENANCE_MODE") == "1"
+	c.DryRunPlugins = splitList(getenv("GOPHER_DRY_RUN_PLUGINS"))
+
+	c.SafeBrowsingAPIKey = getenv("GOPHER_SAFE_BROWSING_API_KEY")
+	c.FileScanAVURL = getenv("GOPHER_FILESCAN_AV_URL")
+	c.PriorityChannels = splitList(getenv("GOPHER_PRIORITY_CHANNELS"))
+	c.WorkqueueCodec = getenv("GOPHER_WORKQUEUE_CODEC")
+	c.WorkqueueCompression = getenv("GOPHER_WORKQUEUE_COMPRESSION")
+	c.WorkqueueEncryption = getenv("GOPHER_WORKQUEUE_ENCRYPTION") == "1"
+	c.Admins = splitList(getenv("GOPHER_ADMINS"))
+	c.DatabaseURL = getenv("DATABASE_URL")
+	c.DigestChannelID = getenv("GOPHER_DIGEST_CHANNEL")
+	c.MetricsToken = getenv("GOPHER_METRICS_TOKEN")
+	c.AdminAPIToken = getenv("GOPHER_ADMIN_API_TOKEN")
+	c.TokenEncryptionKey = getenv("GOPHER_TOKEN_ENCRYPTION_KEY")
+	c.TokenEncryptionOldKeys = splitList(getenv("GOPHER_TOKEN_ENCRYPTION_OLD_KEYS"))
+	c.DiscordBotToken = getenv("GOPHER_DISCORD_BOT_TOKEN")
+	c.TLSCertFile = getenv("GOPHER_TLS_CERT_FILE")
+	c.TLSKeyFile = getenv("GOPHER_TLS_KEY_FILE")
+	c.TLSAutocertDomains = splitList(getenv("GOPHER_TLS_AUTOCERT_DOMAINS"))
+	c.TLSAutocertCacheDir = getenv("GOPHER_TLS_AUTOCERT_CACHE_DIR")
+	c.SentryDSN = firstEnv(getenv, "GOPHER_SENTRY_DSN", "SENTRY_DSN")
+
+	if len(errs) > 0 {
+		return C{}, errs
 	}
 
-	return r
+	return c, nil
 }
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// Load loads the configuration from the file named by the GOPHER_CONFIG_FILE
+// environment variable, like LoadFile, or from the environment alone if it's
+// unset. Secret references are resolved with the DefaultSecretsProviders.
+func Load() (C, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), secretsTimeout)
+	defer cancel()
+
+	c, err := LoadSecrets(ctx, DefaultSecretsProviders())
+
+	_ = os.Unsetenv("VAULT_TOKEN") // paranoia
+
+	return c, err
+}
+
+// LoadSecrets is like Load, but resolves secret references with the providers,
+// by name. Variables holding secrets, like GOPHER_SLACK_BOT_ACCESS_TOKEN or
+// REDIS_URL, can be set to a reference to the secret instead, of the form
+// <provider>:<ref>, like file:/run/secrets/slack_bot_token.
+func LoadSecrets(ctx context.Context, providers map[string]SecretsProvider) (C, error) {
+	getenv := os.Getenv
+
+	if path := os.Getenv("GOPHER_CONFIG_FILE"); len(path) > 0 {
+		vars, err := readFile(path)
+		if err != nil {
+			return C{}, err
+		}
+
+		getenv = fileGetenv(vars)
+	}
+
+	getenv, err := resolveSecrets(ctx, getenv, providers)
+	if err != nil {
+		return C{}, err
+	}
+
+	return load(getenv)
+}
+
+// LoadFile loads the configuration from the YAML or TOML file at path, chosen
+// by its extension, merged with the environment variables. The file's keys are
+// the environment variable names, in any case, so a file is a drop-in for
+// exporting the variables:
+//
+//	redis_url: redis://localhost:6379
+//	gopher_log_level: debug
+//	gopher_admins: [U123, U456]
+//
+// Lists are joined into comma-separated values, and booleans become 1 or 0.
+// Environment variables that are set win over the file.
+func LoadFile(path string) (C, error) {
+	vars, err := readFile(path)
+	if err != nil {
+		return C{}, err
+	}
+
+	return load(fileGetenv(vars))
+}
+
+// fileGetenv returns a getenv func for the config file's variables, that
+// prefers the environment's.
+func fileGetenv(vars map[string]string) func(string) string {
+	return func(name string) string {
+		if v, ok := os.LookupEnv(name); ok {
+			return v
+		}
+
+		return vars[name]
+	}
+}
+
+// readFile returns the variables in the config file, by upper case name.
+func readFile(path string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	raw := make(map[string]interface{})
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+
+	case ".toml":
+		_, err = toml.Decode(string(data), &raw)
+
+	default:
+		return nil, fmt.Errorf("config file %s must be .yaml, .yml, or .toml", path)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	vars := make(map[string]string, len(raw))
+
+	for k, v := range raw {
+		s, err := fileValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s in config file %s: %w", k, path, err)
+		}
+
+		vars[strings.ToUpper(k)] = s
+	}
+
+	return vars, nil
+}
+
+// fileValue returns the config file value as it would be in the environment.
+func fileValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+
+	case string:
+		return v, nil
+
+	case bool:
+		if v {
+			return "1", nil
+		}
+
+		return "0", nil
+
+	case int, int64, uint64, float64:
+		return fmt.Sprint(v), nil
+
+	case []interface{}:
+		l := make([]string, 0, len(v))
+
+		for _, e := range v {
+			if _, ok := e.([]interface{}); ok {
+				return "", fmt.Errorf("lists can't be nested")
+			}
+
+			s, err := fileValue(e)
+			if err != nil {
+				return "", err
+			}
+
+			l = append(l, s)
+		}
+
+		return strings.Join(l, ","), nil
+
+	default:
+		return "", fmt.Errorf("must be a string, number, boolean, or list, not %T", v)
+	}
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rs/zerolog"
+)
+
+func TestLoadFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopher-config")
+	if err != nil {
+		t.Fatalf("failed to make temp dir: %v", err)
+	}
+
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	want := C{
+		LogLevel:        zerolog.DebugLevel,
+		Env:             Testing,
+		Port:            1234,
+		ShutdownTimeout: DefaultShutdownTimeout,
+		Redis: R{
+			Addr:     "redis.example.org:4321",
+			Password: "1234",
+		},
+		Slack: S{
+			BotAccessToken: "xoxb-123",
+			SocketMode:     true,
+			AppToken:       "xapp-123",
+		},
+		DryRun: true,
+		Admins: []string{"U123", "U456"},
+	}
+
+	tests := []struct {
+		name string
+		file string
+		data string
+		env  map[string]string
+		err  string
+		want C
+	}{
+		{
+			name: "yaml",
+			file: "gopher.yaml",
+			data: `
+env: testing
+port: 1234
+redis_url: rediss://:1234@redis.example.org:4321
+gopher_log_level: debug
+GOPHER_SLACK_BOT_ACCESS_TOKEN: xoxb-123
+gopher_slack_app_token: xapp-123
+gopher_slack_socket_mode: true
+gopher_dry_run: 1
+gopher_admins: [U123, U456]
+`,
+			want: want,
+		},
+		{
+			name: "toml",
+			file: "gopher.toml",
+			data: `
+env = "testing"
+port = 1234
+redis_url = "rediss://:1234@redis.example.org:4321"
+gopher_log_level = "debug"
+GOPHER_SLACK_BOT_ACCESS_TOKEN = "xoxb-123"
+gopher_slack_app_token = "xapp-123"
+gopher_slack_socket_mode = true
+gopher_dry_run = 1
+gopher_admins = ["U123", "U456"]
+`,
+			want: want,
+		},
+		{
+			name: "env_wins",
+			file: "gopher.yml",
+			data: `
+env: production
+gopher_log_level: debug
+`,
+			env: map[string]string{"ENV": "testing", "GOPHER_LOG_LEVEL": "warn"},
+			want: C{
+				LogLevel:        zerolog.WarnLevel,
+				Env:             Testing,
+				Port:            DefaultPort,
+				ShutdownTimeout: DefaultShutdownTimeout,
+			},
+		},
+		{
+			name: "nested",
+			file: "gopher.yaml",
+			data: `
+redis:
+  url: redis://localhost
+`,
+			err: "invalid redis in config file",
+		},
+		{
+			name: "unknown_extension",
+			file: "gopher.json",
+			data: `{}`,
+			err:  "must be .yaml, .yml, or .toml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.file)
+
+			if err := ioutil.WriteFile(path, []byte(tt.data), 0600); err != nil {
+				t.Fatalf("failed to write config file: %v", err)
+			}
+
+			for k, v := range tt.env {
+				_ = os.Setenv(k, v)
+				defer func(k string) { _ = os.Unsetenv(k) }(k)
+			}
+
+			got, err := LoadFile(path)
+			if cont := testErrCheck(t, "LoadFile()", tt.err, err); !cont {
+				return
+			}
+
+			cmpDiff(t, "C", cmp.Diff(tt.want, got))
+		})
+	}
+}
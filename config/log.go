@@ -0,0 +1,157 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// logState is what the loggers from DefaultLogger filter their messages by. A
+// zerolog.Logger's own level can't change once it's built, so it's kept here
+// instead, where the Watcher can change it.
+type logState struct {
+	level    zerolog.Level
+	handlers map[string]zerolog.Level
+	sampler  zerolog.Sampler
+}
+
+// logs is the current *logState
+var logs atomic.Value
+
+// setLogLevels sets the log levels, and the sampling, to those from cfg.
+func setLogLevels(cfg C) {
+	s := &logState{
+		level:    cfg.LogLevel,
+		handlers: cfg.HandlerLogLevels,
+	}
+
+	if cfg.LogSampleRate > 1 {
+		b := &zerolog.BasicSampler{N: cfg.LogSampleRate}
+		s.sampler = zerolog.LevelSampler{TraceSampler: b, DebugSampler: b}
+	}
+
+	// the global level is the lowest of them, so the handlers can log at lower
+	// levels than everything else
+	min := cfg.LogLevel
+
+	for _, l := range cfg.HandlerLogLevels {
+		if l < min {
+			min = l
+		}
+	}
+
+	logs.Store(s)
+	zerolog.SetGlobalLevel(min)
+}
+
+// levelSampler is a zerolog.Sampler that drops the messages below the current
+// log level, for the handler if it has its own, and then samples the rest.
+type levelSampler struct {
+	handler string
+}
+
+func (ls levelSampler) Sample(lvl zerolog.Level) bool {
+	s, _ := logs.Load().(*logState)
+	if s == nil {
+		return true
+	}
+
+	if lvl < s.handlerLevel(ls.handler) {
+		return false
+	}
+
+	if s.sampler != nil {
+		return s.sampler.Sample(lvl)
+	}
+
+	return true
+}
+
+// handlerLevel returns the log level for the handler. Handlers registered on
+// the same stream more than once are named like "message#2", and use the
+// level for "message" unless they have their own.
+func (s *logState) handlerLevel(handler string) zerolog.Level {
+	if len(handler) == 0 {
+		return s.level
+	}
+
+	if l, ok := s.handlers[handler]; ok {
+		return l
+	}
+
+	if i := strings.IndexByte(handler, '#'); i > 0 {
+		if l, ok := s.handlers[handler[:i]]; ok {
+			return l
+		}
+	}
+
+	return s.level
+}
+
+// parseHandlerLogLevels parses a list of handler=level pairs.
+func parseHandlerLogLevels(list []string) (map[string]zerolog.Level, error) {
+	levels := make(map[string]zerolog.Level, len(list))
+
+	for _, hl := range list {
+		i := strings.IndexByte(hl, '=')
+		if i < 1 {
+			return nil, fmt.Errorf("%q must be <handler>=<level>", hl)
+		}
+
+		l, err := zerolog.ParseLevel(hl[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid level for handler %s: %w", hl[:i], err)
+		}
+
+		levels[hl[:i]] = l
+	}
+
+	return levels, nil
+}
+
+// DefaultLogger returns a zerolog.Logger using settings from our config struct.
+func DefaultLogger(cfg C) zerolog.Logger {
+	// set up zerolog
+	zerolog.TimestampFieldName = "timestamp"
+	zerolog.TimeFieldFormat = zerolog.TimeFormatUnixMs
+	setLogLevels(cfg)
+
+	var w io.Writer = os.Stdout
+
+	if cfg.LogFormat == "console" {
+		w = zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}
+	}
+
+	// set up logging, with which dyno it's from so logs from all of them can
+	// be told apart once they're aggregated
+	return herokuContext(zerolog.New(w).Sample(levelSampler{}).With().Timestamp(), cfg.Heroku).
+		Logger()
+}
+
+// herokuContext adds the Heroku dyno metadata that's set to zc.
+func herokuContext(zc zerolog.Context, h H) zerolog.Context {
+	fields := []struct{ key, value string }{
+		{"app", h.AppName},
+		{"dyno_id", h.DynoID},
+		{"release", h.ReleaseVersion},
+	}
+
+	for _, f := range fields {
+		if len(f.value) > 0 {
+			zc = zc.Str(f.key, f.value)
+		}
+	}
+
+	return zc
+}
+
+// HandlerLogger returns l, which should be from DefaultLogger, using the log
+// level for the named workqueue handler from C.HandlerLogLevels.
+func HandlerLogger(l zerolog.Logger, handler string) zerolog.Logger {
+	return l.Sample(levelSampler{handler: handler})
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestHandlerLogger(t *testing.T) {
+	defer setLogLevels(C{LogLevel: zerolog.TraceLevel})
+
+	setLogLevels(C{
+		LogLevel: zerolog.InfoLevel,
+		HandlerLogLevels: map[string]zerolog.Level{
+			"message":   zerolog.DebugLevel,
+			"team_join": zerolog.WarnLevel,
+		},
+	})
+
+	var buf bytes.Buffer
+
+	l := zerolog.New(&buf).Sample(levelSampler{})
+
+	tests := []struct {
+		handler string
+		level   zerolog.Level
+		want    bool
+	}{
+		{handler: "", level: zerolog.DebugLevel, want: false},
+		{handler: "", level: zerolog.InfoLevel, want: true},
+		{handler: "message", level: zerolog.DebugLevel, want: true},
+		{handler: "message", level: zerolog.TraceLevel, want: false},
+		{handler: "message#2", level: zerolog.DebugLevel, want: true},
+		{handler: "team_join", level: zerolog.InfoLevel, want: false},
+		{handler: "team_join", level: zerolog.WarnLevel, want: true},
+		{handler: "raw", level: zerolog.DebugLevel, want: false},
+		{handler: "raw", level: zerolog.InfoLevel, want: true},
+	}
+
+	for _, tt := range tests {
+		buf.Reset()
+
+		hl := l
+		if len(tt.handler) > 0 {
+			hl = HandlerLogger(l, tt.handler)
+		}
+
+		hl.WithLevel(tt.level).Msg("test")
+
+		if got := buf.Len() > 0; got != tt.want {
+			t.Errorf("handler %q at %s logged = %t, want %t", tt.handler, tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestHandlerLogger_sampling(t *testing.T) {
+	defer setLogLevels(C{LogLevel: zerolog.TraceLevel})
+
+	setLogLevels(C{LogLevel: zerolog.DebugLevel, LogSampleRate: 3})
+
+	var buf bytes.Buffer
+
+	l := HandlerLogger(zerolog.New(&buf), "message")
+
+	for i := 0; i < 6; i++ {
+		l.Debug().Msg("sampled")
+		l.Info().Msg("always")
+	}
+
+	if got := strings.Count(buf.String(), `"sampled"`); got != 2 {
+		t.Errorf("logged %d debug messages, want 2", got)
+	}
+
+	if got := strings.Count(buf.String(), `"always"`); got != 6 {
+		t.Errorf("logged %d info messages, want 6", got)
+	}
+}
+
+func Test_herokuContext(t *testing.T) {
+	var buf bytes.Buffer
+
+	l := herokuContext(zerolog.New(&buf).With(), H{AppName: "gopher", DynoID: "abc123"}).Logger()
+	l.Info().Msg("test")
+
+	want := `{"level":"info","app":"gopher","dyno_id":"abc123","message":"test"}` + "\n"
+
+	if got := buf.String(); got != want {
+		t.Fatalf("logged %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// secretsTimeout is how long Load waits for the secrets providers
+const secretsTimeout = 30 * time.Second
+
+// secretVars are the variables whose values may be secret references, of the
+// form <provider>:<ref>, like vault:secret/data/gopherbot#slack_bot_token.
+var secretVars = []string{
+	"REDIS_URL",
+	"DATABASE_URL",
+	"GOPHER_SLACK_CLIENT_SECRET",
+	"GOPHER_SLACK_REQUEST_SECRET",
+	"SLACK_SIGNING_SECRET",
+	"GOPHER_SLACK_BOT_ACCESS_TOKEN",
+	"SLACK_BOT_TOKEN",
+	"GOPHER_SLACK_ADMIN_ACCESS_TOKEN",
+	"GOPHER_SLACK_APP_TOKEN",
+	"SLACK_APP_TOKEN",
+	"GOPHER_SAFE_BROWSING_API_KEY",
+	"GOPHER_METRICS_TOKEN",
+	"GOPHER_ADMIN_API_TOKEN",
+	"GOPHER_TOKEN_ENCRYPTION_KEY",
+	"GOPHER_TOKEN_ENCRYPTION_OLD_KEYS",
+	"GOPHER_DISCORD_BOT_TOKEN",
+	"GOPHER_SENTRY_DSN",
+	"SENTRY_DSN",
+}
+
+// SecretsProvider resolves secret references to the secrets' values. Because
+// they're resolved when the configuration is loaded, a secret is rotated by
+// changing it in the provider and restarting, without a deploy.
+type SecretsProvider interface {
+	Secret(ctx context.Context, ref string) (string, error)
+}
+
+// EnvSecrets is a SecretsProvider whose refs are the names of the environment
+// variables holding the secrets.
+type EnvSecrets struct{}
+
+// Secret satisfies SecretsProvider.
+func (EnvSecrets) Secret(_ context.Context, ref string) (string, error) {
+	v, ok := os.LookupEnv(ref)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", ref)
+	}
+
+	return v, nil
+}
+
+// FileSecrets is a SecretsProvider whose refs are the paths of files holding
+// the secrets, like Docker or Kubernetes secrets. Trailing newlines are
+// trimmed.
+type FileSecrets struct{}
+
+// Secret satisfies SecretsProvider.
+func (FileSecrets) Secret(_ context.Context, ref string) (string, error) {
+	b, err := ioutil.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
+
+// VaultSecrets is a SecretsProvider for HashiCorp Vault's key-value secrets
+// engine. Its refs are the path of the secret and the key within it, joined by
+// #, like secret/data/gopherbot#slack_bot_token. Both versions of the engine
+// are supported, as long as the path is the one the API is called with.
+type VaultSecrets struct {
+	// Addr is the URL of the Vault server, like https://vault.example.org:8200
+	Addr string
+
+	// Token is the Vault token to authenticate with
+	Token string
+
+	// Namespace is the Vault Enterprise namespace. Optional.
+	Namespace string
+
+	// HTTPClient is the client to make requests with. Optional.
+	HTTPClient *http.Client
+}
+
+// Secret satisfies SecretsProvider.
+func (v VaultSecrets) Secret(ctx context.Context, ref string) (string, error) {
+	i := strings.LastIndexByte(ref, '#')
+	if i <= 0 || i == len(ref)-1 {
+		return "", fmt.Errorf("vault reference %q must be <path>#<key>", ref)
+	}
+
+	path, key := strings.Trim(ref[:i], "/"), ref[i+1:]
+
+	u, err := url.Parse(strings.TrimRight(v.Addr, "/") + "/v1/" + path)
+	if err != nil {
+		return "", fmt.Errorf("failed to build Vault URL: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build Vault request: %w", err)
+	}
+
+	req.Header.Set("X-Vault-Token", v.Token)
+
+	if len(v.Namespace) > 0 {
+		req.Header.Set("X-Vault-Namespace", v.Namespace)
+	}
+
+	hc := v.HTTPClient
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+
+	resp, err := hc.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get %s from Vault: %w", path, err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get %s from Vault: unexpected status %s", path, resp.Status)
+	}
+
+	var body struct {
+		Data map[string]json.RawMessage `json:"data"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode Vault response: %w", err)
+	}
+
+	data := body.Data
+
+	// version 2 of the engine nests the secret's data next to its metadata
+	if inner, ok := data["data"]; ok {
+		if _, ok := data["metadata"]; ok {
+			data = nil
+
+			if err := json.Unmarshal(inner, &data); err != nil {
+				return "", fmt.Errorf("failed to decode Vault secret: %w", err)
+			}
+		}
+	}
+
+	raw, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %s", path, key)
+	}
+
+	var s string
+
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", fmt.Errorf("vault secret %s key %s is not a string", path, key)
+	}
+
+	return s, nil
+}
+
+// DefaultSecretsProviders returns the env and file providers, and the vault
+// provider if VAULT_ADDR is set, configured with VAULT_TOKEN and
+// VAULT_NAMESPACE.
+func DefaultSecretsProviders() map[string]SecretsProvider {
+	p := map[string]SecretsProvider{
+		"env":  EnvSecrets{},
+		"file": FileSecrets{},
+	}
+
+	if addr := os.Getenv("VAULT_ADDR"); len(addr) > 0 {
+		p["vault"] = VaultSecrets{
+			Addr:       addr,
+			Token:      os.Getenv("VAULT_TOKEN"),
+			Namespace:  os.Getenv("VAULT_NAMESPACE"),
+			HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+
+	return p
+}
+
+// resolveSecrets returns a getenv func that returns the resolved secrets for
+// the secretVars holding references to the providers, and whatever getenv
+// returns for the others. A value whose prefix isn't a provider's name, like
+// a redis:// URL, isn't a reference.
+func resolveSecrets(ctx context.Context, getenv func(string) string, providers map[string]SecretsProvider) (func(string) string, error) {
+	resolved := make(map[string]string)
+
+	for _, name := range secretVars {
+		v := getenv(name)
+
+		i := strings.IndexByte(v, ':')
+		if i <= 0 {
+			continue
+		}
+
+		p, ok := providers[v[:i]]
+		if !ok {
+			continue
+		}
+
+		s, err := p.Secret(ctx, v[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve %s secret for %s: %w", v[:i], name, err)
+		}
+
+		if len(s) == 0 {
+			return nil, errors.New("resolved empty secret for " + name)
+		}
+
+		resolved[name] = s
+	}
+
+	return func(name string) string {
+		if s, ok := resolved[name]; ok {
+			return s
+		}
+
+		return getenv(name)
+	}, nil
+}
@@ -0,0 +1,148 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type mapSecrets map[string]string
+
+func (m mapSecrets) Secret(_ context.Context, ref string) (string, error) {
+	s, ok := m[ref]
+	if !ok {
+		return "", errors.New("no secret " + ref)
+	}
+
+	return s, nil
+}
+
+func Test_resolveSecrets(t *testing.T) {
+	env := map[string]string{
+		"REDIS_URL":                     "redis://u:p@redis.example.org",
+		"DATABASE_URL":                  "test:database_url",
+		"GOPHER_SLACK_BOT_ACCESS_TOKEN": "test:bot_token",
+		"GOPHER_SLACK_APP_TOKEN":        "other:app_token",
+		"GOPHER_SLACK_APP_ID":           "test:app_id",
+	}
+
+	getenv := func(name string) string { return env[name] }
+
+	providers := map[string]SecretsProvider{
+		"test": mapSecrets{
+			"database_url": "postgres://u:p@db.example.org/gopher",
+			"bot_token":    "xoxb-123",
+			"app_id":       "A123",
+		},
+	}
+
+	got, err := resolveSecrets(context.Background(), getenv, providers)
+	if err != nil {
+		t.Fatalf("resolveSecrets() unexpected error: %v", err)
+	}
+
+	want := map[string]string{
+		"REDIS_URL":                     "redis://u:p@redis.example.org",
+		"DATABASE_URL":                  "postgres://u:p@db.example.org/gopher",
+		"GOPHER_SLACK_BOT_ACCESS_TOKEN": "xoxb-123",
+		"GOPHER_SLACK_APP_TOKEN":        "other:app_token", // unknown provider
+		"GOPHER_SLACK_APP_ID":           "test:app_id",     // not a secret
+	}
+
+	for name, w := range want {
+		if g := got(name); g != w {
+			t.Errorf("%s = %q, want %q", name, g, w)
+		}
+	}
+
+	env["GOPHER_METRICS_TOKEN"] = "test:missing"
+
+	_, err = resolveSecrets(context.Background(), getenv, providers)
+	testErrCheck(t, "resolveSecrets()", "failed to resolve test secret for GOPHER_METRICS_TOKEN: no secret missing", err)
+}
+
+func TestVaultSecrets_Secret(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "s.token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/secret/data/gopherbot":
+			_, _ = w.Write([]byte(`{"data":{"data":{"bot_token":"xoxb-123","n":1},"metadata":{"version":2}}}`))
+		case "/v1/kv/gopherbot":
+			_, _ = w.Write([]byte(`{"data":{"bot_token":"xoxb-456"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name  string
+		token string
+		ref   string
+		want  string
+		err   string
+	}{
+		{
+			name: "kv_v2",
+			ref:  "secret/data/gopherbot#bot_token",
+			want: "xoxb-123",
+		},
+		{
+			name: "kv_v1",
+			ref:  "/kv/gopherbot#bot_token",
+			want: "xoxb-456",
+		},
+		{
+			name: "missing_key",
+			ref:  "secret/data/gopherbot#app_token",
+			err:  "vault secret secret/data/gopherbot has no key app_token",
+		},
+		{
+			name: "not_string",
+			ref:  "secret/data/gopherbot#n",
+			err:  "is not a string",
+		},
+		{
+			name: "not_found",
+			ref:  "secret/data/other#bot_token",
+			err:  "unexpected status 404 Not Found",
+		},
+		{
+			name:  "forbidden",
+			token: "s.other",
+			ref:   "secret/data/gopherbot#bot_token",
+			err:   "unexpected status 403 Forbidden",
+		},
+		{
+			name: "no_key",
+			ref:  "secret/data/gopherbot",
+			err:  "must be <path>#<key>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := tt.token
+			if len(token) == 0 {
+				token = "s.token"
+			}
+
+			v := VaultSecrets{Addr: srv.URL + "/", Token: token}
+
+			got, err := v.Secret(context.Background(), tt.ref)
+			if cont := testErrCheck(t, "Secret()", tt.err, err); !cont {
+				return
+			}
+
+			if got != tt.want {
+				t.Fatalf("Secret() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
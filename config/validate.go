@@ -0,0 +1,131 @@
+package config
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// DefaultPort is the C.Port if PORT isn't set.
+	DefaultPort = 8080
+
+	// DefaultLogLevel is the C.LogLevel if GOPHER_LOG_LEVEL isn't set.
+	DefaultLogLevel = zerolog.InfoLevel
+
+	// DefaultShutdownTimeout is the C.ShutdownTimeout if
+	// GOPHER_SHUTDOWN_TIMEOUT isn't set. It leaves some of Heroku's 30 seconds
+	// for the process to exit.
+	DefaultShutdownTimeout = 25 * time.Second
+)
+
+// Errors are all the problems found with the configuration, so they can be
+// fixed in one go instead of one restart at a time.
+type Errors []error
+
+// Error satisfies error.
+func (e Errors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d configuration errors:", len(e))
+
+	for _, err := range e {
+		b.WriteString("\n\t")
+		b.WriteString(err.Error())
+	}
+
+	return b.String()
+}
+
+// Validate returns Errors describing every problem with the configuration, or
+// nil if there are none. The Load functions already call it.
+func (c C) Validate() error {
+	if errs := c.problems(); len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func (c C) problems() Errors {
+	var errs Errors
+
+	if c.Port == 0 {
+		errs = append(errs, fmt.Errorf("PORT must be between 1 and 65535, got 0"))
+	}
+
+	if len(c.Redis.SentinelMaster) > 0 && len(c.Redis.SentinelAddrs) == 0 {
+		errs = append(errs, fmt.Errorf("GOPHER_REDIS_SENTINEL_MASTER requires GOPHER_REDIS_SENTINEL_ADDRS"))
+	}
+
+	if len(c.Redis.SentinelMaster) > 0 && len(c.Redis.ClusterAddrs) > 0 {
+		errs = append(errs, fmt.Errorf("GOPHER_REDIS_SENTINEL_MASTER and GOPHER_REDIS_CLUSTER_ADDRS can't both be set"))
+	}
+
+	if err := c.Slack.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("invalid Slack configuration: %w", err))
+	}
+
+	switch c.LogFormat {
+	case "", "json", "console":
+	default:
+		errs = append(errs, fmt.Errorf("GOPHER_LOG_FORMAT must be json or console, got %q", c.LogFormat))
+	}
+
+	switch c.WorkqueueCodec {
+	case "", "json", "msgpack":
+	default:
+		errs = append(errs, fmt.Errorf("GOPHER_WORKQUEUE_CODEC must be json or msgpack, got %q", c.WorkqueueCodec))
+	}
+
+	switch c.WorkqueueCompression {
+	case "", "gzip":
+	default:
+		errs = append(errs, fmt.Errorf("GOPHER_WORKQUEUE_COMPRESSION must be gzip or empty, got %q", c.WorkqueueCompression))
+	}
+
+	if len(c.FileScanAVURL) > 0 {
+		if u, err := url.Parse(c.FileScanAVURL); err != nil || !u.IsAbs() || len(u.Host) == 0 {
+			errs = append(errs, fmt.Errorf("GOPHER_FILESCAN_AV_URL must be an absolute URL, got %q", c.FileScanAVURL))
+		}
+	}
+
+	if len(c.TokenEncryptionKey) > 0 && !validEncryptionKey(c.TokenEncryptionKey) {
+		errs = append(errs, fmt.Errorf("GOPHER_TOKEN_ENCRYPTION_KEY must be 64 hex characters"))
+	}
+
+	if c.WorkqueueEncryption && len(c.TokenEncryptionKey) == 0 {
+		errs = append(errs, fmt.Errorf("GOPHER_WORKQUEUE_ENCRYPTION requires GOPHER_TOKEN_ENCRYPTION_KEY"))
+	}
+
+	if len(c.TokenEncryptionOldKeys) > 0 && len(c.TokenEncryptionKey) == 0 {
+		errs = append(errs, fmt.Errorf("GOPHER_TOKEN_ENCRYPTION_OLD_KEYS requires GOPHER_TOKEN_ENCRYPTION_KEY"))
+	}
+
+	for _, k := range c.TokenEncryptionOldKeys {
+		if !validEncryptionKey(k) {
+			errs = append(errs, fmt.Errorf("GOPHER_TOKEN_ENCRYPTION_OLD_KEYS must be 64 hex characters each"))
+			break
+		}
+	}
+
+	if (len(c.TLSCertFile) > 0) != (len(c.TLSKeyFile) > 0) {
+		errs = append(errs, fmt.Errorf("GOPHER_TLS_CERT_FILE and GOPHER_TLS_KEY_FILE must both be set, or neither"))
+	}
+
+	return errs
+}
+
+// validEncryptionKey returns whether s is an AES-256 key encoded as hex.
+func validEncryptionKey(s string) bool {
+	k, err := hex.DecodeString(s)
+	return err == nil && len(k) == 32
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// watchInterval is how often the Watcher checks whether the config file
+// changed
+const watchInterval = 5 * time.Second
+
+// Watcher reloads the configuration when the process gets a SIGHUP, or the
+// file named by GOPHER_CONFIG_FILE changes, and tells its subscribers. The
+// environment can't change while the process is running, so reloading is only
+// useful with a config file.
+//
+// Only the settings that can change without a restart are reloaded: the log
+// levels and sampling, the gateway filter, the priority channels, the plugins
+// in dry-run mode, the admins, and the digest channel. The rest, like global
+// dry-run mode and the Redis and Slack credentials, keep the values they
+// started with. A change only takes effect in the commands that subscribe to
+// it.
+type Watcher struct {
+	path string
+	l    zerolog.Logger
+
+	mu   sync.Mutex
+	cur  C
+	subs []func(old, cur C)
+}
+
+// NewWatcher returns a *Watcher, starting from c, which it doesn't reload
+// until it's Run.
+func NewWatcher(c C, logger zerolog.Logger) *Watcher {
+	return &Watcher{
+		path: os.Getenv("GOPHER_CONFIG_FILE"),
+		l:    logger,
+		cur:  c,
+	}
+}
+
+// Subscribe has fn called with the old and new configuration each time it
+// changes. Subscribers are called in the order they subscribed, from the
+// goroutine that reloaded it.
+func (w *Watcher) Subscribe(fn func(old, cur C)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subs = append(w.subs, fn)
+}
+
+// Current returns the current configuration.
+func (w *Watcher) Current() C {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.cur
+}
+
+// Reload reloads the configuration, telling the subscribers if it changed. If
+// the new configuration can't be loaded, the current one is kept.
+func (w *Watcher) Reload() error {
+	getenv := os.Getenv
+
+	if len(w.path) > 0 {
+		vars, err := readFile(w.path)
+		if err != nil {
+			return err
+		}
+
+		getenv = fileGetenv(vars)
+	}
+
+	// the secrets aren't reloaded, and may be references, so they're left out
+	next, err := parse(withoutSecrets(getenv))
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+
+	old := w.cur
+	w.cur = reloadable(old, next)
+	cur := w.cur
+	subs := make([]func(C, C), len(w.subs))
+	copy(subs, w.subs)
+
+	w.mu.Unlock()
+
+	if reflect.DeepEqual(old, cur) {
+		return nil
+	}
+
+	for _, fn := range subs {
+		fn(old, cur)
+	}
+
+	return nil
+}
+
+// Run reloads the configuration on SIGHUP, or when the config file changes,
+// until ctx is canceled. The returned channel is closed once it has stopped.
+func (w *Watcher) Run(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		defer close(done)
+		defer signal.Stop(hup)
+
+		t := time.NewTicker(watchInterval)
+		defer t.Stop()
+
+		mod := w.modTime()
+
+		for {
+			select {
+			case <-hup:
+				w.l.Info().Msg("SIGHUP received: reloading config")
+
+			case <-t.C:
+				m := w.modTime()
+				if m.Equal(mod) {
+					continue
+				}
+
+				mod = m
+
+				w.l.Info().
+					Str("path", w.path).
+					Msg("config file changed: reloading config")
+
+			case <-ctx.Done():
+				return
+			}
+
+			if err := w.Reload(); err != nil {
+				w.l.Error().
+					Err(err).
+					Msg("failed to reload config; keeping the current one")
+			}
+		}
+	}()
+
+	return done
+}
+
+// modTime returns when the config file was last modified, or the zero time if
+// there's no config file or it can't be read.
+func (w *Watcher) modTime() time.Time {
+	if len(w.path) == 0 {
+		return time.Time{}
+	}
+
+	fi, err := os.Stat(w.path)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return fi.ModTime()
+}
+
+// withoutSecrets returns a getenv func that returns nothing for the variables
+// that may hold secrets.
+func withoutSecrets(getenv func(string) string) func(string) string {
+	return func(name string) string {
+		for _, s := range secretVars {
+			if s == name {
+				return ""
+			}
+		}
+
+		return getenv(name)
+	}
+}
+
+// reloadable returns cur with the settings that can change without a restart
+// taken from next.
+func reloadable(cur, next C) C {
+	cur.LogLevel = next.LogLevel
+	cur.LogSampleRate = next.LogSampleRate
+	cur.HandlerLogLevels = next.HandlerLogLevels
+	cur.Filter = next.Filter
+	cur.DryRunPlugins = next.DryRunPlugins
+	cur.PriorityChannels = next.PriorityChannels
+	cur.Admins = next.Admins
+	cur.DigestChannelID = next.DigestChannelID
+
+	return cur
+}
+
+// LogLevelSubscriber returns a Watcher subscriber that applies changes to the
+// log levels and sampling, which DefaultLogger set.
+func LogLevelSubscriber(logger zerolog.Logger) func(old, cur C) {
+	return func(old, cur C) {
+		if old.LogLevel == cur.LogLevel &&
+			old.LogSampleRate == cur.LogSampleRate &&
+			reflect.DeepEqual(old.HandlerLogLevels, cur.HandlerLogLevels) {
+			return
+		}
+
+		setLogLevels(cur)
+
+		logger.Info().
+			Str("old_log_level", old.LogLevel.String()).
+			Str("log_level", cur.LogLevel.String()).
+			Uint32("log_sample_rate", cur.LogSampleRate).
+			Int("handler_log_levels", len(cur.HandlerLogLevels)).
+			Msg("log levels changed")
+	}
+}
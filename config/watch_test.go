@@ -0,0 +1,115 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestWatcher_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gopher-config")
+	if err != nil {
+		t.Fatalf("failed to make temp dir: %v", err)
+	}
+
+	defer func() { _ = os.RemoveAll(dir) }()
+
+	path := filepath.Join(dir, "gopher.yaml")
+
+	write := func(data string) {
+		t.Helper()
+
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatalf("failed to write config file: %v", err)
+		}
+	}
+
+	write(`
+gopher_log_level: info
+gopher_slack_bot_access_token: xoxb-123
+gopher_admins: [U123]
+`)
+
+	_ = os.Setenv("GOPHER_CONFIG_FILE", path)
+	defer func() { _ = os.Unsetenv("GOPHER_CONFIG_FILE") }()
+
+	c, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() unexpected error: %v", err)
+	}
+
+	w := NewWatcher(c, zerolog.Nop())
+
+	var calls []C
+
+	w.Subscribe(func(old, cur C) { calls = append(calls, cur) })
+
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() unexpected error: %v", err)
+	}
+
+	if len(calls) != 0 {
+		t.Fatalf("subscriber called %d times without a change", len(calls))
+	}
+
+	// the token is a reference now, but secrets aren't reloaded
+	write(`
+gopher_log_level: debug
+gopher_slack_bot_access_token: vault:secret/data/gopherbot#bot_token
+gopher_admins: [U123, U456]
+`)
+
+	if err := w.Reload(); err != nil {
+		t.Fatalf("Reload() unexpected error: %v", err)
+	}
+
+	if len(calls) != 1 {
+		t.Fatalf("subscriber called %d times, want 1", len(calls))
+	}
+
+	got := w.Current()
+
+	if got.LogLevel != zerolog.DebugLevel {
+		t.Errorf("LogLevel = %s, want debug", got.LogLevel)
+	}
+
+	if len(got.Admins) != 2 {
+		t.Errorf("Admins = %v, want 2 admins", got.Admins)
+	}
+
+	if got.Slack.BotAccessToken != "xoxb-123" {
+		t.Errorf("BotAccessToken changed to %q", got.Slack.BotAccessToken)
+	}
+
+	write(`gopher_log_level: loud`)
+
+	if err := w.Reload(); err == nil {
+		t.Fatal("Reload() error = <nil>, want an error")
+	}
+
+	if w.Current().LogLevel != zerolog.DebugLevel {
+		t.Errorf("config changed by a failed reload")
+	}
+}
+
+func TestReloadable(t *testing.T) {
+	cur := C{DryRun: false, DryRunPlugins: []string{"linkscan"}, DigestChannelID: "C123"}
+	next := C{DryRun: true, DryRunPlugins: []string{"filescan"}, DigestChannelID: "C456"}
+
+	got := reloadable(cur, next)
+
+	if got.DryRun {
+		t.Error("DryRun reloaded, but it needs a restart")
+	}
+
+	if len(got.DryRunPlugins) != 1 || got.DryRunPlugins[0] != "filescan" {
+		t.Errorf("DryRunPlugins = %q, want [filescan]", got.DryRunPlugins)
+	}
+
+	if got.DigestChannelID != "C456" {
+		t.Errorf("DigestChannelID = %q, want C456", got.DigestChannelID)
+	}
+}
@@ -0,0 +1,334 @@
+// Package factoids stores the canned responses admins teach the bot, like
+// "gopath is the old way of organizing Go code", in Redis. Lookups are fuzzy,
+// so small typos still find the factoid, and how often each is used is
+// tracked.
+package factoids
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	redisEntriesKey = "factoids:entries"
+	redisUsesKey    = "factoids:uses"
+
+	// MaxKeyLength is the longest a factoid's key can be, in characters.
+	MaxKeyLength = 64
+
+	// MaxTextLength is the longest a factoid's text can be, in characters.
+	MaxTextLength = 3000
+)
+
+var (
+	// ErrNotFound is returned when there's no factoid with the key.
+	ErrNotFound = errors.New("factoid not found")
+
+	// ErrInvalid is returned when a factoid's key or text isn't allowed.
+	ErrInvalid = errors.New("invalid factoid")
+)
+
+// Factoid is a canned response.
+type Factoid struct {
+	// Key is what the factoid is looked up by. It's normalized to lower case,
+	// with single spaces.
+	Key string `json:"key"`
+
+	// Text is the response.
+	Text string `json:"text"`
+
+	// Author is the ID of the user who taught the bot the factoid.
+	Author string `json:"author"`
+
+	// Updated is when the factoid was last changed.
+	Updated time.Time `json:"updated"`
+}
+
+// NormalizeKey returns the key that key is stored as.
+func NormalizeKey(key string) string {
+	return strings.ToLower(strings.Join(strings.Fields(key), " "))
+}
+
+func (f Factoid) validate() error {
+	switch {
+	case len(f.Key) == 0:
+		return fmt.Errorf("%w: the key is empty", ErrInvalid)
+	case utf8.RuneCountInString(f.Key) > MaxKeyLength:
+		return fmt.Errorf("%w: the key is longer than %d characters", ErrInvalid, MaxKeyLength)
+	case len(strings.TrimSpace(f.Text)) == 0:
+		return fmt.Errorf("%w: the text is empty", ErrInvalid)
+	case utf8.RuneCountInString(f.Text) > MaxTextLength:
+		return fmt.Errorf("%w: the text is longer than %d characters", ErrInvalid, MaxTextLength)
+	default:
+		return nil
+	}
+}
+
+// Usage is how often a factoid was looked up.
+type Usage struct {
+	Key  string
+	Uses int64
+}
+
+// Store is the Redis-backed factoid store.
+type Store struct {
+	r redis.UniversalClient
+}
+
+// New returns a *Store.
+func New(rc redis.UniversalClient) *Store {
+	return &Store{r: rc}
+}
+
+// Learn adds the factoid, or replaces the one with the same key.
+func (s *Store) Learn(ctx context.Context, f Factoid) (Factoid, error) {
+	f.Key = NormalizeKey(f.Key)
+	f.Text = strings.TrimSpace(f.Text)
+
+	if f.Updated.IsZero() {
+		f.Updated = time.Now().UTC()
+	}
+
+	if err := f.validate(); err != nil {
+		return Factoid{}, err
+	}
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return Factoid{}, fmt.Errorf("failed to marshal factoid %q: %w", f.Key, err)
+	}
+
+	if err := s.r.HSet(ctx, redisEntriesKey, f.Key, data).Err(); err != nil {
+		return Factoid{}, fmt.Errorf("failed to store factoid %q: %w", f.Key, err)
+	}
+
+	return f, nil
+}
+
+// Forget removes the factoid, and its usage stats.
+func (s *Store) Forget(ctx context.Context, key string) error {
+	key = NormalizeKey(key)
+
+	pipe := s.r.TxPipeline()
+	del := pipe.HDel(ctx, redisEntriesKey, key)
+	pipe.HDel(ctx, redisUsesKey, key)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to forget factoid %q: %w", key, err)
+	}
+
+	if del.Val() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// All returns all the factoids, sorted by key.
+func (s *Store) All(ctx context.Context) ([]Factoid, error) {
+	all, err := s.r.HGetAll(ctx, redisEntriesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get factoids: %w", err)
+	}
+
+	list := make([]Factoid, 0, len(all))
+
+	for key, data := range all {
+		var f Factoid
+
+		if err := json.Unmarshal([]byte(data), &f); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal factoid %q: %w", key, err)
+		}
+
+		list = append(list, f)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+
+	return list, nil
+}
+
+// Lookup returns the factoid with the key, or the closest one if there isn't
+// an exact match. If the factoid was a fuzzy match, exact is false. Each
+// lookup is counted in the usage stats.
+func (s *Store) Lookup(ctx context.Context, key string) (f Factoid, exact bool, err error) {
+	key = NormalizeKey(key)
+
+	data, err := s.r.HGet(ctx, redisEntriesKey, key).Bytes()
+	if err != nil && err != redis.Nil {
+		return Factoid{}, false, fmt.Errorf("failed to get factoid %q: %w", key, err)
+	}
+
+	exact = err == nil
+
+	if !exact {
+		keys, err := s.r.HKeys(ctx, redisEntriesKey).Result()
+		if err != nil {
+			return Factoid{}, false, fmt.Errorf("failed to get factoid keys: %w", err)
+		}
+
+		match, ok := closest(key, keys)
+		if !ok {
+			return Factoid{}, false, ErrNotFound
+		}
+
+		if data, err = s.r.HGet(ctx, redisEntriesKey, match).Bytes(); err != nil {
+			if err == redis.Nil {
+				return Factoid{}, false, ErrNotFound
+			}
+
+			return Factoid{}, false, fmt.Errorf("failed to get factoid %q: %w", match, err)
+		}
+	}
+
+	if err := json.Unmarshal(data, &f); err != nil {
+		return Factoid{}, false, fmt.Errorf("failed to unmarshal factoid %q: %w", key, err)
+	}
+
+	if err := s.r.HIncrBy(ctx, redisUsesKey, f.Key, 1).Err(); err != nil {
+		return Factoid{}, false, fmt.Errorf("failed to count factoid %q use: %w", f.Key, err)
+	}
+
+	return f, exact, nil
+}
+
+// Stats returns the n most used factoids, most used first.
+func (s *Store) Stats(ctx context.Context, n int) ([]Usage, error) {
+	all, err := s.r.HGetAll(ctx, redisUsesKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get factoid stats: %w", err)
+	}
+
+	stats := make([]Usage, 0, len(all))
+
+	for key, v := range all {
+		var u int64
+		if _, err := fmt.Sscan(v, &u); err != nil {
+			continue
+		}
+
+		stats = append(stats, Usage{Key: key, Uses: u})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Uses == stats[j].Uses {
+			return stats[i].Key < stats[j].Key
+		}
+
+		return stats[i].Uses > stats[j].Uses
+	})
+
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+
+	return stats, nil
+}
+
+// Export returns all the factoids as a JSON array, to be imported with Import.
+func (s *Store) Export(ctx context.Context) ([]byte, error) {
+	list, err := s.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(list, "", "  ")
+}
+
+// Import learns each of the factoids in the JSON array, like one from Export.
+// They're all validated before any are stored, and it returns how many were
+// imported.
+func (s *Store) Import(ctx context.Context, data []byte) (int, error) {
+	var list []Factoid
+
+	if err := json.Unmarshal(data, &list); err != nil {
+		return 0, fmt.Errorf("%w: failed to parse JSON: %s", ErrInvalid, err)
+	}
+
+	for i := range list {
+		list[i].Key = NormalizeKey(list[i].Key)
+		list[i].Text = strings.TrimSpace(list[i].Text)
+
+		if err := list[i].validate(); err != nil {
+			return 0, fmt.Errorf("factoid %d: %w", i, err)
+		}
+	}
+
+	for i, f := range list {
+		if _, err := s.Learn(ctx, f); err != nil {
+			return i, err
+		}
+	}
+
+	return len(list), nil
+}
+
+// closest returns the key nearest to key, if it's near enough that it was
+// probably a typo.
+func closest(key string, keys []string) (string, bool) {
+	// allow roughly one typo for every four characters
+	max := utf8.RuneCountInString(key) / 4
+	if max > 3 {
+		max = 3
+	}
+
+	best, bestDist := "", max+1
+
+	for _, k := range keys {
+		d := distance(key, k)
+		if d < bestDist || (d == bestDist && k < best) {
+			best, bestDist = k, d
+		}
+	}
+
+	return best, bestDist <= max && len(best) > 0
+}
+
+// distance returns the Levenshtein distance between a and b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func min(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+
+	if c < a {
+		a = c
+	}
+
+	return a
+}
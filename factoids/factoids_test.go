@@ -0,0 +1,61 @@
+package factoids
+
+import "testing"
+
+func Test_closest(t *testing.T) {
+	keys := []string{"gopath", "modules", "go modules", "generics", "gc"}
+
+	tests := []struct {
+		name   string
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{
+			name:   "exact",
+			key:    "generics",
+			want:   "generics",
+			wantOK: true,
+		},
+		{
+			name:   "typo",
+			key:    "genercs",
+			want:   "generics",
+			wantOK: true,
+		},
+		{
+			name:   "transposed",
+			key:    "go modlues",
+			want:   "go modules",
+			wantOK: true,
+		},
+		{
+			name: "short",
+			key:  "gd",
+		},
+		{
+			name: "too_far",
+			key:  "goroutines",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := closest(tt.key, keys)
+
+			if ok != tt.wantOK {
+				t.Fatalf("closest() ok = %t, want %t", ok, tt.wantOK)
+			}
+
+			if ok && got != tt.want {
+				t.Fatalf("closest() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeKey(t *testing.T) {
+	if got, want := NormalizeKey("  Go   Modules "), "go modules"; got != want {
+		t.Fatalf("NormalizeKey() = %q, want %q", got, want)
+	}
+}
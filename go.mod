@@ -5,16 +5,19 @@ module github.com/gobridge/gopherbot
 go 1.14
 
 require (
-	github.com/go-redis/redis v6.15.7+incompatible
-	github.com/google/go-cmp v0.4.0
+	github.com/BurntSushi/toml v0.3.1
+	github.com/alicebob/miniredis/v2 v2.14.1
+	github.com/getsentry/sentry-go v0.11.0
+	github.com/go-redis/redis/v8 v8.11.4
+	github.com/google/go-cmp v0.5.6
+	github.com/gorilla/websocket v1.4.2
 	github.com/heroku/x v0.0.22
-	github.com/onsi/ginkgo v1.10.1 // indirect
-	github.com/onsi/gomega v1.7.0 // indirect
-	github.com/robinjoseph08/redisqueue v1.1.0
+	github.com/lib/pq v1.8.0
+	github.com/prometheus/client_golang v1.6.0
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/rs/zerolog v1.18.0
 	github.com/slack-go/slack v0.6.4
 	github.com/valyala/fastjson v1.5.1
-	golang.org/x/tools v0.0.0-20200420001825-978e26b7c37c // indirect
-	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
-	gopkg.in/yaml.v2 v2.2.4 // indirect
+	golang.org/x/crypto v0.0.0-20200622213623-75b288015ac9
+	gopkg.in/yaml.v2 v2.4.0
 )
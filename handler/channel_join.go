@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -77,6 +78,15 @@ func (c *ChannelJoinActions) Handler(ctx workqueue.Context, cj *slackevents.Memb
 		return false, true, nil // no reason given, as it's normal and shouldn't be logged
 	}
 
+	cs, err := ctx.ChannelSettings(j.channelID)
+	if err != nil && !errors.Is(err, workqueue.ErrNoSettingsSvc) {
+		return true, false, fmt.Errorf("failed to get channel settings: %w", err)
+	}
+
+	if err == nil && !cs.WelcomeEnabled {
+		return false, true, errors.New("welcome message disabled in channel settings")
+	}
+
 	var someWorked bool
 
 	for _, a := range actions {
@@ -165,7 +165,10 @@ func (m *MessageActions) Handler(ctx workqueue.Context, me *slackevents.MessageE
 		return false, true, fmt.Errorf("discarding message: %s", reason)
 	}
 
-	actions := m.Match(
+	// the bot's ID is the one in the workspace the message is from, which
+	// differs from selfID if the app is installed to more than one
+	actions := m.match(
+		ctx.Self().ID,
 		NewMessage(
 			me.Channel, me.ChannelType, me.User, me.ThreadTimeStamp, me.TimeStamp, me.SubType, me.Text, me.Files,
 		),
@@ -232,10 +235,15 @@ func isDM(c ChannelType) bool {
 // Match looks at the trigger to see if it matches any known handlers. Some
 // handlers are only invoked if the bot was mentioned.
 func (m *MessageActions) Match(message Message) []MessageAction {
+	return m.match(m.selfID, message)
+}
+
+// match is Match for the bot with the ID selfID.
+func (m *MessageActions) match(selfID string, message Message) []MessageAction {
 	message.text, message.allMentions = mparser.ParseAndSplice(message.rawText, message.channelID)
 	message.text = strings.TrimSpace(message.text) // Slack already trims the space off the end
 
-	message.userMentions, message.botMentioned = onlyOtherUserMMentions(m.selfID, message.allMentions)
+	message.userMentions, message.botMentioned = onlyOtherUserMMentions(selfID, message.allMentions)
 
 	t := message.text
 	lt := strings.ToLower(t) // for where we can't easily use EqualFold()
@@ -489,3 +497,19 @@ func (m *MessageActions) HandleDynamic(matchFn MessageMatchFn, actionFn MessageA
 
 	m.dynamic = append(m.dynamic, ra)
 }
+
+// WithSlackClient wraps fn so that both the workqueue.Context and the Responder
+// it's given use sc, instead of the Slack client from the workqueue. This lets
+// individual handlers run with a differently configured client, like one in
+// dry-run mode.
+func WithSlackClient(sc workqueue.SlackClient, fn MessageActionFn) MessageActionFn {
+	return func(ctx workqueue.Context, m Messenger, r Responder) error {
+		ctx = workqueue.WithSlack(ctx, sc)
+
+		if msg, ok := m.(Message); ok {
+			r = response{sc: sc, m: msg}
+		}
+
+		return fn(ctx, m, r)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/gobridge/gopherbot/mparser"
+	"github.com/gobridge/gopherbot/workqueue"
 	"github.com/slack-go/slack"
 )
 
@@ -54,7 +55,7 @@ type Responder interface {
 }
 
 type response struct {
-	sc *slack.Client
+	sc workqueue.SlackClient
 	m  Message
 }
 
@@ -0,0 +1,120 @@
+// Package installs stores the Slack workspaces the app is installed to, with
+// the OAuth flow, and their bot tokens, so the app can be distributed to
+// workspaces other than the one it's deployed for. The installations are
+// encrypted in Redis with a keyring.Keyring, since the tokens are as good as a
+// password.
+package installs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/internal/keyring"
+)
+
+// Installation is the app being installed to a workspace.
+type Installation struct {
+	TeamID       string `json:"team_id"`
+	TeamName     string `json:"team_name"`
+	EnterpriseID string `json:"enterprise_id,omitempty"`
+	AppID        string `json:"app_id"`
+
+	// BotUserID is the bot's user in the workspace, and BotToken the token it
+	// calls the Slack API with, starting with xoxb-.
+	BotUserID string `json:"bot_user_id"`
+	BotToken  string `json:"bot_token"`
+
+	// Scope is the comma-separated scopes the bot was granted.
+	Scope string `json:"scope"`
+
+	// InstalledBy is the user who installed the app.
+	InstalledBy string    `json:"installed_by"`
+	InstalledAt time.Time `json:"installed_at"`
+}
+
+const redisKeyPrefix = "installs:team:"
+
+func key(teamID string) string { return redisKeyPrefix + teamID }
+
+// Store is the Redis-backed store of installations.
+type Store struct {
+	r  redis.UniversalClient
+	kr *keyring.Keyring
+}
+
+// NewStore returns a *Store that encrypts the installations with kr.
+func NewStore(rc redis.UniversalClient, kr *keyring.Keyring) *Store {
+	return &Store{r: rc, kr: kr}
+}
+
+// Save stores the installation, replacing the workspace's previous one, like
+// when the app is reinstalled with more scopes.
+func (s *Store) Save(ctx context.Context, in Installation) error {
+	if len(in.TeamID) == 0 {
+		return errors.New("installation has no team ID")
+	}
+
+	doc, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("failed to marshal team %s installation: %w", in.TeamID, err)
+	}
+
+	// the team ID is authenticated along with it, so one workspace's
+	// installation can't be passed off as another's
+	data, err := s.kr.Seal(doc, []byte(in.TeamID))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt team %s installation: %w", in.TeamID, err)
+	}
+
+	if err := s.r.Set(ctx, key(in.TeamID), data, 0).Err(); err != nil {
+		return fmt.Errorf("failed to save team %s installation: %w", in.TeamID, err)
+	}
+
+	return nil
+}
+
+// Get returns the workspace's installation, or false if the app isn't
+// installed to it. Installations encrypted with an old key are encrypted again
+// with the current one.
+func (s *Store) Get(ctx context.Context, teamID string) (Installation, bool, error) {
+	data, err := s.r.Get(ctx, key(teamID)).Result()
+	if err == redis.Nil {
+		return Installation{}, false, nil
+	}
+
+	if err != nil {
+		return Installation{}, false, fmt.Errorf("failed to get team %s installation: %w", teamID, err)
+	}
+
+	doc, stale, err := s.kr.Open(data, []byte(teamID))
+	if err != nil {
+		return Installation{}, false, fmt.Errorf("failed to decrypt team %s installation: %w", teamID, err)
+	}
+
+	var in Installation
+
+	if err := json.Unmarshal(doc, &in); err != nil {
+		return Installation{}, false, fmt.Errorf("failed to parse team %s installation: %w", teamID, err)
+	}
+
+	// it's tried again the next time if this fails
+	if stale {
+		_ = s.Save(ctx, in)
+	}
+
+	return in, true, nil
+}
+
+// Delete removes the workspace's installation, like once the app is
+// uninstalled from it.
+func (s *Store) Delete(ctx context.Context, teamID string) error {
+	if err := s.r.Del(ctx, key(teamID)).Err(); err != nil {
+		return fmt.Errorf("failed to delete team %s installation: %w", teamID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,109 @@
+package installs
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/internal/keyring"
+)
+
+func newTestStore(t *testing.T, keys ...[]byte) (*Store, *miniredis.Miniredis) {
+	t.Helper()
+
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+
+	t.Cleanup(s.Close)
+
+	rc := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { _ = rc.Close() })
+
+	kr, err := keyring.New(keys[0], keys[1:]...)
+	if err != nil {
+		t.Fatalf("keyring.New() unexpected error: %v", err)
+	}
+
+	return NewStore(rc, kr), s
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+
+	oldKey, newKey := bytes.Repeat([]byte{1}, keyring.KeySize), bytes.Repeat([]byte{2}, keyring.KeySize)
+
+	old, mr := newTestStore(t, oldKey)
+
+	in := Installation{TeamID: "T123", TeamName: "Gophers", BotUserID: "U123", BotToken: "xoxb-123"}
+
+	if err := old.Save(ctx, in); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+
+	stored, err := mr.Get(key("T123"))
+	if err != nil {
+		t.Fatalf("failed to get stored installation: %v", err)
+	}
+
+	if strings.Contains(stored, "xoxb") {
+		t.Fatalf("stored installation %q isn't encrypted", stored)
+	}
+
+	// rotate the key, sharing the Redis
+	kr, err := keyring.New(newKey, oldKey)
+	if err != nil {
+		t.Fatalf("keyring.New() unexpected error: %v", err)
+	}
+
+	s := NewStore(old.r, kr)
+
+	got, ok, err := s.Get(ctx, "T123")
+	if err != nil || !ok {
+		t.Fatalf("Get() = %t, %v, want true, <nil>", ok, err)
+	}
+
+	if got.BotToken != in.BotToken || got.TeamName != in.TeamName {
+		t.Fatalf("Get() = %+v, want %+v", got, in)
+	}
+
+	resealed, _ := mr.Get(key("T123"))
+	if resealed == stored {
+		t.Fatal("Get() didn't encrypt the installation again with the current key")
+	}
+
+	// it's only readable with the new key now
+	newOnly, err := keyring.New(newKey)
+	if err != nil {
+		t.Fatalf("keyring.New() unexpected error: %v", err)
+	}
+
+	if _, _, err := newOnly.Open(resealed, []byte("T123")); err != nil {
+		t.Fatalf("resealed installation can't be opened with the current key: %v", err)
+	}
+
+	// another team's installation can't be passed off as this one's
+	if err := mr.Set(key("T456"), resealed); err != nil {
+		t.Fatalf("failed to copy installation: %v", err)
+	}
+
+	if _, _, err := s.Get(ctx, "T456"); err == nil {
+		t.Fatal("Get() of another team's installation expected an error")
+	}
+
+	if _, ok, err := s.Get(ctx, "T789"); ok || err != nil {
+		t.Fatalf("Get() of a missing installation = %t, %v, want false, <nil>", ok, err)
+	}
+
+	if err := s.Delete(ctx, "T123"); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+
+	if _, ok, _ := s.Get(ctx, "T123"); ok {
+		t.Fatal("Get() after Delete() found the installation")
+	}
+}
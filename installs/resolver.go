@@ -0,0 +1,106 @@
+package installs
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+)
+
+// resolverTTL is how long a workspace is cached for, so a reinstall or
+// an uninstall is picked up without restarting the consumers
+const resolverTTL = 5 * time.Minute
+
+// Resolver satisfies the workqueue's WorkspaceSvc, with a Slack client for
+// each workspace built from its installation.
+type Resolver struct {
+	s    *Store
+	wrap func(workqueue.SlackClient) workqueue.SlackClient
+	opts []slack.Option
+
+	mu sync.Mutex
+	ws map[string]resolved
+}
+
+var _ workqueue.WorkspaceSvc = (*Resolver)(nil)
+
+// resolved is a cached workspace
+type resolved struct {
+	w       workqueue.Workspace
+	ok      bool
+	token   string
+	expires time.Time
+}
+
+// NewResolver returns a *Resolver for the installations in s. The Slack clients
+// are built with opts, and then given to wrap, if it isn't nil, like to queue
+// their messages with a *postqueue.Queue.
+func NewResolver(s *Store, wrap func(workqueue.SlackClient) workqueue.SlackClient, opts ...slack.Option) *Resolver {
+	return &Resolver{
+		s:    s,
+		wrap: wrap,
+		opts: opts,
+		ws:   make(map[string]resolved),
+	}
+}
+
+// Workspace satisfies workqueue.WorkspaceSvc.
+func (r *Resolver) Workspace(ctx context.Context, teamID string) (workqueue.Workspace, bool, error) {
+	r.mu.Lock()
+	prev, cached := r.ws[teamID]
+	r.mu.Unlock()
+
+	if cached && time.Now().Before(prev.expires) {
+		return prev.w, prev.ok, nil
+	}
+
+	in, ok, err := r.s.Get(ctx, teamID)
+	if err != nil {
+		return workqueue.Workspace{}, false, err
+	}
+
+	res := resolved{ok: ok, expires: time.Now().Add(resolverTTL)}
+
+	switch {
+	case !ok:
+
+	case cached && prev.ok && prev.token == in.BotToken:
+		// keep the client, so anything it's queued keeps its place
+		res.w, res.token = prev.w, prev.token
+
+	default:
+		if res.w, err = r.workspace(ctx, in); err != nil {
+			return workqueue.Workspace{}, false, err
+		}
+
+		res.token = in.BotToken
+	}
+
+	r.mu.Lock()
+	r.ws[teamID] = res
+	r.mu.Unlock()
+
+	return res.w, res.ok, nil
+}
+
+// workspace builds the Slack client for the installation, and gets its bot
+// user.
+func (r *Resolver) workspace(ctx context.Context, in Installation) (workqueue.Workspace, error) {
+	sc := slack.New(in.BotToken, r.opts...)
+
+	self, err := sc.GetUserInfoContext(ctx, in.BotUserID)
+	if err != nil {
+		return workqueue.Workspace{}, fmt.Errorf("failed to get team %s bot user info: %w", in.TeamID, err)
+	}
+
+	w := workqueue.Workspace{SlackClient: sc, SlackUser: self}
+
+	if r.wrap != nil {
+		w.SlackClient = r.wrap(w.SlackClient)
+	}
+
+	return w, nil
+}
@@ -0,0 +1,721 @@
+package discord
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gobridge/gopherbot/chat"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+	"github.com/valyala/fastjson"
+)
+
+const (
+	defaultAPIURL = "https://discord.com/api/v10"
+
+	// userAgent is the format Discord requires bots to use
+	userAgent = "DiscordBot (https://github.com/gobridge/gopherbot, 1)"
+
+	// errCodeUnknownChannel is the API's error code for a channel that doesn't
+	// exist, which is what posting to a user's ID gets
+	errCodeUnknownChannel = 10003
+
+	// flagSuppressEmbeds is the message flag for not unfurling its links
+	flagSuppressEmbeds = 1 << 2
+)
+
+// ErrUnsupported is wrapped by the errors of the SlackClient methods Discord
+// has nothing like, like uploading files. They're wrapped with
+// workqueue.Discard, so the handlers' messages aren't retried. It wraps
+// chat.ErrUnsupported, like the errors of chat.Replier methods a platform
+// can't do.
+var ErrUnsupported = fmt.Errorf("not supported on discord: %w", chat.ErrUnsupported)
+
+// Capabilities are what replying with a Client can do, for chat handlers. The
+// Client sends ephemeral messages as DMs instead of failing them, but they
+// aren't in the channel, so they're left out.
+const Capabilities = chat.Threads | chat.Reactions | chat.DirectMessages
+
+func unsupported(method string) error {
+	return workqueue.Discard(fmt.Errorf("%s: %w", method, ErrUnsupported))
+}
+
+// APIError is an error response from the Discord API.
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("discord API returned %d: %s (code %d)", e.Status, e.Message, e.Code)
+}
+
+// Client is the workqueue.SlackClient for Discord, which handlers reply to
+// Discord messages with. It does what Slack would where Discord has something
+// similar: messages are converted from Slack's mrkdwn to Discord's markdown,
+// attachments are sent as embeds, thread replies as replies, ephemeral
+// messages as DMs, and reactions with Slack's emoji names are added with the
+// same emoji.
+type Client struct {
+	token string
+	url   string
+	hc    *http.Client
+}
+
+var _ workqueue.SlackClient = (*Client)(nil)
+
+// NewClient returns a *Client that calls the Discord API as the bot with the
+// token, using hc.
+func NewClient(token string, hc *http.Client) *Client {
+	return &Client{token: token, url: defaultAPIURL, hc: hc}
+}
+
+// do calls the Discord API, marshaling body as the request and unmarshaling
+// the response into out, if they aren't nil. Rate limits and server errors are
+// retryable.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var r io.Reader
+
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s %s request: %w", method, path, err)
+		}
+
+		r = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.url+path, r)
+	if err != nil {
+		return fmt.Errorf("failed to build %s %s request: %w", method, path, err)
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bot "+c.token)
+	req.Header.Set("User-Agent", userAgent)
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.hc.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call %s %s: %w", method, path, err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 400 {
+		ae := &APIError{Status: resp.StatusCode}
+		_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(ae)
+
+		err := fmt.Errorf("failed to call %s %s: %w", method, path, ae)
+
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return workqueue.Retryable(err)
+		}
+
+		return err
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s %s response: %w", method, path, err)
+	}
+
+	return nil
+}
+
+func isUnknownChannel(err error) bool {
+	var ae *APIError
+	return errors.As(err, &ae) && ae.Code == errCodeUnknownChannel
+}
+
+func channelPath(channelID string) string {
+	return "/channels/" + url.PathEscape(channelID)
+}
+
+func messagePath(channelID, messageID string) string {
+	return channelPath(channelID) + "/messages/" + url.PathEscape(messageID)
+}
+
+type message struct {
+	Content          string            `json:"content"`
+	Embeds           []embed           `json:"embeds,omitempty"`
+	Flags            int               `json:"flags,omitempty"`
+	MessageReference *messageReference `json:"message_reference,omitempty"`
+	AllowedMentions  allowedMentions   `json:"allowed_mentions"`
+}
+
+type messageReference struct {
+	MessageID       string `json:"message_id"`
+	FailIfNotExists bool   `json:"fail_if_not_exists"`
+}
+
+// allowedMentions is who a message may ping. Only users are, so a handler
+// echoing someone's @everyone doesn't ping the whole server.
+type allowedMentions struct {
+	Parse []string `json:"parse"`
+}
+
+type embed struct {
+	Title       string       `json:"title,omitempty"`
+	URL         string       `json:"url,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Color       int          `json:"color,omitempty"`
+	Author      *embedAuthor `json:"author,omitempty"`
+	Fields      []embedField `json:"fields,omitempty"`
+	Image       *embedImage  `json:"image,omitempty"`
+	Thumbnail   *embedImage  `json:"thumbnail,omitempty"`
+	Footer      *embedFooter `json:"footer,omitempty"`
+}
+
+type embedAuthor struct {
+	Name    string `json:"name"`
+	URL     string `json:"url,omitempty"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+type embedField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline"`
+}
+
+type embedImage struct {
+	URL string `json:"url"`
+}
+
+type embedFooter struct {
+	Text    string `json:"text"`
+	IconURL string `json:"icon_url,omitempty"`
+}
+
+// sendConfig is what the Slack message options say to do.
+type sendConfig struct {
+	// endpoint is the Slack API method, like chat.postMessage
+	endpoint string
+	values   url.Values
+	m        message
+}
+
+func newSendConfig(channelID string, options []slack.MsgOption) (sendConfig, error) {
+	endpoint, v, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	if err != nil {
+		return sendConfig{}, fmt.Errorf("failed to apply message options: %w", err)
+	}
+
+	m := message{
+		Content:         slackToMarkdown(v.Get("text")),
+		AllowedMentions: allowedMentions{Parse: []string{"users"}},
+	}
+
+	if len(m.Content) == 0 {
+		m.Content = slackToMarkdown(blocksText(v.Get("blocks")))
+	}
+
+	if a := v.Get("attachments"); len(a) > 0 {
+		var attachments []slack.Attachment
+
+		if err := json.Unmarshal([]byte(a), &attachments); err != nil {
+			return sendConfig{}, fmt.Errorf("failed to unmarshal attachments: %w", err)
+		}
+
+		var pretext []string
+
+		for _, at := range attachments {
+			if len(at.Pretext) > 0 {
+				pretext = append(pretext, slackToMarkdown(at.Pretext))
+			}
+
+			m.Embeds = append(m.Embeds, attachmentEmbed(at))
+		}
+
+		if len(pretext) > 0 {
+			m.Content = strings.TrimSpace(m.Content + "\n" + strings.Join(pretext, "\n"))
+		}
+	}
+
+	if ts := v.Get("thread_ts"); len(ts) > 0 {
+		m.MessageReference = &messageReference{MessageID: ts}
+	}
+
+	if v.Get("unfurl_links") == "false" && len(m.Embeds) == 0 {
+		m.Flags |= flagSuppressEmbeds
+	}
+
+	return sendConfig{endpoint: endpoint, values: v, m: m}, nil
+}
+
+// attachmentColors are the colors of Slack's named attachment colors.
+var attachmentColors = map[string]int{
+	"good":    0x2eb886,
+	"warning": 0xdaa038,
+	"danger":  0xa30200,
+}
+
+func attachmentEmbed(at slack.Attachment) embed {
+	e := embed{
+		Title:       at.Title,
+		URL:         at.TitleLink,
+		Description: slackToMarkdown(at.Text),
+		Color:       attachmentColors[at.Color],
+	}
+
+	if len(e.Description) == 0 && len(e.Title) == 0 {
+		e.Description = slackToMarkdown(at.Fallback)
+	}
+
+	if n, err := strconv.ParseInt(strings.TrimPrefix(at.Color, "#"), 16, 32); err == nil {
+		e.Color = int(n)
+	}
+
+	if len(at.AuthorName) > 0 {
+		e.Author = &embedAuthor{Name: at.AuthorName, URL: at.AuthorLink, IconURL: at.AuthorIcon}
+	}
+
+	for _, f := range at.Fields {
+		e.Fields = append(e.Fields, embedField{Name: f.Title, Value: slackToMarkdown(f.Value), Inline: f.Short})
+	}
+
+	if len(at.ImageURL) > 0 {
+		e.Image = &embedImage{URL: at.ImageURL}
+	}
+
+	if len(at.ThumbURL) > 0 {
+		e.Thumbnail = &embedImage{URL: at.ThumbURL}
+	}
+
+	if len(at.Footer) > 0 {
+		e.Footer = &embedFooter{Text: at.Footer, IconURL: at.FooterIcon}
+	}
+
+	return e
+}
+
+// blocksText returns the text of the Block Kit blocks, a line per text
+// object, as Discord has no blocks.
+func blocksText(blocks string) string {
+	if len(blocks) == 0 {
+		return ""
+	}
+
+	v, err := fastjson.Parse(blocks)
+	if err != nil {
+		return ""
+	}
+
+	var lines []string
+
+	add := func(s []byte) {
+		if len(s) > 0 {
+			lines = append(lines, string(s))
+		}
+	}
+
+	for _, b := range v.GetArray() {
+		switch string(b.GetStringBytes("type")) {
+		case "header":
+			if s := b.GetStringBytes("text", "text"); len(s) > 0 {
+				lines = append(lines, "*"+string(s)+"*")
+			}
+
+		case "section":
+			add(b.GetStringBytes("text", "text"))
+
+			for _, f := range b.GetArray("fields") {
+				add(f.GetStringBytes("text"))
+			}
+
+		case "context":
+			for _, el := range b.GetArray("elements") {
+				add(el.GetStringBytes("text"))
+			}
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+var (
+	slackLink        = regexp.MustCompile(`<((?:https?|mailto):[^|>]+)\|([^>]+)>`)
+	slackURL         = regexp.MustCompile(`<((?:https?|mailto):[^|>]+)>`)
+	slackChannelLink = regexp.MustCompile(`<#([^|>]+)\|[^>]*>`)
+	slackBold        = regexp.MustCompile(`(^|[\s(])\*([^*\n]+)\*`)
+	slackStrike      = regexp.MustCompile(`(^|[\s(])~([^~\n]+)~`)
+
+	slackSpecial = strings.NewReplacer("<!here>", "@here", "<!channel>", "@everyone", "<!everyone>", "@everyone")
+	slackEscapes = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&amp;", "&")
+)
+
+// slackToMarkdown converts Slack's mrkdwn to Discord's markdown. User
+// mentions, italics, and code are the same in both.
+func slackToMarkdown(s string) string {
+	s = slackLink.ReplaceAllString(s, "[$2]($1)")
+	s = slackURL.ReplaceAllString(s, "$1")
+	s = slackChannelLink.ReplaceAllString(s, "<#$1>")
+	s = slackSpecial.Replace(s)
+	s = slackBold.ReplaceAllString(s, "$1**$2**")
+	s = slackStrike.ReplaceAllString(s, "$1~~$2~~")
+
+	return slackEscapes.Replace(s)
+}
+
+type sentMessage struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+}
+
+// post posts the message to the channel. Slack takes a user's ID as the
+// channel to DM them, where Discord needs the DM opened first, so that's done
+// if the channel doesn't exist.
+func (c *Client) post(ctx context.Context, channelID string, m message) (sentMessage, error) {
+	var sm sentMessage
+
+	err := c.do(ctx, http.MethodPost, channelPath(channelID)+"/messages", m, &sm)
+	if isUnknownChannel(err) {
+		dm, derr := c.openDM(ctx, channelID)
+		if derr != nil {
+			return sentMessage{}, err
+		}
+
+		err = c.do(ctx, http.MethodPost, channelPath(dm)+"/messages", m, &sm)
+	}
+
+	if err != nil {
+		return sentMessage{}, err
+	}
+
+	return sm, nil
+}
+
+func (c *Client) openDM(ctx context.Context, userID string) (string, error) {
+	var ch struct {
+		ID string `json:"id"`
+	}
+
+	body := struct {
+		RecipientID string `json:"recipient_id"`
+	}{userID}
+
+	if err := c.do(ctx, http.MethodPost, "/users/@me/channels", body, &ch); err != nil {
+		return "", err
+	}
+
+	return ch.ID, nil
+}
+
+// send does what the options say to, like chat.update, as the postqueue sends
+// every kind of message with SendMessageContext.
+func (c *Client) send(ctx context.Context, channelID string, options []slack.MsgOption) (string, string, string, error) {
+	sc, err := newSendConfig(channelID, options)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	switch {
+	case strings.HasSuffix(sc.endpoint, "chat.update"):
+		ts := sc.values.Get("ts")
+
+		// replies can't be changed to reply to something else
+		sc.m.MessageReference = nil
+
+		if err := c.do(ctx, http.MethodPatch, messagePath(channelID, ts), sc.m, nil); err != nil {
+			return "", "", "", err
+		}
+
+		return channelID, ts, sc.m.Content, nil
+
+	case strings.HasSuffix(sc.endpoint, "chat.delete"):
+		ts := sc.values.Get("ts")
+
+		if err := c.do(ctx, http.MethodDelete, messagePath(channelID, ts), nil, nil); err != nil {
+			return "", "", "", err
+		}
+
+		return channelID, ts, "", nil
+
+	case strings.HasSuffix(sc.endpoint, "chat.postEphemeral"):
+		// only the user sees a DM, which is as close as Discord gets, but it
+		// can't reply to a message in another channel
+		sc.m.MessageReference = nil
+		channelID = sc.values.Get("user")
+
+	case strings.HasSuffix(sc.endpoint, "chat.postMessage"):
+	default:
+		return "", "", "", unsupported(sc.endpoint)
+	}
+
+	sm, err := c.post(ctx, channelID, sc.m)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return sm.ChannelID, sm.ID, sc.m.Content, nil
+}
+
+// PostMessageContext satisfies workqueue.SlackClient.
+func (c *Client) PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error) {
+	ch, ts, _, err := c.send(ctx, channelID, options)
+	return ch, ts, err
+}
+
+// SendMessageContext satisfies workqueue.SlackClient.
+func (c *Client) SendMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, string, error) {
+	return c.send(ctx, channelID, options)
+}
+
+// PostEphemeralContext satisfies workqueue.SlackClient. The message is sent as
+// a DM.
+func (c *Client) PostEphemeralContext(ctx context.Context, channelID, userID string, options ...slack.MsgOption) (string, error) {
+	_, ts, _, err := c.send(ctx, channelID, append(options, slack.MsgOptionPostEphemeral(userID)))
+	return ts, err
+}
+
+// UpdateMessageContext satisfies workqueue.SlackClient.
+func (c *Client) UpdateMessageContext(ctx context.Context, channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error) {
+	return c.send(ctx, channelID, append(options, slack.MsgOptionUpdate(timestamp)))
+}
+
+// DeleteMessageContext satisfies workqueue.SlackClient.
+func (c *Client) DeleteMessageContext(ctx context.Context, channelID, messageTimestamp string) (string, string, error) {
+	if err := c.do(ctx, http.MethodDelete, messagePath(channelID, messageTimestamp), nil, nil); err != nil {
+		return "", "", err
+	}
+
+	return channelID, messageTimestamp, nil
+}
+
+type channel struct {
+	ID      string `json:"id"`
+	Type    int    `json:"type"`
+	GuildID string `json:"guild_id"`
+	Name    string `json:"name"`
+	Topic   string `json:"topic"`
+}
+
+// the Discord channel types that aren't a guild's text channels
+const (
+	channelTypeDM      = 1
+	channelTypeGroupDM = 3
+)
+
+// GetPermalinkContext satisfies workqueue.SlackClient.
+func (c *Client) GetPermalinkContext(ctx context.Context, params *slack.PermalinkParameters) (string, error) {
+	var ch channel
+
+	if err := c.do(ctx, http.MethodGet, channelPath(params.Channel), nil, &ch); err != nil {
+		return "", err
+	}
+
+	guild := ch.GuildID
+	if len(guild) == 0 {
+		guild = "@me"
+	}
+
+	return "https://discord.com/channels/" + guild + "/" + params.Channel + "/" + params.Ts, nil
+}
+
+// emoji are the emoji for Slack's names of the ones the handlers react with,
+// as Discord's API takes the emoji itself.
+var emoji = map[string]string{
+	"+1":               "\U0001F44D",
+	"thumbsup":         "\U0001F44D",
+	"-1":               "\U0001F44E",
+	"thumbsdown":       "\U0001F44E",
+	"white_check_mark": "✅",
+	"heavy_check_mark": "✔️",
+	"x":                "❌",
+	"warning":          "⚠️",
+	"no_entry":         "⛔",
+	"eyes":             "\U0001F440",
+	"wave":             "\U0001F44B",
+	"tada":             "\U0001F389",
+	"heart":            "❤️",
+	"rocket":           "\U0001F680",
+	"fire":             "\U0001F525",
+	"100":              "\U0001F4AF",
+	"pray":             "\U0001F64F",
+	"clap":             "\U0001F44F",
+	"raised_hands":     "\U0001F64C",
+	"ok_hand":          "\U0001F44C",
+	"sparkles":         "✨",
+	"star":             "⭐",
+	"thinking_face":    "\U0001F914",
+	"smile":            "\U0001F604",
+	"joy":              "\U0001F602",
+	"question":         "❓",
+	"robot_face":       "\U0001F916",
+	"wastebasket":      "\U0001F5D1️",
+}
+
+// customEmoji is a Discord custom emoji, as name:id
+var customEmoji = regexp.MustCompile(`^\w+:\d+$`)
+
+// reactionPath returns the API path of the bot's reaction to the message.
+func reactionPath(name string, item slack.ItemRef) (string, error) {
+	name = strings.Trim(name, ":")
+
+	// drop skin tones, like thumbsup::skin-tone-2
+	if i := strings.Index(name, "::"); i > 0 {
+		name = name[:i]
+	}
+
+	e, ok := emoji[name]
+	if !ok {
+		if !customEmoji.MatchString(name) {
+			return "", workqueue.Discard(fmt.Errorf("no discord emoji for %q: %w", name, ErrUnsupported))
+		}
+
+		e = name
+	}
+
+	return messagePath(item.Channel, item.Timestamp) + "/reactions/" + url.PathEscape(e) + "/@me", nil
+}
+
+// AddReactionContext satisfies workqueue.SlackClient. Only emoji it knows the
+// Slack name of, and Discord custom emoji as name:id, can be added.
+func (c *Client) AddReactionContext(ctx context.Context, name string, item slack.ItemRef) error {
+	p, err := reactionPath(name, item)
+	if err != nil {
+		return err
+	}
+
+	return c.do(ctx, http.MethodPut, p, nil, nil)
+}
+
+// RemoveReactionContext satisfies workqueue.SlackClient.
+func (c *Client) RemoveReactionContext(ctx context.Context, name string, item slack.ItemRef) error {
+	p, err := reactionPath(name, item)
+	if err != nil {
+		return err
+	}
+
+	return c.do(ctx, http.MethodDelete, p, nil, nil)
+}
+
+// OpenConversationContext satisfies workqueue.SlackClient. Bots can only open
+// DMs with a single user.
+func (c *Client) OpenConversationContext(ctx context.Context, params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error) {
+	if len(params.ChannelID) > 0 {
+		ch, err := c.GetConversationInfoContext(ctx, params.ChannelID, false)
+		return ch, false, false, err
+	}
+
+	if len(params.Users) != 1 {
+		return nil, false, false, unsupported("group DMs")
+	}
+
+	id, err := c.openDM(ctx, params.Users[0])
+	if err != nil {
+		return nil, false, false, err
+	}
+
+	ch := &slack.Channel{}
+	ch.ID, ch.IsIM, ch.IsOpen, ch.User = id, true, true, params.Users[0]
+
+	return ch, false, true, nil
+}
+
+// GetConversationInfoContext satisfies workqueue.SlackClient.
+func (c *Client) GetConversationInfoContext(ctx context.Context, channelID string, includeLocale bool) (*slack.Channel, error) {
+	var dc channel
+
+	if err := c.do(ctx, http.MethodGet, channelPath(channelID), nil, &dc); err != nil {
+		return nil, err
+	}
+
+	ch := &slack.Channel{}
+	ch.ID, ch.Name, ch.NameNormalized, ch.Topic.Value = dc.ID, dc.Name, dc.Name, dc.Topic
+
+	switch dc.Type {
+	case channelTypeDM:
+		ch.IsIM = true
+	case channelTypeGroupDM:
+		ch.IsMpIM = true
+	default:
+		ch.IsChannel = true
+	}
+
+	return ch, nil
+}
+
+// GetConversationHistoryContext satisfies workqueue.SlackClient, but isn't
+// supported.
+func (c *Client) GetConversationHistoryContext(ctx context.Context, params *slack.GetConversationHistoryParameters) (*slack.GetConversationHistoryResponse, error) {
+	return nil, unsupported("conversations.history")
+}
+
+// GetConversationRepliesContext satisfies workqueue.SlackClient, but isn't
+// supported.
+func (c *Client) GetConversationRepliesContext(ctx context.Context, params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error) {
+	return nil, false, "", unsupported("conversations.replies")
+}
+
+type apiUser struct {
+	user
+	Avatar string `json:"avatar"`
+}
+
+func (u apiUser) slackUser() *slack.User {
+	su := &slack.User{
+		ID:       u.ID,
+		Name:     u.Username,
+		RealName: u.realName(),
+		IsBot:    u.Bot,
+	}
+
+	su.Profile.RealName = su.RealName
+	su.Profile.DisplayName = su.RealName
+
+	if len(u.Avatar) > 0 {
+		su.Profile.Image72 = "https://cdn.discordapp.com/avatars/" + u.ID + "/" + u.Avatar + ".png"
+	}
+
+	return su
+}
+
+// GetUserInfoContext satisfies workqueue.SlackClient.
+func (c *Client) GetUserInfoContext(ctx context.Context, userID string) (*slack.User, error) {
+	var u apiUser
+
+	if err := c.do(ctx, http.MethodGet, "/users/"+url.PathEscape(userID), nil, &u); err != nil {
+		return nil, err
+	}
+
+	return u.slackUser(), nil
+}
+
+// Self returns the bot's user, for the workqueue.Workspace the consumer
+// handles Discord events with.
+func (c *Client) Self(ctx context.Context) (*slack.User, error) {
+	return c.GetUserInfoContext(ctx, "@me")
+}
+
+// GetFileInfoContext satisfies workqueue.SlackClient, but isn't supported.
+func (c *Client) GetFileInfoContext(ctx context.Context, fileID string, count, page int) (*slack.File, []slack.Comment, *slack.Paging, error) {
+	return nil, nil, nil, unsupported("files.info")
+}
+
+// GetFile satisfies workqueue.SlackClient, but isn't supported.
+func (c *Client) GetFile(downloadURL string, writer io.Writer) error {
+	return unsupported("file downloads")
+}
+
+// UploadFileContext satisfies workqueue.SlackClient, but isn't supported.
+func (c *Client) UploadFileContext(ctx context.Context, params slack.FileUploadParameters) (*slack.File, error) {
+	return nil, unsupported("files.upload")
+}
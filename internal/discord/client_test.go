@@ -0,0 +1,116 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gobridge/gopherbot/chat"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func response(status int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func Test_slackToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "hello <@U123>", want: "hello <@U123>"},
+		{name: "link", in: "see <https://golang.org|the site>", want: "see [the site](https://golang.org)"},
+		{name: "url", in: "see <https://golang.org>", want: "see https://golang.org"},
+		{name: "channel", in: "ask in <#C123|newbies>", want: "ask in <#C123>"},
+		{name: "bold_strike", in: "*bold* and ~gone~", want: "**bold** and ~~gone~~"},
+		{name: "special", in: "<!here> &lt;3 &amp; more", want: "@here <3 & more"},
+		{name: "code", in: "`a*b*c`", want: "`a*b*c`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := slackToMarkdown(tt.in); got != tt.want {
+				t.Fatalf("slackToMarkdown(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_reactionPath(t *testing.T) {
+	item := slack.NewRefToMessage("123", "456")
+
+	got, err := reactionPath(":thumbsup::skin-tone-2:", item)
+	if err != nil {
+		t.Fatalf("reactionPath() unexpected error: %v", err)
+	}
+
+	if want := "/channels/123/messages/456/reactions/%F0%9F%91%8D/@me"; got != want {
+		t.Fatalf("reactionPath() = %q, want %q", got, want)
+	}
+
+	if got, err = reactionPath("gopher:789", item); err != nil || got != "/channels/123/messages/456/reactions/gopher:789/@me" {
+		t.Fatalf("reactionPath() = %q, %v; want the custom emoji", got, err)
+	}
+
+	_, err = reactionPath("not_an_emoji", item)
+	if !errors.Is(err, ErrUnsupported) || !errors.Is(err, chat.ErrUnsupported) || !workqueue.IsDiscarded(err) {
+		t.Fatalf("reactionPath() error = %v, want a discarded ErrUnsupported", err)
+	}
+}
+
+func TestClient_PostMessageContext_dm(t *testing.T) {
+	var paths []string
+
+	hc := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+
+		switch r.URL.Path {
+		case "/api/v10/channels/42/messages":
+			return response(http.StatusNotFound, `{"code":10003,"message":"Unknown Channel"}`), nil
+		case "/api/v10/users/@me/channels":
+			return response(http.StatusOK, `{"id":"777"}`), nil
+		default:
+			return response(http.StatusOK, `{"id":"888","channel_id":"777"}`), nil
+		}
+	})}
+
+	ch, ts, err := NewClient("token", hc).PostMessageContext(context.Background(), "42", slack.MsgOptionText("hi", false))
+	if err != nil {
+		t.Fatalf("PostMessageContext() unexpected error: %v", err)
+	}
+
+	if ch != "777" || ts != "888" {
+		t.Fatalf("PostMessageContext() = %q, %q; want %q, %q", ch, ts, "777", "888")
+	}
+
+	want := []string{"POST /api/v10/channels/42/messages", "POST /api/v10/users/@me/channels", "POST /api/v10/channels/777/messages"}
+	if strings.Join(paths, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("requests = %v, want %v", paths, want)
+	}
+}
+
+func TestClient_do_retryable(t *testing.T) {
+	hc := &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return response(http.StatusTooManyRequests, `{"code":0,"message":"You are being rate limited."}`), nil
+	})}
+
+	err := NewClient("token", hc).AddReactionContext(context.Background(), "eyes", slack.NewRefToMessage("123", "456"))
+
+	var ae *APIError
+	if !errors.As(err, &ae) || ae.Status != http.StatusTooManyRequests || !workqueue.IsRetryable(err) {
+		t.Fatalf("AddReactionContext() error = %v, want a retryable *APIError", err)
+	}
+}
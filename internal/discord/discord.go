@@ -0,0 +1,514 @@
+// Package discord connects gopherbot to a Discord community, so the handlers
+// written for Slack serve it too. The Gateway receives messages and members
+// joining over the Discord gateway, and publishes them to the workqueue as the
+// Slack message and team_join events the handlers already handle, with a
+// "platform" field of "discord". The Client is the workqueue.SlackClient the
+// consumer handles them with, which replies with Discord's REST API.
+//
+// Reading the messages' content, and members joining, takes the privileged
+// Message Content and Server Members intents, which have to be turned on for
+// the bot in the Discord developer portal.
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog"
+)
+
+// Platform is the platform events from Discord are published with.
+const Platform = "discord"
+
+const (
+	defaultGatewayURL = "wss://gateway.discord.gg"
+	gatewayQuery      = "/?v=10&encoding=json"
+
+	// reconnectDelay is how long to wait before reconnecting, after the
+	// connection failed
+	reconnectDelay = 5 * time.Second
+
+	// helloTimeout is how long Discord has to say hello once connected
+	helloTimeout = 10 * time.Second
+
+	// writeTimeout is how long writing a payload to the connection may take
+	writeTimeout = 5 * time.Second
+
+	// publishTimeout is how long publishing an event to the workqueue may
+	// take, before the next one is read
+	publishTimeout = 5 * time.Second
+)
+
+// the intents are the events Discord sends the bot. GUILD_MEMBERS and
+// MESSAGE_CONTENT are privileged.
+const (
+	intentGuilds         = 1 << 0
+	intentGuildMembers   = 1 << 1
+	intentGuildMessages  = 1 << 9
+	intentDirectMessages = 1 << 12
+	intentMessageContent = 1 << 15
+
+	intents = intentGuilds | intentGuildMembers | intentGuildMessages | intentDirectMessages | intentMessageContent
+)
+
+// the gateway opcodes
+const (
+	opDispatch       = 0
+	opHeartbeat      = 1
+	opIdentify       = 2
+	opResume         = 6
+	opReconnect      = 7
+	opInvalidSession = 9
+	opHello          = 10
+	opHeartbeatACK   = 11
+)
+
+// ErrFatal is returned by Run when Discord closed the connection for a reason
+// reconnecting won't fix, like the token being invalid or the privileged
+// intents not being enabled.
+var ErrFatal = errors.New("discord gateway closed the connection for good")
+
+var (
+	errReconnect      = errors.New("discord asked to reconnect")
+	errInvalidSession = errors.New("discord invalidated the session")
+)
+
+// Publisher is the part of the workqueue the Gateway publishes events with.
+// Generally this is the gateway's outbox, so events outlive Redis being
+// briefly unavailable.
+type Publisher interface {
+	Publish(ctx context.Context, e workqueue.Event, p workqueue.Priority, eventTimestamp int64, eventID, requestID string, jsonData []byte) error
+}
+
+// Config is the configuration for a Gateway.
+type Config struct {
+	// Token is the bot token. Required.
+	Token string
+
+	// Publisher is where the events are published. Required.
+	Publisher Publisher
+
+	// Logger is the logger to use.
+	Logger zerolog.Logger
+
+	// URL is the Discord gateway URL, without the query. Defaults to
+	// wss://gateway.discord.gg.
+	URL string
+}
+
+// Gateway receives events over a Discord gateway connection, and publishes
+// them to the workqueue. There should only be one running for a bot, or every
+// event is published once per Gateway.
+type Gateway struct {
+	token string
+	p     Publisher
+	l     zerolog.Logger
+	url   string
+
+	// the session, which is resumed after reconnecting so the events missed
+	// while disconnected are sent, and the bot's user ID, both only used by
+	// Run's goroutine
+	sessionID string
+	resumeURL string
+	selfID    string
+
+	// seq is the sequence number of the last event, which the heartbeats
+	// send
+	seq int64
+}
+
+// New returns a *Gateway, which doesn't connect until it's Run.
+func New(cfg Config) (*Gateway, error) {
+	if len(cfg.Token) == 0 {
+		return nil, errors.New("must provide a cfg.Token")
+	}
+
+	if cfg.Publisher == nil {
+		return nil, errors.New("must provide a cfg.Publisher")
+	}
+
+	if len(cfg.URL) == 0 {
+		cfg.URL = defaultGatewayURL
+	}
+
+	return &Gateway{
+		token: cfg.Token,
+		p:     cfg.Publisher,
+		l:     cfg.Logger,
+		url:   cfg.URL,
+	}, nil
+}
+
+// Run keeps a connection open until ctx is canceled, reconnecting whenever
+// it's lost. It only returns an error if Discord closed the connection for
+// good, which wraps ErrFatal.
+func (g *Gateway) Run(ctx context.Context) error {
+	for {
+		err := g.connect(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if errors.Is(err, ErrFatal) {
+			return err
+		}
+
+		g.l.Warn().
+			Err(err).
+			Dur("delay", reconnectDelay).
+			Msg("discord gateway connection lost; reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+type payload struct {
+	Op int             `json:"op"`
+	D  json.RawMessage `json:"d"`
+	S  *int64          `json:"s"`
+	T  string          `json:"t"`
+}
+
+// conn is a single connection to the gateway.
+type conn struct {
+	ws *websocket.Conn
+
+	// mu serializes writes, as the heartbeats are sent from their own
+	// goroutine
+	mu sync.Mutex
+
+	// acked is 1 once the last heartbeat was acknowledged
+	acked int32
+}
+
+func (c *conn) send(op int, d interface{}) error {
+	b, err := json.Marshal(struct {
+		Op int         `json:"op"`
+		D  interface{} `json:"d"`
+	}{op, d})
+	if err != nil {
+		return fmt.Errorf("failed to marshal op %d payload: %w", op, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_ = c.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+
+	if err := c.ws.WriteMessage(websocket.TextMessage, b); err != nil {
+		return fmt.Errorf("failed to send op %d payload: %w", op, err)
+	}
+
+	return nil
+}
+
+// heartbeat sends a heartbeat with the sequence number, which is null before
+// the first event.
+func (c *conn) heartbeat(seq int64) error {
+	if seq == 0 {
+		return c.send(opHeartbeat, nil)
+	}
+
+	return c.send(opHeartbeat, seq)
+}
+
+// close closes the connection with the code. Any code but a normal closure
+// leaves the session to be resumed.
+func (c *conn) close(code int) {
+	_ = c.ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, ""), time.Now().Add(time.Second))
+	_ = c.ws.Close()
+}
+
+// connect opens a connection, resuming the session if there's one, and handles
+// its events until it's closed.
+func (g *Gateway) connect(ctx context.Context) error {
+	u, resuming := g.url, len(g.sessionID) > 0
+	if resuming && len(g.resumeURL) > 0 {
+		u = g.resumeURL
+	}
+
+	ws, _, err := websocket.DefaultDialer.DialContext(ctx, u+gatewayQuery, nil)
+	if err != nil {
+		return fmt.Errorf("failed to connect to discord gateway: %w", err)
+	}
+
+	c := &conn{ws: ws, acked: 1}
+
+	defer func() { _ = ws.Close() }()
+
+	// closing the connection is the only way to interrupt ReadMessage
+	done := make(chan struct{})
+	defer close(done)
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.close(websocket.CloseNormalClosure)
+		case <-done:
+		}
+	}()
+
+	interval, err := g.hello(c)
+	if err != nil {
+		return err
+	}
+
+	if resuming {
+		err = c.send(opResume, struct {
+			Token     string `json:"token"`
+			SessionID string `json:"session_id"`
+			Seq       int64  `json:"seq"`
+		}{g.token, g.sessionID, atomic.LoadInt64(&g.seq)})
+	} else {
+		err = c.send(opIdentify, identify{
+			Token:   g.token,
+			Intents: intents,
+			Properties: identifyProperties{
+				OS:      "linux",
+				Browser: "gopherbot",
+				Device:  "gopherbot",
+			},
+		})
+	}
+
+	if err != nil {
+		return err
+	}
+
+	go g.heartbeat(c, interval, done)
+
+	for {
+		// there's a heartbeat acknowledgement at least every interval
+		_ = ws.SetReadDeadline(time.Now().Add(2 * interval))
+
+		_, msg, err := ws.ReadMessage()
+		if err != nil {
+			return g.readError(err)
+		}
+
+		if err := g.handle(ctx, c, msg); err != nil {
+			return err
+		}
+	}
+}
+
+type identify struct {
+	Token      string             `json:"token"`
+	Intents    int                `json:"intents"`
+	Properties identifyProperties `json:"properties"`
+}
+
+type identifyProperties struct {
+	OS      string `json:"os"`
+	Browser string `json:"browser"`
+	Device  string `json:"device"`
+}
+
+// hello reads the hello Discord sends first, returning how often to send
+// heartbeats.
+func (g *Gateway) hello(c *conn) (time.Duration, error) {
+	_ = c.ws.SetReadDeadline(time.Now().Add(helloTimeout))
+
+	_, msg, err := c.ws.ReadMessage()
+	if err != nil {
+		return 0, g.readError(err)
+	}
+
+	var p payload
+
+	if err := json.Unmarshal(msg, &p); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal hello: %w", err)
+	}
+
+	if p.Op != opHello {
+		return 0, fmt.Errorf("expected hello, got op %d", p.Op)
+	}
+
+	var h struct {
+		HeartbeatInterval int64 `json:"heartbeat_interval"`
+	}
+
+	if err := json.Unmarshal(p.D, &h); err != nil || h.HeartbeatInterval <= 0 {
+		return 0, fmt.Errorf("hello had no heartbeat interval: %s", p.D)
+	}
+
+	return time.Duration(h.HeartbeatInterval) * time.Millisecond, nil
+}
+
+// heartbeat sends heartbeats until done is closed. If the last one wasn't
+// acknowledged the connection is dead, even if it looks open, so it's closed
+// to reconnect.
+func (g *Gateway) heartbeat(c *conn, interval time.Duration, done <-chan struct{}) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+		}
+
+		if !atomic.CompareAndSwapInt32(&c.acked, 1, 0) {
+			g.l.Warn().Msg("discord gateway heartbeat wasn't acknowledged; reconnecting")
+
+			c.close(4000)
+			return
+		}
+
+		if err := c.heartbeat(atomic.LoadInt64(&g.seq)); err != nil {
+			g.l.Warn().Err(err).Msg("failed to send discord gateway heartbeat")
+
+			c.close(4000)
+			return
+		}
+	}
+}
+
+// readError returns the error to reconnect with, after reading from the
+// connection failed, and forgets the session if it can't be resumed.
+func (g *Gateway) readError(err error) error {
+	var ce *websocket.CloseError
+	if !errors.As(err, &ce) {
+		return fmt.Errorf("failed to read from discord gateway: %w", err)
+	}
+
+	switch ce.Code {
+	// authentication failed, invalid shard, sharding required, invalid API
+	// version, and invalid or disallowed intents
+	case 4004, 4010, 4011, 4012, 4013, 4014:
+		return fmt.Errorf("%w: %d %s", ErrFatal, ce.Code, ce.Text)
+
+	// invalid sequence number, and session timed out
+	case 4007, 4009:
+		g.resetSession()
+	}
+
+	return fmt.Errorf("discord gateway closed the connection: %w", err)
+}
+
+func (g *Gateway) resetSession() {
+	g.sessionID, g.resumeURL = "", ""
+	atomic.StoreInt64(&g.seq, 0)
+}
+
+// handle handles a single gateway payload.
+func (g *Gateway) handle(ctx context.Context, c *conn, msg []byte) error {
+	var p payload
+
+	if err := json.Unmarshal(msg, &p); err != nil {
+		g.l.Error().
+			Err(err).
+			Msg("failed to unmarshal discord gateway payload")
+
+		return nil
+	}
+
+	if p.S != nil {
+		atomic.StoreInt64(&g.seq, *p.S)
+	}
+
+	switch p.Op {
+	case opDispatch:
+		g.dispatch(ctx, p.T, p.D)
+
+	case opHeartbeat:
+		// Discord wants one right away
+		return c.heartbeat(atomic.LoadInt64(&g.seq))
+
+	case opHeartbeatACK:
+		atomic.StoreInt32(&c.acked, 1)
+
+	case opReconnect:
+		return errReconnect
+
+	case opInvalidSession:
+		// d is whether the session can be resumed
+		var resumable bool
+		_ = json.Unmarshal(p.D, &resumable)
+
+		if !resumable {
+			g.resetSession()
+		}
+
+		return errInvalidSession
+	}
+
+	return nil
+}
+
+// dispatch handles an event, publishing the ones the handlers handle.
+func (g *Gateway) dispatch(ctx context.Context, t string, d []byte) {
+	logger := g.l.With().Str("discord_event_type", t).Logger()
+
+	switch t {
+	case "READY":
+		var r struct {
+			SessionID        string `json:"session_id"`
+			ResumeGatewayURL string `json:"resume_gateway_url"`
+			User             user   `json:"user"`
+		}
+
+		if err := json.Unmarshal(d, &r); err != nil {
+			logger.Error().
+				Err(err).
+				Msg("failed to unmarshal discord ready event")
+
+			return
+		}
+
+		g.sessionID, g.resumeURL, g.selfID = r.SessionID, r.ResumeGatewayURL, r.User.ID
+
+		logger.Info().
+			Str("user_id", r.User.ID).
+			Msg("discord gateway connected")
+
+		return
+
+	case "RESUMED":
+		logger.Info().Msg("discord gateway session resumed")
+		return
+	}
+
+	ev, ok, err := mapEvent(t, d, g.selfID)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to map discord event")
+
+		return
+	}
+
+	if !ok {
+		return
+	}
+
+	pctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	// Discord doesn't send events again, so this one's lost
+	if err := g.p.Publish(pctx, ev.e, workqueue.PriorityNormal, ev.timestamp, ev.id, ev.id, ev.data); err != nil {
+		logger.Error().
+			Err(err).
+			Str("event_id", ev.id).
+			Msg("failed to publish discord event to workqueue")
+
+		return
+	}
+
+	logger.Debug().
+		Str("event_type", string(ev.e)).
+		Str("event_id", ev.id).
+		Msg("published discord event")
+}
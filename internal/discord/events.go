@@ -0,0 +1,212 @@
+package discord
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gobridge/gopherbot/workqueue"
+)
+
+// discordEpoch is the Unix time, in milliseconds, that snowflake timestamps
+// count from.
+const discordEpoch = 1420070400000
+
+// the Discord message types that are someone saying something, instead of
+// joins, pins, boosts, and the like
+const (
+	messageTypeDefault = 0
+	messageTypeReply   = 19
+)
+
+type user struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name"`
+	Bot        bool   `json:"bot"`
+}
+
+// realName returns the user's display name, falling back to their username.
+func (u user) realName() string {
+	if len(u.GlobalName) > 0 {
+		return u.GlobalName
+	}
+
+	return u.Username
+}
+
+type messageCreate struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id"`
+	Author    user   `json:"author"`
+	Content   string `json:"content"`
+	Type      int    `json:"type"`
+
+	MessageReference *struct {
+		MessageID string `json:"message_id"`
+	} `json:"message_reference"`
+}
+
+type guildMemberAdd struct {
+	GuildID  string    `json:"guild_id"`
+	User     user      `json:"user"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// event is a Discord gateway event mapped onto a workqueue one.
+type event struct {
+	e         workqueue.Event
+	id        string
+	timestamp int64
+	data      []byte
+}
+
+// mapEvent maps the Discord dispatch event onto the Slack event the handlers
+// already handle, with the platform set to Discord. It returns false for
+// events that aren't published, including the bot's own messages.
+func mapEvent(t string, d []byte, selfID string) (event, bool, error) {
+	switch t {
+	case "MESSAGE_CREATE":
+		var m messageCreate
+		if err := json.Unmarshal(d, &m); err != nil {
+			return event{}, false, fmt.Errorf("failed to unmarshal %s: %w", t, err)
+		}
+
+		if m.Author.ID == selfID || (m.Type != messageTypeDefault && m.Type != messageTypeReply) {
+			return event{}, false, nil
+		}
+
+		return mapMessage(m)
+
+	case "GUILD_MEMBER_ADD":
+		var gm guildMemberAdd
+		if err := json.Unmarshal(d, &gm); err != nil {
+			return event{}, false, fmt.Errorf("failed to unmarshal %s: %w", t, err)
+		}
+
+		return mapMemberAdd(gm)
+
+	default:
+		return event{}, false, nil
+	}
+}
+
+func mapMessage(m messageCreate) (event, bool, error) {
+	ms, err := snowflakeTime(m.ID)
+	if err != nil {
+		return event{}, false, fmt.Errorf("failed to parse message ID: %w", err)
+	}
+
+	// messages without a guild are DMs
+	e, ct := workqueue.SlackMessageChannel, "channel"
+	if len(m.GuildID) == 0 {
+		e, ct = workqueue.SlackMessageIM, "im"
+	}
+
+	me := struct {
+		Type           string `json:"type"`
+		SubType        string `json:"subtype,omitempty"`
+		Platform       string `json:"platform"`
+		Channel        string `json:"channel"`
+		ChannelType    string `json:"channel_type"`
+		Team           string `json:"team,omitempty"`
+		User           string `json:"user"`
+		BotID          string `json:"bot_id,omitempty"`
+		Username       string `json:"username,omitempty"`
+		Text           string `json:"text"`
+		TimeStamp      string `json:"ts"`
+		ThreadTS       string `json:"thread_ts,omitempty"`
+		EventTimeStamp string `json:"event_ts"`
+	}{
+		Type:        "message",
+		Platform:    Platform,
+		Channel:     m.ChannelID,
+		ChannelType: ct,
+		Team:        m.GuildID,
+		User:        m.Author.ID,
+		Username:    m.Author.Username,
+		Text:        normalizeMentions(m.Content),
+
+		// replying to ts, like handlers do in threads, replies to the
+		// message
+		TimeStamp:      m.ID,
+		EventTimeStamp: fmt.Sprintf("%d.%03d", ms/1000, ms%1000),
+	}
+
+	if m.MessageReference != nil {
+		me.ThreadTS = m.MessageReference.MessageID
+	}
+
+	if m.Author.Bot {
+		me.SubType, me.BotID = "bot_message", m.Author.ID
+	}
+
+	data, err := json.Marshal(me)
+	if err != nil {
+		return event{}, false, fmt.Errorf("failed to marshal message: %w", err)
+	}
+
+	return event{e: e, id: "discord:" + m.ID, timestamp: ms / 1000, data: data}, true, nil
+}
+
+func mapMemberAdd(gm guildMemberAdd) (event, bool, error) {
+	tj := struct {
+		Type     string `json:"type"`
+		Platform string `json:"platform"`
+		User     struct {
+			ID       string `json:"id"`
+			TeamID   string `json:"team_id"`
+			Name     string `json:"name"`
+			RealName string `json:"real_name"`
+			IsBot    bool   `json:"is_bot"`
+		} `json:"user"`
+	}{
+		Type:     "team_join",
+		Platform: Platform,
+	}
+
+	tj.User.ID = gm.User.ID
+	tj.User.TeamID = gm.GuildID
+	tj.User.Name = gm.User.Username
+	tj.User.RealName = gm.User.realName()
+	tj.User.IsBot = gm.User.Bot
+
+	data, err := json.Marshal(tj)
+	if err != nil {
+		return event{}, false, fmt.Errorf("failed to marshal member: %w", err)
+	}
+
+	ts := gm.JoinedAt.Unix()
+	if gm.JoinedAt.IsZero() {
+		ts = time.Now().Unix()
+	}
+
+	return event{
+		e:         workqueue.SlackTeamJoin,
+		id:        "discord:join:" + gm.GuildID + ":" + gm.User.ID,
+		timestamp: ts,
+		data:      data,
+	}, true, nil
+}
+
+// snowflakeTime returns the Unix time, in milliseconds, the snowflake ID was
+// created at.
+func snowflakeTime(id string) (int64, error) {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(n>>22) + discordEpoch, nil
+}
+
+var nicknameMention = regexp.MustCompile(`<@!(\d+)>`)
+
+// normalizeMentions rewrites nickname mentions, <@!id>, as the plain <@id>
+// mentions the handlers look for.
+func normalizeMentions(s string) string {
+	return nicknameMention.ReplaceAllString(s, "<@$1>")
+}
@@ -0,0 +1,159 @@
+package discord
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_mapEvent(t *testing.T) {
+	tests := []struct {
+		name   string
+		t      string
+		d      string
+		e      workqueue.Event
+		id     string
+		ts     int64
+		want   map[string]interface{}
+		notPub bool
+	}{
+		{
+			name: "guild_message",
+			t:    "MESSAGE_CREATE",
+			d:    `{"id":"175928847299117063","channel_id":"123","guild_id":"456","type":0,"content":"hi <@!789>","author":{"id":"42","username":"gopher"}}`,
+			e:    workqueue.SlackMessageChannel,
+			id:   "discord:175928847299117063",
+			ts:   1462015105,
+			want: map[string]interface{}{
+				"type":         "message",
+				"platform":     "discord",
+				"channel":      "123",
+				"channel_type": "channel",
+				"team":         "456",
+				"user":         "42",
+				"username":     "gopher",
+				"text":         "hi <@789>",
+				"ts":           "175928847299117063",
+				"event_ts":     "1462015105.796",
+			},
+		},
+		{
+			name: "dm_reply",
+			t:    "MESSAGE_CREATE",
+			d:    `{"id":"175928847299117063","channel_id":"123","type":19,"content":"thanks","author":{"id":"42","username":"gopher"},"message_reference":{"message_id":"175928847299117000"}}`,
+			e:    workqueue.SlackMessageIM,
+			id:   "discord:175928847299117063",
+			ts:   1462015105,
+			want: map[string]interface{}{
+				"type":         "message",
+				"platform":     "discord",
+				"channel":      "123",
+				"channel_type": "im",
+				"user":         "42",
+				"username":     "gopher",
+				"text":         "thanks",
+				"ts":           "175928847299117063",
+				"thread_ts":    "175928847299117000",
+				"event_ts":     "1462015105.796",
+			},
+		},
+		{
+			name: "bot_message",
+			t:    "MESSAGE_CREATE",
+			d:    `{"id":"175928847299117063","channel_id":"123","guild_id":"456","type":0,"content":"beep","author":{"id":"99","username":"robot","bot":true}}`,
+			e:    workqueue.SlackMessageChannel,
+			id:   "discord:175928847299117063",
+			ts:   1462015105,
+			want: map[string]interface{}{
+				"type":         "message",
+				"subtype":      "bot_message",
+				"platform":     "discord",
+				"channel":      "123",
+				"channel_type": "channel",
+				"team":         "456",
+				"user":         "99",
+				"bot_id":       "99",
+				"username":     "robot",
+				"text":         "beep",
+				"ts":           "175928847299117063",
+				"event_ts":     "1462015105.796",
+			},
+		},
+		{
+			name:   "own_message",
+			t:      "MESSAGE_CREATE",
+			d:      `{"id":"175928847299117063","channel_id":"123","guild_id":"456","type":0,"content":"hi","author":{"id":"1","username":"gopherbot","bot":true}}`,
+			notPub: true,
+		},
+		{
+			name:   "pin",
+			t:      "MESSAGE_CREATE",
+			d:      `{"id":"175928847299117063","channel_id":"123","guild_id":"456","type":6,"author":{"id":"42","username":"gopher"}}`,
+			notPub: true,
+		},
+		{
+			name: "member_add",
+			t:    "GUILD_MEMBER_ADD",
+			d:    `{"guild_id":"456","joined_at":"2021-01-02T03:04:05.000000+00:00","user":{"id":"42","username":"gopher","global_name":"Gopher"}}`,
+			e:    workqueue.SlackTeamJoin,
+			id:   "discord:join:456:42",
+			ts:   1609556645,
+			want: map[string]interface{}{
+				"type":     "team_join",
+				"platform": "discord",
+				"user": map[string]interface{}{
+					"id":        "42",
+					"team_id":   "456",
+					"name":      "gopher",
+					"real_name": "Gopher",
+					"is_bot":    false,
+				},
+			},
+		},
+		{
+			name:   "typing",
+			t:      "TYPING_START",
+			d:      `{"channel_id":"123","user_id":"42"}`,
+			notPub: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ev, ok, err := mapEvent(tt.t, []byte(tt.d), "1")
+			if err != nil {
+				t.Fatalf("mapEvent() unexpected error: %v", err)
+			}
+
+			if ok == tt.notPub {
+				t.Fatalf("mapEvent() published = %t, want %t", ok, !tt.notPub)
+			}
+
+			if !ok {
+				return
+			}
+
+			if ev.e != tt.e || ev.id != tt.id || ev.timestamp != tt.ts {
+				t.Fatalf("mapEvent() = %s, %s, %d; want %s, %s, %d", ev.e, ev.id, ev.timestamp, tt.e, tt.id, tt.ts)
+			}
+
+			var got map[string]interface{}
+
+			if err := json.Unmarshal(ev.data, &got); err != nil {
+				t.Fatalf("failed to unmarshal data: %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("mapEvent() data differs (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func Test_mapEvent_invalid(t *testing.T) {
+	if _, _, err := mapEvent("MESSAGE_CREATE", []byte(`{"id":"nope","type":0,"author":{"id":"42"}}`), "1"); err == nil {
+		t.Fatal("mapEvent() expected an error for an invalid message ID")
+	}
+}
@@ -0,0 +1,155 @@
+// Package dryrun provides an http.RoundTripper for the Slack client that logs
+// the API calls that would change something in Slack, instead of making them.
+// This allows new moderation rules and bulk actions to be validated against
+// production traffic without side effects.
+package dryrun
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// fakeTS is the message timestamp returned for messages that were never sent
+const fakeTS = "0000000000.000000"
+
+// methods are the Slack API methods that are intercepted. Anything not in this
+// list, like reading channel history, is passed through.
+var methods = map[string]struct{}{
+	"chat.delete":             {},
+	"chat.meMessage":          {},
+	"chat.postEphemeral":      {},
+	"chat.postMessage":        {},
+	"chat.update":             {},
+	"conversations.invite":    {},
+	"conversations.kick":      {},
+	"files.delete":            {},
+	"files.upload":            {},
+	"pins.add":                {},
+	"pins.remove":             {},
+	"reactions.add":           {},
+	"reactions.remove":        {},
+	"users.admin.setInactive": {},
+}
+
+// Transport is the dry-run http.RoundTripper.
+type Transport struct {
+	base   http.RoundTripper
+	logger zerolog.Logger
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// New returns a *Transport wrapping base, which is used for all requests that
+// are not intercepted. Intercepted calls are logged to logger at info level.
+func New(base http.RoundTripper, logger zerolog.Logger) *Transport {
+	return &Transport{
+		base:   base,
+		logger: logger,
+	}
+}
+
+// Client returns an *http.Client using the *Transport.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// RoundTrip satisfies http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+
+	if _, ok := methods[method]; !ok || !strings.HasPrefix(req.URL.Path, "/api/") {
+		return t.base.RoundTrip(req)
+	}
+
+	var body []byte
+
+	if req.Body != nil {
+		b, err := ioutil.ReadAll(req.Body)
+		_ = req.Body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read dry-run request body: %w", err)
+		}
+
+		body = b
+	}
+
+	payload := payloadFor(req, body)
+
+	t.logger.Info().
+		Str("slack_method", method).
+		Interface("payload", payload).
+		Msg("dry run: skipped Slack API call")
+
+	resp, err := fakeResponse(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json; charset=utf-8"}},
+		Body:          ioutil.NopCloser(bytes.NewReader(resp)),
+		ContentLength: int64(len(resp)),
+		Request:       req,
+	}, nil
+}
+
+// payloadFor returns the request parameters, without the token. Non-form bodies
+// (like multipart file uploads) are summarized by their size instead.
+func payloadFor(req *http.Request, body []byte) map[string]string {
+	p := make(map[string]string)
+
+	values := req.URL.Query()
+
+	mt, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if mt == "application/x-www-form-urlencoded" {
+		if fv, err := url.ParseQuery(string(body)); err == nil {
+			for k, v := range fv {
+				values[k] = v
+			}
+		}
+	} else if len(body) > 0 {
+		p["body_bytes"] = fmt.Sprint(len(body))
+	}
+
+	for k := range values {
+		if k == "token" {
+			continue
+		}
+
+		p[k] = values.Get(k)
+	}
+
+	return p
+}
+
+// fakeResponse builds a successful response, with enough fields set for the
+// slack-go client to be happy with it.
+func fakeResponse(payload map[string]string) ([]byte, error) {
+	r := map[string]interface{}{
+		"ok":      true,
+		"channel": payload["channel"],
+		"ts":      fakeTS,
+		"file":    map[string]string{"id": "F00000000"},
+	}
+
+	if ts, ok := payload["ts"]; ok {
+		r["ts"] = ts
+	}
+
+	return json.Marshal(r)
+}
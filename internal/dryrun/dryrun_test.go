@@ -0,0 +1,85 @@
+package dryrun
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+var errPassedThrough = errors.New("request passed through")
+
+func TestTransport_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name        string
+		method      string
+		passThrough bool
+		want        string
+	}{
+		{
+			name:   "post_message",
+			method: "chat.postMessage",
+			want:   `{"channel":"C123","file":{"id":"F00000000"},"ok":true,"ts":"0000000000.000000"}`,
+		},
+		{
+			name:   "update_keeps_ts",
+			method: "chat.update",
+			want:   `{"channel":"C123","file":{"id":"F00000000"},"ok":true,"ts":"1234.5678"}`,
+		},
+		{
+			name:        "history",
+			method:      "conversations.history",
+			passThrough: true,
+		},
+	}
+
+	base := roundTripperFunc(func(*http.Request) (*http.Response, error) { return nil, errPassedThrough })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := New(base, zerolog.Nop())
+
+			body := "token=xoxb-secret&channel=C123"
+			if tt.method == "chat.update" {
+				body += "&ts=1234.5678"
+			}
+
+			req, err := http.NewRequest(http.MethodPost, "https://slack.com/api/"+tt.method, strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("failed to build request: %v", err)
+			}
+
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			resp, err := tr.RoundTrip(req)
+
+			if tt.passThrough {
+				if !errors.Is(err, errPassedThrough) {
+					t.Fatalf("RoundTrip() error = %v, want %v", err, errPassedThrough)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("RoundTrip() unexpected error: %v", err)
+			}
+
+			got, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read body: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Fatalf("RoundTrip() body = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Package errreport provides the error reporters for the gateway and the
+// workqueue, so production failures page someone instead of scrolling past in
+// the logs.
+package errreport
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// Config is the configuration for a *Sentry.
+type Config struct {
+	// DSN is the Sentry project's client key DSN.
+	DSN string
+
+	// Environment is the environment the errors are reported from, like
+	// "production".
+	Environment string
+
+	// Release is the commit of the code running.
+	Release string
+
+	// ServerName is the name of the process reporting errors, like the Heroku
+	// dyno ID.
+	ServerName string
+}
+
+// Sentry reports errors to Sentry. It satisfies workqueue.ErrorReporter.
+type Sentry struct {
+	c *sentry.Client
+}
+
+// NewSentry returns a *Sentry reporting to the project with cfg.DSN.
+func NewSentry(cfg Config) (*Sentry, error) {
+	if len(cfg.DSN) == 0 {
+		return nil, errors.New("Sentry DSN must be set")
+	}
+
+	c, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:         cfg.DSN,
+		Environment: cfg.Environment,
+		Release:     cfg.Release,
+		ServerName:  cfg.ServerName,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build Sentry client: %w", err)
+	}
+
+	return &Sentry{c: c}, nil
+}
+
+// ReportError reports err to Sentry in the background, tagged with tags. The
+// stack trace is taken from where it's called, so panics should be reported
+// from the deferred function that recovered them.
+func (s *Sentry) ReportError(err error, tags map[string]string) {
+	scope := sentry.NewScope()
+	scope.SetTags(tags)
+
+	if tags["kind"] == "panic" {
+		scope.SetLevel(sentry.LevelFatal)
+	}
+
+	s.c.CaptureException(err, nil, scope)
+}
+
+// Flush waits up to timeout for the reported errors to be sent, returning
+// false if they weren't all sent.
+func (s *Sentry) Flush(timeout time.Duration) bool {
+	return s.c.Flush(timeout)
+}
@@ -8,15 +8,15 @@ import (
 	"sync"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 	"github.com/rs/zerolog"
 )
 
 const redisKeyFormat = "heartbeat:%s:%s"
 
 type redisClient interface {
-	Get(key string) *redis.StringCmd
-	Set(key string, value interface{}, expiration time.Duration) *redis.StatusCmd
+	Get(ctx context.Context, key string) *redis.StringCmd
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) *redis.StatusCmd
 }
 
 type Config struct {
@@ -83,7 +83,7 @@ func New(ctx context.Context, cfg Config) (*Heart, error) {
 		shutdownFn: cfg.ShutdownFn,
 	}
 
-	if err := h.beat(); err != nil {
+	if err := h.beat(h.ctx); err != nil {
 		return nil, fmt.Errorf("initial beat error: %w", err)
 	}
 
@@ -175,7 +175,7 @@ func (h *Heart) pulse() {
 			// escape out to for loop
 		}
 
-		if err := h.beat(); err != nil {
+		if err := h.beat(h.ctx); err != nil {
 			h.l.Error().Err(err).
 				Msg("heartbeat failed")
 		}
@@ -184,15 +184,15 @@ func (h *Heart) pulse() {
 	}
 }
 
-func (h *Heart) beat() error {
+func (h *Heart) beat(ctx context.Context) error {
 	tn := time.Now().UnixNano() / int64(time.Millisecond)
 
-	status := h.r.Set(h.key, tn, h.fail+time.Minute)
+	status := h.r.Set(ctx, h.key, tn, h.fail+time.Minute)
 	if err := status.Err(); err != nil {
 		return fmt.Errorf("failed to beat: %w", err)
 	}
 
-	res := h.r.Get(h.key)
+	res := h.r.Get(ctx, h.key)
 	if err := res.Err(); err != nil {
 		return fmt.Errorf("failed to read beat: %w", err)
 	}
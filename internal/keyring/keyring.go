@@ -0,0 +1,159 @@
+// Package keyring encrypts the secrets, and personal data, that are kept in
+// Redis, like workspaces' bot tokens, what members ask to be reminded of, and
+// the events in the workqueue, since a Heroku Redis addon is shared
+// infrastructure. Data is encrypted with AES-256-GCM.
+//
+// A Keyring can hold old keys along with the current one, to rotate keys:
+// everything is encrypted with the current key, and can still be decrypted
+// with the old ones, which say when data should be encrypted again.
+package keyring
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// KeySize is the size of the keys, which are AES-256 keys.
+const KeySize = 32
+
+var (
+	// ErrInvalidKey is returned when a key isn't KeySize bytes.
+	ErrInvalidKey = fmt.Errorf("encryption key must be %d bytes", KeySize)
+
+	// ErrUnknownKey is returned by Open when the data was encrypted with a
+	// key the Keyring doesn't have, like one that was rotated out too soon.
+	ErrUnknownKey = errors.New("data was encrypted with an unknown key")
+)
+
+// key is one of the keys, with its ID, which is what the data encrypted with it
+// is prefixed with
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring encrypts data with its current key, and decrypts data encrypted with
+// any of its keys.
+type Keyring struct {
+	keys []key
+}
+
+// New returns a *Keyring that encrypts with current, and decrypts with it or
+// any of the old keys. Each key must be KeySize bytes.
+func New(current []byte, old ...[]byte) (*Keyring, error) {
+	kr := &Keyring{keys: make([]key, 0, 1+len(old))}
+
+	for _, k := range append([][]byte{current}, old...) {
+		if len(k) != KeySize {
+			return nil, ErrInvalidKey
+		}
+
+		block, err := aes.NewCipher(k)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build cipher: %w", err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build GCM cipher: %w", err)
+		}
+
+		// the ID only tells the keys apart, and doesn't give the key away
+		sum := sha256.Sum256(k)
+
+		kr.keys = append(kr.keys, key{id: hex.EncodeToString(sum[:4]), aead: aead})
+	}
+
+	return kr, nil
+}
+
+// Parse returns a *Keyring for keys encoded as hex, like they're configured.
+func Parse(current string, old ...string) (*Keyring, error) {
+	keys := make([][]byte, 0, 1+len(old))
+
+	for _, s := range append([]string{current}, old...) {
+		k, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode encryption key: %w", err)
+		}
+
+		keys = append(keys, k)
+	}
+
+	return New(keys[0], keys[1:]...)
+}
+
+// Seal encrypts data with the current key, returning it as text that can be
+// stored in Redis. The associated data isn't encrypted, but Open only succeeds
+// with the same associated data, like the key the data is stored at, so it
+// can't be passed off as something else's.
+func (kr *Keyring) Seal(data, associated []byte) (string, error) {
+	k := kr.keys[0]
+
+	nonce := make([]byte, k.aead.NonceSize(), k.aead.NonceSize()+len(data)+k.aead.Overhead())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return k.id + ":" + base64.StdEncoding.EncodeToString(k.aead.Seal(nonce, nonce, data, associated)), nil
+}
+
+// Open decrypts what Seal returned. It also returns whether the data was
+// encrypted with an old key, in which case it should be encrypted again with
+// Seal.
+//
+// Data without a key ID is tried with each key, for data stored before it had
+// one.
+func (kr *Keyring) Open(sealed string, associated []byte) (data []byte, stale bool, err error) {
+	id, ct := "", sealed
+
+	if i := strings.IndexByte(sealed, ':'); i >= 0 {
+		id, ct = sealed[:i], sealed[i+1:]
+	}
+
+	b, err := base64.StdEncoding.DecodeString(ct)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to decode ciphertext: %w", err)
+	}
+
+	for n, k := range kr.keys {
+		if len(id) > 0 && k.id != id {
+			continue
+		}
+
+		data, err := open(k.aead, b, associated)
+		if err != nil {
+			if len(id) > 0 {
+				return nil, false, err
+			}
+
+			continue
+		}
+
+		return data, n > 0 || len(id) == 0, nil
+	}
+
+	return nil, false, ErrUnknownKey
+}
+
+func open(aead cipher.AEAD, b, associated []byte) ([]byte, error) {
+	if len(b) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+
+	data, err := aead.Open(nil, b[:aead.NonceSize()], b[aead.NonceSize():], associated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return data, nil
+}
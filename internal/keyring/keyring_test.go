@@ -0,0 +1,97 @@
+package keyring
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, KeySize)
+	key2 = bytes.Repeat([]byte{2}, KeySize)
+)
+
+func TestKeyring(t *testing.T) {
+	old, err := New(key1)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	data := []byte(`{"bot_token":"xoxb-123"}`)
+	ad := []byte("installs:team:T123")
+
+	sealed, err := old.Seal(data, ad)
+	if err != nil {
+		t.Fatalf("Seal() unexpected error: %v", err)
+	}
+
+	if strings.Contains(sealed, "xoxb") {
+		t.Fatalf("Seal() = %q, which isn't encrypted", sealed)
+	}
+
+	if again, _ := old.Seal(data, ad); again == sealed {
+		t.Fatal("Seal() returned the same ciphertext twice")
+	}
+
+	got, stale, err := old.Open(sealed, ad)
+	if err != nil || stale || !bytes.Equal(got, data) {
+		t.Fatalf("Open() = %s, %t, %v; want %s, false, <nil>", got, stale, err, data)
+	}
+
+	if _, _, err := old.Open(sealed, []byte("installs:team:T456")); err == nil {
+		t.Fatal("Open() with other associated data expected an error")
+	}
+
+	// rotate to key2, keeping key1 to decrypt with
+	kr, err := New(key2, key1)
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	got, stale, err = kr.Open(sealed, ad)
+	if err != nil || !stale || !bytes.Equal(got, data) {
+		t.Fatalf("Open() = %s, %t, %v; want %s, true, <nil>", got, stale, err, data)
+	}
+
+	resealed, err := kr.Seal(data, ad)
+	if err != nil {
+		t.Fatalf("Seal() unexpected error: %v", err)
+	}
+
+	if _, stale, err := kr.Open(resealed, ad); err != nil || stale {
+		t.Fatalf("Open() = %t, %v; want false, <nil>", stale, err)
+	}
+
+	// once key1 is dropped
+	if _, _, err := old.Open(resealed, ad); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Open() error = %v, want ErrUnknownKey", err)
+	}
+
+	// data stored before it had a key ID
+	legacy := sealed[strings.IndexByte(sealed, ':')+1:]
+
+	got, stale, err = kr.Open(legacy, ad)
+	if err != nil || !stale || !bytes.Equal(got, data) {
+		t.Fatalf("Open() = %s, %t, %v; want %s, true, <nil>", got, stale, err, data)
+	}
+
+	if _, _, err := kr.Open(base64.StdEncoding.EncodeToString([]byte("hi")), ad); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("Open() error = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	if _, err := Parse(strings.Repeat("01", KeySize), strings.Repeat("02", KeySize)); err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+
+	if _, err := Parse(strings.Repeat("01", KeySize), "0011"); !errors.Is(err, ErrInvalidKey) {
+		t.Fatalf("Parse() error = %v, want ErrInvalidKey", err)
+	}
+
+	if _, err := Parse("not hex"); err == nil {
+		t.Fatal("Parse() expected an error")
+	}
+}
@@ -0,0 +1,274 @@
+// Package leader elects a leader using a lock in Redis, so that singleton tasks
+// only run on one dyno at a time while the others wait to take over. The lock
+// is taken with SET NX PX and renewed while it's held, so it expires if the
+// leader dies without letting go.
+package leader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog"
+)
+
+const (
+	defaultTTL = 30 * time.Second
+
+	// releaseTimeout is how long resigning waits for Redis to delete the lock
+	releaseTimeout = 5 * time.Second
+)
+
+// Config is the configuration for an Elector.
+type Config struct {
+	// RedisClient is the client for the Redis holding the lock. Required.
+	RedisClient redis.UniversalClient
+
+	// Logger is the logger to use.
+	Logger zerolog.Logger
+
+	// Key is the Redis key of the lock. Electors with the same Key compete
+	// for the same leadership. Required.
+	Key string
+
+	// ID uniquely identifies this elector, and is the lock's value while
+	// it's the leader. Required.
+	ID string
+
+	// TTL is how long the lock is held without being renewed, so another
+	// elector takes over if the leader dies. Defaults to 30 seconds.
+	TTL time.Duration
+
+	// RenewInterval is how often the leader renews the lock, and how often
+	// the others try to take it. It must be shorter than TTL. Defaults to a
+	// third of TTL.
+	RenewInterval time.Duration
+
+	// OnElected is called in its own goroutine when this elector becomes the
+	// leader, and should do the singleton work until ctx is canceled. That
+	// happens when it stops being the leader, so it must return soon after,
+	// before another elector takes over. Required.
+	OnElected func(ctx context.Context)
+
+	// OnResigned is called once OnElected has returned after this elector
+	// stopped being the leader. Optional.
+	OnResigned func()
+}
+
+// Elector runs its OnElected callback while it's the leader.
+type Elector struct {
+	r  redis.UniversalClient
+	l  zerolog.Logger
+	id string
+
+	key        string
+	ttl        time.Duration
+	interval   time.Duration
+	onElected  func(context.Context)
+	onResigned func()
+}
+
+// New returns an *Elector, which doesn't compete for leadership until it's
+// Run.
+func New(cfg Config) (*Elector, error) {
+	if cfg.RedisClient == nil {
+		return nil, errors.New("must provide a cfg.RedisClient")
+	}
+
+	if len(cfg.Key) == 0 {
+		return nil, errors.New("must provide cfg.Key")
+	}
+
+	if len(cfg.ID) == 0 {
+		return nil, errors.New("must provide cfg.ID")
+	}
+
+	if cfg.OnElected == nil {
+		return nil, errors.New("must provide cfg.OnElected")
+	}
+
+	if cfg.TTL <= 0 {
+		cfg.TTL = defaultTTL
+	}
+
+	if cfg.RenewInterval <= 0 {
+		cfg.RenewInterval = cfg.TTL / 3
+	}
+
+	if cfg.RenewInterval >= cfg.TTL {
+		return nil, fmt.Errorf("cfg.RenewInterval (%s) must be shorter than cfg.TTL (%s)", cfg.RenewInterval, cfg.TTL)
+	}
+
+	return &Elector{
+		r:          cfg.RedisClient,
+		l:          cfg.Logger.With().Str("leader_key", cfg.Key).Logger(),
+		id:         cfg.ID,
+		key:        cfg.Key,
+		ttl:        cfg.TTL,
+		interval:   cfg.RenewInterval,
+		onElected:  cfg.OnElected,
+		onResigned: cfg.OnResigned,
+	}, nil
+}
+
+// Run competes for leadership until ctx is canceled, calling the callbacks as
+// it's won and lost. When ctx is canceled the leader resigns, letting go of
+// the lock so another elector can take over straight away. The returned
+// channel is closed once it has stopped.
+func (e *Elector) Run(ctx context.Context) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		t := time.NewTicker(e.interval)
+		defer t.Stop()
+
+		var (
+			cancel  context.CancelFunc
+			elected <-chan struct{} // nil if not the leader
+			renewed time.Time
+		)
+
+		for {
+			ok, err := e.acquire(ctx)
+
+			now := time.Now()
+
+			switch {
+			case err != nil:
+				e.l.Error().
+					Err(err).
+					Bool("leader", elected != nil).
+					Msg("failed to take or renew leader lock")
+
+				// the lock may still be ours, so the leader keeps at it
+				// until it might have expired
+				ok = elected != nil && !expiring(renewed, now, e.interval, e.ttl)
+
+			case ok:
+				renewed = now
+			}
+
+			switch {
+			case ok && elected == nil:
+				cancel, elected = e.elect(ctx)
+
+			case !ok && elected != nil:
+				e.l.Warn().Msg("lost leadership")
+
+				e.resign(cancel, elected, false)
+				elected = nil
+			}
+
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				if elected != nil {
+					e.resign(cancel, elected, true)
+				}
+
+				e.l.Info().
+					Err(ctx.Err()).
+					Msg("context canceled: shutting down leader election")
+
+				return
+			}
+		}
+	}()
+
+	return done
+}
+
+// elect calls OnElected with a child of ctx, returning its cancel func and a
+// channel that's closed once it returns.
+func (e *Elector) elect(ctx context.Context) (context.CancelFunc, <-chan struct{}) {
+	e.l.Info().Msg("elected leader")
+
+	lctx, cancel := context.WithCancel(ctx)
+	elected := make(chan struct{})
+
+	go func() {
+		defer close(elected)
+		e.onElected(lctx)
+	}()
+
+	return cancel, elected
+}
+
+// resign cancels the OnElected callback, waits for it to return, and calls
+// OnResigned. The lock is deleted if release is true.
+func (e *Elector) resign(cancel context.CancelFunc, elected <-chan struct{}, release bool) {
+	cancel()
+	<-elected
+
+	if release {
+		// the Run context is already canceled, so it can't be used to let go
+		ctx, rcancel := context.WithTimeout(context.Background(), releaseTimeout)
+
+		if err := e.release(ctx); err != nil {
+			e.l.Error().
+				Err(err).
+				Msg("failed to release leader lock")
+		}
+
+		rcancel()
+	}
+
+	e.l.Info().Msg("resigned leadership")
+
+	if e.onResigned != nil {
+		e.onResigned()
+	}
+}
+
+// expiring reports whether a lock last renewed at renewed may expire before
+// the next attempt to renew it, one interval after now.
+func expiring(renewed, now time.Time, interval, ttl time.Duration) bool {
+	return now.Add(interval).Sub(renewed) >= ttl
+}
+
+// renewLock extends the lock at KEYS[1] by ARGV[2] milliseconds, if it's held
+// by ARGV[1].
+var renewLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+
+return 0
+`)
+
+// releaseLock deletes the lock at KEYS[1], if it's held by ARGV[1].
+var releaseLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+
+return 0
+`)
+
+// acquire takes the lock, or renews it if this elector already holds it. It
+// returns whether this elector holds the lock.
+func (e *Elector) acquire(ctx context.Context) (bool, error) {
+	ok, err := e.r.SetNX(ctx, e.key, e.id, e.ttl).Result()
+	if err != nil {
+		return false, err
+	}
+
+	if ok {
+		return true, nil
+	}
+
+	n, err := renewLock.Run(ctx, e.r, []string{e.key}, e.id, e.ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (e *Elector) release(ctx context.Context) error {
+	return releaseLock.Run(ctx, e.r, []string{e.key}, e.id).Err()
+}
@@ -0,0 +1,43 @@
+package leader
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_expiring(t *testing.T) {
+	renewed := time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{
+			name: "just_renewed",
+			now:  renewed,
+		},
+		{
+			name: "one_failed_renewal",
+			now:  renewed.Add(10 * time.Second),
+		},
+		{
+			name: "two_failed_renewals",
+			now:  renewed.Add(20 * time.Second),
+			want: true,
+		},
+		{
+			name: "expired",
+			now:  renewed.Add(time.Minute),
+			want: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expiring(renewed, tt.now, 10*time.Second, 30*time.Second); got != tt.want {
+				t.Fatalf("expiring() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
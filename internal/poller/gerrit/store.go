@@ -5,7 +5,7 @@ import (
 	"fmt"
 	"time"
 
-	"github.com/go-redis/redis"
+	"github.com/go-redis/redis/v8"
 )
 
 const (
@@ -15,14 +15,14 @@ const (
 
 // DefaultStore is a default implementation of the Store interface.
 type DefaultStore struct {
-	r *redis.Client
+	r redis.UniversalClient
 }
 
 var _ Store = (*DefaultStore)(nil)
 
 // NewStore returns a new DefaultStore.
-func NewStore(rc *redis.Client) (*DefaultStore, error) {
-	res := rc.Set(redisTestKey, "foobar", 1*time.Second)
+func NewStore(ctx context.Context, rc redis.UniversalClient) (*DefaultStore, error) {
+	res := rc.Set(ctx, redisTestKey, "foobar", 1*time.Second)
 
 	if err := res.Err(); err != nil {
 		return nil, fmt.Errorf("failed to write to redis: %w", err)
@@ -40,7 +40,7 @@ func (s *DefaultStore) Get(ctx context.Context) (int64, bool, error) {
 		// noop
 	}
 
-	res := s.r.Get(redisKey)
+	res := s.r.Get(ctx, redisKey)
 	if err := res.Err(); err != nil {
 		if err == redis.Nil {
 			return 0, true, nil
@@ -74,7 +74,7 @@ func (s *DefaultStore) Put(ctx context.Context, id int64) error {
 	}
 
 	// set for 31 days
-	res := s.r.Set(redisKey, id, 31*24*time.Hour)
+	res := s.r.Set(ctx, redisKey, id, 31*24*time.Hour)
 
 	if err := res.Err(); err != nil {
 		return fmt.Errorf("failed to set last ID %d: %w", id, err)
@@ -0,0 +1,354 @@
+// Package postqueue provides a Slack client for handlers that queues the
+// messages they post, per channel, so the bot's replies to a channel are sent
+// in the order they were posted, no faster than Slack's limit of about one
+// message per second per channel. Messages that pile up behind that limit are
+// combined into one, so a burst of triggers gets one reply instead of many.
+package postqueue
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+const (
+	// DefaultInterval is the time between messages to a channel, when New
+	// isn't given one.
+	DefaultInterval = time.Second
+
+	// maxCombined is the most messages combined into one
+	maxCombined = 10
+
+	// maxCombinedText is the longest text combined messages can have, which
+	// is well short of Slack's limit, so they stay readable
+	maxCombinedText = 4000
+)
+
+// Queue is a workqueue.SlackClient that queues posted messages per channel.
+// Calls other than posting a message go straight to the wrapped client.
+//
+// Posting still blocks until the message is sent, and returns what Slack did,
+// so a handler sees the error and can be retried. A caller whose context ends
+// while queued gets the context's error, and its message isn't sent.
+type Queue struct {
+	workqueue.SlackClient
+
+	l        zerolog.Logger
+	interval time.Duration
+
+	mu       sync.Mutex
+	channels map[string]*channelQueue
+}
+
+var _ workqueue.SlackClient = (*Queue)(nil)
+
+// channelQueue is the messages waiting to be posted to one channel
+type channelQueue struct {
+	posts []*post
+}
+
+// post is one queued message
+type post struct {
+	ctx     context.Context
+	channel string
+	opts    []slack.MsgOption
+
+	// key is what the message must share with others to be combined with
+	// them, which is everything but its text, or empty if it can't be
+	key    string
+	text   string
+	values url.Values
+
+	done chan result
+}
+
+// result is what posting a message returned
+type result struct {
+	channel string
+	ts      string
+	text    string
+	err     error
+}
+
+// New returns a *Queue that posts with sc, sending at most one message per
+// interval to each channel. An interval <= 0 uses DefaultInterval.
+func New(sc workqueue.SlackClient, logger zerolog.Logger, interval time.Duration) *Queue {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Queue{
+		SlackClient: sc,
+		l:           logger,
+		interval:    interval,
+		channels:    make(map[string]*channelQueue),
+	}
+}
+
+// PostMessageContext satisfies workqueue.SlackClient, queueing the message.
+func (q *Queue) PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error) {
+	r, queued := q.post(ctx, channelID, options)
+	if !queued {
+		return q.SlackClient.PostMessageContext(ctx, channelID, options...)
+	}
+
+	return r.channel, r.ts, r.err
+}
+
+// SendMessageContext satisfies workqueue.SlackClient, queueing the message if
+// it's being posted, rather than being updated or posted as an ephemeral one.
+func (q *Queue) SendMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, string, error) {
+	r, queued := q.post(ctx, channelID, options)
+	if !queued {
+		return q.SlackClient.SendMessageContext(ctx, channelID, options...)
+	}
+
+	return r.channel, r.ts, r.text, r.err
+}
+
+// post queues the message and waits for it to be sent. It returns false,
+// without queueing it, if the options aren't for posting a message.
+func (q *Queue) post(ctx context.Context, channelID string, options []slack.MsgOption) (result, bool) {
+	p, ok := newPost(ctx, channelID, options)
+	if !ok {
+		return result{}, false
+	}
+
+	q.enqueue(p)
+
+	select {
+	case r := <-p.done:
+		return r, true
+
+	case <-ctx.Done():
+		return result{err: ctx.Err()}, true
+	}
+}
+
+// newPost returns the post for the message, or false if it isn't one that's
+// being posted.
+func newPost(ctx context.Context, channelID string, options []slack.MsgOption) (*post, bool) {
+	endpoint, values, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	if err != nil || endpoint != "chat.postMessage" {
+		return nil, false
+	}
+
+	p := &post{
+		ctx:     ctx,
+		channel: channelID,
+		opts:    options,
+		text:    values.Get("text"),
+		values:  values,
+		done:    make(chan result, 1),
+	}
+
+	if combinable(values) {
+		key := url.Values{}
+
+		for k, v := range values {
+			if k != "text" {
+				key[k] = v
+			}
+		}
+
+		p.key = key.Encode()
+	}
+
+	return p, true
+}
+
+// combinedValues are the values a message may have to be combined with others,
+// and the ones they may be set to, if they're limited. They're the ones
+// options rebuilds the message from, since the options a message was posted
+// with can't have their text replaced.
+var combinedValues = map[string][]string{
+	"token":           nil,
+	"channel":         nil,
+	"text":            nil,
+	"thread_ts":       nil,
+	"unfurl_links":    {"true", "false"},
+	"unfurl_media":    {"false"},
+	"reply_broadcast": {"true"},
+	"mrkdwn":          {"false"},
+	"as_user":         {"true"},
+}
+
+// combinable returns whether the message with values is plain text, without
+// attachments, blocks, or anything else options can't rebuild.
+func combinable(values url.Values) bool {
+	if len(values.Get("text")) == 0 {
+		return false
+	}
+
+	for k, v := range values {
+		allowed, ok := combinedValues[k]
+		if !ok || len(v) != 1 {
+			return false
+		}
+
+		if allowed != nil && !contains(allowed, v[0]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// options returns the options for posting a combinable message with values,
+// but with text instead.
+func options(values url.Values, text string) []slack.MsgOption {
+	opts := []slack.MsgOption{slack.MsgOptionText(text, false)}
+
+	if ts := values.Get("thread_ts"); len(ts) > 0 {
+		opts = append(opts, slack.MsgOptionTS(ts))
+	}
+
+	switch values.Get("unfurl_links") {
+	case "true":
+		opts = append(opts, slack.MsgOptionEnableLinkUnfurl())
+	case "false":
+		opts = append(opts, slack.MsgOptionDisableLinkUnfurl())
+	}
+
+	if values.Get("unfurl_media") == "false" {
+		opts = append(opts, slack.MsgOptionDisableMediaUnfurl())
+	}
+
+	if values.Get("reply_broadcast") == "true" {
+		opts = append(opts, slack.MsgOptionBroadcast())
+	}
+
+	if values.Get("mrkdwn") == "false" {
+		opts = append(opts, slack.MsgOptionDisableMarkdown())
+	}
+
+	if values.Get("as_user") == "true" {
+		opts = append(opts, slack.MsgOptionAsUser(true))
+	}
+
+	return opts
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q *Queue) enqueue(p *post) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	cq, ok := q.channels[p.channel]
+	if !ok {
+		cq = &channelQueue{}
+		q.channels[p.channel] = cq
+
+		go q.run(p.channel, cq)
+	}
+
+	cq.posts = append(cq.posts, p)
+}
+
+// run sends the channel's messages until there are none left, waiting the
+// interval after each one.
+func (q *Queue) run(channel string, cq *channelQueue) {
+	for {
+		q.mu.Lock()
+
+		if len(cq.posts) == 0 {
+			delete(q.channels, channel)
+			q.mu.Unlock()
+
+			return
+		}
+
+		batch := next(cq)
+
+		q.mu.Unlock()
+
+		if q.send(batch) {
+			time.Sleep(q.interval)
+		}
+	}
+}
+
+// next removes the next message from cq, along with those right behind it it
+// can be combined with.
+func next(cq *channelQueue) []*post {
+	n := 1
+
+	if key := cq.posts[0].key; len(key) > 0 {
+		size := len(cq.posts[0].text)
+
+		for n < len(cq.posts) && n < maxCombined {
+			p := cq.posts[n]
+
+			size += len(p.text) + 1
+
+			if p.key != key || size > maxCombinedText {
+				break
+			}
+
+			n++
+		}
+	}
+
+	batch := cq.posts[:n:n]
+	cq.posts = cq.posts[n:]
+
+	return batch
+}
+
+// send posts the batch as one message, returning whether anything was sent.
+// Messages whose callers stopped waiting are dropped.
+func (q *Queue) send(batch []*post) bool {
+	live := batch[:0]
+
+	for _, p := range batch {
+		if p.ctx.Err() == nil {
+			live = append(live, p)
+		}
+	}
+
+	if len(live) == 0 {
+		return false
+	}
+
+	first := live[0]
+	opts := first.opts
+
+	if len(live) > 1 {
+		texts := make([]string, len(live))
+
+		for i, p := range live {
+			texts[i] = p.text
+		}
+
+		opts = options(first.values, strings.Join(texts, "\n"))
+
+		q.l.Debug().
+			Str("channel_id", first.channel).
+			Int("messages", len(live)).
+			Msg("combined queued messages")
+	}
+
+	var r result
+	r.channel, r.ts, r.text, r.err = q.SlackClient.SendMessageContext(first.ctx, first.channel, opts...)
+
+	for _, p := range live {
+		p.done <- r
+	}
+
+	return true
+}
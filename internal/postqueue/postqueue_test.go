@@ -0,0 +1,115 @@
+package postqueue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+)
+
+// recorder is a workqueue.SlackClient that records the text of each message
+// sent, taking delay to send each one.
+type recorder struct {
+	workqueue.SlackClient
+
+	delay time.Duration
+
+	mu    sync.Mutex
+	texts []string
+}
+
+func (r *recorder) SendMessageContext(_ context.Context, channelID string, options ...slack.MsgOption) (string, string, string, error) {
+	time.Sleep(r.delay)
+
+	_, values, _ := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+
+	r.mu.Lock()
+	r.texts = append(r.texts, values.Get("text"))
+	r.mu.Unlock()
+
+	return channelID, "1234.5678", values.Get("text"), nil
+}
+
+func (r *recorder) sent() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]string(nil), r.texts...)
+}
+
+func TestQueue(t *testing.T) {
+	r := &recorder{}
+	q := New(r, zerolog.Nop(), 50*time.Millisecond)
+	ctx := context.Background()
+
+	if _, ts, err := q.PostMessageContext(ctx, "C123", slack.MsgOptionText("first", false)); err != nil || ts != "1234.5678" {
+		t.Fatalf("PostMessageContext() = %q, %v; want 1234.5678, <nil>", ts, err)
+	}
+
+	// these queue up behind the first one's interval, so they're combined,
+	// except for the one with an attachment
+	var wg sync.WaitGroup
+
+	post := func(opts ...slack.MsgOption) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if _, _, _, err := q.SendMessageContext(ctx, "C123", opts...); err != nil {
+				t.Errorf("SendMessageContext() unexpected error: %v", err)
+			}
+		}()
+
+		// keep the order they're queued in deterministic
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	post(slack.MsgOptionText("second", false))
+	post(slack.MsgOptionText("third", false))
+	post(slack.MsgOptionText("fourth", false), slack.MsgOptionAttachments(slack.Attachment{Text: "attached"}))
+	post(slack.MsgOptionText("fifth", false))
+
+	wg.Wait()
+
+	want := []string{"first", "second\nthird", "fourth", "fifth"}
+	got := r.sent()
+
+	if len(got) != len(want) {
+		t.Fatalf("sent %q, want %q", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("sent %q, want %q", got, want)
+		}
+	}
+}
+
+func TestQueue_canceled(t *testing.T) {
+	r := &recorder{delay: 20 * time.Millisecond}
+	q := New(r, zerolog.Nop(), time.Millisecond)
+
+	go func() {
+		_, _, _ = q.PostMessageContext(context.Background(), "C123", slack.MsgOptionText("first", false))
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+
+	if _, _, err := q.PostMessageContext(ctx, "C123", slack.MsgOptionText("second", false)); err != context.DeadlineExceeded {
+		t.Fatalf("PostMessageContext() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if got := r.sent(); len(got) != 1 || got[0] != "first" {
+		t.Fatalf("sent %q, want only the first message", got)
+	}
+}
@@ -0,0 +1,94 @@
+// Package redisutil builds the Redis clients every component uses, from the
+// Redis section of the config, so they all connect the same way.
+package redisutil
+
+import (
+	"crypto/tls"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/config"
+)
+
+const (
+	timeout      = 2 * time.Second
+	poolSize     = 20
+	minIdleConns = 5
+)
+
+// tlsConfig returns the TLS config for connecting to Redis, or nil if it's
+// connected to over plain text.
+func tlsConfig(r config.R) *tls.Config {
+	if r.Insecure {
+		return nil
+	}
+
+	return &tls.Config{
+		InsecureSkipVerify: r.SkipVerify,
+	} // #nosec G402 -- Heroku Redis has an untrusted cert
+}
+
+// Options returns the options for connecting to the Redis server at r.Addr.
+func Options(r config.R) *redis.Options {
+	return &redis.Options{
+		Network:      "tcp",
+		Addr:         r.Addr,
+		Password:     r.Password,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		PoolSize:     poolSize,
+		MinIdleConns: minIdleConns,
+		PoolTimeout:  timeout,
+		TLSConfig:    tlsConfig(r),
+	}
+}
+
+// FailoverOptions returns the options for connecting to the master the
+// Sentinels at r.SentinelAddrs know as r.SentinelMaster.
+func FailoverOptions(r config.R) *redis.FailoverOptions {
+	return &redis.FailoverOptions{
+		MasterName:    r.SentinelMaster,
+		SentinelAddrs: r.SentinelAddrs,
+		Password:      r.Password,
+		DialTimeout:   timeout,
+		ReadTimeout:   timeout,
+		WriteTimeout:  timeout,
+		PoolSize:      poolSize,
+		MinIdleConns:  minIdleConns,
+		PoolTimeout:   timeout,
+		TLSConfig:     tlsConfig(r),
+	}
+}
+
+// ClusterOptions returns the options for connecting to the Redis Cluster with
+// the nodes at r.ClusterAddrs.
+func ClusterOptions(r config.R) *redis.ClusterOptions {
+	return &redis.ClusterOptions{
+		Addrs:        r.ClusterAddrs,
+		Password:     r.Password,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		PoolSize:     poolSize,
+		MinIdleConns: minIdleConns,
+		PoolTimeout:  timeout,
+		TLSConfig:    tlsConfig(r),
+	}
+}
+
+// ConnectRedis returns a client for the Redis server in r, which is found
+// through Sentinel if r.SentinelMaster is set, or for the Redis Cluster if
+// r.ClusterAddrs is. It doesn't connect until the first command, so the caller
+// should check the connection if it needs to.
+func ConnectRedis(r config.R) redis.UniversalClient {
+	if len(r.ClusterAddrs) > 0 {
+		return redis.NewClusterClient(ClusterOptions(r))
+	}
+
+	if len(r.SentinelMaster) > 0 {
+		return redis.NewFailoverClient(FailoverOptions(r))
+	}
+
+	return redis.NewClient(Options(r))
+}
@@ -0,0 +1,81 @@
+// Package shutdown provides a way to shut a process down in order, like
+// stopping HTTP intake before draining the workqueue handlers, within one
+// deadline such as Heroku's 30 seconds after SIGTERM.
+package shutdown
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// step is one stage of shutting down
+type step struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// Sequence is the stages of shutting down, run in the order they're added.
+type Sequence struct {
+	l     zerolog.Logger
+	steps []step
+}
+
+// New returns a *Sequence that logs each stage with logger.
+func New(logger zerolog.Logger) *Sequence {
+	return &Sequence{l: logger}
+}
+
+// Then adds a stage, to be run once the ones before it have returned. The
+// ctx it's given expires at the Sequence's deadline, so it only has what the
+// stages before it left. It returns s, so stages can be chained.
+func (s *Sequence) Then(name string, fn func(ctx context.Context) error) *Sequence {
+	s.steps = append(s.steps, step{name: name, fn: fn})
+	return s
+}
+
+// Run runs the stages in order, giving them timeout between them. Every stage
+// is run, even once one has failed or the deadline has passed, so things like
+// connections still get closed. It returns the error from the first stage
+// that failed.
+func (s *Sequence) Run(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+
+	var err error
+
+	for _, st := range s.steps {
+		ss := time.Now()
+
+		serr := st.fn(ctx)
+
+		l := s.l.With().
+			Str("step", st.name).
+			Dur("step_duration", time.Since(ss)).
+			Logger()
+
+		if serr != nil {
+			l.Error().Err(serr).Msg("shutdown step failed")
+
+			if err == nil {
+				err = fmt.Errorf("failed to %s: %w", st.name, serr)
+			}
+
+			continue
+		}
+
+		l.Info().Msg("shutdown step done")
+	}
+
+	s.l.Info().
+		Dur("duration", time.Since(start)).
+		Dur("timeout", timeout).
+		Bool("deadline_exceeded", ctx.Err() != nil).
+		Msg("shut down")
+
+	return err
+}
@@ -0,0 +1,66 @@
+package shutdown
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+func TestSequence_Run(t *testing.T) {
+	var ran []string
+
+	record := func(name string, err error) func(context.Context) error {
+		return func(context.Context) error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+
+	err := New(zerolog.Nop()).
+		Then("stop intake", record("stop intake", nil)).
+		Then("drain handlers", record("drain handlers", errors.New("timed out"))).
+		Then("close redis", record("close redis", errors.New("closed"))).
+		Run(time.Second)
+
+	if err == nil || err.Error() != "failed to drain handlers: timed out" {
+		t.Fatalf("Run() error = %v, want the drain handlers error", err)
+	}
+
+	want := []string{"stop intake", "drain handlers", "close redis"}
+
+	if len(ran) != len(want) {
+		t.Fatalf("ran %v, want %v", ran, want)
+	}
+
+	for i := range want {
+		if ran[i] != want[i] {
+			t.Fatalf("ran %v, want %v", ran, want)
+		}
+	}
+}
+
+func TestSequence_Run_deadline(t *testing.T) {
+	var last error
+
+	err := New(zerolog.Nop()).
+		Then("slow", func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}).
+		Then("close", func(ctx context.Context) error {
+			last = ctx.Err()
+			return nil
+		}).
+		Run(10 * time.Millisecond)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	if !errors.Is(last, context.DeadlineExceeded) {
+		t.Fatalf("later step wasn't run with the expired context: %v", last)
+	}
+}
@@ -0,0 +1,119 @@
+package slacklimit
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics are the Prometheus metrics for the Transport. It satisfies
+// prometheus.Collector, so the caller can register it with whichever registry
+// they expose. A nil *Metrics records nothing.
+type Metrics struct {
+	rateLimited *prometheus.CounterVec
+	retries     *prometheus.CounterVec
+	opened      *prometheus.CounterVec
+	rejected    *prometheus.CounterVec
+	wait        *prometheus.HistogramVec
+}
+
+var _ prometheus.Collector = (*Metrics)(nil)
+
+// NewMetrics returns a new *Metrics, to be given to New.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gopherbot",
+			Subsystem: "slack",
+			Name:      "rate_limited_total",
+			Help:      "Slack API calls that were rate limited, by method.",
+		}, []string{"method"}),
+
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gopherbot",
+			Subsystem: "slack",
+			Name:      "retries_total",
+			Help:      "Rate limited Slack API calls that were retried, by method.",
+		}, []string{"method"}),
+
+		opened: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gopherbot",
+			Subsystem: "slack",
+			Name:      "circuit_opened_total",
+			Help:      "Times a method was rate limited enough to fail its calls fast, by method.",
+		}, []string{"method"}),
+
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gopherbot",
+			Subsystem: "slack",
+			Name:      "circuit_rejected_total",
+			Help:      "Slack API calls failed fast because their method's circuit was open, by method.",
+		}, []string{"method"}),
+
+		wait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gopherbot",
+			Subsystem: "slack",
+			Name:      "rate_limit_wait_seconds",
+			Help:      "How long Slack API calls waited for their method's rate limit, by method.",
+			Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60},
+		}, []string{"method"}),
+	}
+}
+
+// Describe satisfies prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.rateLimited.Describe(ch)
+	m.retries.Describe(ch)
+	m.opened.Describe(ch)
+	m.rejected.Describe(ch)
+	m.wait.Describe(ch)
+}
+
+// Collect satisfies prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.rateLimited.Collect(ch)
+	m.retries.Collect(ch)
+	m.opened.Collect(ch)
+	m.rejected.Collect(ch)
+	m.wait.Collect(ch)
+}
+
+func (m *Metrics) observeRateLimited(method string) {
+	if m == nil {
+		return
+	}
+
+	m.rateLimited.WithLabelValues(method).Inc()
+}
+
+func (m *Metrics) observeRetry(method string) {
+	if m == nil {
+		return
+	}
+
+	m.retries.WithLabelValues(method).Inc()
+}
+
+func (m *Metrics) observeCircuitOpen(method string) {
+	if m == nil {
+		return
+	}
+
+	m.opened.WithLabelValues(method).Inc()
+}
+
+func (m *Metrics) observeRejected(method string) {
+	if m == nil {
+		return
+	}
+
+	m.rejected.WithLabelValues(method).Inc()
+}
+
+func (m *Metrics) observeWait(method string, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.wait.WithLabelValues(method).Observe(d.Seconds())
+}
@@ -0,0 +1,253 @@
+// Package slacklimit provides an http.RoundTripper for the Slack client that
+// handles Slack's rate limits. Calls that are rate limited are retried after
+// the Retry-After Slack gives, and calls to the same API method wait until
+// then instead of being rate limited too. A method that keeps being rate
+// limited has its calls fail fast for a while, so a spike doesn't tie up every
+// handler waiting on Slack.
+package slacklimit
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// maxRetries is how many times a rate limited call is retried
+	maxRetries = 3
+
+	// maxWait is the longest Retry-After that's waited for, anything longer
+	// is returned to the caller to deal with
+	maxWait = time.Minute
+
+	// defaultRetryAfter is used when Slack doesn't say how long to wait
+	defaultRetryAfter = time.Second
+
+	// failureThreshold is how many times in a row a method is rate limited
+	// before its circuit opens
+	failureThreshold = 5
+
+	// cooldown is how long calls to a method fail fast once its circuit opens
+	cooldown = 30 * time.Second
+)
+
+// ErrCircuitOpen is returned for calls to a method that's been rate limited
+// too many times in a row, until its cooldown has passed.
+var ErrCircuitOpen = errors.New("slack method is being rate limited, circuit open")
+
+// Transport is the rate-limit-aware http.RoundTripper.
+type Transport struct {
+	base    http.RoundTripper
+	logger  zerolog.Logger
+	metrics *Metrics
+
+	mu      sync.Mutex
+	methods map[string]*limit
+}
+
+var _ http.RoundTripper = (*Transport)(nil)
+
+// New returns a *Transport wrapping base. m may be nil.
+func New(base http.RoundTripper, logger zerolog.Logger, m *Metrics) *Transport {
+	return &Transport{
+		base:    base,
+		logger:  logger,
+		metrics: m,
+		methods: make(map[string]*limit),
+	}
+}
+
+// Client returns an *http.Client using the *Transport.
+func (t *Transport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+// limit is the rate limit state of a single API method, since Slack's limits
+// are per method.
+type limit struct {
+	mu sync.Mutex
+
+	// until is when calls can be made again, per the last Retry-After
+	until time.Time
+
+	// failures is how many times in a row calls were rate limited
+	failures int
+
+	// openUntil is when the circuit closes again
+	openUntil time.Time
+}
+
+func (t *Transport) limitFor(method string) *limit {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	l, ok := t.methods[method]
+	if !ok {
+		l = &limit{}
+		t.methods[method] = l
+	}
+
+	return l
+}
+
+// delay returns how long to wait before making a call, or ErrCircuitOpen.
+func (l *limit) delay(now time.Time) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Before(l.openUntil) {
+		return 0, ErrCircuitOpen
+	}
+
+	return l.until.Sub(now), nil
+}
+
+// limited records a rate limited call, returning whether it opened the
+// circuit.
+func (l *limit) limited(now time.Time, retryAfter time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if u := now.Add(retryAfter); u.After(l.until) {
+		l.until = u
+	}
+
+	l.failures++
+
+	if l.failures < failureThreshold || now.Before(l.openUntil) {
+		return false
+	}
+
+	l.openUntil = now.Add(cooldown)
+
+	return true
+}
+
+// succeeded records a call that wasn't rate limited.
+func (l *limit) succeeded() {
+	l.mu.Lock()
+	l.failures = 0
+	l.mu.Unlock()
+}
+
+// RoundTrip satisfies http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	l := t.limitFor(method)
+
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(req, method, l); err != nil {
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if err != nil {
+			return nil, err
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests {
+			l.succeeded()
+			return resp, nil
+		}
+
+		ra := retryAfter(resp.Header)
+
+		t.metrics.observeRateLimited(method)
+
+		if l.limited(time.Now(), ra) {
+			t.metrics.observeCircuitOpen(method)
+
+			t.logger.Warn().
+				Str("slack_method", method).
+				Dur("cooldown", cooldown).
+				Msg("slack method rate limited too many times; failing calls fast")
+		}
+
+		if attempt >= maxRetries || ra > maxWait || !rewindable(req) {
+			return resp, nil
+		}
+
+		// let the connection be reused
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		_ = resp.Body.Close()
+
+		if req, err = rewind(req); err != nil {
+			return nil, err
+		}
+
+		t.metrics.observeRetry(method)
+
+		t.logger.Debug().
+			Str("slack_method", method).
+			Dur("retry_after", ra).
+			Int("attempt", attempt+1).
+			Msg("slack method rate limited; retrying")
+	}
+}
+
+// wait blocks until calls to the method can be made, or the request's context
+// is done.
+func (t *Transport) wait(req *http.Request, method string, l *limit) error {
+	d, err := l.delay(time.Now())
+	if err != nil {
+		t.metrics.observeRejected(method)
+		return fmt.Errorf("%s: %w", method, err)
+	}
+
+	if d <= 0 {
+		return nil
+	}
+
+	t.metrics.observeWait(method, d)
+
+	tmr := time.NewTimer(d)
+	defer tmr.Stop()
+
+	select {
+	case <-tmr.C:
+		return nil
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// retryAfter returns how long Slack asked us to wait.
+func retryAfter(h http.Header) time.Duration {
+	n, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || n < 0 {
+		return defaultRetryAfter
+	}
+
+	return time.Duration(n) * time.Second
+}
+
+// rewindable returns whether the request can be sent again.
+func rewindable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewind returns a copy of the request with a fresh body, to send it again.
+func rewind(req *http.Request) (*http.Request, error) {
+	r := req.Clone(req.Context())
+
+	if req.GetBody == nil {
+		return r, nil
+	}
+
+	body, err := req.GetBody()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rewind request body: %w", err)
+	}
+
+	r.Body = body
+
+	return r, nil
+}
@@ -0,0 +1,131 @@
+package slacklimit
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func response(status int, retryAfter string) *http.Response {
+	h := make(http.Header)
+	if len(retryAfter) > 0 {
+		h.Set("Retry-After", retryAfter)
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     h,
+		Body:       ioutil.NopCloser(strings.NewReader("{}")),
+	}
+}
+
+func newRequest(t *testing.T) *http.Request {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, "https://slack.com/api/chat.postMessage", strings.NewReader("channel=C123"))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+
+	return req
+}
+
+func TestTransport_RoundTrip_retries(t *testing.T) {
+	var bodies []string
+
+	base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		b, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+
+		if len(bodies) == 1 {
+			return response(http.StatusTooManyRequests, "0"), nil
+		}
+
+		return response(http.StatusOK, ""), nil
+	})
+
+	resp, err := New(base, zerolog.Nop(), NewMetrics()).RoundTrip(newRequest(t))
+	if err != nil {
+		t.Fatalf("RoundTrip() unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("RoundTrip() status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if len(bodies) != 2 {
+		t.Fatalf("base called %d times, want 2", len(bodies))
+	}
+
+	for i, b := range bodies {
+		if b != "channel=C123" {
+			t.Fatalf("call %d body = %q, want %q", i, b, "channel=C123")
+		}
+	}
+}
+
+func TestTransport_RoundTrip_circuit(t *testing.T) {
+	var calls int
+
+	base := roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+		return response(http.StatusTooManyRequests, "0"), nil
+	})
+
+	tr := New(base, zerolog.Nop(), nil)
+
+	resp, err := tr.RoundTrip(newRequest(t))
+	if err != nil {
+		t.Fatalf("RoundTrip() unexpected error: %v", err)
+	}
+
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("RoundTrip() status = %d, want %d", resp.StatusCode, http.StatusTooManyRequests)
+	}
+
+	if calls != maxRetries+1 {
+		t.Fatalf("base called %d times, want %d", calls, maxRetries+1)
+	}
+
+	if _, err = tr.RoundTrip(newRequest(t)); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("RoundTrip() error = %v, want %v", err, ErrCircuitOpen)
+	}
+
+	if calls != failureThreshold {
+		t.Fatalf("base called %d times, want %d", calls, failureThreshold)
+	}
+}
+
+func Test_retryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{name: "seconds", value: "30", want: 30 * time.Second},
+		{name: "missing", want: defaultRetryAfter},
+		{name: "invalid", value: "soon", want: defaultRetryAfter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := make(http.Header)
+			if len(tt.value) > 0 {
+				h.Set("Retry-After", tt.value)
+			}
+
+			if got := retryAfter(h); got != tt.want {
+				t.Fatalf("retryAfter() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
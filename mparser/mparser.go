@@ -375,3 +375,44 @@ func Parse(message, channelID string) ([]Mention, [][]int) {
 
 	return mentions, locations
 }
+
+// Link represents a URL found in a Slack message. Slack wraps any links it
+// recognized in angle brackets, optionally followed by a pipe and the text
+// that was displayed to the user (e.g., <https://golang.org|golang.org>).
+type Link struct {
+	URL   string
+	Label string
+}
+
+// Links returns the http and https links Slack identified in the message, in
+// the order they appear. Mentions and other special sequences are ignored.
+func Links(message string) []Link {
+	var links []Link
+
+	for {
+		start := strings.IndexByte(message, '<')
+		if start == -1 {
+			return links
+		}
+
+		end := strings.IndexByte(message[start:], '>')
+		if end == -1 {
+			return links
+		}
+
+		inner := message[start+1 : start+end]
+		message = message[start+end+1:]
+
+		if !strings.HasPrefix(inner, "http://") && !strings.HasPrefix(inner, "https://") {
+			continue
+		}
+
+		l := Link{URL: inner}
+
+		if i := strings.IndexByte(inner, '|'); i > -1 {
+			l.URL, l.Label = inner[:i], inner[i+1:]
+		}
+
+		links = append(links, l)
+	}
+}
@@ -81,3 +81,40 @@ func TestParseAndSplice(t *testing.T) {
 		})
 	}
 }
+
+func TestLinks(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []Link
+	}{
+		{
+			name: "nothing",
+		},
+		{
+			name:  "no_links",
+			input: "Hey <@UA1234>, welcome to <#CTST123|general>!",
+		},
+		{
+			name:  "links",
+			input: "see <https://golang.org/doc|the docs> or <http://example.org> and <mailto:a@example.org|a@example.org>",
+			want: []Link{
+				{URL: "https://golang.org/doc", Label: "the docs"},
+				{URL: "http://example.org"},
+			},
+		},
+		{
+			name:  "unterminated",
+			input: "<https://golang.org> <https://example.org",
+			want: []Link{
+				{URL: "https://golang.org"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmpDiff(t, "links", cmp.Diff(tt.want, Links(tt.input)))
+		})
+	}
+}
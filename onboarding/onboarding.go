@@ -0,0 +1,253 @@
+// Package onboarding sends new members of the workspace a sequence of DMs,
+// like a welcome message followed by the rules and some channel suggestions.
+// Each step after the first is published to the workqueue as a scheduled job,
+// and each member's progress is kept in Redis, so restarts neither lose steps
+// nor send them twice.
+package onboarding
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/handler"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+)
+
+// JobName is the name of the scheduled job the steps are published as. The
+// consumer's scheduled job handler should run Pipeline.Deliver for it.
+const JobName = "onboarding"
+
+const (
+	redisProgressPrefix = "onboarding:progress:"
+
+	// lockPrefix is the prefix of the name of the lock held while a step is
+	// being sent
+	lockPrefix = "onboarding:"
+
+	// progressTTL is how long a member's progress is kept, which needs to be
+	// longer than the whole sequence takes
+	progressTTL = 30 * 24 * time.Hour
+
+	// claimTTL is how long a step is claimed while it's being sent, so only
+	// one consumer sends it
+	claimTTL = time.Minute
+)
+
+// errClaimed is returned when another consumer is sending the step.
+var errClaimed = errors.New("step is being sent by another consumer")
+
+// MessageFn returns the message to send the user for a step.
+type MessageFn func(ctx workqueue.Context, userID string) (string, error)
+
+// Step is one of the DMs in the sequence.
+type Step struct {
+	// Name uniquely identifies the step, and is what the member's progress is
+	// tracked by. Renaming a step means members who already had it are sent it
+	// again.
+	Name string
+
+	// Delay is how long after the previous step this one is sent. The first
+	// step's Delay is how long after the member joined.
+	Delay time.Duration
+
+	// Message builds the message.
+	Message MessageFn
+}
+
+// Pipeline is the onboarding sequence.
+type Pipeline struct {
+	r     redis.UniversalClient
+	q     workqueue.Publisher
+	steps []Step
+}
+
+// New returns a *Pipeline that sends the steps in order. The scheduled steps
+// are published to q.
+func New(rc redis.UniversalClient, q workqueue.Publisher, steps ...Step) (*Pipeline, error) {
+	if len(steps) == 0 {
+		return nil, errors.New("onboarding needs at least one step")
+	}
+
+	names := make(map[string]struct{}, len(steps))
+
+	for i, s := range steps {
+		if len(s.Name) == 0 {
+			return nil, fmt.Errorf("step %d has no name", i)
+		}
+
+		if _, ok := names[s.Name]; ok {
+			return nil, fmt.Errorf("step %d has duplicate name %q", i, s.Name)
+		}
+
+		if s.Message == nil {
+			return nil, fmt.Errorf("step %q has no Message", s.Name)
+		}
+
+		names[s.Name] = struct{}{}
+	}
+
+	return &Pipeline{
+		r:     rc,
+		q:     q,
+		steps: steps,
+	}, nil
+}
+
+type jobPayload struct {
+	UserID string `json:"user_id"`
+	Step   string `json:"step"`
+}
+
+// TeamJoinAction satisfies handler.TeamJoinActionFn. It starts the sequence
+// for the member who joined.
+func (p *Pipeline) TeamJoinAction(ctx workqueue.Context, tj handler.TeamJoiner, _ handler.Responder) error {
+	return p.run(ctx, tj.User().ID, 0, p.steps[0].Delay)
+}
+
+// Deliver sends the step for the scheduled job, and the ones after it. It
+// satisfies the same signature as a workqueue.ScheduledJobHandler.
+func (p *Pipeline) Deliver(ctx workqueue.Context, sj *workqueue.ScheduledJobEvent) (bool, bool, error) {
+	var jp jobPayload
+
+	if err := json.Unmarshal(sj.Payload, &jp); err != nil {
+		return false, false, fmt.Errorf("failed to unmarshal onboarding payload: %w", err)
+	}
+
+	idx := p.index(jp.Step)
+	if idx == -1 {
+		return false, true, fmt.Errorf("onboarding step %q no longer exists", jp.Step)
+	}
+
+	if err := p.run(ctx, jp.UserID, idx, 0); err != nil {
+		return true, false, err
+	}
+
+	return false, false, nil
+}
+
+func (p *Pipeline) index(name string) int {
+	for i, s := range p.steps {
+		if s.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// run sends the steps from idx onwards, until it reaches one with a delay,
+// which it schedules instead. The delay for step idx is given by wait, so
+// scheduled steps aren't delayed a second time.
+func (p *Pipeline) run(ctx workqueue.Context, userID string, idx int, wait time.Duration) error {
+	for ; idx < len(p.steps); idx++ {
+		s := p.steps[idx]
+
+		if wait > 0 {
+			return p.schedule(ctx, userID, s, wait)
+		}
+
+		if err := p.send(ctx, userID, s); err != nil {
+			return fmt.Errorf("failed to send onboarding step %s: %w", s.Name, err)
+		}
+
+		if idx+1 < len(p.steps) {
+			wait = p.steps[idx+1].Delay
+		}
+	}
+
+	return nil
+}
+
+func (p *Pipeline) schedule(ctx context.Context, userID string, s Step, d time.Duration) error {
+	payload, err := json.Marshal(jobPayload{UserID: userID, Step: s.Name})
+	if err != nil {
+		return fmt.Errorf("failed to marshal onboarding payload: %w", err)
+	}
+
+	at := time.Now().Add(d)
+
+	job, err := json.Marshal(workqueue.ScheduledJobEvent{
+		Name:          JobName,
+		ScheduledTime: at.Unix(),
+		Payload:       payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal onboarding job: %w", err)
+	}
+
+	id := JobName + ":" + userID + ":" + s.Name
+
+	if err := p.q.PublishAt(ctx, workqueue.ScheduledJob, at, workqueue.PriorityNormal, at.Unix(), id, id, job); err != nil {
+		return fmt.Errorf("failed to schedule onboarding step %s: %w", s.Name, err)
+	}
+
+	return nil
+}
+
+// send sends the step to the user, unless it already was.
+func (p *Pipeline) send(ctx workqueue.Context, userID string, s Step) error {
+	progress := redisProgressPrefix + userID
+
+	sent, err := p.r.HExists(ctx, progress, s.Name).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get progress: %w", err)
+	}
+
+	if sent {
+		ctx.Logger().Debug().
+			Str("user_id", userID).
+			Str("onboarding_step", s.Name).
+			Msg("onboarding step already sent")
+
+		return nil
+	}
+
+	claim, err := ctx.Lock(lockPrefix+userID+":"+s.Name, claimTTL)
+	if errors.Is(err, workqueue.ErrLockHeld) {
+		return errClaimed
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to claim step: %w", err)
+	}
+
+	defer func() { _ = claim.Unlock(ctx) }()
+
+	msg, err := s.Message(ctx, userID)
+	if err == nil {
+		_, _, err = ctx.Slack().PostMessageContext(ctx, userID, slack.MsgOptionText(msg, false))
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ms := time.Now().UnixNano() / int64(time.Millisecond)
+
+	pipe := p.r.TxPipeline()
+	pipe.HSet(ctx, progress, s.Name, strconv.FormatInt(ms, 10))
+	pipe.Expire(ctx, progress, progressTTL)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		// the message was sent, so don't fail the step and send it again
+		ctx.Logger().Error().
+			Err(err).
+			Str("user_id", userID).
+			Str("onboarding_step", s.Name).
+			Msg("failed to record onboarding progress")
+	}
+
+	ctx.Logger().Debug().
+		Str("user_id", userID).
+		Str("onboarding_step", s.Name).
+		Int("msg_len", len(msg)).
+		Msg("sent onboarding step")
+
+	return nil
+}
@@ -0,0 +1,235 @@
+// Package permissions maps Slack users, and user groups, to roles so handlers
+// and commands have a consistent way to gate destructive actions. The roles
+// are stored in Redis, so they're shared by all consumers.
+package permissions
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/slack-go/slack"
+)
+
+// The roles, from least to most privileged. Each role has the permissions of
+// the ones before it.
+const (
+	Member    = "member"
+	Moderator = "moderator"
+	Admin     = "admin"
+)
+
+var ranks = map[string]int{
+	Member:    0,
+	Moderator: 1,
+	Admin:     2,
+}
+
+// ValidRole returns whether role is one of the roles.
+func ValidRole(role string) bool {
+	_, ok := ranks[role]
+	return ok
+}
+
+const (
+	redisUsersKey         = "permissions:users"
+	redisGroupsKey        = "permissions:groups"
+	redisGroupMembersPref = "permissions:group_members:"
+
+	// groupMembersTTL is how long user group memberships are cached, before
+	// asking Slack again
+	groupMembersTTL = 5 * time.Minute
+)
+
+// Store is the Redis-backed permissions store. It satisfies the workqueue's
+// PermissionSvc interface.
+type Store struct {
+	r  redis.UniversalClient
+	sc *slack.Client
+
+	// mu protects admins
+	mu     sync.RWMutex
+	admins map[string]struct{}
+}
+
+// New returns a *Store. The users in admins are always admins, regardless of
+// what's in Redis, so there's someone to assign the other roles.
+func New(rc redis.UniversalClient, sc *slack.Client, admins []string) *Store {
+	s := &Store{
+		r:  rc,
+		sc: sc,
+	}
+
+	s.SetAdmins(admins)
+
+	return s
+}
+
+// SetAdmins replaces the users that are always admins, like when the config
+// is reloaded.
+func (s *Store) SetAdmins(admins []string) {
+	m := make(map[string]struct{}, len(admins))
+
+	for _, a := range admins {
+		m[a] = struct{}{}
+	}
+
+	s.mu.Lock()
+	s.admins = m
+	s.mu.Unlock()
+}
+
+// isAdmin returns whether the user is always an admin.
+func (s *Store) isAdmin(userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.admins[userID]
+
+	return ok
+}
+
+// SetUserRole gives the user the role.
+func (s *Store) SetUserRole(ctx context.Context, userID, role string) error {
+	if !ValidRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+
+	if err := s.r.HSet(ctx, redisUsersKey, userID, role).Err(); err != nil {
+		return fmt.Errorf("failed to set role of user %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+// SetGroupRole gives the members of the Slack user group the role.
+func (s *Store) SetGroupRole(ctx context.Context, groupID, role string) error {
+	if !ValidRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+
+	if err := s.r.HSet(ctx, redisGroupsKey, groupID, role).Err(); err != nil {
+		return fmt.Errorf("failed to set role of group %s: %w", groupID, err)
+	}
+
+	return nil
+}
+
+// RemoveUser removes the role given to the user, but not those they have
+// through their user groups.
+func (s *Store) RemoveUser(ctx context.Context, userID string) error {
+	if err := s.r.HDel(ctx, redisUsersKey, userID).Err(); err != nil {
+		return fmt.Errorf("failed to remove role of user %s: %w", userID, err)
+	}
+
+	return nil
+}
+
+// RemoveGroup removes the role given to the user group.
+func (s *Store) RemoveGroup(ctx context.Context, groupID string) error {
+	if err := s.r.HDel(ctx, redisGroupsKey, groupID).Err(); err != nil {
+		return fmt.Errorf("failed to remove role of group %s: %w", groupID, err)
+	}
+
+	return nil
+}
+
+// RoleOf returns the most privileged role the user has, either directly or
+// through one of their user groups. Everyone is at least a Member.
+func (s *Store) RoleOf(ctx context.Context, userID string) (string, error) {
+	if s.isAdmin(userID) {
+		return Admin, nil
+	}
+
+	role := Member
+
+	ur, err := s.r.HGet(ctx, redisUsersKey, userID).Result()
+	if err != nil && err != redis.Nil {
+		return "", fmt.Errorf("failed to get role of user %s: %w", userID, err)
+	}
+
+	if ranks[ur] > ranks[role] {
+		role = ur
+	}
+
+	groups, err := s.r.HGetAll(ctx, redisGroupsKey).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to get group roles: %w", err)
+	}
+
+	for gid, gr := range groups {
+		// no point checking membership if it wouldn't change anything
+		if ranks[gr] <= ranks[role] {
+			continue
+		}
+
+		member, err := s.inGroup(ctx, gid, userID)
+		if err != nil {
+			return "", err
+		}
+
+		if member {
+			role = gr
+		}
+	}
+
+	return role, nil
+}
+
+// UserHasRole returns whether the user has the role, or a more privileged one.
+func (s *Store) UserHasRole(ctx context.Context, userID, role string) (bool, error) {
+	if !ValidRole(role) {
+		return false, fmt.Errorf("unknown role %q", role)
+	}
+
+	r, err := s.RoleOf(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return ranks[r] >= ranks[role], nil
+}
+
+// inGroup returns whether the user is a member of the user group, using the
+// cached membership if there is one.
+func (s *Store) inGroup(ctx context.Context, groupID, userID string) (bool, error) {
+	key := redisGroupMembersPref + groupID
+
+	n, err := s.r.Exists(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check group %s members cache: %w", groupID, err)
+	}
+
+	if n == 0 {
+		members, err := s.sc.GetUserGroupMembersContext(ctx, groupID)
+		if err != nil {
+			return false, fmt.Errorf("failed to get group %s members: %w", groupID, err)
+		}
+
+		// include the group itself, so an empty group is still cached
+		vals := make([]interface{}, 0, len(members)+1)
+		vals = append(vals, groupID)
+
+		for _, m := range members {
+			vals = append(vals, m)
+		}
+
+		pipe := s.r.TxPipeline()
+		pipe.Del(ctx, key)
+		pipe.SAdd(ctx, key, vals...)
+		pipe.Expire(ctx, key, groupMembersTTL)
+
+		if _, err := pipe.Exec(ctx); err != nil {
+			return false, fmt.Errorf("failed to cache group %s members: %w", groupID, err)
+		}
+	}
+
+	ok, err := s.r.SIsMember(ctx, key, userID).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check group %s membership: %w", groupID, err)
+	}
+
+	return ok, nil
+}
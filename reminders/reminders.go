@@ -0,0 +1,299 @@
+// Package reminders stores the reminders users ask the bot for, and delivers
+// them when they're due. Each reminder is published to the workqueue as a
+// scheduled job for when it's due, so it survives restarts, and is kept in
+// Redis so it can be listed or canceled until then.
+package reminders
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/internal/keyring"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+)
+
+// JobName is the name of the scheduled job reminders are published as. The
+// consumer's scheduled job handler should run Store.Deliver for it.
+const JobName = "reminder"
+
+const (
+	redisSeqKey       = "reminders:seq"
+	redisByIDPrefix   = "reminders:by_id:"
+	redisByUserPrefix = "reminders:by_user:"
+
+	// keepAfterDue is how long a reminder is kept after it was due, in case
+	// the consumers were down when it was
+	keepAfterDue = 24 * time.Hour
+)
+
+// ErrNotFound is returned by Store.Cancel when the user doesn't have a
+// reminder with the ID.
+var ErrNotFound = errors.New("reminder not found")
+
+// Reminder is a reminder a user asked for.
+type Reminder struct {
+	ID string `json:"id"`
+
+	// UserID is the user who asked for the reminder.
+	UserID string `json:"user_id"`
+
+	// ChannelID is where the reminder is posted. It's the user's ID for
+	// reminders sent to them as a DM.
+	ChannelID string `json:"channel_id"`
+
+	// Text is what to remind them of.
+	Text string `json:"text"`
+
+	// At is when the reminder is due.
+	At time.Time `json:"at"`
+}
+
+// DM returns whether the reminder is sent to the user as a DM, instead of to a
+// channel.
+func (r Reminder) DM() bool { return r.ChannelID == r.UserID }
+
+// message returns the text of the reminder when it's posted.
+func (r Reminder) message() string {
+	if r.DM() {
+		return fmt.Sprintf(":alarm_clock: You asked me to remind you: %s", r.Text)
+	}
+
+	return fmt.Sprintf(":alarm_clock: <@%s> asked me to remind this channel: %s", r.UserID, r.Text)
+}
+
+type jobPayload struct {
+	ID string `json:"id"`
+}
+
+// Store is the Redis-backed reminders store.
+type Store struct {
+	r  redis.UniversalClient
+	q  workqueue.Publisher
+	kr *keyring.Keyring
+}
+
+// New returns a *Store, which publishes reminders to q. Since reminders are
+// what members wrote, they're encrypted with kr if it isn't nil. Reminders
+// stored before they were encrypted can still be read.
+func New(rc redis.UniversalClient, q workqueue.Publisher, kr *keyring.Keyring) *Store {
+	return &Store{
+		r:  rc,
+		q:  q,
+		kr: kr,
+	}
+}
+
+func byID(id string) string       { return redisByIDPrefix + id }
+func byUser(userID string) string { return redisByUserPrefix + userID }
+
+// Add stores the reminder, and schedules it to be delivered. The reminder's ID
+// is set by Add, and it's returned with it.
+func (s *Store) Add(ctx context.Context, r Reminder) (Reminder, error) {
+	seq, err := s.r.Incr(ctx, redisSeqKey).Result()
+	if err != nil {
+		return Reminder{}, fmt.Errorf("failed to get reminder ID: %w", err)
+	}
+
+	r.ID = strconv.FormatInt(seq, 10)
+
+	data, err := s.marshal(r)
+	if err != nil {
+		return Reminder{}, err
+	}
+
+	pipe := s.r.TxPipeline()
+	pipe.Set(ctx, byID(r.ID), data, time.Until(r.At)+keepAfterDue)
+	pipe.ZAdd(ctx, byUser(r.UserID), &redis.Z{Score: float64(r.At.Unix()), Member: r.ID})
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return Reminder{}, fmt.Errorf("failed to store reminder %s: %w", r.ID, err)
+	}
+
+	payload, err := json.Marshal(jobPayload{ID: r.ID})
+	if err != nil {
+		return Reminder{}, fmt.Errorf("failed to marshal reminder %s payload: %w", r.ID, err)
+	}
+
+	job, err := json.Marshal(workqueue.ScheduledJobEvent{
+		Name:          JobName,
+		ScheduledTime: r.At.Unix(),
+		Payload:       payload,
+	})
+	if err != nil {
+		return Reminder{}, fmt.Errorf("failed to marshal reminder %s job: %w", r.ID, err)
+	}
+
+	id := JobName + ":" + r.ID
+
+	if err := s.q.PublishAt(ctx, workqueue.ScheduledJob, r.At, workqueue.PriorityNormal, r.At.Unix(), id, id, job); err != nil {
+		_ = s.remove(ctx, r)
+		return Reminder{}, fmt.Errorf("failed to schedule reminder %s: %w", r.ID, err)
+	}
+
+	return r, nil
+}
+
+// Get returns the reminder with the ID. If it doesn't exist, ok is false and
+// err is nil.
+func (s *Store) Get(ctx context.Context, id string) (r Reminder, ok bool, err error) {
+	data, err := s.r.Get(ctx, byID(id)).Bytes()
+	if err != nil {
+		if err == redis.Nil {
+			return Reminder{}, false, nil
+		}
+
+		return Reminder{}, false, fmt.Errorf("failed to get reminder %s: %w", id, err)
+	}
+
+	r, stale, err := s.unmarshal(id, data)
+	if err != nil {
+		return Reminder{}, false, err
+	}
+
+	// it's tried again the next time if this fails, and isn't stored again if
+	// it was removed meanwhile
+	if stale {
+		if data, err := s.marshal(r); err == nil {
+			_ = s.r.SetXX(ctx, byID(id), data, redis.KeepTTL).Err()
+		}
+	}
+
+	return r, true, nil
+}
+
+// marshal returns the reminder as it's stored.
+func (s *Store) marshal(r Reminder) (string, error) {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal reminder %s: %w", r.ID, err)
+	}
+
+	if s.kr == nil {
+		return string(data), nil
+	}
+
+	// the key is authenticated along with it, so one reminder can't be passed
+	// off as another
+	sealed, err := s.kr.Seal(data, []byte(byID(r.ID)))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt reminder %s: %w", r.ID, err)
+	}
+
+	return sealed, nil
+}
+
+// unmarshal returns the reminder stored as data, and whether it should be
+// stored again, because it isn't encrypted with the current key.
+func (s *Store) unmarshal(id string, data []byte) (r Reminder, stale bool, err error) {
+	// stored before reminders were encrypted
+	if bytes.HasPrefix(data, []byte("{")) {
+		stale = s.kr != nil
+	} else {
+		if s.kr == nil {
+			return Reminder{}, false, fmt.Errorf("reminder %s is encrypted, and there's no key to decrypt it with", id)
+		}
+
+		if data, stale, err = s.kr.Open(string(data), []byte(byID(id))); err != nil {
+			return Reminder{}, false, fmt.Errorf("failed to decrypt reminder %s: %w", id, err)
+		}
+	}
+
+	if err := json.Unmarshal(data, &r); err != nil {
+		return Reminder{}, false, fmt.Errorf("failed to unmarshal reminder %s: %w", id, err)
+	}
+
+	return r, stale, nil
+}
+
+// List returns the user's pending reminders, soonest first.
+func (s *Store) List(ctx context.Context, userID string) ([]Reminder, error) {
+	ids, err := s.r.ZRange(ctx, byUser(userID), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user %s reminders: %w", userID, err)
+	}
+
+	list := make([]Reminder, 0, len(ids))
+
+	for _, id := range ids {
+		r, ok, err := s.Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+
+		// it expired without being delivered
+		if !ok {
+			_ = s.r.ZRem(ctx, byUser(userID), id).Err()
+			continue
+		}
+
+		list = append(list, r)
+	}
+
+	return list, nil
+}
+
+// Cancel removes the user's reminder, so it isn't delivered.
+func (s *Store) Cancel(ctx context.Context, userID, id string) error {
+	r, ok, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if !ok || r.UserID != userID {
+		return ErrNotFound
+	}
+
+	return s.remove(ctx, r)
+}
+
+func (s *Store) remove(ctx context.Context, r Reminder) error {
+	pipe := s.r.TxPipeline()
+	pipe.Del(ctx, byID(r.ID))
+	pipe.ZRem(ctx, byUser(r.UserID), r.ID)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to remove reminder %s: %w", r.ID, err)
+	}
+
+	return nil
+}
+
+// Deliver posts the reminder for the scheduled job, unless it was canceled.
+// It satisfies the same signature as a workqueue.ScheduledJobHandler.
+func (s *Store) Deliver(ctx workqueue.Context, sj *workqueue.ScheduledJobEvent) (bool, bool, error) {
+	var p jobPayload
+
+	if err := json.Unmarshal(sj.Payload, &p); err != nil {
+		return false, false, fmt.Errorf("failed to unmarshal reminder payload: %w", err)
+	}
+
+	r, ok, err := s.Get(ctx, p.ID)
+	if err != nil {
+		return true, false, err
+	}
+
+	if !ok {
+		return false, true, fmt.Errorf("reminder %s was canceled", p.ID)
+	}
+
+	_, _, err = ctx.Slack().PostMessageContext(ctx, r.ChannelID, slack.MsgOptionText(r.message(), false))
+	if err != nil {
+		return true, false, fmt.Errorf("failed to post reminder %s: %w", r.ID, err)
+	}
+
+	if err := s.remove(ctx, r); err != nil {
+		ctx.Logger().Error().
+			Err(err).
+			Str("reminder_id", r.ID).
+			Msg("failed to remove delivered reminder")
+	}
+
+	return false, false, nil
+}
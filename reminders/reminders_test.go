@@ -0,0 +1,85 @@
+package reminders
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gobridge/gopherbot/internal/keyring"
+)
+
+func TestStore_marshal(t *testing.T) {
+	oldKey, err := keyring.New(bytes.Repeat([]byte{1}, keyring.KeySize))
+	if err != nil {
+		t.Fatalf("keyring.New() unexpected error: %v", err)
+	}
+
+	kr, err := keyring.New(bytes.Repeat([]byte{2}, keyring.KeySize), bytes.Repeat([]byte{1}, keyring.KeySize))
+	if err != nil {
+		t.Fatalf("keyring.New() unexpected error: %v", err)
+	}
+
+	r := Reminder{ID: "42", UserID: "U123", ChannelID: "U123", Text: "call the vet", At: time.Unix(1600000000, 0).UTC()}
+
+	plain, err := (&Store{}).marshal(r)
+	if err != nil {
+		t.Fatalf("marshal() unexpected error: %v", err)
+	}
+
+	old, err := (&Store{kr: oldKey}).marshal(r)
+	if err != nil {
+		t.Fatalf("marshal() unexpected error: %v", err)
+	}
+
+	current, err := (&Store{kr: kr}).marshal(r)
+	if err != nil {
+		t.Fatalf("marshal() unexpected error: %v", err)
+	}
+
+	for _, data := range []string{old, current} {
+		if strings.Contains(data, r.Text) {
+			t.Fatalf("marshal() = %q, which isn't encrypted", data)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		s     *Store
+		data  string
+		stale bool
+		err   bool
+	}{
+		{name: "plain", s: &Store{}, data: plain},
+		{name: "plain_encrypting", s: &Store{kr: kr}, data: plain, stale: true},
+		{name: "old_key", s: &Store{kr: kr}, data: old, stale: true},
+		{name: "current_key", s: &Store{kr: kr}, data: current},
+		{name: "no_key", s: &Store{}, data: current, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stale, err := tt.s.unmarshal(r.ID, []byte(tt.data))
+			if tt.err {
+				if err == nil {
+					t.Fatal("unmarshal() expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unmarshal() unexpected error: %v", err)
+			}
+
+			if got != r || stale != tt.stale {
+				t.Fatalf("unmarshal() = %+v, %t; want %+v, %t", got, stale, r, tt.stale)
+			}
+		})
+	}
+
+	// another reminder's ciphertext
+	if _, _, err := (&Store{kr: kr}).unmarshal("43", []byte(current)); err == nil {
+		t.Fatal("unmarshal() of another reminder expected an error")
+	}
+}
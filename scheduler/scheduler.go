@@ -0,0 +1,286 @@
+// Package scheduler runs cron-style jobs, which are stored in Redis so they can
+// be changed without a deploy. When a job fires the scheduler publishes a
+// workqueue.ScheduledJob event, which the consumers handle like any other
+// event. Only one scheduler runs the jobs at a time, elected with the leader
+// package, so it's safe to run more than one.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/internal/leader"
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/robfig/cron/v3"
+	"github.com/rs/zerolog"
+)
+
+const (
+	redisJobsKey    = "scheduler:jobs"
+	redisLastRunKey = "scheduler:last_run"
+	redisLockKey    = "scheduler:lock"
+
+	// lockTTL is how long the lock is held without being renewed, so another
+	// scheduler takes over if the runner dies
+	lockTTL = 30 * time.Second
+
+	pollInterval = 5 * time.Second
+)
+
+// Job is a scheduled job definition.
+type Job struct {
+	// Name is the unique name of the job.
+	Name string `json:"name"`
+
+	// Spec is when the job fires, as a standard 5 field cron expression in
+	// UTC, or a descriptor like @hourly or @every 1h30m.
+	Spec string `json:"spec"`
+
+	// Payload is included in the event when the job fires.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// Validate returns an error if the job can't be scheduled.
+func (j Job) Validate() error {
+	if len(j.Name) == 0 {
+		return errors.New("job name must not be empty")
+	}
+
+	if _, err := cron.ParseStandard(j.Spec); err != nil {
+		return fmt.Errorf("invalid spec %q: %w", j.Spec, err)
+	}
+
+	if len(j.Payload) > 0 && !json.Valid(j.Payload) {
+		return errors.New("payload is not valid JSON")
+	}
+
+	return nil
+}
+
+// Scheduler runs the jobs.
+type Scheduler struct {
+	r  redis.UniversalClient
+	q  workqueue.Publisher
+	l  zerolog.Logger
+	id string
+}
+
+// New returns a *Scheduler. The id uniquely identifies this scheduler, and
+// is used to tell who is the leader.
+func New(rc redis.UniversalClient, q workqueue.Publisher, logger zerolog.Logger, id string) *Scheduler {
+	return &Scheduler{
+		r:  rc,
+		q:  q,
+		l:  logger,
+		id: id,
+	}
+}
+
+// Put adds the job, or replaces the one with the same name. A new job first
+// fires at the next time matching its Spec.
+func (s *Scheduler) Put(ctx context.Context, j Job) error {
+	if err := j.Validate(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job %s: %w", j.Name, err)
+	}
+
+	if err := s.r.HSet(ctx, redisJobsKey, j.Name, data).Err(); err != nil {
+		return fmt.Errorf("failed to store job %s: %w", j.Name, err)
+	}
+
+	return nil
+}
+
+// Delete removes the job.
+func (s *Scheduler) Delete(ctx context.Context, name string) error {
+	pipe := s.r.TxPipeline()
+	pipe.HDel(ctx, redisJobsKey, name)
+	pipe.HDel(ctx, redisLastRunKey, name)
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to delete job %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// Jobs returns all the jobs, sorted by name.
+func (s *Scheduler) Jobs(ctx context.Context) ([]Job, error) {
+	all, err := s.r.HGetAll(ctx, redisJobsKey).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get jobs: %w", err)
+	}
+
+	jobs := make([]Job, 0, len(all))
+
+	for name, data := range all {
+		var j Job
+
+		if err := json.Unmarshal([]byte(data), &j); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal job %s: %w", name, err)
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	sort.Slice(jobs, func(i, k int) bool { return jobs[i].Name < jobs[k].Name })
+
+	return jobs, nil
+}
+
+// Run runs the jobs while this scheduler is the elected leader, until ctx is
+// canceled. The returned channel is closed once it has stopped.
+func (s *Scheduler) Run(ctx context.Context) (<-chan struct{}, error) {
+	e, err := leader.New(leader.Config{
+		RedisClient: s.r,
+		Logger:      s.l,
+		Key:         redisLockKey,
+		ID:          s.id,
+		TTL:         lockTTL,
+		OnElected:   s.runJobs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build leader elector: %w", err)
+	}
+
+	s.l.Info().Msg("starting scheduler")
+
+	return e.Run(ctx), nil
+}
+
+// runJobs runs the due jobs every pollInterval, until ctx is canceled.
+func (s *Scheduler) runJobs(ctx context.Context) {
+	t := time.NewTicker(pollInterval)
+	defer t.Stop()
+
+	for {
+		s.runDue(ctx, time.Now())
+
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runDue publishes an event for each job that's due at now.
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) {
+	jobs, err := s.Jobs(ctx)
+	if err != nil {
+		s.l.Error().
+			Err(err).
+			Msg("failed to get scheduled jobs")
+
+		return
+	}
+
+	last, err := s.r.HGetAll(ctx, redisLastRunKey).Result()
+	if err != nil {
+		s.l.Error().
+			Err(err).
+			Msg("failed to get scheduled jobs' last run times")
+
+		return
+	}
+
+	for _, j := range jobs {
+		logger := s.l.With().Str("job_name", j.Name).Logger()
+
+		due, ok, err := next(j, last[j.Name])
+		if err != nil {
+			logger.Error().
+				Err(err).
+				Msg("failed to schedule job")
+
+			continue
+		}
+
+		// jobs that have never run start from now, instead of firing straight
+		// away
+		if !ok {
+			s.setLastRun(ctx, logger, j.Name, now)
+			continue
+		}
+
+		if due.After(now) {
+			continue
+		}
+
+		if err := s.fire(ctx, j, due); err != nil {
+			logger.Error().
+				Err(err).
+				Time("scheduled_time", due).
+				Msg("failed to publish scheduled job")
+
+			continue
+		}
+
+		// runs that were missed, like while no scheduler was running, are only
+		// fired once
+		s.setLastRun(ctx, logger, j.Name, now)
+
+		logger.Info().
+			Time("scheduled_time", due).
+			Msg("published scheduled job")
+	}
+}
+
+// next returns when the job is next due, after it last ran. If the job has
+// never run, ok is false.
+func next(j Job, lastRun string) (due time.Time, ok bool, err error) {
+	sched, err := cron.ParseStandard(j.Spec)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid spec %q: %w", j.Spec, err)
+	}
+
+	if len(lastRun) == 0 {
+		return time.Time{}, false, nil
+	}
+
+	ms, err := strconv.ParseInt(lastRun, 10, 64)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to parse last run %q: %w", lastRun, err)
+	}
+
+	last := time.Unix(0, ms*int64(time.Millisecond)).UTC()
+
+	return sched.Next(last), true, nil
+}
+
+func (s *Scheduler) setLastRun(ctx context.Context, logger zerolog.Logger, name string, t time.Time) {
+	ms := t.UnixNano() / int64(time.Millisecond)
+
+	if err := s.r.HSet(ctx, redisLastRunKey, name, strconv.FormatInt(ms, 10)).Err(); err != nil {
+		logger.Error().
+			Err(err).
+			Msg("failed to store job's last run time")
+	}
+}
+
+func (s *Scheduler) fire(ctx context.Context, j Job, due time.Time) error {
+	data, err := json.Marshal(workqueue.ScheduledJobEvent{
+		Name:          j.Name,
+		ScheduledTime: due.Unix(),
+		Payload:       j.Payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	// the ID is the same for a given job and time, so it's easy to spot
+	// duplicates
+	id := j.Name + ":" + strconv.FormatInt(due.Unix(), 10)
+
+	return s.q.Publish(ctx, workqueue.ScheduledJob, workqueue.PriorityNormal, due.Unix(), id, id, data)
+}
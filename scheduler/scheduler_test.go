@@ -0,0 +1,84 @@
+package scheduler
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func Test_next(t *testing.T) {
+	now := time.Date(2020, time.June, 1, 12, 30, 0, 0, time.UTC)
+	ms := func(t time.Time) string { return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10) }
+
+	tests := []struct {
+		name    string
+		spec    string
+		lastRun string
+		want    time.Time
+		wantOK  bool
+		wantErr bool
+	}{
+		{
+			name: "never_run",
+			spec: "@hourly",
+		},
+		{
+			name:    "due",
+			spec:    "0 * * * *",
+			lastRun: ms(now.Add(-45 * time.Minute)),
+			want:    time.Date(2020, time.June, 1, 12, 0, 0, 0, time.UTC),
+			wantOK:  true,
+		},
+		{
+			name:    "not_due",
+			spec:    "0 9 * * 1",
+			lastRun: ms(now.Add(-time.Minute)),
+			want:    time.Date(2020, time.June, 8, 9, 0, 0, 0, time.UTC),
+			wantOK:  true,
+		},
+		{
+			name:    "every",
+			spec:    "@every 10m",
+			lastRun: ms(now),
+			want:    now.Add(10 * time.Minute),
+			wantOK:  true,
+		},
+		{
+			name:    "bad_spec",
+			spec:    "every tuesday",
+			wantErr: true,
+		},
+		{
+			name:    "bad_last_run",
+			spec:    "@daily",
+			lastRun: "yesterday",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok, err := next(Job{Name: tt.name, Spec: tt.spec}, tt.lastRun)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("next() expected error, got nil")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("next() unexpected error: %v", err)
+			}
+
+			if ok != tt.wantOK {
+				t.Fatalf("next() ok = %t, want %t", ok, tt.wantOK)
+			}
+
+			if !got.Equal(tt.want) {
+				t.Fatalf("next() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
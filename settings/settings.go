@@ -0,0 +1,214 @@
+// Package settings stores the per-channel settings, like whether new members
+// are welcomed, as a JSON document for each channel in Redis. Handlers read
+// them through the workqueue Context, and admins change them with the !config
+// command.
+package settings
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The moderation levels, from least to most strict.
+const (
+	ModerationOff    = "off"
+	ModerationNormal = "normal"
+	ModerationStrict = "strict"
+)
+
+// Channel is the settings document for a channel.
+type Channel struct {
+	// WelcomeEnabled is whether users joining the channel are sent its
+	// welcome message, if it has one.
+	WelcomeEnabled bool `json:"welcome_enabled"`
+
+	// ModerationLevel is how strictly messages in the channel are moderated.
+	// It's one of the Moderation constants.
+	ModerationLevel string `json:"moderation_level"`
+}
+
+// Default is the settings of a channel that hasn't changed any of them.
+var Default = Channel{
+	WelcomeEnabled:  true,
+	ModerationLevel: ModerationNormal,
+}
+
+var (
+	// ErrUnknownSetting is returned when changing a setting that doesn't
+	// exist.
+	ErrUnknownSetting = errors.New("unknown setting")
+
+	// ErrInvalidValue is returned when changing a setting to a value it
+	// doesn't accept.
+	ErrInvalidValue = errors.New("invalid value")
+)
+
+// field is a setting that can be changed by name
+type field struct {
+	desc string
+	get  func(c Channel) string
+	set  func(c *Channel, value string) error
+}
+
+var fields = map[string]field{
+	"welcome_enabled": {
+		desc: "whether users joining the channel get its welcome message (`true` or `false`)",
+		get:  func(c Channel) string { return strconv.FormatBool(c.WelcomeEnabled) },
+		set: func(c *Channel, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%w: %q is not true or false", ErrInvalidValue, value)
+			}
+
+			c.WelcomeEnabled = b
+
+			return nil
+		},
+	},
+	"moderation_level": {
+		desc: "how strictly messages are moderated (`off`, `normal`, or `strict`)",
+		get:  func(c Channel) string { return c.ModerationLevel },
+		set: func(c *Channel, value string) error {
+			switch value {
+			case ModerationOff, ModerationNormal, ModerationStrict:
+				c.ModerationLevel = value
+				return nil
+			default:
+				return fmt.Errorf("%w: %q is not off, normal, or strict", ErrInvalidValue, value)
+			}
+		},
+	},
+}
+
+// Keys returns the names of the settings, sorted.
+func Keys() []string {
+	keys := make([]string, 0, len(fields))
+
+	for k := range fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Describe returns the description of the setting, and whether it exists.
+func Describe(key string) (string, bool) {
+	f, ok := fields[key]
+	return f.desc, ok
+}
+
+// Get returns the value of the setting in c, formatted as a string.
+func (c Channel) Get(key string) (string, error) {
+	f, ok := fields[key]
+	if !ok {
+		return "", fmt.Errorf("%w %q", ErrUnknownSetting, key)
+	}
+
+	return f.get(c), nil
+}
+
+// Set parses value and sets the setting in c to it.
+func (c *Channel) Set(key, value string) error {
+	f, ok := fields[key]
+	if !ok {
+		return fmt.Errorf("%w %q", ErrUnknownSetting, key)
+	}
+
+	return f.set(c, value)
+}
+
+const redisKeyPrefix = "settings:channel:"
+
+func key(channelID string) string { return redisKeyPrefix + channelID }
+
+// parse decodes the document on top of the defaults, so settings added after
+// it was stored have their default value.
+func parse(data []byte) (Channel, error) {
+	c := Default
+
+	if len(data) == 0 {
+		return c, nil
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil {
+		return Channel{}, err
+	}
+
+	return c, nil
+}
+
+// Store is the Redis-backed settings store. It satisfies the workqueue's
+// SettingsSvc interface.
+type Store struct {
+	r redis.UniversalClient
+}
+
+// New returns a *Store.
+func New(rc redis.UniversalClient) *Store {
+	return &Store{r: rc}
+}
+
+// Channel returns the settings for the channel. Channels without any stored
+// settings get the Default ones.
+func (s *Store) Channel(ctx context.Context, channelID string) (Channel, error) {
+	data, err := s.r.Get(ctx, key(channelID)).Bytes()
+	if err != nil && err != redis.Nil {
+		return Channel{}, fmt.Errorf("failed to get channel %s settings: %w", channelID, err)
+	}
+
+	c, err := parse(data)
+	if err != nil {
+		return Channel{}, fmt.Errorf("failed to parse channel %s settings: %w", channelID, err)
+	}
+
+	return c, nil
+}
+
+// Set changes a single setting for the channel, and returns the channel's
+// updated settings. The change is made in a transaction, so concurrent
+// changes to other settings aren't lost.
+func (s *Store) Set(ctx context.Context, channelID, setting, value string) (Channel, error) {
+	k := key(channelID)
+
+	var c Channel
+
+	err := s.r.Watch(ctx, func(tx *redis.Tx) error {
+		data, err := tx.Get(ctx, k).Bytes()
+		if err != nil && err != redis.Nil {
+			return fmt.Errorf("failed to get channel %s settings: %w", channelID, err)
+		}
+
+		if c, err = parse(data); err != nil {
+			return fmt.Errorf("failed to parse channel %s settings: %w", channelID, err)
+		}
+
+		if err := c.Set(setting, value); err != nil {
+			return err
+		}
+
+		doc, err := json.Marshal(c)
+		if err != nil {
+			return fmt.Errorf("failed to marshal channel %s settings: %w", channelID, err)
+		}
+
+		_, err = tx.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, k, doc, 0)
+			return nil
+		})
+
+		return err
+	}, k)
+	if err != nil {
+		return Channel{}, err
+	}
+
+	return c, nil
+}
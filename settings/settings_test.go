@@ -0,0 +1,116 @@
+package settings
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_parse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want Channel
+	}{
+		{
+			name: "empty",
+			want: Default,
+		},
+		{
+			name: "partial",
+			data: `{"moderation_level":"strict"}`,
+			want: Channel{WelcomeEnabled: true, ModerationLevel: ModerationStrict},
+		},
+		{
+			name: "all",
+			data: `{"welcome_enabled":false,"moderation_level":"off"}`,
+			want: Channel{WelcomeEnabled: false, ModerationLevel: ModerationOff},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("parse() unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChannel_Set(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		value   string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "welcome",
+			key:   "welcome_enabled",
+			value: "false",
+			want:  "false",
+		},
+		{
+			name:  "moderation",
+			key:   "moderation_level",
+			value: "strict",
+			want:  "strict",
+		},
+		{
+			name:    "bad_bool",
+			key:     "welcome_enabled",
+			value:   "nope",
+			wantErr: ErrInvalidValue,
+		},
+		{
+			name:    "bad_level",
+			key:     "moderation_level",
+			value:   "max",
+			wantErr: ErrInvalidValue,
+		},
+		{
+			name:    "unknown",
+			key:     "colour",
+			value:   "blue",
+			wantErr: ErrUnknownSetting,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default
+
+			err := c.Set(tt.key, tt.value)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Set() error = %v, want %v", err, tt.wantErr)
+				}
+
+				if c != Default {
+					t.Fatalf("Set() changed settings on error: %+v", c)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Set() unexpected error: %v", err)
+			}
+
+			got, err := c.Get(tt.key)
+			if err != nil {
+				t.Fatalf("Get() unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("Get() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
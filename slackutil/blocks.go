@@ -0,0 +1,217 @@
+// Package slackutil provides helpers for building Slack messages.
+package slackutil
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/slack-go/slack"
+)
+
+// The Slack Block Kit limits the Builder validates against. Slack rejects the
+// whole message if any of them are exceeded.
+const (
+	MaxBlocks          = 50
+	MaxSectionText     = 3000
+	MaxSectionFields   = 10
+	MaxFieldText       = 2000
+	MaxContextElements = 10
+	MaxActionElements  = 25
+	MaxButtonText      = 75
+	MaxButtonValue     = 2000
+	MaxActionID        = 255
+	MaxURL             = 3000
+)
+
+// Button is a button in the Builder's Buttons block.
+type Button struct {
+	// ActionID identifies the button in the interaction payload sent when
+	// it's clicked. Required unless URL is set.
+	ActionID string
+
+	// Text is the button's label.
+	Text string
+
+	// Value is sent in the interaction payload when the button is clicked.
+	Value string
+
+	// URL makes the button open a link. Optional.
+	URL string
+
+	// Style is either slack.StylePrimary, slack.StyleDanger, or empty for the
+	// default style.
+	Style slack.Style
+}
+
+// Builder builds Block Kit messages. Its methods return the *Builder so calls
+// can be chained, and the first error from any of them is returned by Build.
+type Builder struct {
+	blocks []slack.Block
+	err    error
+}
+
+// NewBuilder returns an empty *Builder.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+func (b *Builder) add(blk slack.Block) *Builder {
+	if b.err != nil {
+		return b
+	}
+
+	if len(b.blocks) == MaxBlocks {
+		b.err = fmt.Errorf("message cannot have more than %d blocks", MaxBlocks)
+		return b
+	}
+
+	b.blocks = append(b.blocks, blk)
+
+	return b
+}
+
+func (b *Builder) fail(format string, args ...interface{}) *Builder {
+	if b.err == nil {
+		b.err = fmt.Errorf("block %d: %s", len(b.blocks), fmt.Sprintf(format, args...))
+	}
+
+	return b
+}
+
+// Section adds a section block with the markdown text.
+func (b *Builder) Section(text string) *Builder {
+	if n := utf8.RuneCountInString(text); n == 0 || n > MaxSectionText {
+		return b.fail("section text must be 1-%d characters, got %d", MaxSectionText, n)
+	}
+
+	return b.add(slack.NewSectionBlock(markdown(text), nil, nil))
+}
+
+// Fields adds a section block with the markdown fields, which Slack shows in
+// two columns.
+func (b *Builder) Fields(fields ...string) *Builder {
+	if len(fields) == 0 || len(fields) > MaxSectionFields {
+		return b.fail("section must have 1-%d fields, got %d", MaxSectionFields, len(fields))
+	}
+
+	tbos := make([]*slack.TextBlockObject, 0, len(fields))
+
+	for i, f := range fields {
+		if n := utf8.RuneCountInString(f); n == 0 || n > MaxFieldText {
+			return b.fail("field %d must be 1-%d characters, got %d", i, MaxFieldText, n)
+		}
+
+		tbos = append(tbos, markdown(f))
+	}
+
+	return b.add(slack.NewSectionBlock(nil, tbos, nil))
+}
+
+// Context adds a context block with the markdown elements, which Slack shows
+// in small text.
+func (b *Builder) Context(elements ...string) *Builder {
+	if len(elements) == 0 || len(elements) > MaxContextElements {
+		return b.fail("context must have 1-%d elements, got %d", MaxContextElements, len(elements))
+	}
+
+	mes := make([]slack.MixedElement, 0, len(elements))
+
+	for i, e := range elements {
+		if len(e) == 0 {
+			return b.fail("context element %d is empty", i)
+		}
+
+		mes = append(mes, markdown(e))
+	}
+
+	return b.add(slack.NewContextBlock("", mes...))
+}
+
+// Divider adds a divider block.
+func (b *Builder) Divider() *Builder {
+	return b.add(slack.NewDividerBlock())
+}
+
+// Buttons adds an actions block with the buttons.
+func (b *Builder) Buttons(buttons ...Button) *Builder {
+	if len(buttons) == 0 || len(buttons) > MaxActionElements {
+		return b.fail("actions must have 1-%d buttons, got %d", MaxActionElements, len(buttons))
+	}
+
+	elems := make([]slack.BlockElement, 0, len(buttons))
+
+	for i, btn := range buttons {
+		if err := btn.validate(); err != nil {
+			return b.fail("button %d %s", i, err)
+		}
+
+		be := slack.NewButtonBlockElement(btn.ActionID, btn.Value, slack.NewTextBlockObject(slack.PlainTextType, btn.Text, true, false))
+		be.URL = btn.URL
+
+		if len(btn.Style) > 0 {
+			be.WithStyle(btn.Style)
+		}
+
+		elems = append(elems, be)
+	}
+
+	return b.add(slack.NewActionBlock("", elems...))
+}
+
+func (btn Button) validate() error {
+	if n := utf8.RuneCountInString(btn.Text); n == 0 || n > MaxButtonText {
+		return fmt.Errorf("text must be 1-%d characters, got %d", MaxButtonText, n)
+	}
+
+	if len(btn.ActionID) == 0 && len(btn.URL) == 0 {
+		return fmt.Errorf("must have an action ID or URL")
+	}
+
+	if n := utf8.RuneCountInString(btn.ActionID); n > MaxActionID {
+		return fmt.Errorf("action ID must be at most %d characters, got %d", MaxActionID, n)
+	}
+
+	if n := utf8.RuneCountInString(btn.Value); n > MaxButtonValue {
+		return fmt.Errorf("value must be at most %d characters, got %d", MaxButtonValue, n)
+	}
+
+	if n := utf8.RuneCountInString(btn.URL); n > MaxURL {
+		return fmt.Errorf("URL must be at most %d characters, got %d", MaxURL, n)
+	}
+
+	switch btn.Style {
+	case "", slack.StylePrimary, slack.StyleDanger:
+	default:
+		return fmt.Errorf("has unknown style %q", btn.Style)
+	}
+
+	return nil
+}
+
+// Build returns the blocks, or the first error from building them.
+func (b *Builder) Build() ([]slack.Block, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if len(b.blocks) == 0 {
+		return nil, fmt.Errorf("message has no blocks")
+	}
+
+	return b.blocks, nil
+}
+
+// MsgOption returns the blocks as a slack.MsgOption, for posting them with
+// the Slack client.
+func (b *Builder) MsgOption() (slack.MsgOption, error) {
+	blocks, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	return slack.MsgOptionBlocks(blocks...), nil
+}
+
+func markdown(s string) *slack.TextBlockObject {
+	return slack.NewTextBlockObject(slack.MarkdownType, s, false, false)
+}
@@ -0,0 +1,90 @@
+package slackutil
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(*Builder) *Builder
+		want  string
+		err   string
+	}{
+		{
+			name: "message",
+			build: func(b *Builder) *Builder {
+				return b.Section("*hello*").
+					Divider().
+					Context("posted by U123").
+					Buttons(Button{ActionID: "ack", Text: "Ack", Value: "1", Style: slack.StylePrimary})
+			},
+			want: `[{"type":"section","text":{"type":"mrkdwn","text":"*hello*"}},` +
+				`{"type":"divider"},` +
+				`{"type":"context","elements":[{"type":"mrkdwn","text":"posted by U123"}]},` +
+				`{"type":"actions","elements":[{"type":"button","text":{"type":"plain_text","text":"Ack","emoji":true},"action_id":"ack","value":"1","style":"primary"}]}]`,
+		},
+		{
+			name:  "empty",
+			build: func(b *Builder) *Builder { return b },
+			err:   "message has no blocks",
+		},
+		{
+			name:  "section_too_long",
+			build: func(b *Builder) *Builder { return b.Divider().Section(strings.Repeat("a", MaxSectionText+1)) },
+			err:   "block 1: section text must be 1-3000 characters, got 3001",
+		},
+		{
+			name: "too_many_blocks",
+			build: func(b *Builder) *Builder {
+				for i := 0; i <= MaxBlocks; i++ {
+					b.Divider()
+				}
+
+				return b
+			},
+			err: "message cannot have more than 50 blocks",
+		},
+		{
+			name:  "button_without_action",
+			build: func(b *Builder) *Builder { return b.Buttons(Button{Text: "Ack"}) },
+			err:   "block 0: button 0 must have an action ID or URL",
+		},
+		{
+			name:  "first_error_wins",
+			build: func(b *Builder) *Builder { return b.Fields().Context() },
+			err:   "block 0: section must have 1-10 fields, got 0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks, err := tt.build(NewBuilder()).Build()
+
+			if len(tt.err) > 0 {
+				if err == nil || err.Error() != tt.err {
+					t.Fatalf("Build() error = %v, want %s", err, tt.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Build() unexpected error: %v", err)
+			}
+
+			got, err := json.Marshal(blocks)
+			if err != nil {
+				t.Fatalf("failed to marshal blocks: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Fatalf("Build() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
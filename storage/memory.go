@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Memory is a Store that keeps everything in memory, for tests and running
+// locally without Redis. Its namespaces share the same memory.
+type Memory struct {
+	d      *memoryData
+	prefix string
+	err    error
+}
+
+type memoryData struct {
+	mu     sync.Mutex
+	values map[string]memoryValue
+}
+
+type memoryValue struct {
+	value   string
+	expires time.Time
+}
+
+func (v memoryValue) expired(now time.Time) bool {
+	return !v.expires.IsZero() && !now.Before(v.expires)
+}
+
+// NewMemory returns an empty Memory Store for the root namespace.
+func NewMemory() *Memory {
+	return &Memory{
+		d:      &memoryData{values: make(map[string]memoryValue)},
+		prefix: redisKeyPrefix,
+	}
+}
+
+// compile time check: does *Memory satisfy Store?
+var _ Store = (*Memory)(nil)
+
+// Namespace satisfies Store.
+func (s *Memory) Namespace(ns string) Store {
+	n := &Memory{d: s.d}
+	n.prefix, n.err = namespace(s.prefix, s.err, ns)
+
+	return n
+}
+
+// get returns the value of the full key k, dropping it if it expired. It must
+// be called with s.d.mu held.
+func (s *Memory) get(k string) (memoryValue, bool) {
+	v, ok := s.d.values[k]
+	if ok && v.expired(time.Now()) {
+		delete(s.d.values, k)
+		return memoryValue{}, false
+	}
+
+	return v, ok
+}
+
+func expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+
+	return time.Now().Add(ttl)
+}
+
+// Get satisfies Store.
+func (s *Memory) Get(_ context.Context, key string) (string, bool, error) {
+	k, err := prefixKey(s.prefix, s.err, key)
+	if err != nil {
+		return "", false, err
+	}
+
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	v, ok := s.get(k)
+
+	return v.value, ok, nil
+}
+
+// Set satisfies Store.
+func (s *Memory) Set(_ context.Context, key, value string, ttl time.Duration) error {
+	k, err := prefixKey(s.prefix, s.err, key)
+	if err != nil {
+		return err
+	}
+
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	s.d.values[k] = memoryValue{value: value, expires: expiry(ttl)}
+
+	return nil
+}
+
+// Delete satisfies Store.
+func (s *Memory) Delete(_ context.Context, key string) error {
+	k, err := prefixKey(s.prefix, s.err, key)
+	if err != nil {
+		return err
+	}
+
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	delete(s.d.values, k)
+
+	return nil
+}
+
+// Incr satisfies Store.
+func (s *Memory) Incr(_ context.Context, key string, ttl time.Duration) (int64, error) {
+	k, err := prefixKey(s.prefix, s.err, key)
+	if err != nil {
+		return 0, err
+	}
+
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	v, ok := s.get(k)
+	if !ok {
+		v = memoryValue{value: "0", expires: expiry(ttl)}
+	}
+
+	n, err := strconv.ParseInt(v.value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment %s: value is not an integer", k)
+	}
+
+	n++
+	v.value = strconv.FormatInt(n, 10)
+	s.d.values[k] = v
+
+	return n, nil
+}
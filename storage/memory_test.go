@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemory().Namespace("karma")
+
+	if _, ok, err := s.Get(ctx, "U123"); ok || err != nil {
+		t.Fatalf("Get() of missing key = %t, %v; want false, <nil>", ok, err)
+	}
+
+	for want := int64(1); want <= 2; want++ {
+		n, err := s.Incr(ctx, "U123", 0)
+		if err != nil {
+			t.Fatalf("Incr() unexpected error: %v", err)
+		}
+
+		if n != want {
+			t.Fatalf("Incr() = %d, want %d", n, want)
+		}
+	}
+
+	// other namespaces don't see the key
+	if _, ok, _ := s.Namespace("2020").Get(ctx, "U123"); ok {
+		t.Fatal("Get() found the key in a nested namespace")
+	}
+
+	if err := s.Set(ctx, "U456", "x", time.Nanosecond); err != nil {
+		t.Fatalf("Set() unexpected error: %v", err)
+	}
+
+	time.Sleep(time.Millisecond)
+
+	if _, ok, _ := s.Get(ctx, "U456"); ok {
+		t.Fatal("Get() found an expired key")
+	}
+
+	if err := s.Delete(ctx, "U123"); err != nil {
+		t.Fatalf("Delete() unexpected error: %v", err)
+	}
+
+	if _, ok, _ := s.Get(ctx, "U123"); ok {
+		t.Fatal("Get() found a deleted key")
+	}
+
+	if _, err := s.Namespace("a:b").Incr(ctx, "U123", 0); !errors.Is(err, ErrInvalidNamespace) {
+		t.Fatalf("Incr() in invalid namespace error = %v, want %v", err, ErrInvalidNamespace)
+	}
+}
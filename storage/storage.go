@@ -0,0 +1,171 @@
+// Package storage provides the key-value store handlers use to keep state
+// between events, like karma counts or whether a welcome message was sent.
+// Keys are namespaced, so handlers can't clobber each other's state.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Store is a namespaced key-value store. A ttl of 0 means the key doesn't
+// expire.
+type Store interface {
+	// Get returns the value of key. If the key doesn't exist, ok is false and
+	// err is nil.
+	Get(ctx context.Context, key string) (value string, ok bool, err error)
+
+	// Set sets key to value.
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+
+	// Delete removes key. Removing a key that doesn't exist isn't an error.
+	Delete(ctx context.Context, key string) error
+
+	// Incr increments the integer value of key by one, and returns the new
+	// value. Keys that don't exist start at 0. The ttl is only applied when
+	// the key is created, so incrementing it doesn't extend its life.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+
+	// Namespace returns a Store for the namespace ns, within this Store's
+	// namespace.
+	Namespace(ns string) Store
+}
+
+// ErrInvalidNamespace is returned by the methods of a Store that was created
+// with an empty namespace, or one containing a colon.
+var ErrInvalidNamespace = errors.New("namespace must not be empty or contain a colon")
+
+const redisKeyPrefix = "storage:"
+
+// Redis is the Redis-backed Store.
+type Redis struct {
+	r      redis.UniversalClient
+	prefix string
+	err    error
+}
+
+// NewRedis returns a Redis Store for the root namespace.
+func NewRedis(rc redis.UniversalClient) *Redis {
+	return &Redis{
+		r:      rc,
+		prefix: redisKeyPrefix,
+	}
+}
+
+// compile time check: does *Redis satisfy Store?
+var _ Store = (*Redis)(nil)
+
+// Namespace satisfies Store.
+func (s *Redis) Namespace(ns string) Store {
+	n := &Redis{r: s.r}
+	n.prefix, n.err = namespace(s.prefix, s.err, ns)
+
+	return n
+}
+
+func (s *Redis) key(key string) (string, error) {
+	return prefixKey(s.prefix, s.err, key)
+}
+
+// namespace returns the key prefix for the namespace ns within the one with
+// prefix, and the error its methods return, which is err if it's set.
+func namespace(prefix string, err error, ns string) (string, error) {
+	if err == nil && (len(ns) == 0 || strings.Contains(ns, ":")) {
+		err = ErrInvalidNamespace
+	}
+
+	return prefix + ns + ":", err
+}
+
+// prefixKey returns the full key for key within the namespace with prefix,
+// unless the namespace is invalid.
+func prefixKey(prefix string, err error, key string) (string, error) {
+	if err != nil {
+		return "", err
+	}
+
+	if len(key) == 0 {
+		return "", errors.New("key must not be empty")
+	}
+
+	return prefix + key, nil
+}
+
+// Get satisfies Store.
+func (s *Redis) Get(ctx context.Context, key string) (string, bool, error) {
+	k, err := s.key(key)
+	if err != nil {
+		return "", false, err
+	}
+
+	v, err := s.r.Get(ctx, k).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return "", false, nil
+		}
+
+		return "", false, fmt.Errorf("failed to get %s: %w", k, err)
+	}
+
+	return v, true, nil
+}
+
+// Set satisfies Store.
+func (s *Redis) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	k, err := s.key(key)
+	if err != nil {
+		return err
+	}
+
+	if err := s.r.Set(ctx, k, value, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set %s: %w", k, err)
+	}
+
+	return nil
+}
+
+// Delete satisfies Store.
+func (s *Redis) Delete(ctx context.Context, key string) error {
+	k, err := s.key(key)
+	if err != nil {
+		return err
+	}
+
+	if err := s.r.Del(ctx, k).Err(); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", k, err)
+	}
+
+	return nil
+}
+
+// incrExpire increments KEYS[1], setting it to expire in ARGV[1] milliseconds
+// if it was just created. This is done in a script so a key can't be left
+// without its TTL.
+var incrExpire = redis.NewScript(`
+local n = redis.call("INCR", KEYS[1])
+if n == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+
+return n
+`)
+
+// Incr satisfies Store.
+func (s *Redis) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	k, err := s.key(key)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := incrExpire.Run(ctx, s.r, []string{k}, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment %s: %w", k, err)
+	}
+
+	return n, nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRedis_key(t *testing.T) {
+	tests := []struct {
+		name    string
+		store   Store
+		key     string
+		want    string
+		wantErr error
+	}{
+		{
+			name:  "root",
+			store: NewRedis(nil),
+			key:   "foo",
+			want:  "storage:foo",
+		},
+		{
+			name:  "nested",
+			store: NewRedis(nil).Namespace("karma").Namespace("2020"),
+			key:   "U123",
+			want:  "storage:karma:2020:U123",
+		},
+		{
+			name:    "empty_namespace",
+			store:   NewRedis(nil).Namespace(""),
+			key:     "foo",
+			wantErr: ErrInvalidNamespace,
+		},
+		{
+			name:    "colon_namespace",
+			store:   NewRedis(nil).Namespace("a:b").Namespace("c"),
+			key:     "foo",
+			wantErr: ErrInvalidNamespace,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.store.(*Redis).key(tt.key)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("key() error = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("key() unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("key() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
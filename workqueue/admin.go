@@ -0,0 +1,150 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// These are for operators managing the streams and consumer groups, like with
+// cmd/queuectl, and aren't needed to publish or consume events.
+
+// GroupInfo describes a consumer group on a stream.
+type GroupInfo struct {
+	// Name is the consumer group's name.
+	Name string
+
+	// Consumers is how many consumers are in the consumer group.
+	Consumers int64
+
+	// Pending is how many entries were delivered to the consumer group, but
+	// haven't been acknowledged yet.
+	Pending int64
+
+	// Lag is how many entries haven't been delivered to the consumer group
+	// yet. It's -1 if Redis doesn't report it, which is the case before 7.0.
+	Lag int64
+
+	// LastDeliveredID is the ID of the last entry delivered to the consumer
+	// group.
+	LastDeliveredID string
+}
+
+// PendingEntry is a message that was delivered to a consumer group, but hasn't
+// been acknowledged yet.
+type PendingEntry struct {
+	// ID is the message's ID.
+	ID string
+
+	// Consumer is the consumer it's pending for.
+	Consumer string
+
+	// Idle is how long ago it was last delivered.
+	Idle time.Duration
+
+	// Deliveries is how many times it's been delivered.
+	Deliveries int64
+}
+
+// Streams returns the names of all the streams, including ones without
+// registered handlers.
+func (i *I) Streams(ctx context.Context) ([]string, error) {
+	names, err := i.t.listStreams(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list streams: %w", err)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Groups returns the consumer groups on the stream, sorted by name. It returns
+// an error if the stream doesn't exist.
+func (i *I) Groups(ctx context.Context, stream string) ([]GroupInfo, error) {
+	info, err := i.t.info(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	if info == nil {
+		return nil, fmt.Errorf("stream %s doesn't exist", stream)
+	}
+
+	groups := make([]GroupInfo, 0, len(info.groups))
+
+	for name, g := range info.groups {
+		groups = append(groups, GroupInfo{
+			Name:            name,
+			Consumers:       g.consumers,
+			Pending:         g.pending,
+			Lag:             g.lag,
+			LastDeliveredID: g.lastDeliveredID,
+		})
+	}
+
+	sort.Slice(groups, func(a, b int) bool { return groups[a].Name < groups[b].Name })
+
+	return groups, nil
+}
+
+// CreateGroup creates the consumer group on the stream, creating the stream if
+// needed. The group starts after lastID, which can be "$" for only new
+// messages or "0" for all of them. It does nothing if the group already
+// exists.
+func (i *I) CreateGroup(ctx context.Context, stream, group, lastID string) error {
+	if len(group) == 0 {
+		return errors.New("consumer group must not be empty")
+	}
+
+	if err := i.t.createGroup(ctx, stream, group, lastID); err != nil {
+		return fmt.Errorf("failed to create consumer group %s on %s: %w", group, stream, err)
+	}
+
+	return nil
+}
+
+// DeleteGroup deletes the consumer group from the stream, along with its
+// pending messages, and reports whether it existed. Running consumers in the
+// group recreate it, starting with new messages.
+func (i *I) DeleteGroup(ctx context.Context, stream, group string) (bool, error) {
+	ok, err := i.t.deleteGroup(ctx, stream, group)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete consumer group %s on %s: %w", group, stream, err)
+	}
+
+	return ok, nil
+}
+
+// PendingEntries returns up to count of the consumer group's pending messages
+// on the stream, oldest first. If consumer isn't empty, only the ones pending
+// for that consumer are returned.
+func (i *I) PendingEntries(ctx context.Context, stream, group, consumer string, count int64) ([]PendingEntry, error) {
+	entries, err := i.t.pendingEntries(ctx, stream, group, consumer, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending messages of %s on %s: %w", group, stream, err)
+	}
+
+	return entries, nil
+}
+
+// Claim makes the pending messages with the IDs pending for the consumer
+// instead, however long they've been idle, for messages stuck with a consumer
+// that's gone. That doesn't count as a delivery, and since it resets their idle
+// time, they're redelivered once they've been idle for the visibility timeout
+// again, to whichever consumer reclaims them first. It returns the IDs that
+// were pending.
+func (i *I) Claim(ctx context.Context, stream, group, consumer string, ids ...string) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	claimed, err := i.t.claimIDs(ctx, stream, group, consumer, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim messages of %s on %s: %w", group, stream, err)
+	}
+
+	return claimed, nil
+}
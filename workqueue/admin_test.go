@@ -0,0 +1,61 @@
+package workqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestI_admin(t *testing.T) {
+	ctx := context.Background()
+
+	q, err := New(Config{Backend: BackendMemory})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	if err := q.CreateGroup(ctx, "slack_team_join", "gopher", "0"); err != nil {
+		t.Fatalf("CreateGroup() unexpected error: %v", err)
+	}
+
+	_ = q.enqueue(ctx, "slack_team_join", map[string]interface{}{"event_id": "Ev1"})
+	_ = q.enqueue(ctx, "dead_letter", map[string]interface{}{"event_id": "Ev2"})
+
+	msgs, _ := q.t.read(ctx, "gopher", "dead", []string{"slack_team_join"}, 10, time.Millisecond)
+
+	if len(msgs) != 1 {
+		t.Fatalf("read() = %d messages, want 1", len(msgs))
+	}
+
+	streams, err := q.Streams(ctx)
+	if err != nil || len(streams) != 2 || streams[0] != "dead_letter" || streams[1] != "slack_team_join" {
+		t.Fatalf("Streams() = %q, %v; want [dead_letter slack_team_join]", streams, err)
+	}
+
+	groups, err := q.Groups(ctx, "slack_team_join")
+	if err != nil || len(groups) != 1 || groups[0].Name != "gopher" || groups[0].Pending != 1 {
+		t.Fatalf("Groups() = %+v, %v; want gopher with 1 pending", groups, err)
+	}
+
+	if entries, _ := q.PendingEntries(ctx, "slack_team_join", "gopher", "alive", 10); len(entries) != 0 {
+		t.Fatalf("PendingEntries() for another consumer = %+v, want none", entries)
+	}
+
+	claimed, err := q.Claim(ctx, "slack_team_join", "gopher", "alive", msgs[0].ID, "0-1")
+	if err != nil || len(claimed) != 1 || claimed[0] != msgs[0].ID {
+		t.Fatalf("Claim() = %q, %v; want [%s]", claimed, err, msgs[0].ID)
+	}
+
+	entries, err := q.PendingEntries(ctx, "slack_team_join", "gopher", "", 10)
+	if err != nil || len(entries) != 1 || entries[0].Consumer != "alive" || entries[0].Deliveries != 1 {
+		t.Fatalf("PendingEntries() = %+v, %v; want 1 for alive, delivered once", entries, err)
+	}
+
+	if ok, err := q.DeleteGroup(ctx, "slack_team_join", "gopher"); !ok || err != nil {
+		t.Fatalf("DeleteGroup() = %t, %v; want true, <nil>", ok, err)
+	}
+
+	if groups, _ := q.Groups(ctx, "slack_team_join"); len(groups) != 0 {
+		t.Fatalf("Groups() after delete = %+v, want none", groups)
+	}
+}
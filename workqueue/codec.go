@@ -0,0 +1,65 @@
+package workqueue
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Codec encodes the event JSON from the gateway into the payload stored in the
+// Redis stream, and decodes it back for the handlers. The name of the Codec is
+// stored with each message, so consumers can decode messages published with
+// any of the Codecs, regardless of the one they publish with.
+type Codec interface {
+	// Name is the unique name of the codec, stored with each message.
+	Name() string
+
+	// Marshal encodes the event JSON into the stored payload.
+	Marshal(jsonData []byte) ([]byte, error)
+
+	// Unmarshal decodes the stored payload back into the event JSON.
+	Unmarshal(payload []byte) ([]byte, error)
+}
+
+// codecField is the message field holding the name of the Codec the payload
+// was encoded with. Messages without it were encoded with JSONCodec.
+const codecField = "codec"
+
+var codecs = map[string]Codec{
+	JSONCodec.Name():    JSONCodec,
+	MsgpackCodec.Name(): MsgpackCodec,
+}
+
+// CodecByName returns the Codec with the given name, matched without regard to
+// case. It returns false if there isn't one.
+func CodecByName(name string) (Codec, bool) {
+	c, ok := codecs[strings.ToLower(name)]
+	return c, ok
+}
+
+// JSONCodec is the default Codec, which stores the event JSON as-is.
+var JSONCodec Codec = jsonCodec{}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                             { return "json" }
+func (jsonCodec) Marshal(jsonData []byte) ([]byte, error)  { return jsonData, nil }
+func (jsonCodec) Unmarshal(payload []byte) ([]byte, error) { return payload, nil }
+
+// decodePayload decodes the payload, which was encoded with the named Codec.
+func decodePayload(codecName string, payload []byte) ([]byte, error) {
+	if len(codecName) == 0 {
+		return payload, nil
+	}
+
+	c, ok := CodecByName(codecName)
+	if !ok {
+		return nil, fmt.Errorf("unknown payload codec %q", codecName)
+	}
+
+	d, err := c.Unmarshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s payload: %w", c.Name(), err)
+	}
+
+	return d, nil
+}
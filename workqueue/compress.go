@@ -0,0 +1,75 @@
+package workqueue
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+)
+
+const (
+	// compressionField is the message field holding how the payload was
+	// compressed, after being encoded with its Codec. Messages without it
+	// weren't compressed.
+	compressionField = "compression"
+
+	// CompressionGzip is the Config.Compression for gzip.
+	CompressionGzip = "gzip"
+
+	// compressMinSize is the smallest payload that's compressed, as smaller
+	// ones don't shrink enough to be worth it
+	compressMinSize = 1024
+)
+
+// validCompression returns whether name is a supported Config.Compression.
+func validCompression(name string) bool {
+	return len(name) == 0 || name == CompressionGzip
+}
+
+// compress compresses the payload with the named compression, returning the
+// compression that was actually used, which is empty if the payload was left
+// as-is.
+func compress(name string, payload []byte) ([]byte, string, error) {
+	if name != CompressionGzip || len(payload) < compressMinSize {
+		return payload, "", nil
+	}
+
+	var buf bytes.Buffer
+
+	zw := gzip.NewWriter(&buf)
+
+	if _, err := zw.Write(payload); err != nil {
+		return nil, "", fmt.Errorf("failed to gzip payload: %w", err)
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, "", fmt.Errorf("failed to gzip payload: %w", err)
+	}
+
+	return buf.Bytes(), CompressionGzip, nil
+}
+
+// decompress decompresses the payload, which was compressed with the named
+// compression.
+func decompress(name string, payload []byte) ([]byte, error) {
+	switch name {
+	case "":
+		return payload, nil
+
+	case CompressionGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to gunzip payload: %w", err)
+		}
+
+		d, err := ioutil.ReadAll(zr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to gunzip payload: %w", err)
+		}
+
+		return d, nil
+
+	default:
+		return nil, fmt.Errorf("unknown payload compression %q", name)
+	}
+}
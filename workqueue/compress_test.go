@@ -0,0 +1,50 @@
+package workqueue
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func Test_compress(t *testing.T) {
+	tests := []struct {
+		name    string
+		comp    string
+		payload []byte
+		want    string
+	}{
+		{name: "none", payload: []byte(strings.Repeat("x", 2048))},
+		{name: "gzip", comp: CompressionGzip, payload: []byte(strings.Repeat("x", 2048)), want: CompressionGzip},
+		{name: "gzip_small", comp: CompressionGzip, payload: []byte(`{"type":"message"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, used, err := compress(tt.comp, tt.payload)
+			if err != nil {
+				t.Fatalf("compress() unexpected error: %v", err)
+			}
+
+			if used != tt.want {
+				t.Fatalf("compress() compression = %q, want %q", used, tt.want)
+			}
+
+			if len(used) > 0 && len(c) >= len(tt.payload) {
+				t.Fatalf("compress() output is %d bytes, not smaller than %d", len(c), len(tt.payload))
+			}
+
+			got, err := decompress(used, c)
+			if err != nil {
+				t.Fatalf("decompress() unexpected error: %v", err)
+			}
+
+			if !bytes.Equal(got, tt.payload) {
+				t.Fatal("decompress() did not return the original payload")
+			}
+		})
+	}
+
+	if _, err := decompress("zstd", nil); err == nil {
+		t.Fatal("decompress() with an unknown compression did not error")
+	}
+}
@@ -0,0 +1,379 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// defaultGroupName is the consumer group used when Config.ConsumerGroup isn't
+// set. It's the one redisqueue defaulted to, so those groups keep their place
+// in the streams.
+const defaultGroupName = "redisqueue"
+
+// message is an entry read from a stream by a consumer.
+type message struct {
+	ID     string
+	Stream string
+	Values map[string]interface{}
+
+	// Deliveries is how many times the entry has been delivered to the
+	// consumer group, including this time.
+	Deliveries int64
+}
+
+// consumeFunc handles a message. The message is acknowledged if it returns
+// nil. Otherwise it stays pending, to be reclaimed once it's been idle for the
+// visibility timeout.
+type consumeFunc func(m *message) error
+
+// consumerOptions configure a consumer.
+type consumerOptions struct {
+	// Name is the consumer's name within the group. It defaults to the
+	// hostname.
+	Name string
+
+	// GroupName is the consumer group. It defaults to defaultGroupName.
+	GroupName string
+
+	// VisibilityTimeout is how long a message can be pending before any
+	// consumer in the group can reclaim it. If it's zero, messages aren't
+	// reclaimed.
+	VisibilityTimeout time.Duration
+
+	// BlockingTimeout is how long each read blocks waiting for messages,
+	// which is also about as long as it takes to stop reading.
+	BlockingTimeout time.Duration
+
+	// ReclaimInterval is how often pending messages are checked for ones to
+	// reclaim.
+	ReclaimInterval time.Duration
+
+	// BufferSize is how many messages are read ahead of being handled.
+	BufferSize int
+
+	// Concurrency is how many messages are handled at once.
+	Concurrency int
+}
+
+func (o consumerOptions) withDefaults() consumerOptions {
+	if len(o.Name) == 0 {
+		o.Name, _ = os.Hostname()
+	}
+
+	if len(o.GroupName) == 0 {
+		o.GroupName = defaultGroupName
+	}
+
+	if o.BlockingTimeout <= 0 {
+		o.BlockingTimeout = 5 * time.Second
+	}
+
+	if o.ReclaimInterval <= 0 {
+		o.ReclaimInterval = time.Second
+	}
+
+	if o.Concurrency <= 0 {
+		o.Concurrency = 1
+	}
+
+	if o.BufferSize <= 0 {
+		o.BufferSize = o.Concurrency
+	}
+
+	return o
+}
+
+// consumer reads messages from its streams as part of a consumer group, and
+// hands them to their stream's consumeFunc. Messages that another consumer
+// left pending for longer than the visibility timeout are reclaimed.
+type consumer struct {
+	t    transport
+	ps   *pauseState
+	l    *zerolog.Logger
+	m    *Metrics
+	opts consumerOptions
+
+	streams []string
+	fns     map[string]consumeFunc
+	lastIDs map[string]string
+
+	queue    chan *message
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func newConsumer(t transport, ps *pauseState, logger *zerolog.Logger, m *Metrics, opts consumerOptions) *consumer {
+	opts = opts.withDefaults()
+
+	return &consumer{
+		t:       t,
+		ps:      ps,
+		l:       logger,
+		m:       m,
+		opts:    opts,
+		fns:     make(map[string]consumeFunc),
+		lastIDs: make(map[string]string),
+		queue:   make(chan *message, opts.BufferSize),
+		stop:    make(chan struct{}),
+	}
+}
+
+// register has the messages from stream given to fn. If the consumer group
+// doesn't exist on the stream yet, it's created to start after lastID, which
+// can be "$" for only new messages. It must be called before run.
+func (c *consumer) register(stream, lastID string, fn consumeFunc) {
+	if _, ok := c.fns[stream]; !ok {
+		c.streams = append(c.streams, stream)
+	}
+
+	c.fns[stream] = fn
+	c.lastIDs[stream] = lastID
+}
+
+// run creates the consumer groups, and handles messages until shutdown is
+// called. It returns once the messages already read have been handled.
+func (c *consumer) run() {
+	if len(c.streams) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-c.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if err := c.createGroups(ctx, c.streams); err != nil {
+		c.l.Error().
+			Err(err).
+			Msg("failed to create consumer groups")
+
+		return
+	}
+
+	var readers sync.WaitGroup
+
+	for _, streams := range c.readGroups() {
+		readers.Add(1)
+
+		go func(streams []string) {
+			defer readers.Done()
+			c.poll(ctx, streams)
+		}(streams)
+	}
+
+	if c.opts.VisibilityTimeout > 0 {
+		readers.Add(1)
+
+		go func() {
+			defer readers.Done()
+			c.reclaim(ctx)
+		}()
+	}
+
+	var workers sync.WaitGroup
+
+	for n := 0; n < c.opts.Concurrency; n++ {
+		workers.Add(1)
+
+		go func() {
+			defer workers.Done()
+
+			for m := range c.queue {
+				c.process(m)
+			}
+		}()
+	}
+
+	readers.Wait()
+	close(c.queue)
+	workers.Wait()
+}
+
+// shutdown stops reading messages. It doesn't wait for run to return.
+func (c *consumer) shutdown() {
+	c.stopOnce.Do(func() { close(c.stop) })
+}
+
+// readGroups returns the streams to read together, which is all of them unless
+// the transport can't read several at once.
+func (c *consumer) readGroups() [][]string {
+	if c.t.readsTogether() {
+		return [][]string{c.streams}
+	}
+
+	groups := make([][]string, len(c.streams))
+
+	for n, s := range c.streams {
+		groups[n] = []string{s}
+	}
+
+	return groups
+}
+
+func (c *consumer) createGroups(ctx context.Context, streams []string) error {
+	for _, s := range streams {
+		if err := c.t.createGroup(ctx, s, c.opts.GroupName, c.lastIDs[s]); err != nil {
+			return fmt.Errorf("failed to create consumer group on %s: %w", s, err)
+		}
+	}
+
+	return nil
+}
+
+// free returns how many messages can be read without overfilling the buffer.
+// It's at least one, so reads wait on the buffer instead of spinning.
+func (c *consumer) free() int64 {
+	n := int64(cap(c.queue) - len(c.queue))
+	if n < 1 {
+		n = 1
+	}
+
+	return n
+}
+
+// poll reads new messages from the streams that aren't paused until ctx is
+// canceled.
+func (c *consumer) poll(ctx context.Context, streams []string) {
+	for ctx.Err() == nil {
+		active, changed := c.ps.active(streams)
+
+		// wait to be resumed, as reading from no streams is an error
+		if len(active) == 0 {
+			select {
+			case <-changed:
+			case <-ctx.Done():
+			}
+
+			continue
+		}
+
+		msgs, err := c.t.read(ctx, c.opts.GroupName, c.opts.Name, active, c.free(), c.opts.BlockingTimeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+
+			c.l.Error().
+				Err(err).
+				Strs("redis_streams", active).
+				Msg("failed to read from streams")
+
+			// the group is gone if the stream was deleted
+			if errors.Is(err, errNoGroup) {
+				_ = c.createGroups(ctx, active)
+			}
+
+			c.pause(ctx)
+
+			continue
+		}
+
+		for _, m := range msgs {
+			if !c.enqueue(ctx, m) {
+				return
+			}
+		}
+	}
+}
+
+// reclaim claims messages that have been pending for longer than the
+// visibility timeout, every ReclaimInterval, until ctx is canceled.
+func (c *consumer) reclaim(ctx context.Context) {
+	t := time.NewTicker(c.opts.ReclaimInterval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-t.C:
+			for _, s := range c.streams {
+				if err := c.reclaimStream(ctx, s); err != nil && ctx.Err() == nil {
+					c.l.Error().
+						Err(err).
+						Str("redis_stream", s).
+						Msg("failed to reclaim pending messages")
+				}
+			}
+		}
+	}
+}
+
+func (c *consumer) reclaimStream(ctx context.Context, stream string) error {
+	msgs, err := c.t.claim(ctx, stream, c.opts.GroupName, c.opts.Name, c.opts.VisibilityTimeout, c.free())
+	if err != nil {
+		return err
+	}
+
+	for _, m := range msgs {
+		c.m.observeReclaim(stream)
+
+		if !c.enqueue(ctx, m) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// enqueue buffers the message for the workers. It returns false if ctx was
+// canceled first, leaving the message pending.
+func (c *consumer) enqueue(ctx context.Context, m *message) bool {
+	select {
+	case c.queue <- m:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// pause waits a second before reading again after an error, so an outage isn't
+// hammered, unless ctx is canceled first.
+func (c *consumer) pause(ctx context.Context) {
+	t := time.NewTimer(time.Second)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+func (c *consumer) process(m *message) {
+	if err := c.fns[m.Stream](m); err != nil {
+		// it's left for another consumer to claim
+		if errors.Is(err, errDraining) {
+			return
+		}
+
+		c.l.Error().
+			Err(err).
+			Str("redis_stream", m.Stream).
+			Str("redis_message", m.ID).
+			Msg("message left pending for redelivery")
+
+		return
+	}
+
+	// acknowledge it even after shutdown, as it was handled
+	if err := c.t.ack(context.Background(), m.Stream, c.opts.GroupName, m.ID); err != nil {
+		c.l.Error().
+			Err(err).
+			Str("redis_stream", m.Stream).
+			Str("redis_message", m.ID).
+			Msg("failed to acknowledge message")
+	}
+}
@@ -2,17 +2,64 @@ package workqueue
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/settings"
+	"github.com/gobridge/gopherbot/storage"
 	"github.com/rs/zerolog"
 	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
 )
 
 // ChannelSvc is an interface providing the channel service.
 type ChannelSvc interface {
-	Lookup(channelName string) (slack.Channel, bool, error)
+	// Lookup returns the channel with the name. The bool is true if there's
+	// no such channel, not if there is.
+	Lookup(ctx context.Context, channelName string) (slack.Channel, bool, error)
 }
 
+// UserGroupSvc is an interface providing the user group service. Generally
+// this is implemented by a *cache.UserGroup.
+type UserGroupSvc interface {
+	InGroup(ctx context.Context, userID, handle string) (bool, error)
+}
+
+// ErrNoUserGroupSvc is returned by Context.UserInGroup when the workqueue
+// wasn't configured with a UserGroupSvc.
+var ErrNoUserGroupSvc = errors.New("workqueue has no UserGroupSvc")
+
+// SettingsSvc is an interface providing the channel settings service.
+// Generally this is implemented by a *settings.Store.
+type SettingsSvc interface {
+	Channel(ctx context.Context, channelID string) (settings.Channel, error)
+}
+
+// ErrNoSettingsSvc is returned by Context.ChannelSettings when the workqueue
+// wasn't configured with a SettingsSvc.
+var ErrNoSettingsSvc = errors.New("workqueue has no SettingsSvc")
+
+// EmojiSvc is an interface providing the custom emoji service. Generally this
+// is implemented by a *cache.Emoji.
+type EmojiSvc interface {
+	Exists(ctx context.Context, name string) (bool, error)
+}
+
+// ErrNoEmojiSvc is returned by Context.EmojiExists when the workqueue wasn't
+// configured with an EmojiSvc.
+var ErrNoEmojiSvc = errors.New("workqueue has no EmojiSvc")
+
+// PermissionSvc is an interface providing the permissions service. Generally
+// this is implemented by a *permissions.Store.
+type PermissionSvc interface {
+	UserHasRole(ctx context.Context, userID, role string) (bool, error)
+}
+
+// ErrNoPermissionSvc is returned by Context.UserHasRole when the workqueue
+// wasn't configured with a PermissionSvc.
+var ErrNoPermissionSvc = errors.New("workqueue has no PermissionSvc")
+
 // EventMetadata represents the metadata about the event
 type EventMetadata struct {
 	// ID represents the ID as given to us by Slack.
@@ -26,13 +73,47 @@ type EventMetadata struct {
 
 	// RedisEvent is the ID of the message sent through the Redis queue.
 	RedisEvent string
+
+	// RequestID is the ID of the gateway request the event came in on, to
+	// correlate with the gateway's logs.
+	RequestID string
+
+	// GatewayConsumer is the consumer name of the gateway that published the
+	// event, which is its dyno ID. It's empty for events published before the
+	// gateway started including it.
+	GatewayConsumer string
+
+	// ThreadTS is the timestamp of the thread the event happened in, which is
+	// the timestamp of its first message. It's empty if the event wasn't in a
+	// thread.
+	ThreadTS string
+
+	// ParentUserID is the user who started the thread the event happened in,
+	// if it was in one.
+	ParentUserID string
+
+	// TeamID is the workspace the event is from. Events published before the
+	// gateway started including it have the one the event itself says, if
+	// any.
+	TeamID string
+
+	// Platform is the chat platform the event is from, which is PlatformSlack
+	// unless the event was published with another one.
+	Platform string
 }
 
 // Context is a superset of context.Context, including methods needed by
 // workqueue handler authors. The context given to handlers has a timeout for when they should
+// stop, so it can be passed as-is to the Slack client's Context methods and to
+// database drivers.
 type Context interface {
 	context.Context
 
+	// StdContext returns the standard context.Context this Context wraps, with
+	// the same deadline and values, for keeping past the handler call or
+	// passing somewhere the workqueue methods aren't wanted.
+	StdContext() context.Context
+
 	// Meta is the event metadata about this job. When did Slack emit it, when
 	// did we ingest it, etc.
 	Meta() EventMetadata
@@ -42,24 +123,104 @@ type Context interface {
 	Logger() *zerolog.Logger
 
 	// Slack is the Slack client.
-	Slack() *slack.Client
+	Slack() SlackClient
 
 	// Self is the info for the bot user we're using the credentials of.
 	Self() slack.User
 
+	// IsSelf returns whether the message was sent by the bot itself, either as
+	// its user or its bot. For edits, it's the edited message that's checked.
+	IsSelf(me *slackevents.MessageEvent) bool
+
 	// ChannelSvc provides a way to work with the internal channel metadata
 	// cache.
 	ChannelSvc() ChannelSvc
+
+	// RawEvent is the event JSON as published by the gateway, for fields the
+	// decoded event doesn't have, like blocks or bot_profile. It must not be
+	// modified.
+	RawEvent() []byte
+
+	// UserHasRole returns whether the user has the role, or a more privileged
+	// one, like "admin" or "moderator". Use this to gate destructive actions.
+	UserHasRole(userID, role string) (bool, error)
+
+	// Allow returns whether an action limited by key may happen, allowing
+	// bursts of up to limit actions that refill evenly over window. The limit
+	// is shared by all consumers. Use it to keep the bot from being baited
+	// into flooding a channel, with keys like "karma:<user>:<channel>".
+	Allow(key string, limit int, window time.Duration) (bool, error)
+
+	// Lock takes the lock called name, which is shared by all consumers, for
+	// up to ttl. It returns ErrLockHeld if someone else holds it. Use it to
+	// guard actions that mustn't happen twice, like posting a welcome
+	// message, and Unlock it once they're done.
+	Lock(name string, ttl time.Duration) (*Lock, error)
+
+	// Once runs fn, unless it already succeeded for key in the last ttl, and
+	// returns whether it ran. Success is recorded once fn returns nil, so a
+	// handler that's retried after fn failed runs it again, and one that's
+	// retried after it succeeded doesn't. It returns ErrLockHeld if fn is
+	// running for key elsewhere. Use it for things that must happen exactly
+	// once, with keys like "welcome_dm:<user>".
+	Once(key string, ttl time.Duration, fn func() error) (bool, error)
+
+	// ReplyInThread replies in the thread of the event's message, starting
+	// one if it's not in a thread already.
+	ReplyInThread(text string) error
+
+	// ReplyEphemeral replies in the event's channel with a message only the
+	// user can see.
+	ReplyEphemeral(userID, text string) error
+
+	// ReplyDM sends the user a direct message.
+	ReplyDM(userID, text string) error
+
+	// React adds the emoji reaction to the event's message.
+	React(emoji string) error
+
+	// ChannelSettings returns the settings for the channel, or the defaults if
+	// none were changed.
+	ChannelSettings(channelID string) (settings.Channel, error)
+
+	// Store returns the key-value store handlers can keep state in between
+	// events. Use Namespace to get a store for the handler's own keys.
+	Store() storage.Store
+
+	// UserInGroup returns whether the user is a member of the user group with
+	// the handle, like "gopher-admins", using the user group cache.
+	UserInGroup(userID, handle string) (bool, error)
+
+	// EmojiExists returns whether the workspace has a custom emoji with the
+	// name, using the emoji cache instead of calling the Slack API.
+	EmojiExists(name string) (bool, error)
+
+	// IsThreadReply returns whether the event's message is a reply in a
+	// thread, as opposed to the message that started it or one that isn't in
+	// a thread at all.
+	IsThreadReply() bool
 }
 
 type ctxer struct {
 	context.Context
 
-	s *slack.Client
+	s SlackClient
 	l *zerolog.Logger
 	u *slack.User
 	c ChannelSvc
 	e EventMetadata
+	r []byte
+	p PermissionSvc
+	q redis.UniversalClient
+	m EmojiSvc
+	g UserGroupSvc
+	k storage.Store
+	t SettingsSvc
+}
+
+// StdContext satisfies Context.
+func (c ctxer) StdContext() context.Context {
+	return c.Context
 }
 
 // Meta satisfies Context.
@@ -68,7 +229,7 @@ func (c ctxer) Meta() EventMetadata {
 }
 
 // Slack satisfies Context.
-func (c ctxer) Slack() *slack.Client {
+func (c ctxer) Slack() SlackClient {
 	return c.s
 }
 
@@ -82,9 +243,197 @@ func (c ctxer) Self() slack.User {
 	return *c.u
 }
 
+// IsSelf satisfies Context.
+func (c ctxer) IsSelf(me *slackevents.MessageEvent) bool {
+	return isSelf(c.u, me)
+}
+
 // ChannelSvc satisfies Context.
 func (c ctxer) ChannelSvc() ChannelSvc {
 	return c.c
 }
 
+// RawEvent satisfies Context.
+func (c ctxer) RawEvent() []byte {
+	return c.r
+}
+
+// UserHasRole satisfies Context.
+func (c ctxer) UserHasRole(userID, role string) (bool, error) {
+	if c.p == nil {
+		return false, ErrNoPermissionSvc
+	}
+
+	return c.p.UserHasRole(c, userID, role)
+}
+
+// ChannelSettings satisfies Context.
+func (c ctxer) ChannelSettings(channelID string) (settings.Channel, error) {
+	if c.t == nil {
+		return settings.Channel{}, ErrNoSettingsSvc
+	}
+
+	return c.t.Channel(c, channelID)
+}
+
+// Store satisfies Context.
+func (c ctxer) Store() storage.Store {
+	return c.k
+}
+
+// UserInGroup satisfies Context.
+func (c ctxer) UserInGroup(userID, handle string) (bool, error) {
+	if c.g == nil {
+		return false, ErrNoUserGroupSvc
+	}
+
+	return c.g.InGroup(c, userID, handle)
+}
+
+// EmojiExists satisfies Context.
+func (c ctxer) EmojiExists(name string) (bool, error) {
+	if c.m == nil {
+		return false, ErrNoEmojiSvc
+	}
+
+	return c.m.Exists(c, name)
+}
+
+// Allow satisfies Context.
+func (c ctxer) Allow(key string, limit int, window time.Duration) (bool, error) {
+	return allow(c, c.q, key, limit, window)
+}
+
+// Lock satisfies Context.
+func (c ctxer) Lock(name string, ttl time.Duration) (*Lock, error) {
+	return lock(c, c.q, name, ttl)
+}
+
+// Once satisfies Context.
+func (c ctxer) Once(key string, ttl time.Duration, fn func() error) (bool, error) {
+	return once(c, c.q, key, ttl, fn)
+}
+
 var _ Context = ctxer{}
+
+// ContextConfig is what a Context returned by NewContext uses, named like the
+// Config fields for the same things. Anything left nil behaves like it does
+// for a workqueue configured without it.
+type ContextConfig struct {
+	// Meta is returned by Context.Meta.
+	Meta EventMetadata
+
+	// RawEvent is returned by Context.RawEvent.
+	RawEvent []byte
+
+	// Logger is returned by Context.Logger. Leave nil to use a no-op logger.
+	Logger *zerolog.Logger
+
+	// SlackClient is returned by Context.Slack. It can be a fake, or a
+	// *slack.Client pointed at a fake API like the one in wqtest.
+	SlackClient SlackClient
+
+	// SlackUser is returned by Context.Self. Leave nil for an empty user.
+	SlackUser *slack.User
+
+	// RedisClient is used by Context.Allow, Context.Lock, and Context.Once.
+	RedisClient redis.UniversalClient
+
+	// ChannelCache is returned by Context.ChannelSvc.
+	ChannelCache ChannelSvc
+
+	// UserGroupCache is used by Context.UserInGroup.
+	UserGroupCache UserGroupSvc
+
+	// EmojiCache is used by Context.EmojiExists.
+	EmojiCache EmojiSvc
+
+	// Settings is used by Context.ChannelSettings.
+	Settings SettingsSvc
+
+	// Store is returned by Context.Store. Leave nil to use a *storage.Memory.
+	Store storage.Store
+
+	// Permissions is used by Context.UserHasRole.
+	Permissions PermissionSvc
+}
+
+// NewContext returns a Context wrapping ctx, like the one given to handlers,
+// using cfg. It's meant for testing handlers without running a workqueue.
+func NewContext(ctx context.Context, cfg ContextConfig) Context {
+	if cfg.Logger == nil {
+		l := zerolog.Nop()
+		cfg.Logger = &l
+	}
+
+	if cfg.SlackUser == nil {
+		cfg.SlackUser = &slack.User{}
+	}
+
+	if cfg.Store == nil {
+		cfg.Store = storage.NewMemory()
+	}
+
+	return ctxer{
+		Context: ctx,
+		s:       cfg.SlackClient,
+		l:       cfg.Logger,
+		u:       cfg.SlackUser,
+		c:       cfg.ChannelCache,
+		e:       cfg.Meta,
+		r:       cfg.RawEvent,
+		p:       cfg.Permissions,
+		q:       cfg.RedisClient,
+		m:       cfg.EmojiCache,
+		g:       cfg.UserGroupCache,
+		k:       cfg.Store,
+		t:       cfg.Settings,
+	}
+}
+
+type slackCtxer struct {
+	Context
+
+	s SlackClient
+}
+
+// Slack satisfies Context.
+func (c slackCtxer) Slack() SlackClient {
+	return c.s
+}
+
+// WithSlack returns a copy of ctx whose Slack() method returns sc. This is
+// useful for running a handler with a differently configured client, like one
+// that only logs what it would have done.
+func WithSlack(ctx Context, sc SlackClient) Context {
+	return slackCtxer{Context: ctx, s: sc}
+}
+
+type timeoutCtxer struct {
+	Context
+
+	tctx context.Context
+}
+
+// Deadline satisfies context.Context.
+func (c timeoutCtxer) Deadline() (time.Time, bool) { return c.tctx.Deadline() }
+
+// Done satisfies context.Context.
+func (c timeoutCtxer) Done() <-chan struct{} { return c.tctx.Done() }
+
+// Err satisfies context.Context.
+func (c timeoutCtxer) Err() error { return c.tctx.Err() }
+
+// Value satisfies context.Context.
+func (c timeoutCtxer) Value(key interface{}) interface{} { return c.tctx.Value(key) }
+
+// StdContext satisfies Context.
+func (c timeoutCtxer) StdContext() context.Context { return c.tctx }
+
+// WithTimeout returns a copy of ctx that's canceled after d, or when ctx is,
+// whichever happens first. This is useful for giving part of a handler a
+// shorter timeout than the handler's own.
+func WithTimeout(ctx Context, d time.Duration) (Context, context.CancelFunc) {
+	tctx, cancel := context.WithTimeout(ctx, d)
+	return timeoutCtxer{Context: ctx, tctx: tctx}, cancel
+}
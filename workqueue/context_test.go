@@ -0,0 +1,51 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type ctxKey struct{}
+
+func TestNewContext(t *testing.T) {
+	base := context.WithValue(context.Background(), ctxKey{}, "value")
+
+	ctx := NewContext(base, ContextConfig{Meta: EventMetadata{ID: "Ev123"}})
+
+	if ctx.StdContext() != base {
+		t.Fatal("StdContext() isn't the wrapped context")
+	}
+
+	if got := ctx.Meta().ID; got != "Ev123" {
+		t.Fatalf("Meta().ID = %q, want Ev123", got)
+	}
+
+	if ctx.Logger() == nil || ctx.Store() == nil {
+		t.Fatal("Logger() and Store() should have defaults")
+	}
+
+	if _, err := ctx.UserHasRole("U123", "admin"); !errors.Is(err, ErrNoPermissionSvc) {
+		t.Fatalf("UserHasRole() error = %v, want ErrNoPermissionSvc", err)
+	}
+
+	tctx, cancel := WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	std := tctx.StdContext()
+
+	if _, ok := std.Deadline(); !ok {
+		t.Fatal("StdContext() of WithTimeout has no deadline")
+	}
+
+	if std.Value(ctxKey{}) != "value" {
+		t.Fatal("StdContext() of WithTimeout lost the wrapped context's values")
+	}
+}
+
+func TestNewSlackClient(t *testing.T) {
+	if sc := NewSlackClient(nil); sc != nil {
+		t.Fatalf("NewSlackClient(nil) = %#v, want nil", sc)
+	}
+}
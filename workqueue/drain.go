@@ -0,0 +1,91 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// errDraining is returned to the consumer for messages that arrive while the
+// workqueue is draining, so they aren't acknowledged and stay in the stream
+var errDraining = errors.New("workqueue is draining")
+
+func (i *I) isDraining() bool {
+	return atomic.LoadInt32(&i.draining) == 1
+}
+
+// DrainAndShutdown stops handling new messages and shuts down the consumers,
+// waiting for in-flight handlers to finish or for ctx to expire. It returns how
+// many messages were returned to their stream, unacknowledged, for another
+// consumer to claim. That includes those that were buffered but not handled
+// yet, and any still in flight when ctx expired.
+func (i *I) DrainAndShutdown(ctx context.Context) (int64, error) {
+	atomic.StoreInt32(&i.draining, 1)
+
+	i.Shutdown()
+
+	var err error
+
+	select {
+	case <-i.done:
+	case <-ctx.Done():
+		err = fmt.Errorf("failed to wait for in-flight handlers: %w", ctx.Err())
+	}
+
+	returned, perr := i.pending(ctx)
+	if perr != nil && err == nil {
+		err = perr
+	}
+
+	return returned, err
+}
+
+// pending returns how many messages are pending for this consumer, across all
+// registered streams.
+func (i *I) pending(ctx context.Context) (int64, error) {
+	var n int64
+
+	for _, stream := range i.streams {
+		p, err := i.t.pending(ctx, stream, i.copts.GroupName, i.copts.Name)
+		if err != nil {
+			return n, fmt.Errorf("failed to get pending messages for %s: %w", stream, err)
+		}
+
+		n += p
+	}
+
+	return n, nil
+}
+
+// RunContext is the same as Run, but it stops consuming when ctx is canceled.
+// It then waits for the in-flight handlers to finish, like DrainAndShutdown,
+// and returns ctx.Err() unless draining failed.
+func (i *I) RunContext(ctx context.Context) error {
+	drained := make(chan error, 1)
+
+	go func() {
+		select {
+		case <-i.done:
+			drained <- nil
+
+		case <-ctx.Done():
+			// handlers have their own timeouts, so this won't wait forever
+			returned, err := i.DrainAndShutdown(context.Background())
+
+			i.l.Info().
+				Int64("returned", returned).
+				Msg("drained workqueue after context was canceled")
+
+			drained <- err
+		}
+	}()
+
+	i.Run()
+
+	if err := <-drained; err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
@@ -0,0 +1,71 @@
+package workqueue
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// encryptionField is the message field holding how the payload was
+	// encrypted, after being encoded with its Codec and compressed. Messages
+	// without it weren't encrypted.
+	encryptionField = "encryption"
+
+	// encryptionKeyring is the encryptionField value for payloads encrypted
+	// with the Config.Keyring.
+	encryptionKeyring = "keyring"
+)
+
+// Keyring encrypts event payloads, and decrypts them, like the
+// *keyring.Keyring the secrets in Redis are encrypted with.
+type Keyring interface {
+	// Seal encrypts data, authenticating it along with associated.
+	Seal(data, associated []byte) (string, error)
+
+	// Open decrypts what Seal returned, given the same associated data. The
+	// bool is whether it was encrypted with an old key.
+	Open(sealed string, associated []byte) (data []byte, stale bool, err error)
+}
+
+// errNoKeyring is returned when decrypting a payload without a Config.Keyring.
+var errNoKeyring = errors.New("payload is encrypted, but there's no keyring to decrypt it with")
+
+// encrypt encrypts the payload with kr, if it's not nil, returning the
+// encryption that was used, which is empty if the payload was left as-is. The
+// event ID is authenticated along with it, so one event's payload can't be
+// passed off as another's.
+func encrypt(kr Keyring, eventID string, payload []byte) ([]byte, string, error) {
+	if kr == nil {
+		return payload, "", nil
+	}
+
+	sealed, err := kr.Seal(payload, []byte(eventID))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encrypt payload: %w", err)
+	}
+
+	return []byte(sealed), encryptionKeyring, nil
+}
+
+// decrypt decrypts the payload, which was encrypted with the named encryption.
+func decrypt(kr Keyring, name, eventID string, payload []byte) ([]byte, error) {
+	switch name {
+	case "":
+		return payload, nil
+
+	case encryptionKeyring:
+		if kr == nil {
+			return nil, errNoKeyring
+		}
+
+		d, _, err := kr.Open(string(payload), []byte(eventID))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt payload: %w", err)
+		}
+
+		return d, nil
+
+	default:
+		return nil, fmt.Errorf("unknown payload encryption %q", name)
+	}
+}
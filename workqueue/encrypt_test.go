@@ -0,0 +1,103 @@
+package workqueue
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gobridge/gopherbot/internal/keyring"
+)
+
+func testKeyring(t *testing.T) *keyring.Keyring {
+	t.Helper()
+
+	kr, err := keyring.New(bytes.Repeat([]byte{1}, keyring.KeySize))
+	if err != nil {
+		t.Fatalf("keyring.New() unexpected error: %v", err)
+	}
+
+	return kr
+}
+
+func Test_encrypt(t *testing.T) {
+	kr := testKeyring(t)
+	payload := []byte(`{"type":"message","user":"U123","text":"my secret plan"}`)
+
+	c, used, err := encrypt(kr, "Ev123", payload)
+	if err != nil {
+		t.Fatalf("encrypt() unexpected error: %v", err)
+	}
+
+	if used != encryptionKeyring {
+		t.Fatalf("encrypt() encryption = %q, want %q", used, encryptionKeyring)
+	}
+
+	if bytes.Contains(c, []byte("secret plan")) {
+		t.Fatal("encrypt() output contains the plaintext")
+	}
+
+	got, err := decrypt(kr, used, "Ev123", c)
+	if err != nil {
+		t.Fatalf("decrypt() unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(got, payload) {
+		t.Fatal("decrypt() did not return the original payload")
+	}
+
+	if _, err := decrypt(kr, used, "Ev456", c); err == nil {
+		t.Fatal("decrypt() with another event's ID did not error")
+	}
+
+	if _, err := decrypt(nil, used, "Ev123", c); !errors.Is(err, errNoKeyring) {
+		t.Fatalf("decrypt() without a keyring error = %v, want errNoKeyring", err)
+	}
+
+	if _, err := decrypt(kr, "rot13", "Ev123", c); err == nil {
+		t.Fatal("decrypt() with an unknown encryption did not error")
+	}
+
+	if got, used, _ := encrypt(nil, "Ev123", payload); len(used) > 0 || !bytes.Equal(got, payload) {
+		t.Fatal("encrypt() without a keyring changed the payload")
+	}
+}
+
+func TestI_gatewayValues_encrypted(t *testing.T) {
+	if _, err := New(Config{Backend: BackendMemory, EncryptPayloads: true}); err == nil {
+		t.Fatal("New() without a keyring to encrypt with did not error")
+	}
+
+	kr := testKeyring(t)
+
+	q, err := New(Config{Backend: BackendMemory, Compression: CompressionGzip, Keyring: kr, EncryptPayloads: true})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	payload := `{"type":"message","user":"U123","text":"` + strings.Repeat("my secret plan ", 100) + `"}`
+
+	values, err := q.gatewayValues("T123", 1588000000000, "Ev123", "R123", []byte(payload))
+	if err != nil {
+		t.Fatalf("gatewayValues() unexpected error: %v", err)
+	}
+
+	m := &message{Values: values}
+
+	if strings.Contains(stringValue(m, "json"), "U123") {
+		t.Fatal("gatewayValues() payload isn't encrypted")
+	}
+
+	raw, err := decrypt(kr, stringValue(m, encryptionField), stringValue(m, "event_id"), []byte(stringValue(m, "json")))
+	if err == nil {
+		raw, err = decompress(stringValue(m, compressionField), raw)
+	}
+
+	if err != nil {
+		t.Fatalf("failed to decrypt and decompress payload: %v", err)
+	}
+
+	if string(raw) != payload {
+		t.Fatal("payload didn't decrypt to the original")
+	}
+}
@@ -0,0 +1,60 @@
+package workqueue
+
+import (
+	"strings"
+	"time"
+)
+
+// doneField is the message field holding the comma-separated names of the
+// handlers that were done with the message before it was rescheduled
+const doneField = "handlers_done"
+
+// handlerTarget is one of the handlers registered on a stream.
+type handlerTarget struct {
+	name    string
+	timeout time.Duration
+	decode  decodeFunc
+}
+
+// pendingTargets returns the handlers registered on the stream that aren't
+// done with the message yet, and the names of those that are.
+func (i *I) pendingTargets(stream string, m *message) ([]*handlerTarget, []string) {
+	targets := i.targets[stream]
+
+	s := stringValue(m, doneField)
+	if len(s) == 0 {
+		return targets, nil
+	}
+
+	done := strings.Split(s, ",")
+
+	pending := make([]*handlerTarget, 0, len(targets))
+
+	for _, t := range targets {
+		if !contains(done, t.name) {
+			pending = append(pending, t)
+		}
+	}
+
+	return pending, done
+}
+
+func targetNames(targets []*handlerTarget) string {
+	names := make([]string, len(targets))
+
+	for n, t := range targets {
+		names[n] = t.name
+	}
+
+	return strings.Join(names, ",")
+}
+
+func contains(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,212 @@
+package workqueue
+
+import (
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// The handler types below return only an error, instead of the
+// (shouldRetry, discarded, err) of the ones they're named after. What to do
+// with the message on failure is said by wrapping the error with Retryable or
+// Discard; any other error sends it to the dead-letter stream. Their Handler
+// method returns the older type, which is what the Register methods take.
+
+// MessageHandlerV2 is a MessageHandler that returns only an error.
+type MessageHandlerV2 func(ctx Context, me *slackevents.MessageEvent) error
+
+// Handler returns fn as a MessageHandler.
+func (fn MessageHandlerV2) Handler() MessageHandler {
+	return func(ctx Context, me *slackevents.MessageEvent) (bool, bool, error) {
+		return Result(fn(ctx, me))
+	}
+}
+
+// MessageChangedHandlerV2 is a MessageChangedHandler that returns only an error.
+type MessageChangedHandlerV2 func(ctx Context, me *slackevents.MessageEvent) error
+
+// Handler returns fn as a MessageChangedHandler.
+func (fn MessageChangedHandlerV2) Handler() MessageChangedHandler {
+	return func(ctx Context, me *slackevents.MessageEvent) (bool, bool, error) {
+		return Result(fn(ctx, me))
+	}
+}
+
+// MessageDeletedHandlerV2 is a MessageDeletedHandler that returns only an error.
+type MessageDeletedHandlerV2 func(ctx Context, md *MessageDeletedEvent) error
+
+// Handler returns fn as a MessageDeletedHandler.
+func (fn MessageDeletedHandlerV2) Handler() MessageDeletedHandler {
+	return func(ctx Context, md *MessageDeletedEvent) (bool, bool, error) {
+		return Result(fn(ctx, md))
+	}
+}
+
+// TeamJoinHandlerV2 is a TeamJoinHandler that returns only an error.
+type TeamJoinHandlerV2 func(ctx Context, tj *slack.TeamJoinEvent) error
+
+// Handler returns fn as a TeamJoinHandler.
+func (fn TeamJoinHandlerV2) Handler() TeamJoinHandler {
+	return func(ctx Context, tj *slack.TeamJoinEvent) (bool, bool, error) {
+		return Result(fn(ctx, tj))
+	}
+}
+
+// ChannelJoinHandlerV2 is a ChannelJoinHandler that returns only an error.
+type ChannelJoinHandlerV2 func(ctx Context, cj *slackevents.MemberJoinedChannelEvent) error
+
+// Handler returns fn as a ChannelJoinHandler.
+func (fn ChannelJoinHandlerV2) Handler() ChannelJoinHandler {
+	return func(ctx Context, cj *slackevents.MemberJoinedChannelEvent) (bool, bool, error) {
+		return Result(fn(ctx, cj))
+	}
+}
+
+// ChannelLeaveHandlerV2 is a ChannelLeaveHandler that returns only an error.
+type ChannelLeaveHandlerV2 func(ctx Context, cl *ChannelLeaveEvent) error
+
+// Handler returns fn as a ChannelLeaveHandler.
+func (fn ChannelLeaveHandlerV2) Handler() ChannelLeaveHandler {
+	return func(ctx Context, cl *ChannelLeaveEvent) (bool, bool, error) {
+		return Result(fn(ctx, cl))
+	}
+}
+
+// UserDeactivatedHandlerV2 is a UserDeactivatedHandler that returns only an error.
+type UserDeactivatedHandlerV2 func(ctx Context, uc *slack.UserChangeEvent) error
+
+// Handler returns fn as a UserDeactivatedHandler.
+func (fn UserDeactivatedHandlerV2) Handler() UserDeactivatedHandler {
+	return func(ctx Context, uc *slack.UserChangeEvent) (bool, bool, error) {
+		return Result(fn(ctx, uc))
+	}
+}
+
+// ChannelChangeHandlerV2 is a ChannelChangeHandler that returns only an error.
+type ChannelChangeHandlerV2 func(ctx Context, cc *ChannelChangeEvent) error
+
+// Handler returns fn as a ChannelChangeHandler.
+func (fn ChannelChangeHandlerV2) Handler() ChannelChangeHandler {
+	return func(ctx Context, cc *ChannelChangeEvent) (bool, bool, error) {
+		return Result(fn(ctx, cc))
+	}
+}
+
+// ChannelCreatedHandlerV2 is a ChannelCreatedHandler that returns only an error.
+type ChannelCreatedHandlerV2 func(ctx Context, cc *ChannelChangeEvent) error
+
+// Handler returns fn as a ChannelCreatedHandler.
+func (fn ChannelCreatedHandlerV2) Handler() ChannelCreatedHandler {
+	return func(ctx Context, cc *ChannelChangeEvent) (bool, bool, error) {
+		return Result(fn(ctx, cc))
+	}
+}
+
+// ChannelRenamedHandlerV2 is a ChannelRenamedHandler that returns only an error.
+type ChannelRenamedHandlerV2 func(ctx Context, cc *ChannelChangeEvent) error
+
+// Handler returns fn as a ChannelRenamedHandler.
+func (fn ChannelRenamedHandlerV2) Handler() ChannelRenamedHandler {
+	return func(ctx Context, cc *ChannelChangeEvent) (bool, bool, error) {
+		return Result(fn(ctx, cc))
+	}
+}
+
+// ChannelArchivedHandlerV2 is a ChannelArchivedHandler that returns only an error.
+type ChannelArchivedHandlerV2 func(ctx Context, cc *ChannelChangeEvent) error
+
+// Handler returns fn as a ChannelArchivedHandler.
+func (fn ChannelArchivedHandlerV2) Handler() ChannelArchivedHandler {
+	return func(ctx Context, cc *ChannelChangeEvent) (bool, bool, error) {
+		return Result(fn(ctx, cc))
+	}
+}
+
+// ChannelUnarchivedHandlerV2 is a ChannelUnarchivedHandler that returns only an error.
+type ChannelUnarchivedHandlerV2 func(ctx Context, cc *ChannelChangeEvent) error
+
+// Handler returns fn as a ChannelUnarchivedHandler.
+func (fn ChannelUnarchivedHandlerV2) Handler() ChannelUnarchivedHandler {
+	return func(ctx Context, cc *ChannelChangeEvent) (bool, bool, error) {
+		return Result(fn(ctx, cc))
+	}
+}
+
+// SubteamHandlerV2 is a SubteamHandler that returns only an error.
+type SubteamHandlerV2 func(ctx Context, se *SubteamEvent) error
+
+// Handler returns fn as a SubteamHandler.
+func (fn SubteamHandlerV2) Handler() SubteamHandler {
+	return func(ctx Context, se *SubteamEvent) (bool, bool, error) {
+		return Result(fn(ctx, se))
+	}
+}
+
+// FileSharedHandlerV2 is a FileSharedHandler that returns only an error.
+type FileSharedHandlerV2 func(ctx Context, fs *FileSharedEvent) error
+
+// Handler returns fn as a FileSharedHandler.
+func (fn FileSharedHandlerV2) Handler() FileSharedHandler {
+	return func(ctx Context, fs *FileSharedEvent) (bool, bool, error) {
+		return Result(fn(ctx, fs))
+	}
+}
+
+// ReactionHandlerV2 is a ReactionHandler that returns only an error.
+type ReactionHandlerV2 func(ctx Context, re *ReactionEvent) error
+
+// Handler returns fn as a ReactionHandler.
+func (fn ReactionHandlerV2) Handler() ReactionHandler {
+	return func(ctx Context, re *ReactionEvent) (bool, bool, error) {
+		return Result(fn(ctx, re))
+	}
+}
+
+// InteractionHandlerV2 is a InteractionHandler that returns only an error.
+type InteractionHandlerV2 func(ctx Context, ic *slack.InteractionCallback) error
+
+// Handler returns fn as a InteractionHandler.
+func (fn InteractionHandlerV2) Handler() InteractionHandler {
+	return func(ctx Context, ic *slack.InteractionCallback) (bool, bool, error) {
+		return Result(fn(ctx, ic))
+	}
+}
+
+// SlashCommandHandlerV2 is a SlashCommandHandler that returns only an error.
+type SlashCommandHandlerV2 func(ctx Context, sc *slack.SlashCommand) error
+
+// Handler returns fn as a SlashCommandHandler.
+func (fn SlashCommandHandlerV2) Handler() SlashCommandHandler {
+	return func(ctx Context, sc *slack.SlashCommand) (bool, bool, error) {
+		return Result(fn(ctx, sc))
+	}
+}
+
+// ScheduledJobHandlerV2 is a ScheduledJobHandler that returns only an error.
+type ScheduledJobHandlerV2 func(ctx Context, sj *ScheduledJobEvent) error
+
+// Handler returns fn as a ScheduledJobHandler.
+func (fn ScheduledJobHandlerV2) Handler() ScheduledJobHandler {
+	return func(ctx Context, sj *ScheduledJobEvent) (bool, bool, error) {
+		return Result(fn(ctx, sj))
+	}
+}
+
+// ModerationHandlerV2 is a ModerationHandler that returns only an error.
+type ModerationHandlerV2 func(ctx Context, me *ModerationEvent) error
+
+// Handler returns fn as a ModerationHandler.
+func (fn ModerationHandlerV2) Handler() ModerationHandler {
+	return func(ctx Context, me *ModerationEvent) (bool, bool, error) {
+		return Result(fn(ctx, me))
+	}
+}
+
+// RawHandlerV2 is a RawHandler that returns only an error.
+type RawHandlerV2 func(ctx Context, meta EventMetadata, data []byte) error
+
+// Handler returns fn as a RawHandler.
+func (fn RawHandlerV2) Handler() RawHandler {
+	return func(ctx Context, meta EventMetadata, data []byte) (bool, bool, error) {
+		return Result(fn(ctx, meta, data))
+	}
+}
@@ -0,0 +1,122 @@
+package workqueue
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisLockPrefix is the prefix of the keys holding each lock's token
+const redisLockPrefix = "workqueue:lock:"
+
+// ErrLockHeld is returned by Context.Lock when the lock is already held, by
+// Lock.Refresh when it was lost, and by Context.Once when the function is
+// running elsewhere.
+var ErrLockHeld = errors.New("lock is held by someone else")
+
+// extendLock sets the lock at KEYS[1] to expire in ARGV[2] milliseconds, if
+// it's held by ARGV[1].
+var extendLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+
+return 0
+`)
+
+// unlockLock deletes the lock at KEYS[1], if it's held by ARGV[1].
+var unlockLock = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+
+return 0
+`)
+
+// Lock is a lock shared by all consumers, taken with Context.Lock. It expires
+// after its TTL unless it's refreshed, so a consumer that dies holding it
+// doesn't hold it forever. Each Lock has a random token, so a holder whose
+// lock expired can't release or refresh the lock someone else took since.
+type Lock struct {
+	r     redis.UniversalClient
+	key   string
+	token string
+}
+
+// lock takes the lock called name for ttl, returning ErrLockHeld if it's
+// already held.
+func lock(ctx context.Context, rc redis.UniversalClient, name string, ttl time.Duration) (*Lock, error) {
+	if rc == nil {
+		return nil, errors.New("workqueue has no Redis client")
+	}
+
+	if ttl.Milliseconds() <= 0 {
+		return nil, fmt.Errorf("lock TTL must be at least 1ms, got %s", ttl)
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Lock{
+		r:     rc,
+		key:   redisLockPrefix + name,
+		token: token,
+	}
+
+	ok, err := rc.SetNX(ctx, l.key, l.token, ttl).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to take lock %s: %w", name, err)
+	}
+
+	if !ok {
+		return nil, ErrLockHeld
+	}
+
+	return l, nil
+}
+
+// Refresh sets the lock to expire ttl from now, for work that takes longer
+// than expected. It returns ErrLockHeld if the lock already expired.
+func (l *Lock) Refresh(ctx context.Context, ttl time.Duration) error {
+	if ttl.Milliseconds() <= 0 {
+		return fmt.Errorf("lock TTL must be at least 1ms, got %s", ttl)
+	}
+
+	n, err := extendLock.Run(ctx, l.r, []string{l.key}, l.token, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to refresh lock: %w", err)
+	}
+
+	if n != 1 {
+		return ErrLockHeld
+	}
+
+	return nil
+}
+
+// Unlock releases the lock, if it's still held.
+func (l *Lock) Unlock(ctx context.Context) error {
+	if err := unlockLock.Run(ctx, l.r, []string{l.key}, l.token).Err(); err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+
+	return nil
+}
+
+// newToken returns a random token, for telling who holds a key.
+func newToken() (string, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,130 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_lock_contention(t *testing.T) {
+	s, rc := newTestRedis(t)
+
+	ctx := context.Background()
+
+	l, err := lock(ctx, rc, "welcome:U123", time.Minute)
+	if err != nil {
+		t.Fatalf("lock() unexpected error: %v", err)
+	}
+
+	if _, err := lock(ctx, rc, "welcome:U123", time.Minute); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("lock() error = %v while held, want ErrLockHeld", err)
+	}
+
+	// other names aren't affected
+	if _, err := lock(ctx, rc, "welcome:U456", time.Minute); err != nil {
+		t.Fatalf("lock() unexpected error for another name: %v", err)
+	}
+
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock() unexpected error: %v", err)
+	}
+
+	if s.Exists(redisLockPrefix + "welcome:U123") {
+		t.Fatal("lock still held after Unlock")
+	}
+
+	if _, err := lock(ctx, rc, "welcome:U123", time.Minute); err != nil {
+		t.Fatalf("lock() unexpected error after Unlock: %v", err)
+	}
+}
+
+func Test_lock_expires(t *testing.T) {
+	s, rc := newTestRedis(t)
+
+	ctx := context.Background()
+
+	if _, err := lock(ctx, rc, "welcome:U123", time.Minute); err != nil {
+		t.Fatalf("lock() unexpected error: %v", err)
+	}
+
+	s.FastForward(time.Minute)
+
+	if _, err := lock(ctx, rc, "welcome:U123", time.Minute); err != nil {
+		t.Fatalf("lock() unexpected error after it expired: %v", err)
+	}
+}
+
+func TestLock_Refresh(t *testing.T) {
+	s, rc := newTestRedis(t)
+
+	ctx := context.Background()
+
+	l, err := lock(ctx, rc, "welcome:U123", time.Minute)
+	if err != nil {
+		t.Fatalf("lock() unexpected error: %v", err)
+	}
+
+	if err := l.Refresh(ctx, time.Hour); err != nil {
+		t.Fatalf("Refresh() unexpected error: %v", err)
+	}
+
+	if ttl := s.TTL(l.key); ttl != time.Hour {
+		t.Fatalf("lock TTL = %s after Refresh, want %s", ttl, time.Hour)
+	}
+
+	// the lock expires, and someone else takes it
+	s.FastForward(time.Hour)
+
+	other, err := lock(ctx, rc, "welcome:U123", time.Minute)
+	if err != nil {
+		t.Fatalf("lock() unexpected error after it expired: %v", err)
+	}
+
+	if err := l.Refresh(ctx, time.Hour); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("Refresh() error = %v after the lock was lost, want ErrLockHeld", err)
+	}
+
+	if ttl := s.TTL(other.key); ttl != time.Minute {
+		t.Fatalf("lock TTL = %s, want the other holder's %s kept", ttl, time.Minute)
+	}
+}
+
+func TestLock_Unlock_otherHolder(t *testing.T) {
+	s, rc := newTestRedis(t)
+
+	ctx := context.Background()
+
+	l, err := lock(ctx, rc, "welcome:U123", time.Minute)
+	if err != nil {
+		t.Fatalf("lock() unexpected error: %v", err)
+	}
+
+	s.FastForward(time.Minute)
+
+	other, err := lock(ctx, rc, "welcome:U123", time.Minute)
+	if err != nil {
+		t.Fatalf("lock() unexpected error after it expired: %v", err)
+	}
+
+	// the first holder's late Unlock mustn't release the second's lock
+	if err := l.Unlock(ctx); err != nil {
+		t.Fatalf("Unlock() unexpected error: %v", err)
+	}
+
+	if v, _ := s.Get(other.key); v != other.token {
+		t.Fatalf("lock = %q after the old holder's Unlock, want %q", v, other.token)
+	}
+
+	if _, err := lock(ctx, rc, "welcome:U123", time.Minute); !errors.Is(err, ErrLockHeld) {
+		t.Fatalf("lock() error = %v, want ErrLockHeld", err)
+	}
+}
+
+func Test_lock_invalidTTL(t *testing.T) {
+	_, rc := newTestRedis(t)
+
+	if _, err := lock(context.Background(), rc, "welcome:U123", time.Microsecond); err == nil {
+		t.Fatal("lock() expected an error for a TTL under 1ms")
+	}
+}
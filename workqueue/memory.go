@@ -0,0 +1,667 @@
+package workqueue
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// memoryTransport is the transport for BackendMemory. It works like Redis
+// Streams, with consumer groups and pending messages, but only within the
+// process.
+type memoryTransport struct {
+	mu            sync.Mutex
+	streams       map[string]*memoryStream
+	sched         map[string]time.Time
+	pausedStreams map[string]struct{}
+
+	// watchers are sent on when pausedStreams changes
+	watchers map[chan struct{}]struct{}
+
+	// lastMS and seq make the message IDs, which look like Redis's
+	lastMS, seq int64
+
+	// added is closed, and replaced, whenever a message is added, to wake up
+	// blocked reads
+	added chan struct{}
+}
+
+var _ transport = (*memoryTransport)(nil)
+
+type memoryStream struct {
+	entries []memoryEntry
+
+	// trimmed is how many entries were trimmed from the front, so the
+	// absolute index of entries[n] is trimmed+n
+	trimmed int64
+
+	groups map[string]*memoryGroup
+}
+
+type memoryEntry struct {
+	id     string
+	values map[string]interface{}
+}
+
+type memoryGroup struct {
+	// next is the absolute index of the next entry to deliver
+	next      int64
+	lastID    string
+	consumers map[string]struct{}
+	pending   map[string]*memoryPending
+}
+
+type memoryPending struct {
+	index      int64
+	consumer   string
+	delivered  time.Time
+	deliveries int64
+}
+
+func newMemoryTransport() *memoryTransport {
+	return &memoryTransport{
+		streams:       make(map[string]*memoryStream),
+		sched:         make(map[string]time.Time),
+		pausedStreams: make(map[string]struct{}),
+		watchers:      make(map[chan struct{}]struct{}),
+		added:         make(chan struct{}),
+	}
+}
+
+// stream returns the stream, creating it if needed. It must be called with
+// t.mu held.
+func (t *memoryTransport) stream(name string) *memoryStream {
+	s, ok := t.streams[name]
+	if !ok {
+		s = &memoryStream{groups: make(map[string]*memoryGroup)}
+		t.streams[name] = s
+	}
+
+	return s
+}
+
+// entry returns the entry at the absolute index, or false if it was trimmed.
+func (s *memoryStream) entry(index int64) (memoryEntry, bool) {
+	if index < s.trimmed || index >= s.trimmed+int64(len(s.entries)) {
+		return memoryEntry{}, false
+	}
+
+	return s.entries[index-s.trimmed], true
+}
+
+func (e memoryEntry) asMessage(stream string, deliveries int64) *message {
+	values := make(map[string]interface{}, len(e.values))
+
+	for k, v := range e.values {
+		values[k] = v
+	}
+
+	return &message{ID: e.id, Stream: stream, Values: values, Deliveries: deliveries}
+}
+
+func (t *memoryTransport) add(_ context.Context, stream string, values map[string]interface{}) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ms := millis(time.Now())
+
+	if ms <= t.lastMS {
+		t.seq++
+	} else {
+		t.lastMS, t.seq = ms, 0
+	}
+
+	e := memoryEntry{
+		id:     fmt.Sprintf("%d-%d", t.lastMS, t.seq),
+		values: make(map[string]interface{}, len(values)),
+	}
+
+	for k, v := range values {
+		e.values[k] = v
+	}
+
+	s := t.stream(stream)
+	s.entries = append(s.entries, e)
+
+	if over := len(s.entries) - streamMaxLength; over > 0 {
+		s.entries = append([]memoryEntry(nil), s.entries[over:]...)
+		s.trimmed += int64(over)
+	}
+
+	close(t.added)
+	t.added = make(chan struct{})
+
+	return nil
+}
+
+func (t *memoryTransport) createGroup(_ context.Context, stream, group, lastID string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s := t.stream(stream)
+
+	if _, ok := s.groups[group]; ok {
+		return nil
+	}
+
+	g := &memoryGroup{
+		lastID:    "0-0",
+		consumers: make(map[string]struct{}),
+		pending:   make(map[string]*memoryPending),
+	}
+
+	switch lastID {
+	case "0", "0-0":
+
+	case "$":
+		g.next = s.trimmed + int64(len(s.entries))
+
+		if len(s.entries) > 0 {
+			g.lastID = s.entries[len(s.entries)-1].id
+		}
+
+	default:
+		return fmt.Errorf("memory backend can't start consumer group after %q", lastID)
+	}
+
+	s.groups[group] = g
+
+	return nil
+}
+
+func (t *memoryTransport) read(ctx context.Context, group, consumer string, streams []string, count int64, block time.Duration) ([]*message, error) {
+	timer := time.NewTimer(block)
+	defer timer.Stop()
+
+	for {
+		t.mu.Lock()
+		msgs, err := t.deliver(group, consumer, streams, count)
+		added := t.added
+		t.mu.Unlock()
+
+		if err != nil || len(msgs) > 0 {
+			return msgs, err
+		}
+
+		select {
+		case <-added:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// deliver delivers up to count new messages from the streams to the consumer.
+// It must be called with t.mu held.
+func (t *memoryTransport) deliver(group, consumer string, streams []string, count int64) ([]*message, error) {
+	var msgs []*message
+
+	now := time.Now()
+
+	for _, name := range streams {
+		s, ok := t.streams[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errNoGroup, name)
+		}
+
+		g, ok := s.groups[group]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errNoGroup, name)
+		}
+
+		g.consumers[consumer] = struct{}{}
+
+		if g.next < s.trimmed {
+			g.next = s.trimmed
+		}
+
+		for ; int64(len(msgs)) < count; g.next++ {
+			e, ok := s.entry(g.next)
+			if !ok {
+				break
+			}
+
+			g.lastID = e.id
+			g.pending[e.id] = &memoryPending{index: g.next, consumer: consumer, delivered: now, deliveries: 1}
+
+			msgs = append(msgs, e.asMessage(name, 1))
+		}
+	}
+
+	return msgs, nil
+}
+
+func (t *memoryTransport) readsTogether() bool { return true }
+
+func (t *memoryTransport) claim(_ context.Context, stream, group, consumer string, minIdle time.Duration, count int64) ([]*message, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.streams[stream]
+	if !ok {
+		return nil, nil
+	}
+
+	g, ok := s.groups[group]
+	if !ok {
+		return nil, nil
+	}
+
+	var msgs []*message
+
+	now := time.Now()
+
+	for _, id := range g.sortedPending() {
+		if int64(len(msgs)) >= count {
+			break
+		}
+
+		p := g.pending[id]
+
+		if now.Sub(p.delivered) < minIdle {
+			continue
+		}
+
+		// it was trimmed while pending, so there's nothing to redeliver
+		e, ok := s.entry(p.index)
+		if !ok {
+			delete(g.pending, id)
+			continue
+		}
+
+		g.consumers[consumer] = struct{}{}
+
+		p.consumer = consumer
+		p.delivered = now
+		p.deliveries++
+
+		msgs = append(msgs, e.asMessage(stream, p.deliveries))
+	}
+
+	return msgs, nil
+}
+
+func (t *memoryTransport) ack(_ context.Context, stream, group string, ids ...string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if s, ok := t.streams[stream]; ok {
+		if g, ok := s.groups[group]; ok {
+			for _, id := range ids {
+				delete(g.pending, id)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (t *memoryTransport) pending(_ context.Context, stream, group, consumer string) (int64, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var n int64
+
+	if s, ok := t.streams[stream]; ok {
+		if g, ok := s.groups[group]; ok {
+			for _, p := range g.pending {
+				if p.consumer == consumer {
+					n++
+				}
+			}
+		}
+	}
+
+	return n, nil
+}
+
+func (t *memoryTransport) rangeOf(_ context.Context, stream, start, end string, count int64) ([]*message, error) {
+	from, fromExcl, err := parseRangeID(start, false)
+	if err != nil {
+		return nil, err
+	}
+
+	to, toExcl, err := parseRangeID(end, true)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.streams[stream]
+	if !ok {
+		return nil, nil
+	}
+
+	var msgs []*message
+
+	for _, e := range s.entries {
+		if int64(len(msgs)) >= count {
+			break
+		}
+
+		id, _ := parseStreamID(e.id)
+
+		if c := id.compare(from); c < 0 || (c == 0 && fromExcl) {
+			continue
+		}
+
+		if c := id.compare(to); c > 0 || (c == 0 && toExcl) {
+			break
+		}
+
+		msgs = append(msgs, e.asMessage(stream, 0))
+	}
+
+	return msgs, nil
+}
+
+// streamID is a parsed stream entry ID.
+type streamID struct {
+	ms, seq uint64
+}
+
+func (a streamID) compare(b streamID) int {
+	switch {
+	case a.ms < b.ms, a.ms == b.ms && a.seq < b.seq:
+		return -1
+	case a == b:
+		return 0
+	default:
+		return 1
+	}
+}
+
+func parseStreamID(s string) (streamID, error) {
+	parts := strings.SplitN(s, "-", 2)
+
+	ms, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil || len(parts) != 2 {
+		return streamID{}, fmt.Errorf("invalid stream ID %q", s)
+	}
+
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return streamID{}, fmt.Errorf("invalid stream ID %q", s)
+	}
+
+	return streamID{ms: ms, seq: seq}, nil
+}
+
+// parseRangeID parses an XRANGE bound, which can leave off the sequence
+// number to cover the whole millisecond.
+func parseRangeID(s string, end bool) (streamID, bool, error) {
+	switch s {
+	case "-":
+		return streamID{}, false, nil
+	case "+":
+		return streamID{ms: math.MaxUint64, seq: math.MaxUint64}, false, nil
+	}
+
+	exclusive := strings.HasPrefix(s, "(")
+	s = strings.TrimPrefix(s, "(")
+
+	if !strings.Contains(s, "-") {
+		ms, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return streamID{}, false, fmt.Errorf("invalid stream ID %q", s)
+		}
+
+		id := streamID{ms: ms}
+
+		if end {
+			id.seq = math.MaxUint64
+		}
+
+		return id, exclusive, nil
+	}
+
+	id, err := parseStreamID(s)
+
+	return id, exclusive, err
+}
+
+func (t *memoryTransport) listStreams(context.Context) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names := make([]string, 0, len(t.streams))
+
+	for name := range t.streams {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (t *memoryTransport) deleteGroup(_ context.Context, stream, group string) (bool, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.streams[stream]
+	if !ok {
+		return false, fmt.Errorf("stream %s doesn't exist", stream)
+	}
+
+	_, ok = s.groups[group]
+	delete(s.groups, group)
+
+	return ok, nil
+}
+
+// sortedPending returns the group's pending message IDs in stream order. It
+// must be called with t.mu held.
+func (g *memoryGroup) sortedPending() []string {
+	ids := make([]string, 0, len(g.pending))
+
+	for id := range g.pending {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(a, b int) bool { return g.pending[ids[a]].index < g.pending[ids[b]].index })
+
+	return ids
+}
+
+// group returns the consumer group on the stream, or errNoGroup. It must be
+// called with t.mu held.
+func (t *memoryTransport) group(stream, group string) (*memoryGroup, error) {
+	if s, ok := t.streams[stream]; ok {
+		if g, ok := s.groups[group]; ok {
+			return g, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", errNoGroup, stream)
+}
+
+func (t *memoryTransport) pendingEntries(_ context.Context, stream, group, consumer string, count int64) ([]PendingEntry, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	g, err := t.group(stream, group)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []PendingEntry
+
+	now := time.Now()
+
+	for _, id := range g.sortedPending() {
+		if int64(len(entries)) >= count {
+			break
+		}
+
+		p := g.pending[id]
+
+		if len(consumer) > 0 && p.consumer != consumer {
+			continue
+		}
+
+		entries = append(entries, PendingEntry{
+			ID:         id,
+			Consumer:   p.consumer,
+			Idle:       now.Sub(p.delivered),
+			Deliveries: p.deliveries,
+		})
+	}
+
+	return entries, nil
+}
+
+func (t *memoryTransport) claimIDs(_ context.Context, stream, group, consumer string, ids []string) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	g, err := t.group(stream, group)
+	if err != nil {
+		return nil, err
+	}
+
+	var claimed []string
+
+	for _, id := range ids {
+		p, ok := g.pending[id]
+		if !ok {
+			continue
+		}
+
+		g.consumers[consumer] = struct{}{}
+
+		p.consumer = consumer
+		p.delivered = time.Now()
+
+		claimed = append(claimed, id)
+	}
+
+	return claimed, nil
+}
+
+func (t *memoryTransport) info(_ context.Context, stream string) (*streamInfo, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.streams[stream]
+	if !ok {
+		return nil, nil
+	}
+
+	end := s.trimmed + int64(len(s.entries))
+
+	info := &streamInfo{
+		length: int64(len(s.entries)),
+		groups: make(map[string]xinfoGroup, len(s.groups)),
+	}
+
+	for name, g := range s.groups {
+		next := g.next
+		if next < s.trimmed {
+			next = s.trimmed
+		}
+
+		info.groups[name] = xinfoGroup{
+			consumers:       int64(len(g.consumers)),
+			pending:         int64(len(g.pending)),
+			lag:             end - next,
+			lastDeliveredID: g.lastID,
+		}
+	}
+
+	return info, nil
+}
+
+func (t *memoryTransport) setPaused(_ context.Context, stream string, paused bool) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if paused {
+		t.pausedStreams[stream] = struct{}{}
+	} else {
+		delete(t.pausedStreams, stream)
+	}
+
+	for w := range t.watchers {
+		select {
+		case w <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+func (t *memoryTransport) paused(context.Context) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	streams := make([]string, 0, len(t.pausedStreams))
+
+	for stream := range t.pausedStreams {
+		streams = append(streams, stream)
+	}
+
+	return streams, nil
+}
+
+func (t *memoryTransport) watchPaused(ctx context.Context) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+
+	t.mu.Lock()
+	t.watchers[changed] = struct{}{}
+	t.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		t.mu.Lock()
+		delete(t.watchers, changed)
+		t.mu.Unlock()
+	}()
+
+	return changed
+}
+
+func (t *memoryTransport) schedule(_ context.Context, member string, at time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sched[member] = at
+
+	return nil
+}
+
+func (t *memoryTransport) due(_ context.Context, now time.Time, n int64) ([]string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var members []string
+
+	for member, at := range t.sched {
+		if !at.After(now) {
+			members = append(members, member)
+		}
+	}
+
+	sort.Slice(members, func(a, b int) bool { return t.sched[members[a]].Before(t.sched[members[b]]) })
+
+	if int64(len(members)) > n {
+		members = members[:n]
+	}
+
+	for _, member := range members {
+		delete(t.sched, member)
+	}
+
+	return members, nil
+}
@@ -0,0 +1,111 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryTransport(t *testing.T) {
+	ctx := context.Background()
+	mt := newMemoryTransport()
+
+	if _, err := mt.read(ctx, "g", "c1", []string{"s"}, 1, time.Millisecond); !errors.Is(err, errNoGroup) {
+		t.Fatalf("read() without a group error = %v, want %v", err, errNoGroup)
+	}
+
+	if err := mt.add(ctx, "s", map[string]interface{}{"n": "0"}); err != nil {
+		t.Fatalf("add() unexpected error: %v", err)
+	}
+
+	// the group starts after what's already there
+	if err := mt.createGroup(ctx, "s", "g", "$"); err != nil {
+		t.Fatalf("createGroup() unexpected error: %v", err)
+	}
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_ = mt.add(ctx, "s", map[string]interface{}{"n": "1"})
+		_ = mt.add(ctx, "s", map[string]interface{}{"n": "2"})
+	}()
+
+	msgs, err := mt.read(ctx, "g", "c1", []string{"s"}, 10, time.Second)
+	if err != nil {
+		t.Fatalf("read() unexpected error: %v", err)
+	}
+
+	if len(msgs) == 0 || msgs[0].Values["n"] != "1" || msgs[0].Deliveries != 1 {
+		t.Fatalf("read() = %+v, want message 1 delivered once", msgs)
+	}
+
+	if len(msgs) == 1 {
+		more, err := mt.read(ctx, "g", "c1", []string{"s"}, 10, time.Second)
+		if err != nil {
+			t.Fatalf("read() unexpected error: %v", err)
+		}
+
+		msgs = append(msgs, more...)
+	}
+
+	if n, _ := mt.pending(ctx, "s", "g", "c1"); n != 2 {
+		t.Fatalf("pending() = %d, want 2", n)
+	}
+
+	if err := mt.ack(ctx, "s", "g", msgs[0].ID); err != nil {
+		t.Fatalf("ack() unexpected error: %v", err)
+	}
+
+	// the unacknowledged one can be claimed by another consumer once it's idle
+	if claimed, _ := mt.claim(ctx, "s", "g", "c2", time.Hour, 10); len(claimed) != 0 {
+		t.Fatalf("claim() before idle = %+v, want none", claimed)
+	}
+
+	claimed, err := mt.claim(ctx, "s", "g", "c2", 0, 10)
+	if err != nil {
+		t.Fatalf("claim() unexpected error: %v", err)
+	}
+
+	if len(claimed) != 1 || claimed[0].ID != msgs[1].ID || claimed[0].Deliveries != 2 {
+		t.Fatalf("claim() = %+v, want message 2 delivered twice", claimed)
+	}
+
+	info, err := mt.info(ctx, "s")
+	if err != nil {
+		t.Fatalf("info() unexpected error: %v", err)
+	}
+
+	want := xinfoGroup{consumers: 2, pending: 1, lag: 0, lastDeliveredID: msgs[1].ID}
+
+	if info.length != 3 || info.groups["g"] != want {
+		t.Fatalf("info() = %+v, want length 3 and group %+v", info, want)
+	}
+
+	if info, _ := mt.info(ctx, "nope"); info != nil {
+		t.Fatalf("info() of missing stream = %+v, want nil", info)
+	}
+}
+
+func TestMemoryTransport_due(t *testing.T) {
+	ctx := context.Background()
+	mt := newMemoryTransport()
+	now := time.Now()
+
+	_ = mt.schedule(ctx, "later", now.Add(time.Hour))
+	_ = mt.schedule(ctx, "second", now.Add(-time.Second))
+	_ = mt.schedule(ctx, "first", now.Add(-time.Minute))
+
+	got, err := mt.due(ctx, now, 10)
+	if err != nil {
+		t.Fatalf("due() unexpected error: %v", err)
+	}
+
+	if len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Fatalf("due() = %q, want [first second]", got)
+	}
+
+	// they're only returned once
+	if got, _ := mt.due(ctx, now, 10); len(got) != 0 {
+		t.Fatalf("second due() = %q, want none", got)
+	}
+}
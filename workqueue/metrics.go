@@ -0,0 +1,133 @@
+package workqueue
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Handler results, used as the result label of the handled messages metric.
+const (
+	resultSuccess    = "success"
+	resultDiscarded  = "discarded"
+	resultRetry      = "retry"
+	resultRedeliver  = "redeliver"
+	resultDeadLetter = "dead_letter"
+)
+
+// Metrics are the Prometheus metrics for the workqueue. It satisfies
+// prometheus.Collector, so the caller can register it with whichever registry
+// they expose. A nil *Metrics records nothing.
+type Metrics struct {
+	published *prometheus.CounterVec
+	handled   *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+	reclaimed *prometheus.CounterVec
+	paused    *prometheus.GaugeVec
+}
+
+var _ prometheus.Collector = (*Metrics)(nil)
+
+// NewMetrics returns a new *Metrics, to be set as Config.Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		published: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gopherbot",
+			Subsystem: "workqueue",
+			Name:      "published_total",
+			Help:      "Messages published to the workqueue, by stream.",
+		}, []string{"stream"}),
+
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gopherbot",
+			Subsystem: "workqueue",
+			Name:      "handled_total",
+			Help:      "Messages given to handlers, by stream, handler, and result.",
+		}, []string{"stream", "handler", "result"}),
+
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "gopherbot",
+			Subsystem: "workqueue",
+			Name:      "handler_duration_seconds",
+			Help:      "How long handlers took, by stream and handler.",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		}, []string{"stream", "handler"}),
+
+		reclaimed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "gopherbot",
+			Subsystem: "workqueue",
+			Name:      "reclaimed_total",
+			Help:      "Messages reclaimed after another consumer left them pending for the visibility timeout, by stream.",
+		}, []string{"stream"}),
+
+		paused: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "gopherbot",
+			Subsystem: "workqueue",
+			Name:      "paused",
+			Help:      "Whether consuming the stream is paused, by the stream name it was paused with.",
+		}, []string{"stream"}),
+	}
+}
+
+// Describe satisfies prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	m.published.Describe(ch)
+	m.handled.Describe(ch)
+	m.duration.Describe(ch)
+	m.reclaimed.Describe(ch)
+	m.paused.Describe(ch)
+}
+
+// Collect satisfies prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.published.Collect(ch)
+	m.handled.Collect(ch)
+	m.duration.Collect(ch)
+	m.reclaimed.Collect(ch)
+	m.paused.Collect(ch)
+}
+
+func (m *Metrics) observePublish(stream string) {
+	if m == nil {
+		return
+	}
+
+	m.published.WithLabelValues(stream).Inc()
+}
+
+func (m *Metrics) observeResult(stream, handler, result string) {
+	if m == nil {
+		return
+	}
+
+	m.handled.WithLabelValues(stream, handler, result).Inc()
+}
+
+func (m *Metrics) observeDuration(stream, handler string, d time.Duration) {
+	if m == nil {
+		return
+	}
+
+	m.duration.WithLabelValues(stream, handler).Observe(d.Seconds())
+}
+
+func (m *Metrics) observeReclaim(stream string) {
+	if m == nil {
+		return
+	}
+
+	m.reclaimed.WithLabelValues(stream).Inc()
+}
+
+func (m *Metrics) setPaused(stream string, paused bool) {
+	if m == nil {
+		return
+	}
+
+	var v float64
+	if paused {
+		v = 1
+	}
+
+	m.paused.WithLabelValues(stream).Set(v)
+}
@@ -0,0 +1,336 @@
+package workqueue
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// MsgpackCodec is a Codec that stores the event as MessagePack, which is more
+// compact than JSON for the large, deeply nested, message events.
+var MsgpackCodec Codec = msgpackCodec{}
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string { return "msgpack" }
+
+func (msgpackCodec) Marshal(jsonData []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(jsonData))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+
+	var buf bytes.Buffer
+
+	buf.Grow(len(jsonData))
+
+	if err := msgpackEncode(&buf, v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (msgpackCodec) Unmarshal(payload []byte) ([]byte, error) {
+	d := msgpackDecoder{b: payload}
+
+	v, err := d.decode()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(d.b) > 0 {
+		return nil, fmt.Errorf("%d trailing bytes after value", len(d.b))
+	}
+
+	return json.Marshal(v)
+}
+
+// msgpackEncode encodes the values encoding/json decodes into, with
+// json.Number for numbers.
+func msgpackEncode(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+
+	case json.Number:
+		if n, err := strconv.ParseInt(string(v), 10, 64); err == nil {
+			msgpackInt(buf, n)
+			return nil
+		}
+
+		f, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse number %q: %w", v, err)
+		}
+
+		buf.WriteByte(0xcb)
+		_ = binary.Write(buf, binary.BigEndian, math.Float64bits(f))
+
+	case string:
+		msgpackHeader(buf, len(v), 0xa0, 32, 0xd9)
+		buf.WriteString(v)
+
+	case []interface{}:
+		msgpackHeader(buf, len(v), 0x90, 16, 0)
+
+		for _, e := range v {
+			if err := msgpackEncode(buf, e); err != nil {
+				return err
+			}
+		}
+
+	case map[string]interface{}:
+		msgpackHeader(buf, len(v), 0x80, 16, 0)
+
+		// sorted, so the same event always encodes the same way
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			if err := msgpackEncode(buf, k); err != nil {
+				return err
+			}
+
+			if err := msgpackEncode(buf, v[k]); err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("cannot encode %T as msgpack", v)
+	}
+
+	return nil
+}
+
+func msgpackInt(buf *bytes.Buffer, n int64) {
+	switch {
+	case n >= 0 && n < 128:
+		buf.WriteByte(byte(n))
+	case n < 0 && n >= -32:
+		buf.WriteByte(byte(int8(n)))
+	default:
+		buf.WriteByte(0xd3)
+		_ = binary.Write(buf, binary.BigEndian, n)
+	}
+}
+
+// msgpackHeader writes the header for a string, array, or map of length n. fix
+// is the first byte of the fix format, for lengths under fixMax, and str8 is
+// the 8-bit length format, which only strings have.
+func msgpackHeader(buf *bytes.Buffer, n int, fix byte, fixMax int, str8 byte) {
+	// the 16 and 32-bit formats follow the 8-bit one for strings, and the fix
+	// format for arrays (0xdc) and maps (0xde)
+	var f16 byte
+
+	switch fix {
+	case 0xa0:
+		f16 = 0xda
+	case 0x90:
+		f16 = 0xdc
+	default:
+		f16 = 0xde
+	}
+
+	switch {
+	case n < fixMax:
+		buf.WriteByte(fix | byte(n))
+	case str8 != 0 && n <= math.MaxUint8:
+		buf.WriteByte(str8)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(f16)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(f16 + 1)
+		_ = binary.Write(buf, binary.BigEndian, uint32(n))
+	}
+}
+
+var errMsgpackShort = errors.New("unexpected end of msgpack payload")
+
+// msgpackDecoder decodes the subset of msgpack that msgpackEncode produces,
+// plus the other integer formats.
+type msgpackDecoder struct {
+	b []byte
+}
+
+func (d *msgpackDecoder) next(n int) ([]byte, error) {
+	if len(d.b) < n {
+		return nil, errMsgpackShort
+	}
+
+	b := d.b[:n]
+	d.b = d.b[n:]
+
+	return b, nil
+}
+
+func (d *msgpackDecoder) uint(n int) (uint64, error) {
+	b, err := d.next(n)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *msgpackDecoder) decode() (interface{}, error) {
+	t, err := d.next(1)
+	if err != nil {
+		return nil, err
+	}
+
+	c := t[0]
+
+	switch {
+	case c <= 0x7f:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0:
+		return d.str(int(c & 0x1f))
+	case c&0xf0 == 0x90:
+		return d.array(int(c & 0x0f))
+	case c&0xf0 == 0x80:
+		return d.object(int(c & 0x0f))
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+
+	case 0xca:
+		u, err := d.uint(4)
+		return float64(math.Float32frombits(uint32(u))), err
+	case 0xcb:
+		u, err := d.uint(8)
+		return math.Float64frombits(u), err
+
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u, err := d.uint(1 << (c - 0xcc))
+		return u, err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		n := 1 << (c - 0xd0)
+
+		u, err := d.uint(n)
+		if err != nil {
+			return nil, err
+		}
+
+		// sign extend
+		shift := uint(64 - 8*n)
+
+		return int64(u<<shift) >> shift, nil
+
+	case 0xd9, 0xda, 0xdb:
+		n, err := d.uint(1 << (c - 0xd9))
+		if err != nil {
+			return nil, err
+		}
+
+		return d.str(int(n))
+	case 0xdc, 0xdd:
+		n, err := d.uint(2 << (c - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+
+		return d.array(int(n))
+	case 0xde, 0xdf:
+		n, err := d.uint(2 << (c - 0xde))
+		if err != nil {
+			return nil, err
+		}
+
+		return d.object(int(n))
+	}
+
+	return nil, fmt.Errorf("unsupported msgpack type 0x%02x", c)
+}
+
+func (d *msgpackDecoder) str(n int) (string, error) {
+	b, err := d.next(n)
+	return string(b), err
+}
+
+func (d *msgpackDecoder) array(n int) ([]interface{}, error) {
+	if n > len(d.b) {
+		return nil, errMsgpackShort
+	}
+
+	a := make([]interface{}, n)
+
+	for i := range a {
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+
+		a[i] = v
+	}
+
+	return a, nil
+}
+
+func (d *msgpackDecoder) object(n int) (map[string]interface{}, error) {
+	if n > len(d.b) {
+		return nil, errMsgpackShort
+	}
+
+	m := make(map[string]interface{}, n)
+
+	for i := 0; i < n; i++ {
+		kv, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+
+		k, ok := kv.(string)
+		if !ok {
+			return nil, fmt.Errorf("msgpack map key is %T, not a string", kv)
+		}
+
+		v, err := d.decode()
+		if err != nil {
+			return nil, err
+		}
+
+		m[k] = v
+	}
+
+	return m, nil
+}
@@ -0,0 +1,54 @@
+package workqueue
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMsgpackCodec(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{name: "message", json: `{"type":"message","channel":"C123","user":"U123","text":"hello","ts":"1588000000.000200","blocks":[{"type":"rich_text","elements":[]}],"hidden":false,"edited":null}`},
+		{name: "numbers", json: `{"a":0,"b":127,"c":128,"d":-32,"e":-33,"f":1588000000,"g":-9007199254740993,"h":1.5,"i":1e300}`},
+		{name: "long_string", json: `{"text":"` + strings.Repeat("x", 70000) + `"}`},
+		{name: "big_object", json: `{"list":[` + strings.TrimSuffix(strings.Repeat(`{"k":"v"},`, 20), ",") + `]}`},
+		{name: "scalar", json: `"hi"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := MsgpackCodec.Marshal([]byte(tt.json))
+			if err != nil {
+				t.Fatalf("Marshal() unexpected error: %v", err)
+			}
+
+			got, err := MsgpackCodec.Unmarshal(payload)
+			if err != nil {
+				t.Fatalf("Unmarshal() unexpected error: %v", err)
+			}
+
+			var want, have interface{}
+
+			if err := json.Unmarshal([]byte(tt.json), &want); err != nil {
+				t.Fatalf("failed to unmarshal test JSON: %v", err)
+			}
+
+			if err := json.Unmarshal(got, &have); err != nil {
+				t.Fatalf("failed to unmarshal round-tripped JSON %s: %v", got, err)
+			}
+
+			if diff := cmp.Diff(want, have); diff != "" {
+				t.Fatalf("round trip mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, err := MsgpackCodec.Unmarshal([]byte{0x92, 0x01}); err == nil {
+		t.Fatal("Unmarshal() with a truncated payload did not error")
+	}
+}
@@ -0,0 +1,108 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// redisOncePrefix is the prefix of the keys holding each Once key's
+	// state: a random token while its function is running, or onceDone
+	redisOncePrefix = "workqueue:once:"
+
+	onceDone = "done"
+
+	// onceLockTTL is how long a key is held while its function runs, if the
+	// context has no deadline
+	onceLockTTL = time.Minute
+
+	// onceFinishTimeout is how long marking a key done, or releasing it, may
+	// take. The handler's context has often expired by then.
+	onceFinishTimeout = 5 * time.Second
+)
+
+// markOnceDone sets the key at KEYS[1] to ARGV[2], expiring in ARGV[3]
+// milliseconds, if it's held by ARGV[1].
+var markOnceDone = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+	return 1
+end
+
+return 0
+`)
+
+// once runs fn, unless key was marked done in the last ttl. It returns
+// whether fn was run, and ErrLockHeld if fn is being run for the key by
+// someone else.
+//
+// The running and done states are the same Redis key, so marking the key done
+// also releases it, and nobody can see it as neither.
+func once(ctx context.Context, rc redis.UniversalClient, key string, ttl time.Duration, fn func() error) (bool, error) {
+	if rc == nil {
+		return false, errors.New("workqueue has no Redis client")
+	}
+
+	if ttl.Milliseconds() <= 0 {
+		return false, fmt.Errorf("once TTL must be at least 1ms, got %s", ttl)
+	}
+
+	rkey := redisOncePrefix + key
+
+	token, err := newToken()
+	if err != nil {
+		return false, err
+	}
+
+	lttl := onceLockTTL
+
+	if d, ok := ctx.Deadline(); ok && time.Until(d) > time.Millisecond {
+		lttl = time.Until(d)
+	}
+
+	ok, err := rc.SetNX(ctx, rkey, token, lttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim %s: %w", key, err)
+	}
+
+	if !ok {
+		v, err := rc.Get(ctx, rkey).Result()
+		if err != nil && !errors.Is(err, redis.Nil) {
+			return false, fmt.Errorf("failed to check whether %s is done: %w", key, err)
+		}
+
+		if v == onceDone {
+			return false, nil
+		}
+
+		return false, ErrLockHeld
+	}
+
+	fnErr := fn()
+
+	// fn may have used up the handler's deadline, which mustn't keep the key
+	// from being marked done or released
+	fctx, cancel := context.WithTimeout(context.Background(), onceFinishTimeout)
+	defer cancel()
+
+	if fnErr != nil {
+		_ = unlockLock.Run(fctx, rc, []string{rkey}, token).Err()
+		return true, fnErr
+	}
+
+	n, err := markOnceDone.Run(fctx, rc, []string{rkey}, token, onceDone, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return true, fmt.Errorf("failed to mark %s done: %w", key, err)
+	}
+
+	// the key expired while fn ran, and may be someone else's now
+	if n != 1 {
+		return true, fmt.Errorf("failed to mark %s done, as its lock expired: %w", key, ErrLockHeld)
+	}
+
+	return true, nil
+}
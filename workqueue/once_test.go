@@ -0,0 +1,132 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestRedis(t *testing.T) (*miniredis.Miniredis, redis.UniversalClient) {
+	t.Helper()
+
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+
+	t.Cleanup(s.Close)
+
+	rc := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { _ = rc.Close() })
+
+	return s, rc
+}
+
+func Test_once_done(t *testing.T) {
+	s, rc := newTestRedis(t)
+
+	var runs int
+	fn := func() error { runs++; return nil }
+
+	ran, err := once(context.Background(), rc, "welcome:U123", time.Hour, fn)
+	if err != nil || !ran {
+		t.Fatalf("once() = %t, %v; want true, <nil>", ran, err)
+	}
+
+	if v, _ := s.Get(redisOncePrefix + "welcome:U123"); v != onceDone {
+		t.Fatalf("key = %q, want %q", v, onceDone)
+	}
+
+	if ttl := s.TTL(redisOncePrefix + "welcome:U123"); ttl != time.Hour {
+		t.Fatalf("key TTL = %s, want %s", ttl, time.Hour)
+	}
+
+	ran, err = once(context.Background(), rc, "welcome:U123", time.Hour, fn)
+	if err != nil || ran {
+		t.Fatalf("once() = %t, %v; want false, <nil> once done", ran, err)
+	}
+
+	if runs != 1 {
+		t.Fatalf("fn ran %d times, want 1", runs)
+	}
+}
+
+func Test_once_expiredContext(t *testing.T) {
+	s, rc := newTestRedis(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	// fn uses up the handler's deadline
+	ran, err := once(ctx, rc, "welcome:U123", time.Hour, func() error {
+		cancel()
+		return nil
+	})
+	if err != nil || !ran {
+		t.Fatalf("once() = %t, %v; want true, <nil>", ran, err)
+	}
+
+	if v, _ := s.Get(redisOncePrefix + "welcome:U123"); v != onceDone {
+		t.Fatalf("key = %q, want %q", v, onceDone)
+	}
+}
+
+func Test_once_inProgress(t *testing.T) {
+	s, rc := newTestRedis(t)
+
+	if err := s.Set(redisOncePrefix+"welcome:U123", "someone-elses-token"); err != nil {
+		t.Fatalf("failed to set key: %v", err)
+	}
+
+	ran, err := once(context.Background(), rc, "welcome:U123", time.Hour, func() error {
+		t.Fatal("fn ran while running elsewhere")
+		return nil
+	})
+	if !errors.Is(err, ErrLockHeld) || ran {
+		t.Fatalf("once() = %t, %v; want false, ErrLockHeld", ran, err)
+	}
+}
+
+func Test_once_failure(t *testing.T) {
+	s, rc := newTestRedis(t)
+
+	fnErr := errors.New("slack is down")
+
+	ran, err := once(context.Background(), rc, "welcome:U123", time.Hour, func() error { return fnErr })
+	if !errors.Is(err, fnErr) || !ran {
+		t.Fatalf("once() = %t, %v; want true, %v", ran, err, fnErr)
+	}
+
+	if s.Exists(redisOncePrefix + "welcome:U123") {
+		t.Fatal("key still held after fn failed")
+	}
+
+	// a retry runs it again
+	ran, err = once(context.Background(), rc, "welcome:U123", time.Hour, func() error { return nil })
+	if err != nil || !ran {
+		t.Fatalf("once() = %t, %v; want true, <nil> on retry", ran, err)
+	}
+}
+
+func Test_once_lockLost(t *testing.T) {
+	s, rc := newTestRedis(t)
+
+	key := redisOncePrefix + "welcome:U123"
+
+	// the key expires while fn runs, and another consumer claims it
+	ran, err := once(context.Background(), rc, "welcome:U123", time.Hour, func() error {
+		s.Del(key)
+		return s.Set(key, "someone-elses-token")
+	})
+	if !errors.Is(err, ErrLockHeld) || !ran {
+		t.Fatalf("once() = %t, %v; want true, ErrLockHeld", ran, err)
+	}
+
+	if v, _ := s.Get(key); v != "someone-elses-token" {
+		t.Fatalf("key = %q, want the other consumer's token kept", v)
+	}
+}
@@ -0,0 +1,189 @@
+package workqueue
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// redisPausedKey is the set of paused streams, which workers load when
+	// they start, and whenever they're told it changed.
+	redisPausedKey = "workqueue:paused"
+
+	// redisControlChannel is the pub/sub channel workers are told on when
+	// the paused streams change.
+	redisControlChannel = "workqueue:control"
+
+	// pauseReloadInterval is how often workers reload the paused streams
+	// anyway, in case they missed being told while reconnecting.
+	pauseReloadInterval = 30 * time.Second
+)
+
+// Pause stops every worker consuming from the stream until it's resumed,
+// including new workers. Pausing a stream by its event's name also pauses
+// its priority variants. Workers finish what they already read, and stop
+// reading within the blocking timeout. Messages published meanwhile wait in
+// the stream.
+func (i *I) Pause(ctx context.Context, stream string) error {
+	if err := i.t.setPaused(ctx, stream, true); err != nil {
+		return fmt.Errorf("failed to pause %s: %w", stream, err)
+	}
+
+	return nil
+}
+
+// Resume undoes Pause for the stream.
+func (i *I) Resume(ctx context.Context, stream string) error {
+	if err := i.t.setPaused(ctx, stream, false); err != nil {
+		return fmt.Errorf("failed to resume %s: %w", stream, err)
+	}
+
+	return nil
+}
+
+// Paused returns the names the paused streams were paused with, sorted.
+func (i *I) Paused(ctx context.Context) ([]string, error) {
+	streams, err := i.t.paused(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paused streams: %w", err)
+	}
+
+	sort.Strings(streams)
+
+	return streams, nil
+}
+
+// watchPauses keeps the pause state up to date until stop is closed.
+func (i *I) watchPauses(stop <-chan struct{}) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changed := i.t.watchPaused(ctx)
+
+	t := time.NewTicker(pauseReloadInterval)
+	defer t.Stop()
+
+	for {
+		streams, err := i.t.paused(ctx)
+		if err != nil {
+			i.l.Error().
+				Err(err).
+				Msg("failed to load paused streams")
+		} else {
+			i.ps.set(streams, i.m)
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-changed:
+		case <-t.C:
+		}
+	}
+}
+
+// pauseState is which streams the consumers leave alone, shared by all of an
+// I's consumers. A nil *pauseState has nothing paused.
+type pauseState struct {
+	mu     sync.Mutex
+	paused map[string]struct{}
+
+	// changed is closed, and replaced, when paused changes
+	changed chan struct{}
+}
+
+func newPauseState() *pauseState {
+	return &pauseState{
+		paused:  make(map[string]struct{}),
+		changed: make(chan struct{}),
+	}
+}
+
+// set replaces the paused streams, recording the changes in m.
+func (s *pauseState) set(streams []string, m *Metrics) {
+	paused := make(map[string]struct{}, len(streams))
+
+	for _, stream := range streams {
+		paused[stream] = struct{}{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	diff := len(paused) != len(s.paused)
+
+	for stream := range paused {
+		if _, ok := s.paused[stream]; !ok {
+			m.setPaused(stream, true)
+			diff = true
+		}
+	}
+
+	for stream := range s.paused {
+		if _, ok := paused[stream]; !ok {
+			m.setPaused(stream, false)
+		}
+	}
+
+	if !diff {
+		return
+	}
+
+	s.paused = paused
+
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// isPaused reports whether the stream, or the event stream it's a priority
+// variant of, is paused.
+func (s *pauseState) isPaused(stream string) bool {
+	if s == nil {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pausedLocked(stream)
+}
+
+func (s *pauseState) pausedLocked(stream string) bool {
+	if _, ok := s.paused[stream]; ok {
+		return true
+	}
+
+	for _, p := range []Priority{PriorityHigh, PriorityLow} {
+		if base := strings.TrimSuffix(stream, "_"+p.String()); base != stream {
+			_, ok := s.paused[base]
+			return ok
+		}
+	}
+
+	return false
+}
+
+// active returns the streams that aren't paused, and a channel that's closed
+// the next time the paused streams change.
+func (s *pauseState) active(streams []string) ([]string, <-chan struct{}) {
+	if s == nil {
+		return streams, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	active := make([]string, 0, len(streams))
+
+	for _, stream := range streams {
+		if !s.pausedLocked(stream) {
+			active = append(active, stream)
+		}
+	}
+
+	return active, s.changed
+}
@@ -0,0 +1,83 @@
+package workqueue
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_pauseState(t *testing.T) {
+	ps := newPauseState()
+	ps.set([]string{"slack_message_public", "slack_team_join_low"}, nil)
+
+	tests := []struct {
+		stream string
+		want   bool
+	}{
+		{"slack_message_public", true},
+		{"slack_message_public_high", true},
+		{"slack_message_public_low", true},
+		{"slack_team_join", false},
+		{"slack_team_join_low", true},
+		{"slack_reaction_added", false},
+	}
+
+	for _, tt := range tests {
+		if got := ps.isPaused(tt.stream); got != tt.want {
+			t.Errorf("isPaused(%q) = %t, want %t", tt.stream, got, tt.want)
+		}
+	}
+
+	active, changed := ps.active([]string{"slack_message_public_high", "slack_team_join"})
+
+	if len(active) != 1 || active[0] != "slack_team_join" {
+		t.Fatalf("active() = %q, want [slack_team_join]", active)
+	}
+
+	ps.set(nil, nil)
+
+	select {
+	case <-changed:
+	default:
+		t.Fatal("active() channel wasn't closed when the paused streams changed")
+	}
+
+	var nilPS *pauseState
+
+	if nilPS.isPaused("slack_message_public") {
+		t.Fatal("nil pauseState isPaused() = true")
+	}
+}
+
+func TestI_Pause(t *testing.T) {
+	ctx := context.Background()
+
+	q, err := New(Config{Backend: BackendMemory})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	if err := q.Pause(ctx, DefaultDeadLetterStream); err != nil {
+		t.Fatalf("Pause() unexpected error: %v", err)
+	}
+
+	if paused, _ := q.Paused(ctx); len(paused) != 1 || paused[0] != DefaultDeadLetterStream {
+		t.Fatalf("Paused() = %q, want [%s]", paused, DefaultDeadLetterStream)
+	}
+
+	stats, err := q.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats() unexpected error: %v", err)
+	}
+
+	if len(stats) != 1 || !stats[0].Paused {
+		t.Fatalf("Stats() = %+v, want the dead-letter stream paused", stats)
+	}
+
+	if err := q.Resume(ctx, DefaultDeadLetterStream); err != nil {
+		t.Fatalf("Resume() unexpected error: %v", err)
+	}
+
+	if paused, _ := q.Paused(ctx); len(paused) != 0 {
+		t.Fatalf("Paused() after Resume() = %q, want none", paused)
+	}
+}
@@ -0,0 +1,147 @@
+package workqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Priority is the tier an event is published at. Each tier has its own stream,
+// and consumers drain the higher priority streams before the lower ones.
+type Priority uint8
+
+const (
+	// PriorityNormal is the default priority, for ordinary events.
+	PriorityNormal Priority = iota
+
+	// PriorityHigh is for events that shouldn't wait behind a backlog of
+	// ordinary ones, like admin commands and moderation events.
+	PriorityHigh
+
+	// PriorityLow is for events that can wait until nothing else is being
+	// handled.
+	PriorityLow
+)
+
+// priorities are all the tiers, from highest to lowest
+var priorities = [...]Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityLow:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// rank returns the index of p in priorities.
+func (p Priority) rank() int {
+	switch p {
+	case PriorityHigh:
+		return 0
+	case PriorityLow:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// priorityStream returns the stream events for stream are published to at p.
+// Normal priority events use the stream itself, so existing streams keep
+// working.
+func priorityStream(stream string, p Priority) string {
+	if p == PriorityNormal {
+		return stream
+	}
+
+	return stream + "_" + p.String()
+}
+
+// maxPriorityWait is the longest a message waits for higher priority ones to
+// finish, so a steady stream of them can't starve it
+const maxPriorityWait = time.Second
+
+// priorityGate tracks the messages in flight at each priority, so handlers can
+// wait for higher priority ones to finish before starting.
+type priorityGate struct {
+	mu       sync.Mutex
+	inflight [len(priorities)]int
+
+	// changed is closed, and replaced, whenever a message leaves, to wake the
+	// ones waiting to enter
+	changed chan struct{}
+}
+
+// enter waits until no messages with a higher priority than p are in flight,
+// or until maxPriorityWait has passed, and then counts a message at p as in
+// flight. Checking and entering happen under the same lock, so nothing slips
+// in between them. It returns ctx.Err(), without entering, if ctx is done
+// first.
+func (g *priorityGate) enter(ctx context.Context, p Priority) error {
+	t := time.NewTimer(maxPriorityWait)
+	defer t.Stop()
+
+	var waited bool
+
+	for {
+		g.mu.Lock()
+
+		if waited || !g.busyLocked(p) {
+			g.inflight[p.rank()]++
+			g.mu.Unlock()
+
+			return nil
+		}
+
+		if g.changed == nil {
+			g.changed = make(chan struct{})
+		}
+
+		changed := g.changed
+
+		g.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-t.C:
+			waited = true
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// leave counts a message at p as no longer in flight.
+func (g *priorityGate) leave(p Priority) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.inflight[p.rank()]--
+
+	if g.changed != nil {
+		close(g.changed)
+		g.changed = nil
+	}
+}
+
+// busy returns whether any messages with a higher priority than p are in
+// flight.
+func (g *priorityGate) busy(p Priority) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.busyLocked(p)
+}
+
+func (g *priorityGate) busyLocked(p Priority) bool {
+	for r := 0; r < p.rank(); r++ {
+		if g.inflight[r] > 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,116 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func Test_priorityStream(t *testing.T) {
+	tests := []struct {
+		p    Priority
+		want string
+	}{
+		{p: PriorityNormal, want: "slack_message_public"},
+		{p: PriorityHigh, want: "slack_message_public_high"},
+		{p: PriorityLow, want: "slack_message_public_low"},
+	}
+
+	for _, tt := range tests {
+		if got := priorityStream(slackPublicMessage, tt.p); got != tt.want {
+			t.Errorf("priorityStream(%s) = %q, want %q", tt.p, got, tt.want)
+		}
+	}
+}
+
+func TestPriorityGate_busy(t *testing.T) {
+	var g priorityGate
+
+	if err := g.enter(context.Background(), PriorityNormal); err != nil {
+		t.Fatalf("enter(normal) unexpected error: %v", err)
+	}
+
+	if g.busy(PriorityHigh) {
+		t.Error("busy(high) = true with a normal message in flight, want false")
+	}
+
+	if g.busy(PriorityNormal) {
+		t.Error("busy(normal) = true with a normal message in flight, want false")
+	}
+
+	if !g.busy(PriorityLow) {
+		t.Error("busy(low) = false with a normal message in flight, want true")
+	}
+
+	g.leave(PriorityNormal)
+
+	if g.busy(PriorityLow) {
+		t.Error("busy(low) = true with nothing in flight, want false")
+	}
+}
+
+func TestPriorityGate_enter(t *testing.T) {
+	var g priorityGate
+
+	if err := g.enter(context.Background(), PriorityHigh); err != nil {
+		t.Fatalf("enter(high) unexpected error: %v", err)
+	}
+
+	entered := make(chan error, 1)
+
+	go func() { entered <- g.enter(context.Background(), PriorityLow) }()
+
+	select {
+	case err := <-entered:
+		t.Fatalf("enter(low) = %v with a high message in flight, want it to wait", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	g.leave(PriorityHigh)
+
+	select {
+	case err := <-entered:
+		if err != nil {
+			t.Fatalf("enter(low) unexpected error: %v", err)
+		}
+	case <-time.After(maxPriorityWait / 2):
+		t.Fatal("enter(low) still waiting after the high message left")
+	}
+
+	if g.busy(PriorityNormal) {
+		t.Fatal("busy(normal) = true with only a low message in flight")
+	}
+}
+
+func TestPriorityGate_enter_canceled(t *testing.T) {
+	var g priorityGate
+
+	if err := g.enter(context.Background(), PriorityHigh); err != nil {
+		t.Fatalf("enter(high) unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	entered := make(chan error, 1)
+
+	go func() { entered <- g.enter(ctx, PriorityNormal) }()
+
+	cancel()
+
+	select {
+	case err := <-entered:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("enter(normal) = %v, want context.Canceled", err)
+		}
+	case <-time.After(maxPriorityWait / 2):
+		t.Fatal("enter(normal) still waiting after ctx was canceled")
+	}
+
+	g.leave(PriorityHigh)
+
+	// the canceled message didn't enter, so nothing's in flight
+	if g.busy(PriorityLow) {
+		t.Fatal("busy(low) = true, want the canceled message not counted")
+	}
+}
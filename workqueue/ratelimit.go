@@ -0,0 +1,73 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// redisRateLimitPrefix is the prefix of the hashes holding each rate limit
+// key's token bucket
+const redisRateLimitPrefix = "workqueue:ratelimit:"
+
+// tokenBucket takes a token from the bucket at KEYS[1], refilling it first
+// for the time since it was last used. The bucket holds at most ARGV[1]
+// tokens, and refills completely over ARGV[2] milliseconds. ARGV[3] is the
+// current time in milliseconds. It returns 1 if a token was taken.
+//
+// The time comes from the caller, because scripts that call TIME can't be
+// replicated on older versions of Redis.
+var tokenBucket = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local b = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(b[1])
+local ts = tonumber(b[2])
+
+if tokens == nil or ts == nil then
+	tokens = limit
+	ts = now
+end
+
+tokens = math.min(limit, tokens + math.max(0, now - ts) * limit / window)
+
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HMSET", KEYS[1], "tokens", tostring(tokens), "ts", tostring(now))
+redis.call("PEXPIRE", KEYS[1], window)
+
+return allowed
+`)
+
+// allow takes a token from the bucket for key, which holds limit tokens and
+// refills over window.
+func allow(ctx context.Context, rc redis.UniversalClient, key string, limit int, window time.Duration) (bool, error) {
+	if rc == nil {
+		return false, errors.New("workqueue has no Redis client")
+	}
+
+	if limit <= 0 {
+		return false, fmt.Errorf("rate limit must be positive, got %d", limit)
+	}
+
+	wms := window.Milliseconds()
+	if wms <= 0 {
+		return false, fmt.Errorf("rate limit window must be at least 1ms, got %s", window)
+	}
+
+	n, err := tokenBucket.Run(ctx, rc, []string{redisRateLimitPrefix + key}, limit, wms, time.Now().UnixNano()/int64(time.Millisecond)).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rate limit %s: %w", key, err)
+	}
+
+	return n == 1, nil
+}
@@ -0,0 +1,95 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// replayGroupField is the message field naming the only consumer group
+	// that handles a replayed message. The others acknowledge it untouched.
+	replayGroupField = "replay_group"
+
+	// replayedFromField is the message field holding the ID of the message a
+	// replayed one was copied from.
+	replayedFromField = "replayed_from"
+
+	replayBatchSize = 100
+)
+
+// Replay publishes copies of the stream's messages with IDs from start to end,
+// inclusive, so they're handled again, but only by the consumer group named
+// group. That's for reprocessing events after a buggy handler was deployed,
+// without the other groups handling them twice. The start and end can be "-"
+// and "+" for the oldest and newest messages, or have the sequence number left
+// off to cover the whole millisecond.
+//
+// Messages replayed from the dead-letter stream are published to the stream
+// they failed on. Retry and dead-letter bookkeeping isn't copied, so replays
+// start with a clean slate. It returns how many messages were replayed.
+func (i *I) Replay(ctx context.Context, stream, start, end, group string) (int, error) {
+	if len(group) == 0 {
+		return 0, errors.New("consumer group to replay to must not be empty")
+	}
+
+	var replayed int
+
+	for {
+		msgs, err := i.t.rangeOf(ctx, stream, start, end, replayBatchSize)
+		if err != nil {
+			return replayed, fmt.Errorf("failed to read messages from %s: %w", stream, err)
+		}
+
+		for _, m := range msgs {
+			target, values := i.replayValues(m, group)
+
+			if err := i.enqueue(ctx, target, values); err != nil {
+				return replayed, fmt.Errorf("failed to replay message %s to %s: %w", m.ID, target, err)
+			}
+
+			replayed++
+		}
+
+		if len(msgs) < replayBatchSize {
+			return replayed, nil
+		}
+
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+}
+
+// replayValues returns the stream to replay the message to, and the values to
+// publish there.
+func (i *I) replayValues(m *message, group string) (string, map[string]interface{}) {
+	target := m.Stream
+
+	if m.Stream == i.deadLetter {
+		if s := stringValue(m, "dl_stream"); len(s) > 0 {
+			target = s
+		}
+	}
+
+	values := make(map[string]interface{}, len(m.Values)+2)
+
+	for k, v := range m.Values {
+		if k == attemptsField || k == doneField || strings.HasPrefix(k, "dl_") {
+			continue
+		}
+
+		values[k] = v
+	}
+
+	values[replayGroupField] = group
+	values[replayedFromField] = m.ID
+
+	return target, values
+}
+
+// replayedElsewhere reports whether the message was replayed for another
+// consumer group.
+func (i *I) replayedElsewhere(m *message) bool {
+	g := stringValue(m, replayGroupField)
+	return len(g) > 0 && g != i.copts.GroupName
+}
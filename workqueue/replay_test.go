@@ -0,0 +1,78 @@
+package workqueue
+
+import (
+	"context"
+	"testing"
+)
+
+func TestI_Replay(t *testing.T) {
+	ctx := context.Background()
+
+	q, err := New(Config{Backend: BackendMemory, ConsumerGroup: "gopher"})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	for _, values := range []map[string]interface{}{
+		{"event_id": "Ev1", attemptsField: "2", doneField: "a"},
+		{"event_id": "Ev2"},
+	} {
+		if err := q.enqueue(ctx, "slack_message", values); err != nil {
+			t.Fatalf("enqueue() unexpected error: %v", err)
+		}
+	}
+
+	dl := map[string]interface{}{"event_id": "Ev3", "dl_stream": "slack_team_join", "dl_error": "boom"}
+
+	if err := q.enqueue(ctx, DefaultDeadLetterStream, dl); err != nil {
+		t.Fatalf("enqueue() unexpected error: %v", err)
+	}
+
+	originals, _ := q.t.rangeOf(ctx, "slack_message", "-", "+", 10)
+
+	n, err := q.Replay(ctx, "slack_message", "-", originals[0].ID, "archiver")
+	if err != nil {
+		t.Fatalf("Replay() unexpected error: %v", err)
+	}
+
+	if n != 1 {
+		t.Fatalf("Replay() = %d, want 1", n)
+	}
+
+	msgs, _ := q.t.rangeOf(ctx, "slack_message", "("+originals[1].ID, "+", 10)
+
+	if len(msgs) != 1 {
+		t.Fatalf("got %d replayed messages, want 1", len(msgs))
+	}
+
+	want := map[string]interface{}{"event_id": "Ev1", replayGroupField: "archiver", replayedFromField: originals[0].ID}
+
+	for k, v := range want {
+		if msgs[0].Values[k] != v {
+			t.Errorf("replayed value %s = %v, want %v", k, msgs[0].Values[k], v)
+		}
+	}
+
+	if _, ok := msgs[0].Values[attemptsField]; ok {
+		t.Error("replayed message kept its attempts")
+	}
+
+	if !q.replayedElsewhere(msgs[0]) {
+		t.Error("replayedElsewhere() = false for a message replayed to another group")
+	}
+
+	// dead letters go back to the stream they failed on
+	if n, err := q.Replay(ctx, DefaultDeadLetterStream, "-", "+", "gopher"); err != nil || n != 1 {
+		t.Fatalf("Replay() of dead letters = %d, %v; want 1, <nil>", n, err)
+	}
+
+	msgs, _ = q.t.rangeOf(ctx, "slack_team_join", "-", "+", 10)
+
+	if len(msgs) != 1 || msgs[0].Values["dl_error"] != nil || msgs[0].Values["event_id"] != "Ev3" {
+		t.Fatalf("replayed dead letters = %+v, want Ev3 without dead-letter fields", msgs)
+	}
+
+	if q.replayedElsewhere(msgs[0]) {
+		t.Error("replayedElsewhere() = true for a message replayed to this group")
+	}
+}
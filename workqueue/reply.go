@@ -0,0 +1,208 @@
+package workqueue
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/slack-go/slack"
+	"github.com/valyala/fastjson"
+)
+
+// ErrNoReplyTarget is returned by the Context reply helpers when the event
+// doesn't say which channel or message it happened in.
+var ErrNoReplyTarget = errors.New("event has no channel or message to reply to")
+
+// replyTarget is where in Slack the event happened.
+type replyTarget struct {
+	channel  string
+	ts       string
+	threadTS string
+
+	// parentUserID is who started the thread, if it's in one
+	parentUserID string
+}
+
+// eventReplyTarget returns where the event happened, using whichever fields its
+// event type has them in.
+func eventReplyTarget(raw []byte) (replyTarget, error) {
+	v, err := fastjson.ParseBytes(raw)
+	if err != nil {
+		return replyTarget{}, fmt.Errorf("failed to parse event: %w", err)
+	}
+
+	t := replyTarget{
+		channel:      string(v.GetStringBytes("channel")),
+		ts:           string(v.GetStringBytes("ts")),
+		threadTS:     string(v.GetStringBytes("thread_ts")),
+		parentUserID: string(v.GetStringBytes("parent_user_id")),
+	}
+
+	// message_changed, where the top-level ts is the edit's
+	if m := v.Get("message"); m != nil && m.Type() == fastjson.TypeObject {
+		if ts := m.GetStringBytes("ts"); len(ts) > 0 {
+			t.ts = string(ts)
+		}
+
+		t.threadTS = string(m.GetStringBytes("thread_ts"))
+		t.parentUserID = string(m.GetStringBytes("parent_user_id"))
+	}
+
+	// reaction_added and reaction_removed
+	if item := v.Get("item"); item != nil {
+		if len(t.channel) == 0 {
+			t.channel = string(item.GetStringBytes("channel"))
+		}
+
+		if len(t.ts) == 0 {
+			t.ts = string(item.GetStringBytes("ts"))
+		}
+	}
+
+	// file_shared
+	if len(t.channel) == 0 {
+		t.channel = string(v.GetStringBytes("channel_id"))
+	}
+
+	// interactions with a message
+	if c := v.Get("container"); c != nil {
+		if len(t.channel) == 0 {
+			t.channel = string(c.GetStringBytes("channel_id"))
+		}
+
+		if len(t.ts) == 0 {
+			t.ts = string(c.GetStringBytes("message_ts"))
+		}
+
+		if len(t.threadTS) == 0 {
+			t.threadTS = string(c.GetStringBytes("thread_ts"))
+		}
+	}
+
+	if len(t.channel) == 0 {
+		return replyTarget{}, ErrNoReplyTarget
+	}
+
+	return t, nil
+}
+
+// thread returns the thread replies go to: the event's thread if it's in one,
+// otherwise a new thread on the event's message.
+func (t replyTarget) thread() string {
+	if len(t.threadTS) > 0 {
+		return t.threadTS
+	}
+
+	return t.ts
+}
+
+func replyInThread(ctx Context, text string) error {
+	t, err := eventReplyTarget(ctx.RawEvent())
+	if err != nil {
+		return err
+	}
+
+	if len(t.thread()) == 0 {
+		return ErrNoReplyTarget
+	}
+
+	_, _, err = ctx.Slack().PostMessageContext(ctx, t.channel,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionTS(t.thread()),
+		slack.MsgOptionDisableLinkUnfurl(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to PostMessageContext: %w", err)
+	}
+
+	return nil
+}
+
+func replyEphemeral(ctx Context, userID, text string) error {
+	t, err := eventReplyTarget(ctx.RawEvent())
+	if err != nil {
+		return err
+	}
+
+	opts := []slack.MsgOption{slack.MsgOptionText(text, false)}
+
+	// keep it in the thread the user is looking at
+	if len(t.threadTS) > 0 {
+		opts = append(opts, slack.MsgOptionTS(t.threadTS))
+	}
+
+	if _, err = ctx.Slack().PostEphemeralContext(ctx, t.channel, userID, opts...); err != nil {
+		return fmt.Errorf("failed to PostEphemeralContext: %w", err)
+	}
+
+	return nil
+}
+
+func replyDM(ctx Context, userID, text string) error {
+	_, _, err := ctx.Slack().PostMessageContext(ctx, userID,
+		slack.MsgOptionText(text, false),
+		slack.MsgOptionDisableLinkUnfurl(),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to PostMessageContext: %w", err)
+	}
+
+	return nil
+}
+
+func react(ctx Context, emoji string) error {
+	t, err := eventReplyTarget(ctx.RawEvent())
+	if err != nil {
+		return err
+	}
+
+	if len(t.ts) == 0 {
+		return ErrNoReplyTarget
+	}
+
+	if err := ctx.Slack().AddReactionContext(ctx, emoji, slack.NewRefToMessage(t.channel, t.ts)); err != nil {
+		return fmt.Errorf("failed to AddReactionContext: %w", err)
+	}
+
+	return nil
+}
+
+// Each Context implementation has its own reply helpers, so the wrappers around
+// ctxer use their Slack client and deadline instead of the embedded ctxer's.
+
+// ReplyInThread satisfies Context.
+func (c ctxer) ReplyInThread(text string) error { return replyInThread(c, text) }
+
+// ReplyEphemeral satisfies Context.
+func (c ctxer) ReplyEphemeral(userID, text string) error { return replyEphemeral(c, userID, text) }
+
+// ReplyDM satisfies Context.
+func (c ctxer) ReplyDM(userID, text string) error { return replyDM(c, userID, text) }
+
+// React satisfies Context.
+func (c ctxer) React(emoji string) error { return react(c, emoji) }
+
+// ReplyInThread satisfies Context.
+func (c slackCtxer) ReplyInThread(text string) error { return replyInThread(c, text) }
+
+// ReplyEphemeral satisfies Context.
+func (c slackCtxer) ReplyEphemeral(userID, text string) error { return replyEphemeral(c, userID, text) }
+
+// ReplyDM satisfies Context.
+func (c slackCtxer) ReplyDM(userID, text string) error { return replyDM(c, userID, text) }
+
+// React satisfies Context.
+func (c slackCtxer) React(emoji string) error { return react(c, emoji) }
+
+// ReplyInThread satisfies Context.
+func (c timeoutCtxer) ReplyInThread(text string) error { return replyInThread(c, text) }
+
+// ReplyEphemeral satisfies Context.
+func (c timeoutCtxer) ReplyEphemeral(userID, text string) error {
+	return replyEphemeral(c, userID, text)
+}
+
+// ReplyDM satisfies Context.
+func (c timeoutCtxer) ReplyDM(userID, text string) error { return replyDM(c, userID, text) }
+
+// React satisfies Context.
+func (c timeoutCtxer) React(emoji string) error { return react(c, emoji) }
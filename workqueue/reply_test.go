@@ -0,0 +1,64 @@
+package workqueue
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_eventReplyTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want replyTarget
+		err  error
+	}{
+		{
+			name: "message",
+			raw:  `{"type":"message","channel":"C123","ts":"1234.5678"}`,
+			want: replyTarget{channel: "C123", ts: "1234.5678"},
+		},
+		{
+			name: "thread_reply",
+			raw:  `{"type":"message","channel":"C123","ts":"1234.5678","thread_ts":"1234.0000","parent_user_id":"U123"}`,
+			want: replyTarget{channel: "C123", ts: "1234.5678", threadTS: "1234.0000", parentUserID: "U123"},
+		},
+		{
+			name: "message_changed",
+			raw:  `{"type":"message","subtype":"message_changed","channel":"C123","ts":"1234.9999","message":{"ts":"1234.5678","text":"edited"}}`,
+			want: replyTarget{channel: "C123", ts: "1234.5678"},
+		},
+		{
+			name: "reaction",
+			raw:  `{"type":"reaction_added","item":{"type":"message","channel":"C123","ts":"1234.5678"}}`,
+			want: replyTarget{channel: "C123", ts: "1234.5678"},
+		},
+		{
+			name: "file_shared",
+			raw:  `{"type":"file_shared","channel_id":"C123","file_id":"F123"}`,
+			want: replyTarget{channel: "C123"},
+		},
+		{
+			name: "block_actions",
+			raw:  `{"type":"block_actions","channel":{"id":"C123"},"container":{"type":"message","channel_id":"C123","message_ts":"1234.5678"}}`,
+			want: replyTarget{channel: "C123", ts: "1234.5678"},
+		},
+		{
+			name: "team_join",
+			raw:  `{"type":"team_join","user":{"id":"U123"}}`,
+			err:  ErrNoReplyTarget,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := eventReplyTarget([]byte(tt.raw))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("eventReplyTarget() error = %v, want %v", err, tt.err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("eventReplyTarget() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package workqueue
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// The kinds of failure an ErrorReporter is told about, in the "kind" tag.
+const (
+	// FailureHandler is a handler failing with an error it won't be retried
+	// for, or running out of attempts.
+	FailureHandler = "handler"
+
+	// FailurePanic is a handler panicking. The ErrorReporter is called from
+	// the deferred function that recovered it, so it's still on the stack.
+	FailurePanic = "panic"
+
+	// FailureParse is a message or event that can't be parsed.
+	FailureParse = "parse"
+)
+
+// ErrorReporter is told about the failures someone should look at, instead of
+// them only being logged. The tags say where it failed, like the "stream" and
+// "handler", and the "kind" of failure. ReportError shouldn't block.
+// Generally this is implemented by an *errreport.Sentry.
+type ErrorReporter interface {
+	ReportError(err error, tags map[string]string)
+}
+
+// PanicError is the error for a handler that panicked.
+type PanicError struct {
+	// Value is what the handler panicked with.
+	Value interface{}
+
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("handler panicked: %v", e.Value)
+}
+
+// reportError reports err to the ErrorReporter, if there is one.
+func (i *I) reportError(err error, m *message, handler, kind string) {
+	if i.er == nil {
+		return
+	}
+
+	i.er.ReportError(err, map[string]string{
+		"kind":          kind,
+		"handler":       handler,
+		"redis_stream":  m.Stream,
+		"redis_message": m.ID,
+	})
+}
+
+// callHandler invokes the handler, turning a panic into a *PanicError that's
+// reported before it's returned, and an error from Retryable or Discard into
+// the shouldRetry or discarded it stands for.
+func (i *I) callHandler(ctx Context, m *message, name string, invoke invokeFunc) (shouldRetry, discarded bool, err error) {
+	defer func() {
+		v := recover()
+		if v == nil {
+			return
+		}
+
+		pe := &PanicError{Value: v, Stack: debug.Stack()}
+		i.reportError(pe, m, name, FailurePanic)
+
+		shouldRetry, discarded, err = false, false, pe
+	}()
+
+	shouldRetry, discarded, err = invoke(ctx)
+
+	// errors from Retryable and Discard say the same as the bools, so
+	// handlers with either signature can use them
+	return shouldRetry || IsRetryable(err), discarded || IsDiscarded(err), err
+}
@@ -0,0 +1,92 @@
+package workqueue
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type testReporter struct {
+	errs []error
+	tags []map[string]string
+}
+
+func (r *testReporter) ReportError(err error, tags map[string]string) {
+	r.errs = append(r.errs, err)
+	r.tags = append(r.tags, tags)
+}
+
+func TestI_callHandler(t *testing.T) {
+	r := &testReporter{}
+	i := &I{er: r}
+	m := &message{ID: "1-0", Stream: slackPublicMessage}
+
+	_, _, err := i.callHandler(ctxer{}, m, "message", func(Context) (bool, bool, error) {
+		return true, false, errors.New("failed")
+	})
+
+	if err == nil || err.Error() != "failed" {
+		t.Fatalf("callHandler() error = %v, want failed", err)
+	}
+
+	if len(r.errs) != 0 {
+		t.Fatalf("handler error reported by callHandler: %v", r.errs)
+	}
+
+	shouldRetry, _, err := i.callHandler(ctxer{}, m, "message", func(Context) (bool, bool, error) {
+		var m map[string]string
+		m["boom"] = "boom"
+		return true, false, nil
+	})
+
+	var pe *PanicError
+	if !errors.As(err, &pe) {
+		t.Fatalf("callHandler() error = %v, want a *PanicError", err)
+	}
+
+	if shouldRetry {
+		t.Error("panicked handler should not be retried")
+	}
+
+	if !strings.Contains(string(pe.Stack), "TestI_callHandler") {
+		t.Errorf("stack doesn't include the handler:\n%s", pe.Stack)
+	}
+
+	if len(r.errs) != 1 || r.errs[0] != err {
+		t.Fatalf("reported %v, want the panic", r.errs)
+	}
+
+	want := map[string]string{
+		"kind":          FailurePanic,
+		"handler":       "message",
+		"redis_stream":  slackPublicMessage,
+		"redis_message": "1-0",
+	}
+
+	for k, v := range want {
+		if got := r.tags[0][k]; got != v {
+			t.Errorf("tag %s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestI_callHandler_typedErrors(t *testing.T) {
+	i := &I{}
+	m := &message{ID: "1-0", Stream: slackPublicMessage}
+
+	shouldRetry, discarded, _ := i.callHandler(ctxer{}, m, "message", func(Context) (bool, bool, error) {
+		return false, false, Retryable(errors.New("failed"))
+	})
+
+	if !shouldRetry || discarded {
+		t.Fatalf("callHandler() = (%t, %t), want a retry", shouldRetry, discarded)
+	}
+
+	shouldRetry, discarded, _ = i.callHandler(ctxer{}, m, "message", func(Context) (bool, bool, error) {
+		return false, false, Discard(errors.New("not for us"))
+	})
+
+	if shouldRetry || !discarded {
+		t.Fatalf("callHandler() = (%t, %t), want a discard", shouldRetry, discarded)
+	}
+}
@@ -0,0 +1,72 @@
+package workqueue
+
+import "errors"
+
+// retryableError is an error the message should be retried for
+type retryableError struct {
+	err error
+}
+
+func (e *retryableError) Error() string { return e.err.Error() }
+func (e *retryableError) Unwrap() error { return e.err }
+
+// discardError is an error the message should be discarded for
+type discardError struct {
+	err error
+}
+
+func (e *discardError) Error() string { return e.err.Error() }
+func (e *discardError) Unwrap() error { return e.err }
+
+// Retryable wraps err so the handler's message is retried, after a backoff,
+// according to the stream's RetryPolicy. It returns nil if err is nil.
+func Retryable(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &retryableError{err: err}
+}
+
+// Discard wraps err so the handler's message is discarded, with err only being
+// logged as informational. It returns nil if err is nil.
+func Discard(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &discardError{err: err}
+}
+
+// IsRetryable returns whether err, or any error it wraps, is from Retryable.
+func IsRetryable(err error) bool {
+	var re *retryableError
+	return errors.As(err, &re)
+}
+
+// IsDiscarded returns whether err, or any error it wraps, is from Discard.
+func IsDiscarded(err error) bool {
+	var de *discardError
+	return errors.As(err, &de)
+}
+
+// Result returns the (shouldRetry, discarded, err) a handler with the older
+// signature would for err. Any other error goes to the dead-letter stream.
+func Result(err error) (shouldRetry, discarded bool, _ error) {
+	return IsRetryable(err), IsDiscarded(err), err
+}
+
+// ResultError returns the error a handler with the newer, error-only,
+// signature would for the (shouldRetry, discarded, err) of an older one.
+func ResultError(shouldRetry, discarded bool, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case discarded:
+		return Discard(err)
+	case shouldRetry:
+		return Retryable(err)
+	default:
+		return err
+	}
+}
@@ -0,0 +1,64 @@
+package workqueue
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestResult(t *testing.T) {
+	failed := errors.New("failed")
+
+	tests := []struct {
+		name        string
+		err         error
+		shouldRetry bool
+		discarded   bool
+	}{
+		{name: "nil"},
+		{name: "plain", err: failed},
+		{name: "retryable", err: Retryable(failed), shouldRetry: true},
+		{name: "discard", err: Discard(failed), discarded: true},
+		{name: "wrapped", err: fmt.Errorf("failed to post: %w", Retryable(failed)), shouldRetry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shouldRetry, discarded, err := Result(tt.err)
+
+			if shouldRetry != tt.shouldRetry || discarded != tt.discarded {
+				t.Fatalf("Result() = (%t, %t), want (%t, %t)", shouldRetry, discarded, tt.shouldRetry, tt.discarded)
+			}
+
+			if tt.err != nil && !errors.Is(err, failed) {
+				t.Fatalf("Result() error = %v, want it to wrap %v", err, failed)
+			}
+
+			if tt.err != nil && err.Error() != tt.err.Error() {
+				t.Fatalf("Result() error = %q, want %q", err, tt.err)
+			}
+
+			if got := ResultError(shouldRetry, discarded, err); IsRetryable(got) != tt.shouldRetry || IsDiscarded(got) != tt.discarded {
+				t.Fatalf("ResultError() = %v, doesn't round trip", got)
+			}
+		})
+	}
+
+	if Retryable(nil) != nil || Discard(nil) != nil {
+		t.Fatal("wrapping a nil error should return nil")
+	}
+}
+
+func TestMessageHandlerV2_Handler(t *testing.T) {
+	fn := MessageHandlerV2(func(_ Context, me *slackevents.MessageEvent) error {
+		return Retryable(errors.New(me.Text))
+	}).Handler()
+
+	shouldRetry, discarded, err := fn(ctxer{}, &slackevents.MessageEvent{Text: "failed"})
+
+	if !shouldRetry || discarded || err == nil || err.Error() != "failed" {
+		t.Fatalf("Handler() = (%t, %t, %v), want (true, false, failed)", shouldRetry, discarded, err)
+	}
+}
@@ -0,0 +1,119 @@
+package workqueue
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy configures how failed messages are retried.
+type RetryPolicy struct {
+	// MaxAttempts is how many times a message is given to a handler before
+	// it's considered permanently failed, and sent to the dead-letter stream.
+	MaxAttempts int
+
+	// BackoffBase is how long to wait before the first retry. Each retry after
+	// that waits twice as long as the one before it.
+	BackoffBase time.Duration
+
+	// BackoffMax is the longest to wait between retries.
+	BackoffMax time.Duration
+
+	// Jitter is the fraction, between 0 and 1, of each backoff that's
+	// randomized. This avoids messages that failed together being retried
+	// together.
+	Jitter float64
+}
+
+// DefaultRetryPolicy is the RetryPolicy used when one isn't configured.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BackoffBase: time.Second,
+	BackoffMax:  5 * time.Minute,
+	Jitter:      0.2,
+}
+
+func (r RetryPolicy) withDefaults() RetryPolicy {
+	if r.MaxAttempts <= 0 {
+		r.MaxAttempts = DefaultRetryPolicy.MaxAttempts
+	}
+
+	if r.BackoffBase <= 0 {
+		r.BackoffBase = DefaultRetryPolicy.BackoffBase
+	}
+
+	if r.BackoffMax <= 0 {
+		r.BackoffMax = DefaultRetryPolicy.BackoffMax
+	}
+
+	if r.Jitter < 0 || r.Jitter > 1 {
+		r.Jitter = DefaultRetryPolicy.Jitter
+	}
+
+	return r
+}
+
+// backoff returns how long to wait before the next attempt, after attempt
+// attempts have failed.
+func (r RetryPolicy) backoff(attempt int64) time.Duration {
+	d := r.BackoffBase
+
+	for n := int64(1); n < attempt && d < r.BackoffMax; n++ {
+		d *= 2
+	}
+
+	if d > r.BackoffMax {
+		d = r.BackoffMax
+	}
+
+	if r.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * r.Jitter * float64(d)) // #nosec G404 -- jitter doesn't need to be secure
+	}
+
+	return d
+}
+
+// attemptsField is the message field holding how many attempts were made
+// before the message was rescheduled
+const attemptsField = "attempts"
+
+// priorAttempts returns how many attempts were made for the message before it
+// was last rescheduled.
+func priorAttempts(m *message) int64 {
+	v, ok := m.Values[attemptsField].(string)
+	if !ok {
+		return 0
+	}
+
+	n, _ := strconv.ParseInt(v, 10, 64)
+
+	return n
+}
+
+// scheduleRetry adds the message to the schedule, to be published again to its
+// stream after the policy's backoff. The handlers in done aren't given the
+// message again.
+func (i *I) scheduleRetry(ctx context.Context, m *message, policy RetryPolicy, attempts int64, done []string) (time.Duration, error) {
+	values := make(map[string]interface{}, len(m.Values)+1)
+
+	for k, v := range m.Values {
+		values[k] = v
+	}
+
+	values[attemptsField] = strconv.FormatInt(attempts, 10)
+
+	if len(done) > 0 {
+		values[doneField] = strings.Join(done, ",")
+	}
+
+	d := policy.backoff(attempts)
+
+	if err := i.schedule(ctx, m.Stream, values, time.Now().Add(d)); err != nil {
+		return 0, fmt.Errorf("failed to schedule retry: %w", err)
+	}
+
+	return d, nil
+}
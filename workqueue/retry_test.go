@@ -0,0 +1,51 @@
+package workqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	rp := RetryPolicy{
+		MaxAttempts: 10,
+		BackoffBase: time.Second,
+		BackoffMax:  10 * time.Second,
+	}
+
+	tests := []struct {
+		attempt int64
+		want    time.Duration
+	}{
+		{attempt: 1, want: time.Second},
+		{attempt: 2, want: 2 * time.Second},
+		{attempt: 3, want: 4 * time.Second},
+		{attempt: 4, want: 8 * time.Second},
+		{attempt: 5, want: 10 * time.Second},
+		{attempt: 50, want: 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := rp.backoff(tt.attempt); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+		}
+	}
+
+	rp.Jitter = 0.5
+
+	for n := 0; n < 100; n++ {
+		if got := rp.backoff(3); got <= 2*time.Second || got > 4*time.Second {
+			t.Fatalf("backoff(3) with jitter = %s, want (2s, 4s]", got)
+		}
+	}
+}
+
+func TestRetryPolicy_withDefaults(t *testing.T) {
+	got := RetryPolicy{MaxAttempts: 2, Jitter: 3}.withDefaults()
+
+	want := DefaultRetryPolicy
+	want.MaxAttempts = 2
+
+	if got != want {
+		t.Fatalf("withDefaults() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+// Package router provides a routing layer for workqueue message handlers, so
+// handlers declare which messages they want with predicates instead of each
+// re-implementing the same filtering.
+package router
+
+import (
+	"strings"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// Predicate reports whether a message should be given to a handler.
+type Predicate func(ctx workqueue.Context, me *slackevents.MessageEvent) bool
+
+// ChannelIs matches messages in the channel with the given name, looked up with
+// the workqueue's ChannelSvc, or with the given ID.
+func ChannelIs(name string) Predicate {
+	name = strings.TrimPrefix(name, "#")
+
+	return func(ctx workqueue.Context, me *slackevents.MessageEvent) bool {
+		if me.Channel == name {
+			return true
+		}
+
+		c, notFound, err := ctx.ChannelSvc().Lookup(ctx, name)
+		if err != nil {
+			ctx.Logger().Error().
+				Err(err).
+				Str("channel_name", name).
+				Msg("failed to look up channel for route")
+
+			return false
+		}
+
+		return !notFound && c.ID == me.Channel
+	}
+}
+
+// ChannelTypeIs matches messages in the type of channel, like "channel", "im",
+// or "mpim".
+func ChannelTypeIs(channelType string) Predicate {
+	return func(_ workqueue.Context, me *slackevents.MessageEvent) bool {
+		return me.ChannelType == channelType
+	}
+}
+
+// TextHasPrefix matches messages whose text starts with prefix.
+func TextHasPrefix(prefix string) Predicate {
+	return func(_ workqueue.Context, me *slackevents.MessageEvent) bool {
+		return strings.HasPrefix(me.Text, prefix)
+	}
+}
+
+// TextContains matches messages whose text, ignoring case, contains s.
+func TextContains(s string) Predicate {
+	s = strings.ToLower(s)
+
+	return func(_ workqueue.Context, me *slackevents.MessageEvent) bool {
+		return strings.Contains(strings.ToLower(me.Text), s)
+	}
+}
+
+// UserIs matches messages from the user with the given ID.
+func UserIs(userID string) Predicate {
+	return func(_ workqueue.Context, me *slackevents.MessageEvent) bool {
+		return me.User == userID
+	}
+}
+
+// UserIsNot matches messages from anyone but the user with the given ID.
+func UserIsNot(userID string) Predicate {
+	return Not(UserIs(userID))
+}
+
+// NotSelf matches messages that weren't sent by the bot itself.
+func NotSelf() Predicate {
+	return func(ctx workqueue.Context, me *slackevents.MessageEvent) bool {
+		return me.User != ctx.Self().ID
+	}
+}
+
+// NotBot matches messages that weren't sent by any bot.
+func NotBot() Predicate {
+	return func(_ workqueue.Context, me *slackevents.MessageEvent) bool {
+		return len(me.BotID) == 0 && me.SubType != "bot_message"
+	}
+}
+
+// Not matches messages that p doesn't.
+func Not(p Predicate) Predicate {
+	return func(ctx workqueue.Context, me *slackevents.MessageEvent) bool {
+		return !p(ctx, me)
+	}
+}
+
+// All matches messages that all of ps match.
+func All(ps ...Predicate) Predicate {
+	return func(ctx workqueue.Context, me *slackevents.MessageEvent) bool {
+		for _, p := range ps {
+			if !p(ctx, me) {
+				return false
+			}
+		}
+
+		return true
+	}
+}
+
+// Any matches messages that any of ps match.
+func Any(ps ...Predicate) Predicate {
+	return func(ctx workqueue.Context, me *slackevents.MessageEvent) bool {
+		for _, p := range ps {
+			if p(ctx, me) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// Match returns fn wrapped so it's only given the messages all of ps match. The
+// others are skipped, without being treated as an error.
+func Match(fn workqueue.MessageHandler, ps ...Predicate) workqueue.MessageHandler {
+	p := All(ps...)
+
+	return func(ctx workqueue.Context, me *slackevents.MessageEvent) (bool, bool, error) {
+		if !p(ctx, me) {
+			return false, false, nil
+		}
+
+		return fn(ctx, me)
+	}
+}
+
+type route struct {
+	opts workqueue.HandlerOpts
+	fn   workqueue.MessageHandler
+}
+
+// Router is a set of message handlers, each with the predicates a message has
+// to match to be given to it. The zero value is ready to use.
+type Router struct {
+	routes []route
+}
+
+// Handle adds a route for the messages all of ps match. The handler's timeout is
+// taken from opts.
+func (r *Router) Handle(opts workqueue.HandlerOpts, fn workqueue.MessageHandler, ps ...Predicate) {
+	r.routes = append(r.routes, route{opts: opts, fn: Match(fn, ps...)})
+}
+
+// RegisterPublic registers each of the routes as its own public messages
+// handler, so the workqueue fans messages out to them independently.
+func (r *Router) RegisterPublic(q workqueue.Registerer) {
+	for _, rt := range r.routes {
+		q.RegisterPublicMessagesHandler(rt.opts, rt.fn)
+	}
+}
+
+// RegisterPrivate is the same as RegisterPublic, but for private messages.
+func (r *Router) RegisterPrivate(q workqueue.Registerer) {
+	for _, rt := range r.routes {
+		q.RegisterPrivateMessagesHandler(rt.opts, rt.fn)
+	}
+}
@@ -0,0 +1,89 @@
+package router
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// channels is a fake ChannelSvc which, like the cache, returns true for a
+// channel it doesn't have
+type channels map[string]string
+
+func (c channels) Lookup(_ context.Context, name string) (slack.Channel, bool, error) {
+	id, ok := c[name]
+	if !ok {
+		return slack.Channel{}, true, nil
+	}
+
+	var ch slack.Channel
+	ch.ID = id
+
+	return ch, false, nil
+}
+
+func testCtx() workqueue.Context {
+	return workqueue.NewContext(context.Background(), workqueue.ContextConfig{
+		SlackUser:    &slack.User{ID: "UBOT"},
+		ChannelCache: channels{"general": "C123", "announcements": "C789"},
+	})
+}
+
+func TestPredicates(t *testing.T) {
+	me := &slackevents.MessageEvent{
+		Channel:     "C123",
+		ChannelType: "channel",
+		User:        "U456",
+		Text:        "!define goroutine",
+	}
+
+	tests := []struct {
+		name string
+		p    Predicate
+		want bool
+	}{
+		{name: "channel_is_name", p: ChannelIs("#general"), want: true},
+		{name: "channel_is_id", p: ChannelIs("C123"), want: true},
+		{name: "channel_is_other", p: ChannelIs("random")},
+		{name: "channel_is_other_known", p: ChannelIs("#announcements")},
+		{name: "channel_type_is", p: ChannelTypeIs("channel"), want: true},
+		{name: "text_has_prefix", p: TextHasPrefix("!"), want: true},
+		{name: "text_has_prefix_no", p: TextHasPrefix("?")},
+		{name: "text_contains", p: TextContains("GOROUTINE"), want: true},
+		{name: "user_is", p: UserIs("U456"), want: true},
+		{name: "user_is_not", p: UserIsNot("U456")},
+		{name: "not_self", p: NotSelf(), want: true},
+		{name: "not_bot", p: NotBot(), want: true},
+		{name: "all", p: All(TextHasPrefix("!"), UserIs("U456")), want: true},
+		{name: "all_no", p: All(TextHasPrefix("!"), UserIs("U789"))},
+		{name: "any", p: Any(TextHasPrefix("?"), UserIs("U456")), want: true},
+		{name: "any_no", p: Any(TextHasPrefix("?"), UserIs("U789"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p(testCtx(), me); got != tt.want {
+				t.Fatalf("predicate = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	var called int
+
+	fn := Match(func(workqueue.Context, *slackevents.MessageEvent) (bool, bool, error) {
+		called++
+		return false, false, nil
+	}, TextHasPrefix("!"))
+
+	_, _, _ = fn(testCtx(), &slackevents.MessageEvent{Text: "!ping"})
+	_, _, _ = fn(testCtx(), &slackevents.MessageEvent{Text: "ping"})
+
+	if called != 1 {
+		t.Fatalf("handler called %d times, want 1", called)
+	}
+}
@@ -0,0 +1,148 @@
+package workqueue
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// redisScheduleKey is the sorted set of messages waiting to be published,
+	// scored by when they're due in milliseconds. It's named for retries,
+	// which were the first thing scheduled, so entries survive upgrades.
+	redisScheduleKey = "workqueue:retry"
+
+	schedulePollInterval = time.Second
+	scheduleBatchSize    = 100
+)
+
+type scheduleEntry struct {
+	Stream string                 `json:"stream"`
+	Values map[string]interface{} `json:"values"`
+
+	// Binary are the values that are base64 encoded, because they aren't
+	// valid UTF-8 and wouldn't survive JSON, like compressed payloads
+	Binary []string `json:"binary,omitempty"`
+}
+
+func newScheduleEntry(stream string, values map[string]interface{}) scheduleEntry {
+	se := scheduleEntry{Stream: stream, Values: make(map[string]interface{}, len(values))}
+
+	for k, v := range values {
+		if s, ok := v.(string); ok && !utf8.ValidString(s) {
+			v = base64.StdEncoding.EncodeToString([]byte(s))
+			se.Binary = append(se.Binary, k)
+		}
+
+		se.Values[k] = v
+	}
+
+	return se
+}
+
+// values returns the message values, with the Binary ones decoded.
+func (se scheduleEntry) values() (map[string]interface{}, error) {
+	for _, k := range se.Binary {
+		s, _ := se.Values[k].(string)
+
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode binary value %s: %w", k, err)
+		}
+
+		se.Values[k] = string(b)
+	}
+
+	return se.Values, nil
+}
+
+// PublishAt is the same as Publish, except the event is only published to its
+// stream once at has passed. Scheduled events are moved to their stream by the
+// consumers, so they are published late if no consumer is running.
+func (i *I) PublishAt(ctx context.Context, e Event, at time.Time, p Priority, eventTimestamp int64, eventID, requestID string, jsonData []byte) error {
+	values, err := i.gatewayValues(TeamIDFromContext(ctx), eventTimestamp, eventID, requestID, jsonData)
+	if err != nil {
+		return err
+	}
+
+	return i.schedule(ctx, priorityStream(string(e), p), values, at)
+}
+
+// PublishAfter is the same as PublishAt, but for an event to be published
+// after d.
+func (i *I) PublishAfter(ctx context.Context, e Event, d time.Duration, p Priority, eventTimestamp int64, eventID, requestID string, jsonData []byte) error {
+	return i.PublishAt(ctx, e, time.Now().Add(d), p, eventTimestamp, eventID, requestID, jsonData)
+}
+
+// schedule adds the message values to the schedule, to be published to stream
+// at the given time.
+func (i *I) schedule(ctx context.Context, stream string, values map[string]interface{}, at time.Time) error {
+	member, err := json.Marshal(newScheduleEntry(stream, values))
+	if err != nil {
+		return fmt.Errorf("failed to marshal schedule entry: %w", err)
+	}
+
+	if err := i.t.schedule(ctx, string(member), at); err != nil {
+		return fmt.Errorf("failed to add schedule entry: %w", err)
+	}
+
+	return nil
+}
+
+// runSchedule publishes due messages to their streams, until stop is closed.
+func (i *I) runSchedule(stop <-chan struct{}) {
+	ticker := time.NewTicker(schedulePollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+
+		case <-ticker.C:
+			if err := i.publishDue(context.Background()); err != nil {
+				i.l.Error().
+					Err(err).
+					Msg("failed to publish due messages")
+			}
+		}
+	}
+}
+
+func (i *I) publishDue(ctx context.Context) error {
+	members, err := i.t.due(ctx, time.Now(), scheduleBatchSize)
+
+	for n, member := range members {
+		var se scheduleEntry
+		if err := json.Unmarshal([]byte(member), &se); err != nil {
+			i.l.Error().
+				Err(err).
+				Msg("dropping malformed schedule entry")
+
+			continue
+		}
+
+		values, err := se.values()
+		if err != nil {
+			i.l.Error().
+				Err(err).
+				Msg("dropping malformed schedule entry")
+
+			continue
+		}
+
+		if err := i.enqueue(ctx, se.Stream, values); err != nil {
+			// put it and the rest back so they're not lost
+			for _, m := range members[n:] {
+				_ = i.t.schedule(ctx, m, time.Unix(0, 0))
+			}
+
+			return fmt.Errorf("failed to publish scheduled message to %s: %w", se.Stream, err)
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,34 @@
+package workqueue
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_scheduleEntry(t *testing.T) {
+	values := map[string]interface{}{
+		"event_id": "Ev123",
+		"json":     "\x1f\x8b\x08\x00\xff",
+	}
+
+	b, err := json.Marshal(newScheduleEntry("slack_message_public", values))
+	if err != nil {
+		t.Fatalf("failed to marshal entry: %v", err)
+	}
+
+	var se scheduleEntry
+	if err := json.Unmarshal(b, &se); err != nil {
+		t.Fatalf("failed to unmarshal entry: %v", err)
+	}
+
+	got, err := se.values()
+	if err != nil {
+		t.Fatalf("values() unexpected error: %v", err)
+	}
+
+	if diff := cmp.Diff(values, got); diff != "" {
+		t.Fatalf("values() mismatch (-want +got):\n%s", diff)
+	}
+}
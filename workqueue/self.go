@@ -0,0 +1,70 @@
+package workqueue
+
+import (
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/valyala/fastjson"
+)
+
+// authored returns the message whose author is checked: the edited message for
+// edits, which are sent by Slack itself, and otherwise me.
+func authored(me *slackevents.MessageEvent) *slackevents.MessageEvent {
+	if me.SubType == "message_changed" && me.Message != nil {
+		return me.Message
+	}
+
+	return me
+}
+
+// isSelf returns whether the message was sent by the bot user self, or its bot.
+func isSelf(self *slack.User, me *slackevents.MessageEvent) bool {
+	if self == nil || me == nil {
+		return false
+	}
+
+	m := authored(me)
+
+	if len(self.ID) > 0 && m.User == self.ID {
+		return true
+	}
+
+	return len(self.Profile.BotID) > 0 && m.BotID == self.Profile.BotID
+}
+
+// isBot returns whether the message was sent by any bot.
+func isBot(me *slackevents.MessageEvent) bool {
+	m := authored(me)
+	return len(m.BotID) > 0 || m.SubType == "bot_message"
+}
+
+// dropMessage returns why the message should be dropped before it's handled,
+// according to opts, if it should be.
+func (opts HandlerOpts) dropMessage(ctx Context, me *slackevents.MessageEvent) (string, bool) {
+	if opts.IgnoreSelf && ctx.IsSelf(me) {
+		return "message from self", true
+	}
+
+	if opts.IgnoreBots && isBot(me) {
+		return "message from a bot", true
+	}
+
+	return "", false
+}
+
+// dropSubtype returns the message's subtype, and whether opts says to drop it.
+// Only the subtype is parsed, so the event isn't decoded just to be dropped.
+func (opts HandlerOpts) dropSubtype(data []byte) (string, bool) {
+	if len(opts.IgnoreSubtypes) == 0 {
+		return "", false
+	}
+
+	st := fastjson.GetString(data, "subtype")
+
+	for _, ignore := range opts.IgnoreSubtypes {
+		if st == ignore {
+			return st, true
+		}
+	}
+
+	return st, false
+}
@@ -0,0 +1,108 @@
+package workqueue
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+func TestHandlerOpts_dropMessage(t *testing.T) {
+	self := &slack.User{ID: "UBOT", Profile: slack.UserProfile{BotID: "BBOT"}}
+	ctx := ctxer{u: self}
+
+	tests := []struct {
+		name string
+		opts HandlerOpts
+		me   *slackevents.MessageEvent
+		want bool
+	}{
+		{
+			name: "self_allowed",
+			me:   &slackevents.MessageEvent{User: "UBOT"},
+		},
+		{
+			name: "self_user",
+			opts: HandlerOpts{IgnoreSelf: true},
+			me:   &slackevents.MessageEvent{User: "UBOT"},
+			want: true,
+		},
+		{
+			name: "self_bot",
+			opts: HandlerOpts{IgnoreSelf: true},
+			me:   &slackevents.MessageEvent{BotID: "BBOT", SubType: "bot_message"},
+			want: true,
+		},
+		{
+			name: "self_edit",
+			opts: HandlerOpts{IgnoreSelf: true},
+			me: &slackevents.MessageEvent{
+				SubType: "message_changed",
+				Message: &slackevents.MessageEvent{User: "UBOT"},
+			},
+			want: true,
+		},
+		{
+			name: "other_bot_not_self",
+			opts: HandlerOpts{IgnoreSelf: true},
+			me:   &slackevents.MessageEvent{BotID: "B123", SubType: "bot_message"},
+		},
+		{
+			name: "other_bot",
+			opts: HandlerOpts{IgnoreBots: true},
+			me:   &slackevents.MessageEvent{BotID: "B123"},
+			want: true,
+		},
+		{
+			name: "member",
+			opts: HandlerOpts{IgnoreSelf: true, IgnoreBots: true},
+			me:   &slackevents.MessageEvent{User: "U123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, got := tt.opts.dropMessage(ctx, tt.me); got != tt.want {
+				t.Fatalf("dropMessage() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeMessage_ignoreSubtypes(t *testing.T) {
+	var handled []string
+
+	decode := decodeMessage(
+		HandlerOpts{IgnoreSubtypes: []string{"channel_join", "bot_message"}},
+		func(_ Context, me *slackevents.MessageEvent) (bool, bool, error) {
+			handled = append(handled, me.Text)
+			return false, false, nil
+		},
+	)
+
+	events := []string{
+		`{"type":"message","text":"hello"}`,
+		`{"type":"message","subtype":"channel_join","text":"joined"}`,
+		`{"type":"message","subtype":"bot_message","text":"beep"}`,
+		`{"type":"message","subtype":"thread_broadcast","text":"broadcast"}`,
+	}
+
+	l := zerolog.Nop()
+	ctx := ctxer{u: &slack.User{ID: "UBOT"}, l: &l}
+
+	for _, e := range events {
+		invoke, err := decode([]byte(e))
+		if err != nil {
+			t.Fatalf("decode(%s) unexpected error: %v", e, err)
+		}
+
+		if _, _, err := invoke(ctx); err != nil {
+			t.Fatalf("invoke() unexpected error: %v", err)
+		}
+	}
+
+	if len(handled) != 2 || handled[0] != "hello" || handled[1] != "broadcast" {
+		t.Fatalf("handled %v, want [hello broadcast]", handled)
+	}
+}
@@ -0,0 +1,47 @@
+package workqueue
+
+import (
+	"context"
+	"io"
+
+	"github.com/slack-go/slack"
+)
+
+// SlackClient is the part of the Slack API client that handlers use, which is
+// what Context.Slack returns. It's satisfied by *slack.Client, and being an
+// interface lets handler tests use a fake instead.
+type SlackClient interface {
+	PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error)
+	PostEphemeralContext(ctx context.Context, channelID, userID string, options ...slack.MsgOption) (string, error)
+	SendMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, string, error)
+	UpdateMessageContext(ctx context.Context, channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error)
+	DeleteMessageContext(ctx context.Context, channel, messageTimestamp string) (string, string, error)
+	GetPermalinkContext(ctx context.Context, params *slack.PermalinkParameters) (string, error)
+
+	AddReactionContext(ctx context.Context, name string, item slack.ItemRef) error
+	RemoveReactionContext(ctx context.Context, name string, item slack.ItemRef) error
+
+	OpenConversationContext(ctx context.Context, params *slack.OpenConversationParameters) (*slack.Channel, bool, bool, error)
+	GetConversationInfoContext(ctx context.Context, channelID string, includeLocale bool) (*slack.Channel, error)
+	GetConversationHistoryContext(ctx context.Context, params *slack.GetConversationHistoryParameters) (*slack.GetConversationHistoryResponse, error)
+	GetConversationRepliesContext(ctx context.Context, params *slack.GetConversationRepliesParameters) ([]slack.Message, bool, string, error)
+
+	GetUserInfoContext(ctx context.Context, user string) (*slack.User, error)
+
+	GetFileInfoContext(ctx context.Context, fileID string, count, page int) (*slack.File, []slack.Comment, *slack.Paging, error)
+	GetFile(downloadURL string, writer io.Writer) error
+	UploadFileContext(ctx context.Context, params slack.FileUploadParameters) (*slack.File, error)
+}
+
+var _ SlackClient = (*slack.Client)(nil)
+
+// NewSlackClient returns sc as a SlackClient. A nil sc returns a nil
+// SlackClient, instead of one holding a nil *slack.Client, so it can still be
+// compared to nil.
+func NewSlackClient(sc *slack.Client) SlackClient {
+	if sc == nil {
+		return nil
+	}
+
+	return sc
+}
@@ -0,0 +1,79 @@
+package workqueue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// Slash command response types.
+const (
+	// SlashEphemeral responses are only shown to the member that ran the
+	// command. This is the default.
+	SlashEphemeral = "ephemeral"
+
+	// SlashInChannel responses are shown to everyone in the channel.
+	SlashInChannel = "in_channel"
+)
+
+// SlashResponse is a response to a slash command.
+type SlashResponse struct {
+	// ResponseType is SlashEphemeral or SlashInChannel. Empty means
+	// SlashEphemeral.
+	ResponseType string `json:"response_type,omitempty"`
+
+	Text   string        `json:"text,omitempty"`
+	Blocks []slack.Block `json:"blocks,omitempty"`
+
+	// ReplaceOriginal replaces the previous response, instead of adding
+	// another.
+	ReplaceOriginal bool `json:"replace_original,omitempty"`
+}
+
+// slashClient is used to send slash command responses. The response_url isn't
+// part of the Slack Web API, so the Slack client can't be used.
+var slashClient = &http.Client{Timeout: 10 * time.Second}
+
+// RespondSlash responds to the slash command using its response_url. Slack
+// allows up to five responses to each command, within 30 minutes of it being
+// run.
+func RespondSlash(ctx context.Context, sc *slack.SlashCommand, r SlashResponse) error {
+	if len(sc.ResponseURL) == 0 {
+		return fmt.Errorf("slash command %s has no response_url", sc.Command)
+	}
+
+	body, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slash command response: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, sc.ResponseURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build slash command response request: %w", err)
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := slashClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send slash command response: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to send slash command response: unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
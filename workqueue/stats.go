@@ -0,0 +1,164 @@
+package workqueue
+
+import (
+	"context"
+	"fmt"
+)
+
+// StreamStats are the stats for one stream, and this consumer group's progress
+// through it.
+type StreamStats struct {
+	// Stream is the Redis stream name.
+	Stream string
+
+	// Length is how many entries are in the stream.
+	Length int64
+
+	// Consumers is how many consumers are in the consumer group.
+	Consumers int64
+
+	// Pending is how many entries were delivered to the consumer group, but
+	// haven't been acknowledged yet.
+	Pending int64
+
+	// Lag is how many entries haven't been delivered to the consumer group
+	// yet. It's -1 if Redis doesn't report it, which is the case before 7.0.
+	Lag int64
+
+	// LastDeliveredID is the ID of the last entry delivered to the consumer
+	// group.
+	LastDeliveredID string
+
+	// Paused is whether consuming the stream is paused.
+	Paused bool
+}
+
+// Stats returns the StreamStats for each stream with a registered handler, and
+// for the dead-letter stream. Streams that don't exist yet are all zeros, and
+// everything in a stream without the consumer group counts as lag.
+func (i *I) Stats(ctx context.Context) ([]StreamStats, error) {
+	streams := make([]string, 0, len(i.streams)+1)
+	streams = append(streams, i.streams...)
+	streams = append(streams, i.deadLetter)
+
+	paused, err := i.t.paused(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get paused streams: %w", err)
+	}
+
+	// a workqueue that's only publishing doesn't keep its pause state up to
+	// date, so it's loaded fresh
+	ps := newPauseState()
+	ps.set(paused, nil)
+
+	stats := make([]StreamStats, 0, len(streams))
+
+	for _, stream := range streams {
+		s, err := i.streamStats(ctx, stream)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Paused = ps.isPaused(stream)
+
+		stats = append(stats, s)
+	}
+
+	return stats, nil
+}
+
+func (i *I) streamStats(ctx context.Context, stream string) (StreamStats, error) {
+	s := StreamStats{Stream: stream, Lag: -1}
+
+	info, err := i.t.info(ctx, stream)
+	if err != nil {
+		return s, err
+	}
+
+	if info == nil {
+		s.Lag = 0
+		return s, nil
+	}
+
+	s.Length = info.length
+
+	if g, ok := info.groups[i.copts.GroupName]; ok {
+		s.Consumers = g.consumers
+		s.Pending = g.pending
+		s.Lag = g.lag
+		s.LastDeliveredID = g.lastDeliveredID
+	} else {
+		// nothing was delivered to the group yet
+		s.Lag = s.Length
+	}
+
+	return s, nil
+}
+
+// GroupExists reports whether the consumer group exists on the stream for the
+// event and priority. Consumers create it when they start handling the stream,
+// so until then published events pile up without being delivered.
+func (i *I) GroupExists(ctx context.Context, e Event, p Priority) (bool, error) {
+	info, err := i.t.info(ctx, priorityStream(string(e), p))
+	if err != nil || info == nil {
+		return false, err
+	}
+
+	_, ok := info.groups[i.copts.GroupName]
+
+	return ok, nil
+}
+
+type xinfoGroup struct {
+	consumers       int64
+	pending         int64
+	lag             int64
+	lastDeliveredID string
+}
+
+// parseXInfoGroups parses the reply to XINFO GROUPS, which is a list of flat
+// lists of field names and values, into the groups by name.
+func parseXInfoGroups(reply interface{}) (map[string]xinfoGroup, error) {
+	list, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("reply is %T, not a list", reply)
+	}
+
+	groups := make(map[string]xinfoGroup, len(list))
+
+	for _, item := range list {
+		fields, ok := item.([]interface{})
+		if !ok || len(fields)%2 != 0 {
+			return nil, fmt.Errorf("group is %T with %d fields, not a list of pairs", item, len(fields))
+		}
+
+		var name string
+
+		g := xinfoGroup{lag: -1}
+
+		for n := 0; n < len(fields); n += 2 {
+			k, _ := fields[n].(string)
+			v := fields[n+1]
+
+			switch k {
+			case "name":
+				name, _ = v.(string)
+			case "consumers":
+				g.consumers, _ = v.(int64)
+			case "pending":
+				g.pending, _ = v.(int64)
+			case "last-delivered-id":
+				g.lastDeliveredID, _ = v.(string)
+			case "lag":
+				// nil when Redis can't work it out
+				if l, ok := v.(int64); ok {
+					g.lag = l
+				}
+			}
+		}
+
+		groups[name] = g
+	}
+
+	return groups, nil
+}
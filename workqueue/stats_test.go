@@ -0,0 +1,44 @@
+package workqueue
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_parseXInfoGroups(t *testing.T) {
+	reply := []interface{}{
+		[]interface{}{
+			"name", "consumer",
+			"consumers", int64(2),
+			"pending", int64(3),
+			"last-delivered-id", "1588000000000-0",
+			"entries-read", int64(10),
+			"lag", int64(4),
+		},
+		[]interface{}{
+			"name", "old",
+			"consumers", int64(1),
+			"pending", int64(0),
+			"last-delivered-id", "0-0",
+		},
+	}
+
+	got, err := parseXInfoGroups(reply)
+	if err != nil {
+		t.Fatalf("parseXInfoGroups() unexpected error: %v", err)
+	}
+
+	want := map[string]xinfoGroup{
+		"consumer": {consumers: 2, pending: 3, lag: 4, lastDeliveredID: "1588000000000-0"},
+		"old":      {consumers: 1, lag: -1, lastDeliveredID: "0-0"},
+	}
+
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(xinfoGroup{})); diff != "" {
+		t.Fatalf("parseXInfoGroups() mismatch (-want +got):\n%s", diff)
+	}
+
+	if _, err := parseXInfoGroups("nope"); err == nil {
+		t.Fatal("parseXInfoGroups() with a string reply did not error")
+	}
+}
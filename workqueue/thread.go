@@ -0,0 +1,63 @@
+package workqueue
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// ErrNotInThread is returned by FetchThread when the event wasn't in a thread.
+var ErrNotInThread = errors.New("event is not in a thread")
+
+// maxThreadPages is the most pages of replies FetchThread will fetch, so a
+// huge thread can't use up the handler's whole timeout
+const maxThreadPages = 10
+
+// IsThreadReply satisfies Context.
+func (c ctxer) IsThreadReply() bool {
+	t, err := eventReplyTarget(c.r)
+	if err != nil {
+		return false
+	}
+
+	return len(t.threadTS) > 0 && t.threadTS != t.ts
+}
+
+// FetchThread returns the messages in the thread the event happened in, oldest
+// first, starting with the message that started it.
+func FetchThread(ctx Context) ([]slack.Message, error) {
+	t, err := eventReplyTarget(ctx.RawEvent())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(t.threadTS) == 0 {
+		return nil, ErrNotInThread
+	}
+
+	params := &slack.GetConversationRepliesParameters{
+		ChannelID: t.channel,
+		Timestamp: t.threadTS,
+		Limit:     200,
+	}
+
+	var msgs []slack.Message
+
+	for page := 0; page < maxThreadPages; page++ {
+		m, hasMore, cursor, err := ctx.Slack().GetConversationRepliesContext(ctx, params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to GetConversationRepliesContext: %w", err)
+		}
+
+		msgs = append(msgs, m...)
+
+		if !hasMore || len(cursor) == 0 {
+			break
+		}
+
+		params.Cursor = cursor
+	}
+
+	return msgs, nil
+}
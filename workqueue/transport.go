@@ -0,0 +1,538 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The backends a workqueue can move its messages through, set as
+// Config.Backend.
+const (
+	// BackendRedis moves messages through Redis Streams. It's the default.
+	BackendRedis = "redis"
+
+	// BackendMemory keeps messages in memory, so they only reach consumers in
+	// the same process. It's for tests, and running locally without Redis.
+	BackendMemory = "memory"
+)
+
+// errNoGroup is returned by transport.read if the consumer group doesn't
+// exist on one of the streams, like after the stream was deleted.
+var errNoGroup = errors.New("consumer group doesn't exist")
+
+// transport moves messages between publishers and consumers through streams,
+// and keeps scheduled messages until they're due.
+type transport interface {
+	// add appends the values to the stream as a new message, trimming the
+	// stream to roughly streamMaxLength messages.
+	add(ctx context.Context, stream string, values map[string]interface{}) error
+
+	// createGroup creates the consumer group on the stream, starting after
+	// lastID, unless it already exists. The stream is created if needed.
+	createGroup(ctx context.Context, stream, group, lastID string) error
+
+	// read returns up to count messages from the streams that weren't
+	// delivered to the group yet, waiting for up to block for some to be
+	// added. They're pending for the consumer until they're acknowledged.
+	read(ctx context.Context, group, consumer string, streams []string, count int64, block time.Duration) ([]*message, error)
+
+	// readsTogether reports whether read can be given several streams.
+	readsTogether() bool
+
+	// claim makes up to count of the stream's messages that have been pending
+	// for at least minIdle pending for the consumer instead, and returns them.
+	claim(ctx context.Context, stream, group, consumer string, minIdle time.Duration, count int64) ([]*message, error)
+
+	// ack removes the messages from the group's pending messages.
+	ack(ctx context.Context, stream, group string, ids ...string) error
+
+	// pending returns how many of the stream's messages are pending for the
+	// consumer.
+	pending(ctx context.Context, stream, group, consumer string) (int64, error)
+
+	// rangeOf returns up to count of the stream's messages with IDs from
+	// start to end, which can be "-" and "+" for the first and last IDs, or
+	// be prefixed with "(" to exclude them.
+	rangeOf(ctx context.Context, stream, start, end string, count int64) ([]*message, error)
+
+	// listStreams returns the names of all the streams.
+	listStreams(ctx context.Context) ([]string, error)
+
+	// deleteGroup deletes the consumer group from the stream, and reports
+	// whether it existed.
+	deleteGroup(ctx context.Context, stream, group string) (bool, error)
+
+	// pendingEntries returns up to count of the group's pending messages on
+	// the stream, only those of the consumer unless it's empty.
+	pendingEntries(ctx context.Context, stream, group, consumer string, count int64) ([]PendingEntry, error)
+
+	// claimIDs makes the pending messages with the IDs pending for the
+	// consumer instead, however long they were idle, without counting it as a
+	// delivery. It returns the IDs that were pending.
+	claimIDs(ctx context.Context, stream, group, consumer string, ids []string) ([]string, error)
+
+	// info returns the stream's length and groups, or nil if the stream
+	// doesn't exist.
+	info(ctx context.Context, stream string) (*streamInfo, error)
+
+	// setPaused pauses or resumes the stream, and tells the workers.
+	setPaused(ctx context.Context, stream string, paused bool) error
+
+	// paused returns the paused streams.
+	paused(ctx context.Context) ([]string, error)
+
+	// watchPaused returns a channel that's sent on when the paused streams
+	// may have changed, until ctx is canceled.
+	watchPaused(ctx context.Context) <-chan struct{}
+
+	// schedule adds the member to the schedule, to be due at the given time.
+	schedule(ctx context.Context, member string, at time.Time) error
+
+	// due removes up to n members that are due by now from the schedule, and
+	// returns them. Each member is only returned to one caller. Members
+	// removed before an error are returned with it.
+	due(ctx context.Context, now time.Time, n int64) ([]string, error)
+}
+
+// streamInfo describes a stream, and its consumer groups by name.
+type streamInfo struct {
+	length int64
+	groups map[string]xinfoGroup
+}
+
+// newTransport returns the transport for the backend, which uses rc if it's
+// BackendRedis.
+func newTransport(backend string, rc redis.UniversalClient) (transport, error) {
+	switch backend {
+	case "", BackendRedis:
+		if rc == nil {
+			return nil, errors.New("the redis workqueue backend needs a RedisClient")
+		}
+
+		return redisTransport{r: rc}, nil
+
+	case BackendMemory:
+		return newMemoryTransport(), nil
+
+	default:
+		return nil, fmt.Errorf("unknown workqueue backend %q", backend)
+	}
+}
+
+// redisTransport is the transport for BackendRedis.
+type redisTransport struct {
+	r redis.UniversalClient
+}
+
+var _ transport = redisTransport{}
+
+func millis(t time.Time) int64 { return t.UnixNano() / int64(time.Millisecond) }
+
+func (t redisTransport) add(ctx context.Context, stream string, values map[string]interface{}) error {
+	return t.r.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: streamMaxLength,
+		Approx: true,
+		Values: values,
+	}).Err()
+}
+
+func (t redisTransport) createGroup(ctx context.Context, stream, group, lastID string) error {
+	err := t.r.XGroupCreateMkStream(ctx, stream, group, lastID).Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+
+	return nil
+}
+
+func (t redisTransport) read(ctx context.Context, group, consumer string, streams []string, count int64, block time.Duration) ([]*message, error) {
+	args := make([]string, 0, 2*len(streams))
+	args = append(args, streams...)
+
+	for range streams {
+		args = append(args, ">")
+	}
+
+	res, err := t.r.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: consumer,
+		Streams:  args,
+		Count:    count,
+		Block:    block,
+	}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, nil
+		}
+
+		if strings.HasPrefix(err.Error(), "NOGROUP") {
+			return nil, fmt.Errorf("%w: %s", errNoGroup, err)
+		}
+
+		return nil, err
+	}
+
+	var msgs []*message
+
+	for _, s := range res {
+		for _, xm := range s.Messages {
+			msgs = append(msgs, &message{ID: xm.ID, Stream: s.Stream, Values: xm.Values, Deliveries: 1})
+		}
+	}
+
+	return msgs, nil
+}
+
+// readsTogether satisfies transport. A Redis Cluster only allows reading
+// several streams at once if they're in the same hash slot.
+func (t redisTransport) readsTogether() bool {
+	_, ok := t.r.(*redis.ClusterClient)
+	return !ok
+}
+
+func (t redisTransport) claim(ctx context.Context, stream, group, consumer string, minIdle time.Duration, count int64) ([]*message, error) {
+	var claimed []redis.XMessage
+
+	start := "0-0"
+
+	for int64(len(claimed)) < count {
+		// go-redis can't parse the reply from Redis 7, which added a list of
+		// deleted entries, so it's parsed here
+		reply, err := t.r.Do(ctx, "XAUTOCLAIM", stream, group, consumer,
+			minIdle.Milliseconds(), start, "COUNT", count-int64(len(claimed))).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim messages: %w", err)
+		}
+
+		next, msgs, gone, err := parseXAutoClaim(reply)
+		if err != nil {
+			return nil, err
+		}
+
+		// before Redis 7, entries that were deleted while pending are claimed
+		// without their fields, and only acknowledging them removes them
+		if len(gone) > 0 {
+			if err := t.ack(ctx, stream, group, gone...); err != nil {
+				return nil, fmt.Errorf("failed to acknowledge deleted messages: %w", err)
+			}
+		}
+
+		claimed = append(claimed, msgs...)
+
+		if next == "0-0" {
+			break
+		}
+
+		start = next
+	}
+
+	if len(claimed) == 0 {
+		return nil, nil
+	}
+
+	// XAUTOCLAIM doesn't return the delivery counts
+	pending, err := t.r.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   stream,
+		Group:    group,
+		Start:    claimed[0].ID,
+		End:      claimed[len(claimed)-1].ID,
+		Count:    int64(len(claimed)),
+		Consumer: consumer,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery counts: %w", err)
+	}
+
+	deliveries := make(map[string]int64, len(pending))
+
+	for _, p := range pending {
+		deliveries[p.ID] = p.RetryCount
+	}
+
+	msgs := make([]*message, len(claimed))
+
+	for n, xm := range claimed {
+		msgs[n] = &message{ID: xm.ID, Stream: stream, Values: xm.Values, Deliveries: deliveries[xm.ID]}
+
+		if msgs[n].Deliveries < 1 {
+			msgs[n].Deliveries = 1
+		}
+	}
+
+	return msgs, nil
+}
+
+func (t redisTransport) ack(ctx context.Context, stream, group string, ids ...string) error {
+	return t.r.XAck(ctx, stream, group, ids...).Err()
+}
+
+func (t redisTransport) pending(ctx context.Context, stream, group, consumer string) (int64, error) {
+	p, err := t.r.XPending(ctx, stream, group).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	return p.Consumers[consumer], nil
+}
+
+func (t redisTransport) rangeOf(ctx context.Context, stream, start, end string, count int64) ([]*message, error) {
+	xms, err := t.r.XRangeN(ctx, stream, start, end, count).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	msgs := make([]*message, len(xms))
+
+	for n, xm := range xms {
+		msgs[n] = &message{ID: xm.ID, Stream: stream, Values: xm.Values}
+	}
+
+	return msgs, nil
+}
+
+func (t redisTransport) listStreams(ctx context.Context) ([]string, error) {
+	var names []string
+
+	scan := func(ctx context.Context, c redis.Cmdable) error {
+		iter := c.ScanType(ctx, 0, "*", 100, "stream").Iterator()
+
+		for iter.Next(ctx) {
+			names = append(names, iter.Val())
+		}
+
+		return iter.Err()
+	}
+
+	// each node of a cluster only has some of the keys
+	if cc, ok := t.r.(*redis.ClusterClient); ok {
+		var mu sync.Mutex
+
+		err := cc.ForEachMaster(ctx, func(ctx context.Context, c *redis.Client) error {
+			mu.Lock()
+			defer mu.Unlock()
+
+			return scan(ctx, c)
+		})
+
+		return names, err
+	}
+
+	return names, scan(ctx, t.r)
+}
+
+func (t redisTransport) deleteGroup(ctx context.Context, stream, group string) (bool, error) {
+	n, err := t.r.XGroupDestroy(ctx, stream, group).Result()
+	return n == 1, err
+}
+
+func (t redisTransport) pendingEntries(ctx context.Context, stream, group, consumer string, count int64) ([]PendingEntry, error) {
+	pending, err := t.r.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   stream,
+		Group:    group,
+		Start:    "-",
+		End:      "+",
+		Count:    count,
+		Consumer: consumer,
+	}).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]PendingEntry, len(pending))
+
+	for n, p := range pending {
+		entries[n] = PendingEntry{ID: p.ID, Consumer: p.Consumer, Idle: p.Idle, Deliveries: p.RetryCount}
+	}
+
+	return entries, nil
+}
+
+func (t redisTransport) claimIDs(ctx context.Context, stream, group, consumer string, ids []string) ([]string, error) {
+	return t.r.XClaimJustID(ctx, &redis.XClaimArgs{
+		Stream:   stream,
+		Group:    group,
+		Consumer: consumer,
+		Messages: ids,
+	}).Result()
+}
+
+func (t redisTransport) info(ctx context.Context, stream string) (*streamInfo, error) {
+	n, err := t.r.Exists(ctx, stream).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if stream %s exists: %w", stream, err)
+	}
+
+	if n == 0 {
+		return nil, nil
+	}
+
+	length, err := t.r.XLen(ctx, stream).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get length of stream %s: %w", stream, err)
+	}
+
+	// go-redis has XInfoGroups, but it doesn't report the lag
+	cmd := t.r.Do(ctx, "XINFO", "GROUPS", stream)
+
+	if err := cmd.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get consumer groups of stream %s: %w", stream, err)
+	}
+
+	groups, err := parseXInfoGroups(cmd.Val())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse consumer groups of stream %s: %w", stream, err)
+	}
+
+	return &streamInfo{length: length, groups: groups}, nil
+}
+
+func (t redisTransport) setPaused(ctx context.Context, stream string, paused bool) error {
+	var err error
+
+	if paused {
+		err = t.r.SAdd(ctx, redisPausedKey, stream).Err()
+	} else {
+		err = t.r.SRem(ctx, redisPausedKey, stream).Err()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// the set is the source of truth, so workers that miss this still catch
+	// up when they next reload it
+	return t.r.Publish(ctx, redisControlChannel, stream).Err()
+}
+
+func (t redisTransport) paused(ctx context.Context) ([]string, error) {
+	return t.r.SMembers(ctx, redisPausedKey).Result()
+}
+
+func (t redisTransport) watchPaused(ctx context.Context) <-chan struct{} {
+	ps := t.r.Subscribe(ctx, redisControlChannel)
+	changed := make(chan struct{}, 1)
+
+	go func() {
+		defer func() { _ = ps.Close() }()
+
+		msgs := ps.Channel()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case _, ok := <-msgs:
+				if !ok {
+					return
+				}
+
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+
+	return changed
+}
+
+func (t redisTransport) schedule(ctx context.Context, member string, at time.Time) error {
+	return t.r.ZAdd(ctx, redisScheduleKey, &redis.Z{Score: float64(millis(at)), Member: member}).Err()
+}
+
+func (t redisTransport) due(ctx context.Context, now time.Time, n int64) ([]string, error) {
+	members, err := t.r.ZRangeByScore(ctx, redisScheduleKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(millis(now), 10),
+		Count: n,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due messages: %w", err)
+	}
+
+	claimed := make([]string, 0, len(members))
+
+	for _, member := range members {
+		// only one consumer gets to remove it, and that's the one that
+		// publishes it
+		n, err := t.r.ZRem(ctx, redisScheduleKey, member).Result()
+		if err != nil {
+			return claimed, fmt.Errorf("failed to claim scheduled message: %w", err)
+		}
+
+		if n == 1 {
+			claimed = append(claimed, member)
+		}
+	}
+
+	return claimed, nil
+}
+
+// parseXAutoClaim parses the reply to XAUTOCLAIM into the ID to continue
+// claiming from, the claimed messages, and the IDs of claimed entries that
+// were deleted. The reply has a third element, of entries that were deleted
+// and dropped from the pending list, since Redis 7, which are ignored.
+func parseXAutoClaim(reply interface{}) (string, []redis.XMessage, []string, error) {
+	list, ok := reply.([]interface{})
+	if !ok || len(list) < 2 {
+		return "", nil, nil, fmt.Errorf("XAUTOCLAIM reply is %T, not a list of at least 2", reply)
+	}
+
+	next, ok := list[0].(string)
+	if !ok {
+		return "", nil, nil, fmt.Errorf("XAUTOCLAIM cursor is %T, not a string", list[0])
+	}
+
+	entries, ok := list[1].([]interface{})
+	if !ok {
+		return "", nil, nil, fmt.Errorf("XAUTOCLAIM entries are %T, not a list", list[1])
+	}
+
+	var (
+		msgs []redis.XMessage
+		gone []string
+	)
+
+	for _, e := range entries {
+		entry, ok := e.([]interface{})
+		if !ok || len(entry) != 2 {
+			return "", nil, nil, fmt.Errorf("XAUTOCLAIM entry is %T, not an ID and fields", e)
+		}
+
+		id, ok := entry[0].(string)
+		if !ok {
+			return "", nil, nil, fmt.Errorf("XAUTOCLAIM entry ID is %T, not a string", entry[0])
+		}
+
+		if entry[1] == nil {
+			gone = append(gone, id)
+			continue
+		}
+
+		fields, ok := entry[1].([]interface{})
+		if !ok || len(fields)%2 != 0 {
+			return "", nil, nil, fmt.Errorf("XAUTOCLAIM entry %s fields are %T, not a list of pairs", id, entry[1])
+		}
+
+		values := make(map[string]interface{}, len(fields)/2)
+
+		for n := 0; n < len(fields); n += 2 {
+			k, _ := fields[n].(string)
+			values[k] = fields[n+1]
+		}
+
+		msgs = append(msgs, redis.XMessage{ID: id, Values: values})
+	}
+
+	return next, msgs, gone, nil
+}
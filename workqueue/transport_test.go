@@ -0,0 +1,71 @@
+package workqueue
+
+import (
+	"testing"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_parseXAutoClaim(t *testing.T) {
+	entries := []interface{}{
+		[]interface{}{"1588000000000-0", []interface{}{"event_id", "Ev1", "json", "{}"}},
+		[]interface{}{"1588000000001-0", nil},
+	}
+
+	tests := []struct {
+		name  string
+		reply interface{}
+		next  string
+		msgs  []redis.XMessage
+		gone  []string
+	}{
+		{
+			name:  "redis_6",
+			reply: []interface{}{"1588000000002-0", entries},
+			next:  "1588000000002-0",
+			msgs: []redis.XMessage{
+				{ID: "1588000000000-0", Values: map[string]interface{}{"event_id": "Ev1", "json": "{}"}},
+			},
+			gone: []string{"1588000000001-0"},
+		},
+		{
+			name:  "redis_7",
+			reply: []interface{}{"0-0", entries[:1], []interface{}{"1588000000001-0"}},
+			next:  "0-0",
+			msgs: []redis.XMessage{
+				{ID: "1588000000000-0", Values: map[string]interface{}{"event_id": "Ev1", "json": "{}"}},
+			},
+		},
+		{
+			name:  "empty",
+			reply: []interface{}{"0-0", []interface{}{}},
+			next:  "0-0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next, msgs, gone, err := parseXAutoClaim(tt.reply)
+			if err != nil {
+				t.Fatalf("parseXAutoClaim() unexpected error: %v", err)
+			}
+
+			if next != tt.next {
+				t.Errorf("parseXAutoClaim() next = %q, want %q", next, tt.next)
+			}
+
+			if diff := cmp.Diff(tt.msgs, msgs); diff != "" {
+				t.Errorf("parseXAutoClaim() messages mismatch (-want +got):\n%s", diff)
+			}
+
+			if diff := cmp.Diff(tt.gone, gone); diff != "" {
+				t.Errorf("parseXAutoClaim() gone mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	if _, _, _, err := parseXAutoClaim("nope"); err == nil {
+		t.Fatal("parseXAutoClaim() with a string reply did not error")
+	}
+}
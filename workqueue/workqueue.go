@@ -6,10 +6,11 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"sync"
 	"time"
 
-	"github.com/go-redis/redis"
-	"github.com/robinjoseph08/redisqueue"
+	"github.com/go-redis/redis/v8"
+	"github.com/gobridge/gopherbot/storage"
 	"github.com/rs/zerolog"
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
@@ -23,6 +24,24 @@ const (
 	slackPrivateMessage = "slack_message_private"
 	slackTeamJoin       = "slack_team_join"
 	slackChannelJoin    = "slack_channel_join"
+	slackChannelLeave   = "slack_channel_leave"
+	slackUserDeactivate = "slack_user_deactivated"
+	slackFileShared     = "slack_file_shared"
+	slackReactionAdd    = "slack_reaction_added"
+	slackReactionRemove = "slack_reaction_removed"
+	slackInteraction    = "slack_interaction"
+	slackSlashCommand   = "slack_slash_command"
+	slackMessageChanged = "slack_message_changed"
+	slackMessageDeleted = "slack_message_deleted"
+	slackChannelCreate  = "slack_channel_created"
+	slackChannelRename  = "slack_channel_renamed"
+	slackChannelArchive = "slack_channel_archived"
+	slackChannelUnarch  = "slack_channel_unarchived"
+	slackSubteamUpdate  = "slack_subteam_updated"
+	scheduledJob        = "scheduled_job"
+	moderation          = "moderation"
+
+	slackRawPrefix = "slack_raw_"
 )
 
 const (
@@ -44,22 +63,130 @@ const (
 	// aka a group DM
 	SlackMessageMPIM Event = slackPrivateMessage
 
+	// SlackMessageChanged is the Event for a message with a subtype of
+	// "message_changed", aka an edit, in any type of channel
+	SlackMessageChanged Event = slackMessageChanged
+
+	// SlackMessageDeleted is the Event for a message with a subtype of
+	// "message_deleted" in any type of channel
+	SlackMessageDeleted Event = slackMessageDeleted
+
 	// SlackTeamJoin is the Event for a team (workspace) join Slack event
 	SlackTeamJoin Event = slackTeamJoin
 
 	// SlackChannelJoin is the Event for a channel (public or private) join Slack event.
 	SlackChannelJoin Event = slackChannelJoin
+
+	// SlackChannelLeave is the Event for a member leaving, or being removed
+	// from, a channel (public or private).
+	SlackChannelLeave Event = slackChannelLeave
+
+	// SlackUserDeactivated is the Event for a member's account being
+	// deactivated. It's a user_change Slack event for a deleted user, and
+	// other user_change events are published as the RawEvent.
+	SlackUserDeactivated Event = slackUserDeactivate
+
+	// SlackChannelCreated is the Event for a public channel being created.
+	SlackChannelCreated Event = slackChannelCreate
+
+	// SlackChannelRenamed is the Event for a public channel being renamed.
+	SlackChannelRenamed Event = slackChannelRename
+
+	// SlackChannelArchived is the Event for a public channel being archived.
+	SlackChannelArchived Event = slackChannelArchive
+
+	// SlackChannelUnarchived is the Event for a public channel being
+	// unarchived.
+	SlackChannelUnarchived Event = slackChannelUnarch
+
+	// SlackSubteamUpdated is the Event for a user group being created, or
+	// changed, including its members.
+	SlackSubteamUpdated Event = slackSubteamUpdate
+
+	// SlackFileShared is the Event for a file being shared in a channel the
+	// bot is a member of.
+	SlackFileShared Event = slackFileShared
+
+	// SlackReactionAdded is the Event for an emoji reaction being added to an
+	// item.
+	SlackReactionAdded Event = slackReactionAdd
+
+	// SlackReactionRemoved is the Event for an emoji reaction being removed
+	// from an item.
+	SlackReactionRemoved Event = slackReactionRemove
+
+	// SlackBlockActions is the Event for a member clicking a button, or
+	// choosing from a select menu, in a message the bot posted.
+	SlackBlockActions Event = slackInteraction
+
+	// SlackViewSubmission is the Event for a member submitting a modal the bot
+	// opened.
+	SlackViewSubmission Event = slackInteraction
+
+	// SlackSlashCommand is the Event for a member running one of the App's
+	// slash commands.
+	SlackSlashCommand Event = slackSlashCommand
+
+	// ScheduledJob is the Event for a scheduled job firing. These aren't from
+	// Slack, and are instead published by the scheduler.
+	ScheduledJob Event = scheduledJob
+
+	// Moderation is the Event for a member being flagged by one of the
+	// consumer's detectors, like the spam detector. These aren't from Slack,
+	// and are for moderation handlers to act on.
+	Moderation Event = moderation
 )
 
+// RawEvent returns the Event for a Slack event type the workqueue doesn't model
+// itself, like pin_added. The gateway publishes those to this stream, and they
+// can be consumed with RegisterRawHandler.
+func RawEvent(slackEventType string) Event {
+	return Event(slackRawPrefix + slackEventType)
+}
+
 // MessageHandler is the handler for public Slack messages. The handler signals
 // to the workqueue what to do with the item on failure with the shouldRetry
-// bool. If there is an error, and shouldRetry is true, another worker should
-// pick up the work eventually (assuming there are others).
+// bool. If there is an error, and shouldRetry is true, the message is retried
+// after a backoff according to the stream's RetryPolicy. Otherwise, or once it
+// runs out of attempts, it's published to the dead-letter stream.
 //
 // If discarded is true, the returend error isn't treated as an error but
 // instead an informational message.
+//
+// Wrapping the error with Retryable or Discard says the same as shouldRetry or
+// discarded, and MessageHandlerV2 is a handler that only returns the error.
 type MessageHandler func(ctx Context, me *slackevents.MessageEvent) (shouldRetry, discarded bool, err error)
 
+// MessageChangedHandler is the handler for message edits. The edited message
+// is the event's Message field, and the message before the edit is its
+// PreviousMessage field. For info on shouldRetry please see the comment for
+// the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type MessageChangedHandler func(ctx Context, me *slackevents.MessageEvent) (shouldRetry, discarded bool, err error)
+
+// MessageDeletedEvent is a message with the message_deleted subtype. The
+// slackevents package's MessageEvent doesn't include which message was
+// deleted.
+type MessageDeletedEvent struct {
+	Type             string                    `json:"type"`
+	SubType          string                    `json:"subtype"`
+	Channel          string                    `json:"channel"`
+	ChannelType      string                    `json:"channel_type"`
+	TimeStamp        string                    `json:"ts"`
+	DeletedTimeStamp string                    `json:"deleted_ts"`
+	EventTimeStamp   string                    `json:"event_ts"`
+	PreviousMessage  *slackevents.MessageEvent `json:"previous_message,omitempty"`
+}
+
+// MessageDeletedHandler is the handler for message deletions. For info on
+// shouldRetry please see the comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type MessageDeletedHandler func(ctx Context, md *MessageDeletedEvent) (shouldRetry, discarded bool, err error)
+
 // TeamJoinHandler is the handler for team_join Slack events, used when a new
 // member joins the workspace. For info on shouldRetry please see the comment
 // for the MessageHandler type.
@@ -76,17 +203,319 @@ type TeamJoinHandler func(ctx Context, tj *slack.TeamJoinEvent) (shouldRetry, di
 // instead an informational message.
 type ChannelJoinHandler func(ctx Context, cj *slackevents.MemberJoinedChannelEvent) (shouldRetry, discarded bool, err error)
 
+// ChannelLeaveEvent is the member_left_channel Slack event. The slackevents
+// package doesn't have a type for it.
+type ChannelLeaveEvent struct {
+	Type        string `json:"type"`
+	User        string `json:"user"`
+	Channel     string `json:"channel"`
+	ChannelType string `json:"channel_type"`
+	Team        string `json:"team"`
+}
+
+// ChannelLeaveHandler is the handler for member_left_channel Slack events,
+// used when a member leaves a channel. For info on shouldRetry please see the
+// comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type ChannelLeaveHandler func(ctx Context, cl *ChannelLeaveEvent) (shouldRetry, discarded bool, err error)
+
+// UserDeactivatedHandler is the handler for members being deactivated, used
+// for offboarding. The event's User has Deleted set. For info on shouldRetry
+// please see the comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type UserDeactivatedHandler func(ctx Context, uc *slack.UserChangeEvent) (shouldRetry, discarded bool, err error)
+
+// ChannelChangeEvent is a channel_created, channel_rename, channel_archive, or
+// channel_unarchive Slack event. Use the Type field to tell them apart.
+type ChannelChangeEvent struct {
+	Type      string
+	ChannelID string
+
+	// Name is the channel's name. It's only set for channel_created and
+	// channel_rename, the others only include the channel's ID.
+	Name string
+
+	// User is who archived or unarchived the channel.
+	User string
+}
+
+// UnmarshalJSON satisfies json.Unmarshaler. The channel field is an object for
+// some of the event types, and an ID for the others.
+func (c *ChannelChangeEvent) UnmarshalJSON(data []byte) error {
+	var e struct {
+		Type    string          `json:"type"`
+		Channel json.RawMessage `json:"channel"`
+		User    string          `json:"user"`
+	}
+
+	if err := json.Unmarshal(data, &e); err != nil {
+		return err
+	}
+
+	c.Type = e.Type
+	c.User = e.User
+
+	if len(e.Channel) > 0 && e.Channel[0] == '"' {
+		return json.Unmarshal(e.Channel, &c.ChannelID)
+	}
+
+	var ch struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	if err := json.Unmarshal(e.Channel, &ch); err != nil {
+		return err
+	}
+
+	c.ChannelID = ch.ID
+	c.Name = ch.Name
+
+	return nil
+}
+
+// Removed returns true if this is a channel_archive event.
+func (c ChannelChangeEvent) Removed() bool { return c.Type == "channel_archive" }
+
+// ChannelChangeHandler is the handler for changes to public channels, like
+// being created, renamed, or archived. For info on shouldRetry please see the
+// comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type ChannelChangeHandler func(ctx Context, cc *ChannelChangeEvent) (shouldRetry, discarded bool, err error)
+
+// ChannelCreatedHandler is the handler for channel_created Slack events, used
+// when a public channel is created. For info on shouldRetry please see the
+// comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type ChannelCreatedHandler func(ctx Context, cc *ChannelChangeEvent) (shouldRetry, discarded bool, err error)
+
+// ChannelRenamedHandler is the handler for channel_rename Slack events, used
+// when a public channel is renamed. For info on shouldRetry please see the
+// comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type ChannelRenamedHandler func(ctx Context, cc *ChannelChangeEvent) (shouldRetry, discarded bool, err error)
+
+// ChannelArchivedHandler is the handler for channel_archive Slack events, used
+// when a public channel is archived. For info on shouldRetry please see the
+// comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type ChannelArchivedHandler func(ctx Context, cc *ChannelChangeEvent) (shouldRetry, discarded bool, err error)
+
+// ChannelUnarchivedHandler is the handler for channel_unarchive Slack events,
+// used when a public channel is unarchived. For info on shouldRetry please see
+// the comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type ChannelUnarchivedHandler func(ctx Context, cc *ChannelChangeEvent) (shouldRetry, discarded bool, err error)
+
+// SubteamEvent is the subteam_updated Slack event. The slack package only
+// provides the RTM version of this event, which doesn't include the group's
+// members.
+type SubteamEvent struct {
+	Type    string          `json:"type"`
+	Subteam slack.UserGroup `json:"subteam"`
+}
+
+// SubteamHandler is the handler for subteam_updated Slack events, used when a
+// user group is changed. For info on shouldRetry please see the comment for the
+// MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type SubteamHandler func(ctx Context, se *SubteamEvent) (shouldRetry, discarded bool, err error)
+
+// FileSharedEvent is the file_shared Slack event. The slack package only
+// provides the RTM version of this event, which doesn't include the channel or
+// user the file was shared by.
+type FileSharedEvent struct {
+	Type      string `json:"type"`
+	ChannelID string `json:"channel_id"`
+	FileID    string `json:"file_id"`
+	UserID    string `json:"user_id"`
+	EventTS   string `json:"event_ts"`
+}
+
+// FileSharedHandler is the handler for file_shared Slack events, used when a
+// file is shared in a channel the bot is a member of. The handler is only given
+// the file's ID, and needs to fetch its details itself. For info on
+// shouldRetry please see the comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type FileSharedHandler func(ctx Context, fs *FileSharedEvent) (shouldRetry, discarded bool, err error)
+
+// ReactionEvent is a reaction_added or reaction_removed Slack event. The
+// slackevents package has a type for each, but they have the same fields, so
+// this is used for both. Use the Type field to tell them apart.
+type ReactionEvent slackevents.ReactionAddedEvent
+
+// Added returns true if this is a reaction_added event.
+func (r ReactionEvent) Added() bool { return r.Type == "reaction_added" }
+
+// ReactionHandler is the handler for reaction_added and reaction_removed Slack
+// events, used when a member adds or removes an emoji reaction on an item in
+// a channel the bot is a member of. For info on shouldRetry please see the
+// comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type ReactionHandler func(ctx Context, re *ReactionEvent) (shouldRetry, discarded bool, err error)
+
+// InteractionHandler is the handler for block_actions and view_submission
+// Slack interactivity payloads, used when a member clicks a button in a
+// message the bot posted or submits a modal it opened. Use the callback's Type
+// field to tell them apart. For info on shouldRetry please see the comment for
+// the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type InteractionHandler func(ctx Context, ic *slack.InteractionCallback) (shouldRetry, discarded bool, err error)
+
+// SlashCommandHandler is the handler for slash commands. The gateway has
+// already acknowledged the command, so the handler needs to respond using
+// RespondSlash. For info on shouldRetry please see the comment for the
+// MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type SlashCommandHandler func(ctx Context, sc *slack.SlashCommand) (shouldRetry, discarded bool, err error)
+
+// ScheduledJobEvent is the event published by the scheduler when a job fires.
+type ScheduledJobEvent struct {
+	// Name is the name of the job.
+	Name string `json:"name"`
+
+	// ScheduledTime is the Unix time the job was due to fire.
+	ScheduledTime int64 `json:"scheduled_time"`
+
+	// Payload is the job's payload, as it was defined.
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// ScheduledJobHandler is the handler for scheduled jobs firing. All jobs are
+// published to the same stream, so the handler should switch on the job's
+// Name. For info on shouldRetry please see the comment for the MessageHandler
+// type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type ScheduledJobHandler func(ctx Context, sj *ScheduledJobEvent) (shouldRetry, discarded bool, err error)
+
+// ModerationEvent is the event published when a detector flags a member.
+type ModerationEvent struct {
+	// Detector is the name of the detector that flagged the member, like
+	// "spam".
+	Detector string `json:"detector"`
+
+	// Kind is what the member was flagged for, specific to the detector, like
+	// "flood".
+	Kind string `json:"kind"`
+
+	// Reason is a short, human-readable, reason for the flag.
+	Reason string `json:"reason"`
+
+	UserID    string `json:"user_id"`
+	ChannelID string `json:"channel_id"`
+	MessageTS string `json:"message_ts,omitempty"`
+
+	// Channels are the other channels involved, like those the same message
+	// was posted in.
+	Channels []string `json:"channels,omitempty"`
+}
+
+// ModerationHandler is the handler for moderation events. For info on
+// shouldRetry please see the comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type ModerationHandler func(ctx Context, me *ModerationEvent) (shouldRetry, discarded bool, err error)
+
+// RawHandler is the handler for events registered with RegisterRawHandler. It
+// is given the event's metadata, and the JSON of the Slack event as-is, so that
+// it can decode event types this package doesn't model. For info on
+// shouldRetry please see the comment for the MessageHandler type.
+//
+// If discarded is true, the returend error isn't treated as an error but
+// instead an informational message.
+type RawHandler func(ctx Context, meta EventMetadata, data []byte) (shouldRetry, discarded bool, err error)
+
+// HandlerOpts are the per-stream options for a registered handler.
+type HandlerOpts struct {
+	// Timeout is how long the handler has to complete, before its context is
+	// canceled.
+	Timeout time.Duration
+
+	// Concurrency is how many of the stream's messages are handled at once.
+	// If this and Prefetch are left at zero, the stream shares the default
+	// consumer with other streams. When several handlers are registered on a
+	// stream, this and Prefetch are taken from the first one.
+	Concurrency int
+
+	// Prefetch is how many of the stream's messages are buffered locally,
+	// waiting to be handled. Defaults to Concurrency if left at zero.
+	Prefetch int
+
+	// IgnoreSelf drops messages sent by the bot itself before they get to the
+	// handler, so it can't end up replying to its own replies. It's only used
+	// by the message and message changed handlers.
+	IgnoreSelf bool
+
+	// IgnoreBots drops messages sent by any bot, including this one, before
+	// they get to the handler. It's only used by the message and message
+	// changed handlers.
+	IgnoreBots bool
+
+	// IgnoreSubtypes drops messages with any of these subtypes, like
+	// "channel_join" or "bot_message", before they get to the handler. They're
+	// dropped before the event is decoded, so it's cheaper than the handler
+	// checking. It's only used by the message and message changed handlers.
+	IgnoreSubtypes []string
+}
+
 // Publisher is the interface for the workqueue publish behavior.
 type Publisher interface {
-	Publish(e Event, eventTimestamp int64, eventID, requetID string, jsonData []byte) error
+	Publish(ctx context.Context, e Event, p Priority, eventTimestamp int64, eventID, requetID string, jsonData []byte) error
+	PublishAt(ctx context.Context, e Event, at time.Time, p Priority, eventTimestamp int64, eventID, requestID string, jsonData []byte) error
+	PublishAfter(ctx context.Context, e Event, d time.Duration, p Priority, eventTimestamp int64, eventID, requestID string, jsonData []byte) error
 }
 
 // Registerer is the interface for handler registrations within the workqueue.
 type Registerer interface {
-	RegisterTeamJoinsHandler(timeout time.Duration, fn TeamJoinHandler)
-	RegisterChannelJoinsHandler(timeout time.Duration, fn ChannelJoinHandler)
-	RegisterPublicMessagesHandler(timeout time.Duration, fn MessageHandler)
-	RegisterPrivateMessagesHandler(timeout time.Duration, fn MessageHandler)
+	RegisterTeamJoinsHandler(opts HandlerOpts, fn TeamJoinHandler)
+	RegisterChannelJoinsHandler(opts HandlerOpts, fn ChannelJoinHandler)
+	RegisterChannelLeavesHandler(opts HandlerOpts, fn ChannelLeaveHandler)
+	RegisterUserDeactivatedHandler(opts HandlerOpts, fn UserDeactivatedHandler)
+	RegisterChannelChangesHandler(opts HandlerOpts, fn ChannelChangeHandler)
+	RegisterChannelCreatedHandler(opts HandlerOpts, fn ChannelCreatedHandler)
+	RegisterChannelRenamedHandler(opts HandlerOpts, fn ChannelRenamedHandler)
+	RegisterChannelArchivedHandler(opts HandlerOpts, fn ChannelArchivedHandler)
+	RegisterChannelUnarchivedHandler(opts HandlerOpts, fn ChannelUnarchivedHandler)
+	RegisterSubteamHandler(opts HandlerOpts, fn SubteamHandler)
+	RegisterPublicMessagesHandler(opts HandlerOpts, fn MessageHandler)
+	RegisterPrivateMessagesHandler(opts HandlerOpts, fn MessageHandler)
+	RegisterMessageChangedHandler(opts HandlerOpts, fn MessageChangedHandler)
+	RegisterMessageDeletedHandler(opts HandlerOpts, fn MessageDeletedHandler)
+	RegisterFileSharedHandler(opts HandlerOpts, fn FileSharedHandler)
+	RegisterReactionAddedHandler(opts HandlerOpts, fn ReactionHandler)
+	RegisterReactionRemovedHandler(opts HandlerOpts, fn ReactionHandler)
+	RegisterInteractionHandler(opts HandlerOpts, fn InteractionHandler)
+	RegisterSlashCommandHandler(opts HandlerOpts, fn SlashCommandHandler)
+	RegisterScheduledJobHandler(opts HandlerOpts, fn ScheduledJobHandler)
+	RegisterModerationHandler(opts HandlerOpts, fn ModerationHandler)
+	RegisterRawHandler(stream string, opts HandlerOpts, fn RawHandler)
 }
 
 // Q is an interface to describe the entirety of the workqueue.
@@ -102,8 +531,8 @@ type Config struct {
 	ConsumerName string
 
 	// ConsumerGroup is likely this node's application or service name. Leave
-	// blank to use hostname, although that's not recommended. If you are only
-	// producing events this is safe to be kept blank.
+	// blank to use "redisqueue", although that's not recommended. If you are
+	// only producing events this is safe to be kept blank.
 	ConsumerGroup string
 
 	// VisibilityTimeout is how long a consumer will wait for others to finish a
@@ -111,33 +540,177 @@ type Config struct {
 	// only a producer this can be left as its zero value.
 	VisibilityTimeout time.Duration
 
-	// RedisClient is the *redis.Client to use for the workqueue.
-	RedisClient *redis.Client
+	// RedisClient is the Redis client to use for the workqueue. Reclaiming
+	// messages needs Redis 6.2 or later. It can be left nil with
+	// BackendMemory, in which case Context.Allow always fails.
+	RedisClient redis.UniversalClient
+
+	// Backend is what messages are moved through. Leave blank to use
+	// BackendRedis, or set to BackendMemory to not need Redis for the
+	// workqueue itself, so long as everything publishing and consuming shares
+	// this *I.
+	Backend string
 
 	// Logger is the logger
 	Logger *zerolog.Logger
 
-	// SlackClient is the client we give to handlers
-	SlackClient *slack.Client
+	// HandlerLogger, if set, returns the logger for the named handler from the
+	// one its messages are logged with, like to give it its own log level.
+	// Generally this is config.HandlerLogger.
+	HandlerLogger func(l zerolog.Logger, handler string) zerolog.Logger
+
+	// ErrorReporter is told about handler panics, the handler errors messages
+	// are dead-lettered for, and messages that can't be parsed. Leave nil to
+	// only log them.
+	ErrorReporter ErrorReporter
+
+	// SlackClient is the client we give to handlers. Generally this is a
+	// *slack.Client, or something wrapping one like a *postqueue.Queue.
+	SlackClient SlackClient
 
 	// SlackUser is the slack user that this consumer is running as.
 	SlackUser *slack.User
 
+	// Workspaces is the WorkspaceSvc that events from the other workspaces
+	// the app is installed to get their SlackClient and SlackUser from.
+	// Generally this is implemented by an *installs.Resolver. Leave nil to
+	// handle all events with SlackClient and SlackUser.
+	Workspaces WorkspaceSvc
+
+	// Platforms are the clients, and bot users, for events from chat
+	// platforms other than Slack, by the platform they're published with,
+	// like a *discord.Client for "discord". Events from a platform without
+	// one fail without being retried.
+	Platforms map[string]Workspace
+
 	// ChannelCache is the cache the workqueue will present as the ChannelSvc.
 	// Generally this is implemented by a *cache.Channel.
 	ChannelCache ChannelSvc
+
+	// UserGroupCache is the cache used by Context.UserInGroup. Generally this
+	// is implemented by a *cache.UserGroup.
+	UserGroupCache UserGroupSvc
+
+	// EmojiCache is the cache used by Context.EmojiExists. Generally this is
+	// implemented by a *cache.Emoji.
+	EmojiCache EmojiSvc
+
+	// Settings is the SettingsSvc used by Context.ChannelSettings. Generally
+	// this is implemented by a *settings.Store.
+	Settings SettingsSvc
+
+	// Store is the key-value store returned by Context.Store. Leave nil to
+	// use a *storage.Redis using RedisClient, or a *storage.Memory if that's
+	// nil too.
+	Store storage.Store
+
+	// Permissions is the PermissionSvc used by Context.UserHasRole. Leave nil
+	// if handlers don't need it.
+	Permissions PermissionSvc
+
+	// DeadLetterStream is the Redis stream that permanently failed messages
+	// are published to, along with metadata about the failure. Leave blank to
+	// use DefaultDeadLetterStream.
+	DeadLetterStream string
+
+	// RetryPolicy is how messages from all streams are retried when their
+	// handler fails. Any fields left at their zero value are taken from
+	// DefaultRetryPolicy.
+	RetryPolicy RetryPolicy
+
+	// StreamRetryPolicies overrides RetryPolicy for specific streams.
+	StreamRetryPolicies map[Event]RetryPolicy
+
+	// Metrics is where the workqueue records its Prometheus metrics. Leave nil
+	// to not record any.
+	Metrics *Metrics
+
+	// Codec is how published event payloads are encoded. Leave nil to use
+	// JSONCodec. Consumers decode payloads with whichever Codec they were
+	// published with.
+	Codec Codec
+
+	// Compression is how published event payloads are compressed, after being
+	// encoded with the Codec. Leave blank to not compress them, or set to
+	// CompressionGzip. Small payloads are never compressed.
+	Compression string
+
+	// Keyring decrypts the event payloads that were published encrypted, and
+	// encrypts published ones if EncryptPayloads is set. Leave nil if payloads
+	// aren't encrypted.
+	Keyring Keyring
+
+	// EncryptPayloads is whether published event payloads are encrypted with
+	// the Keyring, after being encoded and compressed, since they hold message
+	// text and user IDs. They stay encrypted when they're dead-lettered or
+	// replayed, so consumers need the Keyring to handle them.
+	EncryptPayloads bool
 }
 
-// I is the workqueue struct, which satisfies Q.
-type I struct {
-	p *redisqueue.Producer
-	c *redisqueue.Consumer
+const (
+	// DefaultDeadLetterStream is the default Config.DeadLetterStream.
+	DefaultDeadLetterStream = "dead_letter"
 
-	l *zerolog.Logger
+	// streamMaxLength is roughly how many messages are kept in each stream
+	streamMaxLength = 1024
+)
 
-	sc   *slack.Client
+// I is the workqueue struct, which satisfies Q.
+type I struct {
+	c *consumer
+
+	// cc are the consumers for high priority streams, and for streams with
+	// their own HandlerOpts
+	cc      []*consumer
+	hc      *consumer
+	scs     map[string]*consumer
+	copts   consumerOptions
+	cshared int
+	pg      priorityGate
+
+	l  *zerolog.Logger
+	hl func(zerolog.Logger, string) zerolog.Logger
+	er ErrorReporter
+	r  redis.UniversalClient
+	t  transport
+	ps *pauseState
+
+	sc   SlackClient
 	self *slack.User
+	ws   WorkspaceSvc
+	pfs  map[string]Workspace
 	cs   ChannelSvc
+
+	deadLetter   string
+	retry        RetryPolicy
+	retries      map[string]RetryPolicy
+	stopSchedule chan struct{}
+	stopOnce     sync.Once
+
+	// stopCtx is canceled on Shutdown, to stop messages waiting to start
+	stopCtx  context.Context
+	stopWait context.CancelFunc
+
+	// streams are all the streams handlers are registered on
+	streams []string
+
+	draining int32
+	done     chan struct{}
+
+	m           *Metrics
+	codec       Codec
+	compression string
+	kr          Keyring
+	encrypt     bool
+
+	// targets are the handlers registered on each stream
+	targets map[string][]*handlerTarget
+
+	perms PermissionSvc
+	emoji EmojiSvc
+	ugs   UserGroupSvc
+	store storage.Store
+	sets  SettingsSvc
 }
 
 // compile time check: does *I satisfy Q?
@@ -147,16 +720,7 @@ var _ Q = (*I)(nil)
 // visibilityTimeout can be left at their zero value if you're only using I to
 // publish.
 func New(cfg Config) (*I, error) {
-	p, err := redisqueue.NewProducerWithOptions(&redisqueue.ProducerOptions{
-		ApproximateMaxLength: true,
-		StreamMaxLength:      1024,
-		RedisClient:          cfg.RedisClient,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to make producer: %w", err)
-	}
-
-	c, err := redisqueue.NewConsumerWithOptions(&redisqueue.ConsumerOptions{
+	copts := consumerOptions{
 		Name:              cfg.ConsumerName,
 		GroupName:         cfg.ConsumerGroup,
 		VisibilityTimeout: cfg.VisibilityTimeout,
@@ -164,185 +728,628 @@ func New(cfg Config) (*I, error) {
 		ReclaimInterval:   time.Second,
 		BufferSize:        1,
 		Concurrency:       2,
-		RedisClient:       cfg.RedisClient,
-	})
+	}.withDefaults()
+
+	dl := cfg.DeadLetterStream
+	if len(dl) == 0 {
+		dl = DefaultDeadLetterStream
+	}
+
+	t, err := newTransport(cfg.Backend, cfg.RedisClient)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare consumer: %w", err)
+		return nil, err
 	}
 
+	retries := make(map[string]RetryPolicy, len(cfg.StreamRetryPolicies))
+
+	for e, rp := range cfg.StreamRetryPolicies {
+		retries[string(e)] = rp.withDefaults()
+	}
+
+	ps := newPauseState()
+
 	i := &I{
-		p:    p,
-		c:    c,
-		l:    cfg.Logger,
-		sc:   cfg.SlackClient,
-		self: cfg.SlackUser,
-		cs:   cfg.ChannelCache,
+		c:            newConsumer(t, ps, cfg.Logger, cfg.Metrics, copts),
+		copts:        copts,
+		scs:          make(map[string]*consumer),
+		l:            cfg.Logger,
+		hl:           cfg.HandlerLogger,
+		er:           cfg.ErrorReporter,
+		r:            cfg.RedisClient,
+		t:            t,
+		ps:           ps,
+		sc:           cfg.SlackClient,
+		self:         cfg.SlackUser,
+		ws:           cfg.Workspaces,
+		pfs:          cfg.Platforms,
+		cs:           cfg.ChannelCache,
+		deadLetter:   dl,
+		retry:        cfg.RetryPolicy.withDefaults(),
+		retries:      retries,
+		stopSchedule: make(chan struct{}),
+		done:         make(chan struct{}),
+		m:            cfg.Metrics,
+		codec:        cfg.Codec,
+		compression:  cfg.Compression,
+		kr:           cfg.Keyring,
+		encrypt:      cfg.EncryptPayloads,
+		targets:      make(map[string][]*handlerTarget),
+		perms:        cfg.Permissions,
+		emoji:        cfg.EmojiCache,
+		ugs:          cfg.UserGroupCache,
+		store:        cfg.Store,
+		sets:         cfg.Settings,
+	}
+
+	i.stopCtx, i.stopWait = context.WithCancel(context.Background())
+
+	if i.store == nil {
+		if i.r != nil {
+			i.store = storage.NewRedis(i.r)
+		} else {
+			i.store = storage.NewMemory()
+		}
+	}
+
+	if i.codec == nil {
+		i.codec = JSONCodec
+	}
+
+	if !validCompression(i.compression) {
+		return nil, fmt.Errorf("unknown payload compression %q", i.compression)
+	}
+
+	if i.encrypt && i.kr == nil {
+		return nil, errors.New("encrypting payloads requires a keyring")
 	}
 
 	return i, nil
 }
 
-// Run wraps the redisqueue.Consumer.Run method
+// Run handles messages from the streams with registered handlers, until
+// Shutdown is called. It returns once the messages already read are handled.
 func (i *I) Run() {
-	i.c.Run()
+	defer close(i.done)
+
+	go i.runSchedule(i.stopSchedule)
+	go i.watchPauses(i.stopSchedule)
+
+	consumers := i.cc
+	if i.cshared > 0 {
+		consumers = append(consumers, i.c)
+	}
+
+	var wg sync.WaitGroup
+
+	for _, c := range consumers {
+		wg.Add(1)
+
+		go func(c *consumer) {
+			defer wg.Done()
+			c.run()
+		}(c)
+	}
+
+	wg.Wait()
 }
 
-// Shutdown wraps the redisqueue.Consumer.Shutdown method
+// Shutdown stops the consumers reading messages, and stops publishing
+// scheduled messages. It doesn't wait for Run to return.
 func (i *I) Shutdown() {
-	i.c.Shutdown()
-}
-
-// Publish takes an Event, which roughly map to different Slack event types, the event timestamp (from the Slack side),
-func (i *I) Publish(e Event, eventTimestamp int64, eventID, requestID string, jsonData []byte) error {
-	return i.p.Enqueue(&redisqueue.Message{
-		Stream: string(e),
-		Values: map[string]interface{}{
-			"request_id": requestID,
-			"gateway_ts": strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10),
-			"event_ts":   strconv.FormatInt(eventTimestamp, 10),
-			"event_id":   eventID,
-			"json":       string(jsonData),
-		},
+	i.stopOnce.Do(func() {
+		close(i.stopSchedule)
+		i.stopWait()
 	})
+
+	i.c.shutdown()
+
+	for _, c := range i.cc {
+		c.shutdown()
+	}
+}
+
+// consumerFor returns the consumer a stream with opts should be registered on,
+// at priority p. High priority streams share their own consumer, so their
+// messages aren't buffered behind lower priority ones. Streams without their
+// own concurrency settings share the default consumer.
+func (i *I) consumerFor(stream string, opts HandlerOpts, p Priority) *consumer {
+	if p == PriorityHigh {
+		if i.hc == nil {
+			i.hc = i.addConsumer(i.copts)
+		}
+
+		return i.hc
+	}
+
+	if opts.Concurrency <= 0 && opts.Prefetch <= 0 {
+		i.cshared++
+		return i.c
+	}
+
+	// the normal and low priority streams share one consumer
+	if c, ok := i.scs[stream]; ok {
+		return c
+	}
+
+	co := i.copts
+
+	if opts.Concurrency > 0 {
+		co.Concurrency = opts.Concurrency
+	}
+
+	co.BufferSize = opts.Prefetch
+	if co.BufferSize <= 0 {
+		co.BufferSize = co.Concurrency
+	}
+
+	c := i.addConsumer(co)
+	i.scs[stream] = c
+
+	return c
+}
+
+func (i *I) addConsumer(co consumerOptions) *consumer {
+	c := newConsumer(i.t, i.ps, i.l, i.m, co)
+	i.cc = append(i.cc, c)
+
+	return c
+}
+
+func (i *I) retryPolicy(stream string) RetryPolicy {
+	if rp, ok := i.retries[stream]; ok {
+		return rp
+	}
+
+	return i.retry
+}
+
+// Publish takes an Event, which roughly map to different Slack event types, the
+// Priority to publish it at, the event timestamp (from the Slack side), the
+// event and request IDs, and the event JSON, and publishes it to the event's
+// stream for that priority. The workspace the event is from is taken from ctx,
+// if it was given one with WithTeamID.
+func (i *I) Publish(ctx context.Context, e Event, p Priority, eventTimestamp int64, eventID, requestID string, jsonData []byte) error {
+	stream := priorityStream(string(e), p)
+
+	values, err := i.gatewayValues(TeamIDFromContext(ctx), eventTimestamp, eventID, requestID, jsonData)
+	if err != nil {
+		return err
+	}
+
+	if err := i.enqueue(ctx, stream, values); err != nil {
+		return err
+	}
+
+	i.m.observePublish(stream)
+
+	return nil
+}
+
+// enqueue adds the values to the stream as a new message, trimming the stream
+// to roughly streamMaxLength messages.
+func (i *I) enqueue(ctx context.Context, stream string, values map[string]interface{}) error {
+	return i.t.add(ctx, stream, values)
+}
+
+// gatewayValues returns the message values published for an event, which
+// parseGatewayMessage reads back. The "json" field holds the payload encoded
+// with the Codec, which is only JSON for JSONCodec, and compressed and
+// encrypted if they're configured.
+func (i *I) gatewayValues(teamID string, eventTimestamp int64, eventID, requestID string, jsonData []byte) (map[string]interface{}, error) {
+	payload, err := i.codec.Marshal(jsonData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s payload: %w", i.codec.Name(), err)
+	}
+
+	payload, compression, err := compress(i.compression, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var encryption string
+
+	if i.encrypt {
+		if payload, encryption, err = encrypt(i.kr, eventID, payload); err != nil {
+			return nil, err
+		}
+	}
+
+	values := map[string]interface{}{
+		"request_id": requestID,
+		"gateway":    i.copts.Name,
+		"gateway_ts": strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10),
+		"event_ts":   strconv.FormatInt(eventTimestamp, 10),
+		"event_id":   eventID,
+		"json":       string(payload),
+		codecField:   i.codec.Name(),
+	}
+
+	if len(compression) > 0 {
+		values[compressionField] = compression
+	}
+
+	if len(encryption) > 0 {
+		values[encryptionField] = encryption
+	}
+
+	if len(teamID) > 0 {
+		values["team_id"] = teamID
+	}
+
+	return values, nil
 }
 
 // RegisterPublicMessagesHandler is the method to register a new handler for
-// public Slack messages. That would be those sent to a public channel. The
-// timeout argument specifies how long the handler has to complete, before its
-// context is canceled.
-func (i *I) RegisterPublicMessagesHandler(timeout time.Duration, fn MessageHandler) {
-	i.registerMessageHandler(slackPublicMessage, timeout, fn)
+// public Slack messages. That would be those sent to a public channel.
+func (i *I) RegisterPublicMessagesHandler(opts HandlerOpts, fn MessageHandler) {
+	i.registerMessageHandler(slackPublicMessage, opts, fn)
 }
 
 // RegisterPrivateMessagesHandler is the method to register a new handler for
 // private Slack messages. This would be those sent to a private channel, a
-// 1-on-1 DM, or a group DM. The timeout argument specifies how long the handler
-// has to complete, before its context is canceled.
-func (i *I) RegisterPrivateMessagesHandler(timeout time.Duration, fn MessageHandler) {
-	i.registerMessageHandler(slackPrivateMessage, timeout, fn)
+// 1-on-1 DM, or a group DM.
+func (i *I) RegisterPrivateMessagesHandler(opts HandlerOpts, fn MessageHandler) {
+	i.registerMessageHandler(slackPrivateMessage, opts, fn)
 }
 
-func (i *I) registerMessageHandler(stream string, timeout time.Duration, fn MessageHandler) {
-	i.c.RegisterWithLastID(stream, "$", messageHandlerFactory(i.l, i.sc, i.self, i.cs, timeout, fn))
+func (i *I) registerMessageHandler(stream string, opts HandlerOpts, fn MessageHandler) {
+	i.register(stream, "message", opts, decodeMessage(opts, fn))
+}
+
+// decodeMessage returns the decodeFunc for a message handler, which drops the
+// messages that opts says to before invoking it.
+func decodeMessage(opts HandlerOpts, fn MessageHandler) decodeFunc {
+	return func(data []byte) (invokeFunc, error) {
+		if st, drop := opts.dropSubtype(data); drop {
+			return func(ctx Context) (bool, bool, error) {
+				ctx.Logger().Debug().
+					Str("subtype", st).
+					Msg("dropped message subtype before handling it")
+
+				return false, false, nil
+			}, nil
+		}
+
+		var me *slackevents.MessageEvent
+		if err := json.Unmarshal(data, &me); err != nil {
+			return nil, err
+		}
+
+		return func(ctx Context) (bool, bool, error) {
+			if reason, drop := opts.dropMessage(ctx, me); drop {
+				ctx.Logger().Debug().
+					Str("reason", reason).
+					Msg("dropped message before handling it")
+
+				return false, false, nil
+			}
+
+			return fn(ctx, me)
+		}, nil
+	}
+}
+
+// RegisterMessageChangedHandler registers the handler for messages being
+// edited, in any type of channel.
+func (i *I) RegisterMessageChangedHandler(opts HandlerOpts, fn MessageChangedHandler) {
+	i.register(slackMessageChanged, "message_changed", opts, decodeMessage(opts, MessageHandler(fn)))
+}
+
+// RegisterMessageDeletedHandler registers the handler for messages being
+// deleted, in any type of channel.
+func (i *I) RegisterMessageDeletedHandler(opts HandlerOpts, fn MessageDeletedHandler) {
+	i.register(slackMessageDeleted, "message_deleted", opts, func(data []byte) (invokeFunc, error) {
+		var md *MessageDeletedEvent
+		if err := json.Unmarshal(data, &md); err != nil {
+			return nil, err
+		}
+
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, md) }, nil
+	})
 }
 
 // RegisterTeamJoinsHandler registers the handler for events related to people
 // joining the Slack workspace.
-func (i *I) RegisterTeamJoinsHandler(timeout time.Duration, fn TeamJoinHandler) {
-	i.c.RegisterWithLastID(slackTeamJoin, "$", teamJoinHandlerFactory(i.l, i.sc, i.self, i.cs, timeout, fn))
+func (i *I) RegisterTeamJoinsHandler(opts HandlerOpts, fn TeamJoinHandler) {
+	i.register(slackTeamJoin, "team_join", opts, func(data []byte) (invokeFunc, error) {
+		var tj *slack.TeamJoinEvent
+		if err := json.Unmarshal(data, &tj); err != nil {
+			return nil, err
+		}
+
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, tj) }, nil
+	})
 }
 
 // RegisterChannelJoinsHandler registers the handler for events related to
 // people joining channels in the Slack workspace.
-func (i *I) RegisterChannelJoinsHandler(timeout time.Duration, fn ChannelJoinHandler) {
-	i.c.RegisterWithLastID(slackChannelJoin, "$", channelJoinHandlerFactory(i.l, i.sc, i.self, i.cs, timeout, fn))
+func (i *I) RegisterChannelJoinsHandler(opts HandlerOpts, fn ChannelJoinHandler) {
+	i.register(slackChannelJoin, "channel_join", opts, func(data []byte) (invokeFunc, error) {
+		var cj *slackevents.MemberJoinedChannelEvent
+		if err := json.Unmarshal(data, &cj); err != nil {
+			return nil, err
+		}
+
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, cj) }, nil
+	})
 }
 
-func messageHandlerFactory(baseLogger *zerolog.Logger, sc *slack.Client, botUser *slack.User, csvc ChannelSvc, timeout time.Duration, fn MessageHandler) redisqueue.ConsumerFunc {
-	flogger := baseLogger.With().Str("handler", "message").Logger()
+// RegisterChannelLeavesHandler registers the handler for events related to
+// people leaving channels in the Slack workspace.
+func (i *I) RegisterChannelLeavesHandler(opts HandlerOpts, fn ChannelLeaveHandler) {
+	i.register(slackChannelLeave, "channel_leave", opts, func(data []byte) (invokeFunc, error) {
+		var cl *ChannelLeaveEvent
+		if err := json.Unmarshal(data, &cl); err != nil {
+			return nil, err
+		}
 
-	return func(m *redisqueue.Message) error {
-		start := time.Now()
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, cl) }, nil
+	})
+}
 
-		// build message-local logging context
-		logger := flogger.With().
-			Str("redis_message", m.ID).
-			Str("redis_stream", m.Stream).
-			Logger()
+// RegisterUserDeactivatedHandler registers the handler for members of the
+// Slack workspace being deactivated.
+func (i *I) RegisterUserDeactivatedHandler(opts HandlerOpts, fn UserDeactivatedHandler) {
+	i.register(slackUserDeactivate, "user_deactivated", opts, func(data []byte) (invokeFunc, error) {
+		var uc *slack.UserChangeEvent
+		if err := json.Unmarshal(data, &uc); err != nil {
+			return nil, err
+		}
 
-		eid, et, gt, d, err := parseGatewayMessage(m)
-		if err != nil {
-			logger.Error().
-				Err(err).
-				TimeDiff("duration", time.Now(), start).
-				Msg("failed to parse message from gateway")
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, uc) }, nil
+	})
+}
 
-			return nil
+// decodeChannelChange returns the decodeFunc for the channel change handlers.
+func decodeChannelChange(fn ChannelChangeHandler) decodeFunc {
+	return func(data []byte) (invokeFunc, error) {
+		var cc *ChannelChangeEvent
+		if err := json.Unmarshal(data, &cc); err != nil {
+			return nil, err
 		}
 
-		// log time fired on Slack side, and time it was enqueued
-		logger = logger.With().
-			Time("event_time", et).
-			Str("event_id", eid).
-			Time("enqueued_time", gt).Logger()
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, cc) }, nil
+	}
+}
 
-		var sm *slackevents.MessageEvent
+// RegisterChannelChangesHandler registers the handler for events related to
+// public channels being created, renamed, archived, or unarchived. Each of
+// those has its own stream, so the handler isn't called for a channel's events
+// in the order they happened.
+func (i *I) RegisterChannelChangesHandler(opts HandlerOpts, fn ChannelChangeHandler) {
+	decode := decodeChannelChange(fn)
 
-		if err = json.Unmarshal([]byte(d), &sm); err != nil {
-			logger.Error().
-				Err(err).
-				TimeDiff("duration", time.Now(), start).
-				Msg("failed to parse message JSON")
+	for _, stream := range []string{slackChannelCreate, slackChannelRename, slackChannelArchive, slackChannelUnarch} {
+		i.register(stream, "channel_change", opts, decode)
+	}
+}
 
-			// we can't process it
-			return nil
+// RegisterChannelCreatedHandler registers the handler for public channels
+// being created.
+func (i *I) RegisterChannelCreatedHandler(opts HandlerOpts, fn ChannelCreatedHandler) {
+	i.register(slackChannelCreate, "channel_created", opts, decodeChannelChange(ChannelChangeHandler(fn)))
+}
+
+// RegisterChannelRenamedHandler registers the handler for public channels
+// being renamed.
+func (i *I) RegisterChannelRenamedHandler(opts HandlerOpts, fn ChannelRenamedHandler) {
+	i.register(slackChannelRename, "channel_renamed", opts, decodeChannelChange(ChannelChangeHandler(fn)))
+}
+
+// RegisterChannelArchivedHandler registers the handler for public channels
+// being archived.
+func (i *I) RegisterChannelArchivedHandler(opts HandlerOpts, fn ChannelArchivedHandler) {
+	i.register(slackChannelArchive, "channel_archived", opts, decodeChannelChange(ChannelChangeHandler(fn)))
+}
+
+// RegisterChannelUnarchivedHandler registers the handler for public channels
+// being unarchived.
+func (i *I) RegisterChannelUnarchivedHandler(opts HandlerOpts, fn ChannelUnarchivedHandler) {
+	i.register(slackChannelUnarch, "channel_unarchived", opts, decodeChannelChange(ChannelChangeHandler(fn)))
+}
+
+// RegisterSubteamHandler registers the handler for events related to user
+// groups changing.
+func (i *I) RegisterSubteamHandler(opts HandlerOpts, fn SubteamHandler) {
+	i.register(slackSubteamUpdate, "subteam_updated", opts, func(data []byte) (invokeFunc, error) {
+		var se *SubteamEvent
+		if err := json.Unmarshal(data, &se); err != nil {
+			return nil, err
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, se) }, nil
+	})
+}
 
-		wqctx := ctxer{
-			Context: ctx,
-			s:       sc,
-			l:       &logger,
-			u:       botUser,
-			c:       csvc,
-			e:       EventMetadata{eid, et, gt, m.ID},
+// RegisterFileSharedHandler registers the handler for events related to files
+// being shared in channels the bot is a member of.
+func (i *I) RegisterFileSharedHandler(opts HandlerOpts, fn FileSharedHandler) {
+	i.register(slackFileShared, "file_shared", opts, func(data []byte) (invokeFunc, error) {
+		var fs *FileSharedEvent
+		if err := json.Unmarshal(data, &fs); err != nil {
+			return nil, err
 		}
 
-		// used to calculate handler duration
-		bht := time.Now()
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, fs) }, nil
+	})
+}
 
-		shouldRetry, discarded, err := fn(wqctx, sm)
+// RegisterReactionAddedHandler registers the handler for events related to
+// people adding emoji reactions.
+func (i *I) RegisterReactionAddedHandler(opts HandlerOpts, fn ReactionHandler) {
+	i.registerReactionHandler(slackReactionAdd, "reaction_added", opts, fn)
+}
 
-		// handler runtime duration
-		hrd := time.Since(bht)
+// RegisterReactionRemovedHandler registers the handler for events related to
+// people removing emoji reactions.
+func (i *I) RegisterReactionRemovedHandler(opts HandlerOpts, fn ReactionHandler) {
+	i.registerReactionHandler(slackReactionRemove, "reaction_removed", opts, fn)
+}
 
-		cancel()
+func (i *I) registerReactionHandler(stream, handlerName string, opts HandlerOpts, fn ReactionHandler) {
+	i.register(stream, handlerName, opts, func(data []byte) (invokeFunc, error) {
+		var re *ReactionEvent
+		if err := json.Unmarshal(data, &re); err != nil {
+			return nil, err
+		}
 
-		logger = logger.With().Dur("handler_duration", hrd).Logger()
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, re) }, nil
+	})
+}
 
-		if err != nil {
-			if discarded {
-				logger.Warn().
-					Err(err).
-					TimeDiff("duration", time.Now(), start).
-					Msg("discarded event")
+// RegisterInteractionHandler registers the handler for interactions with the
+// bot's messages and modals.
+func (i *I) RegisterInteractionHandler(opts HandlerOpts, fn InteractionHandler) {
+	i.register(slackInteraction, "interaction", opts, func(data []byte) (invokeFunc, error) {
+		var ic *slack.InteractionCallback
+		if err := json.Unmarshal(data, &ic); err != nil {
+			return nil, err
+		}
 
-				return nil
-			}
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, ic) }, nil
+	})
+}
 
-			logger.Error().Err(err).
-				Bool("should_retry", shouldRetry).
-				TimeDiff("duration", time.Now(), start).
-				Msg("handler failed")
+// RegisterSlashCommandHandler registers the handler for the App's slash
+// commands.
+func (i *I) RegisterSlashCommandHandler(opts HandlerOpts, fn SlashCommandHandler) {
+	i.register(slackSlashCommand, "slash_command", opts, func(data []byte) (invokeFunc, error) {
+		var sc *slack.SlashCommand
+		if err := json.Unmarshal(data, &sc); err != nil {
+			return nil, err
+		}
 
-			if shouldRetry {
-				return err
-			}
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, sc) }, nil
+	})
+}
 
-			return nil
+// RegisterScheduledJobHandler registers the handler for scheduled jobs
+// firing.
+func (i *I) RegisterScheduledJobHandler(opts HandlerOpts, fn ScheduledJobHandler) {
+	i.register(scheduledJob, "scheduled_job", opts, func(data []byte) (invokeFunc, error) {
+		var sj *ScheduledJobEvent
+		if err := json.Unmarshal(data, &sj); err != nil {
+			return nil, err
 		}
 
-		logger.Info().
-			TimeDiff("duration", time.Now(), start).
-			Msg("complete")
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, sj) }, nil
+	})
+}
 
-		return nil
+// RegisterModerationHandler registers the handler for moderation events.
+func (i *I) RegisterModerationHandler(opts HandlerOpts, fn ModerationHandler) {
+	i.register(moderation, "moderation", opts, func(data []byte) (invokeFunc, error) {
+		var me *ModerationEvent
+		if err := json.Unmarshal(data, &me); err != nil {
+			return nil, err
+		}
+
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, me) }, nil
+	})
+}
+
+// RegisterRawHandler registers the handler for a stream, without decoding the
+// event JSON. This is meant to be used with the stream for a RawEvent, but can
+// be used with any stream.
+func (i *I) RegisterRawHandler(stream string, opts HandlerOpts, fn RawHandler) {
+	i.register(stream, "raw", opts, func(data []byte) (invokeFunc, error) {
+		return func(ctx Context) (bool, bool, error) { return fn(ctx, ctx.Meta(), data) }, nil
+	})
+}
+
+// register adds the handler to those for the stream. The first handler
+// registered on a stream registers the stream with its consumer, for each
+// Priority, so its HandlerOpts decide the stream's concurrency.
+func (i *I) register(stream, handlerName string, opts HandlerOpts, decode decodeFunc) {
+	targets := i.targets[stream]
+
+	// handler names need to be unique within the stream, so we know which of
+	// them are done when retrying
+	if n := len(targets); n > 0 {
+		handlerName = handlerName + "#" + strconv.Itoa(n+1)
+	}
+
+	i.targets[stream] = append(targets, &handlerTarget{
+		name:    handlerName,
+		timeout: opts.Timeout,
+		decode:  decode,
+	})
+
+	if len(targets) > 0 {
+		return
+	}
+
+	for _, p := range priorities {
+		ps := priorityStream(stream, p)
+		i.streams = append(i.streams, ps)
+		i.consumerFor(stream, opts, p).register(ps, "$", i.handlerFactory(stream, p))
 	}
 }
 
-func teamJoinHandlerFactory(baseLogger *zerolog.Logger, sc *slack.Client, botUser *slack.User, csvc ChannelSvc, timeout time.Duration, fn TeamJoinHandler) redisqueue.ConsumerFunc {
-	flogger := baseLogger.With().Str("handler", "team_join").Logger()
+// decodeFunc unmarshals the event JSON from the gateway into the type the
+// registered handler expects, returning a function to invoke that handler.
+type decodeFunc func(data []byte) (invokeFunc, error)
+
+// invokeFunc calls a registered handler with its already-decoded event.
+type invokeFunc func(ctx Context) (shouldRetry, discarded bool, err error)
+
+func (i *I) handlerFactory(stream string, p Priority) consumeFunc {
+	flogger := i.l.With().Str("priority", p.String()).Logger()
+	policy := i.retryPolicy(stream)
+
+	return func(m *message) error {
+		if i.isDraining() {
+			return errDraining
+		}
+
+		if i.replayedElsewhere(m) {
+			return nil
+		}
+
+		// the message's bookkeeping isn't tied to its handlers' timeouts, so
+		// it's recorded even when they run out of time
+		ctx := context.Background()
 
-	return func(m *redisqueue.Message) error {
 		start := time.Now()
 
+		// let higher priority messages in flight finish first, unless
+		// shutting down
+		if err := i.pg.enter(i.stopCtx, p); err != nil {
+			return errDraining
+		}
+
+		defer i.pg.leave(p)
+
+		// a retried message only goes to the handlers that didn't finish
+		// with it before
+		targets, done := i.pendingTargets(stream, m)
+		handlerNames := targetNames(targets)
+
 		// build message-local logging context
 		logger := flogger.With().
+			Str("handler", handlerNames).
 			Str("redis_message", m.ID).
 			Str("redis_stream", m.Stream).
 			Logger()
 
+		// the deliveries only count this copy of the message, and it may have
+		// been rescheduled for retry before
+		attempts := priorAttempts(m) + m.Deliveries
+
+		logger = logger.With().Int64("attempts", attempts).Logger()
+
+		if attempts > int64(policy.MaxAttempts) {
+			logger.Error().
+				TimeDiff("duration", time.Now(), start).
+				Msg("message exceeded max attempts")
+
+			err := errors.New("exceeded max attempts")
+			i.reportError(err, m, handlerNames, FailureHandler)
+			i.deadLetterMessage(ctx, &logger, m, handlerNames, attempts, err)
+
+			return nil
+		}
+
 		eid, et, gt, d, err := parseGatewayMessage(m)
 		if err != nil {
 			logger.Error().
@@ -350,171 +1357,302 @@ func teamJoinHandlerFactory(baseLogger *zerolog.Logger, sc *slack.Client, botUse
 				TimeDiff("duration", time.Now(), start).
 				Msg("failed to parse message from gateway")
 
+			i.reportError(err, m, handlerNames, FailureParse)
+			i.deadLetterMessage(ctx, &logger, m, handlerNames, attempts, err)
+
 			return nil
 		}
 
+		// these weren't always published, so they're optional
+		rid, gw := stringValue(m, "request_id"), stringValue(m, "gateway")
+
 		// log time fired on Slack side, and time it was enqueued
 		logger = logger.With().
 			Time("event_time", et).
 			Str("event_id", eid).
+			Str("request_id", rid).
+			Str("gateway_consumer", gw).
 			Time("enqueued_time", gt).Logger()
 
-		var stj *slack.TeamJoinEvent
+		raw, err := decrypt(i.kr, stringValue(m, encryptionField), eid, []byte(d))
+		if err == nil {
+			raw, err = decompress(stringValue(m, compressionField), raw)
+		}
 
-		if err = json.Unmarshal([]byte(d), &stj); err != nil {
+		if err == nil {
+			raw, err = decodePayload(stringValue(m, codecField), raw)
+		}
+
+		if err != nil {
 			logger.Error().
 				Err(err).
 				TimeDiff("duration", time.Now(), start).
-				Msg("failed to parse message JSON")
+				Msg("failed to decode message payload")
+
+			i.reportError(err, m, handlerNames, FailureParse)
+			i.deadLetterMessage(ctx, &logger, m, handlerNames, attempts, err)
 
-			// we can't process it
 			return nil
 		}
 
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		meta := EventMetadata{
+			ID:              eid,
+			Time:            et,
+			IngestTime:      gt,
+			RedisEvent:      m.ID,
+			RequestID:       rid,
+			GatewayConsumer: gw,
+			TeamID:          stringValue(m, "team_id"),
+		}
+
+		platform, teamID := eventSource(raw)
+
+		meta.Platform = platform
 
-		wqctx := ctxer{
-			Context: ctx,
-			s:       sc,
-			l:       &logger,
-			u:       botUser,
-			c:       csvc,
-			e:       EventMetadata{eid, et, gt, m.ID},
+		if len(meta.TeamID) == 0 {
+			meta.TeamID = teamID
 		}
 
-		// used to calculate handler duration
-		bht := time.Now()
+		if t, err := eventReplyTarget(raw); err == nil {
+			meta.ThreadTS = t.threadTS
+			meta.ParentUserID = t.parentUserID
+		}
 
-		shouldRetry, discarded, err := fn(wqctx, stj)
+		// fan the event out to each handler, which each have their own
+		// timeout and outcome
+		results := make([]targetResult, len(targets))
 
-		// handler runtime duration
-		hrd := time.Since(bht)
+		var wg sync.WaitGroup
 
-		cancel()
+		for n, t := range targets {
+			wg.Add(1)
 
-		logger = logger.With().Dur("handler_duration", hrd).Logger()
+			go func(n int, t *handlerTarget) {
+				defer wg.Done()
 
-		if err != nil {
-			if discarded {
-				logger.Warn().
-					Err(err).
-					TimeDiff("duration", time.Now(), start).
-					Msg("discarded event")
+				tlogger := i.handlerLogger(logger, t.name)
+				results[n] = i.invokeTarget(&tlogger, m, t, raw, meta, start)
+			}(n, t)
+		}
+
+		wg.Wait()
+
+		var retry []targetResult
+
+		for n, r := range results {
+			switch {
+			case r.err == nil:
+				done = append(done, targets[n].name)
 
-				return nil
+			case r.shouldRetry:
+				retry = append(retry, r)
+
+			default:
+				if !r.reported {
+					i.reportError(r.err, m, targets[n].name, FailureHandler)
+				}
+
+				tlogger := i.handlerLogger(logger, targets[n].name)
+				i.deadLetterMessage(ctx, &tlogger, m, targets[n].name, attempts, r.err)
+				done = append(done, targets[n].name)
 			}
+		}
 
-			logger.Error().Err(err).
-				Bool("should_retry", shouldRetry).
-				TimeDiff("duration", time.Now(), start).
-				Msg("handler failed")
+		if len(retry) == 0 {
+			return nil
+		}
+
+		if attempts >= int64(policy.MaxAttempts) {
+			for _, r := range retry {
+				if !r.reported {
+					i.reportError(r.err, m, r.name, FailureHandler)
+				}
 
-			if shouldRetry {
-				return err
+				tlogger := i.handlerLogger(logger, r.name)
+				i.deadLetterMessage(ctx, &tlogger, m, r.name, attempts, r.err)
 			}
 
 			return nil
 		}
 
+		backoff, serr := i.scheduleRetry(ctx, m, policy, attempts, done)
+		if serr != nil {
+			logger.Error().
+				Err(serr).
+				Msg("failed to schedule retry, leaving it for redelivery")
+
+			for _, r := range retry {
+				i.m.observeResult(m.Stream, r.name, resultRedeliver)
+			}
+
+			return retry[0].err
+		}
+
 		logger.Info().
-			TimeDiff("duration", time.Now(), start).
-			Msg("complete")
+			Dur("backoff", backoff).
+			Int("retried_handlers", len(retry)).
+			Msg("scheduled retry")
+
+		for _, r := range retry {
+			i.m.observeResult(m.Stream, r.name, resultRetry)
+		}
 
 		return nil
 	}
 }
 
-func channelJoinHandlerFactory(baseLogger *zerolog.Logger, sc *slack.Client, botUser *slack.User, csvc ChannelSvc, timeout time.Duration, fn ChannelJoinHandler) redisqueue.ConsumerFunc {
-	flogger := baseLogger.With().Str("handler", "channel_join").Logger()
+// targetResult is the outcome of giving a message to one handler. If err is
+// nil the handler is done with the message, which includes it being discarded.
+type targetResult struct {
+	name        string
+	shouldRetry bool
+	err         error
 
-	return func(m *redisqueue.Message) error {
-		start := time.Now()
+	// reported is whether err was already given to the ErrorReporter
+	reported bool
+}
 
-		// build message-local logging context
-		logger := flogger.With().
-			Str("redis_message", m.ID).
-			Str("redis_stream", m.Stream).
-			Logger()
+// handlerLogger returns the logger for the named handler.
+func (i *I) handlerLogger(logger zerolog.Logger, handler string) zerolog.Logger {
+	if i.hl != nil {
+		logger = i.hl(logger, handler)
+	}
 
-		eid, et, gt, d, err := parseGatewayMessage(m)
-		if err != nil {
-			logger.Error().
-				Err(err).
-				TimeDiff("duration", time.Now(), start).
-				Msg("failed to parse message from gateway")
+	return logger.With().Str("handler", handler).Logger()
+}
 
-			return nil
-		}
+// invokeTarget decodes the event for the handler, and invokes it.
+func (i *I) invokeTarget(logger *zerolog.Logger, m *message, t *handlerTarget, raw []byte, meta EventMetadata, start time.Time) targetResult {
+	invoke, err := t.decode(raw)
+	if err != nil {
+		logger.Error().
+			Err(err).
+			TimeDiff("duration", time.Now(), start).
+			Msg("failed to parse message JSON")
 
-		// log time fired on Slack side, and time it was enqueued
-		logger = logger.With().
-			Time("event_time", et).
-			Str("event_id", eid).
-			Time("enqueued_time", gt).Logger()
+		i.reportError(err, m, t.name, FailureParse)
 
-		var mjce *slackevents.MemberJoinedChannelEvent
+		// we can't process it
+		return targetResult{name: t.name, err: err, reported: true}
+	}
 
-		if err = json.Unmarshal([]byte(d), &mjce); err != nil {
-			logger.Error().
-				Err(err).
-				TimeDiff("duration", time.Now(), start).
-				Msg("failed to parse message JSON")
+	// events the handler publishes are from the same workspace
+	ctx, cancel := context.WithTimeout(WithTeamID(context.Background(), meta.TeamID), t.timeout)
 
-			// we can't process it
-			return nil
-		}
+	sc, self, err := i.workspace(ctx, meta)
+	if err != nil {
+		cancel()
 
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		logger.Error().
+			Err(err).
+			Str("platform", meta.Platform).
+			Str("team_id", meta.TeamID).
+			TimeDiff("duration", time.Now(), start).
+			Msg("failed to get workspace")
 
-		wqctx := ctxer{
-			Context: ctx,
-			s:       sc,
-			l:       &logger,
-			u:       botUser,
-			c:       csvc,
-			e:       EventMetadata{eid, et, gt, m.ID},
-		}
+		// there's no point retrying without a client for the platform
+		return targetResult{name: t.name, shouldRetry: !errors.Is(err, errNoPlatform), err: fmt.Errorf("failed to get workspace %s: %w", meta.TeamID, err)}
+	}
 
-		// used to calculate handler duration
-		bht := time.Now()
+	wqctx := ctxer{
+		Context: ctx,
+		s:       sc,
+		l:       logger,
+		u:       self,
+		c:       i.cs,
+		e:       meta,
+		r:       raw,
+		p:       i.perms,
+		q:       i.r,
+		m:       i.emoji,
+		g:       i.ugs,
+		k:       i.store,
+		t:       i.sets,
+	}
 
-		shouldRetry, discarded, err := fn(wqctx, mjce)
+	// used to calculate handler duration
+	bht := time.Now()
 
-		// handler runtime duration
-		hrd := time.Since(bht)
+	shouldRetry, discarded, err := i.callHandler(wqctx, m, t.name, invoke)
 
-		cancel()
+	// handler runtime duration
+	hrd := time.Since(bht)
 
-		logger = logger.With().Dur("handler_duration", hrd).Logger()
+	i.m.observeDuration(m.Stream, t.name, hrd)
 
-		if err != nil {
-			if discarded {
-				logger.Warn().
-					Err(err).
-					TimeDiff("duration", time.Now(), start).
-					Msg("discarded event")
+	cancel()
 
-				return nil
-			}
+	l := logger.With().Dur("handler_duration", hrd).Logger()
 
-			logger.Error().Err(err).
-				Bool("should_retry", shouldRetry).
+	if err != nil {
+		if discarded {
+			l.Warn().
+				Err(err).
 				TimeDiff("duration", time.Now(), start).
-				Msg("handler failed")
+				Msg("discarded event")
 
-			if shouldRetry {
-				return err
-			}
+			i.m.observeResult(m.Stream, t.name, resultDiscarded)
 
-			return nil
+			return targetResult{name: t.name}
 		}
 
-		logger.Info().
+		var pe *PanicError
+		if errors.As(err, &pe) {
+			l.Error().Err(err).
+				Str("stack", string(pe.Stack)).
+				TimeDiff("duration", time.Now(), start).
+				Msg("handler panicked")
+
+			return targetResult{name: t.name, err: err, reported: true}
+		}
+
+		l.Error().Err(err).
+			Bool("should_retry", shouldRetry).
 			TimeDiff("duration", time.Now(), start).
-			Msg("complete")
+			Msg("handler failed")
 
-		return nil
+		return targetResult{name: t.name, shouldRetry: shouldRetry, err: err}
+	}
+
+	l.Info().
+		TimeDiff("duration", time.Now(), start).
+		Msg("complete")
+
+	i.m.observeResult(m.Stream, t.name, resultSuccess)
+
+	return targetResult{name: t.name}
+}
+
+// deadLetterMessage publishes the original message, plus metadata about its
+// failure, to the dead-letter stream.
+func (i *I) deadLetterMessage(ctx context.Context, logger *zerolog.Logger, m *message, handlerName string, attempts int64, cause error) {
+	i.m.observeResult(m.Stream, handlerName, resultDeadLetter)
+
+	values := make(map[string]interface{}, len(m.Values)+6)
+
+	for k, v := range m.Values {
+		values[k] = v
 	}
+
+	values["dl_stream"] = m.Stream
+	values["dl_redis_id"] = m.ID
+	values["dl_handler"] = handlerName
+	values["dl_error"] = cause.Error()
+	values["dl_attempts"] = strconv.FormatInt(attempts, 10)
+	values["dl_ts"] = strconv.FormatInt(time.Now().UnixNano()/int64(time.Millisecond), 10)
+
+	if err := i.enqueue(ctx, i.deadLetter, values); err != nil {
+		logger.Error().
+			Err(err).
+			Str("dead_letter_stream", i.deadLetter).
+			Msg("failed to publish message to dead-letter stream")
+
+		return
+	}
+
+	logger.Warn().
+		Str("dead_letter_stream", i.deadLetter).
+		Msg("published message to dead-letter stream")
 }
 
 func unix(i int64) (int64, int64) {
@@ -523,7 +1661,14 @@ func unix(i int64) (int64, int64) {
 	return i / 1000, (i % 1000) * int64(time.Millisecond)
 }
 
-func parseGatewayMessage(m *redisqueue.Message) (eventID string, eventTime, gatewayTime time.Time, data string, err error) {
+// stringValue returns the message value for key, or an empty string if it's
+// missing or not a string.
+func stringValue(m *message, key string) string {
+	s, _ := m.Values[key].(string)
+	return s
+}
+
+func parseGatewayMessage(m *message) (eventID string, eventTime, gatewayTime time.Time, data string, err error) {
 	eti, ok := m.Values["event_ts"]
 	if !ok {
 		return "", time.Time{}, time.Time{}, "", errors.New("redis stream malformed: event_ts not present")
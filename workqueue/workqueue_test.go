@@ -0,0 +1,108 @@
+package workqueue
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestChannelChangeEvent_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want ChannelChangeEvent
+	}{
+		{
+			name: "channel_rename",
+			data: `{"type":"channel_rename","channel":{"id":"C123","name":"gophers","created":1360782804}}`,
+			want: ChannelChangeEvent{Type: "channel_rename", ChannelID: "C123", Name: "gophers"},
+		},
+		{
+			name: "channel_archive",
+			data: `{"type":"channel_archive","channel":"C123","user":"U123"}`,
+			want: ChannelChangeEvent{Type: "channel_archive", ChannelID: "C123", User: "U123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got ChannelChangeEvent
+
+			if err := json.Unmarshal([]byte(tt.data), &got); err != nil {
+				t.Fatalf("json.Unmarshal() unexpected error: %v", err)
+			}
+
+			if got != tt.want {
+				t.Fatalf("json.Unmarshal() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestI_RegisterChannelHandlers(t *testing.T) {
+	l := zerolog.Nop()
+
+	q, err := New(Config{Backend: BackendMemory, Logger: &l})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+
+	var changed, archived []string
+
+	q.RegisterChannelChangesHandler(HandlerOpts{}, func(ctx Context, cc *ChannelChangeEvent) (bool, bool, error) {
+		changed = append(changed, cc.Type)
+		return false, false, nil
+	})
+
+	q.RegisterChannelArchivedHandler(HandlerOpts{}, func(ctx Context, cc *ChannelChangeEvent) (bool, bool, error) {
+		archived = append(archived, cc.Type)
+		return false, false, nil
+	})
+
+	tests := []struct {
+		event Event
+		data  string
+		want  []string
+	}{
+		{event: SlackChannelCreated, data: `{"type":"channel_created","channel":{"id":"C123","name":"gophers"}}`, want: []string{"channel_change"}},
+		{event: SlackChannelRenamed, data: `{"type":"channel_rename","channel":{"id":"C123","name":"golang"}}`, want: []string{"channel_change"}},
+		{event: SlackChannelArchived, data: `{"type":"channel_archive","channel":"C123","user":"U123"}`, want: []string{"channel_change", "channel_archived#2"}},
+		{event: SlackChannelUnarchived, data: `{"type":"channel_unarchive","channel":"C123","user":"U123"}`, want: []string{"channel_change"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.event), func(t *testing.T) {
+			targets := q.targets[string(tt.event)]
+
+			var names []string
+			for _, ht := range targets {
+				names = append(names, ht.name)
+			}
+
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Fatalf("handlers = %q, want %q", names, tt.want)
+			}
+
+			for _, ht := range targets {
+				invoke, err := ht.decode([]byte(tt.data))
+				if err != nil {
+					t.Fatalf("decode() unexpected error: %v", err)
+				}
+
+				if _, _, err := invoke(nil); err != nil {
+					t.Fatalf("invoke() unexpected error: %v", err)
+				}
+			}
+		})
+	}
+
+	if want := []string{"channel_created", "channel_rename", "channel_archive", "channel_unarchive"}; !reflect.DeepEqual(changed, want) {
+		t.Fatalf("ChannelChangeHandler got %q, want %q", changed, want)
+	}
+
+	if want := []string{"channel_archive"}; !reflect.DeepEqual(archived, want) {
+		t.Fatalf("ChannelArchivedHandler got %q, want %q", archived, want)
+	}
+}
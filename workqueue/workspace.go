@@ -0,0 +1,121 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/slack-go/slack"
+	"github.com/valyala/fastjson"
+)
+
+// PlatformSlack is the platform of events from Slack, which is the one events
+// without a platform are from. Other platforms' events are published with a
+// "platform" field in the payload, set to the platform's name.
+const PlatformSlack = "slack"
+
+// Workspace is the Slack client, and bot user, for one of the workspaces the
+// app is installed to. For other platforms, it's the client that handlers
+// post with, which does what it can to do the same as the Slack API, and the
+// bot's user on that platform.
+type Workspace struct {
+	SlackClient SlackClient
+	SlackUser   *slack.User
+}
+
+// WorkspaceSvc is an interface providing the workspaces the app is installed
+// to, so events are handled with the bot token of the workspace they're from.
+// Generally this is implemented by an *installs.Resolver.
+type WorkspaceSvc interface {
+	// Workspace returns the workspace for the team, or false if the app
+	// isn't installed to it.
+	Workspace(ctx context.Context, teamID string) (Workspace, bool, error)
+}
+
+type teamIDKey struct{}
+
+// WithTeamID returns a copy of ctx that says events published with it are from
+// the workspace teamID. The gateway uses it to tell the consumers which
+// workspace each event is from, and handlers' contexts already have it.
+func WithTeamID(ctx context.Context, teamID string) context.Context {
+	if len(teamID) == 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, teamIDKey{}, teamID)
+}
+
+// TeamIDFromContext returns the workspace WithTeamID said events published
+// with ctx are from, or an empty string.
+func TeamIDFromContext(ctx context.Context) string {
+	s, _ := ctx.Value(teamIDKey{}).(string)
+	return s
+}
+
+// eventSource returns the platform the event is from, and the workspace it
+// says it's from, for events published without one. The workspace is only a
+// best guess, since a message's team is its author's, which differs in
+// channels shared with other workspaces.
+func eventSource(raw []byte) (platform, teamID string) {
+	v, err := fastjson.ParseBytes(raw)
+	if err != nil {
+		return PlatformSlack, ""
+	}
+
+	platform = PlatformSlack
+
+	if p := v.GetStringBytes("platform"); len(p) > 0 {
+		platform = string(p)
+	}
+
+	// slash commands and other events
+	for _, k := range []string{"team_id", "team"} {
+		if s := v.GetStringBytes(k); len(s) > 0 {
+			return platform, string(s)
+		}
+	}
+
+	// interactions
+	if s := v.GetStringBytes("team", "id"); len(s) > 0 {
+		return platform, string(s)
+	}
+
+	// team_join and user_change
+	return platform, string(v.GetStringBytes("user", "team_id"))
+}
+
+// errNoPlatform is returned for events from a platform the workqueue has no
+// Workspace for
+var errNoPlatform = errors.New("workqueue has no client for the platform")
+
+// workspace returns the Slack client and bot user to handle an event with.
+// Events from the workspace the consumer is deployed to, or from one the app
+// isn't installed to, use the consumer's own. Events from other platforms use
+// that platform's.
+func (i *I) workspace(ctx context.Context, meta EventMetadata) (SlackClient, *slack.User, error) {
+	if meta.Platform != PlatformSlack && len(meta.Platform) > 0 {
+		w, ok := i.pfs[meta.Platform]
+		if !ok {
+			return nil, nil, fmt.Errorf("%w %q", errNoPlatform, meta.Platform)
+		}
+
+		return w.SlackClient, w.SlackUser, nil
+	}
+
+	teamID := meta.TeamID
+
+	if i.ws == nil || len(teamID) == 0 || (i.self != nil && i.self.TeamID == teamID) {
+		return i.sc, i.self, nil
+	}
+
+	w, ok, err := i.ws.Workspace(ctx, teamID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !ok {
+		return i.sc, i.self, nil
+	}
+
+	return w.SlackClient, w.SlackUser, nil
+}
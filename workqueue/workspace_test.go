@@ -0,0 +1,79 @@
+package workqueue
+
+import (
+	"context"
+	"testing"
+)
+
+func Test_eventSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		platform string
+		want     string
+	}{
+		{
+			name: "message",
+			raw:  `{"type":"message","channel":"C123","team":"T123"}`,
+			want: "T123",
+		},
+		{
+			name: "slash_command",
+			raw:  `{"command":"/gopher","team_id":"T123"}`,
+			want: "T123",
+		},
+		{
+			name: "interaction",
+			raw:  `{"type":"block_actions","team":{"id":"T123","domain":"gophers"}}`,
+			want: "T123",
+		},
+		{
+			name: "team_join",
+			raw:  `{"type":"team_join","user":{"id":"U123","team_id":"T123"}}`,
+			want: "T123",
+		},
+		{
+			name:     "discord",
+			raw:      `{"type":"message","platform":"discord","channel":"123","team":"456"}`,
+			platform: "discord",
+			want:     "456",
+		},
+		{
+			name: "reaction",
+			raw:  `{"type":"reaction_added","item":{"channel":"C123"}}`,
+		},
+		{
+			name: "invalid",
+			raw:  `{`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.platform == "" {
+				tt.platform = PlatformSlack
+			}
+
+			platform, got := eventSource([]byte(tt.raw))
+			if platform != tt.platform || got != tt.want {
+				t.Fatalf("eventSource() = %q, %q; want %q, %q", platform, got, tt.platform, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithTeamID(t *testing.T) {
+	ctx := context.Background()
+
+	if got := TeamIDFromContext(ctx); len(got) > 0 {
+		t.Fatalf("TeamIDFromContext() = %q, want empty", got)
+	}
+
+	if got := WithTeamID(ctx, ""); got != ctx {
+		t.Fatal("WithTeamID() with an empty team ID returned a new context")
+	}
+
+	if got := TeamIDFromContext(WithTeamID(ctx, "T123")); got != "T123" {
+		t.Fatalf("TeamIDFromContext() = %q, want T123", got)
+	}
+}
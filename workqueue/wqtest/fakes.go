@@ -0,0 +1,61 @@
+package wqtest
+
+import (
+	"context"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+)
+
+// Channels is a fake workqueue.ChannelSvc, of channel IDs by name. Like the
+// channel cache, Lookup returns true for a channel that isn't in it.
+type Channels map[string]string
+
+var _ workqueue.ChannelSvc = Channels(nil)
+
+// Lookup satisfies workqueue.ChannelSvc.
+func (c Channels) Lookup(_ context.Context, channelName string) (slack.Channel, bool, error) {
+	id, ok := c[channelName]
+	if !ok {
+		return slack.Channel{}, true, nil
+	}
+
+	var ch slack.Channel
+	ch.ID = id
+	ch.Name = channelName
+
+	return ch, false, nil
+}
+
+// UserGroups is a fake workqueue.UserGroupSvc, of member user IDs by user
+// group handle.
+type UserGroups map[string][]string
+
+var _ workqueue.UserGroupSvc = UserGroups(nil)
+
+// InGroup satisfies workqueue.UserGroupSvc.
+func (g UserGroups) InGroup(_ context.Context, userID, handle string) (bool, error) {
+	return contains(g[handle], userID), nil
+}
+
+// Roles is a fake workqueue.PermissionSvc, of roles by user ID. Unlike the
+// permissions package, a role doesn't imply the less privileged ones, so list
+// each role the user needs to have.
+type Roles map[string][]string
+
+var _ workqueue.PermissionSvc = Roles(nil)
+
+// UserHasRole satisfies workqueue.PermissionSvc.
+func (r Roles) UserHasRole(_ context.Context, userID, role string) (bool, error) {
+	return contains(r[userID], role), nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,131 @@
+package wqtest
+
+import (
+	"context"
+	"runtime/debug"
+	"testing"
+	"time"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// The results of delivering a message, named like the results of the
+// workqueue's handled messages metric.
+const (
+	ResultSuccess    = "success"
+	ResultDiscarded  = "discarded"
+	ResultDeadLetter = "dead_letter"
+)
+
+// Outcome is what happened to a message given to a Queue.
+type Outcome struct {
+	// Result is ResultSuccess, ResultDiscarded, or ResultDeadLetter.
+	Result string
+
+	// Attempts is how many times the handler was called.
+	Attempts int
+
+	// Errors are what the handler returned for each failed attempt.
+	Errors []error
+}
+
+// Err returns the error from the last attempt, if it failed.
+func (o Outcome) Err() error {
+	if len(o.Errors) == 0 || o.Result == ResultSuccess {
+		return nil
+	}
+
+	return o.Errors[len(o.Errors)-1]
+}
+
+// Queue is an in-memory queue, which gives a message to a handler until it's
+// done with it, following the workqueue's rules for retrying, discarding, and
+// dead-lettering messages. It doesn't wait out the retry backoffs.
+type Queue struct {
+	tb  testing.TB
+	cfg workqueue.ContextConfig
+
+	// Policy is the retry policy, of which only MaxAttempts is used. It's
+	// workqueue.DefaultRetryPolicy unless changed.
+	Policy workqueue.RetryPolicy
+
+	// Timeout is how long the handler has for each attempt, like
+	// HandlerOpts.Timeout. It's 30 seconds unless changed.
+	Timeout time.Duration
+
+	// DeadLetters are the outcomes of the messages that were dead-lettered.
+	DeadLetters []Outcome
+}
+
+// NewQueue returns a *Queue that calls handlers with a Context using cfg.
+func NewQueue(tb testing.TB, cfg workqueue.ContextConfig) *Queue {
+	return &Queue{
+		tb:      tb,
+		cfg:     cfg,
+		Policy:  workqueue.DefaultRetryPolicy,
+		Timeout: 30 * time.Second,
+	}
+}
+
+// Deliver gives the message to invoke, which calls the handler with it, until
+// the handler succeeds, discards it, or it's dead-lettered.
+func (q *Queue) Deliver(invoke func(ctx workqueue.Context) (shouldRetry, discarded bool, err error)) Outcome {
+	var o Outcome
+
+	for {
+		o.Attempts++
+
+		shouldRetry, discarded, err := q.attempt(invoke)
+
+		switch {
+		case err == nil:
+			o.Result = ResultSuccess
+			return o
+
+		case discarded:
+			o.Errors = append(o.Errors, err)
+			o.Result = ResultDiscarded
+			return o
+		}
+
+		o.Errors = append(o.Errors, err)
+
+		if !shouldRetry || o.Attempts >= q.Policy.MaxAttempts {
+			o.Result = ResultDeadLetter
+			q.DeadLetters = append(q.DeadLetters, o)
+			return o
+		}
+	}
+}
+
+// DeliverMessage gives the message to fn, like Deliver.
+func (q *Queue) DeliverMessage(fn workqueue.MessageHandler, me *slackevents.MessageEvent) Outcome {
+	return q.Deliver(func(ctx workqueue.Context) (bool, bool, error) {
+		return fn(ctx, me)
+	})
+}
+
+// attempt calls invoke once, turning a panic into a *workqueue.PanicError
+// that's not retried, like the workqueue does.
+func (q *Queue) attempt(invoke func(ctx workqueue.Context) (bool, bool, error)) (shouldRetry, discarded bool, err error) {
+	cfg := q.cfg
+	if cfg.Logger == nil {
+		l := Logger(q.tb)
+		cfg.Logger = &l
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), q.Timeout)
+	defer cancel()
+
+	defer func() {
+		if v := recover(); v != nil {
+			shouldRetry, discarded = false, false
+			err = &workqueue.PanicError{Value: v, Stack: debug.Stack()}
+		}
+	}()
+
+	shouldRetry, discarded, err = invoke(workqueue.NewContext(ctx, cfg))
+
+	return shouldRetry || workqueue.IsRetryable(err), discarded || workqueue.IsDiscarded(err), err
+}
@@ -0,0 +1,107 @@
+package wqtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+)
+
+// Call is one Slack API request the Slack server received.
+type Call struct {
+	// Method is the API method, like "chat.postMessage".
+	Method string
+
+	// Values are the request's form and query values, including the token.
+	Values url.Values
+}
+
+// response is what the Slack server replies to a method with
+type response struct {
+	status int
+	body   string
+}
+
+// Slack is a fake Slack API server, which records the calls made to it. Every
+// method succeeds with {"ok":true} unless told to Respond otherwise.
+type Slack struct {
+	s *httptest.Server
+
+	mu        sync.Mutex
+	calls     []Call
+	responses map[string]response
+}
+
+// NewSlack returns a *Slack that's closed when the test finishes.
+func NewSlack(tb testing.TB) *Slack {
+	s := &Slack{responses: make(map[string]response)}
+	s.s = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	tb.Cleanup(s.s.Close)
+
+	return s
+}
+
+// Client returns a Slack client that calls s, to use as ContextConfig's
+// SlackClient.
+func (s *Slack) Client() *slack.Client {
+	return slack.New("xoxb-wqtest", slack.OptionAPIURL(s.s.URL+"/"))
+}
+
+// Respond makes calls to the method, like "chat.postMessage", get the HTTP
+// status and JSON body. Use it to have Slack fail, like with a 500 to see that
+// the handler is retried, or to return the data the handler needs.
+func (s *Slack) Respond(method string, status int, body string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.responses[method] = response{status: status, body: body}
+}
+
+// Calls returns the calls made so far, in the order they were made.
+func (s *Slack) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	calls := make([]Call, len(s.calls))
+	copy(calls, s.calls)
+
+	return calls
+}
+
+// CallsTo returns the calls made so far to the method.
+func (s *Slack) CallsTo(method string) []Call {
+	var calls []Call
+
+	for _, c := range s.Calls() {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+
+	return calls
+}
+
+func (s *Slack) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+
+	method := strings.TrimPrefix(r.URL.Path, "/")
+
+	s.mu.Lock()
+	s.calls = append(s.calls, Call{Method: method, Values: r.Form})
+	resp, ok := s.responses[method]
+	s.mu.Unlock()
+
+	if !ok {
+		resp = response{status: http.StatusOK, body: `{"ok":true}`}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.status)
+
+	_, _ = w.Write([]byte(resp.body))
+}
@@ -0,0 +1,47 @@
+// Package wqtest provides helpers for unit testing workqueue handlers without
+// Redis or Slack: a Context to call them with, fakes for the services it
+// exposes, a Slack API server that records what was called, and a Queue that
+// retries or discards messages the way the workqueue does.
+package wqtest
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/rs/zerolog"
+)
+
+// NewContext returns a workqueue.Context using cfg, like workqueue.NewContext,
+// that's canceled when the test finishes. If cfg has no Logger, it logs to the
+// test's log, so what the handler logged is shown when a test fails.
+func NewContext(tb testing.TB, cfg workqueue.ContextConfig) workqueue.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	tb.Cleanup(cancel)
+
+	if cfg.Logger == nil {
+		l := Logger(tb)
+		cfg.Logger = &l
+	}
+
+	return workqueue.NewContext(ctx, cfg)
+}
+
+// Logger returns a logger that writes to the test's log.
+func Logger(tb testing.TB) zerolog.Logger {
+	w := zerolog.ConsoleWriter{Out: testWriter{tb: tb}, NoColor: true}
+	return zerolog.New(w).With().Timestamp().Logger()
+}
+
+// testWriter writes each log line with tb.Log
+type testWriter struct {
+	tb testing.TB
+}
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.tb.Helper()
+	w.tb.Log(string(bytes.TrimRight(p, "\n")))
+
+	return len(p), nil
+}
@@ -0,0 +1,122 @@
+package wqtest
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gobridge/gopherbot/workqueue"
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+// echo is a handler like the ones in the consumer, which replies in the
+// #general channel with the message's text.
+func echo(ctx workqueue.Context, me *slackevents.MessageEvent) error {
+	if me.Text == "" {
+		return workqueue.Discard(errors.New("empty message"))
+	}
+
+	c, notFound, err := ctx.ChannelSvc().Lookup(ctx, "general")
+	if err != nil {
+		return fmt.Errorf("failed to find #general: %w", err)
+	}
+
+	if notFound {
+		return workqueue.Discard(errors.New("#general not found"))
+	}
+
+	if _, _, err := ctx.Slack().PostMessageContext(ctx, c.ID, slack.MsgOptionText(me.Text, false)); err != nil {
+		return workqueue.Retryable(fmt.Errorf("failed to post message: %w", err))
+	}
+
+	return nil
+}
+
+func TestQueue(t *testing.T) {
+	s := NewSlack(t)
+
+	q := NewQueue(t, workqueue.ContextConfig{
+		SlackClient:  s.Client(),
+		ChannelCache: Channels{"general": "C123"},
+	})
+	q.Policy.MaxAttempts = 3
+
+	fn := workqueue.MessageHandlerV2(echo).Handler()
+
+	o := q.DeliverMessage(fn, &slackevents.MessageEvent{Text: "hello"})
+
+	if o.Result != ResultSuccess || o.Attempts != 1 {
+		t.Fatalf("Outcome = %+v, want success on the first attempt", o)
+	}
+
+	calls := s.CallsTo("chat.postMessage")
+
+	if len(calls) != 1 || calls[0].Values.Get("channel") != "C123" || calls[0].Values.Get("text") != "hello" {
+		t.Fatalf("chat.postMessage calls = %+v, want one to C123 with hello", calls)
+	}
+
+	s.Respond("chat.postMessage", http.StatusInternalServerError, `{"ok":false}`)
+
+	o = q.DeliverMessage(fn, &slackevents.MessageEvent{Text: "hello"})
+
+	if o.Result != ResultDeadLetter || o.Attempts != 3 || o.Err() == nil {
+		t.Fatalf("Outcome = %+v, want dead-lettered after 3 attempts", o)
+	}
+
+	if n := len(s.CallsTo("chat.postMessage")); n != 4 {
+		t.Fatalf("chat.postMessage called %d times, want 4", n)
+	}
+
+	if len(q.DeadLetters) != 1 {
+		t.Fatalf("got %d dead letters, want 1", len(q.DeadLetters))
+	}
+
+	o = q.DeliverMessage(fn, &slackevents.MessageEvent{})
+
+	if o.Result != ResultDiscarded || o.Attempts != 1 {
+		t.Fatalf("Outcome = %+v, want discarded on the first attempt", o)
+	}
+
+	o = q.Deliver(func(workqueue.Context) (bool, bool, error) {
+		panic("boom")
+	})
+
+	var pe *workqueue.PanicError
+	if o.Result != ResultDeadLetter || o.Attempts != 1 || !errors.As(o.Err(), &pe) {
+		t.Fatalf("Outcome = %+v, want a panic dead-lettered without retrying", o)
+	}
+}
+
+func TestFakes(t *testing.T) {
+	ctx := NewContext(t, workqueue.ContextConfig{
+		ChannelCache:   Channels{"general": "C123"},
+		UserGroupCache: UserGroups{"gopher-admins": {"U123"}},
+		Permissions:    Roles{"U123": {"moderator"}},
+	})
+
+	if c, notFound, err := ctx.ChannelSvc().Lookup(ctx, "general"); err != nil || notFound || c.ID != "C123" {
+		t.Fatalf("Lookup() = %q, %t, %v; want C123, false, <nil>", c.ID, notFound, err)
+	}
+
+	if _, notFound, _ := ctx.ChannelSvc().Lookup(ctx, "random"); !notFound {
+		t.Fatal("Lookup() notFound = false for a channel that isn't there")
+	}
+
+	if ok, err := ctx.UserInGroup("U123", "gopher-admins"); err != nil || !ok {
+		t.Fatalf("UserInGroup() = %t, %v; want true, <nil>", ok, err)
+	}
+
+	if ok, _ := ctx.UserInGroup("U456", "gopher-admins"); ok {
+		t.Fatal("UserInGroup() = true for a user not in the group")
+	}
+
+	if ok, err := ctx.UserHasRole("U123", "moderator"); err != nil || !ok {
+		t.Fatalf("UserHasRole() = %t, %v; want true, <nil>", ok, err)
+	}
+
+	if ok, _ := ctx.UserHasRole("U123", "admin"); ok {
+		t.Fatal("UserHasRole() = true for a role the user doesn't have")
+	}
+}